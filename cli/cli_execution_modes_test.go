@@ -2,11 +2,13 @@ package main
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -262,6 +264,51 @@ func TestGetInputReaderEdgeCases(t *testing.T) {
 	})
 }
 
+// TestValidateRunOptions tests which flag combinations a run accepts
+func TestValidateRunOptions(t *testing.T) {
+	signing := contractSigning{keys: []ed25519.PrivateKey{ed25519.NewKeyFromSeed(make([]byte, 32))}}
+	compress := contractSigning{compress: true}
+	signAndCompress := contractSigning{keys: signing.keys, compress: true}
+
+	tests := []struct {
+		name    string
+		opts    runOptions
+		wantErr string // Empty if the options are valid
+	}{
+		{"defaults", runOptions{format: "text"}, ""},
+		{"sign new contract", runOptions{format: "text", dryRun: true, resolve: true, signing: signing}, ""},
+		{"co-sign contract", runOptions{format: "text", planFile: "c.plan", signing: signing}, ""},
+		{"sign without contract", runOptions{format: "text", dryRun: true, signing: signing}, "--sign-key only applies when writing a contract"},
+		{"drift report with contract", runOptions{format: "text", planFile: "c.plan", driftReportFile: "d.json"}, ""},
+		{"drift report without contract", runOptions{format: "text", driftReportFile: "d.json"}, "--drift-report only applies when verifying a contract"},
+		{"pipefail", runOptions{format: "text", pipefail: true}, ""},
+		{"pipefail with contract", runOptions{format: "text", planFile: "c.plan", pipefail: true}, "--pipefail is recorded in the contract"},
+		{"json dry run", runOptions{format: "json", dryRun: true}, ""},
+		{"json without dry run", runOptions{format: "json"}, "--format only applies to --dry-run"},
+		{"compress new contract", runOptions{format: "text", dryRun: true, resolve: true, signing: compress}, ""},
+		{"compress co-signed contract", runOptions{format: "text", planFile: "c.plan", signing: signAndCompress}, ""},
+		{"compress unsigned contract", runOptions{format: "text", planFile: "c.plan", signing: compress}, "--compress only applies when writing a contract"},
+		{"compress json plan", runOptions{format: "json", dryRun: true, resolve: true, signing: compress}, "--compress only applies when writing a contract"},
+		{"keep every run", runOptions{format: "text", retention: runRetention{}}, ""},
+		{"negative runs keep", runOptions{format: "text", retention: runRetention{keep: -1}}, "can't be negative"},
+		{"negative runs max age", runOptions{format: "text", retention: runRetention{maxAge: -time.Hour}}, "can't be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRunOptions(tt.opts)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var cliErr *CLIError
+			require.ErrorAs(t, err, &cliErr)
+			assert.Equal(t, "usage", cliErr.Type)
+			assert.Contains(t, cliErr.Message, tt.wantErr)
+		})
+	}
+}
+
 // TestStdinDetection tests the logic for detecting piped input
 func TestStdinDetection(t *testing.T) {
 	t.Run("StdinStatError", func(t *testing.T) {
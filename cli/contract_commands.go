@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/core/planfmt/formatter"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+// planKindNames names PlanHeader.PlanKind values.
+var planKindNames = map[uint8]string{
+	0: "view",
+	1: "contract",
+	2: "executed",
+}
+
+// readContractFile reads a contract from disk.
+func readContractFile(planFile string) (string, [32]byte, *planfmt.Plan, error) {
+	f, err := os.Open(planFile)
+	if err != nil {
+		return "", [32]byte{}, nil, fmt.Errorf("failed to open plan file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	target, hash, plan, err := planfmt.ReadContract(f)
+	if err != nil {
+		return "", [32]byte{}, nil, fmt.Errorf("failed to read contract: %w", err)
+	}
+	return target, hash, plan, nil
+}
+
+// contractVault creates a vault keyed with the contract's PlanSalt, so
+// replanning produces the same DisplayIDs as the contract.
+func contractVault(planFile string) (*vault.Vault, error) {
+	_, _, plan, err := readContractFile(planFile)
+	if err != nil {
+		return nil, err
+	}
+	return vault.NewWithPlanKey(plan.PlanSalt), nil
+}
+
+// runInspect prints a contract without needing its source: header,
+// target, hash, provenance, approvals, steps and secret use sites. Contracts hold
+// DisplayIDs, never values, so nothing printed here is secret.
+func runInspect(w io.Writer, planFile string, useColor bool) error {
+	target, hash, plan, err := readContractFile(planFile)
+	if err != nil {
+		return err
+	}
+
+	label := func(name string) string {
+		return Colorize(fmt.Sprintf("%-11s", name+":"), ColorCyan, useColor)
+	}
+
+	kind, ok := planKindNames[plan.Header.PlanKind]
+	if !ok {
+		kind = "unknown"
+	}
+
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("Contract"), planFile)
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("Target"), target)
+	_, _ = fmt.Fprintf(w, "%s %x\n", label("Hash"), hash)
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("SchemaID"), formatHeaderID(plan.Header.SchemaID))
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("CreatedAt"), formatCreatedAt(plan.Header.CreatedAt))
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("Compiler"), formatHeaderID(plan.Header.Compiler))
+	_, _ = fmt.Fprintf(w, "%s %s (%d)\n", label("PlanKind"), kind, plan.Header.PlanKind)
+	if plan.Header.Build != "" {
+		_, _ = fmt.Fprintf(w, "%s %s\n", label("Build"), plan.Header.Build)
+	}
+
+	if pv := plan.Provenance; !pv.IsZero() {
+		_, _ = fmt.Fprintf(w, "%s %x\n", label("Source"), pv.SourceDigest)
+		decorators := make([]string, 0, len(pv.Decorators))
+		for _, d := range pv.Decorators {
+			decorators = append(decorators, fmt.Sprintf("@%s %s", d.Path, d.Version))
+		}
+		_, _ = fmt.Fprintf(w, "%s %s\n", label("Decorators"), strings.Join(decorators, ", "))
+	}
+	if plan.Pipefail {
+		_, _ = fmt.Fprintf(w, "%s on\n", label("Pipefail"))
+	}
+
+	if len(plan.Signatures) == 0 {
+		_, _ = fmt.Fprintf(w, "%s none\n", label("Signatures"))
+	} else {
+		_, _ = fmt.Fprintf(w, "%s %d\n", label("Signatures"), len(plan.Signatures))
+		for _, sig := range plan.Signatures {
+			_, _ = fmt.Fprintf(w, "  ed25519 %s\n", sig.Fingerprint())
+		}
+	}
+
+	_, _ = fmt.Fprintln(w)
+	formatter.FormatTree(w, plan, useColor)
+
+	if len(plan.SecretUses) > 0 {
+		_, _ = fmt.Fprintf(w, "\n%s\n", Colorize("Secret uses:", ColorCyan, useColor))
+		for _, use := range plan.SecretUses {
+			_, _ = fmt.Fprintf(w, "  %s  %s\n", use.DisplayID, use.Site)
+		}
+	}
+	return nil
+}
+
+// formatHeaderID formats a fixed-size header ID, or "unset" if it is zero.
+func formatHeaderID(id [16]byte) string {
+	if id == [16]byte{} {
+		return "unset"
+	}
+	return fmt.Sprintf("%x", id)
+}
+
+// formatCreatedAt formats PlanHeader.CreatedAt (Unix nanoseconds, UTC).
+func formatCreatedAt(ns uint64) string {
+	if ns == 0 {
+		return "unset"
+	}
+	return time.Unix(0, int64(ns)).UTC().Format(time.RFC3339)
+}
+
+// runVerify replans the source and compares it with the contract without
+// executing anything. Drift is reported as a CLIError with ExitContractDrift.
+func runVerify(w io.Writer, opts runOptions, args targetArgs, vlt *vault.Vault) error {
+	// Planning may still open transports (@ssh.connect)
+	sessions := decorator.NewSessionPool()
+	defer sessions.CloseAll()
+
+	contract, err := verifyContract(opts, args, vlt, sessions, nil)
+	if err != nil {
+		return failDriftReport(opts.driftReportFile, vlt, err)
+	}
+
+	report := formatter.NewDriftReport(contract.plan, contract.freshPlan, contract.hash, contract.freshHash)
+	if opts.driftReportFile != "" {
+		if err := writeDriftReport(opts.driftReportFile, report); err != nil {
+			return err
+		}
+	}
+
+	if !report.Verified {
+		FormatContractVerificationError(os.Stderr, contract.plan, contract.freshPlan, report.Drifts, !opts.noColor)
+		return newContractDriftError(opts.planFile, report)
+	}
+
+	_, _ = fmt.Fprintf(w, "%s %s matches %s (%d steps, hash %x)\n",
+		Colorize("✓", ColorGreen, !opts.noColor), opts.planFile, opts.file, len(contract.freshPlan.Steps), contract.hash[:8])
+	for _, note := range report.Notes {
+		_, _ = fmt.Fprintf(w, "  note: %s\n", note.Detail)
+	}
+	return nil
+}
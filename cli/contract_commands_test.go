@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+// writeTestContract writes plan as a contract file.
+func writeTestContract(t *testing.T, plan *planfmt.Plan, opts ...planfmt.WriteOption) string {
+	t.Helper()
+	hash, err := planfmt.Write(&bytes.Buffer{}, plan)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, planfmt.WriteContract(&buf, plan.Target, hash, plan, opts...))
+	path := filepath.Join(t.TempDir(), "deploy.plan")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	return path
+}
+
+func TestRunInspect(t *testing.T) {
+	plan := &planfmt.Plan{
+		Header: planfmt.PlanHeader{CreatedAt: 1700000000000000000, Build: "github.com/opal-lang/opal/cli v0.3.0 go1.25.0"},
+		Target: "deploy",
+		Steps: []planfmt.Step{{
+			ID: 1,
+			Tree: &planfmt.CommandNode{
+				Decorator: "@shell",
+				Args:      []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "kubectl apply --token opal:AAAA"}}},
+			},
+		}},
+		SecretUses: []planfmt.SecretUse{{DisplayID: "opal:AAAA", SiteID: "site", Site: "root/step-1/params/command"}},
+		Provenance: planfmt.Provenance{
+			Decorators: []planfmt.DecoratorVersion{{Path: "retry", Version: "1.1.0"}, {Path: "shell", Version: "1.0.0"}},
+		},
+	}
+	path := writeTestContract(t, plan)
+
+	var out bytes.Buffer
+	require.NoError(t, runInspect(&out, path, false))
+	output := out.String()
+
+	assert.Contains(t, output, "Target:     deploy")
+	assert.Contains(t, output, "SchemaID:   unset")
+	assert.Contains(t, output, "CreatedAt:  2023-11-14T22:13:20Z")
+	assert.Contains(t, output, "PlanKind:   view (0)")
+	assert.Contains(t, output, "Signatures: none")
+	assert.Contains(t, output, "Build:      github.com/opal-lang/opal/cli v0.3.0 go1.25.0")
+	assert.Contains(t, output, "Decorators: @retry 1.1.0, @shell 1.0.0")
+	assert.Contains(t, output, "kubectl apply --token opal:AAAA")
+	assert.Contains(t, output, "opal:AAAA  root/step-1/params/command")
+}
+
+func TestRunInspectErrors(t *testing.T) {
+	err := runInspect(&bytes.Buffer{}, filepath.Join(t.TempDir(), "missing.plan"), false)
+	assert.ErrorContains(t, err, "failed to open plan file")
+
+	garbage := filepath.Join(t.TempDir(), "garbage.plan")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a contract"), 0o644))
+	err = runInspect(&bytes.Buffer{}, garbage, false)
+	assert.ErrorContains(t, err, "failed to read contract")
+}
+
+// TestVerifyCommand verifies opal verify exits 0 on a match and
+// ExitContractDrift when the source changed, without executing
+func TestVerifyCommand(t *testing.T) {
+	opalBin := buildOpalBinary(t)
+	defer os.Remove(opalBin)
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	testFile := createTestFile(t, `fun deploy = touch "`+marker+`"`)
+	defer os.Remove(testFile)
+
+	planFile := filepath.Join(dir, "deploy.plan")
+	planData, err := exec.Command(opalBin, "-f", testFile, "deploy", "--dry-run", "--resolve").Output()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(planFile, planData, 0o644))
+
+	output, err := exec.Command(opalBin, "verify", planFile, "-f", testFile, "--no-color").CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Contains(t, string(output), "matches")
+	assert.NoFileExists(t, marker, "verify must not execute the plan")
+
+	require.NoError(t, os.WriteFile(testFile, []byte(`fun deploy = touch "`+marker+`.changed"`), 0o644))
+	output, err = exec.Command(opalBin, "verify", planFile, "-f", testFile, "--no-color").CombinedOutput()
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr), "expected exit error, got %v", err)
+	assert.Equal(t, ExitContractDrift, exitErr.ExitCode())
+	assert.Contains(t, string(output), "source_changed")
+
+	_, err = exec.Command(opalBin, "verify", filepath.Join(dir, "missing.plan"), "-f", testFile).CombinedOutput()
+	require.True(t, errors.As(err, &exitErr), "expected exit error, got %v", err)
+	assert.Equal(t, 1, exitErr.ExitCode())
+}
+
+func TestFunctionNamedLikeSubcommand(t *testing.T) {
+	opalBin := buildOpalBinary(t)
+	defer os.Remove(opalBin)
+
+	testFile := createTestFile(t, `fun verify = echo "verified by target"
+fun inspect(what: String) = echo "inspecting"`)
+	defer os.Remove(testFile)
+
+	output, err := exec.Command(opalBin, "-f", testFile, "verify").CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Contains(t, string(output), "verified by target")
+
+	output, err = exec.Command(opalBin, "-f", testFile, "inspect", "disk").CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Contains(t, string(output), "inspecting")
+
+	// Without such a function, the subcommand still applies
+	otherFile := createTestFile(t, `fun deploy = echo "deploying"`)
+	defer os.Remove(otherFile)
+	output, err = exec.Command(opalBin, "-f", otherFile, "verify").CombinedOutput()
+	require.Error(t, err)
+	assert.Contains(t, string(output), "requires at least 1 arg")
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/opal-lang/opal/core/planfmt"
@@ -12,3 +13,43 @@ import (
 func DisplayPlan(w io.Writer, plan *planfmt.Plan, useColor bool) {
 	formatter.FormatTree(w, plan, useColor)
 }
+
+// checkPlanFormat validates --format. Structured formats replace the dry-run
+// tree or contract, so they cannot be signed or compressed.
+func checkPlanFormat(format string, dryRun bool, signing contractSigning) error {
+	switch format {
+	case "text":
+		return nil
+	case "json", "yaml":
+	default:
+		return &CLIError{
+			Type:    "usage",
+			Message: fmt.Sprintf("unknown --format %q", format),
+			Hint:    "Use --format=text, --format=json or --format=yaml",
+		}
+	}
+
+	if !dryRun {
+		return &CLIError{
+			Type:    "usage",
+			Message: "--format only applies to --dry-run",
+			Hint:    fmt.Sprintf("opal <target> --dry-run --format=%s", format),
+		}
+	}
+	if len(signing.keys) > 0 {
+		return &CLIError{
+			Type:    "usage",
+			Message: fmt.Sprintf("--format=%s cannot be combined with --sign-key", format),
+			Hint:    "Signed contracts are binary; write one with --dry-run --resolve and inspect it with opal inspect",
+		}
+	}
+	return nil
+}
+
+// writePlanDocument writes plan as a JSON or YAML document (see planfmt.WriteJSON).
+func writePlanDocument(w io.Writer, plan *planfmt.Plan, format string) error {
+	if format == "yaml" {
+		return planfmt.WriteYAML(w, plan)
+	}
+	return planfmt.WriteJSON(w, plan)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"testing"
 
 	"github.com/opal-lang/opal/core/planfmt"
@@ -154,3 +155,14 @@ func TestDisplayPlan_NestedDecorator(t *testing.T) {
 
 	assert.Equal(t, expected, output)
 }
+
+func TestCheckPlanFormat(t *testing.T) {
+	assert.NoError(t, checkPlanFormat("text", false, contractSigning{}))
+	assert.NoError(t, checkPlanFormat("json", true, contractSigning{}))
+	assert.NoError(t, checkPlanFormat("yaml", true, contractSigning{}))
+
+	assert.ErrorContains(t, checkPlanFormat("xml", true, contractSigning{}), `unknown --format "xml"`)
+	assert.ErrorContains(t, checkPlanFormat("json", false, contractSigning{}), "--format only applies to --dry-run")
+	signing := contractSigning{keys: []ed25519.PrivateKey{ed25519.NewKeyFromSeed(make([]byte, 32))}}
+	assert.ErrorContains(t, checkPlanFormat("yaml", true, signing), "cannot be combined with --sign-key")
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
 	"github.com/opal-lang/opal/core/planfmt"
 	"github.com/opal-lang/opal/core/planfmt/formatter"
+	"github.com/opal-lang/opal/runtime/executor"
 	"github.com/opal-lang/opal/runtime/planner"
+	"github.com/opal-lang/opal/runtime/vault"
 )
 
 // CLIError represents a formatted CLI error with context
@@ -16,6 +21,9 @@ type CLIError struct {
 	Message string
 	Details string // Additional context
 	Hint    string // How to fix it
+
+	// ExitCode overrides the process exit code (0 means the default, 1)
+	ExitCode int
 }
 
 // Error implements the error interface
@@ -33,6 +41,64 @@ func (e *CLIError) Error() string {
 	return b.String()
 }
 
+// ExitTimeout is the process exit code when a @timeout deadline stops
+// execution (GNU timeout convention).
+const ExitTimeout = 124
+
+// ExitContractDrift is the process exit code when a contract no longer
+// matches its source, so CI can tell drift apart from other failures.
+const ExitContractDrift = 3
+
+// newContractDriftError reports a contract whose fresh plan hashes differently.
+func newContractDriftError(planFile string, report *formatter.DriftReport) *CLIError {
+	causes := make([]string, len(report.Causes))
+	for i, cause := range report.Causes {
+		causes[i] = string(cause)
+	}
+	return &CLIError{
+		Type:    "contract",
+		Message: fmt.Sprintf("contract verification failed: plan has drifted since contract was created (%s)", strings.Join(causes, ", ")),
+		Details: "The differences are shown above.",
+		Hint: fmt.Sprintf("Review the changes to ensure they are intentional, then regenerate the contract:\n"+
+			"opal %s --dry-run --resolve > %s", report.Target, planFile),
+		ExitCode: ExitContractDrift,
+	}
+}
+
+// pipefailInContractError rejects --pipefail for a contract: the setting is
+// recorded and hashed when the contract is written, so a flag can't change it.
+func pipefailInContractError(planFile string) *CLIError {
+	return &CLIError{
+		Type:    "usage",
+		Message: "--pipefail is recorded in the contract and can't be changed when running it",
+		Hint:    fmt.Sprintf("Write a new contract with: opal <command> --pipefail --dry-run --resolve > %s", planFile),
+	}
+}
+
+// newTimeoutError reports a @timeout deadline separately from a normal failure.
+func newTimeoutError(result *executor.ExecutionResult) *CLIError {
+	details := fmt.Sprintf("A @timeout block %s and was terminated.", result.Timeout)
+	if result.Telemetry != nil && result.Telemetry.FailedStep != nil {
+		details = fmt.Sprintf("Step %d: @timeout block %s and was terminated.", *result.Telemetry.FailedStep, result.Timeout)
+	}
+	return &CLIError{
+		Type:     "timeout",
+		Message:  fmt.Sprintf("execution %s", result.Timeout),
+		Details:  details,
+		Hint:     "Increase the @timeout duration or check why the command hangs.",
+		ExitCode: ExitTimeout,
+	}
+}
+
+// exitCodeFor returns the process exit code for an error returned by the root command.
+func exitCodeFor(err error) int {
+	var cliErr *CLIError
+	if errors.As(err, &cliErr) && cliErr.ExitCode != 0 {
+		return cliErr.ExitCode
+	}
+	return 1
+}
+
 // FormatError formats an error for CLI output with colors
 func FormatError(w io.Writer, err error, useColor bool) {
 	if err == nil {
@@ -95,11 +161,64 @@ func formatCLIError(w io.Writer, err *CLIError, useColor bool) {
 }
 
 // FormatContractVerificationError formats contract verification failures with diff
-func FormatContractVerificationError(w io.Writer, contractPlan, freshPlan *planfmt.Plan, useColor bool) {
+// and the classified drift causes
+func FormatContractVerificationError(w io.Writer, contractPlan, freshPlan *planfmt.Plan, drifts []formatter.Drift, useColor bool) {
 	_, _ = fmt.Fprintf(w, "%sCONTRACT VERIFICATION FAILED%s\n\n", Colorize("", ColorRed, useColor), ColorReset)
 
 	// Show detailed diff of what changed
 	diff := formatter.Diff(contractPlan, freshPlan)
 	diffOutput := formatter.FormatDiff(diff, useColor)
 	_, _ = fmt.Fprint(w, diffOutput)
+	_, _ = fmt.Fprint(w, formatter.FormatDriftCauses(drifts, useColor))
+}
+
+// writeDriftReport writes the verification report as JSON for CI.
+func writeDriftReport(path string, report *formatter.DriftReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode drift report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write drift report: %w", err)
+	}
+	return nil
+}
+
+// failDriftReport records a verification that stopped before the plans were
+// compared, so a report from an earlier run is not left behind. The error
+// message is scrubbed with vlt's secrets (nil before any value is resolved).
+// It returns verifyErr, with any failure to write the report as a detail.
+func failDriftReport(path string, vlt *vault.Vault, verifyErr error) error {
+	if path == "" {
+		return verifyErr
+	}
+	message := verifyErr.Error()
+	if vlt != nil {
+		scrubbed, err := vlt.SecretProvider().HandleChunk([]byte(message))
+		if err != nil {
+			message = "<redacted>"
+		} else {
+			message = string(scrubbed)
+		}
+	}
+	report := &formatter.DriftReport{
+		Causes: []formatter.DriftCause{},
+		Drifts: []formatter.Drift{},
+		Error:  message,
+	}
+	writeErr := writeDriftReport(path, report)
+	if writeErr == nil {
+		return verifyErr
+	}
+
+	var cliErr *CLIError
+	if !errors.As(verifyErr, &cliErr) {
+		return fmt.Errorf("%w\n  (%v)", verifyErr, writeErr)
+	}
+	withDetail := *cliErr
+	if withDetail.Details != "" {
+		withDetail.Details += "\n"
+	}
+	withDetail.Details += writeErr.Error()
+	return &withDetail
 }
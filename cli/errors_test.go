@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/opal-lang/opal/core/planfmt/formatter"
+	"github.com/opal-lang/opal/runtime/executor"
+	"github.com/opal-lang/opal/runtime/vault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutErrorReportedSeparately(t *testing.T) {
+	failed := uint64(3)
+	result := &executor.ExecutionResult{
+		ExitCode:  decorator.ExitCanceled,
+		Timeout:   &decorator.TimeoutError{Duration: 5 * time.Minute},
+		Telemetry: &executor.ExecutionTelemetry{FailedStep: &failed},
+	}
+
+	err := newTimeoutError(result)
+
+	var buf bytes.Buffer
+	FormatError(&buf, err, false)
+	output := buf.String()
+
+	assert.Contains(t, output, "execution timed out after 5m0s")
+	assert.Contains(t, output, "Step 3")
+	assert.Contains(t, output, "Hint:")
+	assert.Equal(t, ExitTimeout, exitCodeFor(err))
+}
+
+func TestExitCodeForGenericError(t *testing.T) {
+	assert.Equal(t, 1, exitCodeFor(errors.New("boom")))
+	assert.Equal(t, 1, exitCodeFor(&CLIError{Message: "plain"}))
+	assert.Equal(t, ExitTimeout, exitCodeFor(fmt.Errorf("wrapped: %w", &CLIError{ExitCode: ExitTimeout})))
+}
+
+func TestFailDriftReportScrubsError(t *testing.T) {
+	vlt := vault.NewWithPlanKey(make([]byte, 32))
+	exprID := vlt.DeclareVariable("TOKEN", "literal:s3cr3t-value")
+	vlt.StoreUnresolvedValue(exprID, "s3cr3t-value")
+	vlt.MarkTouched(exprID)
+	vlt.ResolveAllTouched()
+
+	path := filepath.Join(t.TempDir(), "drift.json")
+	verifyErr := &CLIError{Type: "contract", Message: "approval missing for s3cr3t-value", ExitCode: ExitContractDrift}
+	assert.Same(t, verifyErr, failDriftReport(path, vlt, verifyErr))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cr3t-value")
+	var report formatter.DriftReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Contains(t, report.Error, "approval missing for")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestFailDriftReportWriteFailureKeepsError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "drift.json")
+	verifyErr := &CLIError{Type: "contract", Message: "plan has drifted", Hint: "regenerate", ExitCode: ExitContractDrift}
+
+	err := failDriftReport(path, nil, verifyErr)
+	var cliErr *CLIError
+	require.ErrorAs(t, err, &cliErr)
+	assert.Equal(t, "plan has drifted", cliErr.Message)
+	assert.Equal(t, "regenerate", cliErr.Hint)
+	assert.Contains(t, cliErr.Details, "failed to write drift report")
+	assert.Equal(t, ExitContractDrift, exitCodeFor(err))
+
+	plain := errors.New("failed to read contract")
+	err = failDriftReport(path, nil, plain)
+	assert.ErrorIs(t, err, plain)
+	assert.Contains(t, err.Error(), "failed to write drift report")
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/core/planfmt/formatter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -229,6 +233,183 @@ func TestContractVerificationFailure(t *testing.T) {
 	assert.Contains(t, string(output), "contract", "Error should mention contract verification")
 }
 
+// TestContractPipefail verifies a contract keeps the pipefail setting it was
+// written with and rejects the flag when run
+func TestContractPipefail(t *testing.T) {
+	opalBin := buildOpalBinary(t)
+	defer os.Remove(opalBin)
+
+	testFile := createTestFile(t, `fun build = ls /nonexistent-opal-dir | cat`)
+	defer os.Remove(testFile)
+
+	planFile := filepath.Join(t.TempDir(), "build.plan")
+	planData, err := exec.Command(opalBin, "-f", testFile, "build", "--pipefail", "--dry-run", "--resolve").Output()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(planFile, planData, 0o644))
+
+	// The recorded setting applies without the flag
+	cmd := exec.Command(opalBin, "--plan", planFile, "-f", testFile)
+	assert.Error(t, cmd.Run(), "a contract written with --pipefail should fail the pipeline")
+
+	output, err := exec.Command(opalBin, "--plan", planFile, "-f", testFile, "--pipefail").CombinedOutput()
+	assert.Error(t, err)
+	assert.Contains(t, string(output), "--pipefail is recorded in the contract")
+}
+
+// TestContractTiming verifies --timing shows the pipeline breakdown when
+// running a contract, as it does when running from source
+func TestContractTiming(t *testing.T) {
+	opalBin := buildOpalBinary(t)
+	defer os.Remove(opalBin)
+
+	testFile := createTestFile(t, `fun build = echo "built"`)
+	defer os.Remove(testFile)
+
+	planFile := filepath.Join(t.TempDir(), "build.plan")
+	planData, err := exec.Command(opalBin, "-f", testFile, "build", "--dry-run", "--resolve").Output()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(planFile, planData, 0o644))
+
+	output, err := exec.Command(opalBin, "--plan", planFile, "-f", testFile, "--timing").CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Contains(t, string(output), "Pipeline Timing:")
+	assert.Contains(t, string(output), "Execute: ")
+	assert.Contains(t, string(output), "Step 1: ")
+}
+
+// TestContractDriftReport verifies --drift-report classifies why a contract
+// no longer matches
+func TestContractDriftReport(t *testing.T) {
+	opalBin := buildOpalBinary(t)
+	defer os.Remove(opalBin)
+
+	testFile := createTestFile(t, `var REPLICAS = @env.OPAL_TEST_REPLICAS
+fun deploy {
+    echo "replicas=@var.REPLICAS"
+    echo "done"
+}`)
+	defer os.Remove(testFile)
+
+	dir := t.TempDir()
+	planFile := filepath.Join(dir, "deploy.plan")
+	reportFile := filepath.Join(dir, "drift.json")
+
+	cmd := exec.Command(opalBin, "-f", testFile, "deploy", "--dry-run", "--resolve")
+	cmd.Env = append(os.Environ(), "OPAL_TEST_REPLICAS=three")
+	planData, err := cmd.Output()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(planFile, planData, 0o644))
+
+	readReport := func(t *testing.T) formatter.DriftReport {
+		t.Helper()
+		data, err := os.ReadFile(reportFile)
+		require.NoError(t, err)
+		var report formatter.DriftReport
+		require.NoError(t, json.Unmarshal(data, &report))
+		return report
+	}
+
+	t.Run("verified", func(t *testing.T) {
+		cmd := exec.Command(opalBin, "--plan", planFile, "-f", testFile, "--drift-report", reportFile)
+		cmd.Env = append(os.Environ(), "OPAL_TEST_REPLICAS=three")
+		require.NoError(t, cmd.Run())
+		report := readReport(t)
+		assert.True(t, report.Verified)
+		assert.Empty(t, report.Causes)
+	})
+
+	t.Run("env changed", func(t *testing.T) {
+		cmd := exec.Command(opalBin, "--plan", planFile, "-f", testFile, "--drift-report", reportFile, "--no-color")
+		cmd.Env = append(os.Environ(), "OPAL_TEST_REPLICAS=five")
+		output, err := cmd.CombinedOutput()
+		assert.Error(t, err)
+		assert.Contains(t, string(output), "Drift causes:")
+		report := readReport(t)
+		assert.False(t, report.Verified)
+		assert.Equal(t, []formatter.DriftCause{formatter.DriftValueChanged}, report.Causes)
+		require.Len(t, report.Drifts, 1)
+		assert.Equal(t, 1, report.Drifts[0].Step)
+	})
+
+	t.Run("source changed", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(testFile, []byte(`var REPLICAS = @env.OPAL_TEST_REPLICAS
+fun deploy {
+    echo "replicas=@var.REPLICAS"
+    echo "finished"
+}`), 0o644))
+		cmd := exec.Command(opalBin, "--plan", planFile, "-f", testFile, "--drift-report", reportFile)
+		cmd.Env = append(os.Environ(), "OPAL_TEST_REPLICAS=three")
+		assert.Error(t, cmd.Run())
+		report := readReport(t)
+		assert.Equal(t, []formatter.DriftCause{formatter.DriftSourceChanged}, report.Causes)
+	})
+
+	t.Run("unreadable contract", func(t *testing.T) {
+		garbage := filepath.Join(dir, "garbage.plan")
+		require.NoError(t, os.WriteFile(garbage, []byte("not a contract"), 0o644))
+		for _, args := range [][]string{
+			{"--plan", garbage, "-f", testFile, "--drift-report", reportFile},
+			{"verify", garbage, "-f", testFile, "--drift-report", reportFile},
+		} {
+			require.NoError(t, os.WriteFile(reportFile, []byte(`{"target": "deploy", "verified": true}`), 0o644))
+			assert.Error(t, exec.Command(opalBin, args...).Run())
+			report := readReport(t)
+			assert.False(t, report.Verified, "stale report left by %v", args)
+			assert.NotEmpty(t, report.Error)
+			assert.Empty(t, report.Drifts)
+		}
+	})
+}
+
+// TestDryRunStructuredFormats verifies --format=json/yaml documents read
+// back to a plan with the hash they declare
+func TestDryRunStructuredFormats(t *testing.T) {
+	opalBin := buildOpalBinary(t)
+	defer os.Remove(opalBin)
+
+	testFile := createTestFile(t, `var REPLICAS = "three"
+fun deploy {
+    echo "replicas=@var.REPLICAS" > out.txt && echo done | cat
+    @retry(times=2) { echo "rollout" }
+}`)
+	defer os.Remove(testFile)
+
+	readers := map[string]func(*bytes.Reader) (*planfmt.Plan, error){
+		"json": func(r *bytes.Reader) (*planfmt.Plan, error) { return planfmt.ReadJSON(r) },
+		"yaml": func(r *bytes.Reader) (*planfmt.Plan, error) { return planfmt.ReadYAML(r) },
+	}
+	for format, read := range readers {
+		for _, resolve := range []bool{false, true} {
+			args := []string{"-f", testFile, "deploy", "--dry-run", "--format=" + format}
+			if resolve {
+				args = append(args, "--resolve")
+			}
+			output, err := exec.Command(opalBin, args...).Output()
+			require.NoError(t, err, "opal %v", args)
+			assert.NotContains(t, string(output), "three", "values must not appear in exported plans")
+
+			plan, err := read(bytes.NewReader(output))
+			require.NoError(t, err, "opal %v", args)
+			assert.Equal(t, "deploy", plan.Target)
+			require.Len(t, plan.Steps, 2)
+			_, ok := plan.Steps[0].Tree.(*planfmt.AndNode)
+			assert.True(t, ok, "step 1 keeps its operator tree")
+		}
+	}
+}
+
+// TestRepoCommandsFilePlans keeps the repository's own commands.opl plannable
+func TestRepoCommandsFilePlans(t *testing.T) {
+	opalBin := buildOpalBinary(t)
+	defer os.Remove(opalBin)
+
+	commandsFile := filepath.Join("..", "commands.opl")
+	for _, fn := range []string{"test", "lint", "format", "clean", "build", "ci", "info"} {
+		output := runOpal(t, opalBin, "-f", commandsFile, fn, "--dry-run", "--no-color")
+		assert.Contains(t, output, fn+":")
+	}
+}
+
 // TestPlanSaltDeterminism verifies that Mode 3 uses PlanSalt for deterministic DisplayIDs
 // and Mode 4 reuses PlanSalt from contract for verification
 func TestPlanSaltDeterminism(t *testing.T) {
@@ -319,6 +500,7 @@ func buildOpalBinary(t *testing.T) string {
 
 	tmpDir := t.TempDir()
 	opalBin := filepath.Join(tmpDir, "opal")
+	t.Setenv("OPAL_RUNS_DIR", filepath.Join(tmpDir, "runs")) // Keep run history out of HOME
 
 	// Build from current directory (cli package)
 	cmd := exec.Command("go", "build", "-o", opalBin, ".")
@@ -504,3 +686,43 @@ func TestPlanSaltValidationErrors(t *testing.T) {
 		}
 	})
 }
+
+// TestCommandArguments verifies command parameters bind from positional
+// arguments and --arg flags, and are type-checked before execution.
+func TestCommandArguments(t *testing.T) {
+	opalBin := buildOpalBinary(t)
+
+	testFile := createTestFile(t, `
+fun deploy(env: String, replicas: Int = 3) {
+    echo "deploying @var.env"
+    echo "done"
+}
+`)
+
+	t.Run("Positional", func(t *testing.T) {
+		output := runOpal(t, opalBin, "-f", testFile, "deploy", "production")
+		assert.Contains(t, output, "deploying opal:")
+		assert.NotContains(t, output, "production", "parameter values are scrubbed from output")
+		assert.Contains(t, output, "done")
+	})
+
+	t.Run("NamedFlag", func(t *testing.T) {
+		output := runOpal(t, opalBin, "-f", testFile, "deploy", "--arg", "env=production", "--arg", "replicas=5")
+		assert.Contains(t, output, "done")
+	})
+
+	t.Run("TypeMismatch", func(t *testing.T) {
+		cmd := exec.Command(opalBin, "-f", testFile, "deploy", "--arg", "env=production", "--arg", "replicas=five", "--no-color")
+		output, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(output), "parameter 'replicas' of function deploy must be Int")
+		assert.NotContains(t, string(output), "done")
+	})
+
+	t.Run("MalformedFlag", func(t *testing.T) {
+		cmd := exec.Command(opalBin, "-f", testFile, "deploy", "--arg", "env", "--no-color")
+		output, err := cmd.CombinedOutput()
+		require.Error(t, err)
+		assert.Contains(t, string(output), `invalid --arg "env"`)
+	})
+}
@@ -19,10 +19,14 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/opal-lang/opal/core/decorator"
 	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/core/planfmt/formatter"
 	"github.com/opal-lang/opal/core/sdk/secret"
 	_ "github.com/opal-lang/opal/runtime/decorators" // Register built-in decorators
 	"github.com/opal-lang/opal/runtime/executor"
@@ -47,10 +51,44 @@ func main() {
 		debug    bool
 		noColor  bool
 		timing   bool
+		pipefail bool
+		argFlags []string
+
+		signKeyFiles     []string
+		trustedKeysFile  string
+		requireSigned    int
+		compressContract bool
+		driftReportFile  string
+		planFormat       string
+		scrubMode        string
+		scrubLogFile     string
+		runsDir          string
+		runsKeep         int
+		runsMaxAge       time.Duration
+		otelEndpoint     string
+		otelFile         string
 	)
 
+	// flagOptions gathers the parsed flags for a run or a verify
+	flagOptions := func(signing contractSigning) runOptions {
+		return runOptions{
+			file:            file,
+			planFile:        planFile,
+			format:          planFormat,
+			dryRun:          dryRun,
+			resolve:         resolve,
+			debug:           debug,
+			noColor:         noColor,
+			timing:          timing,
+			pipefail:        pipefail,
+			driftReportFile: driftReportFile,
+			signing:         signing,
+			retention:       runRetention{keep: runsKeep, maxAge: runsMaxAge},
+		}
+	}
+
 	rootCmd := &cobra.Command{
-		Use:   "opal [command]",
+		Use:   "opal [command] [args...]",
 		Short: "Plan-first execution platform for deployments and operations",
 		Long: `Opal converts operational workflows into verifiable execution contracts.
 
@@ -61,8 +99,8 @@ Scripts are planned before execution, generating a deterministic contract that c
 
 All secrets are automatically scrubbed from output, replaced with content-addressed
 DisplayID placeholders for security.`,
-		Args:          cobra.MaximumNArgs(1), // 0 args if --plan, 1 arg otherwise
-		SilenceErrors: true,                  // We handle error printing ourselves
+		Args:          cobra.ArbitraryArgs, // 0 args if --plan, command name and its arguments otherwise
+		SilenceErrors: true,                // We handle error printing ourselves
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Create Opal-specific placeholder generator
 			opalGen, err := streamscrub.NewOpalPlaceholderGenerator()
@@ -70,37 +108,63 @@ DisplayID placeholders for security.`,
 				return fmt.Errorf("failed to create placeholder generator: %w", err)
 			}
 
+			var positional []string
+			if len(args) > 1 {
+				positional = args[1:]
+			}
+			targetArgs, err := parseTargetArgs(positional, argFlags)
+			if err != nil {
+				return err
+			}
+
+			signing, err := newContractSigning(signKeyFiles, trustedKeysFile, requireSigned)
+			if err != nil {
+				return err
+			}
+			signing.compress = compressContract
+			opts := flagOptions(signing)
+			if err := validateRunOptions(opts); err != nil {
+				return err
+			}
+			scrub, err := newOutputScrubbing(scrubMode, scrubLogFile, dryRun)
+			if err != nil {
+				return err
+			}
+			tracing, err := newRunTracing(otelEndpoint, otelFile)
+			if err != nil {
+				return err
+			}
+
 			// Mode 4: Execute from plan file (contract verification)
 			if planFile != "" {
 				if len(args) > 0 {
 					return fmt.Errorf("cannot specify command name with --plan flag")
 				}
 
-				// Load contract to get PlanSalt
-				f, err := os.Open(planFile)
-				if err != nil {
-					return fmt.Errorf("failed to open plan file: %w", err)
-				}
-				_, _, contractPlan, err := planfmt.ReadContract(f)
-				_ = f.Close()
-				if err != nil {
-					return fmt.Errorf("failed to read contract: %w", err)
-				}
-
 				// Create vault with contract's PlanSalt for deterministic DisplayIDs
 				// CRITICAL: Reusing PlanSalt ensures same DisplayIDs during verification
-				vlt := vault.NewWithPlanKey(contractPlan.PlanSalt)
+				vlt, err := contractVault(planFile)
+				if err != nil {
+					return failDriftReport(driftReportFile, nil, err)
+				}
+				defer vlt.Close() // Wipe secrets after the scrubber is done (defers run in reverse)
 
-				// Create scrubber with vault's secret provider
-				scrubber := streamscrub.New(&outputBuf,
-					streamscrub.WithPlaceholderFunc(opalGen.PlaceholderFunc()),
-					streamscrub.WithSecretProvider(vlt.SecretProvider()))
+				// Create scrubber with vault's secret provider; scrubbed output
+				// is also recorded in the run history
+				runs := newRunRecorder(runsDir, opts.retention, &outputBuf)
+				scrubber := scrub.newScrubber(runs, opalGen.PlaceholderFunc(), vlt.SecretProvider())
 
 				// Redirect stdout/stderr through scrubber
 				restore := scrubber.LockdownStreams()
 				defer restore()
 
-				exitCode, err := runFromPlan(planFile, file, debug, noColor, vlt, scrubber, &outputBuf)
+				tracing.start(vlt)
+				exitCode, err := runFromPlan(opts, targetArgs, vlt, scrubber, scrub, runs, tracing, &outputBuf)
+				restore() // Drain opal's own output too: strict scrubbing may reject it
+				if leak := scrub.err(); leak != nil && err == nil {
+					exitCode, err = 1, leak
+				}
+				tracing.finish(exitCode)
 				if err != nil {
 					cmd.SilenceUsage = true // We've already printed detailed error
 					return err
@@ -119,25 +183,34 @@ DisplayID placeholders for security.`,
 				return fmt.Errorf("failed to generate plan key: %w", err)
 			}
 			vlt := vault.NewWithPlanKey(planKey)
+			defer vlt.Close() // Wipe secrets after the scrubber is done (defers run in reverse)
 
-			// Create scrubber with vault's secret provider
-			scrubber := streamscrub.New(&outputBuf,
-				streamscrub.WithPlaceholderFunc(opalGen.PlaceholderFunc()),
-				streamscrub.WithSecretProvider(vlt.SecretProvider()))
+			// Create scrubber with vault's secret provider; scrubbed output
+			// is also recorded in the run history
+			runs := newRunRecorder(runsDir, opts.retention, &outputBuf)
+			scrubber := scrub.newScrubber(runs, opalGen.PlaceholderFunc(), vlt.SecretProvider())
 
 			// Redirect stdout/stderr through scrubber
 			restore := scrubber.LockdownStreams()
 			defer restore()
 
 			// 0 args = script mode (execute all top-level commands)
-			// 1 arg = command mode (execute specific function)
+			// 1+ args = command mode (execute specific function with its arguments)
 			var commandName string
-			if len(args) == 1 {
+			if len(args) >= 1 {
 				commandName = args[0]
+			} else if len(targetArgs.named) > 0 {
+				return fmt.Errorf("--arg requires a command name")
 			}
 			// else: commandName = "" (script mode)
 
-			exitCode, err := runCommand(cmd, commandName, file, dryRun, resolve, debug, noColor, timing, vlt, scrubber, &outputBuf)
+			tracing.start(vlt)
+			exitCode, err := runCommand(opts, commandName, targetArgs, vlt, scrubber, scrub, runs, tracing, &outputBuf)
+			restore() // Drain opal's own output too: strict scrubbing may reject it
+			if leak := scrub.err(); leak != nil && err == nil {
+				exitCode, err = 1, leak
+			}
+			tracing.finish(exitCode)
 			if err != nil {
 				cmd.SilenceUsage = true // We've already printed detailed error
 				return err
@@ -155,9 +228,123 @@ DisplayID placeholders for security.`,
 	rootCmd.PersistentFlags().StringVar(&planFile, "plan", "", "Execute from pre-generated plan file (Mode 4)")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show execution plan without running commands")
 	rootCmd.PersistentFlags().BoolVar(&resolve, "resolve", false, "Resolve all values in plan (use with --dry-run)")
+	rootCmd.PersistentFlags().StringVar(&planFormat, "format", "text", "Dry-run output format: text, json or yaml")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug output")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output")
 	rootCmd.PersistentFlags().BoolVar(&timing, "timing", false, "Show pipeline timing breakdown")
+	rootCmd.PersistentFlags().BoolVar(&pipefail, "pipefail", false, "Fail a pipeline when any command in it fails, not only the last")
+	rootCmd.PersistentFlags().StringArrayVar(&argFlags, "arg", nil, "Set a command parameter as name=value (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&signKeyFiles, "sign-key", nil, "Sign the contract with an Ed25519 private key (with --dry-run --resolve, or --plan to co-sign; repeatable)")
+	rootCmd.PersistentFlags().StringVar(&trustedKeysFile, "trusted-keys", "", "File of approvers' public keys (authorized_keys format) for --require-signed")
+	rootCmd.PersistentFlags().BoolVar(&compressContract, "compress", false, "Compress the written contract body (the contract hash is unchanged)")
+	rootCmd.PersistentFlags().StringVar(&driftReportFile, "drift-report", "", "Write the --plan verification result and drift causes to this file as JSON")
+	rootCmd.PersistentFlags().StringVar(&scrubMode, "scrub", scrubRedact, "Secret scrubbing mode: redact, strict (fail the run if a secret reaches output) or audit")
+	rootCmd.PersistentFlags().StringVar(&scrubLogFile, "scrub-log", "", "Write every redaction (step, DisplayID, output offset) to this file as JSON (with --scrub=audit)")
+	rootCmd.PersistentFlags().StringVar(&runsDir, "runs-dir", defaultRunsDir(), "Run history directory (set the default with $OPAL_RUNS_DIR; empty records no history)")
+	rootCmd.PersistentFlags().IntVar(&runsKeep, "runs-keep", defaultRunsKeep(), "Keep the newest N runs in the run history, 0 for all (set the default with $OPAL_RUNS_KEEP)")
+	rootCmd.PersistentFlags().DurationVar(&runsMaxAge, "runs-max-age", defaultRunsMaxAge(), "Remove runs older than this from the run history, 0 to keep them (set the default with $OPAL_RUNS_MAX_AGE)")
+	rootCmd.PersistentFlags().StringVar(&otelEndpoint, "otel-endpoint", defaultOTLPEndpoint(), "Export the run's trace to this OTLP/HTTP collector (set the default with $OTEL_EXPORTER_OTLP_ENDPOINT)")
+	rootCmd.PersistentFlags().StringVar(&otelFile, "otel-file", "", "Append the run's trace to this file as OTLP/JSON lines")
+	rootCmd.PersistentFlags().IntVar(&requireSigned, "require-signed", 0, "Only run a --plan contract signed by this many trusted keys (default 1 with --trusted-keys)")
+
+	// Contract subcommands. A function of the same name in the command file
+	// takes precedence; keep the set small: no generated completion command.
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(yieldToFunction(rootCmd, &file, &cobra.Command{
+		Use:   "inspect <contract>",
+		Short: "Show a contract's header, steps and secret use sites without its source",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runInspect(os.Stdout, args[0], !noColor)
+		},
+	}), yieldToFunction(rootCmd, &file, &cobra.Command{
+		Use:   "verify <contract> [args...]",
+		Short: "Replan the source and check it still matches a contract, without executing",
+		Long: `Replans the command definitions file (-f) with the contract's plan salt and
+compares hashes. Nothing is executed.
+
+Exit codes: 0 if the contract matches, 3 if the plan has drifted, 1 on
+other errors (unreadable contract, syntax errors, missing approvals).`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			targetArgs, err := parseTargetArgs(args[1:], argFlags)
+			if err != nil {
+				return err
+			}
+			signing, err := newContractSigning(signKeyFiles, trustedKeysFile, requireSigned)
+			if err != nil {
+				return err
+			}
+			if len(signing.keys) > 0 {
+				return &CLIError{
+					Type:    "usage",
+					Message: "--sign-key only applies when writing a contract",
+					Hint:    fmt.Sprintf("Co-sign with: opal --plan %s --sign-key <key> > signed.plan", args[0]),
+				}
+			}
+			if pipefail {
+				return pipefailInContractError(args[0])
+			}
+
+			vlt, err := contractVault(args[0])
+			if err != nil {
+				return failDriftReport(driftReportFile, nil, err)
+			}
+			defer vlt.Close()
+			opalGen, err := streamscrub.NewOpalPlaceholderGenerator()
+			if err != nil {
+				return fmt.Errorf("failed to create placeholder generator: %w", err)
+			}
+			scrubber := streamscrub.New(&outputBuf,
+				streamscrub.WithPlaceholderFunc(opalGen.PlaceholderFunc()),
+				streamscrub.WithSecretProvider(vlt.SecretProvider()))
+			restore := scrubber.LockdownStreams()
+			defer restore()
+
+			opts := flagOptions(signing)
+			opts.planFile = args[0]
+			return runVerify(os.Stdout, opts, targetArgs, vlt)
+		},
+	}))
+
+	// Run history subcommands
+	runsCmd := &cobra.Command{
+		Use:   "runs",
+		Short: "List, show and compare recorded runs (see --runs-dir)",
+	}
+	var listTarget string
+	var listLimit int
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List recorded runs, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runsList(os.Stdout, runsDir, listTarget, listLimit, !noColor)
+		},
+	}
+	listCmd.Flags().StringVar(&listTarget, "target", "", "Only list runs of this target")
+	listCmd.Flags().IntVar(&listLimit, "limit", 20, "List at most this many runs (0 for all)")
+	runsCmd.AddCommand(listCmd, &cobra.Command{
+		Use:   "show <run-id>",
+		Short: "Show a run's outcome, steps and the output of the step that failed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runsShow(os.Stdout, runsDir, args[0], !noColor)
+		},
+	}, &cobra.Command{
+		Use:   "diff <run-a> <run-b>",
+		Short: "Compare two runs: outcome, plan changes and step results",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runsDiff(os.Stdout, runsDir, args[0], args[1], !noColor)
+		},
+	})
+	rootCmd.AddCommand(yieldToFunction(rootCmd, &file, runsCmd))
 
 	// Execute command and capture exit code
 	exitCode := 0
@@ -165,7 +352,7 @@ DisplayID placeholders for security.`,
 		// Error messages go through scrubber
 		// Use FormatError for consistent, colored error output
 		FormatError(os.Stderr, err, !noColor)
-		exitCode = 1
+		exitCode = exitCodeFor(err)
 	}
 
 	// Write captured (and scrubbed) output to real stdout
@@ -186,20 +373,113 @@ func newCancellableContext() (context.Context, context.CancelFunc) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Cancel context when signal received
+	// Cancel context when signal received. finally blocks still run after
+	// cancellation, so restore default handling: a second signal kills opal.
 	go func() {
 		<-sigChan
 		cancel()
+		signal.Stop(sigChan)
 	}()
 
 	return ctx, cancel
 }
 
-func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, debug, noColor, timing bool, vlt *vault.Vault, scrubber *streamscrub.Scrubber, outputBuf *bytes.Buffer) (int, error) {
+// targetArgs are the command-line arguments for the command's parameters.
+type targetArgs struct {
+	positional []string          // Bound to parameters in declaration order
+	named      map[string]string // From --arg name=value
+}
+
+// parseTargetArgs builds targetArgs from positional arguments and --arg flags.
+func parseTargetArgs(positional, argFlags []string) (targetArgs, error) {
+	args := targetArgs{positional: positional}
+	for _, flag := range argFlags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok || name == "" {
+			return targetArgs{}, &CLIError{
+				Type:    "usage",
+				Message: fmt.Sprintf("invalid --arg %q", flag),
+				Hint:    "Use --arg name=value, e.g. --arg module=cli",
+			}
+		}
+		if _, dup := args.named[name]; dup {
+			return targetArgs{}, &CLIError{
+				Type:    "usage",
+				Message: fmt.Sprintf("duplicate --arg for parameter %q", name),
+			}
+		}
+		if args.named == nil {
+			args.named = make(map[string]string)
+		}
+		args.named[name] = value
+	}
+	return args, nil
+}
+
+// runOptions are the command-line settings of a run, filled once from the
+// flags. The zero value runs the default file without extra output.
+type runOptions struct {
+	file            string // Command definitions file (-f)
+	planFile        string // Contract to verify and execute (--plan)
+	format          string // Dry-run output format: text (default), json or yaml
+	dryRun          bool
+	resolve         bool
+	debug           bool
+	noColor         bool
+	timing          bool
+	pipefail        bool
+	driftReportFile string
+	signing         contractSigning
+	retention       runRetention // Run history limits (--runs-keep, --runs-max-age)
+}
+
+// validateRunOptions rejects flag combinations that don't apply to the run
+// they describe, before anything is planned or executed.
+func validateRunOptions(opts runOptions) error {
+	if len(opts.signing.keys) > 0 && opts.planFile == "" && !(opts.dryRun && opts.resolve) {
+		return &CLIError{
+			Type:    "usage",
+			Message: "--sign-key only applies when writing a contract",
+			Hint:    "Sign a new contract with --dry-run --resolve, or co-sign one with --plan <file>",
+		}
+	}
+	if opts.driftReportFile != "" && opts.planFile == "" {
+		return &CLIError{
+			Type:    "usage",
+			Message: "--drift-report only applies when verifying a contract",
+			Hint:    "Use it with --plan <file>",
+		}
+	}
+	if opts.pipefail && opts.planFile != "" {
+		return pipefailInContractError(opts.planFile)
+	}
+	if err := checkPlanFormat(opts.format, opts.dryRun, opts.signing); err != nil {
+		return err
+	}
+	writesContract := opts.format == "text" && opts.dryRun && opts.resolve
+	coSigns := opts.planFile != "" && len(opts.signing.keys) > 0
+	if opts.signing.compress && !writesContract && !coSigns {
+		return &CLIError{
+			Type:    "usage",
+			Message: "--compress only applies when writing a contract",
+			Hint:    "Use it with --dry-run --resolve, or with --plan <file> --sign-key <key>",
+		}
+	}
+	if opts.retention.keep < 0 || opts.retention.maxAge < 0 {
+		return &CLIError{
+			Type:    "usage",
+			Message: "--runs-keep and --runs-max-age can't be negative",
+			Hint:    "Use 0 to keep every run",
+		}
+	}
+	return nil
+}
+
+func runCommand(opts runOptions, commandName string, args targetArgs, vlt *vault.Vault, scrubber *streamscrub.Scrubber, scrub *outputScrubbing, runs *runRecorder, trace *runTracing, outputBuf *bytes.Buffer) (int, error) {
 	// commandName is empty string for script mode, function name for command mode
 
 	// Get input reader based on file options
-	reader, closeFunc, err := getInputReader(file)
+	reader, closeFunc, err := getInputReader(opts.file)
 	if err != nil {
 		return 1, err
 	}
@@ -219,7 +499,7 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 			Type:    "usage",
 			Message: fmt.Sprintf("Cannot execute function %q in shebang script", commandName),
 			Details: "Script files with shebang (#!/usr/bin/env opal) are executable scripts, not command libraries.\nThey run in script mode only.",
-			Hint:    fmt.Sprintf("Remove the shebang line to use this file as a command library\nOr run in script mode: opal -f %s", file),
+			Hint:    fmt.Sprintf("Remove the shebang line to use this file as a command library\nOr run in script mode: opal -f %s", opts.file),
 		}
 		return 1, err
 	}
@@ -228,20 +508,25 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 	// For now, strip shebang line if present to allow executable scripts
 	source = stripShebang(source)
 
+	if commandName != "" {
+		trace.setAttr("opal.target", commandName)
+	}
+	trace.setAttr("opal.source", opts.file)
+
 	// Lex
+	lexSpan := trace.phase("lex")
 	l := lexer.NewLexer()
 	l.Init(source)
 	tokens := l.GetTokens()
+	lexSpan.SetAttr("opal.tokens", len(tokens))
+	lexSpan.End()
 
 	// Parse with telemetry if timing enabled
 	var tree *parser.ParseTree
-	var pipelineTiming struct {
-		ParseTime   time.Duration
-		PlanTime    time.Duration
-		ExecuteTime time.Duration
-	}
+	var pipelineTiming pipelineTiming
 
-	if timing {
+	parseSpan := trace.phase("parse")
+	if opts.timing {
 		tree = parser.Parse(source, parser.WithTelemetryTiming())
 		if tree.Telemetry != nil {
 			pipelineTiming.ParseTime = tree.Telemetry.TotalTime
@@ -249,13 +534,15 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 	} else {
 		tree = parser.Parse(source)
 	}
+	parseSpan.SetAttr("opal.errors", len(tree.Errors))
+	parseSpan.End()
 	if len(tree.Errors) > 0 {
 		// Use parser's error formatter for nice output
 		formatter := &parser.ErrorFormatter{
 			Source:   source,
-			Filename: file,
+			Filename: opts.file,
 			Compact:  false, // Use detailed format
-			Color:    !noColor,
+			Color:    !opts.noColor,
 		}
 		for _, parseErr := range tree.Errors {
 			fmt.Fprint(os.Stderr, formatter.Format(parseErr))
@@ -270,7 +557,7 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 
 	// Plan
 	debugLevel := planner.DebugOff
-	if debug {
+	if opts.debug {
 		debugLevel = planner.DebugDetailed
 	}
 
@@ -280,7 +567,7 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 	// - Mode 3 (contract generation): no IDFactory needed (PlanSalt stored in contract)
 	// - Mode 4 (contract execution): use ModePlan with contract's PlanSalt
 	var idFactory secret.IDFactory
-	if !dryRun && !resolve {
+	if !opts.dryRun && !opts.resolve {
 		// Mode 1: Direct execution - use random IDs for security
 		var err error
 		idFactory, err = planfmt.NewRunIDFactory()
@@ -290,16 +577,27 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 	}
 	// Modes 2 & 3: leave idFactory as nil (PlanSalt is in the plan, will be stored in contract)
 
+	// Transports (@ssh.connect) connect while planning; the executor
+	// reuses those sessions
+	sessions := decorator.NewSessionPool()
+	defer sessions.CloseAll()
+
 	// Plan with telemetry if timing enabled
+	planSpan := trace.phase("plan")
 	var plan *planfmt.Plan
-	if timing {
+	if opts.timing {
 		planResult, err := planner.PlanWithObservability(tree.Events, tokens, planner.Config{
 			Target:    commandName,
+			Args:      args.positional,
+			NamedArgs: args.named,
 			IDFactory: idFactory,
 			Vault:     vlt, // Share vault with scrubber for variable scrubbing
+			Sessions:  sessions,
+			Pipefail:  opts.pipefail,
 			Debug:     debugLevel,
 			Telemetry: planner.TelemetryTiming,
 		})
+		planSpan.End()
 		if err != nil {
 			return 1, fmt.Errorf("planning failed: %w", err)
 		}
@@ -309,18 +607,31 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 		var err error
 		plan, err = planner.Plan(tree.Events, tokens, planner.Config{
 			Target:    commandName,
+			Args:      args.positional,
+			NamedArgs: args.named,
 			IDFactory: idFactory,
 			Vault:     vlt, // Share vault with scrubber for variable scrubbing
+			Sessions:  sessions,
+			Pipefail:  opts.pipefail,
 			Debug:     debugLevel,
 		})
+		planSpan.End()
 		if err != nil {
 			return 1, fmt.Errorf("planning failed: %w", err)
 		}
 	}
+	trace.setAttr("opal.step_count", len(plan.Steps))
 
 	// Dry-run mode: show plan or generate contract
-	if dryRun {
-		if resolve {
+	if opts.dryRun {
+		if opts.format != "" && opts.format != "text" {
+			// Structured export (JSON/YAML) of the quick or resolved plan
+			if err := writePlanDocument(os.Stdout, plan, opts.format); err != nil {
+				return 1, fmt.Errorf("failed to write %s plan: %w", opts.format, err)
+			}
+			return 0, nil
+		}
+		if opts.resolve {
 			// Mode 3: Resolved Plan (Contract Generation)
 			// Generate plan hash and write minimal contract file
 			// Note: In MVP, we don't actually resolve values yet (no value decorators)
@@ -333,10 +644,10 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 				return 1, fmt.Errorf("failed to compute plan hash: %w", err)
 			}
 
-			// Write contract to stdout (target + hash + full plan)
+			// Write contract to stdout (target + hash + full plan + signatures)
 			// Note: Don't write messages to stderr here - they go through lockdown
 			// and end up in the output buffer along with the contract
-			if err := planfmt.WriteContract(os.Stdout, commandName, planHash, plan); err != nil {
+			if err := planfmt.WriteContract(os.Stdout, commandName, planHash, plan, opts.signing.writeOptions()...); err != nil {
 				return 1, fmt.Errorf("failed to write contract: %w", err)
 			}
 
@@ -345,14 +656,14 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 		} else {
 			// Mode 2: Quick Plan (Dry-Run)
 			// Display plan as tree
-			DisplayPlan(os.Stdout, plan, !noColor)
+			DisplayPlan(os.Stdout, plan, !opts.noColor)
 		}
 		return 0, nil
 	}
 
 	// Execute (lockdown already active from main())
 	execDebug := executor.DebugOff
-	if debug {
+	if opts.debug {
 		execDebug = executor.DebugDetailed
 	}
 
@@ -360,9 +671,10 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 	// The executor only sees SDK types - it has no knowledge of planfmt
 	steps := planfmt.ToSDKSteps(plan.Steps)
 
-	// Execute with telemetry level based on timing flag
+	// Execute with telemetry level based on timing flag (--debug shows
+	// per-step PIPESTATUS from step timings too)
 	telemetryLevel := executor.TelemetryBasic
-	if timing {
+	if opts.timing || opts.debug {
 		telemetryLevel = executor.TelemetryTiming
 	}
 
@@ -370,27 +682,52 @@ func runCommand(cmd *cobra.Command, commandName, file string, dryRun, resolve, d
 	ctx, cancel := newCancellableContext()
 	defer cancel()
 
-	result, err := executor.Execute(ctx, steps, executor.Config{
+	config := executor.Config{
 		Debug:     execDebug,
 		Telemetry: telemetryLevel,
-	}, vlt)
+		Color:     !opts.noColor,
+		Sessions:  sessions,
+		Pipefail:  plan.Pipefail,
+	}
+	if runs.enabled() || trace.enabled() {
+		planHash, err := planfmt.Write(io.Discard, plan)
+		if err != nil {
+			return 1, fmt.Errorf("failed to compute plan hash: %w", err)
+		}
+		trace.setAttr("opal.plan_hash", fmt.Sprintf("%x", planHash))
+		startRun(runs, commandName, opts.file, "", plan, planHash)
+		if id := runs.id(); id != "" {
+			trace.setAttr("opal.run_id", id)
+		}
+	}
+	scrub.attach(&config, scrubber, cancel)
+	runs.attach(&config)
+	trace.attach(&config)
+	result, err := executor.Execute(ctx, steps, config, vlt)
 	if err != nil {
+		finishRun(runs, nil, err)
 		return 1, fmt.Errorf("execution failed: %w", err)
 	}
+	scrubErr := scrub.finish()
+	finishRun(runs, result, scrubErr)
+	if scrubErr != nil {
+		return 1, scrubErr
+	}
 
 	pipelineTiming.ExecuteTime = result.Duration
 
 	// Print timing breakdown if timing flag enabled
-	if timing {
+	if opts.timing {
 		displayPipelineTiming(pipelineTiming, result)
 	}
 
 	// Print execution summary if debug enabled
-	if debug {
-		fmt.Fprintf(os.Stderr, "\nExecution summary:\n")
-		fmt.Fprintf(os.Stderr, "  Steps run: %d/%d\n", result.StepsRun, len(steps))
-		fmt.Fprintf(os.Stderr, "  Duration: %v\n", result.Duration)
-		fmt.Fprintf(os.Stderr, "  Exit code: %d\n", result.ExitCode)
+	if opts.debug {
+		displayExecutionSummary(result, len(steps))
+	}
+
+	if result.Timeout != nil {
+		return result.ExitCode, newTimeoutError(result)
 	}
 
 	// Return exit code to main (don't call os.Exit - skips defers!)
@@ -439,56 +776,213 @@ func hasPipedInput() bool {
 }
 
 // runFromPlan executes with contract verification (Mode 4: Contract Execution)
-// Flow: Load contract → Replan fresh → Compare hashes → Execute if match
-func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Vault, scrubber *streamscrub.Scrubber, outputBuf *bytes.Buffer) (int, error) {
+// Flow: Load contract → Check signatures → Replan fresh → Compare hashes → Execute if match
+//
+// With signing keys, the verified contract is co-signed and written to
+// stdout instead of executed, so each approver signs what they checked.
+// With a drift report file, the verification result is also written there
+// as JSON, whether or not the hashes match.
+func runFromPlan(opts runOptions, args targetArgs, vlt *vault.Vault, scrubber *streamscrub.Scrubber, scrub *outputScrubbing, runs *runRecorder, trace *runTracing, outputBuf *bytes.Buffer) (int, error) {
+	// Transports (@ssh.connect) connect while planning; the executor
+	// reuses those sessions
+	sessions := decorator.NewSessionPool()
+	defer sessions.CloseAll()
+
+	trace.setAttr("opal.contract", opts.planFile)
+	trace.setAttr("opal.source", opts.file)
+	contract, err := verifyContract(opts, args, vlt, sessions, trace)
+	if err != nil {
+		return 1, failDriftReport(opts.driftReportFile, vlt, err)
+	}
+	target, contractHash, contractPlan, freshPlan := contract.target, contract.hash, contract.plan, contract.freshPlan
+	trace.setAttr("opal.target", target)
+	trace.setAttr("opal.plan_hash", fmt.Sprintf("%x", contractHash))
+	trace.setAttr("opal.step_count", len(freshPlan.Steps))
+
+	report := formatter.NewDriftReport(contractPlan, freshPlan, contractHash, contract.freshHash)
+	if opts.driftReportFile != "" {
+		if err := writeDriftReport(opts.driftReportFile, report); err != nil {
+			return 1, err
+		}
+	}
+
+	if !report.Verified {
+		// Use error formatter for consistent output
+		FormatContractVerificationError(os.Stderr, contractPlan, freshPlan, report.Drifts, !opts.noColor)
+
+		// Show hashes for debugging
+		if opts.debug {
+			fmt.Fprintf(os.Stderr, "\n%s\n", Colorize("Debug info:", ColorCyan, !opts.noColor))
+			fmt.Fprintf(os.Stderr, "  Contract hash: %x\n", contractHash)
+			fmt.Fprintf(os.Stderr, "  Fresh hash:    %x\n", contract.freshHash)
+		}
+
+		return 1, newContractDriftError(opts.planFile, report)
+	}
+
+	if opts.debug {
+		fmt.Fprintf(os.Stderr, "✓ Contract verified (hash matches)\n")
+		fmt.Fprintf(os.Stderr, "Steps: %d\n", len(freshPlan.Steps))
+		for _, note := range report.Notes {
+			fmt.Fprintf(os.Stderr, "Note: %s\n", note.Detail)
+		}
+	}
+
+	// Co-signing: add signatures to the verified contract instead of running it
+	if len(opts.signing.keys) > 0 {
+		if err := planfmt.WriteContract(os.Stdout, target, contractHash, contractPlan, opts.signing.writeOptions()...); err != nil {
+			return 1, fmt.Errorf("failed to write contract: %w", err)
+		}
+		return 0, nil
+	}
+
+	// Step 4: Execute the verified plan
+	execDebug := executor.DebugOff
+	if opts.debug {
+		execDebug = executor.DebugDetailed
+	}
+
+	// Convert plan to SDK steps at the boundary
+	steps := planfmt.ToSDKSteps(freshPlan.Steps)
+
+	// Create cancellable context for Ctrl+C handling
+	ctx, cancel := newCancellableContext()
+	defer cancel()
+
+	config := executor.Config{
+		Debug:     execDebug,
+		Telemetry: executor.TelemetryBasic,
+		Color:     !opts.noColor,
+		Sessions:  sessions,
+		Pipefail:  freshPlan.Pipefail,
+	}
+	if opts.timing || opts.debug {
+		config.Telemetry = executor.TelemetryTiming // Step timings and per-step PIPESTATUS
+	}
+	startRun(runs, target, opts.file, opts.planFile, freshPlan, contractHash)
+	if id := runs.id(); id != "" {
+		trace.setAttr("opal.run_id", id)
+	}
+	scrub.attach(&config, scrubber, cancel)
+	runs.attach(&config)
+	trace.attach(&config)
+	result, err := executor.Execute(ctx, steps, config, vlt)
+	if err != nil {
+		finishRun(runs, nil, err)
+		return 1, fmt.Errorf("execution failed: %w", err)
+	}
+	scrubErr := scrub.finish()
+	finishRun(runs, result, scrubErr)
+	if scrubErr != nil {
+		return 1, scrubErr
+	}
+
+	// Print timing breakdown if timing flag enabled
+	if opts.timing {
+		timing := contract.timing
+		timing.ExecuteTime = result.Duration
+		displayPipelineTiming(timing, result)
+	}
+
+	// Print execution summary if debug enabled
+	if opts.debug {
+		displayExecutionSummary(result, len(steps))
+	}
+
+	if result.Timeout != nil {
+		return result.ExitCode, newTimeoutError(result)
+	}
+
+	return result.ExitCode, nil
+}
+
+// verifiedContract is a contract and the plan freshly made from source to
+// compare it with.
+type verifiedContract struct {
+	target    string
+	hash      [32]byte      // Contract hash
+	plan      *planfmt.Plan // Contract plan
+	freshPlan *planfmt.Plan // Replanned from current source with the contract's PlanSalt
+	freshHash [32]byte
+	timing    pipelineTiming // Parse and plan times of the replan (with --timing)
+}
+
+// verifyContract loads a contract, checks its approvals and replans the
+// source with the contract's PlanSalt. Whether the hashes match is left to
+// the caller. Transport sessions opened while planning go into sessions.
+func verifyContract(opts runOptions, args targetArgs, vlt *vault.Vault, sessions *decorator.SessionPool, trace *runTracing) (*verifiedContract, error) {
 	// Step 1: Load contract from plan file
-	f, err := os.Open(planFile)
+	f, err := os.Open(opts.planFile)
 	if err != nil {
-		return 1, fmt.Errorf("failed to open plan file: %w", err)
+		return nil, fmt.Errorf("failed to open plan file: %w", err)
 	}
 	defer func() { _ = f.Close() }()
 
 	target, contractHash, contractPlan, err := planfmt.ReadContract(f)
 	if err != nil {
-		return 1, fmt.Errorf("failed to read contract: %w", err)
+		return nil, fmt.Errorf("failed to read contract: %w", err)
 	}
 
-	if debug {
-		fmt.Fprintf(os.Stderr, "Loaded contract from %s\n", planFile)
+	if opts.debug {
+		fmt.Fprintf(os.Stderr, "Loaded contract from %s\n", opts.planFile)
 		fmt.Fprintf(os.Stderr, "Contract hash: %x\n", contractHash)
 		fmt.Fprintf(os.Stderr, "Target: %s\n", target)
 		fmt.Fprintf(os.Stderr, "Contract plan steps: %d\n", len(contractPlan.Steps))
 	}
 
+	// Check approvals before touching the source: an unapproved contract
+	// never runs, whatever the source says
+	signers, err := opts.signing.verifySignatures(opts.planFile, contractHash, contractPlan)
+	if err != nil {
+		return nil, err
+	}
+	if opts.debug && len(signers) > 0 {
+		fmt.Fprintf(os.Stderr, "Approved by: %s\n", strings.Join(signers, ", "))
+	}
+
 	// Step 2: Replan from current source
-	reader, closeFunc, err := getInputReader(sourceFile)
+	reader, closeFunc, err := getInputReader(opts.file)
 	if err != nil {
-		return 1, err
+		return nil, err
 	}
 	defer func() { _ = closeFunc() }()
 
 	source, err := io.ReadAll(reader)
 	if err != nil {
-		return 1, fmt.Errorf("error reading source: %w", err)
+		return nil, fmt.Errorf("error reading source: %w", err)
 	}
 
 	// Strip shebang if present
 	source = stripShebang(source)
 
 	// Lex
+	lexSpan := trace.phase("lex")
 	l := lexer.NewLexer()
 	l.Init(source)
 	tokens := l.GetTokens()
+	lexSpan.SetAttr("opal.tokens", len(tokens))
+	lexSpan.End()
 
-	// Parse
-	tree := parser.Parse(source)
+	// Parse with telemetry if timing enabled
+	var timing pipelineTiming
+	var parseOpts []parser.ParserOpt
+	if opts.timing {
+		parseOpts = append(parseOpts, parser.WithTelemetryTiming())
+	}
+	parseSpan := trace.phase("parse")
+	tree := parser.Parse(source, parseOpts...)
+	if tree.Telemetry != nil {
+		timing.ParseTime = tree.Telemetry.TotalTime
+	}
+	parseSpan.SetAttr("opal.errors", len(tree.Errors))
+	parseSpan.End()
 	if len(tree.Errors) > 0 {
 		// Use parser's error formatter for nice output
 		formatter := &parser.ErrorFormatter{
 			Source:   source,
-			Filename: sourceFile,
+			Filename: opts.file,
 			Compact:  false, // Use detailed format
-			Color:    !noColor,
+			Color:    !opts.noColor,
 		}
 		for _, parseErr := range tree.Errors {
 			fmt.Fprint(os.Stderr, formatter.Format(parseErr))
@@ -496,13 +990,13 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 
 		errorCount := len(tree.Errors)
 		if errorCount == 1 {
-			return 1, fmt.Errorf(
+			return nil, fmt.Errorf(
 				"found 1 syntax error in source file (see details above)\n\n" +
 					"Cannot verify contract with syntax errors.\n" +
 					"Fix the syntax error and try again",
 			)
 		}
-		return 1, fmt.Errorf(
+		return nil, fmt.Errorf(
 			"found %d syntax errors in source file (see details above)\n\n"+
 				"Cannot verify contract with syntax errors.\n"+
 				"Fix the syntax errors and try again",
@@ -512,7 +1006,7 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 
 	// Plan (use same target as contract)
 	debugLevel := planner.DebugOff
-	if debug {
+	if opts.debug {
 		debugLevel = planner.DebugDetailed
 	}
 
@@ -522,7 +1016,7 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 	// Validate PlanSalt before using it (NewIDFactory panics if not 32 bytes)
 	if len(contractPlan.PlanSalt) != 32 {
 		if len(contractPlan.PlanSalt) == 0 {
-			return 1, fmt.Errorf(
+			return nil, fmt.Errorf(
 				"contract file '%s' is missing plan salt\n\n"+
 					"The contract file may be corrupted or manually edited.\n"+
 					"Plan salt is required for contract verification to ensure DisplayIDs remain consistent.\n\n"+
@@ -530,10 +1024,10 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 					"  1. Regenerate the contract: opal plan --mode=contract <file>\n"+
 					"  2. Or restore from backup if available\n"+
 					"  3. Or use --mode=plan to execute without contract verification",
-				planFile,
+				opts.planFile,
 			)
 		}
-		return 1, fmt.Errorf(
+		return nil, fmt.Errorf(
 			"contract file '%s' has corrupted plan salt\n\n"+
 				"Expected 32 bytes, but found %d bytes.\n"+
 				"The contract file may be corrupted or manually edited.\n\n"+
@@ -541,99 +1035,61 @@ func runFromPlan(planFile, sourceFile string, debug, noColor bool, vlt *vault.Va
 				"  1. Regenerate the contract: opal plan --mode=contract <file>\n"+
 				"  2. Or restore from backup if available\n"+
 				"  3. Or use --mode=plan to execute without contract verification",
-			planFile, len(contractPlan.PlanSalt),
+			opts.planFile, len(contractPlan.PlanSalt),
 		)
 	}
 
 	idFactory := secret.NewIDFactory(secret.ModePlan, contractPlan.PlanSalt)
 
-	freshPlan, err := planner.Plan(tree.Events, tokens, planner.Config{
+	planSpan := trace.phase("plan")
+	planResult, err := planner.PlanWithObservability(tree.Events, tokens, planner.Config{
 		Target:    target,
+		Args:      args.positional,
+		NamedArgs: args.named,
 		IDFactory: idFactory,
 		Vault:     vlt, // Share vault with scrubber for variable scrubbing
+		Sessions:  sessions,
+		Pipefail:  contractPlan.Pipefail, // A contract's settings come from the contract, not the flags
 		Debug:     debugLevel,
 	})
+	planSpan.End()
 	if err != nil {
-		return 1, fmt.Errorf("planning failed: %w", err)
+		return nil, fmt.Errorf("planning failed: %w", err)
 	}
+	freshPlan := planResult.Plan
+	timing.PlanTime = planResult.PlanTime
 
 	// CRITICAL: Copy PlanSalt from contract to fresh plan
 	// Without this, fresh plan gets random PlanSalt (from NewPlan) and hash will never match
 	// The IDFactory uses PlanSalt to generate DisplayIDs, but the plan itself needs the same salt
 	freshPlan.PlanSalt = contractPlan.PlanSalt
 
-	// Step 3: Compare hashes (contract verification)
+	// Step 3: Hash the fresh plan for the caller to compare
 	var freshHashBuf bytes.Buffer
 	freshHash, err := planfmt.Write(&freshHashBuf, freshPlan)
 	if err != nil {
-		return 1, fmt.Errorf("failed to hash fresh plan: %w", err)
-	}
-
-	if freshHash != contractHash {
-		// Use error formatter for consistent output
-		FormatContractVerificationError(os.Stderr, contractPlan, freshPlan, !noColor)
-
-		// Show hashes for debugging
-		if debug {
-			fmt.Fprintf(os.Stderr, "\n%s\n", Colorize("Debug info:", ColorCyan, !noColor))
-			fmt.Fprintf(os.Stderr, "  Contract hash: %x\n", contractHash)
-			fmt.Fprintf(os.Stderr, "  Fresh hash:    %x\n", freshHash)
-		}
-
-		return 1, fmt.Errorf(
-			"contract verification failed: source file has changed since contract was created\n\n"+
-				"The differences are shown above. To fix:\n"+
-				"  1. Review the changes to ensure they are intentional\n"+
-				"  2. Regenerate the contract: opal plan --mode=contract %s\n"+
-				"  3. Or use --mode=plan to execute without verification",
-			planFile,
-		)
-	}
-
-	if debug {
-		fmt.Fprintf(os.Stderr, "✓ Contract verified (hash matches)\n")
-		fmt.Fprintf(os.Stderr, "Steps: %d\n", len(freshPlan.Steps))
-	}
-
-	// Step 4: Execute the verified plan
-	execDebug := executor.DebugOff
-	if debug {
-		execDebug = executor.DebugDetailed
-	}
-
-	// Convert plan to SDK steps at the boundary
-	steps := planfmt.ToSDKSteps(freshPlan.Steps)
-
-	// Create cancellable context for Ctrl+C handling
-	ctx, cancel := newCancellableContext()
-	defer cancel()
-
-	result, err := executor.Execute(ctx, steps, executor.Config{
-		Debug:     execDebug,
-		Telemetry: executor.TelemetryBasic,
-	}, vlt)
-	if err != nil {
-		return 1, fmt.Errorf("execution failed: %w", err)
+		return nil, fmt.Errorf("failed to hash fresh plan: %w", err)
 	}
 
-	// Print execution summary if debug enabled
-	if debug {
-		fmt.Fprintf(os.Stderr, "\nExecution summary:\n")
-		fmt.Fprintf(os.Stderr, "  Steps run: %d/%d\n", result.StepsRun, len(steps))
-		fmt.Fprintf(os.Stderr, "  Duration: %v\n", result.Duration)
-		fmt.Fprintf(os.Stderr, "  Exit code: %d\n", result.ExitCode)
-	}
-
-	return result.ExitCode, nil
+	return &verifiedContract{
+		target:    target,
+		hash:      contractHash,
+		plan:      contractPlan,
+		freshPlan: freshPlan,
+		freshHash: freshHash,
+		timing:    timing,
+	}, nil
 }
 
-// displayPipelineTiming shows a breakdown of pipeline timing
-func displayPipelineTiming(timing struct {
+// pipelineTiming is the --timing breakdown of a run
+type pipelineTiming struct {
 	ParseTime   time.Duration
 	PlanTime    time.Duration
 	ExecuteTime time.Duration
-}, result *executor.ExecutionResult,
-) {
+}
+
+// displayPipelineTiming shows a breakdown of pipeline timing
+func displayPipelineTiming(timing pipelineTiming, result *executor.ExecutionResult) {
 	totalTime := timing.ParseTime + timing.PlanTime + timing.ExecuteTime
 
 	fmt.Fprintf(os.Stderr, "\nPipeline Timing:\n")
@@ -646,7 +1102,14 @@ func displayPipelineTiming(timing struct {
 		// Show per-step timing if available
 		if len(result.Telemetry.StepTimings) > 0 {
 			for _, st := range result.Telemetry.StepTimings {
-				fmt.Fprintf(os.Stderr, "    Step %d: %v (exit %d)\n", st.StepID, st.Duration, st.ExitCode)
+				if st.PipeStatus != nil {
+					fmt.Fprintf(os.Stderr, "    Step %d: %v (exit %d, pipestatus %s)\n", st.StepID, st.Duration, st.ExitCode, formatPipeStatus(st.PipeStatus))
+				} else {
+					fmt.Fprintf(os.Stderr, "    Step %d: %v (exit %d)\n", st.StepID, st.Duration, st.ExitCode)
+				}
+				for _, at := range st.Attempts {
+					fmt.Fprintf(os.Stderr, "      Attempt %d: %v (exit %d)\n", at.Attempt, at.Duration, at.ExitCode)
+				}
 			}
 		}
 	} else {
@@ -656,6 +1119,33 @@ func displayPipelineTiming(timing struct {
 	fmt.Fprintf(os.Stderr, "  Total:   %v\n", totalTime)
 }
 
+// displayExecutionSummary shows the --debug execution summary, with the
+// PIPESTATUS of each step that ran a pipeline
+func displayExecutionSummary(result *executor.ExecutionResult, stepCount int) {
+	fmt.Fprintf(os.Stderr, "\nExecution summary:\n")
+	fmt.Fprintf(os.Stderr, "  Steps run: %d/%d\n", result.StepsRun, stepCount)
+	fmt.Fprintf(os.Stderr, "  Duration: %v\n", result.Duration)
+	fmt.Fprintf(os.Stderr, "  Exit code: %d\n", result.ExitCode)
+	if result.Telemetry == nil {
+		return
+	}
+	for _, st := range result.Telemetry.StepTimings {
+		if st.PipeStatus != nil {
+			fmt.Fprintf(os.Stderr, "  Step %d PIPESTATUS: %s\n", st.StepID, formatPipeStatus(st.PipeStatus))
+		}
+	}
+}
+
+// formatPipeStatus formats pipeline exit codes like bash's
+// ${PIPESTATUS[@]}: "0 1 0"
+func formatPipeStatus(codes []int) string {
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = strconv.Itoa(code)
+	}
+	return strings.Join(parts, " ")
+}
+
 // stripShebang removes shebang line if present (#!/usr/bin/env opal)
 // TODO: Support shebang properly in parser by adding # as comment character
 func stripShebang(source []byte) []byte {
@@ -673,3 +1163,57 @@ func stripShebang(source []byte) []byte {
 	}
 	return source
 }
+
+// yieldToFunction lets a function in the command file take over the built-in
+// subcommand sub of the same name, so `opal verify` runs `fun verify` when the
+// file defines one. Flags are parsed before Args, so file is set by then.
+func yieldToFunction(root *cobra.Command, file *string, sub *cobra.Command) *cobra.Command {
+	validate, run := sub.Args, sub.RunE
+	sub.Args = func(cmd *cobra.Command, args []string) error {
+		if validate == nil || definesFunction(*file, cmd.Name()) {
+			return nil
+		}
+		return validate(cmd, args)
+	}
+	sub.RunE = func(cmd *cobra.Command, args []string) error {
+		if definesFunction(*file, cmd.Name()) {
+			return root.RunE(cmd, append([]string{cmd.Name()}, args...))
+		}
+		if run == nil {
+			return cmd.Help() // A group of subcommands, such as runs
+		}
+		return run(cmd, args)
+	}
+	return sub
+}
+
+// definesFunction reports whether the command file defines a top-level
+// function called name. Stdin is never read here (it is the source the run
+// itself needs), and shebang scripts define no callable functions.
+func definesFunction(file, name string) bool {
+	if file == "-" || (file == "commands.opl" && hasPipedInput()) {
+		return false
+	}
+	source, err := os.ReadFile(file)
+	if err != nil || bytes.HasPrefix(source, []byte("#!")) {
+		return false
+	}
+
+	tree := parser.Parse(source)
+	depth := 0
+	for i, evt := range tree.Events {
+		switch evt.Kind {
+		case parser.EventOpen:
+			// Event structure: OPEN Function, TOKEN(fun), TOKEN(name), ...
+			if depth == 1 && parser.NodeKind(evt.Data) == parser.NodeFunction &&
+				i+2 < len(tree.Events) && tree.Events[i+2].Kind == parser.EventToken &&
+				string(tree.Tokens[tree.Events[i+2].Data].Text) == name {
+				return true
+			}
+			depth++
+		case parser.EventClose:
+			depth--
+		}
+	}
+	return false
+}
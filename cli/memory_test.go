@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/sdk/secure"
+	"github.com/opal-lang/opal/runtime/streamscrub"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+// TestSecretNotInHeapAfterRun runs a script using a secret, closes the vault
+// like main does, and scans a heap snapshot for the plaintext. The snapshot
+// holds live objects, so this catches copies the run keeps reachable
+// (caches, buffer pools), not freed memory awaiting reuse.
+func TestSecretNotInHeapAfterRun(t *testing.T) {
+	// The secret is random so no copy exists in the binary, and the test only
+	// keeps it masked: the needle is rebuilt after the snapshot is written.
+	mask := make([]byte, 32)
+	masked := make([]byte, 32)
+	if _, err := rand.Read(mask); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rand.Read(masked); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	opalFile := filepath.Join(dir, "commands.opl")
+	source := []byte("var TOKEN = \"" + unmaskSecret(masked, mask) + "\"\nfun deploy {\n    echo \"token is @var.TOKEN\"\n}\n")
+	err := os.WriteFile(opalFile, source, 0o644)
+	secure.Wipe(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := runAndClose(t, opalFile)
+	if !strings.Contains(output, "token is opal:") {
+		t.Fatalf("unexpected output: %q", output)
+	}
+
+	runtime.GC()
+	dumpFile := filepath.Join(dir, "heap.dump")
+	f, err := os.Create(dumpFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	debug.WriteHeapDump(f.Fd())
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dump, err := os.ReadFile(dumpFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dump) == 0 {
+		t.Fatal("empty heap dump")
+	}
+	if bytes.Contains(dump, []byte(unmaskSecret(masked, mask))) {
+		t.Error("secret plaintext found in the heap after the run")
+	}
+}
+
+// runAndClose runs the deploy function and closes its vault, returning the
+// scrubbed output.
+func runAndClose(t *testing.T, opalFile string) string {
+	t.Helper()
+	vlt := vault.NewWithPlanKey(make([]byte, 32))
+	defer vlt.Close()
+
+	opalGen, err := streamscrub.NewOpalPlaceholderGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outputBuf bytes.Buffer
+	scrubber := streamscrub.New(&outputBuf,
+		streamscrub.WithPlaceholderFunc(opalGen.PlaceholderFunc()),
+		streamscrub.WithSecretProvider(vlt.SecretProvider()))
+
+	restore := scrubber.LockdownStreams()
+	exitCode, err := runCommand(runOptions{file: opalFile, noColor: true}, "deploy", targetArgs{}, vlt, scrubber, nil, nil, nil, &outputBuf)
+	restore()
+	if err != nil || exitCode != 0 {
+		t.Fatalf("run failed: exit %d, %v", exitCode, err)
+	}
+	return outputBuf.String()
+}
+
+// unmaskSecret rebuilds the secret as hex.
+func unmaskSecret(masked, mask []byte) string {
+	secret := make([]byte, len(masked))
+	for i := range masked {
+		secret[i] = masked[i] ^ mask[i]
+	}
+	defer secure.Wipe(secret)
+	return hex.EncodeToString(secret)
+}
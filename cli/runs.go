@@ -0,0 +1,503 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/runtime/executor"
+)
+
+// Run history: every execution gets a run ID and a directory in the runs
+// directory (--runs-dir, $OPAL_RUNS_DIR or ~/.opal/runs):
+//
+//	<runs-dir>/<run-id>/
+//	├── plan.json      # The executed plan (planfmt.WriteJSON: DisplayIDs, never values)
+//	├── summary.json   # runSummary: status, exit code, plan hash, step timings
+//	└── steps/<id>.log # Scrubbed output of each top-level step
+//
+// Everything is written after scrubbing, so the history holds no more than
+// the terminal showed. After each run the history is pruned to the newest
+// --runs-keep runs and to runs younger than --runs-max-age.
+
+// Run statuses
+const (
+	runRunning   = "running" // Still running, or opal stopped before recording the end
+	runSucceeded = "succeeded"
+	runFailed    = "failed"
+	runTimedOut  = "timed_out"
+	runCanceled  = "canceled"
+)
+
+// runSummary is a run's summary.json.
+type runSummary struct {
+	RunID      string    `json:"run_id"`
+	Target     string    `json:"target"`             // Empty in script mode
+	Source     string    `json:"source"`             // Command definitions file
+	Contract   string    `json:"contract,omitempty"` // Contract file (--plan runs)
+	PlanHash   string    `json:"plan_hash"`          // Contract hash of the executed plan
+	Status     string    `json:"status"`
+	ExitCode   int       `json:"exit_code"`
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at,omitzero"`
+	DurationMS int64     `json:"duration_ms"`
+	StepCount  int       `json:"step_count"`
+	StepsRun   int       `json:"steps_run"`
+	FailedStep *uint64   `json:"failed_step,omitempty"`
+	Steps      []runStep `json:"steps"`
+}
+
+// runStep is one top-level step of a run.
+type runStep struct {
+	ID         uint64       `json:"id"`
+	ExitCode   int          `json:"exit_code"`
+	DurationMS int64        `json:"duration_ms"`
+	Attempts   []runAttempt `json:"attempts,omitempty"`   // @retry attempts
+	PipeStatus []int        `json:"pipestatus,omitempty"` // Exit codes of the step's last pipeline
+	Log        string       `json:"log"`                  // Relative to the run directory
+}
+
+// runAttempt is one @retry attempt of a step.
+type runAttempt struct {
+	Attempt    int   `json:"attempt"`
+	ExitCode   int   `json:"exit_code"`
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// defaultRunsDir is the run history directory when --runs-dir is not set:
+// $OPAL_RUNS_DIR, or ~/.opal/runs ("" if there is no home directory).
+func defaultRunsDir() string {
+	if dir := os.Getenv("OPAL_RUNS_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".opal", "runs")
+}
+
+// Run history retention defaults
+const (
+	runsKeepDefault   = 100
+	runsMaxAgeDefault = 30 * 24 * time.Hour
+)
+
+// runRetention limits the run history. A zero limit keeps every run.
+type runRetention struct {
+	keep   int           // Newest runs kept (--runs-keep)
+	maxAge time.Duration // Older runs are removed (--runs-max-age)
+}
+
+// defaultRunsKeep is --runs-keep when not set: $OPAL_RUNS_KEEP, or 100.
+func defaultRunsKeep() int {
+	if n, err := strconv.Atoi(os.Getenv("OPAL_RUNS_KEEP")); err == nil && n >= 0 {
+		return n
+	}
+	return runsKeepDefault
+}
+
+// defaultRunsMaxAge is --runs-max-age when not set: $OPAL_RUNS_MAX_AGE, or
+// 30 days.
+func defaultRunsMaxAge() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("OPAL_RUNS_MAX_AGE")); err == nil && d >= 0 {
+		return d
+	}
+	return runsMaxAgeDefault
+}
+
+// runRecorder records an execution in the run history. It is the
+// scrubber's destination: output is passed on to out and copied to the log
+// of the step running. A nil recorder records nothing.
+type runRecorder struct {
+	dir       string       // Runs directory ("" records nothing)
+	retention runRetention // Applied to the runs directory after the run
+	out       io.Writer    // Where scrubbed output goes
+
+	mu      sync.Mutex
+	runDir  string // This run's directory (set by start)
+	summary runSummary
+	log     *os.File // Log of the step running
+	err     error    // First error writing a log
+}
+
+// newRunRecorder creates a recorder writing scrubbed output to out.
+func newRunRecorder(dir string, retention runRetention, out io.Writer) *runRecorder {
+	return &runRecorder{dir: dir, retention: retention, out: out}
+}
+
+// Write passes scrubbed output on, copying it to the running step's log.
+func (r *runRecorder) Write(p []byte) (int, error) {
+	n, err := r.out.Write(p)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.log != nil && r.err == nil {
+		if _, logErr := r.log.Write(p[:n]); logErr != nil {
+			r.err = logErr
+		}
+	}
+	return n, err
+}
+
+// start creates the run's directory with its plan and a "running" summary.
+// On error nothing more is recorded; the caller warns and runs anyway.
+func (r *runRecorder) start(target, source, contract string, plan *planfmt.Plan, hash [32]byte) error {
+	if !r.enabled() {
+		return nil
+	}
+	if err := os.MkdirAll(r.dir, 0o700); err != nil {
+		return err
+	}
+
+	started := time.Now().UTC()
+	base := fmt.Sprintf("run-%s-%x", started.Format("20060102-150405"), hash[:4])
+	id := base
+	for n := 2; ; n++ {
+		err := os.Mkdir(filepath.Join(r.dir, id), 0o700)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		id = base + "-" + strconv.Itoa(n) // Same plan started twice in a second
+	}
+	runDir := filepath.Join(r.dir, id)
+
+	if err := os.Mkdir(filepath.Join(runDir, "steps"), 0o700); err != nil {
+		return err
+	}
+	if err := writeFileWith(filepath.Join(runDir, "plan.json"), func(w io.Writer) error {
+		return planfmt.WriteJSON(w, plan)
+	}); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runDir = runDir
+	r.summary = runSummary{
+		RunID:     id,
+		Target:    target,
+		Source:    source,
+		Contract:  contract,
+		PlanHash:  fmt.Sprintf("%x", hash),
+		Status:    runRunning,
+		StartedAt: started,
+		StepCount: len(plan.Steps),
+		Steps:     []runStep{},
+	}
+	return r.writeSummary()
+}
+
+// enabled reports whether runs are recorded.
+func (r *runRecorder) enabled() bool {
+	return r != nil && r.dir != ""
+}
+
+// id returns the run's ID ("" if the run is not recorded).
+func (r *runRecorder) id() string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.summary.RunID
+}
+
+// startRun starts recording a run. Failing to record does not stop the
+// run: opal warns and runs without a history entry.
+func startRun(runs *runRecorder, target, source, contract string, plan *planfmt.Plan, hash [32]byte) {
+	if err := runs.start(target, source, contract, plan, hash); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: run not recorded in %s: %v\n", runs.dir, err)
+	}
+}
+
+// finishRun records how a run ended and prunes the history, warning if
+// either fails.
+func finishRun(runs *runRecorder, result *executor.ExecutionResult, runErr error) {
+	if err := runs.finish(result, runErr); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: run history incomplete: %v\n", err)
+	}
+	if err := runs.prune(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: run history not pruned: %v\n", err)
+	}
+}
+
+// attach sends each step's output to its own log. Call it after
+// outputScrubbing.attach, whose StepStarted flushes the previous step's
+// output first.
+func (r *runRecorder) attach(config *executor.Config) {
+	if r == nil || r.runDir == "" {
+		return
+	}
+	// Step timings and attempts go into the summary
+	config.Telemetry = executor.TelemetryTiming
+
+	flush := config.StepStarted
+	config.StepStarted = func(stepID uint64) {
+		if flush != nil {
+			flush(stepID)
+		}
+		r.openLog(stepID)
+	}
+}
+
+// openLog switches output to the log of the step starting.
+func (r *runRecorder) openLog(stepID uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeLog()
+	if r.err != nil {
+		return
+	}
+	r.log, r.err = os.OpenFile(filepath.Join(r.runDir, stepLogName(stepID)), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+}
+
+// closeLog closes the running step's log. Callers hold mu.
+func (r *runRecorder) closeLog() {
+	if r.log == nil {
+		return
+	}
+	if err := r.log.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	r.log = nil
+}
+
+// finish records how the run ended. result is nil if execution could not
+// run; runErr is an error that failed the run after execution (e.g., a
+// secret caught by strict scrubbing). Call it once output is flushed.
+func (r *runRecorder) finish(result *executor.ExecutionResult, runErr error) error {
+	if r == nil || r.runDir == "" {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeLog()
+
+	s := &r.summary
+	s.EndedAt = time.Now().UTC()
+	s.DurationMS = s.EndedAt.Sub(s.StartedAt).Milliseconds()
+	s.Status, s.ExitCode = runStatus(result, runErr)
+	if result != nil {
+		s.StepsRun = result.StepsRun
+		if t := result.Telemetry; t != nil {
+			s.FailedStep = t.FailedStep
+			for _, st := range t.StepTimings {
+				step := runStep{
+					ID:         st.StepID,
+					ExitCode:   st.ExitCode,
+					DurationMS: st.Duration.Milliseconds(),
+					PipeStatus: st.PipeStatus,
+					Log:        stepLogName(st.StepID),
+				}
+				for _, at := range st.Attempts {
+					step.Attempts = append(step.Attempts, runAttempt{
+						Attempt:    at.Attempt,
+						ExitCode:   at.ExitCode,
+						DurationMS: at.Duration.Milliseconds(),
+					})
+				}
+				s.Steps = append(s.Steps, step)
+			}
+		}
+	}
+
+	if err := r.writeSummary(); err != nil {
+		return err
+	}
+	if r.err != nil {
+		return fmt.Errorf("step logs incomplete: %w", r.err)
+	}
+	return nil
+}
+
+// prune removes the runs beyond the retention limits. The recorded run is
+// always kept, and so is a run still running unless it is past maxAge
+// (opal stopped before recording its end).
+func (r *runRecorder) prune() error {
+	if r == nil || r.runDir == "" || r.retention == (runRetention{}) {
+		return nil
+	}
+	runs, err := readRuns(r.dir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var errs []error
+	for i, run := range runs {
+		if run.dir == r.runDir {
+			continue
+		}
+		expired := r.retention.maxAge > 0 && now.Sub(run.summary.StartedAt) > r.retention.maxAge
+		excess := r.retention.keep > 0 && i >= r.retention.keep
+		if run.summary.Status == runRunning && !expired {
+			continue
+		}
+		if expired || excess {
+			if err := os.RemoveAll(run.dir); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runStatus classifies how a run ended, with its exit code.
+func runStatus(result *executor.ExecutionResult, runErr error) (string, int) {
+	switch {
+	case result == nil:
+		return runFailed, 1
+	case runErr != nil:
+		if result.ExitCode > 0 {
+			return runFailed, result.ExitCode
+		}
+		return runFailed, 1
+	case result.Timeout != nil:
+		return runTimedOut, result.ExitCode
+	case result.ExitCode < 0:
+		return runCanceled, result.ExitCode
+	case result.ExitCode != 0:
+		return runFailed, result.ExitCode
+	}
+	return runSucceeded, 0
+}
+
+// writeSummary writes summary.json. Callers hold mu.
+func (r *runRecorder) writeSummary() error {
+	return writeFileWith(filepath.Join(r.runDir, "summary.json"), func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(&r.summary)
+	})
+}
+
+// stepLogName is a step's log, relative to the run directory.
+func stepLogName(stepID uint64) string {
+	return filepath.Join("steps", fmt.Sprintf("%d.log", stepID))
+}
+
+// writeFileWith creates path (private to the user) and fills it with write.
+func writeFileWith(path string, write func(io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// storedRun is a run read back from the history.
+type storedRun struct {
+	dir     string
+	summary runSummary
+}
+
+// readRun reads a run's summary.
+func readRun(runsDir, id string) (*storedRun, error) {
+	if id == "" || id != filepath.Base(id) || id == "." || id == ".." {
+		return nil, &CLIError{Type: "usage", Message: fmt.Sprintf("invalid run ID %q", id)}
+	}
+	dir := filepath.Join(runsDir, id)
+	data, err := os.ReadFile(filepath.Join(dir, "summary.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &CLIError{
+			Type:    "usage",
+			Message: fmt.Sprintf("no run %s in %s", id, runsDir),
+			Hint:    "List recorded runs with: opal runs list",
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	run := &storedRun{dir: dir}
+	if err := json.Unmarshal(data, &run.summary); err != nil {
+		return nil, fmt.Errorf("run %s: invalid summary.json: %w", id, err)
+	}
+	return run, nil
+}
+
+// plan reads the run's plan.json.
+func (r *storedRun) plan() (*planfmt.Plan, error) {
+	f, err := os.Open(filepath.Join(r.dir, "plan.json"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	plan, err := planfmt.ReadJSON(f)
+	if err != nil {
+		return nil, fmt.Errorf("run %s: invalid plan.json: %w", r.summary.RunID, err)
+	}
+	return plan, nil
+}
+
+// stepLog reads a step's scrubbed output (nil if the step has no log).
+func (r *storedRun) stepLog(stepID uint64) []byte {
+	data, err := os.ReadFile(filepath.Join(r.dir, stepLogName(stepID)))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// readRuns reads the recorded runs, newest first. Directories without a
+// readable summary are skipped.
+func readRuns(runsDir string) ([]*storedRun, error) {
+	entries, err := os.ReadDir(runsDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*storedRun
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		run, err := readRun(runsDir, entry.Name())
+		if err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].summary.StartedAt.After(runs[j].summary.StartedAt)
+	})
+	return runs, nil
+}
+
+// listRuns reads the summaries of recorded runs, newest first. A non-empty
+// target keeps only that target's runs.
+func listRuns(runsDir, target string) ([]runSummary, error) {
+	stored, err := readRuns(runsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []runSummary
+	for _, run := range stored {
+		if target != "" && run.summary.Target != target {
+			continue
+		}
+		runs = append(runs, run.summary)
+	}
+	return runs, nil
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/core/planfmt/formatter"
+)
+
+// logTailLines is how much of a failed step's output runs show prints.
+const logTailLines = 20
+
+// displayIDPattern matches DisplayIDs in step output.
+var displayIDPattern = regexp.MustCompile(`opal:[A-Za-z0-9_-]{22}`)
+
+// runsList prints recorded runs, newest first, at most limit (0 = all).
+func runsList(w io.Writer, runsDir, target string, limit int, useColor bool) error {
+	runs, err := listRuns(runsDir, target)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		if target != "" {
+			_, _ = fmt.Fprintf(w, "No runs of %s in %s\n", target, runsDir)
+		} else {
+			_, _ = fmt.Fprintf(w, "No runs in %s\n", runsDir)
+		}
+		return nil
+	}
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "RUN\tTARGET\tSTATUS\tEXIT\tSTARTED\tDURATION")
+	for _, run := range runs {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			run.RunID, runTarget(run.Target), colorStatus(run.Status, useColor), run.ExitCode,
+			run.StartedAt.Local().Format("2006-01-02 15:04:05"), runDuration(run))
+	}
+	return tw.Flush()
+}
+
+// runsShow prints a run: summary, each step with its result, and the
+// output of the step that failed.
+func runsShow(w io.Writer, runsDir, id string, useColor bool) error {
+	run, err := readRun(runsDir, id)
+	if err != nil {
+		return err
+	}
+	plan, err := run.plan()
+	if err != nil {
+		return err
+	}
+	s := run.summary
+
+	label := func(name string) string {
+		return Colorize(fmt.Sprintf("%-11s", name+":"), ColorCyan, useColor)
+	}
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("Run"), s.RunID)
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("Target"), runTarget(s.Target))
+	_, _ = fmt.Fprintf(w, "%s %s (exit %d)\n", label("Status"), colorStatus(s.Status, useColor), s.ExitCode)
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("Started"), s.StartedAt.Format(time.RFC3339))
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("Duration"), runDuration(s))
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("Plan hash"), s.PlanHash)
+	_, _ = fmt.Fprintf(w, "%s %s\n", label("Source"), s.Source)
+	if s.Contract != "" {
+		_, _ = fmt.Fprintf(w, "%s %s\n", label("Contract"), s.Contract)
+	}
+	_, _ = fmt.Fprintf(w, "%s %d/%d\n", label("Steps run"), s.StepsRun, s.StepCount)
+
+	_, _ = fmt.Fprintf(w, "\n%s\n", Colorize("Steps:", ColorCyan, useColor))
+	results := stepResults(s)
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		result, ran := results[step.ID]
+		mark, outcome := "-", "not run"
+		if ran {
+			mark = Colorize("✓", ColorGreen, useColor)
+			if result.ExitCode != 0 {
+				mark = Colorize("✗", ColorRed, useColor)
+			}
+			outcome = formatStepResult(result)
+		}
+		_, _ = fmt.Fprintf(w, "  %s %d. %s  (%s)\n", mark, i+1, formatter.FormatStep(step), outcome)
+		for _, at := range result.Attempts {
+			_, _ = fmt.Fprintf(w, "       attempt %d: exit %d, %s\n", at.Attempt, at.ExitCode, msDuration(at.DurationMS))
+		}
+	}
+
+	if s.FailedStep != nil {
+		if tail := lastLines(run.stepLog(*s.FailedStep), logTailLines); tail != "" {
+			_, _ = fmt.Fprintf(w, "\n%s\n%s", Colorize("Output of the failed step:", ColorCyan, useColor), tail)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\nLogs: %s\n", run.dir)
+	return nil
+}
+
+// runsDiff compares two runs: outcome, plan changes and each step's
+// result, so a failing run can be held against the last good one.
+func runsDiff(w io.Writer, runsDir, idA, idB string, useColor bool) error {
+	runA, err := readRun(runsDir, idA)
+	if err != nil {
+		return err
+	}
+	runB, err := readRun(runsDir, idB)
+	if err != nil {
+		return err
+	}
+	planA, err := runA.plan()
+	if err != nil {
+		return err
+	}
+	planB, err := runB.plan()
+	if err != nil {
+		return err
+	}
+	a, b := runA.summary, runB.summary
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "\tA: %s\tB: %s\n", a.RunID, b.RunID)
+	_, _ = fmt.Fprintf(tw, "Target\t%s\t%s\n", runTarget(a.Target), runTarget(b.Target))
+	_, _ = fmt.Fprintf(tw, "Status\t%s (exit %d)\t%s (exit %d)\n", a.Status, a.ExitCode, b.Status, b.ExitCode)
+	_, _ = fmt.Fprintf(tw, "Started\t%s\t%s\n", a.StartedAt.Format(time.RFC3339), b.StartedAt.Format(time.RFC3339))
+	_, _ = fmt.Fprintf(tw, "Duration\t%s\t%s\n", runDuration(a), runDuration(b))
+	_, _ = fmt.Fprintf(tw, "Plan hash\t%s\t%s\n", shortHash(a.PlanHash), shortHash(b.PlanHash))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Runs planned with different salts (e.g., not from the same contract)
+	// have unrelated DisplayIDs: their values cannot be compared
+	sameSalt := bytes.Equal(planA.PlanSalt, planB.PlanSalt)
+
+	_, _ = fmt.Fprintf(w, "\n%s\n", Colorize("Plan changes:", ColorCyan, useColor))
+	var changes []formatter.Drift
+	if a.PlanHash != b.PlanHash {
+		for _, d := range formatter.ClassifyDrift(planA, planB) {
+			if !sameSalt && (d.Cause == formatter.DriftValueChanged || d.Cause == formatter.DriftSecretUseMoved) {
+				continue
+			}
+			changes = append(changes, d)
+		}
+	}
+	if len(changes) == 0 {
+		_, _ = fmt.Fprintln(w, "  none")
+	}
+	for _, d := range changes {
+		_, _ = fmt.Fprintf(w, "  %s: %s\n", d.Cause, d.Detail)
+		if d.Cause != formatter.DriftSourceChanged || d.Step == 0 {
+			continue
+		}
+		if d.Expected != "" {
+			_, _ = fmt.Fprintf(w, "    %s\n", Colorize("- "+d.Expected, ColorRed, useColor))
+		}
+		if d.Actual != "" {
+			_, _ = fmt.Fprintf(w, "    %s\n", Colorize("+ "+d.Actual, ColorGreen, useColor))
+		}
+	}
+	if !sameSalt {
+		_, _ = fmt.Fprintln(w, "  (values not compared: the runs were planned with different salts)")
+	}
+
+	_, _ = fmt.Fprintf(w, "\n%s\n", Colorize("Steps:", ColorCyan, useColor))
+	resultsA, resultsB := stepResults(a), stepResults(b)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  STEP\tA\tB\t")
+	for i := 0; i < max(len(planA.Steps), len(planB.Steps)); i++ {
+		outA := newStepOutcome(runA, planA, resultsA, i)
+		outB := newStepOutcome(runB, planB, resultsB, i)
+		var notes []string
+		if outA.status() != outB.status() {
+			notes = append(notes, "result differs")
+		}
+		logA, logB := outA.log, outB.log
+		if !sameSalt {
+			logA = displayIDPattern.ReplaceAll(logA, []byte("opal:*"))
+			logB = displayIDPattern.ReplaceAll(logB, []byte("opal:*"))
+		}
+		if outA.ran && outB.ran && !bytes.Equal(logA, logB) {
+			notes = append(notes, "output differs")
+		}
+		note := strings.Join(notes, ", ")
+		if note != "" {
+			note = Colorize(note, ColorYellow, useColor)
+		}
+		_, _ = fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\n", i+1, outA, outB, note)
+	}
+	return tw.Flush()
+}
+
+// stepOutcome is what happened to one step of a run.
+type stepOutcome struct {
+	inPlan bool // The run's plan has the step
+	ran    bool
+	result runStep
+	log    []byte // Scrubbed output
+}
+
+// newStepOutcome looks up the i-th step of a run.
+func newStepOutcome(run *storedRun, plan *planfmt.Plan, results map[uint64]runStep, i int) stepOutcome {
+	if i >= len(plan.Steps) {
+		return stepOutcome{}
+	}
+	result, ran := results[plan.Steps[i].ID]
+	if !ran {
+		return stepOutcome{inPlan: true}
+	}
+	return stepOutcome{inPlan: true, ran: true, result: result, log: run.stepLog(result.ID)}
+}
+
+// status is the outcome without timing, for comparing runs.
+func (o stepOutcome) status() string {
+	if !o.ran {
+		return o.String()
+	}
+	return fmt.Sprintf("exit %d", o.result.ExitCode)
+}
+
+func (o stepOutcome) String() string {
+	switch {
+	case !o.inPlan:
+		return "absent"
+	case !o.ran:
+		return "not run"
+	}
+	return formatStepResult(o.result)
+}
+
+// stepResults indexes a run's step results by step ID.
+func stepResults(s runSummary) map[uint64]runStep {
+	results := make(map[uint64]runStep, len(s.Steps))
+	for _, step := range s.Steps {
+		results[step.ID] = step
+	}
+	return results
+}
+
+// formatStepResult formats a step's exit code, PIPESTATUS and duration.
+func formatStepResult(step runStep) string {
+	if step.PipeStatus != nil {
+		return fmt.Sprintf("exit %d, pipestatus %s, %s", step.ExitCode, formatPipeStatus(step.PipeStatus), msDuration(step.DurationMS))
+	}
+	return fmt.Sprintf("exit %d, %s", step.ExitCode, msDuration(step.DurationMS))
+}
+
+// colorStatus colors a run status by outcome.
+func colorStatus(status string, useColor bool) string {
+	switch status {
+	case runSucceeded:
+		return Colorize(status, ColorGreen, useColor)
+	case runFailed:
+		return Colorize(status, ColorRed, useColor)
+	case runTimedOut, runCanceled:
+		return Colorize(status, ColorYellow, useColor)
+	}
+	return Colorize(status, ColorGray, useColor)
+}
+
+// runTarget names a run's target; script-mode runs have none.
+func runTarget(target string) string {
+	if target == "" {
+		return "(script)"
+	}
+	return target
+}
+
+// runDuration formats a run's duration ("-" while it is running).
+func runDuration(s runSummary) string {
+	if s.EndedAt.IsZero() {
+		return "-"
+	}
+	return msDuration(s.DurationMS)
+}
+
+// msDuration formats a duration in milliseconds.
+func msDuration(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// shortHash abbreviates a plan hash.
+func shortHash(hash string) string {
+	if len(hash) > 16 {
+		return hash[:16]
+	}
+	return hash
+}
+
+// lastLines returns the last n lines of output.
+func lastLines(output []byte, n int) string {
+	lines := strings.SplitAfter(strings.TrimRight(string(output), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	tail := strings.Join(lines, "")
+	if tail == "" {
+		return ""
+	}
+	return tail + "\n"
+}
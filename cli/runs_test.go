@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/runtime/executor"
+	"github.com/opal-lang/opal/runtime/streamscrub"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+// recordRun runs the deploy function of source with run history in runsDir
+// and returns the recorded run.
+func recordRun(t *testing.T, runsDir, source string) *storedRun {
+	t.Helper()
+	opalFile := filepath.Join(t.TempDir(), "commands.opl")
+	if err := os.WriteFile(opalFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	before, err := listRuns(runsDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vlt := vault.NewWithPlanKey(make([]byte, 32))
+	opalGen, err := streamscrub.NewOpalPlaceholderGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scrub, err := newOutputScrubbing("redact", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outputBuf bytes.Buffer
+	runs := newRunRecorder(runsDir, runRetention{}, &outputBuf)
+	scrubber := scrub.newScrubber(runs, opalGen.PlaceholderFunc(), vlt.SecretProvider())
+
+	_, _ = runCommand(runOptions{file: opalFile, noColor: true}, "deploy", targetArgs{}, vlt, scrubber, scrub, runs, nil, &outputBuf)
+	if err := scrubber.Close(); err != nil {
+		t.Fatalf("Failed to close scrubber: %v", err)
+	}
+
+	after, err := listRuns(runsDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before)+1 {
+		t.Fatalf("Expected one new run, have %d runs (had %d)", len(after), len(before))
+	}
+	run, err := readRun(runsDir, after[0].RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return run
+}
+
+const runsTestSource = `var TOKEN = "s3cr3t-value"
+fun deploy {
+    echo "starting"
+    echo "token is @var.TOKEN"
+    sh -c "echo failing; exit 3"
+    echo "never here"
+}`
+
+func TestRunHistory_RecordsFailedRun(t *testing.T) {
+	runsDir := t.TempDir()
+	run := recordRun(t, runsDir, runsTestSource)
+	s := run.summary
+
+	if s.Target != "deploy" || s.Status != runFailed || s.ExitCode != 3 {
+		t.Errorf("target=%q status=%q exit=%d, want deploy failed 3", s.Target, s.Status, s.ExitCode)
+	}
+	if s.StepCount != 4 || s.StepsRun != 3 || len(s.Steps) != 3 {
+		t.Fatalf("steps=%d run=%d recorded=%d, want 4 3 3", s.StepCount, s.StepsRun, len(s.Steps))
+	}
+	if s.FailedStep == nil || *s.FailedStep != s.Steps[2].ID {
+		t.Errorf("failed step = %v, want %d", s.FailedStep, s.Steps[2].ID)
+	}
+	if s.EndedAt.IsZero() || len(s.PlanHash) != 64 {
+		t.Errorf("summary not finished: %+v", s)
+	}
+
+	// Each step has its own scrubbed log
+	for i, want := range []string{"starting\n", "token is opal:", "failing\n"} {
+		if log := string(run.stepLog(s.Steps[i].ID)); !strings.HasPrefix(log, want) {
+			t.Errorf("step %d log = %q, want prefix %q", i+1, log, want)
+		}
+	}
+
+	// Nothing in the run directory holds the secret
+	err := filepath.WalkDir(run.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(data, []byte("s3cr3t-value")) {
+			t.Errorf("%s contains the secret", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunsShow(t *testing.T) {
+	runsDir := t.TempDir()
+	run := recordRun(t, runsDir, runsTestSource)
+
+	var out bytes.Buffer
+	if err := runsShow(&out, runsDir, run.summary.RunID, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Run:        " + run.summary.RunID,
+		"Status:     failed (exit 3)",
+		"Steps run:  3/4",
+		"✓ 1. @shell echo \"starting\"  (exit 0, ",
+		"✗ 3. @shell sh -c \"echo failing; exit 3\"  (exit 3, ",
+		"- 4. @shell echo \"never here\"  (not run)",
+		"Output of the failed step:\nfailing\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunsDiff(t *testing.T) {
+	runsDir := t.TempDir()
+	good := recordRun(t, runsDir, strings.Replace(runsTestSource, "exit 3", "exit 0", 1))
+	bad := recordRun(t, runsDir, runsTestSource)
+
+	var out bytes.Buffer
+	if err := runsDiff(&out, runsDir, good.summary.RunID, bad.summary.RunID, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Status     succeeded (exit 0)",
+		"failed (exit 3)",
+		"source_changed: step 3 changed",
+		`- @shell sh -c "echo failing; exit 0"`,
+		`+ @shell sh -c "echo failing; exit 3"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	stepLine := func(n string) string {
+		for _, line := range lines {
+			if strings.HasPrefix(strings.TrimSpace(line), n+" ") {
+				return line
+			}
+		}
+		return ""
+	}
+	if line := stepLine("1"); strings.Contains(line, "differs") {
+		t.Errorf("step 1 should match (DisplayIDs masked across salts): %q", line)
+	}
+	if line := stepLine("3"); !strings.Contains(line, "result differs") {
+		t.Errorf("step 3 should differ: %q", line)
+	}
+	if line := stepLine("4"); !strings.Contains(line, "not run") || !strings.Contains(line, "result differs") {
+		t.Errorf("step 4 should be not run in B: %q", line)
+	}
+}
+
+func TestRunHistory_PipeStatus(t *testing.T) {
+	runsDir := t.TempDir()
+	run := recordRun(t, runsDir, `fun deploy {
+    sh -c "exit 3" | cat
+}`)
+	s := run.summary
+	if s.ExitCode != 0 || len(s.Steps) != 1 {
+		t.Fatalf("exit=%d steps=%d, want 0 1", s.ExitCode, len(s.Steps))
+	}
+	if got := formatPipeStatus(s.Steps[0].PipeStatus); got != "3 0" {
+		t.Errorf("pipestatus = %q, want \"3 0\"", got)
+	}
+
+	var out bytes.Buffer
+	if err := runsShow(&out, runsDir, s.RunID, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "(exit 0, pipestatus 3 0, ") {
+		t.Errorf("output missing the pipestatus:\n%s", out.String())
+	}
+}
+
+func TestRunHistory_Disabled(t *testing.T) {
+	var runs *runRecorder
+	if runs.enabled() || newRunRecorder("", runRetention{}, &bytes.Buffer{}).enabled() {
+		t.Error("recorder without a runs directory should be disabled")
+	}
+	config := executor.Config{}
+	newRunRecorder("", runRetention{}, &bytes.Buffer{}).attach(&config)
+	if config.StepStarted != nil || config.Telemetry != executor.TelemetryOff {
+		t.Error("disabled recorder should not change the executor config")
+	}
+	if err := runs.finish(nil, nil); err != nil {
+		t.Errorf("finish on nil recorder: %v", err)
+	}
+}
+
+func TestRunStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     *executor.ExecutionResult
+		runErr     error
+		wantStatus string
+		wantExit   int
+	}{
+		{"succeeded", &executor.ExecutionResult{}, nil, runSucceeded, 0},
+		{"failed", &executor.ExecutionResult{ExitCode: 2}, nil, runFailed, 2},
+		{"not executed", nil, nil, runFailed, 1},
+		{"failed after execution", &executor.ExecutionResult{}, os.ErrClosed, runFailed, 1},
+		{"canceled", &executor.ExecutionResult{ExitCode: -1}, nil, runCanceled, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, exit := runStatus(tt.result, tt.runErr)
+			if status != tt.wantStatus || exit != tt.wantExit {
+				t.Errorf("runStatus = %s %d, want %s %d", status, exit, tt.wantStatus, tt.wantExit)
+			}
+		})
+	}
+}
+
+// writeRunSummary records a run in runsDir with only its summary.
+func writeRunSummary(t *testing.T, runsDir string, s runSummary) {
+	t.Helper()
+	dir := filepath.Join(runsDir, s.RunID)
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(s)
+	if err := os.WriteFile(filepath.Join(dir, "summary.json"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListRuns(t *testing.T) {
+	runsDir := t.TempDir()
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, target := range []string{"deploy", "build", "deploy"} {
+		writeRunSummary(t, runsDir, runSummary{RunID: "run-" + target + string(rune('a'+i)), Target: target, StartedAt: base.Add(time.Duration(i) * time.Minute)})
+	}
+	if err := os.Mkdir(filepath.Join(runsDir, "not-a-run"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := listRuns(runsDir, "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, run := range runs {
+		ids = append(ids, run.RunID)
+	}
+	if strings.Join(ids, " ") != "run-deployc run-deploya" {
+		t.Errorf("runs = %v, want deploy runs newest first", ids)
+	}
+
+	if runs, err := listRuns(filepath.Join(runsDir, "missing"), ""); err != nil || runs != nil {
+		t.Errorf("missing runs directory: %v, %v", runs, err)
+	}
+}
+
+func TestPruneRuns(t *testing.T) {
+	now := time.Now().UTC()
+	runs := []runSummary{
+		{RunID: "run-current", Status: runRunning, StartedAt: now},
+		{RunID: "run-recent", Status: runSucceeded, StartedAt: now.Add(-time.Hour)},
+		{RunID: "run-concurrent", Status: runRunning, StartedAt: now.Add(-2 * time.Hour)},
+		{RunID: "run-excess", Status: runFailed, StartedAt: now.Add(-3 * time.Hour)},
+		{RunID: "run-expired", Status: runSucceeded, StartedAt: now.Add(-48 * time.Hour)},
+		{RunID: "run-abandoned", Status: runRunning, StartedAt: now.Add(-72 * time.Hour)},
+	}
+	remaining := func(t *testing.T, runsDir string) string {
+		t.Helper()
+		left, err := listRuns(runsDir, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, run := range left {
+			ids = append(ids, run.RunID)
+		}
+		return strings.Join(ids, " ")
+	}
+
+	tests := []struct {
+		name      string
+		retention runRetention
+		want      string
+	}{
+		{"no limits", runRetention{}, "run-current run-recent run-concurrent run-excess run-expired run-abandoned"},
+		{"keep", runRetention{keep: 2}, "run-current run-recent run-concurrent run-abandoned"},
+		{"max age", runRetention{maxAge: 24 * time.Hour}, "run-current run-recent run-concurrent run-excess"},
+		{"both", runRetention{keep: 2, maxAge: 24 * time.Hour}, "run-current run-recent run-concurrent"},
+		{"keep one", runRetention{keep: 1}, "run-current run-concurrent run-abandoned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runsDir := t.TempDir()
+			for _, s := range runs {
+				writeRunSummary(t, runsDir, s)
+			}
+			// Directories that aren't runs are left alone
+			if err := os.Mkdir(filepath.Join(runsDir, "not-a-run"), 0o700); err != nil {
+				t.Fatal(err)
+			}
+
+			r := newRunRecorder(runsDir, tt.retention, &bytes.Buffer{})
+			r.runDir = filepath.Join(runsDir, "run-current")
+			if err := r.prune(); err != nil {
+				t.Fatal(err)
+			}
+			if got := remaining(t, runsDir); got != tt.want {
+				t.Errorf("runs left = %q, want %q", got, tt.want)
+			}
+			if _, err := os.Stat(filepath.Join(runsDir, "not-a-run")); err != nil {
+				t.Errorf("pruned a directory that isn't a run: %v", err)
+			}
+		})
+	}
+
+	// Pruning runs after each recorded run
+	runsDir := t.TempDir()
+	writeRunSummary(t, runsDir, runSummary{RunID: "run-old", Status: runSucceeded, StartedAt: now.Add(-time.Hour)})
+	recorder := newRunRecorder(runsDir, runRetention{keep: 1}, &bytes.Buffer{})
+	if err := recorder.start("deploy", "commands.opl", "", &planfmt.Plan{Target: "deploy"}, [32]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	finishRun(recorder, &executor.ExecutionResult{}, nil)
+	if got := remaining(t, runsDir); got != recorder.id() {
+		t.Errorf("runs left = %q, want only %q", got, recorder.id())
+	}
+}
+
+func TestReadRun_InvalidID(t *testing.T) {
+	runsDir := t.TempDir()
+	for _, id := range []string{"", ".", "..", "../etc", "a/b"} {
+		if _, err := readRun(runsDir, id); err == nil || !strings.Contains(err.Error(), "invalid run ID") {
+			t.Errorf("readRun(%q) = %v, want invalid run ID", id, err)
+		}
+	}
+	if _, err := readRun(runsDir, "run-missing"); err == nil || !strings.Contains(err.Error(), "no run run-missing") {
+		t.Errorf("readRun(missing) = %v", err)
+	}
+}
+
+func TestRunsFunctionTakesPrecedence(t *testing.T) {
+	opalBin := buildOpalBinary(t)
+	defer os.Remove(opalBin)
+
+	opalFile := filepath.Join(t.TempDir(), "commands.opl")
+	if err := os.WriteFile(opalFile, []byte(`fun runs = echo "target runs"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runsDir := t.TempDir()
+	output, err := exec.Command(opalBin, "-f", opalFile, "--runs-dir", runsDir, "runs").CombinedOutput()
+	if err != nil {
+		t.Fatalf("opal runs: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "target runs") {
+		t.Errorf("output = %q, want the runs function's output", output)
+	}
+	recorded, err := listRuns(runsDir, "runs")
+	if err != nil || len(recorded) != 1 {
+		t.Fatalf("recorded runs = %v, %v; want the runs function recorded", recorded, err)
+	}
+
+	// The subcommands still apply to files without such a function
+	other := filepath.Join(t.TempDir(), "commands.opl")
+	if err := os.WriteFile(other, []byte(`fun deploy = echo "deploying"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output, err = exec.Command(opalBin, "-f", other, "--runs-dir", runsDir, "runs", "list", "--no-color").CombinedOutput()
+	if err != nil {
+		t.Fatalf("opal runs list: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), recorded[0].RunID) {
+		t.Errorf("runs list output = %q, want run %s", output, recorded[0].RunID)
+	}
+}
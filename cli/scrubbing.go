@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/opal-lang/opal/runtime/executor"
+	"github.com/opal-lang/opal/runtime/streamscrub"
+)
+
+// --scrub modes
+const (
+	scrubRedact = "redact" // Replace secrets with DisplayIDs (default)
+	scrubStrict = "strict" // Fail the run when a secret reaches output
+	scrubAudit  = "audit"  // Redact, and log every redaction to --scrub-log
+)
+
+// outputScrubbing applies --scrub to a run: the scrubber's provider and,
+// while executing, the step each redaction or leaked secret belongs to.
+type outputScrubbing struct {
+	mode    string
+	logFile string // Audit log (audit mode only)
+
+	// Set while executing (strict and audit modes)
+	streams []*streamscrub.StreamWriter // Scrubber streams commands write to
+	cancel  context.CancelFunc          // Stops execution when a secret leaks
+
+	mu         sync.Mutex
+	step       uint64           // Top-level step running (0 outside steps)
+	redactions []redactionEntry // Audit log entries
+	leak       *CLIError        // First leaked secret (strict mode)
+}
+
+// redactionEntry is one redaction in the audit log. It never holds the secret.
+type redactionEntry struct {
+	Step      uint64 `json:"step"`       // Top-level step whose output it was in (0 outside steps)
+	DisplayID string `json:"display_id"` // Placeholder written instead of the secret
+	Offset    int64  `json:"offset"`     // Byte offset of the placeholder in opal's output
+}
+
+// newOutputScrubbing validates the --scrub and --scrub-log flags.
+func newOutputScrubbing(mode, logFile string, dryRun bool) (*outputScrubbing, error) {
+	switch mode {
+	case scrubRedact, scrubStrict, scrubAudit:
+	default:
+		return nil, &CLIError{
+			Type:    "usage",
+			Message: fmt.Sprintf("unknown --scrub mode %q", mode),
+			Hint:    "Use --scrub=redact, --scrub=strict or --scrub=audit",
+		}
+	}
+	if mode != scrubRedact && dryRun {
+		return nil, &CLIError{
+			Type:    "usage",
+			Message: fmt.Sprintf("--scrub=%s only applies when executing", mode),
+			Hint:    "Remove --dry-run, or drop --scrub",
+		}
+	}
+	if mode == scrubAudit && logFile == "" {
+		return nil, &CLIError{
+			Type:    "usage",
+			Message: "--scrub=audit requires --scrub-log",
+			Hint:    "Add --scrub-log <file> to choose where the redaction log is written",
+		}
+	}
+	if mode != scrubAudit && logFile != "" {
+		return nil, &CLIError{
+			Type:    "usage",
+			Message: "--scrub-log only applies with --scrub=audit",
+		}
+	}
+	return &outputScrubbing{mode: mode, logFile: logFile}, nil
+}
+
+// newScrubber creates the run's scrubber: strict mode rejects output with
+// secrets instead of redacting them, audit mode records each redaction.
+func (o *outputScrubbing) newScrubber(w io.Writer, placeholder streamscrub.PlaceholderFunc, provider streamscrub.SecretProvider) *streamscrub.Scrubber {
+	opts := []streamscrub.Option{streamscrub.WithPlaceholderFunc(placeholder)}
+	switch o.mode {
+	case scrubStrict:
+		// Output that bypasses the command streams (opal's own messages,
+		// writers defaulting to os.Stdout) reaches the scrubber through
+		// LockdownStreams; a leak there stops the run too
+		opts = append(opts,
+			streamscrub.WithSecretProvider(streamscrub.NewStrictProvider(provider)),
+			streamscrub.WithDropHandler(o.recordLeak))
+	case scrubAudit:
+		opts = append(opts,
+			streamscrub.WithSecretProvider(provider),
+			streamscrub.WithRedactionHandler(o.recordRedaction))
+	default:
+		opts = append(opts, streamscrub.WithSecretProvider(provider))
+	}
+	return streamscrub.New(w, opts...)
+}
+
+// attach routes commands' output straight into the scrubber, so each
+// step's output is scrubbed (and attributed to it, in the audit log and the
+// run history) before the next starts. A nil scrubbing leaves config
+// unchanged.
+func (o *outputScrubbing) attach(config *executor.Config, scrubber *streamscrub.Scrubber, cancel context.CancelFunc) {
+	if o == nil {
+		return
+	}
+
+	stdout, stderr := scrubber.Stream(), scrubber.Stream()
+	o.streams = []*streamscrub.StreamWriter{stdout, stderr}
+	o.mu.Lock()
+	o.cancel = cancel
+	o.mu.Unlock()
+	config.Stdout = &leakGuard{o: o, w: stdout}
+	config.Stderr = &leakGuard{o: o, w: stderr}
+	config.StepStarted = func(stepID uint64) {
+		o.flush() // The previous step's output is complete
+		o.mu.Lock()
+		o.step = stepID
+		o.mu.Unlock()
+	}
+}
+
+// finish flushes the last step's output, writes the audit log and returns
+// the leak that stopped execution, if any.
+func (o *outputScrubbing) finish() error {
+	if o == nil {
+		return nil
+	}
+
+	o.flush()
+	for _, st := range o.streams {
+		_ = st.Close() // Already flushed: releases the stream
+	}
+	o.streams = nil
+	o.mu.Lock()
+	o.step = 0
+	leak := o.leak
+	o.mu.Unlock()
+
+	if o.mode == scrubAudit {
+		if err := o.writeLog(); err != nil {
+			return err
+		}
+	}
+	if leak != nil {
+		return leak
+	}
+	return nil
+}
+
+// flush writes out the carry of the streams commands write to.
+func (o *outputScrubbing) flush() {
+	for _, w := range o.streams {
+		if err := w.Flush(); err != nil {
+			o.recordLeak(err)
+		}
+	}
+}
+
+// recordRedaction is the audit-mode redaction handler.
+func (o *outputScrubbing) recordRedaction(r streamscrub.Redaction) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.redactions = append(o.redactions, redactionEntry{Step: o.step, DisplayID: r.Placeholder, Offset: r.Offset})
+}
+
+// recordLeak stops execution on the first secret rejected by strict mode.
+func (o *outputScrubbing) recordLeak(err error) {
+	var leak *streamscrub.SecretLeakError
+	if !errors.As(err, &leak) {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.leak != nil {
+		return
+	}
+	o.leak = &CLIError{
+		Type:    "scrub",
+		Message: fmt.Sprintf("secret %s reached the output of step %d", leak.Placeholder, o.step),
+		Details: "Strict scrubbing stopped the run. The output containing the secret was not written.",
+		Hint:    "Stop the step from printing the secret (raw or encoded), or run without --scrub=strict to redact it",
+	}
+	if o.cancel != nil {
+		o.cancel()
+	}
+}
+
+// err returns the leak that stopped the run, if any. Unlike finish, it also
+// sees leaks in output drained by LockdownStreams after execution.
+func (o *outputScrubbing) err() error {
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.leak != nil {
+		return o.leak
+	}
+	return nil
+}
+
+// writeLog writes the audit log as JSON.
+func (o *outputScrubbing) writeLog() error {
+	o.mu.Lock()
+	log := struct {
+		Redactions []redactionEntry `json:"redactions"`
+	}{Redactions: append([]redactionEntry{}, o.redactions...)}
+	o.mu.Unlock()
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode scrub log: %w", err)
+	}
+	if err := os.WriteFile(o.logFile, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write scrub log: %w", err)
+	}
+	return nil
+}
+
+// leakGuard is a command output writer that stops execution when strict
+// scrubbing rejects a write. Output after a leak (e.g., commands reporting
+// the cancellation) is dropped.
+type leakGuard struct {
+	o *outputScrubbing
+	w io.Writer
+}
+
+func (g *leakGuard) Write(p []byte) (int, error) {
+	g.o.mu.Lock()
+	leaked := g.o.leak != nil
+	g.o.mu.Unlock()
+	if leaked {
+		return len(p), nil
+	}
+
+	n, err := g.w.Write(p)
+	if err != nil {
+		g.o.recordLeak(err)
+	}
+	return n, err
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/runtime/streamscrub"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+func TestNewOutputScrubbing(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		logFile string
+		dryRun  bool
+		wantErr string
+	}{
+		{"default", "redact", "", false, ""},
+		{"strict", "strict", "", false, ""},
+		{"audit", "audit", "scrub.json", false, ""},
+		{"unknown mode", "loose", "", false, `unknown --scrub mode "loose"`},
+		{"strict dry run", "strict", "", true, "--scrub=strict only applies when executing"},
+		{"audit without log", "audit", "", false, "--scrub=audit requires --scrub-log"},
+		{"log without audit", "strict", "scrub.json", false, "--scrub-log only applies with --scrub=audit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newOutputScrubbing(tt.mode, tt.logFile, tt.dryRun)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+const scrubTestSource = `var TOKEN = "s3cr3t-value"
+fun leak {
+    echo "starting"
+    echo "token is @var.TOKEN"
+    echo "never here"
+}`
+
+// runScrubbed runs the leak function of source under the given scrubbing
+// mode and returns the scrubbed output.
+func runScrubbed(t *testing.T, scrub *outputScrubbing, source string) (string, int, error) {
+	t.Helper()
+	opalFile := filepath.Join(t.TempDir(), "commands.opl")
+	if err := os.WriteFile(opalFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	vlt := vault.NewWithPlanKey(make([]byte, 32))
+	opalGen, err := streamscrub.NewOpalPlaceholderGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outputBuf bytes.Buffer
+	scrubber := scrub.newScrubber(&outputBuf, opalGen.PlaceholderFunc(), vlt.SecretProvider())
+
+	exitCode, runErr := runCommand(runOptions{file: opalFile, noColor: true}, "leak", targetArgs{}, vlt, scrubber, scrub, nil, nil, &outputBuf)
+	if err := scrubber.Close(); err != nil {
+		t.Fatalf("Failed to close scrubber: %v", err)
+	}
+	return outputBuf.String(), exitCode, runErr
+}
+
+// TestScrubStrictStopsRun verifies a secret in a step's output stops the
+// run with an error naming the step and DisplayID, not the value
+func TestScrubStrictStopsRun(t *testing.T) {
+	scrub, err := newOutputScrubbing(scrubStrict, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, exitCode, err := runScrubbed(t, scrub, scrubTestSource)
+
+	var cliErr *CLIError
+	if !errors.As(err, &cliErr) {
+		t.Fatalf("Expected CLIError, got %v", err)
+	}
+	if exitCode == 0 {
+		t.Error("Expected non-zero exit code")
+	}
+	if !strings.Contains(cliErr.Message, "reached the output of step 2") || !strings.Contains(cliErr.Message, "opal:") {
+		t.Errorf("error should name the step and DisplayID: %q", cliErr.Message)
+	}
+	if strings.Contains(err.Error(), "s3cr3t-value") || strings.Contains(output, "s3cr3t-value") {
+		t.Error("secret value revealed")
+	}
+	if !strings.Contains(output, "starting") {
+		t.Errorf("output before the leak is missing: %q", output)
+	}
+	if strings.Contains(output, "never here") {
+		t.Errorf("run continued after the leak: %q", output)
+	}
+}
+
+// TestScrubStrictStopsRunOnRedirectError verifies strict mode also catches a
+// secret in opal's own error message for a redirect, not only in output
+// written by commands
+func TestScrubStrictStopsRunOnRedirectError(t *testing.T) {
+	scrub, err := newOutputScrubbing(scrubStrict, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, exitCode, err := runScrubbed(t, scrub, `var TOKEN = "s3cr3t-value"
+fun leak {
+    echo "@var.TOKEN" > /dev/null
+    echo "starting" > /nonexistent-dir/s3cr3t-value/out
+    echo "never here"
+}`)
+
+	var cliErr *CLIError
+	if !errors.As(err, &cliErr) {
+		t.Fatalf("Expected CLIError, got %v (output %q)", err, output)
+	}
+	if exitCode == 0 {
+		t.Error("Expected non-zero exit code")
+	}
+	if !strings.Contains(cliErr.Message, "reached the output of step 2") {
+		t.Errorf("error should name the step: %q", cliErr.Message)
+	}
+	if strings.Contains(output, "s3cr3t-value") {
+		t.Error("secret value revealed")
+	}
+	if strings.Contains(output, "never here") {
+		t.Errorf("run continued after the leak: %q", output)
+	}
+}
+
+// TestScrubAuditLog verifies audit mode redacts and logs each redaction
+// with its step and output offset
+func TestScrubAuditLog(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "scrub.json")
+	scrub, err := newOutputScrubbing(scrubAudit, logFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, exitCode, err := runScrubbed(t, scrub, scrubTestSource)
+	if err != nil || exitCode != 0 {
+		t.Fatalf("run failed: exit %d, %v", exitCode, err)
+	}
+	if strings.Contains(output, "s3cr3t-value") || !strings.Contains(output, "never here") {
+		t.Fatalf("unexpected output: %q", output)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Redactions []redactionEntry `json:"redactions"`
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("invalid log: %v\n%s", err, data)
+	}
+	if len(log.Redactions) != 1 {
+		t.Fatalf("Expected 1 redaction, got %s", data)
+	}
+	r := log.Redactions[0]
+	if r.Step != 2 {
+		t.Errorf("Step = %d, want 2", r.Step)
+	}
+	if !strings.HasPrefix(output[r.Offset:], r.DisplayID) || !strings.HasPrefix(r.DisplayID, "opal:") {
+		t.Errorf("offset %d does not point at %s in %q", r.Offset, r.DisplayID, output)
+	}
+}
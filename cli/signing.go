@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+// contractSigning holds the --sign-key, --trusted-keys and --require-signed
+// settings, and --compress for the contracts it writes.
+type contractSigning struct {
+	keys     []ed25519.PrivateKey // Keys to sign written contracts with
+	trust    *planfmt.TrustStore  // Keys allowed to approve contracts
+	required int                  // Trusted signatures needed to run a contract
+	compress bool                 // Compress written contract bodies
+}
+
+// newContractSigning loads signing keys and the trust store.
+// --trusted-keys on its own requires one signature.
+func newContractSigning(keyFiles []string, trustedKeysFile string, required int) (contractSigning, error) {
+	var signing contractSigning
+	if required < 0 {
+		return signing, &CLIError{Type: "usage", Message: "--require-signed must not be negative"}
+	}
+
+	for _, path := range keyFiles {
+		key, err := loadSigningKey(path)
+		if err != nil {
+			return signing, err
+		}
+		signing.keys = append(signing.keys, key)
+	}
+
+	if trustedKeysFile != "" {
+		trust, err := loadTrustStore(trustedKeysFile)
+		if err != nil {
+			return signing, err
+		}
+		signing.trust = trust
+		if required == 0 {
+			required = 1
+		}
+	}
+	if required > 0 && signing.trust == nil {
+		return signing, &CLIError{
+			Type:    "usage",
+			Message: "--require-signed needs --trusted-keys",
+			Hint:    "Pass the approvers' public keys, e.g. --trusted-keys .opal/approvers.pub",
+		}
+	}
+	signing.required = required
+	return signing, nil
+}
+
+// loadSigningKey reads an unencrypted Ed25519 private key in OpenSSH or
+// PKCS#8 PEM format (as written by ssh-keygen -t ed25519).
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	raw, err := ssh.ParseRawPrivateKey(data)
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, &CLIError{
+				Type:    "usage",
+				Message: fmt.Sprintf("signing key %s is encrypted", path),
+				Hint:    "Use an unencrypted key for signing, e.g. a dedicated approval key",
+			}
+		}
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+
+	switch key := raw.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *ed25519.PrivateKey:
+		return *key, nil
+	default:
+		return nil, fmt.Errorf("signing key %s is %T, expected Ed25519", path, raw)
+	}
+}
+
+// loadTrustStore reads approvers' public keys from an authorized_keys-style
+// file: one "ssh-ed25519 AAAA... name" line per key, # comments allowed.
+// The comment names the approver in messages.
+func loadTrustStore(path string) (*planfmt.TrustStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+
+	trust := planfmt.NewTrustStore()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pub, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		cryptoPub, ok := pub.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unsupported key type %s", path, lineNum, pub.Type())
+		}
+		key, ok := cryptoPub.CryptoPublicKey().(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: %s key, expected ssh-ed25519", path, lineNum, pub.Type())
+		}
+		trust.Add(comment, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+	if trust.Len() == 0 {
+		return nil, fmt.Errorf("no keys in %s", path)
+	}
+	return trust, nil
+}
+
+// writeOptions returns the planfmt options for writing a contract.
+func (s contractSigning) writeOptions() []planfmt.WriteOption {
+	var opts []planfmt.WriteOption
+	if len(s.keys) > 0 {
+		opts = append(opts, planfmt.WithSigners(s.keys...))
+	}
+	if s.compress {
+		opts = append(opts, planfmt.WithCompression())
+	}
+	return opts
+}
+
+// verifySignatures checks that the contract carries enough trusted
+// signatures. Returns the approvers' names.
+func (s contractSigning) verifySignatures(planFile string, contractHash [32]byte, plan *planfmt.Plan) ([]string, error) {
+	if s.required == 0 {
+		return nil, nil
+	}
+	signers, err := s.trust.Verify(contractHash, plan.Signatures, s.required)
+	if err != nil {
+		return nil, &CLIError{
+			Type:    "contract",
+			Message: fmt.Sprintf("contract %s is not approved", planFile),
+			Details: err.Error(),
+			Hint:    fmt.Sprintf("Approvers co-sign with: opal --plan %s --sign-key <key> > signed.plan", planFile),
+		}
+	}
+	return signers, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+// writeTestKeyPair writes an OpenSSH private key and returns its path and
+// authorized_keys line.
+func writeTestKeyPair(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	block, err := ssh.MarshalPrivateKey(priv, name)
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	line := string(bytes.TrimSpace(ssh.MarshalAuthorizedKey(sshPub))) + " " + name
+	return path, line
+}
+
+func TestContractSigning_KeysAndTrustStore(t *testing.T) {
+	dir := t.TempDir()
+	aliceKey, aliceLine := writeTestKeyPair(t, dir, "alice")
+	_, bobLine := writeTestKeyPair(t, dir, "bob")
+	trusted := filepath.Join(dir, "approvers.pub")
+	require.NoError(t, os.WriteFile(trusted, []byte("# approvers\n"+aliceLine+"\n\n"+bobLine+"\n"), 0o644))
+
+	signing, err := newContractSigning([]string{aliceKey}, trusted, 0)
+	require.NoError(t, err)
+	require.Len(t, signing.keys, 1)
+	assert.Equal(t, 2, signing.trust.Len())
+	assert.Equal(t, 1, signing.required, "--trusted-keys alone requires one signature")
+
+	hash := [32]byte{1, 2, 3}
+	plan := &planfmt.Plan{Signatures: []planfmt.Signature{planfmt.Sign(signing.keys[0], hash)}}
+	signers, err := signing.verifySignatures("deploy.plan", hash, plan)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, signers)
+
+	signing.required = 2
+	_, err = signing.verifySignatures("deploy.plan", hash, plan)
+	var cliErr *CLIError
+	require.ErrorAs(t, err, &cliErr)
+	assert.Equal(t, "contract deploy.plan is not approved", cliErr.Message)
+	assert.Contains(t, cliErr.Details, "needs 2 trusted signature(s), has 1 (alice)")
+}
+
+func TestContractSigning_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := newContractSigning(nil, "", 2)
+	assert.ErrorContains(t, err, "--require-signed needs --trusted-keys")
+
+	_, err = newContractSigning([]string{filepath.Join(dir, "missing")}, "", 0)
+	assert.ErrorContains(t, err, "failed to read signing key")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecPub, err := ssh.NewPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	ecFile := filepath.Join(dir, "ecdsa.pub")
+	require.NoError(t, os.WriteFile(ecFile, ssh.MarshalAuthorizedKey(ecPub), 0o644))
+	_, err = newContractSigning(nil, ecFile, 0)
+	assert.ErrorContains(t, err, "expected ssh-ed25519")
+
+	empty := filepath.Join(dir, "empty.pub")
+	require.NoError(t, os.WriteFile(empty, []byte("# nobody yet\n"), 0o644))
+	_, err = newContractSigning(nil, empty, 0)
+	assert.ErrorContains(t, err, "no keys in")
+
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(ed25519.NewKeyFromSeed(make([]byte, 32)), "", []byte("secret"))
+	require.NoError(t, err)
+	encrypted := filepath.Join(dir, "encrypted")
+	require.NoError(t, os.WriteFile(encrypted, pem.EncodeToMemory(block), 0o600))
+	_, err = newContractSigning([]string{encrypted}, "", 0)
+	assert.ErrorContains(t, err, "is encrypted")
+}
+
+func TestContractSigning_WriteOptions(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	plan := &planfmt.Plan{Target: "deploy"}
+	hash, err := planfmt.Write(&bytes.Buffer{}, plan)
+	require.NoError(t, err)
+
+	signing := contractSigning{keys: []ed25519.PrivateKey{key}, compress: true}
+	var buf bytes.Buffer
+	require.NoError(t, planfmt.WriteContract(&buf, plan.Target, hash, plan, signing.writeOptions()...))
+
+	_, readHash, readPlan, err := planfmt.ReadContract(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, hash, readHash, "compression does not change the contract hash")
+	assert.Len(t, readPlan.Signatures, 1)
+	assert.Empty(t, contractSigning{}.writeOptions())
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/opal-lang/opal/runtime/executor"
+	"github.com/opal-lang/opal/runtime/otlp"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+// traceExportTimeout bounds exporting a run's trace after it ends.
+const traceExportTimeout = 10 * time.Second
+
+// defaultOTLPEndpoint is the --otel-endpoint default: the standard
+// OpenTelemetry environment variables, or "" (no export).
+func defaultOTLPEndpoint() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+}
+
+// runTracing exports a run as an OpenTelemetry trace: a root "run" span
+// with the lex, parse and plan phases, one span per step and decorator
+// spans below. A nil runTracing traces nothing.
+type runTracing struct {
+	exporters []otlp.Exporter
+	tracer    *otlp.Tracer // Set by start
+	root      *otlp.Span
+}
+
+// newRunTracing validates --otel-endpoint and --otel-file. It returns nil
+// if neither is set.
+func newRunTracing(endpoint, file string) (*runTracing, error) {
+	var exporters []otlp.Exporter
+	if endpoint != "" {
+		headers, err := otlp.ParseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+		if err != nil {
+			return nil, &CLIError{Type: "usage", Message: err.Error(), Hint: "Set OTEL_EXPORTER_OTLP_HEADERS as key=value,key=value"}
+		}
+		exporter, err := otlp.NewHTTPExporter(endpoint, headers)
+		if err != nil {
+			return nil, &CLIError{Type: "usage", Message: err.Error(), Hint: "Use the collector's OTLP/HTTP address, e.g. --otel-endpoint http://localhost:4318"}
+		}
+		exporters = append(exporters, exporter)
+	}
+	if file != "" {
+		exporters = append(exporters, otlp.NewFileExporter(file))
+	}
+	if len(exporters) == 0 {
+		return nil, nil
+	}
+	return &runTracing{exporters: exporters}, nil
+}
+
+// start starts the root span. String attributes are scrubbed with vlt's
+// secrets before export.
+func (t *runTracing) start(vlt *vault.Vault) {
+	if t == nil {
+		return
+	}
+	provider := vlt.SecretProvider()
+	t.tracer = otlp.NewTracer(otlp.Config{
+		Exporters: t.exporters,
+		Redact: func(s string) string {
+			scrubbed, err := provider.HandleChunk([]byte(s))
+			if err != nil {
+				return "<redacted>"
+			}
+			return string(scrubbed)
+		},
+	})
+	t.root = t.tracer.Start("run", nil)
+}
+
+// enabled reports whether the run is traced.
+func (t *runTracing) enabled() bool {
+	return t != nil && t.root != nil
+}
+
+// phase starts the span of a pipeline phase ("lex", "parse", "plan").
+func (t *runTracing) phase(name string) decorator.Span {
+	if !t.enabled() {
+		return decorator.NoOpSpan{}
+	}
+	return t.root.Child(name, nil)
+}
+
+// setAttr records an attribute of the run (target, plan hash, ...).
+func (t *runTracing) setAttr(key string, value any) {
+	if t.enabled() {
+		t.root.SetAttr(key, value)
+	}
+}
+
+// attach traces each step under the root span.
+func (t *runTracing) attach(config *executor.Config) {
+	if t.enabled() {
+		config.Trace = t.root
+	}
+}
+
+// finish ends the root span and exports the trace. Failing to export does
+// not fail the run: opal warns.
+func (t *runTracing) finish(exitCode int) {
+	if !t.enabled() {
+		return
+	}
+	t.root.SetAttr("exit_code", exitCode)
+	t.root.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), traceExportTimeout)
+	defer cancel()
+	if err := t.tracer.Flush(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: trace not exported: %v\n", err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/runtime/streamscrub"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+func TestNewRunTracing(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		file     string
+		headers  string
+		wantNil  bool
+		wantErr  string
+	}{
+		{"off", "", "", "", true, ""},
+		{"file", "", "traces.jsonl", "", false, ""},
+		{"endpoint", "http://localhost:4318", "", "Authorization=Bearer%20x", false, ""},
+		{"endpoint without scheme", "localhost:4318", "", "", false, "want an http:// or https:// URL"},
+		{"bad headers", "http://localhost:4318", "", "Authorization", false, "invalid OTLP header #1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", tt.headers)
+			tracing, err := newRunTracing(tt.endpoint, tt.file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (tracing == nil) != tt.wantNil {
+				t.Errorf("tracing = %v, want nil: %v", tracing, tt.wantNil)
+			}
+		})
+	}
+}
+
+// TestRunTracing_File runs a function with --otel-file and checks the
+// exported span tree: run, its phases and steps, decorators below
+func TestRunTracing_File(t *testing.T) {
+	dir := t.TempDir()
+	opalFile := filepath.Join(dir, "commands.opl")
+	source := `var TOKEN = "s3cr3t-value"
+fun deploy {
+    echo "token is @var.TOKEN"
+    @retry(times=2, delay=0s) { sh -c "exit 3" }
+}`
+	if err := os.WriteFile(opalFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	traceFile := filepath.Join(dir, "traces.jsonl")
+
+	tracing, err := newRunTracing("", traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vlt := vault.NewWithPlanKey(make([]byte, 32))
+	opalGen, err := streamscrub.NewOpalPlaceholderGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outputBuf bytes.Buffer
+	scrubber := streamscrub.New(&outputBuf, streamscrub.WithPlaceholderFunc(opalGen.PlaceholderFunc()), streamscrub.WithSecretProvider(vlt.SecretProvider()))
+
+	tracing.start(vlt)
+	exitCode, err := runCommand(runOptions{file: opalFile, noColor: true}, "deploy", targetArgs{}, vlt, scrubber, nil, nil, tracing, &outputBuf)
+	tracing.finish(exitCode)
+	if err != nil || exitCode != 3 {
+		t.Fatalf("exit %d, err %v; want exit 3", exitCode, err)
+	}
+
+	data, err := os.ReadFile(traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("s3cr3t-value")) {
+		t.Fatalf("trace contains the secret: %s", data)
+	}
+
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+					Attributes   []struct {
+						Key   string            `json:"key"`
+						Value map[string]string `json:"value"`
+					} `json:"attributes"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &request); err != nil {
+		t.Fatalf("trace file is not one OTLP/JSON request: %v", err)
+	}
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+
+	// Rebuild "parent/child" paths by name
+	names := map[string]string{}
+	parents := map[string]string{}
+	attrs := map[string]map[string]string{}
+	for _, s := range spans {
+		names[s.SpanID] = s.Name
+		parents[s.SpanID] = s.ParentSpanID
+		attrs[s.SpanID] = map[string]string{}
+		for _, a := range s.Attributes {
+			for _, v := range a.Value {
+				attrs[s.SpanID][a.Key] = v
+			}
+		}
+	}
+	var paths []string
+	var runAttrs map[string]string
+	for id, name := range names {
+		path := name
+		for p := parents[id]; p != ""; p = parents[p] {
+			path = names[p] + "/" + path
+		}
+		paths = append(paths, path)
+		if name == "run" {
+			runAttrs = attrs[id]
+		}
+	}
+	for _, want := range []string{
+		"run", "run/lex", "run/parse", "run/plan", "run/step", "run/step/decorator.shell",
+		"run/step/decorator.retry/retry.attempt/decorator.shell",
+	} {
+		found := false
+		for _, path := range paths {
+			found = found || path == want
+		}
+		if !found {
+			t.Errorf("missing span %s in %v", want, paths)
+		}
+	}
+
+	if runAttrs["opal.target"] != "deploy" || runAttrs["exit_code"] != "3" || len(runAttrs["opal.plan_hash"]) != 64 {
+		t.Errorf("run attributes = %v", runAttrs)
+	}
+}
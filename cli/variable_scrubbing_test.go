@@ -11,7 +11,6 @@ import (
 
 	"github.com/opal-lang/opal/runtime/streamscrub"
 	"github.com/opal-lang/opal/runtime/vault"
-	"github.com/spf13/cobra"
 )
 
 // TestVariableScrubbing_EndToEnd tests the complete CLI→Planner→Scrubber integration.
@@ -53,8 +52,7 @@ echo "The secret is: @var.SECRET"`
 	defer restore()
 
 	// Run command (script mode - no command name)
-	cmd := &cobra.Command{}
-	exitCode, err := runCommand(cmd, "", opalFile, false, false, false, true, false, vlt, scrubber, &outputBuf)
+	exitCode, err := runCommand(runOptions{file: opalFile, noColor: true}, "", targetArgs{}, vlt, scrubber, nil, nil, nil, &outputBuf)
 	if err != nil {
 		t.Fatalf("runCommand failed: %v", err)
 	}
@@ -119,9 +117,7 @@ echo "API: @var.API_KEY, Token: @var.TOKEN, Pass: @var.PASSWORD"`
 
 	// Run command in dry-run mode (plan only, don't execute)
 	// Executor doesn't yet support DisplayID resolution, so we can't execute
-	cmd := &cobra.Command{}
-	dryRun := true
-	exitCode, err := runCommand(cmd, "", opalFile, dryRun, false, false, true, false, vlt, scrubber, &outputBuf)
+	exitCode, err := runCommand(runOptions{file: opalFile, dryRun: true, noColor: true}, "", targetArgs{}, vlt, scrubber, nil, nil, nil, &outputBuf)
 	if err != nil {
 		t.Fatalf("runCommand failed: %v", err)
 	}
@@ -158,3 +154,100 @@ echo "API: @var.API_KEY, Token: @var.TOKEN, Pass: @var.PASSWORD"`
 
 	t.Logf("All %d secrets scrubbed successfully", len(secrets))
 }
+
+// TestVariableScrubbing_LoopInDecoratorBlock tests that loop variables used
+// inside a decorator block resolve at execution time. The executor must
+// derive the same site paths as the planner for unrolled iterations.
+func TestVariableScrubbing_LoopInDecoratorBlock(t *testing.T) {
+	tmpDir := t.TempDir()
+	opalFile := filepath.Join(tmpDir, "loop.opl")
+	outFile := filepath.Join(tmpDir, "out.txt")
+
+	source := `var ITEMS = ["alpha-value", "beta-value"]
+@retry(times=2) {
+    for item in @var.ITEMS {
+        echo "@var.item" >> ` + outFile + `
+    }
+}`
+
+	err := os.WriteFile(opalFile, []byte(source), 0o644)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	planKey := make([]byte, 32)
+	_, err = rand.Read(planKey)
+	if err != nil {
+		t.Fatalf("Failed to generate plan key: %v", err)
+	}
+	vlt := vault.NewWithPlanKey(planKey)
+
+	var outputBuf bytes.Buffer
+	scrubber := streamscrub.New(&outputBuf, streamscrub.WithSecretProvider(vlt.SecretProvider()))
+
+	exitCode, err := runCommand(runOptions{file: opalFile, noColor: true}, "", targetArgs{}, vlt, scrubber, nil, nil, nil, &outputBuf)
+	if err != nil {
+		t.Fatalf("runCommand failed: %v", err)
+	}
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (output: %s)", exitCode, outputBuf.String())
+	}
+
+	// The commands themselves see the real values, in iteration order
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if got := string(data); got != "alpha-value\nbeta-value\n" {
+		t.Errorf("Expected both iterations in order, got %q", got)
+	}
+}
+
+// TestVariableScrubbing_ConditionalBranch tests that variables used inside
+// the taken branch of an if resolve at execution time. Branches share the
+// enclosing scope, so the executor must not add a scope segment for them.
+func TestVariableScrubbing_ConditionalBranch(t *testing.T) {
+	tmpDir := t.TempDir()
+	opalFile := filepath.Join(tmpDir, "if.opl")
+	outFile := filepath.Join(tmpDir, "out.txt")
+
+	source := `var ENV = "prod-value"
+if @var.ENV == "prod-value" {
+    var REGION = "region-value"
+    echo "@var.ENV" >> ` + outFile + `
+} else {
+    echo "wrong branch" >> ` + outFile + `
+}
+echo "@var.REGION" >> ` + outFile
+
+	err := os.WriteFile(opalFile, []byte(source), 0o644)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	planKey := make([]byte, 32)
+	_, err = rand.Read(planKey)
+	if err != nil {
+		t.Fatalf("Failed to generate plan key: %v", err)
+	}
+	vlt := vault.NewWithPlanKey(planKey)
+
+	var outputBuf bytes.Buffer
+	scrubber := streamscrub.New(&outputBuf, streamscrub.WithSecretProvider(vlt.SecretProvider()))
+
+	exitCode, err := runCommand(runOptions{file: opalFile, noColor: true}, "", targetArgs{}, vlt, scrubber, nil, nil, nil, &outputBuf)
+	if err != nil {
+		t.Fatalf("runCommand failed: %v", err)
+	}
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d (output: %s)", exitCode, outputBuf.String())
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if got := string(data); got != "prod-value\nregion-value\n" {
+		t.Errorf("Expected only the taken branch, got %q", got)
+	}
+}
@@ -71,6 +71,9 @@ const (
 
 	// TransportScopeRemote means decorator works in any remote transport (SSH, Docker, etc.)
 	TransportScopeRemote TransportScope = 3
+
+	// TransportScopeDocker means decorator only works in a container transport
+	TransportScopeDocker TransportScope = 4
 )
 
 // String returns the string representation of TransportScope.
@@ -84,6 +87,8 @@ func (s TransportScope) String() string {
 		return "SSH"
 	case TransportScopeRemote:
 		return "Remote"
+	case TransportScopeDocker:
+		return "Docker"
 	default:
 		return "Unknown"
 	}
@@ -98,7 +103,7 @@ func (s TransportScope) Allows(current TransportScope) bool {
 
 	// Remote scope allows any remote transport (SSH, Docker, etc.)
 	if s == TransportScopeRemote {
-		return current == TransportScopeSSH || current == TransportScopeRemote
+		return current == TransportScopeSSH || current == TransportScopeDocker || current == TransportScopeRemote
 	}
 
 	// Otherwise, exact match required
@@ -173,6 +178,13 @@ func NewDescriptor(path string) *DescriptorBuilder {
 	}
 }
 
+// Version sets the decorator version (semver). Plans record the version of
+// every decorator they use, so a change is reported when verifying a contract.
+func (b *DescriptorBuilder) Version(version string) *DescriptorBuilder {
+	b.desc.Version = version
+	return b
+}
+
 // Summary sets the one-line description.
 func (b *DescriptorBuilder) Summary(summary string) *DescriptorBuilder {
 	b.desc.Summary = summary
@@ -194,6 +206,13 @@ func (b *DescriptorBuilder) TransportScope(scope TransportScope) *DescriptorBuil
 	return b
 }
 
+// SwitchesTransport marks the decorator as opening a new transport for its block
+// (e.g., @ssh.connect). Commands and session reads inside the block run there.
+func (b *DescriptorBuilder) SwitchesTransport() *DescriptorBuilder {
+	b.desc.Schema.SwitchesTransport = true
+	return b
+}
+
 // Pure marks the decorator as deterministic (can be cached/constant-folded).
 func (b *DescriptorBuilder) Pure() *DescriptorBuilder {
 	b.desc.Capabilities.Purity = true
@@ -212,6 +231,20 @@ func (b *DescriptorBuilder) Block(req BlockRequirement) *DescriptorBuilder {
 	return b
 }
 
+// ForwardsParams allows parameters not declared in the schema.
+// They are passed on unvalidated (e.g., @cmd forwards them to the called function).
+func (b *DescriptorBuilder) ForwardsParams() *DescriptorBuilder {
+	b.desc.Schema.ForwardsParameters = true
+	return b
+}
+
+// RedirectIn declares the decorator can be read from with < (cmd < @decorator).
+// The decorator provides the reader by implementing sdk.SourceProvider.
+func (b *DescriptorBuilder) RedirectIn() *DescriptorBuilder {
+	b.desc.Schema.RedirectIn = true
+	return b
+}
+
 // Roles sets the decorator roles (auto-inferred by registry, but can be set explicitly).
 func (b *DescriptorBuilder) Roles(roles ...Role) *DescriptorBuilder {
 	b.desc.Roles = roles
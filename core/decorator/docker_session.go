@@ -0,0 +1,345 @@
+package decorator
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opal-lang/opal/core/invariant"
+)
+
+// DockerSession implements Session for commands inside a running container.
+// Every operation shells out to the docker CLI through the host session, so
+// a container on a remote host is reached by nesting @docker.exec inside
+// @ssh.connect. No daemon connection is held open.
+//
+// Canceling a Run stops the local docker client; the process inside the
+// container may keep running (docker exec has no remote kill).
+type DockerSession struct {
+	host      Session           // Session the docker CLI runs in
+	container string            // Container name or ID
+	user      string            // docker exec --user (empty = image default)
+	env       map[string]string // Environment delta (copy-on-write)
+	cwd       string            // Working directory inside the container
+}
+
+// NewDockerSession creates a session for a running container.
+//
+// Parameters:
+//   - container (required): container name or ID
+//   - user: user to run commands as (default: the image's user)
+//
+// The container must exist and be running; its configured working
+// directory becomes the session's working directory.
+func NewDockerSession(host Session, params map[string]any) (*DockerSession, error) {
+	invariant.NotNil(host, "host")
+
+	container, ok := params["container"].(string)
+	if !ok || container == "" {
+		return nil, fmt.Errorf("container parameter required")
+	}
+	user, _ := params["user"].(string)
+
+	s := &DockerSession{
+		host:      host,
+		container: container,
+		user:      user,
+		env:       make(map[string]string),
+	}
+
+	var stdout bytes.Buffer
+	err := s.docker(context.Background(), nil, &stdout,
+		"inspect", "--type", "container", "--format", "{{.State.Running}} {{.Config.WorkingDir}}", container)
+	if err != nil {
+		return nil, err
+	}
+	running, workdir, _ := strings.Cut(strings.TrimSpace(stdout.String()), " ")
+	if running != "true" {
+		return nil, fmt.Errorf("container %s is not running", container)
+	}
+	if workdir == "" {
+		workdir = "/"
+	}
+	s.cwd = workdir
+
+	return s, nil
+}
+
+// Run executes a command in the container with docker exec.
+// Environment values reach docker through its own environment (-e NAME),
+// so they never appear in the docker command line.
+func (s *DockerSession) Run(ctx context.Context, argv []string, opts RunOpts) (Result, error) {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(len(argv) > 0, "argv cannot be empty")
+
+	if ctx.Err() != nil {
+		return Result{ExitCode: -1}, ctx.Err()
+	}
+
+	workdir := s.cwd
+	if opts.Dir != "" {
+		workdir = s.resolve(opts.Dir)
+	}
+
+	args := []string{"docker", "exec"}
+	if opts.Stdin != nil {
+		args = append(args, "--interactive")
+	}
+	if s.user != "" {
+		args = append(args, "--user", s.user)
+	}
+	args = append(args, "--workdir", workdir)
+	for _, name := range sortedKeys(s.env) {
+		args = append(args, "--env", name)
+	}
+	args = append(args, s.container)
+	args = append(args, argv...)
+
+	host := s.host
+	if len(s.env) > 0 {
+		host = host.WithEnv(s.env)
+	}
+	return host.Run(ctx, args, RunOpts{Stdin: opts.Stdin, Stdout: opts.Stdout, Stderr: opts.Stderr})
+}
+
+// Put writes data to a file in the container.
+// The file is streamed to docker cp as a single-entry tar archive, so the
+// container needs no shell; the parent directory must exist.
+func (s *DockerSession) Put(ctx context.Context, data []byte, p string, mode fs.FileMode) error {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(p != "", "path cannot be empty")
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	target := s.resolve(p)
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Base(target),
+		Mode:     int64(mode.Perm()),
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return s.docker(ctx, &archive, nil, "cp", "-", s.container+":"+path.Dir(target))
+}
+
+// Get reads a file from the container.
+// docker cp streams it back as a tar archive; symlinks are followed.
+func (s *DockerSession) Get(ctx context.Context, p string) ([]byte, error) {
+	invariant.NotNil(ctx, "ctx")
+	invariant.Precondition(p != "", "path cannot be empty")
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	target := s.resolve(p)
+	var archive bytes.Buffer
+	if err := s.docker(ctx, nil, &archive, "cp", "--follow-link", s.container+":"+target, "-"); err != nil {
+		return nil, err
+	}
+
+	tr := tar.NewReader(&archive)
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("docker cp %s: reading archive: %w", target, err)
+	}
+	if header.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("%s in container %s is not a regular file", target, s.container)
+	}
+	return io.ReadAll(tr)
+}
+
+// Env returns the container's environment with the session's delta applied.
+func (s *DockerSession) Env() map[string]string {
+	result, err := s.Run(context.Background(), []string{"env"}, RunOpts{})
+	if err != nil || result.ExitCode != 0 {
+		env := make(map[string]string, len(s.env))
+		for k, v := range s.env {
+			env[k] = v
+		}
+		return env
+	}
+
+	env := parseEnv(string(result.Stdout))
+	for k, v := range s.env {
+		env[k] = v
+	}
+	return env
+}
+
+// WithEnv returns a new Session with environment delta applied (copy-on-write).
+func (s *DockerSession) WithEnv(delta map[string]string) Session {
+	clone := s.clone()
+	for k, v := range delta {
+		clone.env[k] = v
+	}
+	return clone
+}
+
+// WithWorkdir returns a new Session with the working directory changed.
+// Relative paths are joined onto the current working directory.
+func (s *DockerSession) WithWorkdir(dir string) Session {
+	invariant.Precondition(dir != "", "dir cannot be empty")
+	clone := s.clone()
+	clone.cwd = s.resolve(dir)
+	return clone
+}
+
+// Cwd returns the working directory inside the container.
+func (s *DockerSession) Cwd() string {
+	return s.cwd
+}
+
+// ID returns the session identifier for container sessions.
+// Format: "docker:container", prefixed by the host session's ID when the
+// docker CLI runs remotely ("ssh:web1/docker:app").
+func (s *DockerSession) ID() string {
+	if id := s.host.ID(); id != "local" {
+		return id + "/docker:" + s.container
+	}
+	return "docker:" + s.container
+}
+
+// TransportScope returns the transport scope for container sessions.
+func (s *DockerSession) TransportScope() TransportScope {
+	return TransportScopeDocker
+}
+
+// Close is a no-op: each operation is its own docker invocation.
+func (s *DockerSession) Close() error {
+	return nil
+}
+
+// clone returns a copy with its own env map.
+func (s *DockerSession) clone() *DockerSession {
+	clone := *s
+	clone.env = make(map[string]string, len(s.env))
+	for k, v := range s.env {
+		clone.env[k] = v
+	}
+	return &clone
+}
+
+// resolve joins a relative container path onto the working directory.
+func (s *DockerSession) resolve(p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(s.cwd, p)
+}
+
+// docker runs a docker CLI command in the host session.
+// A non-zero exit becomes an error carrying docker's stderr.
+func (s *DockerSession) docker(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) error {
+	var stderr bytes.Buffer
+	opts := RunOpts{Stdin: stdin, Stdout: stdout, Stderr: &stderr}
+	if stdout == nil {
+		opts.Stdout = io.Discard
+	}
+
+	result, err := s.host.Run(ctx, append([]string{"docker"}, args...), opts)
+	if err != nil {
+		if errors.Is(err, ctx.Err()) {
+			return err
+		}
+		return fmt.Errorf("docker %s: %w", args[0], err)
+	}
+	if result.ExitCode != 0 {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = fmt.Sprintf("exit status %d", result.ExitCode)
+		}
+		return fmt.Errorf("docker %s: %s", args[0], msg)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DockerTransport implements the @docker.exec transport decorator.
+// Runs its block inside a running container.
+type DockerTransport struct{}
+
+// Descriptor returns the decorator metadata.
+func (t *DockerTransport) Descriptor() Descriptor {
+	return NewDescriptor("docker.exec").
+		Version("1.0.0").
+		Summary("Execute block inside a running container").
+		Roles(RoleBoundary).
+		ParamString("container", "Container name or ID").
+		Required().
+		MinLength(1).
+		Examples("app", "opal-build-1").
+		Done().
+		ParamString("user", "User to run commands as (defaults to the image's user)").
+		Examples("root", "1000:1000").
+		Done().
+		ParamObject("env", "Environment variables set for commands in the block").
+		AllowAdditionalProperties().
+		Done().
+		TransportScope(TransportScopeAny).
+		SwitchesTransport().
+		Idempotent().
+		Block(BlockRequired).
+		Build()
+}
+
+// Open checks the container is running. The docker CLI runs in parent, so
+// nesting inside @ssh.connect reaches the remote host's containers.
+func (t *DockerTransport) Open(parent Session, params map[string]any) (Session, error) {
+	return NewDockerSession(parent, params)
+}
+
+// Wrap implements the Exec interface.
+// Like @ssh.connect, the executor passes the opened session in ctx.Session.
+func (t *DockerTransport) Wrap(next ExecNode, params map[string]any) ExecNode {
+	return &dockerNode{next: next, env: EnvParams(params)}
+}
+
+// dockerNode runs a block in a container session.
+type dockerNode struct {
+	next ExecNode
+	env  map[string]string
+}
+
+// Execute implements the ExecNode interface.
+func (n *dockerNode) Execute(ctx ExecContext) (Result, error) {
+	invariant.NotNil(ctx.Session, "ctx.Session")
+	if n.next == nil {
+		return Result{ExitCode: ExitFailure}, fmt.Errorf("@docker.exec requires a block to execute")
+	}
+	if len(n.env) > 0 {
+		ctx.Session = ctx.Session.WithEnv(n.env)
+	}
+	return n.next.Execute(ctx)
+}
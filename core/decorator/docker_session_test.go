@@ -0,0 +1,235 @@
+package decorator
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestDockerSession installs the fake docker CLI and opens a session on
+// container "app" whose working directory is dir.
+func newTestDockerSession(t *testing.T, dir string) (*DockerSession, *FakeDocker) {
+	t.Helper()
+	fake := InstallFakeDocker(t, dir, "app")
+	session, err := NewDockerSession(NewLocalSession(), map[string]any{"container": "app"})
+	if err != nil {
+		t.Fatalf("NewDockerSession failed: %v", err)
+	}
+	return session, fake
+}
+
+// TestDockerSessionRun verifies commands run through docker exec in the
+// container's working directory
+func TestDockerSessionRun(t *testing.T) {
+	dir := t.TempDir()
+	session, fake := newTestDockerSession(t, dir)
+
+	result, err := session.Run(context.Background(), []string{"sh", "-c", "pwd; exit 3"}, RunOpts{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode: got %d, want 3", result.ExitCode)
+	}
+	if got := strings.TrimSpace(string(result.Stdout)); got != dir {
+		t.Errorf("pwd: got %q, want %q", got, dir)
+	}
+
+	calls := fake.Calls()
+	want := "exec --workdir " + dir + " app sh -c pwd; exit 3"
+	if calls[len(calls)-1] != want {
+		t.Errorf("docker call: got %q, want %q", calls[len(calls)-1], want)
+	}
+}
+
+// TestDockerSessionRunWithStdin verifies stdin is streamed with --interactive
+func TestDockerSessionRunWithStdin(t *testing.T) {
+	session, fake := newTestDockerSession(t, t.TempDir())
+
+	result, err := session.Run(context.Background(), []string{"cat"}, RunOpts{Stdin: strings.NewReader("hello")})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if string(result.Stdout) != "hello" {
+		t.Errorf("Stdout: got %q, want %q", result.Stdout, "hello")
+	}
+	calls := fake.Calls()
+	if !strings.HasPrefix(calls[len(calls)-1], "exec --interactive ") {
+		t.Errorf("Expected --interactive, got %q", calls[len(calls)-1])
+	}
+}
+
+// TestDockerSessionWithEnv verifies env values reach the container without
+// appearing on the docker command line
+func TestDockerSessionWithEnv(t *testing.T) {
+	session, fake := newTestDockerSession(t, t.TempDir())
+	withEnv := session.WithEnv(map[string]string{"API_TOKEN": "s3cr3t"})
+
+	result, err := withEnv.Run(context.Background(), []string{"sh", "-c", "echo $API_TOKEN"}, RunOpts{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := strings.TrimSpace(string(result.Stdout)); got != "s3cr3t" {
+		t.Errorf("API_TOKEN: got %q, want %q", got, "s3cr3t")
+	}
+	if got := withEnv.Env()["API_TOKEN"]; got != "s3cr3t" {
+		t.Errorf("Env()[API_TOKEN]: got %q, want %q", got, "s3cr3t")
+	}
+	for _, call := range fake.Calls() {
+		if strings.Contains(call, "s3cr3t") {
+			t.Errorf("Value leaked into docker arguments: %q", call)
+		}
+	}
+
+	// Original session is unchanged
+	if _, ok := session.env["API_TOKEN"]; ok {
+		t.Error("WithEnv modified the original session")
+	}
+}
+
+// TestDockerSessionWithWorkdir verifies relative workdirs join onto the
+// current one and drive Put/Get path resolution
+func TestDockerSessionWithWorkdir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	session, _ := newTestDockerSession(t, dir)
+
+	sub := session.WithWorkdir("sub")
+	if sub.Cwd() != filepath.Join(dir, "sub") {
+		t.Errorf("Cwd: got %q, want %q", sub.Cwd(), filepath.Join(dir, "sub"))
+	}
+	if session.Cwd() != dir {
+		t.Errorf("WithWorkdir modified the original session: %q", session.Cwd())
+	}
+
+	ctx := context.Background()
+	if err := sub.Put(ctx, []byte("data"), "file.txt", 0o640); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "sub", "file.txt"))
+	if err != nil {
+		t.Fatalf("Put did not write into workdir: %v", err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("Mode: got %v, want %v", info.Mode().Perm(), os.FileMode(0o640))
+	}
+
+	data, err := sub.Get(ctx, "file.txt")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(data) != "data" {
+		t.Errorf("Get: got %q, want %q", data, "data")
+	}
+}
+
+// TestDockerSessionGetErrors verifies missing files and directories fail
+func TestDockerSessionGetErrors(t *testing.T) {
+	dir := t.TempDir()
+	session, _ := newTestDockerSession(t, dir)
+
+	if _, err := session.Get(context.Background(), "missing.txt"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	if _, err := session.Get(context.Background(), dir); err == nil || !strings.Contains(err.Error(), "not a regular file") {
+		t.Errorf("Expected regular file error, got %v", err)
+	}
+}
+
+// TestDockerSessionOpenErrors verifies missing and stopped containers fail
+// when the session is opened
+func TestDockerSessionOpenErrors(t *testing.T) {
+	fake := InstallFakeDocker(t, "/", "app", "stopped")
+	fake.Stop(t, "stopped")
+
+	tests := []struct {
+		name    string
+		params  map[string]any
+		wantErr string
+	}{
+		{"no container", map[string]any{}, "container parameter required"},
+		{"missing", map[string]any{"container": "nope"}, "No such container: nope"},
+		{"stopped", map[string]any{"container": "stopped"}, "container stopped is not running"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDockerSession(NewLocalSession(), tt.params)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestDockerSessionNoDockerCLI verifies a missing docker binary is reported
+func TestDockerSessionNoDockerCLI(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, err := NewDockerSession(NewLocalSession(), map[string]any{"container": "app"})
+	if err == nil || !strings.Contains(err.Error(), "docker inspect") {
+		t.Errorf("Expected docker inspect error, got %v", err)
+	}
+}
+
+// TestDockerSessionIDAndScope verifies container sessions are their own
+// transport, scoped under a remote host session
+func TestDockerSessionIDAndScope(t *testing.T) {
+	session, _ := newTestDockerSession(t, "/")
+	if session.ID() != "docker:app" {
+		t.Errorf("ID: got %q, want %q", session.ID(), "docker:app")
+	}
+	if session.TransportScope() != TransportScopeDocker {
+		t.Errorf("TransportScope: got %v, want Docker", session.TransportScope())
+	}
+
+	nested := &DockerSession{host: &mockSession{}, container: "app"}
+	if nested.ID() != "mock/docker:app" {
+		t.Errorf("nested ID: got %q, want %q", nested.ID(), "mock/docker:app")
+	}
+}
+
+// TestDockerTransportWrapAppliesEnv verifies env={...} overrides reach the block
+func TestDockerTransportWrapAppliesEnv(t *testing.T) {
+	InstallFakeDocker(t, t.TempDir(), "app")
+
+	params := map[string]any{"container": "app", "env.DEPLOY_ENV": "staging"}
+	transport := &DockerTransport{}
+	session, err := transport.Open(NewLocalSession(), params)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	var stdout bytes.Buffer
+	block := &testNode{fn: func(ctx ExecContext) (Result, error) {
+		return ctx.Session.Run(ctx.Context, []string{"sh", "-c", "echo $DEPLOY_ENV"}, RunOpts{Stdout: &stdout})
+	}}
+	result, err := transport.Wrap(block, params).Execute(ExecContext{
+		Context: context.Background(),
+		Session: session,
+	})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Execute failed: exit=%d err=%v", result.ExitCode, err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "staging" {
+		t.Errorf("DEPLOY_ENV: got %q, want %q", got, "staging")
+	}
+}
+
+// TestDockerTransportDescriptor verifies @docker.exec is a transport boundary
+func TestDockerTransportDescriptor(t *testing.T) {
+	desc := (&DockerTransport{}).Descriptor()
+	if desc.Path != "docker.exec" {
+		t.Errorf("Path: got %q, want %q", desc.Path, "docker.exec")
+	}
+	if !desc.Schema.SwitchesTransport {
+		t.Error("Expected SwitchesTransport")
+	}
+	if !desc.Schema.Parameters["container"].Required {
+		t.Error("Expected container to be required")
+	}
+}
@@ -0,0 +1,112 @@
+package decorator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDockerScript stands in for the docker CLI. "Containers" share the
+// host filesystem: exec runs the command locally and cp moves tar archives
+// with the host's tar. Every invocation's arguments are appended to
+// $FAKE_DOCKER_LOG.
+const fakeDockerScript = `#!/bin/sh
+printf '%s\n' "$*" >> "$FAKE_DOCKER_LOG"
+
+check() {
+	for c in $FAKE_DOCKER_CONTAINERS; do
+		[ "$c" = "$1" ] && return 0
+	done
+	echo "Error response from daemon: No such container: $1" >&2
+	exit 1
+}
+
+cmd=$1
+shift
+case $cmd in
+inspect)
+	for name; do :; done
+	check "$name"
+	running=true
+	for c in $FAKE_DOCKER_STOPPED; do
+		[ "$c" = "$name" ] && running=false
+	done
+	echo "$running $FAKE_DOCKER_WORKDIR"
+	;;
+exec)
+	while [ $# -gt 0 ]; do
+		case $1 in
+		--interactive) shift ;;
+		--user|--env) shift 2 ;;
+		--workdir) cd "$2" || exit 126; shift 2 ;;
+		-*) echo "unknown flag: $1" >&2; exit 125 ;;
+		*) break ;;
+		esac
+	done
+	check "$1"
+	shift
+	exec "$@"
+	;;
+cp)
+	follow=
+	if [ "$1" = "--follow-link" ]; then follow=-h; shift; fi
+	if [ "$1" = "-" ]; then
+		check "${2%%:*}"
+		exec tar -x -C "${2#*:}"
+	fi
+	check "${1%%:*}"
+	src=${1#*:}
+	exec tar $follow -c -C "$(dirname "$src")" "$(basename "$src")"
+	;;
+*)
+	echo "unknown command: $cmd" >&2
+	exit 1
+	;;
+esac
+`
+
+// FakeDocker is a docker CLI stand-in installed on PATH for a test.
+type FakeDocker struct {
+	logPath string
+}
+
+// InstallFakeDocker puts a fake docker binary first on PATH for the rest of
+// the test. The listed containers exist and are running; their working
+// directory is workdir. Skips the test where no POSIX shell is available.
+func InstallFakeDocker(t *testing.T, workdir string, containers ...string) *FakeDocker {
+	t.Helper()
+
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("fake docker needs /bin/sh")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(fakeDockerScript), 0o755); err != nil {
+		t.Fatalf("failed to write fake docker: %v", err)
+	}
+
+	logPath := filepath.Join(dir, "docker.log")
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_DOCKER_LOG", logPath)
+	t.Setenv("FAKE_DOCKER_CONTAINERS", strings.Join(containers, " "))
+	t.Setenv("FAKE_DOCKER_STOPPED", "")
+	t.Setenv("FAKE_DOCKER_WORKDIR", workdir)
+
+	return &FakeDocker{logPath: logPath}
+}
+
+// Stop marks a container as existing but not running.
+func (d *FakeDocker) Stop(t *testing.T, container string) {
+	t.Helper()
+	t.Setenv("FAKE_DOCKER_STOPPED", strings.TrimSpace(os.Getenv("FAKE_DOCKER_STOPPED")+" "+container))
+}
+
+// Calls returns the arguments of each docker invocation so far.
+func (d *FakeDocker) Calls() []string {
+	data, err := os.ReadFile(d.logPath)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
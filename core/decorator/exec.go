@@ -18,6 +18,15 @@ type ExecNode interface {
 	Execute(ctx ExecContext) (Result, error)
 }
 
+// BlockNode is an ExecNode backed by a decorator block.
+// Branches splits the block into one node per top-level step, so decorators
+// like @parallel can schedule steps independently. Executing a BlockNode
+// directly runs the steps in order, stopping at the first failure.
+type BlockNode interface {
+	ExecNode
+	Branches() []ExecNode
+}
+
 // ExecContext provides the execution context for command execution.
 type ExecContext struct {
 	// Context is the parent context for cancellation and deadlines
@@ -44,4 +53,8 @@ type ExecContext struct {
 	// Opal runtime creates parent span automatically
 	// Decorators can create child spans for internal tracking
 	Trace Span
+
+	// Color reports whether decorators may write ANSI color codes
+	// False under --no-color or NO_COLOR
+	Color bool
 }
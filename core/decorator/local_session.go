@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/opal-lang/opal/core/invariant"
 )
@@ -53,11 +54,14 @@ func (s *LocalSession) Run(ctx context.Context, argv []string, opts RunOpts) (Re
 
 	// CRITICAL: Set process group for proper cancellation
 	// On Unix: Setpgid=true creates new process group
-	// We manually kill the entire group on cancellation below
+	// We manually terminate the entire group on cancellation below
 	if runtime.GOOS != osWindows {
 		cmd.SysProcAttr = &syscall.SysProcAttr{
 			Setpgid: true,
 		}
+		// Don't let os/exec SIGKILL the leader on its own - that would
+		// skip the SIGTERM grace period in TerminateProcessGroup
+		cmd.Cancel = func() error { return nil }
 	}
 
 	// Wire up I/O
@@ -90,11 +94,9 @@ func (s *LocalSession) Run(ctx context.Context, argv []string, opts RunOpts) (Re
 
 	select {
 	case <-ctx.Done():
-		// Context canceled - kill entire process group
+		// Context canceled - terminate entire process group
 		if runtime.GOOS != osWindows && cmd.Process != nil {
-			// Send SIGKILL to process group (negative PID)
-			// This kills the parent and all children in the group
-			_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			TerminateProcessGroup(cmd.Process.Pid, GracePeriod(ctx), done)
 		}
 		// Wait for process to actually exit
 		<-done
@@ -120,6 +122,25 @@ func (s *LocalSession) Run(ctx context.Context, argv []string, opts RunOpts) (Re
 	}
 }
 
+// TerminateProcessGroup stops the process group led by pid, where done
+// receives the leader's wait result. With a grace period, the group gets
+// SIGTERM first; SIGKILL (negative PID = whole group) follows when grace
+// expires or the leader exits, so children that ignore SIGTERM don't
+// outlive the command. The exit status is left on done for the caller.
+func TerminateProcessGroup(pid int, grace time.Duration, done chan error) {
+	if grace > 0 {
+		_ = syscall.Kill(-pid, syscall.SIGTERM)
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case err := <-done:
+			done <- err // Put it back for the caller
+		case <-timer.C:
+		}
+	}
+	_ = syscall.Kill(-pid, syscall.SIGKILL)
+}
+
 // Put writes data to a file on the local filesystem.
 // Context controls cancellation (though file writes are typically fast).
 func (s *LocalSession) Put(ctx context.Context, data []byte, path string, mode fs.FileMode) error {
@@ -427,3 +427,68 @@ func TestLocalSessionKillsProcessGroupOnCancel(t *testing.T) {
 	// Note: Verifying no zombie processes is platform-specific and hard to test reliably
 	// The Setpgid=true ensures the entire process group is killed
 }
+
+// TestLocalSessionGracefulTermination verifies SIGTERM is sent first when the
+// context carries a grace period, giving the process a chance to clean up
+func TestLocalSessionGracefulTermination(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Signals not supported on Windows")
+	}
+
+	session := NewLocalSession()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = WithGracePeriod(ctx, 5*time.Second)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+
+	var stdout bytes.Buffer
+	start := time.Now()
+	result, err := session.Run(ctx, []string{"bash", "-c", "trap 'echo cleanup; exit 0' TERM; sleep 10 & wait"}, RunOpts{Stdout: &stdout})
+	duration := time.Since(start)
+
+	if err == nil {
+		t.Error("Expected error due to context cancellation")
+	}
+	if result.ExitCode != ExitCanceled {
+		t.Errorf("ExitCode: got %d, want %d (ExitCanceled)", result.ExitCode, ExitCanceled)
+	}
+	if !strings.Contains(stdout.String(), "cleanup") {
+		t.Errorf("Expected TERM handler to run, stdout: %q", stdout.String())
+	}
+	// Exited on SIGTERM - must not wait for the grace period
+	if duration > 2*time.Second {
+		t.Errorf("Duration: got %v, want < 2s", duration)
+	}
+}
+
+// TestLocalSessionEscalatesToSIGKILL verifies processes ignoring SIGTERM are
+// killed once the grace period expires
+func TestLocalSessionEscalatesToSIGKILL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Signals not supported on Windows")
+	}
+
+	session := NewLocalSession()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	ctx = WithGracePeriod(ctx, 300*time.Millisecond)
+
+	start := time.Now()
+	result, _ := session.Run(ctx, []string{"bash", "-c", "trap '' TERM; sleep 10"}, RunOpts{})
+	duration := time.Since(start)
+
+	if result.ExitCode != ExitCanceled {
+		t.Errorf("ExitCode: got %d, want %d (ExitCanceled)", result.ExitCode, ExitCanceled)
+	}
+	if duration < 300*time.Millisecond {
+		t.Errorf("Duration: got %v, want >= grace period (SIGTERM should be ignored)", duration)
+	}
+	if duration > 3*time.Second {
+		t.Errorf("Duration: got %v, want < 3s (SIGKILL after grace)", duration)
+	}
+}
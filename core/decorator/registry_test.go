@@ -483,6 +483,8 @@ func TestTransportScopeAllows(t *testing.T) {
 
 		// TransportScopeRemote allows any remote (SSH, Docker, etc.)
 		{"Remote allows SSH", TransportScopeRemote, TransportScopeSSH, true},
+		{"Remote allows Docker", TransportScopeRemote, TransportScopeDocker, true},
+		{"SSH denies Docker", TransportScopeSSH, TransportScopeDocker, false},
 		{"Remote denies Local", TransportScopeRemote, TransportScopeLocal, false},
 	}
 
@@ -27,7 +27,8 @@ func NewSessionPool() *SessionPool {
 }
 
 // GetOrCreate returns an existing session or creates a new one.
-// Sessions are keyed by transport name and params hash.
+// Sessions are keyed by parent session, transport name and params hash, so
+// the same container name on two hosts gets two sessions.
 //
 // Thread-safe: Multiple goroutines can call this concurrently.
 func (p *SessionPool) GetOrCreate(
@@ -36,7 +37,11 @@ func (p *SessionPool) GetOrCreate(
 	params map[string]any,
 ) (Session, error) {
 	// Create deterministic key
-	key := sessionKey(transport.Descriptor().Path, params)
+	name := transport.Descriptor().Path
+	if parent != nil {
+		name = parent.ID() + "/" + name
+	}
+	key := sessionKey(name, params)
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
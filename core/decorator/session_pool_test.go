@@ -197,6 +197,25 @@ func TestSessionKeyDifferentParams(t *testing.T) {
 	}
 }
 
+// TestSessionPoolKeyedByParent verifies the same params under different
+// parent sessions open separate sessions (one container name, two hosts)
+func TestSessionPoolKeyedByParent(t *testing.T) {
+	pool := NewSessionPool()
+	transport := &mockTransport{}
+	params := map[string]any{"container": "app"}
+
+	if _, err := pool.GetOrCreate(transport, NewLocalSession(), params); err != nil {
+		t.Fatalf("GetOrCreate failed: %v", err)
+	}
+	if _, err := pool.GetOrCreate(transport, &mockSession{}, params); err != nil {
+		t.Fatalf("GetOrCreate failed: %v", err)
+	}
+
+	if transport.openCount != 2 {
+		t.Errorf("Expected 2 opens, got %d", transport.openCount)
+	}
+}
+
 // Mock implementations for testing
 
 type mockTransport struct {
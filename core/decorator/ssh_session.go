@@ -3,15 +3,21 @@ package decorator
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
 
 	"github.com/opal-lang/opal/core/invariant"
 )
@@ -20,68 +26,100 @@ import (
 type SSHSession struct {
 	client *ssh.Client
 	host   string
+	agent  io.Closer // ssh-agent connection used for auth (nil if a key was given)
 }
 
+// defaultSSHTimeout bounds connecting and the SSH handshake when no
+// timeout parameter is given.
+const defaultSSHTimeout = 30 * time.Second
+
 // NewSSHSession creates a new SSH session from connection parameters.
+//
+// Parameters:
+//   - host (required), user (default $USER), port (default 22)
+//   - key: private key file, or an ssh.Signer; falls back to ssh-agent
+//   - known_hosts_path (default ~/.ssh/known_hosts)
+//   - strict_host_key: false skips host key verification (testing only)
+//   - timeout: limit for connecting and the handshake (default 30s)
+//
+// The host key must be listed in known_hosts unless strict_host_key is false.
 func NewSSHSession(params map[string]any) (*SSHSession, error) {
 	host, ok := params["host"].(string)
-	if !ok {
+	if !ok || host == "" {
 		return nil, fmt.Errorf("host parameter required")
 	}
 
 	user, ok := params["user"].(string)
-	if !ok {
+	if !ok || user == "" {
 		user = os.Getenv("USER")
 	}
 
-	port, ok := params["port"].(int)
-	if !ok {
-		port = 22
+	port, err := sshPort(params["port"])
+	if err != nil {
+		return nil, err
 	}
 
-	// Create SSH client config
-	var authMethods []ssh.AuthMethod
-
-	// Try direct signer first (for testing)
-	switch key := params["key"].(type) {
-	case ssh.Signer:
-		authMethods = append(authMethods, ssh.PublicKeys(key))
-	case string:
-		// Try keyfile auth if string path provided
-		if keyAuth := sshKeyAuth(key); keyAuth != nil {
-			authMethods = append(authMethods, keyAuth)
-		}
+	timeout, err := sshTimeout(params["timeout"])
+	if err != nil {
+		return nil, err
 	}
 
-	// Fall back to SSH agent
-	if len(authMethods) == 0 {
-		if agentAuth := sshAgentAuth(); agentAuth != nil {
-			authMethods = append(authMethods, agentAuth)
-		}
+	hostKeyCallback, err := getHostKeyCallback(params)
+	if err != nil {
+		return nil, err
 	}
 
-	// Host key verification
-	hostKeyCallback := getHostKeyCallback(params)
+	authMethods, agentConn, err := sshAuthMethods(params["key"])
+	if err != nil {
+		return nil, err
+	}
 
 	config := &ssh.ClientConfig{
 		User:            user,
 		Auth:            authMethods,
 		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
 	}
 
-	// Connect
-	addr := fmt.Sprintf("%s:%d", host, port)
-	client, err := ssh.Dial("tcp", addr, config)
+	client, err := dialSSH(net.JoinHostPort(host, strconv.Itoa(port)), config)
 	if err != nil {
-		return nil, fmt.Errorf("ssh dial failed: %w", err)
+		if agentConn != nil {
+			_ = agentConn.Close()
+		}
+		return nil, err
 	}
 
 	return &SSHSession{
 		client: client,
 		host:   host,
+		agent:  agentConn,
 	}, nil
 }
 
+// dialSSH connects and completes the SSH handshake within config.Timeout.
+// ssh.Dial only bounds the TCP connect, so a server that accepts but never
+// answers would otherwise hang the run.
+func dialSSH(addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("ssh dial failed: %w", err)
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(config.Timeout))
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		_ = conn.Close()
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("ssh handshake with %s timed out after %v", addr, config.Timeout)
+		}
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", addr, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
 // Run executes a command on the remote host.
 func (s *SSHSession) Run(ctx context.Context, argv []string, opts RunOpts) (Result, error) {
 	invariant.NotNil(ctx, "ctx")
@@ -131,7 +169,7 @@ func (s *SSHSession) Run(ctx context.Context, argv []string, opts RunOpts) (Resu
 
 	select {
 	case <-ctx.Done():
-		_ = session.Signal(ssh.SIGKILL) // Best effort kill
+		terminateRemote(session, GracePeriod(ctx), done) // Best effort
 		return Result{ExitCode: -1}, ctx.Err()
 	case err := <-done:
 		exitCode := 0
@@ -150,6 +188,22 @@ func (s *SSHSession) Run(ctx context.Context, argv []string, opts RunOpts) (Resu
 	}
 }
 
+// terminateRemote stops a remote command: SIGTERM first when a grace period
+// is set, SIGKILL if it is still running once grace expires.
+func terminateRemote(session *ssh.Session, grace time.Duration, done chan error) {
+	if grace > 0 {
+		_ = session.Signal(ssh.SIGTERM)
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-done:
+			return
+		case <-timer.C:
+		}
+	}
+	_ = session.Signal(ssh.SIGKILL)
+}
+
 // Put writes data to a file on the remote host.
 func (s *SSHSession) Put(ctx context.Context, data []byte, path string, mode fs.FileMode) error {
 	invariant.NotNil(ctx, "ctx")
@@ -267,9 +321,13 @@ func (s *SSHSession) TransportScope() TransportScope {
 	return TransportScopeSSH
 }
 
-// Close closes the SSH connection.
+// Close closes the SSH connection and the ssh-agent connection, if any.
 func (s *SSHSession) Close() error {
-	return s.client.Close()
+	err := s.client.Close()
+	if s.agent != nil {
+		_ = s.agent.Close()
+	}
+	return err
 }
 
 // SSHSessionWithEnv wraps SSHSession to inject environment variables and working directory.
@@ -335,7 +393,7 @@ func (s *SSHSessionWithEnv) Run(ctx context.Context, argv []string, opts RunOpts
 
 	select {
 	case <-ctx.Done():
-		_ = session.Signal(ssh.SIGKILL) // Best effort kill
+		terminateRemote(session, GracePeriod(ctx), done) // Best effort
 		return Result{ExitCode: -1}, ctx.Err()
 	case err := <-done:
 		exitCode := 0
@@ -354,12 +412,24 @@ func (s *SSHSessionWithEnv) Run(ctx context.Context, argv []string, opts RunOpts
 	}
 }
 
+// Put writes data to a file on the remote host.
+// Relative paths are resolved against the session's working directory.
 func (s *SSHSessionWithEnv) Put(ctx context.Context, data []byte, path string, mode fs.FileMode) error {
-	return s.base.Put(ctx, data, path, mode)
+	return s.base.Put(ctx, data, s.resolve(path), mode)
 }
 
+// Get reads a file from the remote host.
+// Relative paths are resolved against the session's working directory.
 func (s *SSHSessionWithEnv) Get(ctx context.Context, path string) ([]byte, error) {
-	return s.base.Get(ctx, path)
+	return s.base.Get(ctx, s.resolve(path))
+}
+
+// resolve joins a relative remote path onto the session's working directory.
+func (s *SSHSessionWithEnv) resolve(p string) string {
+	if s.cwd == "" || path.IsAbs(p) {
+		return p
+	}
+	return path.Join(s.cwd, p)
 }
 
 func (s *SSHSessionWithEnv) Env() map[string]string {
@@ -387,12 +457,14 @@ func (s *SSHSessionWithEnv) WithEnv(delta map[string]string) Session {
 	}
 }
 
+// WithWorkdir returns a new Session with the working directory changed.
+// Relative paths are joined onto the current working directory.
 func (s *SSHSessionWithEnv) WithWorkdir(dir string) Session {
 	invariant.Precondition(dir != "", "dir cannot be empty")
 	return &SSHSessionWithEnv{
 		base:  s.base,
 		delta: s.delta,
-		cwd:   dir,
+		cwd:   s.resolve(dir),
 	}
 }
 
@@ -413,142 +485,243 @@ func (s *SSHSessionWithEnv) TransportScope() TransportScope {
 	return s.base.TransportScope()
 }
 
+// Close is a no-op: the connection belongs to the base session, which may
+// be shared through a SessionPool.
 func (s *SSHSessionWithEnv) Close() error {
-	return s.base.Close()
+	return nil
 }
 
-// SSHTransport implements Transport for SSH connections.
+// SSHTransport implements the @ssh.connect transport decorator.
+// Opens an SSH connection and runs its block on the remote host.
 type SSHTransport struct{}
 
+// Descriptor returns the decorator metadata.
 func (t *SSHTransport) Descriptor() Descriptor {
-	return Descriptor{
-		Path: "ssh",
-	}
-}
-
+	return NewDescriptor("ssh.connect").
+		Version("1.0.0").
+		Summary("Execute block on a remote host over SSH").
+		Roles(RoleBoundary).
+		ParamString("host", "Remote host name or address").
+		Required().
+		MinLength(1).
+		Examples("web1.example.com", "10.0.0.5").
+		Done().
+		ParamString("user", "Remote user (defaults to $USER)").
+		Examples("deploy").
+		Done().
+		ParamInt("port", "SSH port").
+		Default(22).
+		Min(1).
+		Max(65535).
+		Done().
+		ParamString("key", "Private key file (uses ssh-agent when omitted)").
+		Examples("~/.ssh/id_ed25519").
+		Done().
+		ParamString("known_hosts_path", "known_hosts file used to verify the host key").
+		Examples("~/.ssh/known_hosts").
+		Done().
+		ParamBool("strict_host_key", "Verify the host key against known_hosts").
+		Default(true).
+		Done().
+		ParamDuration("timeout", "Limit for connecting and the SSH handshake").
+		Default("30s").
+		Done().
+		ParamObject("env", "Environment variables set for commands in the block").
+		AllowAdditionalProperties().
+		Done().
+		TransportScope(TransportScopeAny).
+		SwitchesTransport().
+		Idempotent().
+		Block(BlockRequired).
+		Build()
+}
+
+// Open connects to the remote host. Environment overrides (env.*) are not
+// part of the connection; Wrap applies them to the block.
 func (t *SSHTransport) Open(parent Session, params map[string]any) (Session, error) {
 	return NewSSHSession(params)
 }
 
+// Wrap implements the Exec interface.
+// The executor opens the session and passes it in ctx.Session; Wrap only
+// applies the env overrides before running the block.
 func (t *SSHTransport) Wrap(next ExecNode, params map[string]any) ExecNode {
-	// TODO: Implement execution wrapping
-	return next
+	return &sshNode{next: next, env: EnvParams(params)}
 }
 
-// Helper functions
-
-func getHostKeyCallback(params map[string]any) ssh.HostKeyCallback {
-	// Check if strict host key checking is disabled (for testing)
-	if strictHostKey, ok := params["strict_host_key"].(bool); ok && !strictHostKey {
-		return ssh.InsecureIgnoreHostKey()
-	}
+// sshNode runs a block in a remote session.
+type sshNode struct {
+	next ExecNode
+	env  map[string]string
+}
 
-	// Get known_hosts path (default: ~/.ssh/known_hosts)
-	knownHostsPath := os.ExpandEnv("$HOME/.ssh/known_hosts")
-	if path, ok := params["known_hosts_path"].(string); ok {
-		knownHostsPath = path
+// Execute implements the ExecNode interface.
+func (n *sshNode) Execute(ctx ExecContext) (Result, error) {
+	invariant.NotNil(ctx.Session, "ctx.Session")
+	if n.next == nil {
+		return Result{ExitCode: ExitFailure}, fmt.Errorf("@ssh.connect requires a block to execute")
 	}
-
-	// Try to load known_hosts file
-	callback, err := loadKnownHosts(knownHostsPath)
-	if err != nil {
-		// If known_hosts doesn't exist or can't be read, use InsecureIgnoreHostKey
-		// This allows first-time connections (TOFU - Trust On First Use)
-		return ssh.InsecureIgnoreHostKey()
+	if len(n.env) > 0 {
+		ctx.Session = ctx.Session.WithEnv(n.env)
 	}
-
-	return callback
+	return n.next.Execute(ctx)
 }
 
-func loadKnownHosts(path string) (ssh.HostKeyCallback, error) {
-	// Read known_hosts file
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse known_hosts
-	knownHosts := make(map[string]ssh.PublicKey)
-	for _, line := range strings.Split(string(data), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+// Helper functions
 
-		// Parse line: hostname key-type key-data
-		parts := strings.Fields(line)
-		if len(parts) < 3 {
-			continue
+// sshPort reads the port parameter. Plan args arrive as int64 and CLI
+// values as strings, so all three forms are accepted.
+func sshPort(value any) (int, error) {
+	var port int
+	switch v := value.(type) {
+	case nil:
+		return 22, nil
+	case int:
+		port = v
+	case int64:
+		port = int(v)
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid port %q: must be a number", v)
 		}
+		port = n
+	default:
+		return 0, fmt.Errorf("invalid port: expected a number, got %T", value)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %d: must be between 1 and 65535", port)
+	}
+	return port, nil
+}
 
-		hostname := parts[0]
-		keyType := parts[1]
-		keyData := parts[2]
-
-		// Decode base64 key
-		keyBytes, err := base64.StdEncoding.DecodeString(keyData)
+// sshTimeout reads the timeout parameter (a duration or duration string).
+func sshTimeout(value any) (time.Duration, error) {
+	var timeout time.Duration
+	switch v := value.(type) {
+	case nil:
+		return defaultSSHTimeout, nil
+	case time.Duration:
+		timeout = v
+	case string:
+		d, err := time.ParseDuration(v)
 		if err != nil {
-			continue
+			return 0, fmt.Errorf("invalid timeout %q: %w", v, err)
 		}
+		timeout = d
+	default:
+		return 0, fmt.Errorf("invalid timeout: expected a duration, got %T", value)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %v: must be positive", timeout)
+	}
+	return timeout, nil
+}
 
-		pubKey, err := ssh.ParsePublicKey(keyBytes)
+// getHostKeyCallback verifies host keys against known_hosts.
+// A missing known_hosts file is an error: connecting to an unverified host
+// would hand it our credentials and commands.
+func getHostKeyCallback(params map[string]any) (ssh.HostKeyCallback, error) {
+	// Check if strict host key checking is disabled (for testing)
+	if strictHostKey, ok := params["strict_host_key"].(bool); ok && !strictHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	knownHostsPath, _ := params["known_hosts_path"].(string)
+	if knownHostsPath == "" {
+		home, err := os.UserHomeDir()
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("cannot locate known_hosts: %w", err)
 		}
+		knownHostsPath = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	knownHostsPath, err := expandHome(knownHostsPath)
+	if err != nil {
+		return nil, err
+	}
 
-		knownHosts[hostname+":"+keyType] = pubKey
+	callback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts %s: %w", knownHostsPath, err)
 	}
 
-	// Return callback that checks against known_hosts
 	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-		// Build lookup key
-		lookupKey := hostname + ":" + key.Type()
-
-		// Check if host key is known
-		knownKey, ok := knownHosts[lookupKey]
-		if !ok {
-			return fmt.Errorf("host key not found in known_hosts: %s", hostname)
+		err := callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			if len(keyErr.Want) == 0 {
+				return fmt.Errorf("host %s is not in %s (add it with ssh-keyscan)", hostname, knownHostsPath)
+			}
+			return fmt.Errorf("host key mismatch for %s: the key does not match %s", hostname, knownHostsPath)
 		}
+		return err
+	}, nil
+}
 
-		// Compare keys
-		if !bytes.Equal(key.Marshal(), knownKey.Marshal()) {
-			return fmt.Errorf("host key mismatch for %s", hostname)
+// sshAuthMethods builds the auth methods from the key parameter: an
+// ssh.Signer, a private key file, or ssh-agent when no key is given.
+// Returns the agent connection so the session can close it.
+func sshAuthMethods(key any) ([]ssh.AuthMethod, io.Closer, error) {
+	switch k := key.(type) {
+	case ssh.Signer:
+		return []ssh.AuthMethod{ssh.PublicKeys(k)}, nil, nil
+	case string:
+		if k != "" {
+			signer, err := loadPrivateKey(k)
+			if err != nil {
+				return nil, nil, err
+			}
+			return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil, nil
 		}
+	case nil:
+	default:
+		return nil, nil, fmt.Errorf("invalid key: expected a file path, got %T", key)
+	}
 
-		return nil
-	}, nil
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, fmt.Errorf("no key given and SSH_AUTH_SOCK is not set (start ssh-agent or pass key=)")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+	}
+	agentClient := agent.NewClient(conn)
+	return []ssh.AuthMethod{ssh.PublicKeysCallback(agentClient.Signers)}, conn, nil
 }
 
-func sshKeyAuth(keyPath string) ssh.AuthMethod {
-	// Read private key file
+// loadPrivateKey reads and parses a private key file.
+func loadPrivateKey(keyPath string) (ssh.Signer, error) {
+	keyPath, err := expandHome(keyPath)
+	if err != nil {
+		return nil, err
+	}
 	keyData, err := os.ReadFile(keyPath)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to read key: %w", err)
 	}
-
-	// Parse private key
 	signer, err := ssh.ParsePrivateKey(keyData)
 	if err != nil {
-		return nil
+		var passErr *ssh.PassphraseMissingError
+		if errors.As(err, &passErr) {
+			return nil, fmt.Errorf("key %s is passphrase protected: load it into ssh-agent and omit key=", keyPath)
+		}
+		return nil, fmt.Errorf("failed to parse key %s: %w", keyPath, err)
 	}
-
-	return ssh.PublicKeys(signer)
+	return signer, nil
 }
 
-func sshAgentAuth() ssh.AuthMethod {
-	// Connect to SSH agent
-	socket := os.Getenv("SSH_AUTH_SOCK")
-	if socket == "" {
-		return nil
+// expandHome expands a leading "~/" to the user's home directory.
+func expandHome(p string) (string, error) {
+	rest, ok := strings.CutPrefix(p, "~/")
+	if !ok {
+		return p, nil
 	}
-
-	conn, err := net.Dial("unix", socket)
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return nil
+		return "", fmt.Errorf("cannot expand %s: %w", p, err)
 	}
-
-	agentClient := agent.NewClient(conn)
-	return ssh.PublicKeysCallback(agentClient.Signers)
+	return filepath.Join(home, rest), nil
 }
 
 func parseEnv(output string) map[string]string {
@@ -1,10 +1,21 @@
 package decorator
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 var sshServer *SSHTestServer
@@ -357,3 +368,447 @@ func TestSSHSessionWithEnvModifiesRemoteEnvironment(t *testing.T) {
 		t.Errorf("Modified SSH session OPAL_SSH_TEST: got %q, want %q", modEnv["OPAL_SSH_TEST"], "ssh_value")
 	}
 }
+
+// TestSSHSessionGracefulTermination verifies remote commands get SIGTERM first
+// when the context carries a grace period
+func TestSSHSessionGracefulTermination(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping SSH integration test in short mode")
+	}
+
+	server := getSSHTestServer(t)
+	if server == nil {
+		t.Skip("SSH test server not available")
+	}
+
+	session, err := NewSSHSession(map[string]any{
+		"host": "127.0.0.1",
+		"port": server.Port,
+		"user": os.Getenv("USER"),
+		"key":  server.ClientKey, "strict_host_key": false,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create SSH session: %v", err)
+	}
+	defer session.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	ctx = WithGracePeriod(ctx, 5*time.Second)
+
+	var stdout bytes.Buffer
+	start := time.Now()
+	result, err := session.Run(ctx, []string{"sh", "-c", "trap 'echo cleanup; exit 0' TERM; sleep 10 & wait"}, RunOpts{Stdout: &stdout})
+	duration := time.Since(start)
+
+	if err == nil {
+		t.Error("Expected error due to context deadline")
+	}
+	if result.ExitCode != ExitCanceled {
+		t.Errorf("ExitCode: got %d, want %d (ExitCanceled)", result.ExitCode, ExitCanceled)
+	}
+	if !strings.Contains(stdout.String(), "cleanup") {
+		t.Errorf("Expected TERM handler to run, stdout: %q", stdout.String())
+	}
+	if duration > 3*time.Second {
+		t.Errorf("Duration: got %v, want < 3s", duration)
+	}
+}
+
+// writeTestFile writes content to a file in a fresh temp dir and returns its path.
+func writeTestFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// TestSSHSessionKnownHosts verifies host keys are checked against known_hosts
+func TestSSHSessionKnownHosts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping SSH integration test in short mode")
+	}
+
+	server := getSSHTestServer(t)
+	if server == nil {
+		t.Skip("SSH test server not available")
+	}
+
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ssh.NewSignerFromKey(otherPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := knownhosts.Normalize(server.Addr())
+
+	tests := []struct {
+		name       string
+		knownHosts string
+		wantErr    string
+	}{
+		{
+			name:       "known host accepted",
+			knownHosts: server.KnownHostsLine() + "\n",
+		},
+		{
+			name:       "unknown host rejected",
+			knownHosts: knownhosts.Line([]string{"[10.0.0.1]:22"}, otherKey.PublicKey()) + "\n",
+			wantErr:    "is not in",
+		},
+		{
+			name:       "changed host key rejected",
+			knownHosts: knownhosts.Line([]string{addr}, otherKey.PublicKey()) + "\n",
+			wantErr:    "host key mismatch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := NewSSHSession(map[string]any{
+				"host":             "127.0.0.1",
+				"port":             server.Port,
+				"user":             os.Getenv("USER"),
+				"key":              server.ClientKey,
+				"known_hosts_path": writeTestFile(t, "known_hosts", []byte(tt.knownHosts)),
+			})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Failed to create SSH session: %v", err)
+				}
+				_ = session.Close()
+				return
+			}
+			if err == nil {
+				_ = session.Close()
+				t.Fatalf("Expected error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Error: got %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestSSHSessionMissingKnownHosts verifies a missing known_hosts file fails
+// instead of trusting the host
+func TestSSHSessionMissingKnownHosts(t *testing.T) {
+	_, err := NewSSHSession(map[string]any{
+		"host":             "127.0.0.1",
+		"port":             22,
+		"key":              "unused",
+		"known_hosts_path": filepath.Join(t.TempDir(), "missing"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "known_hosts") {
+		t.Errorf("Expected known_hosts error, got %v", err)
+	}
+}
+
+// TestSSHSessionKeyFile tests authentication with a private key file
+func TestSSHSessionKeyFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping SSH integration test in short mode")
+	}
+
+	server := getSSHTestServer(t)
+	if server == nil {
+		t.Skip("SSH test server not available")
+	}
+
+	session, err := NewSSHSession(map[string]any{
+		"host":             "127.0.0.1",
+		"port":             int64(server.Port),
+		"user":             os.Getenv("USER"),
+		"key":              writeTestFile(t, "id_ed25519", server.ClientKeyPEM),
+		"known_hosts_path": writeTestFile(t, "known_hosts", []byte(server.KnownHostsLine()+"\n")),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create SSH session: %v", err)
+	}
+	defer session.Close()
+
+	result, err := session.Run(context.Background(), []string{"echo", "key"}, RunOpts{})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Run failed: exit=%d err=%v", result.ExitCode, err)
+	}
+}
+
+// TestSSHSessionKeyFileErrors verifies unreadable keys fail with a clear error
+func TestSSHSessionKeyFileErrors(t *testing.T) {
+	params := map[string]any{
+		"host":            "127.0.0.1",
+		"strict_host_key": false,
+	}
+
+	params["key"] = filepath.Join(t.TempDir(), "missing")
+	if _, err := NewSSHSession(params); err == nil || !strings.Contains(err.Error(), "failed to read key") {
+		t.Errorf("Missing key: got %v", err)
+	}
+
+	params["key"] = writeTestFile(t, "garbage", []byte("not a key"))
+	if _, err := NewSSHSession(params); err == nil || !strings.Contains(err.Error(), "failed to parse key") {
+		t.Errorf("Invalid key: got %v", err)
+	}
+}
+
+// TestSSHSessionAgentAuth tests falling back to ssh-agent when no key is given
+func TestSSHSessionAgentAuth(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping SSH integration test in short mode")
+	}
+
+	server := getSSHTestServer(t)
+	if server == nil {
+		t.Skip("SSH test server not available")
+	}
+
+	t.Setenv("SSH_AUTH_SOCK", server.StartAgent(t))
+
+	session, err := NewSSHSession(map[string]any{
+		"host":            "127.0.0.1",
+		"port":            strconv.Itoa(server.Port),
+		"user":            os.Getenv("USER"),
+		"strict_host_key": false,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create SSH session: %v", err)
+	}
+	defer session.Close()
+
+	result, err := session.Run(context.Background(), []string{"echo", "agent"}, RunOpts{})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Run failed: exit=%d err=%v", result.ExitCode, err)
+	}
+}
+
+// TestSSHSessionNoAgent verifies a missing key and agent is reported
+func TestSSHSessionNoAgent(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	_, err := NewSSHSession(map[string]any{
+		"host":            "127.0.0.1",
+		"strict_host_key": false,
+	})
+	if err == nil || !strings.Contains(err.Error(), "SSH_AUTH_SOCK") {
+		t.Errorf("Expected SSH_AUTH_SOCK error, got %v", err)
+	}
+}
+
+// TestSSHSessionConnectTimeout verifies a server that never completes the
+// handshake fails after the timeout instead of hanging
+func TestSSHSessionConnectTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("Failed to listen:", err)
+	}
+	defer listener.Close()
+
+	// Accept connections but never speak SSH
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	start := time.Now()
+	_, err = NewSSHSession(map[string]any{
+		"host":            "127.0.0.1",
+		"port":            listener.Addr().(*net.TCPAddr).Port,
+		"key":             writeTestFile(t, "id_ed25519", mustTestKeyPEM(t)),
+		"strict_host_key": false,
+		"timeout":         "200ms",
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Connect took %v, want about 200ms", elapsed)
+	}
+}
+
+// mustTestKeyPEM returns a fresh ed25519 private key file
+func mustTestKeyPEM(t *testing.T) []byte {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(block)
+}
+
+// TestSSHSessionParamValidation tests port and timeout parameter parsing
+func TestSSHSessionParamValidation(t *testing.T) {
+	ports := []struct {
+		value   any
+		want    int
+		wantErr bool
+	}{
+		{value: nil, want: 22},
+		{value: 2222, want: 2222},
+		{value: int64(2222), want: 2222},
+		{value: "2222", want: 2222},
+		{value: "ssh", wantErr: true},
+		{value: 0, wantErr: true},
+		{value: 70000, wantErr: true},
+		{value: 22.5, wantErr: true},
+	}
+	for _, tt := range ports {
+		got, err := sshPort(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("sshPort(%v) = %d, %v; want %d, err=%v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	timeouts := []struct {
+		value   any
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: nil, want: defaultSSHTimeout},
+		{value: "5s", want: 5 * time.Second},
+		{value: 2 * time.Second, want: 2 * time.Second},
+		{value: "soon", wantErr: true},
+		{value: "-1s", wantErr: true},
+	}
+	for _, tt := range timeouts {
+		got, err := sshTimeout(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("sshTimeout(%v) = %v, %v; want %v, err=%v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestSSHSessionPutRelativeToWorkdir verifies Put and Get resolve relative
+// paths against the session's working directory
+func TestSSHSessionPutRelativeToWorkdir(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping SSH integration test in short mode")
+	}
+
+	server := getSSHTestServer(t)
+	if server == nil {
+		t.Skip("SSH test server not available")
+	}
+
+	base, err := NewSSHSession(map[string]any{
+		"host": "127.0.0.1",
+		"port": server.Port,
+		"user": os.Getenv("USER"),
+		"key":  server.ClientKey, "strict_host_key": false,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create SSH session: %v", err)
+	}
+	defer base.Close()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	session := base.WithWorkdir(dir).WithWorkdir("sub")
+	if session.Cwd() != filepath.Join(dir, "sub") {
+		t.Errorf("Cwd: got %q, want %q", session.Cwd(), filepath.Join(dir, "sub"))
+	}
+
+	ctx := context.Background()
+	if err := session.Put(ctx, []byte("payload"), "out.txt", 0o644); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "sub", "out.txt"))
+	if err != nil || string(data) != "payload" {
+		t.Errorf("Remote file: got %q, %v; want %q", data, err, "payload")
+	}
+
+	got, err := session.Get(ctx, "out.txt")
+	if err != nil || string(got) != "payload" {
+		t.Errorf("Get: got %q, %v; want %q", got, err, "payload")
+	}
+
+	// Derived sessions share the connection; closing one must not close it
+	if err := session.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := base.Run(ctx, []string{"true"}, RunOpts{}); err != nil {
+		t.Errorf("Base session closed by derived session: %v", err)
+	}
+}
+
+// TestSSHTransportWrapAppliesEnv verifies env={...} overrides reach the block
+func TestSSHTransportWrapAppliesEnv(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping SSH integration test in short mode")
+	}
+
+	server := getSSHTestServer(t)
+	if server == nil {
+		t.Skip("SSH test server not available")
+	}
+
+	params := map[string]any{
+		"host":            "127.0.0.1",
+		"port":            server.Port,
+		"user":            os.Getenv("USER"),
+		"key":             server.ClientKey,
+		"strict_host_key": false,
+		"env.DEPLOY_ENV":  "staging",
+	}
+	transport := &SSHTransport{}
+	session, err := transport.Open(NewLocalSession(), params)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	var stdout bytes.Buffer
+	block := &testNode{fn: func(ctx ExecContext) (Result, error) {
+		return ctx.Session.Run(ctx.Context, []string{"sh", "-c", "echo $DEPLOY_ENV"}, RunOpts{Stdout: &stdout})
+	}}
+	result, err := transport.Wrap(block, params).Execute(ExecContext{
+		Context: context.Background(),
+		Session: session,
+	})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Execute failed: exit=%d err=%v", result.ExitCode, err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "staging" {
+		t.Errorf("DEPLOY_ENV: got %q, want %q", got, "staging")
+	}
+}
+
+// TestSSHTransportDescriptor verifies @ssh.connect is a transport boundary
+func TestSSHTransportDescriptor(t *testing.T) {
+	desc := (&SSHTransport{}).Descriptor()
+	if desc.Path != "ssh.connect" {
+		t.Errorf("Path: got %q, want %q", desc.Path, "ssh.connect")
+	}
+	if !desc.Schema.SwitchesTransport {
+		t.Error("Expected SwitchesTransport")
+	}
+	if desc.Capabilities.Block != BlockRequired {
+		t.Errorf("Block: got %q, want %q", desc.Capabilities.Block, BlockRequired)
+	}
+	if !desc.Schema.Parameters["host"].Required {
+		t.Error("Expected host to be required")
+	}
+}
+
+// testNode is an ExecNode backed by a function
+type testNode struct {
+	fn func(ctx ExecContext) (Result, error)
+}
+
+func (n *testNode) Execute(ctx ExecContext) (Result, error) {
+	return n.fn(ctx)
+}
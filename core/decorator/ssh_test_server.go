@@ -4,26 +4,35 @@ import (
 	"bytes"
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 // SSHTestServer is a pure Go SSH server for testing.
 type SSHTestServer struct {
-	Port      int
-	HostKey   ssh.Signer
-	ClientKey ssh.Signer
-	listener  net.Listener
-	t         *testing.T
-	wg        sync.WaitGroup
-	env       map[string]string
+	Port         int
+	HostKey      ssh.Signer
+	ClientKey    ssh.Signer
+	ClientKeyPEM []byte // ClientKey as an OpenSSH private key file
+	clientPriv   ed25519.PrivateKey
+	listener     net.Listener
+	t            *testing.T
+	wg           sync.WaitGroup
+	env          map[string]string
 }
 
 // StartSSHTestServer creates and starts a pure Go SSH server.
@@ -92,13 +101,21 @@ func StartSSHTestServer(t *testing.T) *SSHTestServer {
 		}
 	}
 
+	clientPEM, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Skip("Failed to marshal client key:", err)
+		return nil
+	}
+
 	server := &SSHTestServer{
-		Port:      port,
-		HostKey:   hostKey,
-		ClientKey: clientKey,
-		listener:  listener,
-		t:         t,
-		env:       env,
+		Port:         port,
+		HostKey:      hostKey,
+		ClientKey:    clientKey,
+		ClientKeyPEM: pem.EncodeToMemory(clientPEM),
+		clientPriv:   clientPriv,
+		listener:     listener,
+		t:            t,
+		env:          env,
 	}
 
 	// Start accepting connections
@@ -166,12 +183,19 @@ func (s *SSHTestServer) handleChannel(newChannel ssh.NewChannel) {
 		sessionEnv[k] = v
 	}
 
+	// Running command (exec runs asynchronously so signal requests can reach it)
+	var running *exec.Cmd
+	var execDone sync.WaitGroup
+	defer execDone.Wait()
+
 	for req := range requests {
 		switch req.Type {
 		case "exec":
-			s.handleExec(channel, req, sessionEnv)
+			running = s.handleExec(channel, req, sessionEnv, &execDone)
 		case "env":
 			s.handleEnv(req, sessionEnv)
+		case "signal":
+			s.handleSignal(req, running)
 		default:
 			if req.WantReply {
 				_ = req.Reply(false, nil)
@@ -194,7 +218,7 @@ func (s *SSHTestServer) handleEnv(req *ssh.Request, sessionEnv map[string]string
 	}
 }
 
-func (s *SSHTestServer) handleExec(channel ssh.Channel, req *ssh.Request, sessionEnv map[string]string) {
+func (s *SSHTestServer) handleExec(channel ssh.Channel, req *ssh.Request, sessionEnv map[string]string, execDone *sync.WaitGroup) *exec.Cmd {
 	// Parse command from request payload
 	var execReq struct {
 		Command string
@@ -204,7 +228,7 @@ func (s *SSHTestServer) handleExec(channel ssh.Channel, req *ssh.Request, sessio
 			_ = req.Reply(false, nil)
 		}
 		_ = channel.Close()
-		return
+		return nil
 	}
 
 	if req.WantReply {
@@ -214,33 +238,73 @@ func (s *SSHTestServer) handleExec(channel ssh.Channel, req *ssh.Request, sessio
 	// Execute command locally (for testing)
 	cmd := exec.Command("sh", "-c", execReq.Command)
 
+	// Own process group so signal requests reach the whole command
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
 	// Set environment
 	cmd.Env = make([]string, 0, len(sessionEnv))
 	for k, v := range sessionEnv {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	// Wire up I/O
+	// Wire up I/O. WaitDelay stops Wait from blocking on a client that
+	// keeps stdin open after the command has exited.
+	cmd.Stdin = channel
 	cmd.Stdout = channel
 	cmd.Stderr = channel.Stderr()
+	cmd.WaitDelay = time.Second
 
-	// Run command
-	err := cmd.Run()
 	exitCode := 0
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		} else {
-			exitCode = 1
+	if err := cmd.Start(); err != nil {
+		exitCode = 127
+		cmd = nil
+	}
+
+	execDone.Add(1)
+	go func() {
+		defer execDone.Done()
+
+		if cmd != nil {
+			if err := cmd.Wait(); err != nil {
+				if exitErr, ok := err.(*exec.ExitError); ok {
+					exitCode = exitErr.ExitCode()
+				} else if errors.Is(err, exec.ErrWaitDelay) {
+					exitCode = cmd.ProcessState.ExitCode()
+				} else {
+					exitCode = 1
+				}
+			}
 		}
-	}
 
-	// Send exit status
-	exitStatus := struct{ Status uint32 }{uint32(exitCode)}
-	_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(&exitStatus))
+		// Send exit status
+		exitStatus := struct{ Status uint32 }{uint32(exitCode)}
+		_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(&exitStatus))
+
+		// Close the channel to signal completion
+		_ = channel.Close()
+	}()
 
-	// Close the channel to signal completion
-	_ = channel.Close()
+	return cmd
+}
+
+// handleSignal delivers a "signal" request (RFC 4254 6.9) to the running command.
+func (s *SSHTestServer) handleSignal(req *ssh.Request, cmd *exec.Cmd) {
+	var sigReq struct {
+		Signal string
+	}
+	if err := ssh.Unmarshal(req.Payload, &sigReq); err == nil && cmd != nil && cmd.Process != nil {
+		signals := map[string]syscall.Signal{
+			string(ssh.SIGTERM): syscall.SIGTERM,
+			string(ssh.SIGKILL): syscall.SIGKILL,
+			string(ssh.SIGINT):  syscall.SIGINT,
+		}
+		if sig, ok := signals[sigReq.Signal]; ok {
+			_ = syscall.Kill(-cmd.Process.Pid, sig)
+		}
+	}
+	if req.WantReply {
+		_ = req.Reply(true, nil)
+	}
 }
 
 // Stop stops the SSH server and waits for all connections to close.
@@ -256,6 +320,44 @@ func (s *SSHTestServer) Addr() string {
 	return fmt.Sprintf("127.0.0.1:%d", s.Port)
 }
 
+// KnownHostsLine returns a known_hosts entry for this server's host key.
+func (s *SSHTestServer) KnownHostsLine() string {
+	return knownhosts.Line([]string{knownhosts.Normalize(s.Addr())}, s.HostKey.PublicKey())
+}
+
+// StartAgent serves an ssh-agent holding ClientKey on a unix socket and
+// returns the socket path. The agent stops when the test ends.
+func (s *SSHTestServer) StartAgent(t *testing.T) string {
+	t.Helper()
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: s.clientPriv}); err != nil {
+		t.Fatalf("Failed to add key to agent: %v", err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skip("Failed to listen on agent socket:", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+
+	return socket
+}
+
 // NewClientConfig returns an ssh.ClientConfig for connecting to this server.
 func (s *SSHTestServer) NewClientConfig(user string) *ssh.ClientConfig {
 	return &ssh.ClientConfig{
@@ -20,7 +20,10 @@ package decorator
 //	    // Decorator can create child spans for internal tracking
 //	    for i := 0; i < n.attempts; i++ {
 //	        attemptSpan := ctx.Trace.Child("retry.attempt", map[string]any{"attempt": i})
-//	        result, err := n.next.Execute(ctx)
+//	        attemptCtx := ctx
+//	        attemptCtx.Trace = attemptSpan // Nested decorators report under the attempt
+//	        result, err := n.next.Execute(attemptCtx)
+//	        attemptSpan.SetAttr("exit_code", result.ExitCode)
 //	        attemptSpan.End()
 //	        if err == nil {
 //	            return result, nil
@@ -29,11 +32,18 @@ package decorator
 //	    return Result{}, fmt.Errorf("all attempts failed")
 //	}
 //
-// This is a stub interface - full implementation in Phase 7.
+// Implementations: NoOpSpan (telemetry off), the executor's in-memory spans
+// (--timing, run history) and runtime/otlp's exported spans. Attributes are
+// exported: record IDs, counts and exit codes, never values that may hold
+// secrets.
 type Span interface {
 	// End marks the span as complete
 	End()
 
+	// SetAttr records an attribute discovered while the span is open
+	// (e.g., the exit code of a retry attempt)
+	SetAttr(key string, value any)
+
 	// Child creates a child span for internal operations (optional)
 	// Decorators can use this to track internal logic
 	Child(name string, attrs map[string]any) Span
@@ -44,3 +54,9 @@ type NoOpSpan struct{}
 
 // End does nothing.
 func (NoOpSpan) End() {}
+
+// SetAttr does nothing.
+func (NoOpSpan) SetAttr(key string, value any) {}
+
+// Child returns another no-op span.
+func (NoOpSpan) Child(name string, attrs map[string]any) Span { return NoOpSpan{} }
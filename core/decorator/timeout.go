@@ -0,0 +1,41 @@
+package decorator
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimeoutError reports that a deadline set by @timeout stopped execution.
+// It unwraps to context.DeadlineExceeded so errors.Is keeps working.
+type TimeoutError struct {
+	Duration time.Duration // The configured timeout
+}
+
+// Error implements the error interface.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %v", e.Duration)
+}
+
+// Unwrap returns context.DeadlineExceeded.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// gracePeriodKey is the context key for the termination grace period.
+type gracePeriodKey struct{}
+
+// WithGracePeriod returns a context that asks sessions to terminate processes
+// gracefully when ctx is done: SIGTERM first, then SIGKILL after grace.
+// A zero grace period means SIGKILL immediately (the default).
+func WithGracePeriod(ctx context.Context, grace time.Duration) context.Context {
+	return context.WithValue(ctx, gracePeriodKey{}, grace)
+}
+
+// GracePeriod returns the termination grace period carried by ctx (0 if unset).
+func GracePeriod(ctx context.Context) time.Duration {
+	if grace, ok := ctx.Value(gracePeriodKey{}).(time.Duration); ok && grace > 0 {
+		return grace
+	}
+	return 0
+}
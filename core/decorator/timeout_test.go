@@ -0,0 +1,38 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeoutErrorUnwrapsDeadlineExceeded(t *testing.T) {
+	var err error = &TimeoutError{Duration: 5 * time.Second}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("TimeoutError should unwrap to context.DeadlineExceeded")
+	}
+	if got, want := err.Error(), "timed out after 5s"; got != want {
+		t.Errorf("Error(): got %q, want %q", got, want)
+	}
+}
+
+func TestGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	if got := GracePeriod(ctx); got != 0 {
+		t.Errorf("GracePeriod without value: got %v, want 0", got)
+	}
+
+	ctx = WithGracePeriod(ctx, 3*time.Second)
+	if got := GracePeriod(ctx); got != 3*time.Second {
+		t.Errorf("GracePeriod: got %v, want 3s", got)
+	}
+
+	// Derived contexts keep the grace period
+	derived, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if got := GracePeriod(derived); got != 3*time.Second {
+		t.Errorf("GracePeriod on derived context: got %v, want 3s", got)
+	}
+}
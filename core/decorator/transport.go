@@ -1,5 +1,10 @@
 package decorator
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Transport is the interface for decorators that create transport boundaries.
 // Transport decorators implement BOTH Open() and Wrap() methods.
 // Examples: @ssh.connect, @docker.exec, @k8s.pod
@@ -12,3 +17,32 @@ type Transport interface {
 	// Wrap wraps execution to use the transport session
 	Wrap(next ExecNode, params map[string]any) ExecNode
 }
+
+// EnvParams collects the env={...} overrides of a transport decorator.
+// Object parameters arrive flattened as "env.KEY"; values are stringified.
+func EnvParams(params map[string]any) map[string]string {
+	env := make(map[string]string)
+	for key, value := range params {
+		if name, ok := strings.CutPrefix(key, "env."); ok && name != "" {
+			if s, ok := value.(string); ok {
+				env[name] = s // fmt would keep a copy of a secret in its buffer pool
+			} else {
+				env[name] = fmt.Sprint(value)
+			}
+		}
+	}
+	return env
+}
+
+// SessionParams returns the params that identify a transport session: all
+// but the env.* overrides, which apply per block rather than per connection.
+// Used as the SessionPool key so blocks with different env share a session.
+func SessionParams(params map[string]any) map[string]any {
+	session := make(map[string]any, len(params))
+	for key, value := range params {
+		if !strings.HasPrefix(key, "env.") {
+			session[key] = value
+		}
+	}
+	return session
+}
@@ -43,7 +43,7 @@ type CanonicalSecretUse struct {
 
 // CanonicalNode is a union type for execution tree nodes in canonical form
 type CanonicalNode struct {
-	Type string // "command", "pipeline", "and", "or", "sequence", "redirect"
+	Type string // "command", "pipeline", "and", "or", "sequence", "redirect", "redirect_in", "group", "try"
 
 	// CommandNode fields
 	Decorator string
@@ -64,6 +64,22 @@ type CanonicalNode struct {
 	Source *CanonicalNode
 	Target *CanonicalNode
 	Mode   int
+
+	// GroupNode fields (omitted when empty so other nodes hash as before)
+	Kind  string          `cbor:",omitempty"`
+	Label string          `cbor:",omitempty"`
+	Steps []CanonicalStep `cbor:",omitempty"`
+
+	// TryNode fields (omitted when empty so other nodes hash as before)
+	Try        []CanonicalStep `cbor:",omitempty"`
+	Catch      []CanonicalStep `cbor:",omitempty"`
+	Finally    []CanonicalStep `cbor:",omitempty"`
+	HasCatch   bool            `cbor:",omitempty"`
+	HasFinally bool            `cbor:",omitempty"`
+
+	// RedirectInNode fields (omitted when empty so other nodes hash as before)
+	Command *CanonicalNode `cbor:",omitempty"`
+	Input   *CanonicalNode `cbor:",omitempty"`
 }
 
 // CanonicalArg represents an argument in canonical form
@@ -152,6 +168,12 @@ func toCanonicalNode(node ExecutionNode) (CanonicalNode, error) {
 		return canonicalizeSequenceNode(n)
 	case *RedirectNode:
 		return canonicalizeRedirectNode(n)
+	case *RedirectInNode:
+		return canonicalizeRedirectInNode(n)
+	case *GroupNode:
+		return canonicalizeGroupNode(n)
+	case *TryNode:
+		return canonicalizeTryNode(n)
 	default:
 		return CanonicalNode{}, fmt.Errorf("unknown node type: %T", node)
 	}
@@ -284,6 +306,70 @@ func canonicalizeRedirectNode(n *RedirectNode) (CanonicalNode, error) {
 	}, nil
 }
 
+// canonicalizeRedirectInNode converts a RedirectInNode into canonical form
+func canonicalizeRedirectInNode(n *RedirectInNode) (CanonicalNode, error) {
+	command, err := toCanonicalNode(n.Command)
+	if err != nil {
+		return CanonicalNode{}, fmt.Errorf("command: %w", err)
+	}
+
+	input, err := canonicalizeCommandNode(&n.Input)
+	if err != nil {
+		return CanonicalNode{}, fmt.Errorf("input: %w", err)
+	}
+
+	return CanonicalNode{
+		Type:    "redirect_in",
+		Command: &command,
+		Input:   &input,
+	}, nil
+}
+
+// canonicalizeGroupNode converts a GroupNode into canonical form
+func canonicalizeGroupNode(n *GroupNode) (CanonicalNode, error) {
+	cn := CanonicalNode{
+		Type:  "group",
+		Kind:  n.Kind,
+		Label: n.Label,
+		Steps: make([]CanonicalStep, len(n.Steps)),
+	}
+
+	for i := range n.Steps {
+		cs, err := canonicalizeStep(&n.Steps[i])
+		if err != nil {
+			return cn, fmt.Errorf("group step %d: %w", i, err)
+		}
+		cn.Steps[i] = cs
+	}
+
+	return cn, nil
+}
+
+// canonicalizeTryNode converts a TryNode into canonical form
+func canonicalizeTryNode(n *TryNode) (CanonicalNode, error) {
+	cn := CanonicalNode{
+		Type:       "try",
+		HasCatch:   n.HasCatch,
+		HasFinally: n.HasFinally,
+	}
+
+	for _, block := range []struct {
+		name  string
+		steps []Step
+		dst   *[]CanonicalStep
+	}{{"try", n.Try, &cn.Try}, {"catch", n.Catch, &cn.Catch}, {"finally", n.Finally, &cn.Finally}} {
+		for i := range block.steps {
+			cs, err := canonicalizeStep(&block.steps[i])
+			if err != nil {
+				return cn, fmt.Errorf("%s step %d: %w", block.name, i, err)
+			}
+			*block.dst = append(*block.dst, cs)
+		}
+	}
+
+	return cn, nil
+}
+
 // MarshalBinary produces deterministic CBOR encoding of the canonical plan.
 // This ensures byte-for-byte stability across multiple runs.
 func (cp *CanonicalPlan) MarshalBinary() ([]byte, error) {
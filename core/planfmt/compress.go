@@ -0,0 +1,41 @@
+package planfmt
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// compressBody DEFLATE-compresses an encoded body.
+// The output is deterministic for a given body and Go release.
+func compressBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressBody inflates a compressed body, failing once the output
+// exceeds limit bytes rather than inflating a decompression bomb into memory.
+func decompressBody(compressed []byte, limit int64) ([]byte, error) {
+	zr := flate.NewReader(bytes.NewReader(compressed))
+	defer func() { _ = zr.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("decompressed body exceeds maximum %d", limit)
+	}
+	return body, nil
+}
@@ -0,0 +1,115 @@
+package planfmt_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+// unrolledPlan builds a plan with n similar steps, like an unrolled @for loop
+func unrolledPlan(n int) *planfmt.Plan {
+	plan := &planfmt.Plan{Target: "deploy"}
+	for i := 0; i < n; i++ {
+		plan.Steps = append(plan.Steps, planfmt.Step{
+			ID: uint64(i + 1),
+			Tree: &planfmt.CommandNode{
+				Decorator: "@shell",
+				Args: []planfmt.Arg{
+					{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: fmt.Sprintf("kubectl rollout restart deployment/service-%d -n production", i)}},
+				},
+			},
+		})
+	}
+	return plan
+}
+
+// TestCompressedRoundtrip verifies compressed plans read back unchanged,
+// with the same hash as the uncompressed encoding
+func TestCompressedRoundtrip(t *testing.T) {
+	plan := unrolledPlan(500)
+
+	var plain, compressed bytes.Buffer
+	plainHash, err := planfmt.Write(&plain, plan)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	compressedHash, err := planfmt.Write(&compressed, plan, planfmt.WithCompression())
+	if err != nil {
+		t.Fatalf("Write compressed failed: %v", err)
+	}
+
+	if compressedHash != plainHash {
+		t.Errorf("Compression changed the hash: got %x, want %x", compressedHash, plainHash)
+	}
+	flags := planfmt.Flags(binary.LittleEndian.Uint16(compressed.Bytes()[6:8]))
+	if flags&planfmt.FlagCompressed == 0 {
+		t.Error("Expected FlagCompressed to be set")
+	}
+	if compressed.Len()*4 > plain.Len() {
+		t.Errorf("Expected at least 4x smaller, got %d bytes vs %d", compressed.Len(), plain.Len())
+	}
+
+	readPlan, readHash, err := planfmt.Read(&compressed)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if readHash != plainHash {
+		t.Errorf("Read hash mismatch: got %x, want %x", readHash, plainHash)
+	}
+	if len(readPlan.Steps) != len(plan.Steps) {
+		t.Fatalf("Step count mismatch: got %d, want %d", len(readPlan.Steps), len(plan.Steps))
+	}
+	last := readPlan.Steps[len(readPlan.Steps)-1].Tree.(*planfmt.CommandNode)
+	if got := last.Args[0].Val.Str; got != "kubectl rollout restart deployment/service-499 -n production" {
+		t.Errorf("Last command mismatch: got %q", got)
+	}
+}
+
+// TestCompressedSignedContract verifies signatures cover the uncompressed
+// body, so a compressed contract verifies like a plain one
+func TestCompressedSignedContract(t *testing.T) {
+	alicePub, alice := newTestKey(t)
+	plan := unrolledPlan(50)
+
+	hash, err := planfmt.Write(&bytes.Buffer{}, plan)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := planfmt.WriteContract(&buf, plan.Target, hash, plan, planfmt.WithCompression(), planfmt.WithSigners(alice)); err != nil {
+		t.Fatalf("WriteContract failed: %v", err)
+	}
+
+	_, readHash, readPlan, err := planfmt.ReadContract(&buf)
+	if err != nil {
+		t.Fatalf("ReadContract failed: %v", err)
+	}
+	if readHash != hash {
+		t.Errorf("Hash mismatch: got %x, want %x", readHash, hash)
+	}
+
+	trust := planfmt.NewTrustStore()
+	trust.Add("alice", alicePub)
+	if _, err := trust.Verify(readHash, readPlan.Signatures, 1); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+}
+
+// TestWriteContractSignedHashMismatch verifies signing refuses a contract
+// hash that is not the plan's hash
+func TestWriteContractSignedHashMismatch(t *testing.T) {
+	_, alice := newTestKey(t)
+	plan := unrolledPlan(1)
+
+	var buf bytes.Buffer
+	err := planfmt.WriteContract(&buf, plan.Target, [32]byte{1}, plan, planfmt.WithSigners(alice))
+	if err == nil {
+		t.Fatal("Expected hash mismatch error, got nil")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing written, got %d bytes", buf.Len())
+	}
+}
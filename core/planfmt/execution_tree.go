@@ -59,6 +59,39 @@ type SequenceNode struct {
 
 func (*SequenceNode) isExecutionNode() {}
 
+// GroupNode is a labeled group of steps produced by plan-time expansion
+// (e.g., one iteration of an unrolled for loop, the taken branch of an if, or
+// the inlined body of a @cmd call).
+// Steps execute in order and stop at the first failure, like a block.
+//
+// Kind names the construct that produced the group. For loop iterations and
+// @cmd calls it doubles as the scope segment for the group's steps: steps
+// inside a "for" group in step N resolve secrets at "root/.../step-N/@for[N]/...".
+// "if" and "when" groups share the enclosing scope ("root/.../step-N/...").
+type GroupNode struct {
+	Kind  string // Construct that produced the group: "for", "if", "when", "cmd"
+	Label string // Human-readable label, e.g. "for module in @var.MODULES [i=0] module=opal:3J98t56A"
+	Steps []Step // Steps in this group (empty if no branch was taken)
+}
+
+func (*GroupNode) isExecutionNode() {}
+
+// TryNode runs Try, then Catch if Try failed, then Finally unconditionally.
+// Unlike if/when, all three blocks are planned: which one runs depends on
+// execution-time exit codes, not plan-time values.
+//
+// Each block has its own scope segment: steps in the catch block of step N
+// resolve secrets at "root/.../step-N/@catch[N]/...".
+type TryNode struct {
+	Try        []Step // Steps in the try block
+	Catch      []Step // Steps in the catch block (valid only if HasCatch)
+	Finally    []Step // Steps in the finally block (valid only if HasFinally)
+	HasCatch   bool   // A catch block was written (an empty catch still swallows the failure)
+	HasFinally bool   // A finally block was written
+}
+
+func (*TryNode) isExecutionNode() {}
+
 // RedirectMode specifies how to open the sink (overwrite or append).
 type RedirectMode int
 
@@ -93,3 +126,22 @@ type RedirectNode struct {
 }
 
 func (*RedirectNode) isExecutionNode() {}
+
+// RedirectInNode runs Command with its stdin read from the Input decorator.
+// It binds tighter than pipes and output redirects: the input belongs to
+// a single command.
+//
+// Like redirect targets, the input is ALWAYS a decorator that provides
+// the source:
+//
+//	psql < schema.sql
+//	  → @shell("psql") < @shell("schema.sql")
+//
+//	sort < data.txt > sorted.txt
+//	  → (@shell("sort") < @shell("data.txt")) > @shell("sorted.txt")
+type RedirectInNode struct {
+	Command ExecutionNode // Command reading the input
+	Input   CommandNode   // Decorator providing the source
+}
+
+func (*RedirectInNode) isExecutionNode() {}
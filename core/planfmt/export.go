@@ -0,0 +1,550 @@
+package planfmt
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Structured export: JSON and YAML documents for tools (review bots,
+// dashboards). The document carries everything the binary body does, so
+// ReadJSON/ReadYAML rebuild a Plan with the identical hash.
+//
+// Document shape (JSON shown, YAML uses the same keys):
+//
+//	{
+//	  "format": "opal-plan", "version": 1,
+//	  "hash": "<hex>", "target": "deploy", "plan_salt": "<hex>",
+//	  "header": {"schema_id": "<hex>", "created_at": 0, "compiler": "<hex>", "plan_kind": 0, "build": "..."},
+//	  "steps": [{"id": 1, "tree": {"type": "command", "decorator": "@shell",
+//	             "args": [{"key": "command", "kind": "string", "string": "echo hi"}]}}],
+//	  "secret_uses": [{"display_id": "opal:...", "site_id": "...", "site": "root/step-1/params/command"}],
+//	  "provenance": {"source_digest": "<hex>", "decorators": [{"path": "shell", "version": "1.0.0"}]},
+//	  "signatures": [{"public_key": "<hex>", "signature": "<hex>"}]
+//	}
+//
+// Node "type" is one of command, pipeline, and, or, sequence, redirect,
+// redirect_in, group, try (the same names as CanonicalNode).
+
+// exportFormat identifies structured plan documents.
+const exportFormat = "opal-plan"
+
+// exportVersion is the document schema version.
+const exportVersion = 1
+
+type exportPlan struct {
+	Format     string            `json:"format" yaml:"format"`
+	Version    int               `json:"version" yaml:"version"`
+	Hash       string            `json:"hash,omitempty" yaml:"hash,omitempty"` // Checked on read when present
+	Target     string            `json:"target" yaml:"target"`
+	PlanSalt   string            `json:"plan_salt,omitempty" yaml:"plan_salt,omitempty"`
+	Header     exportHeader      `json:"header" yaml:"header"`
+	Steps      []exportStep      `json:"steps" yaml:"steps"`
+	SecretUses []exportSecretUse `json:"secret_uses,omitempty" yaml:"secret_uses,omitempty"`
+	Provenance *exportProvenance `json:"provenance,omitempty" yaml:"provenance,omitempty"`
+	Pipefail   bool              `json:"pipefail,omitempty" yaml:"pipefail,omitempty"`
+	Signatures []exportSignature `json:"signatures,omitempty" yaml:"signatures,omitempty"`
+}
+
+type exportHeader struct {
+	SchemaID  string `json:"schema_id" yaml:"schema_id"`
+	CreatedAt uint64 `json:"created_at" yaml:"created_at"`
+	Compiler  string `json:"compiler" yaml:"compiler"`
+	PlanKind  uint8  `json:"plan_kind" yaml:"plan_kind"`
+	Build     string `json:"build,omitempty" yaml:"build,omitempty"`
+}
+
+type exportStep struct {
+	ID   uint64      `json:"id" yaml:"id"`
+	Tree *exportNode `json:"tree" yaml:"tree"`
+}
+
+type exportNode struct {
+	Type string `json:"type" yaml:"type"`
+
+	// command
+	Decorator string       `json:"decorator,omitempty" yaml:"decorator,omitempty"`
+	Args      []exportArg  `json:"args,omitempty" yaml:"args,omitempty"`
+	Block     []exportStep `json:"block,omitempty" yaml:"block,omitempty"`
+
+	// pipeline
+	Commands []*exportNode `json:"commands,omitempty" yaml:"commands,omitempty"`
+
+	// and, or
+	Left  *exportNode `json:"left,omitempty" yaml:"left,omitempty"`
+	Right *exportNode `json:"right,omitempty" yaml:"right,omitempty"`
+
+	// sequence
+	Nodes []*exportNode `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+
+	// redirect
+	Source *exportNode `json:"source,omitempty" yaml:"source,omitempty"`
+	Target *exportNode `json:"target,omitempty" yaml:"target,omitempty"`
+	Mode   string      `json:"mode,omitempty" yaml:"mode,omitempty"` // "overwrite" or "append"
+
+	// redirect_in
+	Command *exportNode `json:"command,omitempty" yaml:"command,omitempty"`
+	Input   *exportNode `json:"input,omitempty" yaml:"input,omitempty"`
+
+	// group
+	Kind  string       `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Label string       `json:"label,omitempty" yaml:"label,omitempty"`
+	Steps []exportStep `json:"steps,omitempty" yaml:"steps,omitempty"`
+
+	// try
+	Try        []exportStep `json:"try,omitempty" yaml:"try,omitempty"`
+	Catch      []exportStep `json:"catch,omitempty" yaml:"catch,omitempty"`
+	Finally    []exportStep `json:"finally,omitempty" yaml:"finally,omitempty"`
+	HasCatch   bool         `json:"has_catch,omitempty" yaml:"has_catch,omitempty"`
+	HasFinally bool         `json:"has_finally,omitempty" yaml:"has_finally,omitempty"`
+}
+
+// exportArg holds one decorator argument; Kind says which value field is set.
+type exportArg struct {
+	Key  string `json:"key" yaml:"key"`
+	Kind string `json:"kind" yaml:"kind"` // "string", "int", "bool", "placeholder"
+	Str  string `json:"string,omitempty" yaml:"string,omitempty"`
+	Int  int64  `json:"int,omitempty" yaml:"int,omitempty"`
+	Bool bool   `json:"bool,omitempty" yaml:"bool,omitempty"`
+	Ref  uint32 `json:"ref,omitempty" yaml:"ref,omitempty"`
+}
+
+type exportSecretUse struct {
+	DisplayID string `json:"display_id" yaml:"display_id"`
+	SiteID    string `json:"site_id" yaml:"site_id"`
+	Site      string `json:"site" yaml:"site"`
+}
+
+type exportProvenance struct {
+	SourceDigest string                   `json:"source_digest" yaml:"source_digest"`
+	Decorators   []exportDecoratorVersion `json:"decorators,omitempty" yaml:"decorators,omitempty"`
+}
+
+type exportDecoratorVersion struct {
+	Path    string `json:"path" yaml:"path"`
+	Version string `json:"version" yaml:"version"`
+}
+
+type exportSignature struct {
+	PublicKey string `json:"public_key" yaml:"public_key"`
+	Signature string `json:"signature" yaml:"signature"`
+}
+
+var valueKindNames = map[ValueKind]string{
+	ValueString:      "string",
+	ValueInt:         "int",
+	ValueBool:        "bool",
+	ValuePlaceholder: "placeholder",
+}
+
+var redirectModeNames = map[RedirectMode]string{
+	RedirectOverwrite: "overwrite",
+	RedirectAppend:    "append",
+}
+
+// WriteJSON writes p as an indented JSON document.
+func WriteJSON(w io.Writer, p *Plan) error {
+	doc, err := toExport(p)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ReadJSON reads a plan written by WriteJSON.
+func ReadJSON(r io.Reader) (*Plan, error) {
+	var doc exportPlan
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode JSON plan: %w", err)
+	}
+	return fromExport(&doc)
+}
+
+// WriteYAML writes p as a YAML document.
+func WriteYAML(w io.Writer, p *Plan) error {
+	doc, err := toExport(p)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// ReadYAML reads a plan written by WriteYAML.
+func ReadYAML(r io.Reader) (*Plan, error) {
+	var doc exportPlan
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode YAML plan: %w", err)
+	}
+	return fromExport(&doc)
+}
+
+// toExport converts p to its document form, including its hash.
+// Like Write, it sorts args and SecretUses first.
+func toExport(p *Plan) (*exportPlan, error) {
+	hash, err := Write(io.Discard, p)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &exportPlan{
+		Format:   exportFormat,
+		Version:  exportVersion,
+		Hash:     hex.EncodeToString(hash[:]),
+		Target:   p.Target,
+		PlanSalt: hex.EncodeToString(p.PlanSalt),
+		Header: exportHeader{
+			SchemaID:  hex.EncodeToString(p.Header.SchemaID[:]),
+			CreatedAt: p.Header.CreatedAt,
+			Compiler:  hex.EncodeToString(p.Header.Compiler[:]),
+			PlanKind:  p.Header.PlanKind,
+			Build:     p.Header.Build,
+		},
+		Steps:    exportSteps(p.Steps),
+		Pipefail: p.Pipefail,
+	}
+	if doc.Steps == nil {
+		doc.Steps = []exportStep{}
+	}
+	for _, use := range p.SecretUses {
+		doc.SecretUses = append(doc.SecretUses, exportSecretUse(use))
+	}
+	if !p.Provenance.IsZero() {
+		doc.Provenance = &exportProvenance{
+			SourceDigest: hex.EncodeToString(p.Provenance.SourceDigest[:]),
+		}
+		for _, d := range p.Provenance.Decorators {
+			doc.Provenance.Decorators = append(doc.Provenance.Decorators, exportDecoratorVersion(d))
+		}
+	}
+	for _, sig := range p.Signatures {
+		doc.Signatures = append(doc.Signatures, exportSignature{
+			PublicKey: hex.EncodeToString(sig.PublicKey),
+			Signature: hex.EncodeToString(sig.Sig),
+		})
+	}
+	return doc, nil
+}
+
+func exportSteps(steps []Step) []exportStep {
+	if len(steps) == 0 {
+		return nil
+	}
+	result := make([]exportStep, len(steps))
+	for i := range steps {
+		result[i] = exportStep{ID: steps[i].ID, Tree: exportExecutionNode(steps[i].Tree)}
+	}
+	return result
+}
+
+func exportExecutionNode(node ExecutionNode) *exportNode {
+	switch n := node.(type) {
+	case *CommandNode:
+		return exportCommand(n)
+	case *PipelineNode:
+		out := &exportNode{Type: "pipeline"}
+		for _, cmd := range n.Commands {
+			out.Commands = append(out.Commands, exportExecutionNode(cmd))
+		}
+		return out
+	case *AndNode:
+		return &exportNode{Type: "and", Left: exportExecutionNode(n.Left), Right: exportExecutionNode(n.Right)}
+	case *OrNode:
+		return &exportNode{Type: "or", Left: exportExecutionNode(n.Left), Right: exportExecutionNode(n.Right)}
+	case *SequenceNode:
+		out := &exportNode{Type: "sequence"}
+		for _, child := range n.Nodes {
+			out.Nodes = append(out.Nodes, exportExecutionNode(child))
+		}
+		return out
+	case *RedirectNode:
+		return &exportNode{
+			Type:   "redirect",
+			Source: exportExecutionNode(n.Source),
+			Target: exportCommand(&n.Target),
+			Mode:   redirectModeNames[n.Mode],
+		}
+	case *RedirectInNode:
+		return &exportNode{
+			Type:    "redirect_in",
+			Command: exportExecutionNode(n.Command),
+			Input:   exportCommand(&n.Input),
+		}
+	case *GroupNode:
+		return &exportNode{Type: "group", Kind: n.Kind, Label: n.Label, Steps: exportSteps(n.Steps)}
+	case *TryNode:
+		return &exportNode{
+			Type:       "try",
+			Try:        exportSteps(n.Try),
+			Catch:      exportSteps(n.Catch),
+			Finally:    exportSteps(n.Finally),
+			HasCatch:   n.HasCatch,
+			HasFinally: n.HasFinally,
+		}
+	default:
+		panic(fmt.Sprintf("export: unknown execution node type %T", node))
+	}
+}
+
+func exportCommand(cmd *CommandNode) *exportNode {
+	out := &exportNode{Type: "command", Decorator: cmd.Decorator, Block: exportSteps(cmd.Block)}
+	for _, arg := range cmd.Args {
+		out.Args = append(out.Args, exportArg{
+			Key:  arg.Key,
+			Kind: valueKindNames[arg.Val.Kind],
+			Str:  arg.Val.Str,
+			Int:  arg.Val.Int,
+			Bool: arg.Val.Bool,
+			Ref:  arg.Val.Ref,
+		})
+	}
+	return out
+}
+
+// fromExport rebuilds a Plan and checks it against the document's hash.
+func fromExport(doc *exportPlan) (*Plan, error) {
+	if doc.Format != exportFormat {
+		return nil, fmt.Errorf("not an opal plan document (format %q)", doc.Format)
+	}
+	if doc.Version != exportVersion {
+		return nil, fmt.Errorf("unsupported plan document version %d, expected %d", doc.Version, exportVersion)
+	}
+
+	p := &Plan{Target: doc.Target, Pipefail: doc.Pipefail}
+	var err error
+	if p.PlanSalt, err = decodeHex("plan_salt", doc.PlanSalt); err != nil {
+		return nil, err
+	}
+	if err := decodeHeaderID("header.schema_id", doc.Header.SchemaID, &p.Header.SchemaID); err != nil {
+		return nil, err
+	}
+	if err := decodeHeaderID("header.compiler", doc.Header.Compiler, &p.Header.Compiler); err != nil {
+		return nil, err
+	}
+	p.Header.CreatedAt = doc.Header.CreatedAt
+	p.Header.PlanKind = doc.Header.PlanKind
+	p.Header.Build = doc.Header.Build
+
+	if p.Steps, err = importSteps(doc.Steps); err != nil {
+		return nil, err
+	}
+	for _, use := range doc.SecretUses {
+		p.SecretUses = append(p.SecretUses, SecretUse(use))
+	}
+	if pv := doc.Provenance; pv != nil {
+		digest, err := decodeHex("provenance.source_digest", pv.SourceDigest)
+		if err != nil {
+			return nil, err
+		}
+		if len(digest) != 0 && len(digest) != len(p.Provenance.SourceDigest) {
+			return nil, fmt.Errorf("provenance.source_digest: expected %d bytes, got %d", len(p.Provenance.SourceDigest), len(digest))
+		}
+		copy(p.Provenance.SourceDigest[:], digest)
+		for _, d := range pv.Decorators {
+			p.Provenance.Decorators = append(p.Provenance.Decorators, DecoratorVersion(d))
+		}
+	}
+	for i, sig := range doc.Signatures {
+		pub, err := decodeHex(fmt.Sprintf("signatures[%d].public_key", i), sig.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		s, err := decodeHex(fmt.Sprintf("signatures[%d].signature", i), sig.Signature)
+		if err != nil {
+			return nil, err
+		}
+		p.Signatures = append(p.Signatures, Signature{PublicKey: ed25519.PublicKey(pub), Sig: s})
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	hash, err := Write(io.Discard, p)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Hash != "" && doc.Hash != hex.EncodeToString(hash[:]) {
+		return nil, fmt.Errorf("plan hash mismatch: document says %s, plan hashes to %x", doc.Hash, hash)
+	}
+	return p, nil
+}
+
+func importSteps(steps []exportStep) ([]Step, error) {
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	result := make([]Step, len(steps))
+	for i, step := range steps {
+		tree, err := importNode(step.Tree)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", step.ID, err)
+		}
+		result[i] = Step{ID: step.ID, Tree: tree}
+	}
+	return result, nil
+}
+
+func importNode(node *exportNode) (ExecutionNode, error) {
+	if node == nil {
+		return nil, fmt.Errorf("missing execution node")
+	}
+
+	switch node.Type {
+	case "command":
+		return importCommand(node)
+	case "pipeline":
+		out := &PipelineNode{}
+		for _, child := range node.Commands {
+			cmd, err := importNode(child)
+			if err != nil {
+				return nil, err
+			}
+			out.Commands = append(out.Commands, cmd)
+		}
+		return out, nil
+	case "and", "or":
+		left, err := importNode(node.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := importNode(node.Right)
+		if err != nil {
+			return nil, err
+		}
+		if node.Type == "and" {
+			return &AndNode{Left: left, Right: right}, nil
+		}
+		return &OrNode{Left: left, Right: right}, nil
+	case "sequence":
+		out := &SequenceNode{}
+		for _, child := range node.Nodes {
+			n, err := importNode(child)
+			if err != nil {
+				return nil, err
+			}
+			out.Nodes = append(out.Nodes, n)
+		}
+		return out, nil
+	case "redirect":
+		source, err := importNode(node.Source)
+		if err != nil {
+			return nil, err
+		}
+		if node.Target == nil || node.Target.Type != "command" {
+			return nil, fmt.Errorf("redirect target must be a command node")
+		}
+		target, err := importCommand(node.Target)
+		if err != nil {
+			return nil, err
+		}
+		out := &RedirectNode{Source: source, Target: *target}
+		switch node.Mode {
+		case "overwrite":
+			out.Mode = RedirectOverwrite
+		case "append":
+			out.Mode = RedirectAppend
+		default:
+			return nil, fmt.Errorf("unknown redirect mode %q", node.Mode)
+		}
+		return out, nil
+	case "redirect_in":
+		command, err := importNode(node.Command)
+		if err != nil {
+			return nil, err
+		}
+		if node.Input == nil || node.Input.Type != "command" {
+			return nil, fmt.Errorf("redirect input must be a command node")
+		}
+		input, err := importCommand(node.Input)
+		if err != nil {
+			return nil, err
+		}
+		return &RedirectInNode{Command: command, Input: *input}, nil
+	case "group":
+		steps, err := importSteps(node.Steps)
+		if err != nil {
+			return nil, err
+		}
+		return &GroupNode{Kind: node.Kind, Label: node.Label, Steps: steps}, nil
+	case "try":
+		out := &TryNode{HasCatch: node.HasCatch, HasFinally: node.HasFinally}
+		var err error
+		if out.Try, err = importSteps(node.Try); err != nil {
+			return nil, err
+		}
+		if out.Catch, err = importSteps(node.Catch); err != nil {
+			return nil, err
+		}
+		if out.Finally, err = importSteps(node.Finally); err != nil {
+			return nil, err
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unknown node type %q", node.Type)
+	}
+}
+
+func importCommand(node *exportNode) (*CommandNode, error) {
+	cmd := &CommandNode{Decorator: node.Decorator}
+	for _, arg := range node.Args {
+		val := Value{Str: arg.Str, Int: arg.Int, Bool: arg.Bool, Ref: arg.Ref}
+		switch arg.Kind {
+		case "string":
+			val.Kind = ValueString
+		case "int":
+			val.Kind = ValueInt
+		case "bool":
+			val.Kind = ValueBool
+		case "placeholder":
+			val.Kind = ValuePlaceholder
+		default:
+			return nil, fmt.Errorf("arg %q: unknown kind %q", arg.Key, arg.Kind)
+		}
+		cmd.Args = append(cmd.Args, Arg{Key: arg.Key, Val: val})
+	}
+
+	block, err := importSteps(node.Block)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Block = block
+	return cmd, nil
+}
+
+func decodeHex(field, s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", field, err)
+	}
+	return b, nil
+}
+
+func decodeHeaderID(field, s string, dst *[16]byte) error {
+	b, err := decodeHex(field, s)
+	if err != nil {
+		return err
+	}
+	if len(b) != 0 && len(b) != len(dst) {
+		return fmt.Errorf("%s: expected %d bytes, got %d", field, len(dst), len(b))
+	}
+	copy(dst[:], b)
+	return nil
+}
@@ -0,0 +1,190 @@
+package planfmt_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+func shellNode(cmd string) *planfmt.CommandNode {
+	return &planfmt.CommandNode{
+		Decorator: "@shell",
+		Args:      []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: cmd}}},
+	}
+}
+
+// exportTestPlan builds a plan using every node and value kind
+func exportTestPlan() *planfmt.Plan {
+	return &planfmt.Plan{
+		Header: planfmt.PlanHeader{
+			SchemaID:  [16]byte{1, 2, 3},
+			CreatedAt: 1700000000000000000,
+			Compiler:  [16]byte{0xaa, 0xbb},
+			PlanKind:  1,
+			Build:     "github.com/opal-lang/opal/cli v0.3.0 go1.25.0",
+		},
+		Target:   "deploy",
+		PlanSalt: bytes.Repeat([]byte{7}, 32),
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: &planfmt.AndNode{
+				Left:  shellNode("kubectl apply -f k8s/"),
+				Right: &planfmt.OrNode{Left: shellNode("test -f ok"), Right: shellNode("echo missing")},
+			}},
+			{ID: 2, Tree: &planfmt.PipelineNode{Commands: []planfmt.ExecutionNode{
+				shellNode("cat log"),
+				&planfmt.RedirectNode{
+					Source: shellNode("grep ERROR"),
+					Target: *shellNode("errors.txt"),
+					Mode:   planfmt.RedirectAppend,
+				},
+			}}},
+			{ID: 3, Tree: &planfmt.SequenceNode{Nodes: []planfmt.ExecutionNode{
+				shellNode("echo a"),
+				&planfmt.RedirectNode{Source: shellNode("echo b"), Target: *shellNode("out.txt"), Mode: planfmt.RedirectOverwrite},
+			}}},
+			{ID: 4, Tree: &planfmt.CommandNode{
+				Decorator: "@retry",
+				Args: []planfmt.Arg{
+					{Key: "delay", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "2s"}},
+					{Key: "times", Val: planfmt.Value{Kind: planfmt.ValueInt, Int: 3}},
+					{Key: "verbose", Val: planfmt.Value{Kind: planfmt.ValueBool, Bool: true}},
+					{Key: "token", Val: planfmt.Value{Kind: planfmt.ValuePlaceholder, Ref: 2}},
+				},
+				Block: []planfmt.Step{{ID: 5, Tree: shellNode("kubectl rollout status deployment/app")}},
+			}},
+			{ID: 6, Tree: &planfmt.GroupNode{
+				Kind:  "for",
+				Label: "for region in @var.REGIONS [i=0] region=opal:3J98t56A",
+				Steps: []planfmt.Step{{ID: 7, Tree: shellNode("deploy opal:3J98t56A")}},
+			}},
+			{ID: 8, Tree: &planfmt.TryNode{
+				Try:        []planfmt.Step{{ID: 9, Tree: shellNode("migrate")}},
+				Catch:      []planfmt.Step{{ID: 10, Tree: shellNode("rollback")}},
+				HasCatch:   true,
+				HasFinally: true,
+			}},
+			{ID: 11, Tree: &planfmt.PipelineNode{Commands: []planfmt.ExecutionNode{
+				&planfmt.RedirectInNode{Command: shellNode("cat"), Input: *shellNode("in.txt")},
+				shellNode("sort"),
+			}}},
+		},
+		SecretUses: []planfmt.SecretUse{
+			{DisplayID: "opal:3J98t56A", SiteID: "site-7", Site: "root/step-7/@shell[0]/params/command"},
+		},
+		Provenance: planfmt.Provenance{
+			SourceDigest: [32]byte{9, 8, 7},
+			Decorators:   []planfmt.DecoratorVersion{{Path: "retry", Version: "1.1.0"}, {Path: "shell", Version: "1.0.0"}},
+		},
+	}
+}
+
+// TestExportRoundTrip verifies JSON and YAML documents read back to a plan
+// with the same hash and structure
+func TestExportRoundTrip(t *testing.T) {
+	formats := []struct {
+		name  string
+		write func(*bytes.Buffer, *planfmt.Plan) error
+		read  func(*bytes.Buffer) (*planfmt.Plan, error)
+	}{
+		{
+			name:  "json",
+			write: func(b *bytes.Buffer, p *planfmt.Plan) error { return planfmt.WriteJSON(b, p) },
+			read:  func(b *bytes.Buffer) (*planfmt.Plan, error) { return planfmt.ReadJSON(b) },
+		},
+		{
+			name:  "yaml",
+			write: func(b *bytes.Buffer, p *planfmt.Plan) error { return planfmt.WriteYAML(b, p) },
+			read:  func(b *bytes.Buffer) (*planfmt.Plan, error) { return planfmt.ReadYAML(b) },
+		},
+	}
+
+	for _, tt := range formats {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := exportTestPlan()
+			_, priv, err := ed25519.GenerateKey(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantHash, err := planfmt.Write(&bytes.Buffer{}, plan)
+			if err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			plan.Signatures = []planfmt.Signature{planfmt.Sign(priv, wantHash)}
+
+			var doc bytes.Buffer
+			if err := tt.write(&doc, plan); err != nil {
+				t.Fatalf("export failed: %v", err)
+			}
+			text := doc.String()
+			for _, want := range []string{"opal-plan", "redirect", "append", "redirect_in", "placeholder", "has_finally", "opal:3J98t56A", "source_digest"} {
+				if !strings.Contains(text, want) {
+					t.Errorf("document missing %q:\n%s", want, text)
+				}
+			}
+
+			got, err := tt.read(&doc)
+			if err != nil {
+				t.Fatalf("import failed: %v", err)
+			}
+			gotHash, err := planfmt.Write(&bytes.Buffer{}, got)
+			if err != nil {
+				t.Fatalf("Write of imported plan failed: %v", err)
+			}
+			if gotHash != wantHash {
+				t.Errorf("hash mismatch: got %x, want %x", gotHash, wantHash)
+			}
+
+			opts := []cmp.Option{cmpopts.IgnoreUnexported(planfmt.Plan{}, planfmt.PlanHeader{}), cmpopts.EquateEmpty()}
+			if diff := cmp.Diff(plan, got, opts...); diff != "" {
+				t.Errorf("plan mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestExportRejectsTamperedDocument verifies edits that change the plan
+// are caught by the document hash
+func TestExportRejectsTamperedDocument(t *testing.T) {
+	var doc bytes.Buffer
+	if err := planfmt.WriteJSON(&doc, exportTestPlan()); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	tampered := strings.Replace(doc.String(), "kubectl apply -f k8s/", "kubectl delete -f k8s/", 1)
+
+	_, err := planfmt.ReadJSON(strings.NewReader(tampered))
+	if err == nil || !strings.Contains(err.Error(), "plan hash mismatch") {
+		t.Errorf("Expected hash mismatch error, got %v", err)
+	}
+}
+
+func TestExportReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"wrong format", `{"format": "other", "version": 1}`, "not an opal plan document"},
+		{"wrong version", `{"format": "opal-plan", "version": 2}`, "unsupported plan document version 2"},
+		{"unknown field", `{"format": "opal-plan", "version": 1, "extra": true}`, "unknown field"},
+		{"unknown node", `{"format": "opal-plan", "version": 1, "steps": [{"id": 1, "tree": {"type": "loop"}}]}`, `unknown node type "loop"`},
+		{"missing tree", `{"format": "opal-plan", "version": 1, "steps": [{"id": 1}]}`, "missing execution node"},
+		{"bad arg kind", `{"format": "opal-plan", "version": 1, "steps": [{"id": 1, "tree": {"type": "command", "decorator": "@shell", "args": [{"key": "x", "kind": "float"}]}}]}`, `unknown kind "float"`},
+		{"bad salt", `{"format": "opal-plan", "version": 1, "plan_salt": "zz"}`, "plan_salt"},
+		{"redirect input not a command", `{"format": "opal-plan", "version": 1, "steps": [{"id": 1, "tree": {"type": "redirect_in", "command": {"type": "command", "decorator": "@shell"}, "input": {"type": "group"}}}]}`, "redirect input must be a command node"},
+		{"duplicate step", `{"format": "opal-plan", "version": 1, "steps": [{"id": 1, "tree": {"type": "group"}}, {"id": 1, "tree": {"type": "group"}}]}`, "duplicate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planfmt.ReadJSON(strings.NewReader(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package formatter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+// DriftCause categorizes why a fresh plan no longer matches its contract.
+type DriftCause string
+
+const (
+	// DriftSourceChanged means steps were added, removed or edited.
+	DriftSourceChanged DriftCause = "source_changed"
+	// DriftValueChanged means a step is unchanged apart from a resolved
+	// value's DisplayID (e.g., @env.REPLICAS or a secret now has a
+	// different value). The value's source is not part of the plan.
+	DriftValueChanged DriftCause = "value_changed"
+	// DriftSecretUseMoved means a value is now authorized at different sites.
+	DriftSecretUseMoved DriftCause = "secret_use_moved"
+	// DriftDecoratorVersionChanged means a decorator the plan uses has a
+	// different version (e.g., @retry 1.0.0 → 1.1.0).
+	DriftDecoratorVersionChanged DriftCause = "decorator_version_changed"
+	// DriftCompilerChanged means the plan was made by a different opal
+	// build. The build is outside the hash, so this is only ever a note.
+	DriftCompilerChanged DriftCause = "compiler_changed"
+)
+
+// Drift is one classified difference between a contract and a fresh plan.
+type Drift struct {
+	Cause    DriftCause `json:"cause"`
+	Step     int        `json:"step,omitempty"`     // Step number (1-indexed), 0 if not step-specific
+	Expected string     `json:"expected,omitempty"` // Contract side (step, DisplayID, site or version)
+	Actual   string     `json:"actual,omitempty"`   // Fresh plan side
+	Detail   string     `json:"detail"`             // Human-readable summary
+}
+
+// DriftReport is the machine-readable result of contract verification.
+type DriftReport struct {
+	Target       string       `json:"target"`
+	Verified     bool         `json:"verified"`
+	ContractHash string       `json:"contract_hash"`
+	FreshHash    string       `json:"fresh_hash"`
+	Causes       []DriftCause `json:"causes"` // Distinct causes, sorted
+	Drifts       []Drift      `json:"drifts"`
+	Notes        []Drift      `json:"notes,omitempty"` // Differences outside the hash; they never fail verification
+	Error        string       `json:"error,omitempty"` // Why verification stopped before comparing plans
+}
+
+// displayIDPattern matches value placeholders in formatted steps
+// (e.g., "opal:3J98t56A" or "opal:s:3J98t56A").
+var displayIDPattern = regexp.MustCompile(`opal:[A-Za-z0-9_:-]+`)
+
+// ClassifyDrift compares a contract plan with a fresh plan and explains
+// each difference. Plans that hash equal produce no drifts.
+func ClassifyDrift(expected, actual *planfmt.Plan) []Drift {
+	var drifts []Drift
+	diff := Diff(expected, actual)
+	for _, d := range diff.Modified {
+		drifts = append(drifts, classifyModifiedStep(d)...)
+	}
+	for _, d := range diff.Added {
+		drifts = append(drifts, Drift{
+			Cause:  DriftSourceChanged,
+			Step:   d.StepNum,
+			Actual: d.Actual,
+			Detail: fmt.Sprintf("step %d added", d.StepNum),
+		})
+	}
+	for _, d := range diff.Removed {
+		drifts = append(drifts, Drift{
+			Cause:    DriftSourceChanged,
+			Step:     d.StepNum,
+			Expected: d.Expected,
+			Detail:   fmt.Sprintf("step %d removed", d.StepNum),
+		})
+	}
+
+	drifts = append(drifts, classifySecretUses(expected.SecretUses, actual.SecretUses)...)
+	return append(drifts, classifyProvenance(&expected.Provenance, &actual.Provenance, drifts)...)
+}
+
+// classifyProvenance reports changed decorator versions. A changed source digest is only reported when no step change already
+// explains it (e.g., an edited declaration whose value is unused).
+// Contracts written before provenance existed have none to compare.
+func classifyProvenance(expected, actual *planfmt.Provenance, found []Drift) []Drift {
+	if expected.IsZero() || actual.IsZero() {
+		return nil
+	}
+
+	var drifts []Drift
+	actualVersions := make(map[string]string, len(actual.Decorators))
+	for _, d := range actual.Decorators {
+		actualVersions[d.Path] = d.Version
+	}
+	for _, d := range expected.Decorators {
+		version, ok := actualVersions[d.Path]
+		if !ok || version == d.Version {
+			continue // Removed decorators show up as step changes
+		}
+		drifts = append(drifts, Drift{
+			Cause:    DriftDecoratorVersionChanged,
+			Expected: "@" + d.Path + " " + d.Version,
+			Actual:   "@" + d.Path + " " + version,
+			Detail:   fmt.Sprintf("decorator @%s changed %s→%s", d.Path, formatVersion(d.Version), formatVersion(version)),
+		})
+	}
+
+	if expected.SourceDigest != actual.SourceDigest && !hasCause(found, DriftSourceChanged) {
+		drifts = append(drifts, Drift{
+			Cause:    DriftSourceChanged,
+			Expected: fmt.Sprintf("%x", expected.SourceDigest),
+			Actual:   fmt.Sprintf("%x", actual.SourceDigest),
+			Detail:   fmt.Sprintf("source digest changed from %x to %x", expected.SourceDigest[:6], actual.SourceDigest[:6]),
+		})
+	}
+	return drifts
+}
+
+// ClassifyNotes reports differences the plan hash does not cover: a
+// different opal build. They never fail verification. Plans without a
+// recorded build have none to compare.
+func ClassifyNotes(expected, actual *planfmt.Plan) []Drift {
+	was, now := expected.Header.Build, actual.Header.Build
+	if was == "" || now == "" || was == now {
+		return nil
+	}
+	return []Drift{{
+		Cause:    DriftCompilerChanged,
+		Expected: was,
+		Actual:   now,
+		Detail:   fmt.Sprintf("compiler changed from %s to %s", was, now),
+	}}
+}
+
+// formatVersion formats a decorator version, which may be unset.
+func formatVersion(version string) string {
+	if version == "" {
+		return "unversioned"
+	}
+	return version
+}
+
+// hasCause reports whether any drift has the given cause.
+func hasCause(drifts []Drift, cause DriftCause) bool {
+	for _, d := range drifts {
+		if d.Cause == cause {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyModifiedStep separates value changes from edits. A step that is
+// identical once DisplayIDs are masked only differs in resolved values.
+func classifyModifiedStep(d StepDiff) []Drift {
+	maskedExpected := displayIDPattern.ReplaceAllString(d.Expected, "opal:*")
+	maskedActual := displayIDPattern.ReplaceAllString(d.Actual, "opal:*")
+	if maskedExpected != maskedActual {
+		return []Drift{{
+			Cause:    DriftSourceChanged,
+			Step:     d.StepNum,
+			Expected: d.Expected,
+			Actual:   d.Actual,
+			Detail:   fmt.Sprintf("step %d changed", d.StepNum),
+		}}
+	}
+
+	// Same shape: pair up placeholders by position
+	expectedIDs := displayIDPattern.FindAllString(d.Expected, -1)
+	actualIDs := displayIDPattern.FindAllString(d.Actual, -1)
+	var drifts []Drift
+	seen := make(map[string]bool)
+	for i := range expectedIDs {
+		if expectedIDs[i] == actualIDs[i] || seen[expectedIDs[i]+actualIDs[i]] {
+			continue
+		}
+		seen[expectedIDs[i]+actualIDs[i]] = true
+		drifts = append(drifts, Drift{
+			Cause:    DriftValueChanged,
+			Step:     d.StepNum,
+			Expected: expectedIDs[i],
+			Actual:   actualIDs[i],
+			Detail:   fmt.Sprintf("step %d: value %s is now %s", d.StepNum, expectedIDs[i], actualIDs[i]),
+		})
+	}
+	return drifts
+}
+
+// classifySecretUses reports values authorized at different sites. Uses of
+// values that changed are skipped: their DisplayIDs differ, so they are
+// already covered by a value_changed drift.
+func classifySecretUses(expected, actual []planfmt.SecretUse) []Drift {
+	expectedSites := secretUseSites(expected)
+	actualSites := secretUseSites(actual)
+
+	displayIDs := make([]string, 0, len(expectedSites))
+	for id := range expectedSites {
+		if _, ok := actualSites[id]; ok {
+			displayIDs = append(displayIDs, id)
+		}
+	}
+	sort.Strings(displayIDs)
+
+	var drifts []Drift
+	for _, id := range displayIDs {
+		was := strings.Join(expectedSites[id], ", ")
+		now := strings.Join(actualSites[id], ", ")
+		if was == now {
+			continue
+		}
+		drifts = append(drifts, Drift{
+			Cause:    DriftSecretUseMoved,
+			Expected: was,
+			Actual:   now,
+			Detail:   fmt.Sprintf("%s used at %s, was %s", id, now, was),
+		})
+	}
+	return drifts
+}
+
+// secretUseSites groups use sites by DisplayID, sorted.
+func secretUseSites(uses []planfmt.SecretUse) map[string][]string {
+	sites := make(map[string][]string)
+	for _, use := range uses {
+		sites[use.DisplayID] = append(sites[use.DisplayID], use.Site)
+	}
+	for id := range sites {
+		sort.Strings(sites[id])
+	}
+	return sites
+}
+
+// NewDriftReport builds the verification report for a contract and a
+// fresh plan with the given hashes.
+func NewDriftReport(contractPlan, freshPlan *planfmt.Plan, contractHash, freshHash [32]byte) *DriftReport {
+	report := &DriftReport{
+		Target:       contractPlan.Target,
+		Verified:     contractHash == freshHash,
+		ContractHash: fmt.Sprintf("%x", contractHash),
+		FreshHash:    fmt.Sprintf("%x", freshHash),
+		Causes:       []DriftCause{},
+		Drifts:       []Drift{},
+		Notes:        ClassifyNotes(contractPlan, freshPlan),
+	}
+	if report.Verified {
+		return report
+	}
+
+	report.Drifts = append(report.Drifts, ClassifyDrift(contractPlan, freshPlan)...)
+	if len(report.Drifts) == 0 {
+		// Hashes differ in something the step diff does not show
+		// (e.g., a secret used at an additional site)
+		report.Drifts = append(report.Drifts, Drift{
+			Cause:  DriftSourceChanged,
+			Detail: "plan body changed",
+		})
+	}
+	seen := make(map[DriftCause]bool)
+	for _, d := range report.Drifts {
+		if !seen[d.Cause] {
+			seen[d.Cause] = true
+			report.Causes = append(report.Causes, d.Cause)
+		}
+	}
+	sort.Slice(report.Causes, func(i, j int) bool { return report.Causes[i] < report.Causes[j] })
+	return report
+}
+
+// FormatDriftCauses returns a human-readable list of drift causes.
+func FormatDriftCauses(drifts []Drift, useColor bool) string {
+	if len(drifts) == 0 {
+		return ""
+	}
+
+	yellow := ""
+	reset := ""
+	if useColor {
+		yellow = ColorYellow
+		reset = ColorReset
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%sDrift causes:%s\n", yellow, reset)
+	for _, d := range drifts {
+		fmt.Fprintf(&b, "  %-25s %s\n", d.Cause, d.Detail)
+	}
+	fmt.Fprintln(&b)
+	return b.String()
+}
@@ -0,0 +1,214 @@
+package formatter_test
+
+import (
+	"testing"
+
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/core/planfmt/formatter"
+)
+
+// shellPlan builds a plan with one @shell step per command
+func shellPlan(target string, commands ...string) *planfmt.Plan {
+	plan := &planfmt.Plan{Target: target}
+	for i, cmd := range commands {
+		plan.Steps = append(plan.Steps, planfmt.Step{
+			ID:   uint64(i + 1),
+			Tree: &planfmt.CommandNode{Decorator: "@shell", Args: []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: cmd}}}},
+		})
+	}
+	return plan
+}
+
+// TestClassifyDrift verifies each kind of mismatch gets its cause
+func TestClassifyDrift(t *testing.T) {
+	tests := []struct {
+		name       string
+		expected   *planfmt.Plan
+		actual     *planfmt.Plan
+		wantCauses []formatter.DriftCause
+	}{
+		{
+			name:     "identical",
+			expected: shellPlan("deploy", "echo opal:AAAA"),
+			actual:   shellPlan("deploy", "echo opal:AAAA"),
+		},
+		{
+			name:       "value changed",
+			expected:   shellPlan("deploy", "kubectl scale --replicas=opal:AAAA", "echo done"),
+			actual:     shellPlan("deploy", "kubectl scale --replicas=opal:BBBB", "echo done"),
+			wantCauses: []formatter.DriftCause{formatter.DriftValueChanged},
+		},
+		{
+			name:       "command edited",
+			expected:   shellPlan("deploy", "kubectl apply -f opal:AAAA"),
+			actual:     shellPlan("deploy", "kubectl delete -f opal:AAAA"),
+			wantCauses: []formatter.DriftCause{formatter.DriftSourceChanged},
+		},
+		{
+			name:       "command edited and value changed",
+			expected:   shellPlan("deploy", "kubectl apply -f opal:AAAA"),
+			actual:     shellPlan("deploy", "kubectl delete -f opal:BBBB"),
+			wantCauses: []formatter.DriftCause{formatter.DriftSourceChanged},
+		},
+		{
+			name:       "step added",
+			expected:   shellPlan("deploy", "echo a"),
+			actual:     shellPlan("deploy", "echo a", "echo b"),
+			wantCauses: []formatter.DriftCause{formatter.DriftSourceChanged},
+		},
+		{
+			name:       "step removed",
+			expected:   shellPlan("deploy", "echo a", "echo b"),
+			actual:     shellPlan("deploy", "echo a"),
+			wantCauses: []formatter.DriftCause{formatter.DriftSourceChanged},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drifts := formatter.ClassifyDrift(tt.expected, tt.actual)
+			if len(drifts) != len(tt.wantCauses) {
+				t.Fatalf("Expected %d drifts, got %+v", len(tt.wantCauses), drifts)
+			}
+			for i, want := range tt.wantCauses {
+				if drifts[i].Cause != want {
+					t.Errorf("drift %d: got %s, want %s", i, drifts[i].Cause, want)
+				}
+			}
+		})
+	}
+}
+
+// TestClassifyDriftValueChange verifies value drifts name both DisplayIDs,
+// once per distinct change
+func TestClassifyDriftValueChange(t *testing.T) {
+	expected := shellPlan("deploy", "echo opal:s:AAAA opal:s:AAAA opal:s:CCCC")
+	actual := shellPlan("deploy", "echo opal:s:BBBB opal:s:BBBB opal:s:CCCC")
+
+	drifts := formatter.ClassifyDrift(expected, actual)
+	if len(drifts) != 1 {
+		t.Fatalf("Expected 1 drift, got %+v", drifts)
+	}
+	d := drifts[0]
+	if d.Step != 1 || d.Expected != "opal:s:AAAA" || d.Actual != "opal:s:BBBB" {
+		t.Errorf("Unexpected drift: %+v", d)
+	}
+}
+
+// TestClassifyDriftSecretUseMoved verifies a value authorized at a
+// different site is reported even when the steps look the same
+func TestClassifyDriftSecretUseMoved(t *testing.T) {
+	expected := shellPlan("deploy", "echo a")
+	expected.SecretUses = []planfmt.SecretUse{{DisplayID: "opal:AAAA", SiteID: "s1", Site: "root/step-1/@shell[0]/params/command"}}
+	actual := shellPlan("deploy", "echo a")
+	actual.SecretUses = []planfmt.SecretUse{{DisplayID: "opal:AAAA", SiteID: "s2", Site: "root/step-1/@retry[0]/params/token"}}
+
+	drifts := formatter.ClassifyDrift(expected, actual)
+	if len(drifts) != 1 || drifts[0].Cause != formatter.DriftSecretUseMoved {
+		t.Fatalf("Expected secret_use_moved, got %+v", drifts)
+	}
+	if drifts[0].Actual != "root/step-1/@retry[0]/params/token" {
+		t.Errorf("Actual site: got %q", drifts[0].Actual)
+	}
+
+	// A value that changed is not also reported as moved
+	actual.SecretUses[0].DisplayID = "opal:BBBB"
+	if drifts := formatter.ClassifyDrift(expected, actual); len(drifts) != 0 {
+		t.Errorf("Expected no drifts for a changed DisplayID, got %+v", drifts)
+	}
+}
+
+// TestNewDriftReport verifies the report summarizes distinct causes
+func TestNewDriftReport(t *testing.T) {
+	expected := shellPlan("deploy", "echo opal:AAAA", "echo a")
+	actual := shellPlan("deploy", "echo opal:BBBB", "echo b", "echo c")
+
+	report := formatter.NewDriftReport(expected, actual, [32]byte{1}, [32]byte{2})
+	if report.Verified {
+		t.Error("Expected unverified report")
+	}
+	if len(report.Causes) != 2 || report.Causes[0] != formatter.DriftSourceChanged || report.Causes[1] != formatter.DriftValueChanged {
+		t.Errorf("Causes: got %v", report.Causes)
+	}
+	if len(report.Drifts) != 3 {
+		t.Errorf("Expected 3 drifts, got %+v", report.Drifts)
+	}
+
+	verified := formatter.NewDriftReport(expected, expected, [32]byte{1}, [32]byte{1})
+	if !verified.Verified || len(verified.Causes) != 0 || len(verified.Drifts) != 0 {
+		t.Errorf("Expected clean verified report, got %+v", verified)
+	}
+
+	// Hashes can differ in ways the step diff does not show
+	unexplained := formatter.NewDriftReport(expected, expected, [32]byte{1}, [32]byte{2})
+	if len(unexplained.Drifts) != 1 || unexplained.Drifts[0].Cause != formatter.DriftSourceChanged {
+		t.Errorf("Expected fallback source_changed drift, got %+v", unexplained.Drifts)
+	}
+}
+
+// TestClassifyDriftProvenance verifies decorator version and source digest
+// changes are reported when the steps are unchanged
+func TestClassifyDriftProvenance(t *testing.T) {
+	withProvenance := func(retryVersion string, digest byte) *planfmt.Plan {
+		plan := shellPlan("deploy", "echo a")
+		plan.Provenance = planfmt.Provenance{
+			SourceDigest: [32]byte{digest},
+			Decorators: []planfmt.DecoratorVersion{
+				{Path: "retry", Version: retryVersion},
+				{Path: "shell", Version: "1.0.0"},
+			},
+		}
+		return plan
+	}
+	expected := withProvenance("1.0", 1)
+
+	drifts := formatter.ClassifyDrift(expected, withProvenance("1.1", 1))
+	if len(drifts) != 1 || drifts[0].Cause != formatter.DriftDecoratorVersionChanged {
+		t.Fatalf("Expected decorator_version_changed, got %+v", drifts)
+	}
+	if drifts[0].Detail != "decorator @retry changed 1.0→1.1" {
+		t.Errorf("Detail: got %q", drifts[0].Detail)
+	}
+
+	drifts = formatter.ClassifyDrift(expected, withProvenance("1.0", 2))
+	if len(drifts) != 1 || drifts[0].Cause != formatter.DriftSourceChanged {
+		t.Fatalf("Expected source_changed for the digest, got %+v", drifts)
+	}
+
+	// An edited step already explains the digest change
+	edited := withProvenance("1.0", 2)
+	edited.Steps = shellPlan("deploy", "echo b").Steps
+	if drifts := formatter.ClassifyDrift(expected, edited); len(drifts) != 1 {
+		t.Errorf("Expected only the step drift, got %+v", drifts)
+	}
+
+	// Contracts without provenance have nothing to compare
+	if drifts := formatter.ClassifyDrift(shellPlan("deploy", "echo a"), expected); len(drifts) != 0 {
+		t.Errorf("Expected no drifts against a contract without provenance, got %+v", drifts)
+	}
+}
+
+// TestDriftReportCompilerNote verifies a different opal build is a note that
+// does not fail verification
+func TestDriftReportCompilerNote(t *testing.T) {
+	withBuild := func(build string) *planfmt.Plan {
+		plan := shellPlan("deploy", "echo a")
+		plan.Header.Build = build
+		return plan
+	}
+
+	report := formatter.NewDriftReport(withBuild("opal v1"), withBuild("opal v2"), [32]byte{1}, [32]byte{1})
+	if !report.Verified || len(report.Drifts) != 0 {
+		t.Errorf("Expected verified report without drifts, got %+v", report)
+	}
+	if len(report.Notes) != 1 || report.Notes[0].Cause != formatter.DriftCompilerChanged {
+		t.Fatalf("Expected compiler_changed note, got %+v", report.Notes)
+	}
+	if report.Notes[0].Detail != "compiler changed from opal v1 to opal v2" {
+		t.Errorf("Detail: got %q", report.Notes[0].Detail)
+	}
+
+	if notes := formatter.ClassifyNotes(withBuild(""), withBuild("opal v2")); len(notes) != 0 {
+		t.Errorf("Expected no notes against a contract without a build, got %+v", notes)
+	}
+}
@@ -44,7 +44,7 @@ func formatExecutionNode(node planfmt.ExecutionNode) string {
 	case *planfmt.PipelineNode:
 		var parts []string
 		for _, elem := range n.Commands {
-			// Pipeline elements can be CommandNode or RedirectNode
+			// Pipeline elements can be CommandNode, RedirectNode or RedirectInNode
 			parts = append(parts, formatExecutionNode(elem))
 		}
 		return strings.Join(parts, " | ")
@@ -58,11 +58,43 @@ func formatExecutionNode(node planfmt.ExecutionNode) string {
 			parts = append(parts, formatExecutionNode(child))
 		}
 		return strings.Join(parts, " ; ")
+	case *planfmt.RedirectNode:
+		op := ">"
+		if n.Mode == planfmt.RedirectAppend {
+			op = ">>"
+		}
+		return fmt.Sprintf("%s %s %s", formatExecutionNode(n.Source), op, formatCommandNode(&n.Target))
+	case *planfmt.RedirectInNode:
+		return fmt.Sprintf("%s < %s", formatExecutionNode(n.Command), formatCommandNode(&n.Input))
+	case *planfmt.GroupNode:
+		var parts []string
+		for i := range n.Steps {
+			parts = append(parts, FormatStep(&n.Steps[i]))
+		}
+		return fmt.Sprintf("%s { %s }", n.Label, strings.Join(parts, " ; "))
+	case *planfmt.TryNode:
+		text := "try " + formatBlock(n.Try)
+		if n.HasCatch {
+			text += " catch " + formatBlock(n.Catch)
+		}
+		if n.HasFinally {
+			text += " finally " + formatBlock(n.Finally)
+		}
+		return text
 	default:
 		return fmt.Sprintf("(unknown: %T)", node)
 	}
 }
 
+// formatBlock formats steps as "{ step ; step }"
+func formatBlock(steps []planfmt.Step) string {
+	var parts []string
+	for i := range steps {
+		parts = append(parts, FormatStep(&steps[i]))
+	}
+	return fmt.Sprintf("{ %s }", strings.Join(parts, " ; "))
+}
+
 // formatCommandNode formats a single command node
 func formatCommandNode(cmd *planfmt.CommandNode) string {
 	// Special case: @shell with single "command" arg - show command directly
@@ -71,6 +71,36 @@ func TestFormatStep(t *testing.T) {
 			},
 			expected: `@shell echo hello | @shell grep hello`,
 		},
+		{
+			name: "input and output redirect",
+			step: planfmt.Step{
+				ID: 1,
+				Tree: &planfmt.RedirectNode{
+					Source: &planfmt.RedirectInNode{
+						Command: &planfmt.CommandNode{
+							Decorator: "@shell",
+							Args: []planfmt.Arg{
+								{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "sort"}},
+							},
+						},
+						Input: planfmt.CommandNode{
+							Decorator: "@shell",
+							Args: []planfmt.Arg{
+								{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "in.txt"}},
+							},
+						},
+					},
+					Target: planfmt.CommandNode{
+						Decorator: "@shell",
+						Args: []planfmt.Arg{
+							{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "out.txt"}},
+						},
+					},
+					Mode: planfmt.RedirectAppend,
+				},
+			},
+			expected: `@shell sort < @shell in.txt >> @shell out.txt`,
+		},
 		{
 			name: "retry decorator",
 			step: planfmt.Step{
@@ -84,6 +114,29 @@ func TestFormatStep(t *testing.T) {
 			},
 			expected: `@retry(attempts=3)`,
 		},
+		{
+			name: "try with empty catch and finally",
+			step: planfmt.Step{
+				ID: 1,
+				Tree: &planfmt.TryNode{
+					Try: []planfmt.Step{{ID: 2, Tree: &planfmt.CommandNode{
+						Decorator: "@shell",
+						Args: []planfmt.Arg{
+							{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "deploy"}},
+						},
+					}}},
+					Finally: []planfmt.Step{{ID: 3, Tree: &planfmt.CommandNode{
+						Decorator: "@shell",
+						Args: []planfmt.Arg{
+							{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "cleanup"}},
+						},
+					}}},
+					HasCatch:   true,
+					HasFinally: true,
+				},
+			},
+			expected: `try { @shell deploy } catch {  } finally { @shell cleanup }`,
+		},
 	}
 
 	for _, tt := range tests {
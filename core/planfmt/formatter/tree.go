@@ -60,9 +60,10 @@ func renderTreeStep(w io.Writer, step planfmt.Step, isLast, useColor bool) {
 	treeStr := renderExecutionNode(step.Tree, useColor)
 	_, _ = fmt.Fprintf(w, "%s%s\n", prefix, treeStr)
 
-	// Render nested blocks if this is a CommandNode with a Block
-	if cmd, ok := step.Tree.(*planfmt.CommandNode); ok && len(cmd.Block) > 0 {
-		renderNestedBlock(w, cmd.Block, "   ", useColor)
+	// Render nested blocks (decorator blocks and groups), continuing the
+	// parent's branch line when more steps follow
+	if nested := nestedSteps(step.Tree); len(nested) > 0 {
+		renderNestedBlock(w, nested, childIndent("", isLast), useColor)
 	}
 }
 
@@ -81,13 +82,43 @@ func renderNestedBlock(w io.Writer, steps []planfmt.Step, indent string, useColo
 		_, _ = fmt.Fprintf(w, "%s%s\n", prefix, treeStr)
 
 		// Recursively render nested blocks
-		if cmd, ok := step.Tree.(*planfmt.CommandNode); ok && len(cmd.Block) > 0 {
-			newIndent := indent + "   "
-			renderNestedBlock(w, cmd.Block, newIndent, useColor)
+		if nested := nestedSteps(step.Tree); len(nested) > 0 {
+			renderNestedBlock(w, nested, childIndent(indent, isLast), useColor)
 		}
 	}
 }
 
+// childIndent returns the indent for the children of a step rendered at indent.
+func childIndent(indent string, isLast bool) string {
+	if isLast {
+		return indent + "   "
+	}
+	return indent + "│  "
+}
+
+// nestedSteps returns the steps rendered beneath node: a decorator's
+// block, a group's steps, or a try block followed by its catch and
+// finally sections.
+func nestedSteps(node planfmt.ExecutionNode) []planfmt.Step {
+	switch n := node.(type) {
+	case *planfmt.CommandNode:
+		return n.Block
+	case *planfmt.GroupNode:
+		return n.Steps
+	case *planfmt.TryNode:
+		steps := append([]planfmt.Step(nil), n.Try...)
+		if n.HasCatch {
+			steps = append(steps, planfmt.Step{Tree: &planfmt.GroupNode{Label: "catch", Steps: n.Catch}})
+		}
+		if n.HasFinally {
+			steps = append(steps, planfmt.Step{Tree: &planfmt.GroupNode{Label: "finally", Steps: n.Finally}})
+		}
+		return steps
+	default:
+		return nil
+	}
+}
+
 // renderExecutionNode renders an execution node to a string
 func renderExecutionNode(node planfmt.ExecutionNode, useColor bool) string {
 	switch n := node.(type) {
@@ -101,6 +132,20 @@ func renderExecutionNode(node planfmt.ExecutionNode, useColor bool) string {
 		return renderOrNode(n, useColor)
 	case *planfmt.SequenceNode:
 		return renderSequenceNode(n, useColor)
+	case *planfmt.RedirectNode:
+		op := ">"
+		if n.Mode == planfmt.RedirectAppend {
+			op = ">>"
+		}
+		return fmt.Sprintf("%s %s %s", renderExecutionNode(n.Source, useColor), op, renderRedirectEnd(&n.Target, useColor))
+	case *planfmt.RedirectInNode:
+		return fmt.Sprintf("%s < %s", renderExecutionNode(n.Command, useColor), renderRedirectEnd(&n.Input, useColor))
+	case *planfmt.GroupNode:
+		// Steps are rendered beneath the label
+		return Colorize(n.Label, ColorCyan, useColor)
+	case *planfmt.TryNode:
+		// Blocks are rendered beneath the keyword
+		return Colorize("try", ColorCyan, useColor)
 	default:
 		return fmt.Sprintf("(unknown node type: %T)", node)
 	}
@@ -113,11 +158,20 @@ func renderCommandNode(cmd *planfmt.CommandNode, useColor bool) string {
 	return fmt.Sprintf("%s %s", decorator, commandStr)
 }
 
+// renderRedirectEnd renders a redirect target or input: the path for
+// @shell files, the decorator call otherwise
+func renderRedirectEnd(cmd *planfmt.CommandNode, useColor bool) string {
+	if cmd.Decorator == "@shell" {
+		return getCommandString(cmd)
+	}
+	return renderCommandNode(cmd, useColor)
+}
+
 // renderPipelineNode renders a pipeline (cmd1 | cmd2 | cmd3)
 func renderPipelineNode(pipe *planfmt.PipelineNode, useColor bool) string {
 	var parts []string
 	for _, elem := range pipe.Commands {
-		// Pipeline elements can be CommandNode or RedirectNode
+		// Pipeline elements can be CommandNode, RedirectNode or RedirectInNode
 		parts = append(parts, renderExecutionNode(elem, useColor))
 	}
 	return strings.Join(parts, " | ")
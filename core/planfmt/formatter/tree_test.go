@@ -198,3 +198,80 @@ func TestFormatTree_WithColor(t *testing.T) {
 		t.Error("Expected ANSI color codes when color is enabled")
 	}
 }
+
+func TestFormatTree_WithGroups(t *testing.T) {
+	shell := func(cmd string) *planfmt.CommandNode {
+		return &planfmt.CommandNode{
+			Decorator: "@shell",
+			Args:      []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: cmd}}},
+		}
+	}
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: &planfmt.GroupNode{
+				Kind:  "for",
+				Label: "for service in @var.SERVICES [i=0] service=opal:a",
+				Steps: []planfmt.Step{{ID: 2, Tree: shell("kubectl apply -f k8s/opal:a/")}},
+			}},
+			{ID: 3, Tree: &planfmt.GroupNode{
+				Kind:  "for",
+				Label: "for service in @var.SERVICES [i=1] service=opal:b",
+				Steps: []planfmt.Step{{ID: 4, Tree: shell("kubectl apply -f k8s/opal:b/")}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	FormatTree(&buf, plan, false)
+
+	expected := `deploy:
+├─ for service in @var.SERVICES [i=0] service=opal:a
+│  └─ @shell kubectl apply -f k8s/opal:a/
+└─ for service in @var.SERVICES [i=1] service=opal:b
+   └─ @shell kubectl apply -f k8s/opal:b/
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+func TestFormatTree_WithTry(t *testing.T) {
+	shell := func(cmd string) *planfmt.CommandNode {
+		return &planfmt.CommandNode{
+			Decorator: "@shell",
+			Args:      []planfmt.Arg{{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: cmd}}},
+		}
+	}
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: &planfmt.TryNode{
+				Try: []planfmt.Step{
+					{ID: 2, Tree: shell("kubectl apply -f k8s/")},
+					{ID: 3, Tree: shell("kubectl rollout status deployment/app")},
+				},
+				Catch:      []planfmt.Step{{ID: 4, Tree: shell("kubectl rollout undo deployment/app")}},
+				Finally:    []planfmt.Step{{ID: 5, Tree: shell("kubectl delete pod -l job=temp")}},
+				HasCatch:   true,
+				HasFinally: true,
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	FormatTree(&buf, plan, false)
+
+	expected := `deploy:
+└─ try
+   ├─ @shell kubectl apply -f k8s/
+   ├─ @shell kubectl rollout status deployment/app
+   ├─ catch
+   │  └─ @shell kubectl rollout undo deployment/app
+   └─ finally
+      └─ @shell kubectl delete pod -l job=temp
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
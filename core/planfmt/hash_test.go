@@ -171,3 +171,46 @@ func TestHashNonZero(t *testing.T) {
 		t.Error("Hash is all zeros - hashing not working")
 	}
 }
+
+// TestPipefailCoveredByHash verifies the pipefail setting reads back and
+// changes the hash, with or without provenance
+func TestPipefailCoveredByHash(t *testing.T) {
+	for name, plan := range map[string]*planfmt.Plan{
+		"no provenance": {
+			Target: "deploy",
+			Steps:  []planfmt.Step{{ID: 1, Tree: shellNode("make | tee build.log")}},
+		},
+		"provenance": provenancePlan(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			off, err := planfmt.Write(&bytes.Buffer{}, plan)
+			if err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+
+			plan.Pipefail = true
+			var buf bytes.Buffer
+			on, err := planfmt.Write(&buf, plan)
+			if err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			if on == off {
+				t.Error("setting pipefail did not change the hash")
+			}
+
+			got, readHash, err := planfmt.Read(&buf)
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if readHash != on {
+				t.Errorf("hash mismatch: got %x, want %x", readHash, on)
+			}
+			if !got.Pipefail {
+				t.Error("pipefail not read back")
+			}
+			if got.Provenance.SourceDigest != plan.Provenance.SourceDigest {
+				t.Errorf("provenance source digest = %x, want %x", got.Provenance.SourceDigest, plan.Provenance.SourceDigest)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package planfmt_test
 
 import (
 	"bytes"
+	"compress/flate"
+	"encoding/binary"
 	"strings"
 	"testing"
 
@@ -77,9 +79,13 @@ func TestUnknownFlags(t *testing.T) {
 	}
 }
 
-// TestCompressedFlagRejected verifies that compressed flag is rejected (not implemented yet)
-func TestCompressedFlagRejected(t *testing.T) {
-	plan := &planfmt.Plan{Target: "test"}
+// TestCompressedFlagRequiresCompressedBody verifies that a compressed flag
+// on a plain body is rejected
+func TestCompressedFlagRequiresCompressedBody(t *testing.T) {
+	plan := &planfmt.Plan{
+		Target: "test",
+		Steps:  []planfmt.Step{{ID: 1, Tree: &planfmt.CommandNode{Decorator: "@shell"}}},
+	}
 
 	var buf bytes.Buffer
 	_, err := planfmt.Write(&buf, plan)
@@ -92,19 +98,62 @@ func TestCompressedFlagRejected(t *testing.T) {
 	// Set FlagCompressed (bit 0)
 	data[6] = 0x01
 
-	// Read should reject compressed plans
+	// Read should fail to inflate the plain body
+	_, _, err = planfmt.Read(bytes.NewReader(data))
+	if err == nil {
+		t.Fatal("Expected decompress error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "decompress body") {
+		t.Errorf("Expected 'decompress body' error, got: %v", err)
+	}
+}
+
+// TestDecompressionBombRejected verifies that a small compressed body that
+// inflates past the 32MB body limit is rejected
+func TestDecompressionBombRejected(t *testing.T) {
+	plan := &planfmt.Plan{Target: "test"}
+
+	var buf bytes.Buffer
+	_, err := planfmt.Write(&buf, plan)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	headerLen := binary.LittleEndian.Uint32(buf.Bytes()[8:12])
+	header := buf.Bytes()[20 : 20+headerLen]
+
+	// 33MB of zeros deflates to a few KB
+	var bomb bytes.Buffer
+	zw, err := flate.NewWriter(&bomb, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zw.Write(make([]byte, 33*1024*1024)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := append([]byte(nil), buf.Bytes()[:20]...)
+	binary.LittleEndian.PutUint16(data[6:8], uint16(planfmt.FlagCompressed))
+	binary.LittleEndian.PutUint64(data[12:20], uint64(bomb.Len()))
+	data = append(data, header...)
+	data = append(data, bomb.Bytes()...)
+
 	_, _, err = planfmt.Read(bytes.NewReader(data))
 	if err == nil {
-		t.Fatal("Expected compressed error, got nil")
+		t.Fatal("Expected decompressed size error, got nil")
 	}
 
-	if !strings.Contains(err.Error(), "compressed plans not yet supported") {
-		t.Errorf("Expected 'compressed plans not yet supported' error, got: %v", err)
+	if !strings.Contains(err.Error(), "decompressed body exceeds maximum") {
+		t.Errorf("Expected 'decompressed body exceeds maximum' error, got: %v", err)
 	}
 }
 
-// TestSignedFlagRejected verifies that signed flag is rejected (not implemented yet)
-func TestSignedFlagRejected(t *testing.T) {
+// TestSignedFlagRequiresSignatures verifies that a signed flag without a
+// signature trailer is rejected
+func TestSignedFlagRequiresSignatures(t *testing.T) {
 	plan := &planfmt.Plan{Target: "test"}
 
 	var buf bytes.Buffer
@@ -118,14 +167,14 @@ func TestSignedFlagRejected(t *testing.T) {
 	// Set FlagSigned (bit 1)
 	data[6] = 0x02
 
-	// Read should reject signed plans
+	// Read should reject the missing signatures
 	_, _, err = planfmt.Read(bytes.NewReader(data))
 	if err == nil {
-		t.Fatal("Expected signed error, got nil")
+		t.Fatal("Expected signature error, got nil")
 	}
 
-	if !strings.Contains(err.Error(), "signed plans not yet supported") {
-		t.Errorf("Expected 'signed plans not yet supported' error, got: %v", err)
+	if !strings.Contains(err.Error(), "read signatures") {
+		t.Errorf("Expected 'read signatures' error, got: %v", err)
 	}
 }
 
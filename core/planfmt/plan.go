@@ -62,10 +62,16 @@ type Plan struct {
 	Steps      []Step      // List of steps (newline-separated statements)
 	SecretUses []SecretUse // Authorization list (DisplayID → SiteID mappings)
 	PlanSalt   []byte      // Per-plan random salt (32 bytes, for DisplayID derivation)
+	Provenance Provenance  // Compiler, source digest and decorator versions (covered by the hash)
+	Pipefail   bool        // Pipelines fail when any command in them fails (covered by the hash)
+	Signatures []Signature // Contract approvals (outside the hash, see Signature)
 	Hash       string      // Plan integrity hash (includes SecretUses, computed on Freeze)
 	frozen     bool        // Immutability flag (prevents mutations after Freeze)
 }
 
+// Plan settings: a bit set written after Provenance, omitted when zero
+const settingPipefail uint8 = 1 << 0
+
 // SecretUse records an authorized use-site for a secret.
 // Each SecretUse grants permission for one decorator parameter to unwrap one secret.
 // Site-based authority: secrets accessible ONLY at declared sites, no propagation.
@@ -84,6 +90,12 @@ type PlanHeader struct {
 	Compiler  [16]byte // Build/commit fingerprint
 	PlanKind  uint8    // 0=view, 1=contract, 2=executed
 	_         [3]byte  // Reserved for future use (align to 8 bytes)
+
+	// Build is the full build fingerprint Compiler is derived from (e.g.,
+	// "github.com/opal-lang/opal/cli v0.3.0 go1.25.0 rev=3f2a9c1d0b7e"),
+	// written after the target. Like the rest of the header it is outside
+	// the hash: rebuilding opal does not invalidate contracts.
+	Build string
 }
 
 // Step represents a single step (newline-separated statement).
@@ -244,6 +256,32 @@ func validateNode(node ExecutionNode, stepID uint64, seen map[uint64]bool) error
 				return err
 			}
 		}
+
+	case *RedirectInNode:
+		if err := validateNode(n.Command, stepID, seen); err != nil {
+			return err
+		}
+		if err := validateNode(&n.Input, stepID, seen); err != nil {
+			return err
+		}
+
+	case *GroupNode:
+		// Validate group steps recursively
+		for j := range n.Steps {
+			if err := n.Steps[j].validate(seen); err != nil {
+				return err
+			}
+		}
+
+	case *TryNode:
+		// Validate all three blocks recursively
+		for _, block := range [][]Step{n.Try, n.Catch, n.Finally} {
+			for j := range block {
+				if err := block[j].validate(seen); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
 	return nil
@@ -288,6 +326,26 @@ func sortArgsInNode(node ExecutionNode) {
 		for i := range n.Nodes {
 			sortArgsInNode(n.Nodes[i])
 		}
+
+	case *RedirectNode:
+		sortArgsInNode(n.Source)
+		sortArgsInNode(&n.Target)
+
+	case *RedirectInNode:
+		sortArgsInNode(n.Command)
+		sortArgsInNode(&n.Input)
+
+	case *GroupNode:
+		for i := range n.Steps {
+			n.Steps[i].sortArgs()
+		}
+
+	case *TryNode:
+		for _, block := range [][]Step{n.Try, n.Catch, n.Finally} {
+			for i := range block {
+				block[i].sortArgs()
+			}
+		}
 	}
 }
 
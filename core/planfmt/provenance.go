@@ -0,0 +1,47 @@
+package planfmt
+
+import (
+	"sort"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// SchemaID identifies the body layout this package writes (PlanHeader.SchemaID).
+// Version 1 with steps, PlanSalt, SecretUses and Provenance.
+var SchemaID = [16]byte{0x6f, 0x70, 0x61, 0x6c, 0x2d, 0x70, 0x6c, 0x61, 0x6e, 0x2d, 0x76, 0x31, 0x00, 0x00, 0x00, 0x01}
+
+// Provenance records what a plan was made from: the source it was planned
+// from and the decorators it uses. It is part of the plan body, so the
+// contract hash covers it and verification reports changes to it. The opal
+// build that made the plan is in the header (PlanHeader.Build), outside the hash.
+type Provenance struct {
+	SourceDigest [32]byte           // BLAKE2b-256 of the source tokens the plan was built from (zero if unknown)
+	Decorators   []DecoratorVersion // Decorators the plan uses, sorted by Path
+}
+
+// DecoratorVersion is one entry of the decorator table.
+type DecoratorVersion struct {
+	Path    string // Decorator path without "@" (e.g., "retry", "aws.secret")
+	Version string // Descriptor.Version ("" if unversioned)
+}
+
+// IsZero reports whether no provenance was recorded. Plans without
+// provenance omit the section, so they keep the hashes they had before it existed.
+func (pv *Provenance) IsZero() bool {
+	return pv.SourceDigest == [32]byte{} && len(pv.Decorators) == 0
+}
+
+// CompilerID derives PlanHeader.Compiler from a build fingerprint.
+func CompilerID(fingerprint string) [16]byte {
+	sum := blake2b.Sum256([]byte(fingerprint))
+	var id [16]byte
+	copy(id[:], sum[:16])
+	return id
+}
+
+// sortDecorators sorts the decorator table by path for deterministic encoding.
+func (p *Plan) sortDecorators() {
+	sort.Slice(p.Provenance.Decorators, func(i, j int) bool {
+		return p.Provenance.Decorators[i].Path < p.Provenance.Decorators[j].Path
+	})
+}
@@ -0,0 +1,139 @@
+package planfmt_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+func provenancePlan() *planfmt.Plan {
+	return &planfmt.Plan{
+		Target: "deploy",
+		Steps:  []planfmt.Step{{ID: 1, Tree: shellNode("kubectl apply -f k8s/")}},
+		Provenance: planfmt.Provenance{
+			SourceDigest: [32]byte{1, 2, 3},
+			Decorators: []planfmt.DecoratorVersion{
+				{Path: "shell", Version: "1.0.0"},
+				{Path: "retry", Version: "1.0.0"},
+			},
+		},
+	}
+}
+
+// TestProvenanceRoundTrip verifies the provenance section reads back
+// unchanged, with the decorator table sorted
+func TestProvenanceRoundTrip(t *testing.T) {
+	plan := provenancePlan()
+
+	var buf bytes.Buffer
+	hash, err := planfmt.Write(&buf, plan)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got, readHash, err := planfmt.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if readHash != hash {
+		t.Errorf("hash mismatch: got %x, want %x", readHash, hash)
+	}
+
+	want := planfmt.Provenance{
+		SourceDigest: [32]byte{1, 2, 3},
+		Decorators: []planfmt.DecoratorVersion{
+			{Path: "retry", Version: "1.0.0"},
+			{Path: "shell", Version: "1.0.0"},
+		},
+	}
+	if diff := cmp.Diff(want, got.Provenance); diff != "" {
+		t.Errorf("provenance mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestProvenanceCoveredByHash verifies every provenance field changes the
+// plan hash, and that plans without provenance hash as before
+func TestProvenanceCoveredByHash(t *testing.T) {
+	base, err := planfmt.Write(&bytes.Buffer{}, provenancePlan())
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	changes := map[string]func(*planfmt.Plan){
+		"source digest":     func(p *planfmt.Plan) { p.Provenance.SourceDigest[0] = 0xff },
+		"decorator version": func(p *planfmt.Plan) { p.Provenance.Decorators[1].Version = "1.1.0" },
+		"decorator added": func(p *planfmt.Plan) {
+			p.Provenance.Decorators = append(p.Provenance.Decorators, planfmt.DecoratorVersion{Path: "env"})
+		},
+	}
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			plan := provenancePlan()
+			change(plan)
+			hash, err := planfmt.Write(&bytes.Buffer{}, plan)
+			if err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			if hash == base {
+				t.Errorf("changing the %s did not change the hash", name)
+			}
+		})
+	}
+
+	// No provenance: body is exactly what it was before the section existed
+	plan := provenancePlan()
+	plan.Provenance = planfmt.Provenance{}
+	var withSection, without bytes.Buffer
+	if _, err := planfmt.Write(&without, plan); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	plan.Provenance.SourceDigest[0] = 1
+	if _, err := planfmt.Write(&withSection, plan); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := withSection.Len() - without.Len(); got != 32+2 {
+		t.Errorf("provenance section size = %d, want %d", got, 32+2)
+	}
+}
+
+// TestBuildOutsideHash verifies the build fingerprint reads back from the
+// header without changing the hash, so rebuilding opal keeps contracts valid
+func TestBuildOutsideHash(t *testing.T) {
+	plan := provenancePlan()
+	base, err := planfmt.Write(&bytes.Buffer{}, plan)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	plan.Header.Build = "github.com/opal-lang/opal/cli v0.3.0 go1.25.0 rev=3f2a9c1d0b7e+dirty"
+	var buf bytes.Buffer
+	hash, err := planfmt.Write(&buf, plan)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if hash != base {
+		t.Errorf("the build fingerprint changed the hash")
+	}
+
+	got, _, err := planfmt.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got.Header.Build != plan.Header.Build {
+		t.Errorf("build = %q, want %q", got.Header.Build, plan.Header.Build)
+	}
+}
+
+func TestCompilerID(t *testing.T) {
+	a := planfmt.CompilerID("opal v1")
+	if a == [16]byte{} {
+		t.Error("CompilerID should not be zero")
+	}
+	if a != planfmt.CompilerID("opal v1") {
+		t.Error("CompilerID should be deterministic")
+	}
+	if a == planfmt.CompilerID("opal v2") {
+		t.Error("different fingerprints should have different IDs")
+	}
+}
@@ -55,14 +55,6 @@ func (rd *Reader) ReadPlan() (*Plan, [32]byte, error) {
 		return nil, [32]byte{}, fmt.Errorf("unsupported flags: 0x%04x (unknown bits: 0x%04x)", flags, flags&^knownFlags)
 	}
 
-	// TODO: Implement compression and signature verification
-	if flags&FlagCompressed != 0 {
-		return nil, [32]byte{}, fmt.Errorf("compressed plans not yet supported")
-	}
-	if flags&FlagSigned != 0 {
-		return nil, [32]byte{}, fmt.Errorf("signed plans not yet supported")
-	}
-
 	// Read header length
 	headerLen := binary.LittleEndian.Uint32(preamble[8:12])
 
@@ -100,6 +92,15 @@ func (rd *Reader) ReadPlan() (*Plan, [32]byte, error) {
 		return nil, [32]byte{}, fmt.Errorf("read body: %w", err)
 	}
 
+	// BODY_LEN bounded the compressed size; bound the inflated size too,
+	// since the hash and parser work on the uncompressed body
+	if flags&FlagCompressed != 0 {
+		bodyBuf, err = decompressBody(bodyBuf, maxBodyLen)
+		if err != nil {
+			return nil, [32]byte{}, fmt.Errorf("decompress body: %w", err)
+		}
+	}
+
 	if err := rd.readBody(bytes.NewReader(bodyBuf), plan, maxDepth); err != nil {
 		return nil, [32]byte{}, fmt.Errorf("parse body: %w", err)
 	}
@@ -117,6 +118,22 @@ func (rd *Reader) ReadPlan() (*Plan, [32]byte, error) {
 	// Extract hash
 	var digest [32]byte
 	copy(digest[:], hasher.Sum(nil))
+
+	// Signatures follow the body. Any that fail to verify mean the plan was
+	// altered after signing; which keys are trusted is the caller's decision.
+	if flags&FlagSigned != 0 {
+		sigs, err := readSignatures(rd.r)
+		if err != nil {
+			return nil, [32]byte{}, fmt.Errorf("read signatures: %w", err)
+		}
+		for _, sig := range sigs {
+			if !sig.Verify(digest) {
+				return nil, [32]byte{}, fmt.Errorf("invalid signature by key %s", sig.Fingerprint())
+			}
+		}
+		plan.Signatures = sigs
+	}
+
 	return plan, digest, nil
 }
 
@@ -165,6 +182,13 @@ func (rd *Reader) readHeader(r io.Reader) (*Plan, error) {
 	}
 	plan.Target = string(targetBuf)
 
+	// Read Build fingerprint (optional, absent in older plans)
+	build, err := readString16(r, "build fingerprint")
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	plan.Header.Build = build
+
 	return plan, nil
 }
 
@@ -227,9 +251,74 @@ func (rd *Reader) readBody(r io.Reader, plan *Plan, maxDepth int) error {
 		}
 	}
 
+	// Provenance and settings: optional trailing sections (absent in older plans)
+	if err := rd.readProvenance(r, &plan.Provenance); err != nil {
+		return err
+	}
+	return rd.readSettings(r, plan)
+}
+
+// readSettings reads the plan settings byte, if present
+func (rd *Reader) readSettings(r io.Reader, plan *Plan) error {
+	var settings [1]byte
+	if _, err := io.ReadFull(r, settings[:]); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return fmt.Errorf("read plan settings: %w", err)
+	}
+	if settings[0]&^settingPipefail != 0 {
+		return fmt.Errorf("unknown plan settings %#x", settings[0])
+	}
+	plan.Pipefail = settings[0]&settingPipefail != 0
+	return nil
+}
+
+// readProvenance reads the provenance section, if present
+func (rd *Reader) readProvenance(r io.Reader, pv *Provenance) error {
+	if _, err := io.ReadFull(r, pv.SourceDigest[:]); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return fmt.Errorf("read source digest: %w", err)
+	}
+
+	var count uint16
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return fmt.Errorf("read decorator count: %w", err)
+	}
+	if count > 0 {
+		var err error
+		pv.Decorators = make([]DecoratorVersion, count)
+		for i := range pv.Decorators {
+			if pv.Decorators[i].Path, err = readString16(r, "decorator path"); err != nil {
+				return err
+			}
+			if pv.Decorators[i].Version, err = readString16(r, "decorator version"); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// readString16 reads a 2-byte length followed by the string. Returns
+// io.EOF unwrapped if the input ends before the length.
+func readString16(r io.Reader, fieldName string) (string, error) {
+	var n uint16
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		if err == io.EOF {
+			return "", err
+		}
+		return "", fmt.Errorf("read %s length: %w", fieldName, err)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", fmt.Errorf("read %s: %w", fieldName, err)
+	}
+	return string(b), nil
+}
+
 // readSecretUse reads a single SecretUse entry
 func (rd *Reader) readSecretUse(r io.Reader) (*SecretUse, error) {
 	use := &SecretUse{}
@@ -326,19 +415,19 @@ func (rd *Reader) readExecutionNode(r io.Reader, depth, maxDepth int) (Execution
 		if err := binary.Read(r, binary.LittleEndian, &cmdCount); err != nil {
 			return nil, fmt.Errorf("read pipeline command count: %w", err)
 		}
-		// Read commands (can be CommandNode or RedirectNode)
+		// Read commands (can be CommandNode, RedirectNode or RedirectInNode)
 		commands := make([]ExecutionNode, cmdCount)
 		for i := 0; i < int(cmdCount); i++ {
 			node, err := rd.readExecutionNode(r, depth+1, maxDepth)
 			if err != nil {
 				return nil, fmt.Errorf("read pipeline command %d: %w", i, err)
 			}
-			// Validate that pipeline elements are CommandNode or RedirectNode
+			// Validate that pipeline elements are single (possibly redirected) commands
 			switch node.(type) {
-			case *CommandNode, *RedirectNode:
+			case *CommandNode, *RedirectNode, *RedirectInNode:
 				commands[i] = node
 			default:
-				return nil, fmt.Errorf("pipeline must contain CommandNode or RedirectNode, got %T", node)
+				return nil, fmt.Errorf("pipeline must contain CommandNode, RedirectNode or RedirectInNode, got %T", node)
 			}
 		}
 		return &PipelineNode{Commands: commands}, nil
@@ -386,6 +475,49 @@ func (rd *Reader) readExecutionNode(r io.Reader, depth, maxDepth int) (Execution
 		}
 		return &SequenceNode{Nodes: nodes}, nil
 
+	case 0x06: // GroupNode
+		group, err := rd.readGroup(r, depth+1, maxDepth)
+		if err != nil {
+			return nil, fmt.Errorf("read group node: %w", err)
+		}
+		return group, nil
+
+	case 0x07: // TryNode
+		node, err := rd.readTry(r, depth+1, maxDepth)
+		if err != nil {
+			return nil, fmt.Errorf("read try node: %w", err)
+		}
+		return node, nil
+
+	case 0x08: // RedirectNode
+		var mode byte
+		if err := binary.Read(r, binary.LittleEndian, &mode); err != nil {
+			return nil, fmt.Errorf("read redirect mode: %w", err)
+		}
+		if RedirectMode(mode) != RedirectOverwrite && RedirectMode(mode) != RedirectAppend {
+			return nil, fmt.Errorf("unknown redirect mode: %d", mode)
+		}
+		source, err := rd.readExecutionNode(r, depth+1, maxDepth)
+		if err != nil {
+			return nil, fmt.Errorf("read redirect source: %w", err)
+		}
+		target, err := rd.readCommand(r, depth+1, maxDepth)
+		if err != nil {
+			return nil, fmt.Errorf("read redirect target: %w", err)
+		}
+		return &RedirectNode{Source: source, Target: *target, Mode: RedirectMode(mode)}, nil
+
+	case 0x09: // RedirectInNode
+		command, err := rd.readExecutionNode(r, depth+1, maxDepth)
+		if err != nil {
+			return nil, fmt.Errorf("read redirect-in command: %w", err)
+		}
+		input, err := rd.readCommand(r, depth+1, maxDepth)
+		if err != nil {
+			return nil, fmt.Errorf("read redirect-in input: %w", err)
+		}
+		return &RedirectInNode{Command: command, Input: *input}, nil
+
 	default:
 		return nil, fmt.Errorf("unknown node type: 0x%02x", nodeType)
 	}
@@ -447,6 +579,84 @@ func (rd *Reader) readCommand(r io.Reader, depth, maxDepth int) (*CommandNode, e
 	return cmd, nil
 }
 
+// readGroup reads a group node's kind, label and steps
+func (rd *Reader) readGroup(r io.Reader, depth, maxDepth int) (*GroupNode, error) {
+	group := &GroupNode{}
+
+	// Read kind and label (2-byte length + string each)
+	for _, field := range []struct {
+		name string
+		dst  *string
+	}{{"kind", &group.Kind}, {"label", &group.Label}} {
+		var n uint16
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return nil, fmt.Errorf("read %s length: %w", field.name, err)
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, fmt.Errorf("read %s: %w", field.name, err)
+		}
+		*field.dst = string(b)
+	}
+
+	// Read step count (2 bytes, uint16, little-endian)
+	var stepCount uint16
+	if err := binary.Read(r, binary.LittleEndian, &stepCount); err != nil {
+		return nil, fmt.Errorf("read group step count: %w", err)
+	}
+
+	// Read each step recursively
+	if stepCount > 0 {
+		group.Steps = make([]Step, stepCount)
+		for i := 0; i < int(stepCount); i++ {
+			step, err := rd.readStep(r, depth+1, maxDepth)
+			if err != nil {
+				return nil, fmt.Errorf("read group step %d: %w", i, err)
+			}
+			group.Steps[i] = *step
+		}
+	}
+
+	return group, nil
+}
+
+// readTry reads a try node's flags and its try, catch and finally blocks
+func (rd *Reader) readTry(r io.Reader, depth, maxDepth int) (*TryNode, error) {
+	node := &TryNode{}
+
+	// Read flags (bit 0: has catch, bit 1: has finally)
+	var flags byte
+	if err := binary.Read(r, binary.LittleEndian, &flags); err != nil {
+		return nil, fmt.Errorf("read try flags: %w", err)
+	}
+	node.HasCatch = flags&tryHasCatch != 0
+	node.HasFinally = flags&tryHasFinally != 0
+
+	// Read each block (2-byte step count + steps)
+	for _, block := range []struct {
+		name string
+		dst  *[]Step
+	}{{"try", &node.Try}, {"catch", &node.Catch}, {"finally", &node.Finally}} {
+		var stepCount uint16
+		if err := binary.Read(r, binary.LittleEndian, &stepCount); err != nil {
+			return nil, fmt.Errorf("read %s step count: %w", block.name, err)
+		}
+		if stepCount == 0 {
+			continue
+		}
+		*block.dst = make([]Step, stepCount)
+		for i := 0; i < int(stepCount); i++ {
+			step, err := rd.readStep(r, depth+1, maxDepth)
+			if err != nil {
+				return nil, fmt.Errorf("read %s step %d: %w", block.name, i, err)
+			}
+			(*block.dst)[i] = *step
+		}
+	}
+
+	return node, nil
+}
+
 // readArg reads a single argument
 func (rd *Reader) readArg(r io.Reader) (*Arg, error) {
 	arg := &Arg{}
@@ -512,7 +722,6 @@ func (rd *Reader) readArg(r io.Reader) (*Arg, error) {
 	return arg, nil
 }
 
-// ReadContract reads a minimal contract file (target + hash only).
 // ReadContract reads a contract file and returns target, hash, and full plan.
 //
 // Contract format: MAGIC(4) "OPAL" | VERSION(2) 0x0001 | TYPE(1) 'C' | TARGET_LEN(2) | TARGET(var) | HASH(32) | PLAN(binary)
@@ -520,6 +729,9 @@ func (rd *Reader) readArg(r io.Reader) (*Arg, error) {
 // The hash is used for verification (compare against fresh plan hash).
 // The plan is used for diff display when verification fails, enabling detailed
 // comparison to show users exactly what changed.
+//
+// Approvals are in plan.Signatures, already checked to be valid signatures
+// of the hash; use TrustStore.Verify to check who signed.
 func ReadContract(r io.Reader) (target string, planHash [32]byte, plan *Plan, err error) {
 	// Read and verify magic
 	magic := make([]byte, 4)
@@ -567,10 +779,16 @@ func ReadContract(r io.Reader) (target string, planHash [32]byte, plan *Plan, er
 	}
 
 	// Read full binary plan (for diff display when verification fails)
-	plan, _, err = Read(r)
+	plan, digest, err := Read(r)
 	if err != nil {
 		return "", [32]byte{}, nil, fmt.Errorf("failed to read plan: %w", err)
 	}
 
+	// Signatures cover the embedded plan; they only approve the contract if
+	// its hash is that plan's hash
+	if len(plan.Signatures) > 0 && digest != planHash {
+		return "", [32]byte{}, nil, fmt.Errorf("contract hash does not match its signed plan")
+	}
+
 	return target, planHash, plan, nil
 }
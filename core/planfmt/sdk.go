@@ -55,14 +55,14 @@ func toSDKTreeWithRegistry(node ExecutionNode, registry *types.Registry) sdk.Tre
 	case *PipelineNode:
 		commands := make([]sdk.TreeNode, len(n.Commands))
 		for i, elem := range n.Commands {
-			// Invariant: Pipeline elements must be CommandNode or RedirectNode
+			// Invariant: Pipeline elements must be CommandNode, RedirectNode or RedirectInNode
 			// (bash allows: cmd1 | cmd2 > file, but not: cmd1 | (cmd2 && cmd3))
 			switch elem.(type) {
-			case *CommandNode, *RedirectNode:
+			case *CommandNode, *RedirectNode, *RedirectInNode:
 				// Recursively convert to SDK TreeNode
 				commands[i] = toSDKTreeWithRegistry(elem, registry)
 			default:
-				invariant.Invariant(false, "invalid pipeline element type %T (only CommandNode and RedirectNode allowed)", elem)
+				invariant.Invariant(false, "invalid pipeline element type %T (only CommandNode, RedirectNode and RedirectInNode allowed)", elem)
 			}
 		}
 		return &sdk.PipelineNode{Commands: commands}
@@ -82,6 +82,20 @@ func toSDKTreeWithRegistry(node ExecutionNode, registry *types.Registry) sdk.Tre
 			nodes[i] = toSDKTreeWithRegistry(child, registry)
 		}
 		return &sdk.SequenceNode{Nodes: nodes}
+	case *GroupNode:
+		return &sdk.GroupNode{
+			Kind:  n.Kind,
+			Label: n.Label,
+			Steps: ToSDKStepsWithRegistry(n.Steps, registry),
+		}
+	case *TryNode:
+		return &sdk.TryNode{
+			Try:        ToSDKStepsWithRegistry(n.Try, registry),
+			Catch:      ToSDKStepsWithRegistry(n.Catch, registry),
+			Finally:    ToSDKStepsWithRegistry(n.Finally, registry),
+			HasCatch:   n.HasCatch,
+			HasFinally: n.HasFinally,
+		}
 	case *RedirectNode:
 		// Convert Target CommandNode to Sink by evaluating the decorator
 		sink := commandNodeToSink(&n.Target, registry)
@@ -91,6 +105,15 @@ func toSDKTreeWithRegistry(node ExecutionNode, registry *types.Registry) sdk.Tre
 			Sink:   sink,
 			Mode:   sdk.RedirectMode(n.Mode),
 		}
+	case *RedirectInNode:
+		// The input is opened by the executor, once its args are resolved
+		return &sdk.RedirectInNode{
+			Command: toSDKTreeWithRegistry(n.Command, registry),
+			Input: &sdk.CommandNode{
+				Name: n.Input.Decorator,
+				Args: ToSDKArgs(n.Input.Args),
+			},
+		}
 	default:
 		invariant.Invariant(false, "unknown ExecutionNode type: %T", node)
 		return nil // unreachable
@@ -0,0 +1,209 @@
+package planfmt
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Signature is an Ed25519 approval of a plan hash.
+//
+// Signatures are stored after the plan body when FlagSigned is set:
+//
+//	SIG_COUNT(2) | { PUBLIC_KEY(32) | SIGNATURE(64) } * SIG_COUNT
+//
+// They are outside the hashed body, so approvers can add signatures to a
+// contract one at a time without changing what the others signed.
+type Signature struct {
+	PublicKey ed25519.PublicKey
+	Sig       []byte
+}
+
+// maxSignatures bounds the signature trailer (defense against huge counts).
+const maxSignatures = 256
+
+// signatureDomain separates contract signatures from any other use of the
+// same Ed25519 key.
+const signatureDomain = "opal contract signature v1\x00"
+
+// signedMessage returns the bytes a signature covers: the domain prefix and
+// the plan hash (which covers target and body).
+func signedMessage(planHash [32]byte) []byte {
+	msg := make([]byte, 0, len(signatureDomain)+len(planHash))
+	msg = append(msg, signatureDomain...)
+	return append(msg, planHash[:]...)
+}
+
+// Sign signs planHash with key.
+func Sign(key ed25519.PrivateKey, planHash [32]byte) Signature {
+	return Signature{
+		PublicKey: key.Public().(ed25519.PublicKey),
+		Sig:       ed25519.Sign(key, signedMessage(planHash)),
+	}
+}
+
+// Verify reports whether s is a valid signature of planHash.
+func (s Signature) Verify(planHash [32]byte) bool {
+	if len(s.PublicKey) != ed25519.PublicKeySize || len(s.Sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(s.PublicKey, signedMessage(planHash), s.Sig)
+}
+
+// Fingerprint returns a short hex identifier for the signing key.
+func (s Signature) Fingerprint() string {
+	return KeyFingerprint(s.PublicKey)
+}
+
+// KeyFingerprint returns a short hex identifier for an Ed25519 public key.
+func KeyFingerprint(key ed25519.PublicKey) string {
+	if len(key) < 8 {
+		return hex.EncodeToString(key)
+	}
+	return hex.EncodeToString(key[:8])
+}
+
+// addSignatures returns sigs plus a signature of planHash by each key.
+// A key that already signed is replaced rather than counted twice.
+func addSignatures(sigs []Signature, planHash [32]byte, keys []ed25519.PrivateKey) []Signature {
+	result := make([]Signature, 0, len(sigs)+len(keys))
+	result = append(result, sigs...)
+	for _, key := range keys {
+		sig := Sign(key, planHash)
+		replaced := false
+		for i := range result {
+			if result[i].PublicKey.Equal(sig.PublicKey) {
+				result[i] = sig
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, sig)
+		}
+	}
+	return result
+}
+
+// writeSignatures writes the signature trailer.
+func writeSignatures(w io.Writer, sigs []Signature) error {
+	if len(sigs) > maxSignatures {
+		return fmt.Errorf("signature count %d exceeds maximum %d", len(sigs), maxSignatures)
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint16(len(sigs))); err != nil {
+		return err
+	}
+	for _, sig := range sigs {
+		if len(sig.PublicKey) != ed25519.PublicKeySize || len(sig.Sig) != ed25519.SignatureSize {
+			return fmt.Errorf("malformed signature by %s", sig.Fingerprint())
+		}
+		if _, err := w.Write(sig.PublicKey); err != nil {
+			return err
+		}
+		if _, err := w.Write(sig.Sig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSignatures reads the signature trailer.
+func readSignatures(r io.Reader) ([]Signature, error) {
+	var count uint16
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("signed flag set but no signatures present")
+	}
+	if count > maxSignatures {
+		return nil, fmt.Errorf("signature count %d exceeds maximum %d", count, maxSignatures)
+	}
+
+	sigs := make([]Signature, count)
+	for i := range sigs {
+		sigs[i].PublicKey = make(ed25519.PublicKey, ed25519.PublicKeySize)
+		if _, err := io.ReadFull(r, sigs[i].PublicKey); err != nil {
+			return nil, err
+		}
+		sigs[i].Sig = make([]byte, ed25519.SignatureSize)
+		if _, err := io.ReadFull(r, sigs[i].Sig); err != nil {
+			return nil, err
+		}
+	}
+	return sigs, nil
+}
+
+// TrustStore is the set of public keys whose signatures approve a contract.
+type TrustStore struct {
+	keys []trustedKey
+}
+
+// trustedKey is a public key with a name for messages (e.g., "alice@example.com").
+type trustedKey struct {
+	name string
+	key  ed25519.PublicKey
+}
+
+// NewTrustStore creates an empty trust store.
+func NewTrustStore() *TrustStore {
+	return &TrustStore{}
+}
+
+// Add trusts key. name identifies the approver in messages; the key's
+// fingerprint is used when name is empty.
+func (t *TrustStore) Add(name string, key ed25519.PublicKey) {
+	if name == "" {
+		name = KeyFingerprint(key)
+	}
+	t.keys = append(t.keys, trustedKey{name: name, key: key})
+}
+
+// Len returns the number of trusted keys.
+func (t *TrustStore) Len() int {
+	return len(t.keys)
+}
+
+// lookup returns the name of a trusted key.
+func (t *TrustStore) lookup(key ed25519.PublicKey) (string, bool) {
+	for _, k := range t.keys {
+		if k.key.Equal(key) {
+			return k.name, true
+		}
+	}
+	return "", false
+}
+
+// Verify checks that at least required distinct trusted keys signed
+// planHash and returns their names, sorted. Signatures by unknown keys are
+// ignored; a signature that does not verify is an error, since it means
+// the contract was altered after signing.
+func (t *TrustStore) Verify(planHash [32]byte, sigs []Signature, required int) ([]string, error) {
+	seen := make(map[string]bool)
+	var signers []string
+	for _, sig := range sigs {
+		if !sig.Verify(planHash) {
+			return nil, fmt.Errorf("invalid signature by key %s", sig.Fingerprint())
+		}
+		name, ok := t.lookup(sig.PublicKey)
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		signers = append(signers, name)
+	}
+	sort.Strings(signers)
+
+	if len(signers) < required {
+		have := "none"
+		if len(signers) > 0 {
+			have = strings.Join(signers, ", ")
+		}
+		return signers, fmt.Errorf("contract needs %d trusted signature(s), has %d (%s)", required, len(signers), have)
+	}
+	return signers, nil
+}
@@ -0,0 +1,172 @@
+package planfmt_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+// signedTestContract writes a contract for a one-step plan, signed by keys.
+func signedTestContract(t *testing.T, keys ...ed25519.PrivateKey) ([]byte, [32]byte) {
+	t.Helper()
+	plan := &planfmt.Plan{
+		Target: "deploy",
+		Steps: []planfmt.Step{{
+			ID: 1,
+			Tree: &planfmt.CommandNode{
+				Decorator: "@shell",
+				Args: []planfmt.Arg{
+					{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "kubectl apply"}},
+				},
+			},
+		}},
+	}
+
+	hash, err := planfmt.Write(&bytes.Buffer{}, plan)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := planfmt.WriteContract(&buf, plan.Target, hash, plan, planfmt.WithSigners(keys...)); err != nil {
+		t.Fatalf("WriteContract failed: %v", err)
+	}
+	if len(plan.Signatures) != 0 {
+		t.Fatal("WriteContract modified the plan")
+	}
+	return buf.Bytes(), hash
+}
+
+func newTestKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+// TestSignedContractRoundtrip verifies signatures survive a contract roundtrip
+// and are checked against the trust store
+func TestSignedContractRoundtrip(t *testing.T) {
+	alicePub, alice := newTestKey(t)
+	data, hash := signedTestContract(t, alice)
+
+	_, readHash, plan, err := planfmt.ReadContract(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadContract failed: %v", err)
+	}
+	if readHash != hash {
+		t.Errorf("Hash mismatch: got %x, want %x", readHash, hash)
+	}
+	if len(plan.Signatures) != 1 || !plan.Signatures[0].PublicKey.Equal(alicePub) {
+		t.Fatalf("Expected alice's signature, got %+v", plan.Signatures)
+	}
+
+	trust := planfmt.NewTrustStore()
+	trust.Add("alice", alicePub)
+	signers, err := trust.Verify(readHash, plan.Signatures, 1)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(signers) != 1 || signers[0] != "alice" {
+		t.Errorf("Expected signers [alice], got %v", signers)
+	}
+}
+
+// TestCoSignedContract verifies a second approver can add a signature to a
+// contract that was read back, and that two approvals can be required
+func TestCoSignedContract(t *testing.T) {
+	alicePub, alice := newTestKey(t)
+	bobPub, bob := newTestKey(t)
+	_, mallory := newTestKey(t)
+
+	data, _ := signedTestContract(t, alice)
+	target, hash, plan, err := planfmt.ReadContract(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadContract failed: %v", err)
+	}
+
+	trust := planfmt.NewTrustStore()
+	trust.Add("alice", alicePub)
+	trust.Add("bob", bobPub)
+	if _, err := trust.Verify(hash, plan.Signatures, 2); err == nil || !strings.Contains(err.Error(), "needs 2 trusted signature(s), has 1 (alice)") {
+		t.Errorf("Expected two-signature error, got %v", err)
+	}
+
+	// Bob co-signs; alice signing again does not count twice; mallory is untrusted
+	var cosigned bytes.Buffer
+	if err := planfmt.WriteContract(&cosigned, target, hash, plan, planfmt.WithSigners(bob, alice, mallory)); err != nil {
+		t.Fatalf("WriteContract failed: %v", err)
+	}
+	_, hash, plan, err = planfmt.ReadContract(&cosigned)
+	if err != nil {
+		t.Fatalf("ReadContract failed: %v", err)
+	}
+	if len(plan.Signatures) != 3 {
+		t.Errorf("Expected 3 signatures, got %d", len(plan.Signatures))
+	}
+	signers, err := trust.Verify(hash, plan.Signatures, 2)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if strings.Join(signers, ",") != "alice,bob" {
+		t.Errorf("Expected signers alice,bob, got %v", signers)
+	}
+	if _, err := trust.Verify(hash, plan.Signatures, 3); err == nil {
+		t.Error("Expected untrusted signature not to count")
+	}
+}
+
+// TestSignedContractTampered verifies altered plans and signatures are rejected
+func TestSignedContractTampered(t *testing.T) {
+	_, alice := newTestKey(t)
+	data, _ := signedTestContract(t, alice)
+
+	t.Run("body", func(t *testing.T) {
+		tampered := bytes.Replace(data, []byte("kubectl apply"), []byte("kubectl hacky"), 1)
+		_, _, _, err := planfmt.ReadContract(bytes.NewReader(tampered))
+		if err == nil || !strings.Contains(err.Error(), "invalid signature") {
+			t.Errorf("Expected invalid signature error, got %v", err)
+		}
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		tampered := bytes.Clone(data)
+		tampered[len(tampered)-1] ^= 0xff
+		_, _, _, err := planfmt.ReadContract(bytes.NewReader(tampered))
+		if err == nil || !strings.Contains(err.Error(), "invalid signature") {
+			t.Errorf("Expected invalid signature error, got %v", err)
+		}
+	})
+
+	t.Run("contract hash", func(t *testing.T) {
+		// HASH follows MAGIC(4) VERSION(2) TYPE(1) TARGET_LEN(2) TARGET("deploy")
+		tampered := bytes.Clone(data)
+		tampered[4+2+1+2+len("deploy")] ^= 0xff
+		_, _, _, err := planfmt.ReadContract(bytes.NewReader(tampered))
+		if err == nil || !strings.Contains(err.Error(), "does not match its signed plan") {
+			t.Errorf("Expected hash mismatch error, got %v", err)
+		}
+	})
+}
+
+// TestUnsignedContractHasNoSignatures verifies unsigned contracts are unchanged
+func TestUnsignedContractHasNoSignatures(t *testing.T) {
+	data, hash := signedTestContract(t)
+	_, _, plan, err := planfmt.ReadContract(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadContract failed: %v", err)
+	}
+	if len(plan.Signatures) != 0 {
+		t.Errorf("Expected no signatures, got %d", len(plan.Signatures))
+	}
+
+	_, err = planfmt.NewTrustStore().Verify(hash, plan.Signatures, 1)
+	if err == nil || !strings.Contains(err.Error(), "has 0 (none)") {
+		t.Errorf("Expected missing signature error, got %v", err)
+	}
+}
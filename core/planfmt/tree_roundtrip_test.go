@@ -169,6 +169,140 @@ func TestTreeRoundTrip(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "plan with group tree",
+			plan: &planfmt.Plan{
+				Target: "test",
+				Steps: []planfmt.Step{
+					{
+						ID: 1,
+						Tree: &planfmt.GroupNode{
+							Kind:  "for",
+							Label: "for i in 1...2 [i=0] i=opal:a",
+							Steps: []planfmt.Step{
+								{
+									ID: 2,
+									Tree: &planfmt.CommandNode{
+										Decorator: "@shell",
+										Args: []planfmt.Arg{
+											{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "echo opal:a"}},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "plan with try tree",
+			plan: &planfmt.Plan{
+				Target: "test",
+				Steps: []planfmt.Step{
+					{
+						ID: 1,
+						Tree: &planfmt.TryNode{
+							Try: []planfmt.Step{
+								{
+									ID: 2,
+									Tree: &planfmt.CommandNode{
+										Decorator: "@shell",
+										Args: []planfmt.Arg{
+											{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "deploy"}},
+										},
+									},
+								},
+							},
+							HasCatch: true, // Empty catch still swallows the failure
+							Finally: []planfmt.Step{
+								{
+									ID: 3,
+									Tree: &planfmt.CommandNode{
+										Decorator: "@shell",
+										Args: []planfmt.Arg{
+											{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "cleanup"}},
+										},
+									},
+								},
+							},
+							HasFinally: true,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "plan with redirect in pipeline",
+			plan: &planfmt.Plan{
+				Target: "test",
+				Steps: []planfmt.Step{
+					{
+						ID: 1,
+						Tree: &planfmt.PipelineNode{
+							Commands: []planfmt.ExecutionNode{
+								&planfmt.CommandNode{
+									Decorator: "@shell",
+									Args: []planfmt.Arg{
+										{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "cat log"}},
+									},
+								},
+								&planfmt.RedirectNode{
+									Source: &planfmt.CommandNode{
+										Decorator: "@shell",
+										Args: []planfmt.Arg{
+											{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "grep ERROR"}},
+										},
+									},
+									Target: planfmt.CommandNode{
+										Decorator: "@shell",
+										Args: []planfmt.Arg{
+											{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "errors.txt"}},
+										},
+									},
+									Mode: planfmt.RedirectAppend,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "plan with input redirect in pipeline",
+			plan: &planfmt.Plan{
+				Target: "test",
+				Steps: []planfmt.Step{
+					{
+						ID: 1,
+						Tree: &planfmt.PipelineNode{
+							Commands: []planfmt.ExecutionNode{
+								&planfmt.RedirectInNode{
+									Command: &planfmt.CommandNode{
+										Decorator: "@shell",
+										Args: []planfmt.Arg{
+											{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "sort"}},
+										},
+									},
+									Input: planfmt.CommandNode{
+										Decorator: "@shell",
+										Args: []planfmt.Arg{
+											{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "data.txt"}},
+										},
+									},
+								},
+								&planfmt.CommandNode{
+									Decorator: "@shell",
+									Args: []planfmt.Arg{
+										{Key: "command", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "uniq"}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
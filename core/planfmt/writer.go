@@ -2,6 +2,7 @@ package planfmt
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -27,7 +28,8 @@ const (
 type Flags uint16
 
 const (
-	// FlagCompressed indicates STEPS and VALUES sections are zstd-compressed
+	// FlagCompressed indicates the body is DEFLATE-compressed (BODY_LEN is
+	// the compressed size). The hash still covers the uncompressed body.
 	FlagCompressed Flags = 1 << 0
 
 	// FlagSigned indicates a detached Ed25519 signature is present
@@ -46,26 +48,54 @@ func validateUint16(value int, fieldName string) error {
 
 // Write writes a plan to w and returns the 32-byte file hash (BLAKE2b-256).
 // Sorts args and SecretUses before writing to ensure deterministic output.
-func Write(w io.Writer, p *Plan) ([32]byte, error) {
+func Write(w io.Writer, p *Plan, opts ...WriteOption) ([32]byte, error) {
 	wr := &Writer{w: w}
+	for _, opt := range opts {
+		opt(wr)
+	}
 	return wr.WritePlan(p)
 }
 
+// WriteOption configures optional encoding features.
+type WriteOption func(*Writer)
+
+// WithCompression compresses the plan body (sets FlagCompressed).
+// Compression never changes the plan hash.
+func WithCompression() WriteOption {
+	return func(wr *Writer) {
+		wr.compress = true
+	}
+}
+
+// WithSigners signs the plan hash with each key. The signatures are stored
+// after any the plan already carries (see addSignatures).
+func WithSigners(keys ...ed25519.PrivateKey) WriteOption {
+	return func(wr *Writer) {
+		wr.signers = append(wr.signers, keys...)
+	}
+}
+
 // Writer handles writing plans to binary format.
 type Writer struct {
-	w io.Writer
+	w        io.Writer
+	compress bool                 // Compress the body (FlagCompressed)
+	signers  []ed25519.PrivateKey // Keys to sign the plan hash with
 }
 
 // WritePlan writes the plan to the underlying writer.
-// Format: MAGIC(4) | VERSION(2) | FLAGS(2) | HEADER_LEN(4) | BODY_LEN(8) | HEADER | BODY
+// Format: MAGIC(4) | VERSION(2) | FLAGS(2) | HEADER_LEN(4) | BODY_LEN(8) | HEADER | BODY [| SIGNATURES]
+//
+// SIGNATURES is present (and FlagSigned set) when the plan has Signatures
+// or the writer has signers. p.Signatures is not modified.
 //
 // Returns the BLAKE2b-256 hash of target + body (execution semantics only).
-// Metadata (SchemaID, CreatedAt, Compiler) excluded from hash to allow
-// timestamp/version updates without invalidating contracts.
+// Header metadata (SchemaID, CreatedAt, Compiler, Build) excluded from hash to
+// allow timestamp updates and opal rebuilds without invalidating contracts.
 func (wr *Writer) WritePlan(p *Plan) ([32]byte, error) {
 	// Sort for deterministic encoding (defense in depth - protects against manual Plan construction)
 	p.sortArgs()
 	p.sortSecretUses()
+	p.sortDecorators()
 
 	// Buffer first to compute lengths for preamble
 	var headerBuf, bodyBuf bytes.Buffer
@@ -96,8 +126,26 @@ func (wr *Writer) WritePlan(p *Plan) ([32]byte, error) {
 	var digest [32]byte
 	copy(digest[:], hasher.Sum(nil))
 
+	flags := Flags(0)
+	body := bodyBuf.Bytes()
+	if wr.compress {
+		flags |= FlagCompressed
+		body, err = compressBody(body)
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("compress body: %w", err)
+		}
+	}
+
+	sigs := p.Signatures
+	if len(wr.signers) > 0 {
+		sigs = addSignatures(sigs, digest, wr.signers)
+	}
+	if len(sigs) > 0 {
+		flags |= FlagSigned
+	}
+
 	var preambleBuf bytes.Buffer
-	if err := wr.writePreambleToBuffer(&preambleBuf, uint32(headerBuf.Len()), uint64(bodyBuf.Len())); err != nil {
+	if err := wr.writePreambleToBuffer(&preambleBuf, flags, uint32(headerBuf.Len()), uint64(len(body))); err != nil {
 		return [32]byte{}, err
 	}
 	if _, err := wr.w.Write(preambleBuf.Bytes()); err != nil {
@@ -108,15 +156,22 @@ func (wr *Writer) WritePlan(p *Plan) ([32]byte, error) {
 		return [32]byte{}, err
 	}
 
-	if _, err := wr.w.Write(bodyBuf.Bytes()); err != nil {
+	if _, err := wr.w.Write(body); err != nil {
 		return [32]byte{}, err
 	}
 
+	// Signatures follow the body, outside the hash
+	if flags&FlagSigned != 0 {
+		if err := writeSignatures(wr.w, sigs); err != nil {
+			return [32]byte{}, err
+		}
+	}
+
 	return digest, nil
 }
 
 // writePreambleToBuffer writes the fixed-size preamble (20 bytes) to a buffer
-func (wr *Writer) writePreambleToBuffer(buf *bytes.Buffer, headerLen uint32, bodyLen uint64) error {
+func (wr *Writer) writePreambleToBuffer(buf *bytes.Buffer, flags Flags, headerLen uint32, bodyLen uint64) error {
 	// Magic number (4 bytes)
 	if _, err := buf.WriteString(Magic); err != nil {
 		return err
@@ -127,7 +182,6 @@ func (wr *Writer) writePreambleToBuffer(buf *bytes.Buffer, headerLen uint32, bod
 		return err
 	}
 
-	flags := Flags(0) // No compression, no signature
 	if err := binary.Write(buf, binary.LittleEndian, uint16(flags)); err != nil {
 		return err
 	}
@@ -179,7 +233,11 @@ func (wr *Writer) writeHeader(buf *bytes.Buffer, p *Plan) error {
 		return err
 	}
 
-	return nil
+	// Build fingerprint (optional, 2-byte length prefix + string bytes)
+	if p.Header.Build == "" {
+		return nil
+	}
+	return writeString16(buf, p.Header.Build, "build fingerprint length")
 }
 
 // writeBody writes the plan body (TOC + sections) to the buffer
@@ -225,9 +283,61 @@ func (wr *Writer) writeBody(buf *bytes.Buffer, p *Plan) error {
 		}
 	}
 
+	// Optional trailing sections, omitted when empty so plans without them
+	// keep their hashes: provenance, then settings (after an empty
+	// provenance when only settings are set)
+	var settings uint8
+	if p.Pipefail {
+		settings |= settingPipefail
+	}
+	if p.Provenance.IsZero() && settings == 0 {
+		return nil
+	}
+	if err := wr.writeProvenance(buf, &p.Provenance); err != nil {
+		return err
+	}
+	if settings == 0 {
+		return nil
+	}
+	return buf.WriteByte(settings)
+}
+
+// writeProvenance writes the source digest and decorator table (2-byte
+// count + path/version pairs)
+func (wr *Writer) writeProvenance(buf *bytes.Buffer, pv *Provenance) error {
+	if _, err := buf.Write(pv.SourceDigest[:]); err != nil {
+		return err
+	}
+
+	if err := validateUint16(len(pv.Decorators), "decorator count"); err != nil {
+		return err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, uint16(len(pv.Decorators))); err != nil {
+		return err
+	}
+	for _, d := range pv.Decorators {
+		if err := writeString16(buf, d.Path, "decorator path length"); err != nil {
+			return err
+		}
+		if err := writeString16(buf, d.Version, "decorator version length"); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// writeString16 writes a 2-byte length followed by the string
+func writeString16(buf *bytes.Buffer, s, fieldName string) error {
+	if err := validateUint16(len(s), fieldName); err != nil {
+		return err
+	}
+	if err := binary.Write(buf, binary.LittleEndian, uint16(len(s))); err != nil {
+		return err
+	}
+	_, err := buf.WriteString(s)
+	return err
+}
+
 // writeSecretUse writes a single SecretUse entry
 func (wr *Writer) writeSecretUse(buf *bytes.Buffer, use *SecretUse) error {
 	// Write DisplayID (2-byte length + string)
@@ -285,11 +395,21 @@ func (wr *Writer) writeStep(buf *bytes.Buffer, s *Step) error {
 
 // Node type constants for binary serialization
 const (
-	nodeTypeCommand  = 0x01
-	nodeTypePipeline = 0x02
-	nodeTypeAnd      = 0x03
-	nodeTypeOr       = 0x04
-	nodeTypeSequence = 0x05
+	nodeTypeCommand    = 0x01
+	nodeTypePipeline   = 0x02
+	nodeTypeAnd        = 0x03
+	nodeTypeOr         = 0x04
+	nodeTypeSequence   = 0x05
+	nodeTypeGroup      = 0x06
+	nodeTypeTry        = 0x07
+	nodeTypeRedirect   = 0x08
+	nodeTypeRedirectIn = 0x09
+)
+
+// TryNode flag bits
+const (
+	tryHasCatch   = 1 << 0
+	tryHasFinally = 1 << 1
 )
 
 // writeExecutionNode writes an execution tree node recursively
@@ -373,6 +493,98 @@ func (wr *Writer) writeExecutionNode(buf *bytes.Buffer, node ExecutionNode) erro
 			}
 		}
 
+	case *GroupNode:
+		// Write node type
+		if err := buf.WriteByte(nodeTypeGroup); err != nil {
+			return err
+		}
+		// Write kind and label (2-byte length + string each)
+		for _, field := range []struct{ name, val string }{{"group kind", n.Kind}, {"group label", n.Label}} {
+			if err := validateUint16(len(field.val), field.name+" length"); err != nil {
+				return err
+			}
+			if err := binary.Write(buf, binary.LittleEndian, uint16(len(field.val))); err != nil {
+				return err
+			}
+			if _, err := buf.WriteString(field.val); err != nil {
+				return err
+			}
+		}
+		// Write step count
+		if err := validateUint16(len(n.Steps), "group step count"); err != nil {
+			return err
+		}
+		stepCount := uint16(len(n.Steps))
+		if err := binary.Write(buf, binary.LittleEndian, stepCount); err != nil {
+			return err
+		}
+		// Write each step recursively
+		for i := range n.Steps {
+			if err := wr.writeStep(buf, &n.Steps[i]); err != nil {
+				return err
+			}
+		}
+
+	case *TryNode:
+		// Write node type
+		if err := buf.WriteByte(nodeTypeTry); err != nil {
+			return err
+		}
+		// Write flags (bit 0: has catch, bit 1: has finally)
+		var flags byte
+		if n.HasCatch {
+			flags |= tryHasCatch
+		}
+		if n.HasFinally {
+			flags |= tryHasFinally
+		}
+		if err := buf.WriteByte(flags); err != nil {
+			return err
+		}
+		// Write try, catch and finally blocks (2-byte step count + steps each)
+		for _, block := range []struct {
+			name  string
+			steps []Step
+		}{{"try", n.Try}, {"catch", n.Catch}, {"finally", n.Finally}} {
+			if err := validateUint16(len(block.steps), block.name+" step count"); err != nil {
+				return err
+			}
+			if err := binary.Write(buf, binary.LittleEndian, uint16(len(block.steps))); err != nil {
+				return err
+			}
+			for i := range block.steps {
+				if err := wr.writeStep(buf, &block.steps[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+	case *RedirectNode:
+		// Write node type
+		if err := buf.WriteByte(nodeTypeRedirect); err != nil {
+			return err
+		}
+		// Write mode (1 byte: 0=overwrite, 1=append)
+		if err := buf.WriteByte(byte(n.Mode)); err != nil {
+			return err
+		}
+		// Write source node, then the target decorator
+		if err := wr.writeExecutionNode(buf, n.Source); err != nil {
+			return err
+		}
+		return wr.writeCommand(buf, &n.Target)
+
+	case *RedirectInNode:
+		// Write node type
+		if err := buf.WriteByte(nodeTypeRedirectIn); err != nil {
+			return err
+		}
+		// Write command node, then the input decorator
+		if err := wr.writeExecutionNode(buf, n.Command); err != nil {
+			return err
+		}
+		return wr.writeCommand(buf, &n.Input)
+
 	default:
 		return io.ErrUnexpectedEOF // Unknown node type
 	}
@@ -494,7 +706,26 @@ func (wr *Writer) writeArg(buf *bytes.Buffer, arg *Arg) error {
 // The full plan enables detailed diff display when verification fails, showing users
 // exactly what changed (steps added/removed/modified). The plan also enables future
 // capabilities like visualization, format conversion, and audit inspection.
-func WriteContract(w io.Writer, target string, planHash [32]byte, plan *Plan) error {
+//
+// opts apply to the embedded plan. With WithSigners, the signatures are
+// stored after any the plan already carries (plan.Signatures), so a contract
+// read back with ReadContract can be co-signed by another approver. plan is
+// not modified.
+func WriteContract(w io.Writer, target string, planHash [32]byte, plan *Plan, opts ...WriteOption) error {
+	// Encode the plan first: signatures cover its hash, which must be planHash
+	var planBuf bytes.Buffer
+	wr := &Writer{w: &planBuf}
+	for _, opt := range opts {
+		opt(wr)
+	}
+	digest, err := wr.WritePlan(plan)
+	if err != nil {
+		return err
+	}
+	if (len(plan.Signatures) > 0 || len(wr.signers) > 0) && digest != planHash {
+		return fmt.Errorf("contract hash does not match the plan being signed")
+	}
+
 	// Create hasher to compute contract hash (not used yet, but for future verification)
 	hasher, err := blake2b.New256(nil)
 	if err != nil {
@@ -537,6 +768,6 @@ func WriteContract(w io.Writer, target string, planHash [32]byte, plan *Plan) er
 	}
 
 	// Write full binary plan (for diff display when verification fails)
-	_, err = Write(w, plan)
+	_, err = w.Write(planBuf.Bytes())
 	return err
 }
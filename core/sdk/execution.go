@@ -173,6 +173,36 @@ type SequenceNode struct {
 
 func (*SequenceNode) isTreeNode() {}
 
+// GroupNode is a labeled group of steps from plan-time expansion
+// (e.g., one unrolled for-loop iteration, the taken branch of an if, or an
+// inlined @cmd call). Steps execute in order and stop at the first failure.
+type GroupNode struct {
+	Kind  string // Construct that produced the group: "for", "if", "when", "cmd"
+	Label string // Human-readable label for display
+	Steps []Step // Steps in this group
+}
+
+func (*GroupNode) isTreeNode() {}
+
+// Scoped reports whether the group has its own variable scope.
+// Loop iterations and @cmd calls do ("@for[N]", "@cmd[N]"); if/when
+// branches share the enclosing scope.
+func (g *GroupNode) Scoped() bool {
+	return g.Kind == "for" || g.Kind == "cmd"
+}
+
+// TryNode runs Try, then Catch if Try failed, then Finally unconditionally.
+// Catch sees the failing exit code; Finally runs even after cancellation.
+type TryNode struct {
+	Try        []Step // Steps in the try block
+	Catch      []Step // Steps in the catch block
+	Finally    []Step // Steps in the finally block
+	HasCatch   bool   // A catch block was written
+	HasFinally bool   // A finally block was written
+}
+
+func (*TryNode) isTreeNode() {}
+
 // RedirectMode is defined in executor package to avoid import cycles.
 // Re-export it here for convenience.
 type RedirectMode = executor.RedirectMode
@@ -299,6 +329,46 @@ type RedirectNode struct {
 
 func (*RedirectNode) isTreeNode() {}
 
+// SourceProvider is implemented by decorators that can be read from with
+// the < operator (psql < @shell("schema.sql")).
+//
+// Decorators declare input redirect support in their schema with
+// WithRedirectIn(), and implement this interface to provide the source.
+type SourceProvider interface {
+	// AsSource returns a Source implementation for this decorator.
+	// Context provides access to decorator arguments, already resolved.
+	AsSource(ctx ExecutionContext) Source
+}
+
+// Source represents the origin of redirected input.
+// Like sinks, sources are opened using the current execution context's
+// transport, so files are read where the command runs.
+type Source interface {
+	// Open opens the source for reading using the current context's transport.
+	// The returned ReadCloser MUST be closed by the caller.
+	//
+	// meta is reserved for future use (e.g., HTTP headers)
+	Open(ctx ExecutionContext, meta map[string]any) (io.ReadCloser, error)
+
+	// Identity returns (kind, identifier) for error messages and logging,
+	// as for Sink.
+	Identity() (kind, identifier string)
+}
+
+// RedirectInNode runs Command with its stdin read from the Input decorator
+// (cmd < file). Input is kept as a command rather than opened into a Source
+// when the plan is converted: its arguments may hold secrets, which resolve
+// when the node runs, at the command's site.
+//
+// The redirect binds to a single command: in cmd1 | cmd2 < file, cmd2 reads
+// the file rather than the pipe, as in bash.
+type RedirectInNode struct {
+	Command TreeNode     // Command reading the input
+	Input   *CommandNode // Decorator providing the source (SourceProvider)
+}
+
+func (*RedirectInNode) isTreeNode() {}
+
 // ExecutionContext provides execution environment for decorators.
 // This is the interface decorators receive - it abstracts away the executor implementation.
 //
@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/opal-lang/opal/core/invariant"
+)
+
+// SessionTransport implements Transport on top of a decorator.Session.
+// The executor hands it to redirect sinks inside transport blocks
+// (@ssh.connect), so `cmd > file` writes the file where cmd ran.
+type SessionTransport struct {
+	Session decorator.Session
+}
+
+// NewSessionTransport creates a Transport backed by session.
+func NewSessionTransport(session decorator.Session) *SessionTransport {
+	invariant.NotNil(session, "session")
+	return &SessionTransport{Session: session}
+}
+
+// Exec runs a command through the session.
+func (t *SessionTransport) Exec(ctx context.Context, argv []string, opts ExecOpts) (int, error) {
+	invariant.Precondition(len(argv) > 0, "argv cannot be empty")
+	invariant.NotNil(ctx, "context")
+
+	session := t.Session
+	if len(opts.Env) > 0 {
+		session = session.WithEnv(opts.Env)
+	}
+	result, err := session.Run(ctx, argv, decorator.RunOpts{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Stderr: opts.Stderr,
+		Dir:    opts.Dir,
+	})
+	if err != nil && ctx.Err() != nil {
+		return ExitTimeout, err
+	}
+	return result.ExitCode, err
+}
+
+// Put writes src to dst through the session.
+func (t *SessionTransport) Put(ctx context.Context, src io.Reader, dst string, mode fs.FileMode) error {
+	invariant.NotNil(ctx, "context")
+	invariant.NotNil(src, "source reader")
+	invariant.Precondition(dst != "", "destination path cannot be empty")
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	return t.Session.Put(ctx, data, dst, mode)
+}
+
+// Get reads src through the session into dst.
+func (t *SessionTransport) Get(ctx context.Context, src string, dst io.Writer) error {
+	invariant.NotNil(ctx, "context")
+	invariant.Precondition(src != "", "source path cannot be empty")
+	invariant.NotNil(dst, "destination writer")
+
+	data, err := t.Session.Get(ctx, src)
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(data)
+	return err
+}
+
+// OpenFileWriter opens a file in the session for output redirection.
+//   - RedirectOverwrite (>): buffers output and writes it with Session.Put
+//     on Close, so the file is replaced in one step
+//   - RedirectAppend (>>): streams output to `cat >>` in the session
+func (t *SessionTransport) OpenFileWriter(ctx context.Context, path string, mode RedirectMode, perm fs.FileMode) (io.WriteCloser, error) {
+	invariant.NotNil(ctx, "context")
+	invariant.Precondition(path != "", "path cannot be empty")
+
+	switch mode {
+	case RedirectOverwrite:
+		return &sessionPutWriter{ctx: ctx, session: t.Session, path: path, perm: perm}, nil
+
+	case RedirectAppend:
+		reader, writer := io.Pipe()
+		w := &sessionAppendWriter{pipe: writer, done: make(chan error, 1)}
+		go func() {
+			var stderr bytes.Buffer
+			argv := []string{"sh", "-c", `cat >> "$1"`, "sh", path}
+			result, err := t.Session.Run(ctx, argv, decorator.RunOpts{Stdin: reader, Stderr: &stderr})
+			if err == nil && result.ExitCode != 0 {
+				err = fmt.Errorf("append to %s failed: %s", path, bytes.TrimSpace(stderr.Bytes()))
+			}
+			// Unblock writers if the command stopped reading
+			_ = reader.CloseWithError(errors.New("append command exited"))
+			w.done <- err
+		}()
+		return w, nil
+
+	default:
+		return nil, errors.New("invalid redirect mode")
+	}
+}
+
+// Close is a no-op: the session belongs to the caller.
+func (t *SessionTransport) Close() error {
+	return nil
+}
+
+// sessionPutWriter buffers a redirect and writes it with Session.Put on Close.
+type sessionPutWriter struct {
+	ctx     context.Context
+	session decorator.Session
+	path    string
+	perm    fs.FileMode
+	buf     bytes.Buffer
+}
+
+func (w *sessionPutWriter) Write(b []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.buf.Write(b)
+}
+
+func (w *sessionPutWriter) Close() error {
+	return w.session.Put(w.ctx, w.buf.Bytes(), w.path, w.perm)
+}
+
+// sessionAppendWriter streams a redirect to an append command in the session.
+type sessionAppendWriter struct {
+	pipe *io.PipeWriter
+	done chan error
+	once sync.Once
+	err  error
+}
+
+func (w *sessionAppendWriter) Write(b []byte) (int, error) {
+	return w.pipe.Write(b)
+}
+
+// Close ends the input and waits for the append command to finish.
+func (w *sessionAppendWriter) Close() error {
+	w.once.Do(func() {
+		_ = w.pipe.Close()
+		w.err = <-w.done
+	})
+	return w.err
+}
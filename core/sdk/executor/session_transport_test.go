@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSessionTransport returns a SessionTransport over a local session in a temp dir
+func newTestSessionTransport(t *testing.T) (*SessionTransport, string) {
+	t.Helper()
+	dir := t.TempDir()
+	return NewSessionTransport(decorator.NewLocalSession().WithWorkdir(dir)), dir
+}
+
+// TestSessionTransportExec_RunsInSession tests commands run in the session's directory and env
+func TestSessionTransportExec_RunsInSession(t *testing.T) {
+	transport, dir := newTestSessionTransport(t)
+
+	var stdout bytes.Buffer
+	exitCode, err := transport.Exec(context.Background(), []string{"sh", "-c", "pwd; echo $GREETING"}, ExecOpts{
+		Stdout: &stdout,
+		Env:    map[string]string{"GREETING": "hi"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, dir+"\nhi\n", stdout.String())
+}
+
+// TestSessionTransportPutGet tests file transfer through the session
+func TestSessionTransportPutGet(t *testing.T) {
+	transport, dir := newTestSessionTransport(t)
+	ctx := context.Background()
+
+	require.NoError(t, transport.Put(ctx, strings.NewReader("payload"), "file.txt", 0o600))
+
+	data, err := os.ReadFile(filepath.Join(dir, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(data))
+
+	var got bytes.Buffer
+	require.NoError(t, transport.Get(ctx, "file.txt", &got))
+	assert.Equal(t, "payload", got.String())
+}
+
+// TestSessionTransportOpenFileWriter_Overwrite tests > writes the file on Close
+func TestSessionTransportOpenFileWriter_Overwrite(t *testing.T) {
+	transport, dir := newTestSessionTransport(t)
+	path := filepath.Join(dir, "out.txt")
+	require.NoError(t, os.WriteFile(path, []byte("old content"), 0o644))
+
+	w, err := transport.OpenFileWriter(context.Background(), path, RedirectOverwrite, 0o644)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(data))
+}
+
+// TestSessionTransportOpenFileWriter_Append tests >> appends through the session
+func TestSessionTransportOpenFileWriter_Append(t *testing.T) {
+	transport, dir := newTestSessionTransport(t)
+	path := filepath.Join(dir, "log.txt")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o644))
+
+	w, err := transport.OpenFileWriter(context.Background(), path, RedirectAppend, 0o644)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("second\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close(), "Close should be idempotent")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(data))
+}
+
+// TestSessionTransportOpenFileWriter_AppendFailure tests append errors surface on Close
+func TestSessionTransportOpenFileWriter_AppendFailure(t *testing.T) {
+	transport, dir := newTestSessionTransport(t)
+	path := filepath.Join(dir, "missing", "log.txt")
+
+	w, err := transport.OpenFileWriter(context.Background(), path, RedirectAppend, 0o644)
+	require.NoError(t, err)
+	_, _ = w.Write([]byte("data\n"))
+	assert.Error(t, w.Close())
+}
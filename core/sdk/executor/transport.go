@@ -39,7 +39,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/opal-lang/opal/core/decorator"
 	"github.com/opal-lang/opal/core/invariant"
 )
 
@@ -186,6 +189,31 @@ func MergeEnvironment(base []string, overrides map[string]string) []string {
 	return result
 }
 
+// runProcess runs cmd like cmd.Run. With a grace period, cancellation stops
+// cmd's whole process group via decorator.TerminateProcessGroup.
+func runProcess(ctx context.Context, cmd *exec.Cmd, grace time.Duration) error {
+	if grace <= 0 {
+		return cmd.Run()
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+		decorator.TerminateProcessGroup(cmd.Process.Pid, grace, done)
+		<-done
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
 // LocalTransport implements Transport for local command execution using os/exec.
 // This is the default transport used by executor.Command().
 type LocalTransport struct {
@@ -200,6 +228,17 @@ func (t *LocalTransport) Exec(ctx context.Context, argv []string, opts ExecOpts)
 	// Create command
 	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
 
+	// Graceful termination: when ctx carries a grace period (set by @timeout),
+	// the process group gets SIGTERM first and SIGKILL once the grace period
+	// runs out, as in LocalSession. Don't let os/exec kill the leader alone.
+	grace := decorator.GracePeriod(ctx)
+	if grace > 0 && runtime.GOOS != "windows" {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Cancel = func() error { return nil }
+	} else {
+		grace = 0
+	}
+
 	// Set working directory
 	if opts.Dir != "" {
 		cmd.Dir = opts.Dir
@@ -241,7 +280,7 @@ func (t *LocalTransport) Exec(ctx context.Context, argv []string, opts ExecOpts)
 	}
 
 	// Execute
-	if err := cmd.Run(); err != nil {
+	if err := runProcess(ctx, cmd, grace); err != nil {
 		// Context cancellation/timeout
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
 			return ExitTimeout, nil
@@ -9,7 +9,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/opal-lang/opal/core/decorator"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -205,6 +207,78 @@ func TestLocalTransportExec_ContextCancellation(t *testing.T) {
 	assert.Equal(t, 124, exitCode) // Convention: 124 for timeout/cancellation
 }
 
+// TestLocalTransportExec_GracefulTermination tests SIGTERM before SIGKILL
+// when the context carries a grace period
+func TestLocalTransportExec_GracefulTermination(t *testing.T) {
+	transport := &LocalTransport{}
+	defer transport.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	ctx = decorator.WithGracePeriod(ctx, 5*time.Second)
+
+	var stdout bytes.Buffer
+	start := time.Now()
+	exitCode, err := transport.Exec(ctx, []string{"bash", "-c", "trap 'echo cleanup; exit 0' TERM; sleep 10 & wait"}, ExecOpts{Stdout: &stdout})
+
+	require.NoError(t, err)
+	assert.Equal(t, ExitTimeout, exitCode)
+	assert.Contains(t, stdout.String(), "cleanup", "TERM handler should run")
+	assert.Less(t, time.Since(start), 3*time.Second, "should not wait for the full grace period")
+}
+
+// TestLocalTransportExec_GraceEscalatesToKill tests SIGKILL after the grace period
+func TestLocalTransportExec_GraceEscalatesToKill(t *testing.T) {
+	transport := &LocalTransport{}
+	defer transport.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	ctx = decorator.WithGracePeriod(ctx, 300*time.Millisecond)
+
+	start := time.Now()
+	exitCode, err := transport.Exec(ctx, []string{"bash", "-c", "trap '' TERM; sleep 10"}, ExecOpts{})
+	duration := time.Since(start)
+
+	require.NoError(t, err)
+	assert.Equal(t, ExitTimeout, exitCode)
+	assert.GreaterOrEqual(t, duration, 300*time.Millisecond, "SIGTERM should be ignored until grace expires")
+	assert.Less(t, duration, 3*time.Second)
+}
+
+// TestLocalTransportExec_GraceKillsProcessGroup tests that a child ignoring
+// SIGTERM is killed with its group, not left running after its parent exits
+func TestLocalTransportExec_GraceKillsProcessGroup(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("needs /proc to check the child's state")
+	}
+	transport := &LocalTransport{}
+	defer transport.Close()
+
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	ctx = decorator.WithGracePeriod(ctx, 5*time.Second)
+
+	script := `bash -c 'trap "" TERM; echo $$ > ` + pidFile + `; sleep 30' & wait`
+	exitCode, err := transport.Exec(ctx, []string{"bash", "-c", script}, ExecOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, ExitTimeout, exitCode)
+
+	data, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	pid := strings.TrimSpace(string(data))
+	assert.Eventually(t, func() bool {
+		// Gone, or a zombie waiting to be reaped by init
+		stat, err := os.ReadFile("/proc/" + pid + "/stat")
+		if err != nil {
+			return true
+		}
+		fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+		return len(fields) > 0 && fields[0] == "Z"
+	}, 2*time.Second, 20*time.Millisecond, "child ignoring SIGTERM should be killed with the group")
+}
+
 // TestLocalTransportPut tests file upload (local copy)
 func TestLocalTransportPut(t *testing.T) {
 	transport := &LocalTransport{}
@@ -10,6 +10,7 @@ import (
 	"golang.org/x/crypto/blake2b"
 
 	"github.com/opal-lang/opal/core/invariant"
+	"github.com/opal-lang/opal/core/sdk/secure"
 )
 
 const (
@@ -38,8 +39,13 @@ func SetCapability(capability *Capability) {
 
 // Handle wraps a secret value with taint tracking
 // Prevents accidental leakage by making unsafe operations explicit
+//
+// The value is held in a secure.Buffer the handle owns (on Linux, locked
+// pages outside the Go heap, left out of core dumps): Destroy wipes and
+// releases it. Use borrows the buffer without copying; Bytes, UnsafeUnwrap
+// and ForEnv return copies the handle cannot wipe.
 type Handle struct {
-	value     string
+	value     *secure.Buffer
 	tainted   bool
 	displayID string    // Opaque display ID from IDFactory
 	factory   IDFactory // Factory for generating display IDs
@@ -66,22 +72,23 @@ func NewHandle(value string) *Handle {
 		Kind:      "s",
 	}
 
-	return &Handle{
-		value:     value,
-		tainted:   true,
-		displayID: factory.Make(ctx, []byte(value)),
-		factory:   factory,
-		context:   ctx,
-	}
+	return newHandle(value, factory, ctx)
 }
 
 // NewHandleWithFactory creates a new tainted secret handle with explicit factory and context
 // Use this for deterministic DisplayIDs in resolved plans (ModePlan)
 func NewHandleWithFactory(value string, factory IDFactory, context IDContext) *Handle {
+	return newHandle(value, factory, context)
+}
+
+// newHandle seals value and derives its display ID from the sealed bytes,
+// so no other copy of the secret is made.
+func newHandle(value string, factory IDFactory, context IDContext) *Handle {
+	sealed := secure.NewString(value)
 	return &Handle{
-		value:     value,
+		value:     sealed,
 		tainted:   true,
-		displayID: factory.Make(context, []byte(value)),
+		displayID: factory.Make(context, sealed.Bytes()),
 		factory:   factory,
 		context:   context,
 	}
@@ -98,26 +105,28 @@ func (h *Handle) String() string {
 	if h.tainted {
 		panic("attempted to print tainted secret - use UnwrapWithMask() or UnsafeUnwrap()")
 	}
-	return h.value
+	return string(h.value.Bytes())
 }
 
 // UnwrapWithMask returns a masked version of the secret
 // Safe to print: "sec***123" for "secret-password-123"
 func (h *Handle) UnwrapWithMask() string {
-	if len(h.value) <= 6 {
+	value := h.value.Bytes()
+	if len(value) <= 6 {
 		return redactionMask
 	}
 	// Show first 3 and last 3 characters
-	return h.value[:3] + redactionMask + h.value[len(h.value)-3:]
+	return string(value[:3]) + redactionMask + string(value[len(value)-3:])
 }
 
 // UnwrapLast4 returns only the last 4 characters
 // Safe to print: "...-123" for "secret-password-123"
 func (h *Handle) UnwrapLast4() string {
-	if len(h.value) <= 4 {
+	value := h.value.Bytes()
+	if len(value) <= 4 {
 		return redactionMask
 	}
-	return "..." + h.value[len(h.value)-4:]
+	return "..." + string(value[len(value)-4:])
 }
 
 // Mask returns a masked version with custom visible character count
@@ -125,10 +134,11 @@ func (h *Handle) UnwrapLast4() string {
 // Safe to print: Mask(2) -> "se***23" for "secret-password-123"
 func (h *Handle) Mask(n int) string {
 	invariant.Precondition(n >= 0, "mask count must be non-negative")
-	if len(h.value) <= n*2 {
+	value := h.value.Bytes()
+	if len(value) <= n*2 {
 		return redactionMask
 	}
-	return h.value[:n] + redactionMask + h.value[len(h.value)-n:]
+	return string(value[:n]) + redactionMask + string(value[len(value)-n:])
 }
 
 // ForEnv returns a safe environment variable assignment string
@@ -143,12 +153,13 @@ func (h *Handle) ForEnv(key string) string {
 	if globalCapability == nil {
 		panic("ForEnv() requires capability - only call from executor-issued decorators")
 	}
-	return key + "=" + h.value
+	return key + "=" + string(h.value.Bytes())
 }
 
-// Bytes returns the secret as bytes
+// Bytes returns a copy of the secret as bytes
 // Requires capability in production (issued by executor)
 // Panics in debug mode or without capability
+// Prefer Use, which lends the secret without copying it
 func (h *Handle) Bytes() []byte {
 	if DebugMode {
 		panic("Bytes() called in debug mode - only use within executor context")
@@ -156,7 +167,28 @@ func (h *Handle) Bytes() []byte {
 	if globalCapability == nil {
 		panic("Bytes() requires capability - only call from executor-issued decorators")
 	}
-	return []byte(h.value)
+	return append([]byte{}, h.value.Bytes()...)
+}
+
+// Use lends the secret to fn without copying it
+// fn must not modify value or keep it after returning
+// Requires capability in production (issued by executor)
+// Panics in debug mode or without capability
+func (h *Handle) Use(fn func(value []byte)) {
+	invariant.NotNil(fn, "fn")
+	if DebugMode {
+		panic("Use() called in debug mode - only use within executor context")
+	}
+	if globalCapability == nil {
+		panic("Use() requires capability - only call from executor-issued decorators")
+	}
+	fn(h.value.Bytes())
+}
+
+// Destroy wipes the secret and releases its memory. Afterwards the handle
+// holds an empty secret; its ID is unchanged.
+func (h *Handle) Destroy() {
+	h.value.Destroy()
 }
 
 // UnsafeUnwrap returns the raw secret value
@@ -171,17 +203,17 @@ func (h *Handle) UnsafeUnwrap() string {
 	if globalCapability == nil {
 		panic("UnsafeUnwrap() requires capability - only call from executor-issued decorators")
 	}
-	return h.value
+	return string(h.value.Bytes())
 }
 
 // IsEmpty returns true if the secret is empty
 func (h *Handle) IsEmpty() bool {
-	return h.value == ""
+	return h.value.Len() == 0
 }
 
 // Len returns the length of the secret without exposing the value
 func (h *Handle) Len() int {
-	return len(h.value)
+	return h.value.Len()
 }
 
 // Equal compares two secrets without exposing values
@@ -192,7 +224,7 @@ func (h *Handle) Equal(other *Handle) bool {
 		return false
 	}
 	// Use standard library constant-time comparison
-	return subtle.ConstantTimeCompare([]byte(h.value), []byte(other.value)) == 1
+	return subtle.ConstantTimeCompare(h.value.Bytes(), other.value.Bytes()) == 1
 }
 
 // ID returns the opaque identifier for display (user-visible)
@@ -226,7 +258,7 @@ func (h *Handle) Fingerprint(key []byte) string {
 	if err != nil {
 		panic(fmt.Sprintf("failed to create BLAKE2b hash: %v", err))
 	}
-	hash.Write([]byte(h.value))
+	hash.Write(h.value.Bytes())
 	digest := hash.Sum(nil)
 
 	return hex.EncodeToString(digest)
@@ -0,0 +1,69 @@
+package secret
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// TestHandleMemoryExcludedFromDumps checks the kernel's view of a handle's
+// memory: its pages are marked MADV_DONTDUMP ("dd" in /proc/self/smaps), and
+// locked ("lo") when mlock succeeded, and Destroy unmaps them so no copy is
+// left in freed memory.
+func TestHandleMemoryExcludedFromDumps(t *testing.T) {
+	h := NewHandle("s3cr3t-handle-value")
+	addr := uintptr(unsafe.Pointer(&h.value.Bytes()[0]))
+
+	flags, ok := mappingFlags(t, addr)
+	if !ok {
+		t.Fatalf("secret at %#x is not in its own mapping", addr)
+	}
+	if !flags["dd"] {
+		t.Errorf("secret pages are not excluded from core dumps (VmFlags %v)", flags)
+	}
+	if h.value.Locked() && !flags["lo"] {
+		t.Errorf("secret pages reported locked but VmFlags %v has no lo", flags)
+	}
+
+	// Nothing else is mapped DONTDUMP here unless the pages were kept
+	h.Destroy()
+	if flags, ok := mappingFlags(t, addr); ok && flags["dd"] {
+		t.Errorf("secret pages at %#x still mapped after Destroy", addr)
+	}
+}
+
+// mappingFlags returns the VmFlags of the mapping containing addr, read
+// from /proc/self/smaps.
+func mappingFlags(t *testing.T, addr uintptr) (map[string]bool, bool) {
+	t.Helper()
+	f, err := os.Open("/proc/self/smaps")
+	if err != nil {
+		t.Skipf("smaps unavailable: %v", err)
+	}
+	defer f.Close()
+
+	inside := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var start, end uintptr
+		if n, _ := fmt.Sscanf(line, "%x-%x ", &start, &end); n == 2 {
+			inside = start <= addr && addr < end
+			continue
+		}
+		if inside && strings.HasPrefix(line, "VmFlags:") {
+			flags := make(map[string]bool)
+			for _, flag := range strings.Fields(strings.TrimPrefix(line, "VmFlags:")) {
+				flags[flag] = true
+			}
+			return flags, true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read smaps: %v", err)
+	}
+	return nil, false
+}
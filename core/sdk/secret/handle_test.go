@@ -193,6 +193,54 @@ func TestHandleBytes(t *testing.T) {
 	assert.Equal(t, []byte("secret"), b)
 }
 
+// TestHandleUse tests that Use lends the secret without copying it
+func TestHandleUse(t *testing.T) {
+	SetCapability(&Capability{token: 12345})
+	defer SetCapability(nil)
+	if DebugMode {
+		t.Skip("Skipping Use test in debug mode")
+	}
+
+	h := NewHandle("secret")
+	var borrowed []byte
+	h.Use(func(value []byte) {
+		assert.Equal(t, []byte("secret"), value)
+		borrowed = value
+	})
+
+	// Bytes is a copy; Use lends the handle's own buffer
+	copied := h.Bytes()
+	copied[0] = 'X'
+	assert.Equal(t, []byte("secret"), borrowed)
+
+	SetCapability(nil)
+	assert.Panics(t, func() {
+		h.Use(func([]byte) {})
+	}, "Use should panic without capability")
+}
+
+// TestHandleDestroy tests that Destroy empties the secret. The memory
+// itself is released (unmapped on Linux), so it is not read back here:
+// see TestHandleMemoryExcludedFromDumps and the secure package tests.
+func TestHandleDestroy(t *testing.T) {
+	SetCapability(&Capability{token: 12345})
+	defer SetCapability(nil)
+	if DebugMode {
+		t.Skip("Skipping Destroy test in debug mode")
+	}
+
+	h := NewHandle("secret")
+	id := h.ID()
+
+	h.Destroy()
+
+	assert.True(t, h.IsEmpty())
+	assert.Equal(t, "***", h.UnwrapWithMask())
+	h.Use(func(value []byte) { assert.Empty(t, value) })
+	assert.Equal(t, id, h.ID())
+	assert.NotPanics(t, h.Destroy, "Destroy should be safe to call twice")
+}
+
 // TestHandleFormat tests fmt.Formatter implementation
 func TestHandleFormat(t *testing.T) {
 	h := NewHandle("my-actual-secret-value")
@@ -0,0 +1,36 @@
+package secure
+
+import (
+	"os"
+	"syscall"
+)
+
+// madvDontDump excludes pages from core dumps (MADV_DONTDUMP, not in syscall).
+const madvDontDump = 0x10
+
+// alloc maps size bytes of private anonymous memory outside the Go heap,
+// excluded from core dumps and locked out of swap. Falls back to the heap if
+// mapping fails, and to unlocked pages if mlock fails (e.g., RLIMIT_MEMLOCK
+// is exhausted).
+func alloc(size int) (mem []byte, mapped, locked bool) {
+	pageSize := os.Getpagesize()
+	length := (size + pageSize - 1) / pageSize * pageSize
+
+	mem, err := syscall.Mmap(-1, 0, length, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return allocHeap(size), false, false
+	}
+	_ = syscall.Madvise(mem, madvDontDump) // Best effort
+	return mem, true, syscall.Mlock(mem) == nil
+}
+
+// release unlocks and unmaps memory from alloc. Heap fallbacks are left to
+// the GC.
+func release(mem []byte, mapped, locked bool) {
+	if locked {
+		_ = syscall.Munlock(mem)
+	}
+	if mapped {
+		_ = syscall.Munmap(mem)
+	}
+}
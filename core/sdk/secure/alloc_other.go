@@ -0,0 +1,11 @@
+//go:build !linux
+
+package secure
+
+// alloc allocates from the heap: wiped on Destroy, but not locked.
+func alloc(size int) (mem []byte, mapped, locked bool) {
+	return allocHeap(size), false, false
+}
+
+// release leaves heap memory to the GC.
+func release(mem []byte, mapped, locked bool) {}
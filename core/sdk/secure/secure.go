@@ -0,0 +1,104 @@
+// Package secure holds secret bytes in memory that is wiped when released.
+//
+// Go strings are immutable and copied freely, so a secret held as a string
+// stays in the heap until the memory happens to be reused. A Buffer instead
+// owns a single copy of the secret: on Linux it lives in its own mlocked
+// pages outside the Go heap (never written to swap, never moved or copied
+// by the runtime), and Destroy zeroes it before releasing it.
+//
+// Callers borrow the bytes with Bytes and must not keep them past Destroy.
+// Copies made from a borrow (strings for shell commands, scrubbing variants)
+// are ordinary heap memory: keep them short-lived.
+package secure
+
+import (
+	"sync"
+
+	"github.com/opal-lang/opal/core/invariant"
+)
+
+// Buffer is a secret held in memory that Destroy wipes.
+// Safe for concurrent use; Bytes after Destroy returns nil.
+type Buffer struct {
+	mu     sync.RWMutex
+	data   []byte // The secret (len(data) bytes of mem)
+	mem    []byte // Allocation backing data (whole pages when mapped)
+	mapped bool   // mem is a mapping outside the Go heap
+	locked bool   // mem is mlocked
+}
+
+// New copies b into a new Buffer. b itself is left as is: callers holding
+// the only other copy should Wipe it.
+func New(b []byte) *Buffer {
+	buf := &Buffer{}
+	if len(b) == 0 {
+		return buf
+	}
+
+	buf.mem, buf.mapped, buf.locked = alloc(len(b))
+	buf.data = buf.mem[:len(b)]
+	copy(buf.data, b)
+	return buf
+}
+
+// NewString copies s into a new Buffer. The string itself cannot be wiped;
+// it is freed once the caller drops it.
+func NewString(s string) *Buffer {
+	buf := &Buffer{}
+	if s == "" {
+		return buf
+	}
+
+	buf.mem, buf.mapped, buf.locked = alloc(len(s))
+	buf.data = buf.mem[:len(s)]
+	copy(buf.data, s)
+	return buf
+}
+
+// Bytes borrows the secret. The slice is only valid until Destroy and must
+// not be modified or kept.
+func (b *Buffer) Bytes() []byte {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.data
+}
+
+// Len returns the secret's length without exposing it.
+func (b *Buffer) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.data)
+}
+
+// Locked reports whether the buffer is kept out of swap. False where mlock
+// is unavailable (other platforms, or RLIMIT_MEMLOCK exhausted); the buffer
+// is still wiped on Destroy.
+func (b *Buffer) Locked() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.locked
+}
+
+// Destroy wipes the secret and releases its memory. Safe to call twice.
+func (b *Buffer) Destroy() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.mem == nil {
+		return
+	}
+	Wipe(b.mem)
+	release(b.mem, b.mapped, b.locked)
+	b.data, b.mem, b.mapped, b.locked = nil, nil, false, false
+}
+
+// Wipe zeroes b.
+func Wipe(b []byte) {
+	clear(b)
+}
+
+// allocHeap is the fallback allocation: wiped on Destroy, but not locked.
+func allocHeap(size int) []byte {
+	invariant.Precondition(size > 0, "allocHeap: size must be positive")
+	return make([]byte, size)
+}
@@ -0,0 +1,55 @@
+package secure
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+)
+
+func TestBuffer(t *testing.T) {
+	src := []byte("s3cr3t-value")
+	buf := New(src)
+
+	if !bytes.Equal(buf.Bytes(), src) || buf.Len() != len(src) {
+		t.Fatalf("Bytes = %q, want %q", buf.Bytes(), src)
+	}
+	src[0] = 'X'
+	if buf.Bytes()[0] != 's' {
+		t.Error("buffer should hold its own copy")
+	}
+	if runtime.GOOS == "linux" && !buf.mapped {
+		t.Error("buffer should be mapped outside the heap on linux")
+	}
+
+	buf.Destroy()
+	if buf.Bytes() != nil || buf.Len() != 0 || buf.Locked() {
+		t.Error("destroyed buffer should be empty and unlocked")
+	}
+	buf.Destroy() // Safe twice
+}
+
+func TestNewString(t *testing.T) {
+	buf := NewString("token")
+	defer buf.Destroy()
+	if string(buf.Bytes()) != "token" {
+		t.Errorf("Bytes = %q, want %q", buf.Bytes(), "token")
+	}
+
+	empty := NewString("")
+	if empty.Bytes() != nil || empty.Len() != 0 {
+		t.Error("empty buffer should hold nothing")
+	}
+	empty.Destroy()
+}
+
+// TestDestroyWipes verifies the memory is zeroed before it is released
+func TestDestroyWipes(t *testing.T) {
+	mem := allocHeap(6) // Heap memory stays readable after Destroy
+	copy(mem, "secret")
+	buf := &Buffer{data: mem, mem: mem}
+
+	buf.Destroy()
+	if !bytes.Equal(mem, make([]byte, 6)) {
+		t.Errorf("memory not wiped: %q", mem)
+	}
+}
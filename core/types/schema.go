@@ -212,7 +212,9 @@ type DecoratorSchema struct {
 	BlockRequirement     BlockRequirement       // Whether decorator accepts/requires a block
 	IO                   *IOCapability          // I/O capabilities for pipe operator (nil = no I/O)
 	Redirect             *RedirectCapability    // Redirect capabilities for > and >> operators (nil = no redirect support)
+	RedirectIn           bool                   // Whether the decorator can be read from with < (cmd < @decorator)
 	SwitchesTransport    bool                   // Whether decorator switches execution transport (ssh.connect, docker.exec, etc.)
+	ForwardsParameters   bool                   // Accepts parameters beyond Parameters and passes them on (e.g., @cmd to the called function)
 }
 
 // ParamSchema describes a single parameter
@@ -625,6 +627,15 @@ func (b *SchemaBuilder) WithRedirect(support RedirectSupport) *SchemaBuilder {
 	return b
 }
 
+// WithRedirectIn declares the decorator can be read from with the <
+// operator (psql < @shell("schema.sql")).
+//
+// The decorator must implement sdk.SourceProvider to open the reader.
+func (b *SchemaBuilder) WithRedirectIn() *SchemaBuilder {
+	b.schema.RedirectIn = true
+	return b
+}
+
 // Build returns the constructed schema
 func (b *SchemaBuilder) Build() DecoratorSchema {
 	// Copy parameter order to schema
@@ -15,6 +15,18 @@ func TestBuiltinDecoratorsRegistered(t *testing.T) {
 	if !decorator.Global().IsRegistered("env") {
 		t.Error("built-in decorator 'env' should be registered")
 	}
+
+	if !decorator.Global().IsRegistered("cmd") {
+		t.Error("built-in decorator 'cmd' should be registered")
+	}
+
+	if !decorator.Global().IsRegistered("workdir") {
+		t.Error("built-in decorator 'workdir' should be registered")
+	}
+
+	if !decorator.Global().IsRegistered("log") {
+		t.Error("built-in decorator 'log' should be registered")
+	}
 }
 
 func TestUnknownDecoratorNotRegistered(t *testing.T) {
@@ -0,0 +1,53 @@
+package decorators
+
+import (
+	"fmt"
+
+	"github.com/opal-lang/opal/core/decorator"
+)
+
+// CmdDecorator implements the @cmd execution decorator.
+// @cmd.<name>(args...) calls the function <name>. Calls are expanded by the
+// planner, which inlines the function body with its parameters bound, so
+// @cmd never reaches the executor.
+type CmdDecorator struct{}
+
+// Descriptor returns the decorator metadata.
+func (d *CmdDecorator) Descriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("cmd").
+		Version("1.0.0").
+		Summary("Call a function, expanding its body at plan time").
+		Roles(decorator.RoleWrapper).
+		PrimaryParamString("name", "Function to call").
+		Required().
+		Examples("build", "test_module").
+		Done().
+		ForwardsParams().
+		TransportScope(decorator.TransportScopeAny).
+		Block(decorator.BlockForbidden).
+		Build()
+}
+
+// Wrap implements the Exec interface.
+func (d *CmdDecorator) Wrap(next decorator.ExecNode, params map[string]any) decorator.ExecNode {
+	return &cmdNode{params: params}
+}
+
+// cmdNode reports an unexpanded @cmd call.
+type cmdNode struct {
+	params map[string]any
+}
+
+// Execute implements the ExecNode interface.
+// Reaching it means the plan was not produced by the planner.
+func (n *cmdNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	return decorator.Result{ExitCode: decorator.ExitFailure},
+		fmt.Errorf("@cmd.%v was not expanded at plan time", n.params["name"])
+}
+
+// Register @cmd decorator with the global registry
+func init() {
+	if err := decorator.Register("cmd", &CmdDecorator{}); err != nil {
+		panic(fmt.Sprintf("failed to register @cmd decorator: %v", err))
+	}
+}
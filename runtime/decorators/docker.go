@@ -0,0 +1,15 @@
+package decorators
+
+import (
+	"fmt"
+
+	"github.com/opal-lang/opal/core/decorator"
+)
+
+// Register @docker.exec transport with the global registry.
+// The transport itself lives in core/decorator next to DockerSession.
+func init() {
+	if err := decorator.Register("docker.exec", &decorator.DockerTransport{}); err != nil {
+		panic(fmt.Sprintf("failed to register @docker.exec decorator: %v", err))
+	}
+}
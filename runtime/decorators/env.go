@@ -15,6 +15,7 @@ type EnvDecorator struct{}
 // Descriptor returns the decorator metadata.
 func (d *EnvDecorator) Descriptor() decorator.Descriptor {
 	return decorator.NewDescriptor("env").
+		Version("1.0.0").
 		Summary("Access environment variables from the current session").
 		Roles(decorator.RoleProvider).
 		PrimaryParamString("property", "Environment variable name").
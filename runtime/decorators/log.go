@@ -0,0 +1,100 @@
+package decorators
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/opal-lang/opal/core/planfmt/formatter"
+)
+
+// LogDecorator implements the @log execution decorator.
+// Writes a message to stdout (debug, info) or stderr (warn, error); the CLI
+// routes both through the scrubber.
+type LogDecorator struct{}
+
+// logLevelColors maps each level to its ANSI color.
+var logLevelColors = map[string]string{
+	"debug": formatter.ColorGray,
+	"info":  formatter.ColorCyan,
+	"warn":  formatter.ColorYellow,
+	"error": formatter.ColorRed,
+}
+
+// Descriptor returns the decorator metadata.
+func (d *LogDecorator) Descriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("log").
+		Version("1.0.0").
+		Summary("Write a log message").
+		Roles(decorator.RoleWrapper).
+		ParamString("message", "Message to write").
+		Required().
+		Examples("Deploying...", "Build complete").
+		Done().
+		ParamEnum("level", "Severity of the message").
+		Values("debug", "info", "warn", "error").
+		Default("info").
+		Done().
+		TransportScope(decorator.TransportScopeAny).
+		Block(decorator.BlockForbidden).
+		Build()
+}
+
+// Wrap implements the Exec interface.
+// @log is a leaf decorator - it ignores the 'next' parameter.
+func (d *LogDecorator) Wrap(next decorator.ExecNode, params map[string]any) decorator.ExecNode {
+	return &logNode{params: params}
+}
+
+// logNode writes a single log message.
+type logNode struct {
+	params map[string]any
+}
+
+// Execute implements the ExecNode interface.
+// Info messages are written as-is; other levels get a "level: " prefix.
+// The line is colored by level unless ctx.Color is false.
+func (n *logNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	message, ok := n.params["message"].(string)
+	if !ok {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@log requires a message parameter")
+	}
+	level := "info"
+	if v, ok := n.params["level"].(string); ok && v != "" {
+		level = v
+	}
+	color, ok := logLevelColors[level]
+	if !ok {
+		return decorator.Result{ExitCode: decorator.ExitFailure},
+			fmt.Errorf("@log level must be one of debug, info, warn, error, got %q", level)
+	}
+
+	line := message
+	if level != "info" {
+		line = level + ": " + message
+	}
+
+	// Progress messages stay in order with command output; problems go to stderr
+	var w io.Writer = os.Stdout
+	if ctx.Stdout != nil {
+		w = ctx.Stdout
+	}
+	if level == "warn" || level == "error" {
+		w = os.Stderr
+		if ctx.Stderr != nil {
+			w = ctx.Stderr
+		}
+	}
+	if _, err := fmt.Fprintln(w, formatter.Colorize(line, color, ctx.Color)); err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@log: %w", err)
+	}
+	return decorator.Result{ExitCode: decorator.ExitSuccess}, nil
+}
+
+// Register @log decorator with the global registry
+func init() {
+	if err := decorator.Register("log", &LogDecorator{}); err != nil {
+		panic(fmt.Sprintf("failed to register @log decorator: %v", err))
+	}
+}
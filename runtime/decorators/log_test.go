@@ -0,0 +1,113 @@
+package decorators
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/decorator"
+)
+
+func TestLogDecoratorDescriptor(t *testing.T) {
+	desc := (&LogDecorator{}).Descriptor()
+	if desc.Path != "log" {
+		t.Errorf("expected path 'log', got %q", desc.Path)
+	}
+	if desc.Capabilities.Block != decorator.BlockForbidden {
+		t.Errorf("expected block to be forbidden, got %v", desc.Capabilities.Block)
+	}
+	if desc.Capabilities.TransportScope != decorator.TransportScopeAny {
+		t.Errorf("expected TransportScopeAny, got %v", desc.Capabilities.TransportScope)
+	}
+	level, ok := desc.Schema.Parameters["level"]
+	if !ok || level.EnumSchema == nil {
+		t.Fatal("expected level enum parameter")
+	}
+	if got := strings.Join(level.EnumSchema.Values, ","); got != "debug,info,warn,error" {
+		t.Errorf("unexpected levels %s", got)
+	}
+}
+
+func TestLogLevels(t *testing.T) {
+	tests := []struct {
+		level      string
+		wantStdout string
+		wantStderr string
+	}{
+		{level: "", wantStdout: "Deploying\n"},
+		{level: "debug", wantStdout: "debug: Deploying\n"},
+		{level: "info", wantStdout: "Deploying\n"},
+		{level: "warn", wantStderr: "warn: Deploying\n"},
+		{level: "error", wantStderr: "error: Deploying\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			params := map[string]any{"message": "Deploying"}
+			if tt.level != "" {
+				params["level"] = tt.level
+			}
+			var stdout, stderr bytes.Buffer
+			node := (&LogDecorator{}).Wrap(nil, params)
+
+			result, err := node.Execute(decorator.ExecContext{Context: context.Background(), Stdout: &stdout, Stderr: &stderr})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.ExitCode != decorator.ExitSuccess {
+				t.Errorf("expected exit 0, got %d", result.ExitCode)
+			}
+			if stdout.String() != tt.wantStdout {
+				t.Errorf("stdout: expected %q, got %q", tt.wantStdout, stdout.String())
+			}
+			if stderr.String() != tt.wantStderr {
+				t.Errorf("stderr: expected %q, got %q", tt.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestLogColor(t *testing.T) {
+	params := map[string]any{"message": "careful", "level": "warn"}
+
+	var plain bytes.Buffer
+	_, _ = (&LogDecorator{}).Wrap(nil, params).Execute(decorator.ExecContext{Stderr: &plain})
+	if strings.Contains(plain.String(), "\033[") {
+		t.Errorf("expected no color codes without Color, got %q", plain.String())
+	}
+
+	var colored bytes.Buffer
+	_, _ = (&LogDecorator{}).Wrap(nil, params).Execute(decorator.ExecContext{Stderr: &colored, Color: true})
+	if !strings.HasPrefix(colored.String(), "\033[33m") {
+		t.Errorf("expected yellow warn line, got %q", colored.String())
+	}
+}
+
+func TestLogErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]any
+		wantErr string
+	}{
+		{name: "missing message", params: map[string]any{}, wantErr: "requires a message parameter"},
+		{name: "unknown level", params: map[string]any{"message": "x", "level": "fatal"}, wantErr: "level must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			node := (&LogDecorator{}).Wrap(nil, tt.params)
+			result, err := node.Execute(decorator.ExecContext{Stdout: &out, Stderr: &out})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if result.ExitCode != decorator.ExitFailure {
+				t.Errorf("expected exit %d, got %d", decorator.ExitFailure, result.ExitCode)
+			}
+			if out.Len() != 0 {
+				t.Errorf("expected no output, got %q", out.String())
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package decorators
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"sync"
 
 	"github.com/opal-lang/opal/core/decorator"
 )
@@ -13,6 +18,7 @@ type ParallelDecorator struct{}
 // Descriptor returns the decorator metadata.
 func (d *ParallelDecorator) Descriptor() decorator.Descriptor {
 	return decorator.NewDescriptor("parallel").
+		Version("1.0.0").
 		Summary("Execute tasks in parallel").
 		Roles(decorator.RoleWrapper).
 		ParamInt("maxConcurrency", "Maximum concurrent tasks (0=unlimited)").
@@ -20,6 +26,13 @@ func (d *ParallelDecorator) Descriptor() decorator.Descriptor {
 		Default(0).
 		Examples("0", "5", "10").
 		Done().
+		ParamBool("failFast", "Cancel remaining tasks when one fails").
+		Default(true).
+		Done().
+		ParamEnum("output", "How task output is framed").
+		Values("buffered", "prefixed").
+		Default("buffered").
+		Done().
 		Block(decorator.BlockRequired).
 		Build()
 }
@@ -35,11 +48,286 @@ type parallelNode struct {
 	params map[string]any
 }
 
+// parallelConfig is the validated form of @parallel parameters.
+type parallelConfig struct {
+	maxConcurrency int    // 0 = unlimited
+	failFast       bool   // Cancel siblings on first failure
+	output         string // "buffered" or "prefixed"
+}
+
+// parseParallelConfig applies schema defaults and validates @parallel parameters.
+func parseParallelConfig(params map[string]any) (parallelConfig, error) {
+	cfg := parallelConfig{failFast: true, output: "buffered"}
+	var err error
+
+	if cfg.maxConcurrency, err = intParam(params, "maxConcurrency", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.maxConcurrency < 0 {
+		return cfg, fmt.Errorf("@parallel maxConcurrency must not be negative, got %d", cfg.maxConcurrency)
+	}
+	switch v := params["failFast"].(type) {
+	case nil:
+	case bool:
+		cfg.failFast = v
+	default:
+		return cfg, fmt.Errorf("parameter %q must be a boolean, got %T", "failFast", v)
+	}
+	if v, ok := params["output"].(string); ok && v != "" {
+		cfg.output = v
+	}
+	switch cfg.output {
+	case "buffered", "prefixed":
+	default:
+		return cfg, fmt.Errorf("@parallel output must be buffered or prefixed, got %q", cfg.output)
+	}
+	return cfg, nil
+}
+
 // Execute implements the ExecNode interface.
-// Stub implementation: just executes sequentially for now.
+// Runs each step of the block in its own goroutine (at most maxConcurrency
+// at a time) and waits for all of them. The result is the exit code of the
+// first failing step in block order; steps canceled by failFast only count
+// if nothing else failed.
 func (n *parallelNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
-	// TODO: Implement actual parallel execution logic
-	return n.next.Execute(ctx)
+	cfg, err := parseParallelConfig(n.params)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+	if n.next == nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@parallel requires a block to execute")
+	}
+
+	branches := []decorator.ExecNode{n.next}
+	if block, ok := n.next.(decorator.BlockNode); ok {
+		branches = block.Branches()
+	}
+
+	parent := ctx.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	goCtx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	trace := ctx.Trace
+	if trace == nil {
+		trace = decorator.NoOpSpan{}
+	}
+	out := newFramedOutput(ctx.Stdout, ctx.Stderr, len(branches))
+
+	// Semaphore bounds concurrency; nil means unlimited
+	var sem chan struct{}
+	if cfg.maxConcurrency > 0 {
+		sem = make(chan struct{}, cfg.maxConcurrency)
+	}
+
+	results := make([]decorator.Result, len(branches))
+	errs := make([]error, len(branches))
+	var wg sync.WaitGroup
+	for i, branch := range branches {
+		wg.Add(1)
+		go func(i int, branch decorator.ExecNode) {
+			defer wg.Done()
+
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-goCtx.Done():
+					results[i] = decorator.Result{ExitCode: decorator.ExitCanceled}
+					errs[i] = goCtx.Err()
+					out.finish(i, nil, nil)
+					return
+				}
+			}
+			// Don't start a branch after fail-fast or parent cancellation
+			if goCtx.Err() != nil {
+				results[i] = decorator.Result{ExitCode: decorator.ExitCanceled}
+				errs[i] = goCtx.Err()
+				out.finish(i, nil, nil)
+				return
+			}
+
+			stdout, stderr := out.branch(i+1, cfg.output)
+			span := trace.Child("parallel.branch", map[string]any{"branch": i + 1})
+
+			branchCtx := ctx
+			branchCtx.Context = goCtx
+			branchCtx.Stdout = stdout
+			branchCtx.Stderr = stderr
+			branchCtx.Trace = span
+			results[i], errs[i] = branch.Execute(branchCtx)
+
+			span.SetAttr("exit_code", results[i].ExitCode)
+			span.End()
+			out.finish(i, stdout, stderr)
+
+			if cfg.failFast && results[i].ExitCode != decorator.ExitSuccess {
+				cancel()
+			}
+		}(i, branch)
+	}
+	wg.Wait()
+
+	// Parent cancellation wins over branch failures it caused
+	if parent.Err() != nil {
+		return decorator.Result{ExitCode: decorator.ExitCanceled}, parent.Err()
+	}
+	canceled := -1
+	for i, result := range results {
+		switch result.ExitCode {
+		case decorator.ExitSuccess:
+		case decorator.ExitCanceled:
+			if canceled < 0 {
+				canceled = i
+			}
+		default:
+			return result, errs[i]
+		}
+	}
+	if canceled >= 0 {
+		return results[canceled], errs[canceled]
+	}
+	return decorator.Result{ExitCode: decorator.ExitSuccess}, nil
+}
+
+// framedOutput serializes branch output onto shared stdout/stderr so
+// branches never interleave mid-line. Downstream writers (including the
+// CLI's secret scrubber) only ever see whole lines or whole branch buffers.
+type framedOutput struct {
+	mu     sync.Mutex
+	stdout io.Writer
+	stderr io.Writer
+
+	// Buffered branches are released in block order, not completion order
+	finished [][]*branchWriter // Per branch; nil until the branch finishes
+	done     []bool
+	next     int // First branch not yet released
+}
+
+// newFramedOutput returns a framedOutput for n branches writing to stdout
+// and stderr (nil defaults to the terminal).
+func newFramedOutput(stdout, stderr io.Writer, n int) *framedOutput {
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	return &framedOutput{
+		stdout:   stdout,
+		stderr:   stderr,
+		finished: make([][]*branchWriter, n),
+		done:     make([]bool, n),
+	}
+}
+
+// maxFramedOutput caps what a branch holds per stream: a buffered
+// branch's output, or a prefixed branch's unfinished line. Past it a
+// buffered branch streams like a prefixed one (out of block order), and a
+// longer line is split, so a chatty branch cannot grow memory without bound.
+const maxFramedOutput = 1 << 20 // 1 MiB
+
+// branch returns the stdout and stderr writers for branch id.
+// "buffered" holds all output until the branch and every earlier branch
+// have finished; "prefixed" streams complete lines tagged with "[id] ".
+// Both are capped by maxFramedOutput.
+func (o *framedOutput) branch(id int, mode string) (stdout, stderr *branchWriter) {
+	var prefix []byte
+	if mode == "prefixed" {
+		prefix = branchPrefix(id)
+	}
+	return &branchWriter{out: o, dst: o.stdout, id: id, prefix: prefix},
+		&branchWriter{out: o, dst: o.stderr, id: id, prefix: prefix}
+}
+
+// branchPrefix tags streamed lines with their branch number.
+func branchPrefix(id int) []byte {
+	return []byte(fmt.Sprintf("[%d] ", id))
+}
+
+// finish marks branch i done and writes the remaining output of every
+// finished branch whose predecessors are all done. Each branch's stdout
+// and stderr are written in one critical section so they stay together.
+func (o *framedOutput) finish(i int, writers ...*branchWriter) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.finished[i] = writers
+	o.done[i] = true
+	for o.next < len(o.done) && o.done[o.next] {
+		for _, w := range o.finished[o.next] {
+			if w != nil {
+				w.flushLocked()
+			}
+		}
+		o.finished[o.next] = nil
+		o.next++
+	}
+}
+
+// branchWriter is one branch's stdout or stderr.
+// Branch commands may write from several goroutines (e.g., pipelines),
+// so writes are guarded by the shared framedOutput mutex.
+type branchWriter struct {
+	out    *framedOutput
+	dst    io.Writer
+	id     int    // Branch number (1-indexed)
+	prefix []byte // nil = buffered mode
+	buf    bytes.Buffer
+}
+
+// Write implements io.Writer.
+func (w *branchWriter) Write(p []byte) (int, error) {
+	w.out.mu.Lock()
+	defer w.out.mu.Unlock()
+
+	w.buf.Write(p)
+	if w.prefix == nil {
+		if w.buf.Len() <= maxFramedOutput {
+			return len(p), nil
+		}
+		w.prefix = branchPrefix(w.id) // Too much to hold: stream from here on
+	}
+
+	// Prefixed mode: emit every complete line, keep the partial tail
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// No newline yet - put the partial line back
+			rest := append(line, w.buf.Bytes()...)
+			w.buf.Reset()
+			w.buf.Write(rest)
+			break
+		}
+		if _, err := w.dst.Write(append(append([]byte{}, w.prefix...), line...)); err != nil {
+			return 0, err
+		}
+	}
+	if w.buf.Len() > maxFramedOutput {
+		// A line this long is split rather than held
+		data := append(append(append([]byte{}, w.prefix...), w.buf.Bytes()...), '\n')
+		w.buf.Reset()
+		if _, err := w.dst.Write(data); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flushLocked writes any held output. Assumes out.mu is held.
+func (w *branchWriter) flushLocked() {
+	if w.buf.Len() == 0 {
+		return
+	}
+	data := w.buf.Bytes()
+	if w.prefix != nil {
+		// Terminate the final partial line so the next frame starts cleanly
+		data = append(append(append([]byte{}, w.prefix...), data...), '\n')
+	}
+	_, _ = w.dst.Write(data)
+	w.buf.Reset()
 }
 
 // Register @parallel decorator with the global registry
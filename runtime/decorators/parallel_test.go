@@ -0,0 +1,341 @@
+package decorators
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opal-lang/opal/core/decorator"
+)
+
+// funcNode adapts a function to decorator.ExecNode.
+type funcNode func(ctx decorator.ExecContext) (decorator.Result, error)
+
+func (f funcNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) { return f(ctx) }
+
+// branchBlock is a decorator.BlockNode over fixed branches.
+type branchBlock []decorator.ExecNode
+
+func (b branchBlock) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	for _, branch := range b {
+		if result, err := branch.Execute(ctx); result.ExitCode != 0 || err != nil {
+			return result, err
+		}
+	}
+	return decorator.Result{}, nil
+}
+
+func (b branchBlock) Branches() []decorator.ExecNode { return b }
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writers.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// exitNode returns code after an optional sleep (aborted by cancellation).
+func exitNode(code int, sleep time.Duration) decorator.ExecNode {
+	return funcNode(func(ctx decorator.ExecContext) (decorator.Result, error) {
+		select {
+		case <-time.After(sleep):
+			return decorator.Result{ExitCode: code}, nil
+		case <-ctx.Context.Done():
+			return decorator.Result{ExitCode: decorator.ExitCanceled}, ctx.Context.Err()
+		}
+	})
+}
+
+func parallelCtx() decorator.ExecContext {
+	return decorator.ExecContext{
+		Context: context.Background(),
+		Stdout:  &lockedBuffer{},
+		Stderr:  &lockedBuffer{},
+	}
+}
+
+func TestParallelDecoratorDescriptor(t *testing.T) {
+	desc := (&ParallelDecorator{}).Descriptor()
+	if desc.Path != "parallel" {
+		t.Errorf("expected path 'parallel', got %q", desc.Path)
+	}
+	for _, name := range []string{"maxConcurrency", "failFast", "output"} {
+		if _, ok := desc.Schema.Parameters[name]; !ok {
+			t.Errorf("expected parameter %q in schema", name)
+		}
+	}
+}
+
+func TestParallelRunsBranchesConcurrently(t *testing.T) {
+	var running, peak int32
+	branch := funcNode(func(ctx decorator.ExecContext) (decorator.Result, error) {
+		now := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return decorator.Result{}, nil
+	})
+
+	tests := []struct {
+		maxConcurrency int64
+		want           int32
+	}{
+		{0, 4},
+		{2, 2},
+		{1, 1},
+	}
+	for _, tt := range tests {
+		atomic.StoreInt32(&peak, 0)
+		block := branchBlock{branch, branch, branch, branch}
+		node := (&ParallelDecorator{}).Wrap(block, map[string]any{"maxConcurrency": tt.maxConcurrency})
+
+		result, err := node.Execute(parallelCtx())
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("maxConcurrency=%d: unexpected result %d, %v", tt.maxConcurrency, result.ExitCode, err)
+		}
+		if got := atomic.LoadInt32(&peak); got != tt.want {
+			t.Errorf("maxConcurrency=%d: expected peak concurrency %d, got %d", tt.maxConcurrency, tt.want, got)
+		}
+	}
+}
+
+func TestParallelAggregateExitCode(t *testing.T) {
+	// All branches run; the first failure in block order wins
+	block := branchBlock{exitNode(0, 0), exitNode(3, 30*time.Millisecond), exitNode(5, 0)}
+	node := (&ParallelDecorator{}).Wrap(block, map[string]any{"failFast": false})
+
+	result, _ := node.Execute(parallelCtx())
+	if result.ExitCode != 3 {
+		t.Errorf("expected exit 3, got %d", result.ExitCode)
+	}
+}
+
+func TestParallelFailFastCancelsSiblings(t *testing.T) {
+	// failFast defaults to true
+	block := branchBlock{exitNode(0, 10*time.Second), exitNode(4, 10*time.Millisecond)}
+	node := (&ParallelDecorator{}).Wrap(block, nil)
+
+	start := time.Now()
+	result, err := node.Execute(parallelCtx())
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("failFast should cancel the slow branch")
+	}
+	if result.ExitCode != 4 {
+		t.Errorf("expected the failing branch's exit 4, got %d", result.ExitCode)
+	}
+	if err != nil {
+		t.Errorf("expected no error from the failing branch, got %v", err)
+	}
+}
+
+func TestParallelFailFastSkipsQueuedBranches(t *testing.T) {
+	// With one slot, branches run one at a time in scheduler order;
+	// once the failing branch returns, no queued branch may start
+	var failed, startedAfterFailure int32
+	ok := funcNode(func(ctx decorator.ExecContext) (decorator.Result, error) {
+		if atomic.LoadInt32(&failed) == 1 {
+			atomic.AddInt32(&startedAfterFailure, 1)
+		}
+		return decorator.Result{}, nil
+	})
+	failing := funcNode(func(ctx decorator.ExecContext) (decorator.Result, error) {
+		atomic.StoreInt32(&failed, 1)
+		return decorator.Result{ExitCode: 1}, nil
+	})
+	block := branchBlock{ok, failing, ok, ok}
+	node := (&ParallelDecorator{}).Wrap(block, map[string]any{"maxConcurrency": int64(1), "failFast": true})
+
+	result, _ := node.Execute(parallelCtx())
+	if result.ExitCode != 1 {
+		t.Errorf("expected exit 1, got %d", result.ExitCode)
+	}
+	if got := atomic.LoadInt32(&startedAfterFailure); got != 0 {
+		t.Errorf("expected no branch to start after the failure, %d did", got)
+	}
+}
+
+func TestParallelWithoutFailFastRunsAll(t *testing.T) {
+	var finished int32
+	slow := funcNode(func(ctx decorator.ExecContext) (decorator.Result, error) {
+		time.Sleep(30 * time.Millisecond)
+		atomic.AddInt32(&finished, 1)
+		return decorator.Result{}, nil
+	})
+	block := branchBlock{exitNode(2, 0), slow, slow}
+	node := (&ParallelDecorator{}).Wrap(block, map[string]any{"failFast": false})
+
+	result, _ := node.Execute(parallelCtx())
+	if result.ExitCode != 2 {
+		t.Errorf("expected exit 2, got %d", result.ExitCode)
+	}
+	if got := atomic.LoadInt32(&finished); got != 2 {
+		t.Errorf("expected both slow branches to finish, got %d", got)
+	}
+}
+
+func TestParallelParentCancellation(t *testing.T) {
+	block := branchBlock{exitNode(0, 10*time.Second), exitNode(0, 10*time.Second)}
+	node := (&ParallelDecorator{}).Wrap(block, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	execCtx := parallelCtx()
+	execCtx.Context = ctx
+
+	result, err := node.Execute(execCtx)
+	if result.ExitCode != decorator.ExitCanceled {
+		t.Errorf("expected exit %d, got %d", decorator.ExitCanceled, result.ExitCode)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+// chattyNode writes count lines to stdout in small, unaligned chunks.
+func chattyNode(name string, count int) decorator.ExecNode {
+	return funcNode(func(ctx decorator.ExecContext) (decorator.Result, error) {
+		for i := 0; i < count; i++ {
+			line := name + " line\n"
+			for _, chunk := range []string{line[:2], line[2:5], line[5:]} {
+				if _, err := ctx.Stdout.Write([]byte(chunk)); err != nil {
+					return decorator.Result{ExitCode: 1}, err
+				}
+			}
+			time.Sleep(time.Millisecond)
+		}
+		return decorator.Result{}, nil
+	})
+}
+
+func TestParallelBufferedOutputIsContiguous(t *testing.T) {
+	block := branchBlock{chattyNode("alpha", 20), chattyNode("beta", 20)}
+	node := (&ParallelDecorator{}).Wrap(block, map[string]any{"output": "buffered"})
+
+	ctx := parallelCtx()
+	if _, err := node.Execute(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := ctx.Stdout.(*lockedBuffer).String()
+	want := strings.Repeat("alpha line\n", 20) + strings.Repeat("beta line\n", 20)
+	if out != want {
+		t.Errorf("expected each branch's output as one block, got %q", out)
+	}
+}
+
+func TestParallelBufferedOutputInBlockOrder(t *testing.T) {
+	say := func(msg string, sleep time.Duration) decorator.ExecNode {
+		return funcNode(func(ctx decorator.ExecContext) (decorator.Result, error) {
+			time.Sleep(sleep)
+			_, err := ctx.Stdout.Write([]byte(msg + "\n"))
+			return decorator.Result{}, err
+		})
+	}
+	block := branchBlock{say("Slow", 50*time.Millisecond), say("Fast", 0)}
+	node := (&ParallelDecorator{}).Wrap(block, nil)
+
+	ctx := parallelCtx()
+	if _, err := node.Execute(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ctx.Stdout.(*lockedBuffer).String(); got != "Slow\nFast\n" {
+		t.Errorf("expected output in block order, got %q", got)
+	}
+}
+
+func TestParallelPrefixedOutputIsLineAtomic(t *testing.T) {
+	block := branchBlock{chattyNode("alpha", 20), chattyNode("beta", 20)}
+	node := (&ParallelDecorator{}).Wrap(block, map[string]any{"output": "prefixed"})
+
+	ctx := parallelCtx()
+	if _, err := node.Execute(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(ctx.Stdout.(*lockedBuffer).String(), "\n"), "\n")
+	if len(lines) != 40 {
+		t.Fatalf("expected 40 lines, got %d: %q", len(lines), lines)
+	}
+	for _, line := range lines {
+		if line != "[1] alpha line" && line != "[2] beta line" {
+			t.Errorf("unexpected interleaved or unprefixed line %q", line)
+		}
+	}
+}
+
+func TestParallelPrefixedFlushesPartialLine(t *testing.T) {
+	partial := funcNode(func(ctx decorator.ExecContext) (decorator.Result, error) {
+		_, err := ctx.Stderr.Write([]byte("no newline"))
+		return decorator.Result{}, err
+	})
+	node := (&ParallelDecorator{}).Wrap(branchBlock{partial}, map[string]any{"output": "prefixed"})
+
+	ctx := parallelCtx()
+	if _, err := node.Execute(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ctx.Stderr.(*lockedBuffer).String(); got != "[1] no newline\n" {
+		t.Errorf("expected terminated prefixed line, got %q", got)
+	}
+}
+
+func TestParallelBufferedOutputIsCapped(t *testing.T) {
+	out := newFramedOutput(&lockedBuffer{}, &lockedBuffer{}, 2)
+	stdout, _ := out.branch(2, "buffered")
+	dst := out.stdout.(*lockedBuffer)
+
+	line := strings.Repeat("x", 1023) + "\n"
+	for written := 0; written <= maxFramedOutput; written += len(line) {
+		if _, err := stdout.Write([]byte(line)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Branch 1 has not finished, yet branch 2 streams once over the cap
+	if got := dst.String(); !strings.HasPrefix(got, "[2] "+line) {
+		t.Fatalf("expected prefixed lines past the cap, got %d bytes", len(got))
+	}
+	if stdout.buf.Len() != 0 {
+		t.Errorf("expected nothing held after spilling, got %d bytes", stdout.buf.Len())
+	}
+
+	// An endless line is split instead of held
+	stdout.Write([]byte(strings.Repeat("y", maxFramedOutput+1)))
+	if stdout.buf.Len() != 0 {
+		t.Errorf("expected a long line to be split, %d bytes held", stdout.buf.Len())
+	}
+}
+
+func TestParallelInvalidParams(t *testing.T) {
+	tests := []map[string]any{
+		{"maxConcurrency": int64(-1)},
+		{"failFast": "yes"},
+		{"output": "interleaved"},
+	}
+	for _, params := range tests {
+		node := (&ParallelDecorator{}).Wrap(branchBlock{exitNode(0, 0)}, params)
+		if _, err := node.Execute(parallelCtx()); err == nil {
+			t.Errorf("expected error for params %v", params)
+		}
+	}
+}
@@ -1,7 +1,15 @@
 package decorators
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/opal-lang/opal/core/decorator"
 )
@@ -13,6 +21,7 @@ type RetryDecorator struct{}
 // Descriptor returns the decorator metadata.
 func (d *RetryDecorator) Descriptor() decorator.Descriptor {
 	return decorator.NewDescriptor("retry").
+		Version("1.0.0").
 		Summary("Retry failed operations with exponential backoff").
 		Roles(decorator.RoleWrapper).
 		ParamInt("times", "Number of retry attempts").
@@ -29,6 +38,20 @@ func (d *RetryDecorator) Descriptor() decorator.Descriptor {
 		Values("exponential", "linear", "constant").
 		Default("exponential").
 		Done().
+		ParamDuration("max_delay", "Upper bound for the delay between retries (0 = unbounded)").
+		Default("0s").
+		Examples("30s", "5m").
+		Done().
+		ParamDuration("jitter", "Maximum random duration added to each delay").
+		Default("0s").
+		Examples("100ms", "1s").
+		Done().
+		ParamString("on_exit_codes", "Only retry when the exit code is in this comma-separated list").
+		Examples("1", "1,75").
+		Done().
+		ParamString("unless_exit_codes", "Never retry when the exit code is in this comma-separated list").
+		Examples("2", "2,127").
+		Done().
 		Block(decorator.BlockOptional).
 		Build()
 }
@@ -42,13 +65,305 @@ func (d *RetryDecorator) Wrap(next decorator.ExecNode, params map[string]any) de
 type retryNode struct {
 	next   decorator.ExecNode
 	params map[string]any
+
+	// randInt63n draws jitter; nil uses math/rand (tests replace it)
+	randInt63n func(n int64) int64
+
+	// stdinLimit bounds replayed stdin; 0 uses maxStdinReplay (tests lower it)
+	stdinLimit int
+}
+
+// retryConfig is the validated form of @retry parameters.
+type retryConfig struct {
+	times     int
+	delay     time.Duration
+	backoff   string
+	maxDelay  time.Duration
+	jitter    time.Duration
+	onCodes   map[int]bool // nil = retry any failure
+	skipCodes map[int]bool // nil = no exclusions
+}
+
+// parseRetryConfig applies schema defaults and validates @retry parameters.
+func parseRetryConfig(params map[string]any) (retryConfig, error) {
+	cfg := retryConfig{backoff: "exponential"}
+	var err error
+
+	if cfg.times, err = intParam(params, "times", 3); err != nil {
+		return cfg, err
+	}
+	if cfg.times < 1 || cfg.times > 100 {
+		return cfg, fmt.Errorf("@retry times must be between 1 and 100, got %d", cfg.times)
+	}
+	if cfg.delay, err = durationParam(params, "delay", time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.maxDelay, err = durationParam(params, "max_delay", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.jitter, err = durationParam(params, "jitter", 0); err != nil {
+		return cfg, err
+	}
+	if v, ok := params["backoff"].(string); ok && v != "" {
+		cfg.backoff = v
+	}
+	switch cfg.backoff {
+	case "exponential", "linear", "constant":
+	default:
+		return cfg, fmt.Errorf("@retry backoff must be exponential, linear or constant, got %q", cfg.backoff)
+	}
+	if cfg.onCodes, err = exitCodeSetParam(params, "on_exit_codes"); err != nil {
+		return cfg, err
+	}
+	if cfg.skipCodes, err = exitCodeSetParam(params, "unless_exit_codes"); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// shouldRetry reports whether a failed attempt with this exit code may be retried.
+// ExitCanceled is retryable (e.g., a nested @timeout fired); cancellation of
+// @retry's own context is checked separately in Execute.
+func (c retryConfig) shouldRetry(exitCode int) bool {
+	if exitCode == decorator.ExitSuccess {
+		return false
+	}
+	if c.onCodes != nil && !c.onCodes[exitCode] {
+		return false
+	}
+	return !c.skipCodes[exitCode]
+}
+
+// backoffDelay returns the base delay before retry number n (1-based),
+// capped at maxDelay when set.
+func (c retryConfig) backoffDelay(n int) time.Duration {
+	d := c.delay
+	switch c.backoff {
+	case "linear":
+		d = c.delay * time.Duration(n)
+	case "exponential":
+		for i := 1; i < n; i++ {
+			// Stop doubling before overflow; the cap below bounds it anyway
+			if d > time.Duration(1<<62)/2 {
+				break
+			}
+			d *= 2
+		}
+	}
+	if c.maxDelay > 0 && d > c.maxDelay {
+		d = c.maxDelay
+	}
+	return d
 }
 
 // Execute implements the ExecNode interface.
-// Stub implementation: just executes once for now.
+// Runs next until it succeeds, a retry predicate rejects the exit code,
+// attempts are exhausted, or the context is canceled. Each attempt reads
+// piped stdin from the start; past maxStdinReplay bytes @retry fails
+// instead of retrying with partial input.
 func (n *retryNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
-	// TODO: Implement actual retry logic with backoff
-	return n.next.Execute(ctx)
+	cfg, err := parseRetryConfig(n.params)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+	if n.next == nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@retry requires a block to execute")
+	}
+
+	goCtx := ctx.Context
+	if goCtx == nil {
+		goCtx = context.Background()
+	}
+	trace := ctx.Trace
+	if trace == nil {
+		trace = decorator.NoOpSpan{}
+	}
+
+	// Piped input is recorded as attempts read it, so each retry reads
+	// it again from the start
+	var replay *stdinReplay
+	if ctx.Stdin != nil {
+		replay = &stdinReplay{src: ctx.Stdin, limit: maxStdinReplay}
+		if n.stdinLimit > 0 {
+			replay.limit = n.stdinLimit
+		}
+	}
+
+	var result decorator.Result
+	for attempt := 1; attempt <= cfg.times; attempt++ {
+		span := trace.Child("retry.attempt", map[string]any{"attempt": attempt})
+		attemptCtx := ctx
+		attemptCtx.Trace = span // The attempt's commands nest below it
+		if replay != nil {
+			attemptCtx.Stdin = replay.reader()
+		}
+		result, err = n.next.Execute(attemptCtx)
+		span.SetAttr("exit_code", result.ExitCode)
+		span.End()
+
+		if result.ExitCode == decorator.ExitSuccess || attempt == cfg.times || !cfg.shouldRetry(result.ExitCode) {
+			return result, err
+		}
+		if goCtx.Err() != nil {
+			return decorator.Result{ExitCode: decorator.ExitCanceled}, goCtx.Err()
+		}
+		if replay != nil && replay.overflowed() {
+			return result, fmt.Errorf("@retry cannot retry: its stdin exceeded %d bytes, too much to replay; read the input from a file inside the block", replay.limit)
+		}
+
+		wait := cfg.backoffDelay(attempt) + n.jitterFor(cfg.jitter)
+		if wait <= 0 {
+			continue
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-goCtx.Done():
+			timer.Stop()
+			return decorator.Result{ExitCode: decorator.ExitCanceled}, goCtx.Err()
+		case <-timer.C:
+		}
+	}
+	return result, err
+}
+
+// maxStdinReplay bounds the piped input @retry keeps for replay.
+const maxStdinReplay = 64 << 20
+
+// stdinReplay records what attempts read from piped input, up to limit
+// bytes, so the next attempt can read it again. Past the limit recording
+// stops and the input can no longer be replayed.
+type stdinReplay struct {
+	mu       sync.Mutex // Commands of one attempt may read concurrently (@parallel)
+	src      io.Reader
+	limit    int
+	buf      bytes.Buffer
+	overflow bool
+}
+
+// reader returns the input for the next attempt: what earlier attempts
+// read, then the rest of src.
+func (r *stdinReplay) reader() io.Reader {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return io.MultiReader(bytes.NewReader(r.buf.Bytes()), recordingReader{r})
+}
+
+// overflowed reports whether the input outgrew the limit.
+func (r *stdinReplay) overflowed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.overflow
+}
+
+// recordingReader reads src, recording what it reads.
+type recordingReader struct {
+	r *stdinReplay
+}
+
+func (rr recordingReader) Read(p []byte) (int, error) {
+	r := rr.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n, err := r.src.Read(p)
+	if n > 0 && !r.overflow {
+		if r.buf.Len()+n > r.limit {
+			r.overflow = true
+			r.buf = bytes.Buffer{} // Release the recording
+		} else {
+			r.buf.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+// jitterFor draws a random duration in [0, limit).
+func (n *retryNode) jitterFor(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	draw := rand.Int63n
+	if n.randInt63n != nil {
+		draw = n.randInt63n
+	}
+	return time.Duration(draw(int64(limit)))
+}
+
+// intParam reads an integer parameter, accepting the int64 values produced
+// by the planner as well as plain ints.
+func intParam(params map[string]any, name string, def int) (int, error) {
+	switch v := params[name].(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("parameter %q must be an integer, got %q", name, v)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("parameter %q must be an integer, got %T", name, v)
+	}
+}
+
+// durationParam reads a duration parameter. The planner stores durations
+// as strings (e.g., "500ms"), so both strings and time.Duration are accepted.
+// Negative durations are rejected in either form.
+func durationParam(params map[string]any, name string, def time.Duration) (time.Duration, error) {
+	var d time.Duration
+	switch v := params[name].(type) {
+	case nil:
+		return def, nil
+	case time.Duration:
+		d = v
+	case string:
+		var err error
+		d, err = time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("parameter %q must be a duration, got %q", name, v)
+		}
+	default:
+		return 0, fmt.Errorf("parameter %q must be a duration, got %T", name, v)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("parameter %q must not be negative, got %q", name, d)
+	}
+	return d, nil
+}
+
+// exitCodeSetParam reads a set of exit codes given as a comma-separated
+// string ("1,75") or a single integer. Returns nil when the parameter is absent.
+func exitCodeSetParam(params map[string]any, name string) (map[int]bool, error) {
+	switch v := params[name].(type) {
+	case nil:
+		return nil, nil
+	case int:
+		return map[int]bool{v: true}, nil
+	case int64:
+		return map[int]bool{int(v): true}, nil
+	case string:
+		set := make(map[int]bool)
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			code, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %q must list integer exit codes, got %q", name, field)
+			}
+			set[code] = true
+		}
+		if len(set) == 0 {
+			return nil, nil
+		}
+		return set, nil
+	default:
+		return nil, fmt.Errorf("parameter %q must be a list of exit codes, got %T", name, v)
+	}
 }
 
 // Register @retry decorator with the global registry
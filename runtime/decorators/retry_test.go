@@ -0,0 +1,302 @@
+package decorators
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opal-lang/opal/core/decorator"
+)
+
+// scriptedNode returns exit codes from a script, one per call.
+type scriptedNode struct {
+	codes []int
+	calls int
+}
+
+func (n *scriptedNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	code := n.codes[len(n.codes)-1]
+	if n.calls < len(n.codes) {
+		code = n.codes[n.calls]
+	}
+	n.calls++
+	return decorator.Result{ExitCode: code}, nil
+}
+
+// recordingSpan captures child spans and their attributes.
+type recordingSpan struct {
+	attrs    map[string]any
+	children []*recordingSpan
+	ended    bool
+}
+
+func (s *recordingSpan) End()                          { s.ended = true }
+func (s *recordingSpan) SetAttr(key string, value any) { s.attrs[key] = value }
+func (s *recordingSpan) Child(name string, attrs map[string]any) decorator.Span {
+	child := &recordingSpan{attrs: map[string]any{"name": name}}
+	for k, v := range attrs {
+		child.attrs[k] = v
+	}
+	s.children = append(s.children, child)
+	return child
+}
+
+func retryCtx() decorator.ExecContext {
+	return decorator.ExecContext{Context: context.Background()}
+}
+
+func TestRetryDecoratorDescriptor(t *testing.T) {
+	desc := (&RetryDecorator{}).Descriptor()
+	if desc.Path != "retry" {
+		t.Errorf("expected path 'retry', got %q", desc.Path)
+	}
+	for _, name := range []string{"times", "delay", "backoff", "max_delay", "jitter", "on_exit_codes", "unless_exit_codes"} {
+		if _, ok := desc.Schema.Parameters[name]; !ok {
+			t.Errorf("expected parameter %q in schema", name)
+		}
+	}
+}
+
+func TestRetrySucceedsAfterFailures(t *testing.T) {
+	next := &scriptedNode{codes: []int{1, 1, 0}}
+	node := (&RetryDecorator{}).Wrap(next, map[string]any{"times": int64(5), "delay": "0s"})
+
+	result, err := node.Execute(retryCtx())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected exit 0, got %d", result.ExitCode)
+	}
+	if next.calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", next.calls)
+	}
+}
+
+func TestRetryExhaustsAttempts(t *testing.T) {
+	next := &scriptedNode{codes: []int{7}}
+	node := (&RetryDecorator{}).Wrap(next, map[string]any{"times": int64(3), "delay": "0s"})
+
+	result, _ := node.Execute(retryCtx())
+	if result.ExitCode != 7 {
+		t.Errorf("expected last exit code 7, got %d", result.ExitCode)
+	}
+	if next.calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", next.calls)
+	}
+}
+
+func TestRetryOnExitCodes(t *testing.T) {
+	// 75 is retryable, 2 is not: stop on the second attempt
+	next := &scriptedNode{codes: []int{75, 2, 0}}
+	node := (&RetryDecorator{}).Wrap(next, map[string]any{
+		"times":         int64(5),
+		"delay":         "0s",
+		"on_exit_codes": "1, 75",
+	})
+
+	result, _ := node.Execute(retryCtx())
+	if result.ExitCode != 2 {
+		t.Errorf("expected exit 2, got %d", result.ExitCode)
+	}
+	if next.calls != 2 {
+		t.Errorf("expected 2 attempts, got %d", next.calls)
+	}
+}
+
+func TestRetryUnlessExitCodes(t *testing.T) {
+	next := &scriptedNode{codes: []int{127, 0}}
+	node := (&RetryDecorator{}).Wrap(next, map[string]any{
+		"times":             int64(5),
+		"delay":             "0s",
+		"unless_exit_codes": int64(127),
+	})
+
+	result, _ := node.Execute(retryCtx())
+	if result.ExitCode != 127 {
+		t.Errorf("expected exit 127, got %d", result.ExitCode)
+	}
+	if next.calls != 1 {
+		t.Errorf("expected 1 attempt, got %d", next.calls)
+	}
+}
+
+func TestRetryBackoffDelays(t *testing.T) {
+	tests := []struct {
+		backoff  string
+		maxDelay time.Duration
+		want     []time.Duration
+	}{
+		{"constant", 0, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond}},
+		{"linear", 0, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}},
+		{"exponential", 0, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}},
+		{"exponential", 250 * time.Millisecond, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		cfg := retryConfig{delay: 100 * time.Millisecond, backoff: tt.backoff, maxDelay: tt.maxDelay}
+		for i, want := range tt.want {
+			if got := cfg.backoffDelay(i + 1); got != want {
+				t.Errorf("%s (max %v): retry %d delay = %v, want %v", tt.backoff, tt.maxDelay, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestRetryExponentialBackoffDoesNotOverflow(t *testing.T) {
+	cfg := retryConfig{delay: time.Hour, backoff: "exponential"}
+	if got := cfg.backoffDelay(100); got <= 0 {
+		t.Errorf("expected positive delay, got %v", got)
+	}
+}
+
+func TestRetryJitterAddedToDelay(t *testing.T) {
+	next := &scriptedNode{codes: []int{1, 0}}
+	var drawnLimit int64
+	node := &retryNode{
+		next:   next,
+		params: map[string]any{"delay": "0s", "jitter": "20ms"},
+		randInt63n: func(n int64) int64 {
+			drawnLimit = n
+			return n - 1
+		},
+	}
+
+	start := time.Now()
+	if _, err := node.Execute(retryCtx()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if drawnLimit != int64(20*time.Millisecond) {
+		t.Errorf("expected jitter drawn from [0, 20ms), got limit %v", time.Duration(drawnLimit))
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("expected jitter to delay the retry, elapsed %v", elapsed)
+	}
+}
+
+func TestRetryStopsWhenContextCanceled(t *testing.T) {
+	next := &scriptedNode{codes: []int{1}}
+	node := (&RetryDecorator{}).Wrap(next, map[string]any{"times": int64(10), "delay": "10s"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := node.Execute(decorator.ExecContext{Context: ctx})
+	if time.Since(start) > time.Second {
+		t.Fatal("retry should stop waiting when context is canceled")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if result.ExitCode != decorator.ExitCanceled {
+		t.Errorf("expected exit %d, got %d", decorator.ExitCanceled, result.ExitCode)
+	}
+	if next.calls != 1 {
+		t.Errorf("expected 1 attempt before cancellation, got %d", next.calls)
+	}
+}
+
+func TestRetryRecordsAttemptSpans(t *testing.T) {
+	next := &scriptedNode{codes: []int{3, 0}}
+	node := (&RetryDecorator{}).Wrap(next, map[string]any{"delay": "0s"})
+	root := &recordingSpan{attrs: map[string]any{}}
+
+	ctx := retryCtx()
+	ctx.Trace = root
+	if _, err := node.Execute(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(root.children) != 2 {
+		t.Fatalf("expected 2 attempt spans, got %d", len(root.children))
+	}
+	for i, want := range []int{3, 0} {
+		span := root.children[i]
+		if span.attrs["name"] != "retry.attempt" {
+			t.Errorf("span %d: expected name retry.attempt, got %v", i, span.attrs["name"])
+		}
+		if span.attrs["attempt"] != i+1 {
+			t.Errorf("span %d: expected attempt %d, got %v", i, i+1, span.attrs["attempt"])
+		}
+		if span.attrs["exit_code"] != want {
+			t.Errorf("span %d: expected exit_code %d, got %v", i, want, span.attrs["exit_code"])
+		}
+		if !span.ended {
+			t.Errorf("span %d: expected span to be ended", i)
+		}
+	}
+}
+
+// readingNode reads up to reads[i] bytes of stdin on attempt i (-1 = all),
+// recording what it read, and fails until the last attempt.
+type readingNode struct {
+	reads []int
+	got   []string
+}
+
+func (n *readingNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	r := ctx.Stdin
+	if limit := n.reads[len(n.got)]; limit >= 0 {
+		r = io.LimitReader(r, int64(limit))
+	}
+	data, err := io.ReadAll(r)
+	n.got = append(n.got, string(data))
+	if len(n.got) < len(n.reads) {
+		return decorator.Result{ExitCode: 1}, err
+	}
+	return decorator.Result{ExitCode: 0}, err
+}
+
+func TestRetryReplaysStdin(t *testing.T) {
+	next := &readingNode{reads: []int{3, -1, -1}}
+	node := (&RetryDecorator{}).Wrap(next, map[string]any{"delay": "0s"})
+
+	ctx := retryCtx()
+	ctx.Stdin = strings.NewReader("line 1\nline 2\n")
+	result, err := node.Execute(ctx)
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("exit %d, err %v", result.ExitCode, err)
+	}
+	// A partial read, then the whole input again on each retry
+	want := []string{"lin", "line 1\nline 2\n", "line 1\nline 2\n"}
+	if strings.Join(next.got, "|") != strings.Join(want, "|") {
+		t.Errorf("attempts read %q, want %q", next.got, want)
+	}
+}
+
+func TestRetryRefusesToReplayLargeStdin(t *testing.T) {
+	next := &readingNode{reads: []int{-1, -1}}
+	node := &retryNode{next: next, params: map[string]any{"delay": "0s"}, stdinLimit: 4}
+
+	ctx := retryCtx()
+	ctx.Stdin = strings.NewReader("more than four bytes")
+	result, err := node.Execute(ctx)
+	if err == nil || !strings.Contains(err.Error(), "@retry cannot retry: its stdin exceeded 4 bytes") {
+		t.Errorf("Expected a replay error, got %v", err)
+	}
+	if result.ExitCode != 1 || len(next.got) != 1 {
+		t.Errorf("exit %d after %d attempts, want exit 1 after 1", result.ExitCode, len(next.got))
+	}
+}
+
+func TestRetryInvalidParams(t *testing.T) {
+	tests := []map[string]any{
+		{"times": int64(0)},
+		{"delay": "soon"},
+		{"delay": "-1s"},
+		{"delay": -time.Second},
+		{"jitter": -time.Millisecond},
+		{"backoff": "fibonacci"},
+		{"on_exit_codes": "1,x"},
+	}
+	for _, params := range tests {
+		node := (&RetryDecorator{}).Wrap(&scriptedNode{codes: []int{0}}, params)
+		if _, err := node.Execute(retryCtx()); err == nil {
+			t.Errorf("expected error for params %v", params)
+		}
+	}
+}
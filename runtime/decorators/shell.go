@@ -1,14 +1,17 @@
 package decorators
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 
 	"github.com/opal-lang/opal/core/decorator"
 	"github.com/opal-lang/opal/core/sdk"
+	sdkexec "github.com/opal-lang/opal/core/sdk/executor"
 	"github.com/opal-lang/opal/core/types"
 )
 
@@ -22,6 +25,7 @@ type ShellDecorator struct {
 // Descriptor returns the decorator metadata.
 func (d *ShellDecorator) Descriptor() decorator.Descriptor {
 	return decorator.NewDescriptor("shell").
+		Version("1.0.0").
 		Summary("Execute shell commands or file I/O").
 		ParamString("command", "Shell command or file path").
 		Required().
@@ -29,6 +33,7 @@ func (d *ShellDecorator) Descriptor() decorator.Descriptor {
 		Done().
 		Block(decorator.BlockForbidden).                      // Leaf decorator - no blocks
 		TransportScope(decorator.TransportScopeAny).          // Works in any session
+		RedirectIn().                                         // cmd < @shell("file.txt")
 		Roles(decorator.RoleWrapper, decorator.RoleEndpoint). // Executes work AND provides I/O
 		Build()
 }
@@ -127,7 +132,7 @@ func (d *ShellDecorator) Open(ctx decorator.ExecContext, mode decorator.IOType)
 	}
 }
 
-// shellSDKAdapter adapts ShellDecorator to old SDK interfaces (SinkProvider)
+// shellSDKAdapter adapts ShellDecorator to old SDK interfaces (SinkProvider, SourceProvider)
 // This is a temporary bridge during migration to support redirect targets.
 type shellSDKAdapter struct{}
 
@@ -142,6 +147,17 @@ func (a *shellSDKAdapter) AsSink(ctx sdk.ExecutionContext) sdk.Sink {
 	return &shellFileSink{path: filePath}
 }
 
+// AsSource implements sdk.SourceProvider for input redirects
+func (a *shellSDKAdapter) AsSource(ctx sdk.ExecutionContext) sdk.Source {
+	// Extract file path from args
+	filePath, ok := ctx.Args()["command"].(string)
+	if !ok || filePath == "" {
+		panic("@shell source requires command parameter (file path)")
+	}
+
+	return &shellFileSource{path: filePath}
+}
+
 // shellFileSink implements sdk.Sink for file I/O
 type shellFileSink struct {
 	path string
@@ -156,12 +172,23 @@ func (s *shellFileSink) Caps() sdk.SinkCaps {
 	}
 }
 
+// Open opens the file where the redirected command runs: through the
+// session inside transport blocks (@ssh.connect), otherwise locally with
+// relative paths resolved against the context's working directory.
 func (s *shellFileSink) Open(ctx sdk.ExecutionContext, mode sdk.RedirectMode, meta map[string]any) (io.WriteCloser, error) {
+	if transport, ok := ctx.Transport().(*sdkexec.SessionTransport); ok {
+		return transport.OpenFileWriter(ctx.Context(), s.path, mode, 0o644)
+	}
+
+	path := s.path
+	if !filepath.IsAbs(path) && ctx.Workdir() != "" {
+		path = filepath.Join(ctx.Workdir(), path)
+	}
 	switch mode {
 	case sdk.RedirectOverwrite:
-		return os.Create(s.path)
+		return os.Create(path)
 	case sdk.RedirectAppend:
-		return os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
 	default:
 		return nil, fmt.Errorf("unsupported redirect mode: %v", mode)
 	}
@@ -171,6 +198,34 @@ func (s *shellFileSink) Identity() (string, string) {
 	return "fs.file", s.path
 }
 
+// shellFileSource implements sdk.Source for file input
+type shellFileSource struct {
+	path string
+}
+
+// Open opens the file where the redirected command runs, like
+// shellFileSink.Open. Inside transport blocks the file is read through the
+// session in one go.
+func (s *shellFileSource) Open(ctx sdk.ExecutionContext, meta map[string]any) (io.ReadCloser, error) {
+	if transport, ok := ctx.Transport().(*sdkexec.SessionTransport); ok {
+		var buf bytes.Buffer
+		if err := transport.Get(ctx.Context(), s.path, &buf); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(&buf), nil
+	}
+
+	path := s.path
+	if !filepath.IsAbs(path) && ctx.Workdir() != "" {
+		path = filepath.Join(ctx.Workdir(), path)
+	}
+	return os.Open(path)
+}
+
+func (s *shellFileSource) Identity() (string, string) {
+	return "fs.file", s.path
+}
+
 // Register @shell decorator with both registries (dual registration during migration)
 func init() {
 	// Register with new decorator registry
@@ -0,0 +1,15 @@
+package decorators
+
+import (
+	"fmt"
+
+	"github.com/opal-lang/opal/core/decorator"
+)
+
+// Register @ssh.connect transport with the global registry.
+// The transport itself lives in core/decorator next to SSHSession.
+func init() {
+	if err := decorator.Register("ssh.connect", &decorator.SSHTransport{}); err != nil {
+		panic(fmt.Sprintf("failed to register @ssh.connect decorator: %v", err))
+	}
+}
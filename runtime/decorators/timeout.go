@@ -1,7 +1,10 @@
 package decorators
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/opal-lang/opal/core/decorator"
 )
@@ -13,12 +16,17 @@ type TimeoutDecorator struct{}
 // Descriptor returns the decorator metadata.
 func (d *TimeoutDecorator) Descriptor() decorator.Descriptor {
 	return decorator.NewDescriptor("timeout").
+		Version("1.0.0").
 		Summary("Execute block with timeout constraint").
 		Roles(decorator.RoleWrapper).
 		ParamDuration("duration", "Maximum execution time").
 		Required().
 		Examples("30s", "5m", "1h").
 		Done().
+		ParamDuration("grace", "Time between SIGTERM and SIGKILL once the deadline hits").
+		Default("5s").
+		Examples("0s", "10s", "1m").
+		Done().
 		Block(decorator.BlockRequired).
 		Build()
 }
@@ -35,10 +43,43 @@ type timeoutNode struct {
 }
 
 // Execute implements the ExecNode interface.
-// Stub implementation: just executes without timeout for now.
+// Runs next under a deadline derived from the parent context. Processes still
+// running at the deadline get SIGTERM, then SIGKILL after the grace period.
+// A deadline hit yields ExitCanceled and a *decorator.TimeoutError.
 func (n *timeoutNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
-	// TODO: Implement actual timeout logic with context cancellation
-	return n.next.Execute(ctx)
+	if _, ok := n.params["duration"]; !ok {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@timeout requires duration parameter")
+	}
+	duration, err := durationParam(n.params, "duration", 0)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+	if duration <= 0 {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@timeout duration must be positive, got %v", duration)
+	}
+	grace, err := durationParam(n.params, "grace", 5*time.Second)
+	if err != nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, err
+	}
+	if n.next == nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@timeout requires a block to execute")
+	}
+
+	parent := ctx.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	deadlineCtx, cancel := context.WithTimeout(parent, duration)
+	defer cancel()
+
+	ctx.Context = decorator.WithGracePeriod(deadlineCtx, grace)
+	result, err := n.next.Execute(ctx)
+
+	// Our deadline fired (not a parent cancellation): report it as a timeout
+	if result.ExitCode != decorator.ExitSuccess && errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) && parent.Err() == nil {
+		return decorator.Result{ExitCode: decorator.ExitCanceled}, &decorator.TimeoutError{Duration: duration}
+	}
+	return result, err
 }
 
 // Register @timeout decorator with the global registry
@@ -0,0 +1,134 @@
+package decorators
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opal-lang/opal/core/decorator"
+)
+
+// blockingNode waits for its context to be done, recording what it saw.
+type blockingNode struct {
+	sawDeadline bool
+	grace       time.Duration
+}
+
+func (n *blockingNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	_, n.sawDeadline = ctx.Context.Deadline()
+	n.grace = decorator.GracePeriod(ctx.Context)
+	<-ctx.Context.Done()
+	return decorator.Result{ExitCode: decorator.ExitCanceled}, ctx.Context.Err()
+}
+
+func TestTimeoutDecoratorDescriptor(t *testing.T) {
+	desc := (&TimeoutDecorator{}).Descriptor()
+	if desc.Path != "timeout" {
+		t.Errorf("expected path 'timeout', got %q", desc.Path)
+	}
+	if _, ok := desc.Schema.Parameters["grace"]; !ok {
+		t.Error("expected grace parameter in schema")
+	}
+}
+
+func TestTimeoutDeadlineReturnsTimeoutError(t *testing.T) {
+	next := &blockingNode{}
+	node := (&TimeoutDecorator{}).Wrap(next, map[string]any{"duration": "50ms", "grace": "2s"})
+
+	start := time.Now()
+	result, err := node.Execute(decorator.ExecContext{Context: context.Background()})
+	if time.Since(start) > time.Second {
+		t.Fatal("timeout should stop the block at its deadline")
+	}
+
+	var timeoutErr *decorator.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected *decorator.TimeoutError, got %v", err)
+	}
+	if timeoutErr.Duration != 50*time.Millisecond {
+		t.Errorf("expected duration 50ms, got %v", timeoutErr.Duration)
+	}
+	if result.ExitCode != decorator.ExitCanceled {
+		t.Errorf("expected exit %d, got %d", decorator.ExitCanceled, result.ExitCode)
+	}
+	if !next.sawDeadline {
+		t.Error("block should run under a deadline context")
+	}
+	if next.grace != 2*time.Second {
+		t.Errorf("expected grace period 2s on context, got %v", next.grace)
+	}
+}
+
+func TestTimeoutDefaultGracePeriod(t *testing.T) {
+	next := &blockingNode{}
+	node := (&TimeoutDecorator{}).Wrap(next, map[string]any{"duration": "10ms"})
+
+	_, _ = node.Execute(decorator.ExecContext{Context: context.Background()})
+	if next.grace != 5*time.Second {
+		t.Errorf("expected default grace period 5s, got %v", next.grace)
+	}
+}
+
+func TestTimeoutCompletesBeforeDeadline(t *testing.T) {
+	next := &scriptedNode{codes: []int{3}}
+	node := (&TimeoutDecorator{}).Wrap(next, map[string]any{"duration": "5s"})
+
+	result, err := node.Execute(decorator.ExecContext{Context: context.Background()})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("expected block exit code 3, got %d", result.ExitCode)
+	}
+}
+
+func TestTimeoutParentCancellationIsNotTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	node := (&TimeoutDecorator{}).Wrap(&blockingNode{}, map[string]any{"duration": "10s"})
+	_, err := node.Execute(decorator.ExecContext{Context: ctx})
+
+	var timeoutErr *decorator.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		t.Error("parent cancellation should not be reported as a timeout")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestTimeoutInvalidParams(t *testing.T) {
+	tests := []map[string]any{
+		{},
+		{"duration": "forever"},
+		{"duration": "0s"},
+		{"duration": "1s", "grace": "-1s"},
+		{"duration": -time.Second},
+		{"duration": time.Second, "grace": -time.Second},
+	}
+	for _, params := range tests {
+		node := (&TimeoutDecorator{}).Wrap(&scriptedNode{codes: []int{0}}, params)
+		if _, err := node.Execute(decorator.ExecContext{Context: context.Background()}); err == nil {
+			t.Errorf("expected error for params %v", params)
+		}
+	}
+}
+
+func TestRetryRetriesNestedTimeout(t *testing.T) {
+	// @retry { @timeout { ... } }: a timed-out attempt is retried
+	next := &scriptedNode{codes: []int{decorator.ExitCanceled, 0}}
+	node := (&RetryDecorator{}).Wrap(next, map[string]any{"delay": "0s"})
+
+	result, _ := node.Execute(decorator.ExecContext{Context: context.Background()})
+	if result.ExitCode != 0 {
+		t.Errorf("expected exit 0, got %d", result.ExitCode)
+	}
+	if next.calls != 2 {
+		t.Errorf("expected 2 attempts, got %d", next.calls)
+	}
+}
@@ -16,6 +16,7 @@ type VarDecorator struct{}
 // Descriptor returns the decorator metadata.
 func (d *VarDecorator) Descriptor() decorator.Descriptor {
 	return decorator.NewDescriptor("var").
+		Version("1.0.0").
 		Summary("Access plan-time variables").
 		Roles(decorator.RoleProvider).
 		PrimaryParamString("name", "Variable name to retrieve").
@@ -0,0 +1,81 @@
+package decorators
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/opal-lang/opal/core/invariant"
+)
+
+// WorkdirDecorator implements the @workdir execution decorator.
+// Runs its block with the session's working directory changed.
+type WorkdirDecorator struct{}
+
+// Descriptor returns the decorator metadata.
+func (d *WorkdirDecorator) Descriptor() decorator.Descriptor {
+	return decorator.NewDescriptor("workdir").
+		Version("1.0.0").
+		Summary("Execute block in a different working directory").
+		Roles(decorator.RoleWrapper).
+		ParamString("path", "Directory to run the block in, relative to the current one").
+		Required().
+		MinLength(1).
+		Examples("cli", "../runtime", "/tmp").
+		Done().
+		TransportScope(decorator.TransportScopeAny).
+		Block(decorator.BlockRequired).
+		Build()
+}
+
+// Wrap implements the Exec interface.
+func (d *WorkdirDecorator) Wrap(next decorator.ExecNode, params map[string]any) decorator.ExecNode {
+	return &workdirNode{next: next, params: params}
+}
+
+// workdirNode wraps an execution node with a working directory change.
+type workdirNode struct {
+	next   decorator.ExecNode
+	params map[string]any
+}
+
+// Execute implements the ExecNode interface.
+// Runs next in a session whose working directory is path, resolved against
+// the current one. Local directories are checked before the block runs;
+// on remote transports a missing directory fails the first command.
+func (n *workdirNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	invariant.NotNil(ctx.Session, "ctx.Session")
+
+	path, ok := n.params["path"].(string)
+	if !ok || path == "" {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@workdir requires a path parameter")
+	}
+	if strings.ContainsRune(path, 0) {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@workdir path must not contain NUL bytes")
+	}
+	if n.next == nil {
+		return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@workdir requires a block to execute")
+	}
+
+	session := ctx.Session.WithWorkdir(path)
+	if session.TransportScope() == decorator.TransportScopeLocal {
+		info, err := os.Stat(session.Cwd())
+		if err != nil {
+			return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@workdir: %w", err)
+		}
+		if !info.IsDir() {
+			return decorator.Result{ExitCode: decorator.ExitFailure}, fmt.Errorf("@workdir: %s is not a directory", session.Cwd())
+		}
+	}
+
+	ctx.Session = session
+	return n.next.Execute(ctx)
+}
+
+// Register @workdir decorator with the global registry
+func init() {
+	if err := decorator.Register("workdir", &WorkdirDecorator{}); err != nil {
+		panic(fmt.Sprintf("failed to register @workdir decorator: %v", err))
+	}
+}
@@ -0,0 +1,118 @@
+package decorators
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/decorator"
+)
+
+// cwdNode records the session directory it ran in.
+type cwdNode struct {
+	cwd   string
+	calls int
+}
+
+func (n *cwdNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	n.cwd = ctx.Session.Cwd()
+	n.calls++
+	return decorator.Result{ExitCode: decorator.ExitSuccess}, nil
+}
+
+func TestWorkdirDecoratorDescriptor(t *testing.T) {
+	desc := (&WorkdirDecorator{}).Descriptor()
+	if desc.Path != "workdir" {
+		t.Errorf("expected path 'workdir', got %q", desc.Path)
+	}
+	if desc.Capabilities.Block != decorator.BlockRequired {
+		t.Errorf("expected block to be required, got %v", desc.Capabilities.Block)
+	}
+	if desc.Capabilities.TransportScope != decorator.TransportScopeAny {
+		t.Errorf("expected TransportScopeAny, got %v", desc.Capabilities.TransportScope)
+	}
+	if len(desc.Schema.ParameterOrder) == 0 || desc.Schema.ParameterOrder[0] != "path" {
+		t.Errorf("expected 'path' as first parameter, got %v", desc.Schema.ParameterOrder)
+	}
+}
+
+func TestWorkdirChangesSessionDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	session := decorator.NewLocalSession().WithWorkdir(dir)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "relative", path: "sub", want: filepath.Join(dir, "sub")},
+		{name: "absolute", path: filepath.Join(dir, "sub"), want: filepath.Join(dir, "sub")},
+		{name: "parent", path: "sub/..", want: dir},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &cwdNode{}
+			node := (&WorkdirDecorator{}).Wrap(next, map[string]any{"path": tt.path})
+
+			result, err := node.Execute(decorator.ExecContext{Context: context.Background(), Session: session})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.ExitCode != decorator.ExitSuccess {
+				t.Errorf("expected exit 0, got %d", result.ExitCode)
+			}
+			if filepath.Clean(next.cwd) != tt.want {
+				t.Errorf("expected block to run in %s, got %s", tt.want, next.cwd)
+			}
+		})
+	}
+
+	if session.Cwd() != dir {
+		t.Errorf("parent session should be unchanged, got %s", session.Cwd())
+	}
+}
+
+func TestWorkdirErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	session := decorator.NewLocalSession().WithWorkdir(dir)
+
+	tests := []struct {
+		name    string
+		params  map[string]any
+		wantErr string
+	}{
+		{name: "missing path", params: map[string]any{}, wantErr: "requires a path parameter"},
+		{name: "empty path", params: map[string]any{"path": ""}, wantErr: "requires a path parameter"},
+		{name: "NUL byte", params: map[string]any{"path": "a\x00b"}, wantErr: "must not contain NUL bytes"},
+		{name: "missing directory", params: map[string]any{"path": "missing"}, wantErr: "no such file or directory"},
+		{name: "not a directory", params: map[string]any{"path": "file.txt"}, wantErr: "is not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &cwdNode{}
+			node := (&WorkdirDecorator{}).Wrap(next, tt.params)
+
+			result, err := node.Execute(decorator.ExecContext{Context: context.Background(), Session: session})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if result.ExitCode != decorator.ExitFailure {
+				t.Errorf("expected exit %d, got %d", decorator.ExitFailure, result.ExitCode)
+			}
+			if next.calls != 0 {
+				t.Error("block should not run")
+			}
+		})
+	}
+}
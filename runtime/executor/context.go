@@ -2,15 +2,18 @@ package executor
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/opal-lang/opal/core/decorator"
 	"github.com/opal-lang/opal/core/invariant"
 	"github.com/opal-lang/opal/core/sdk"
 	sdkexec "github.com/opal-lang/opal/core/sdk/executor"
+	"github.com/opal-lang/opal/runtime/vault"
 )
 
 // executionContext implements sdk.ExecutionContext
@@ -19,10 +22,16 @@ type executionContext struct {
 	executor   *executor
 	args       map[string]interface{} // Decorator arguments (from sdk.Command)
 	ctx        context.Context
-	environ    map[string]string // Immutable snapshot
-	workdir    string            // Immutable snapshot
-	stdin      io.Reader         // Piped input (nil if not piped)
-	stdoutPipe io.Writer         // Piped output (nil if not piped)
+	environ    map[string]string   // Immutable snapshot
+	workdir    string              // Immutable snapshot
+	stdin      io.Reader           // Piped input (nil if not piped)
+	stdoutPipe io.Writer           // Piped output (nil if not piped)
+	span       decorator.Span      // Parent span for decorators (nil if telemetry off)
+	stdout     io.Writer           // Unpiped stdout (nil = os.Stdout)
+	stderr     io.Writer           // Stderr (nil = os.Stderr)
+	sitePath   []vault.PathSegment // Position in the plan tree for secret authorization
+	stepID     uint64              // Innermost enclosing step (indexes block scopes)
+	session    decorator.Session   // Transport session commands run in (nil = local)
 }
 
 // newExecutionContext creates a new execution context for a decorator
@@ -46,6 +55,7 @@ func newExecutionContext(args map[string]interface{}, exec *executor, ctx contex
 		workdir:    wd,               // Immutable snapshot
 		stdin:      nil,              // Root context has no piped input
 		stdoutPipe: nil,              // Root context has no piped output
+		sitePath:   []vault.PathSegment{{Name: "root", Index: -1}},
 	}
 }
 
@@ -63,6 +73,90 @@ func (e *executionContext) ExecuteBlock(steps []sdk.Step) (int, error) {
 	return 0, nil
 }
 
+// withSpan returns a copy of the context whose decorators report
+// telemetry under span. Used when a decorator executes its block.
+func (e *executionContext) withSpan(span decorator.Span) *executionContext {
+	clone := *e
+	clone.span = span
+	return &clone
+}
+
+// withOutput returns a copy of the context whose commands write unpiped
+// stdout and stderr to the given writers instead of the terminal.
+// Used by @parallel to frame each branch's output.
+func (e *executionContext) withOutput(stdout, stderr io.Writer) *executionContext {
+	clone := *e
+	clone.stdout = stdout
+	clone.stderr = stderr
+	return &clone
+}
+
+// withStdin returns a copy of the context whose unpiped commands read
+// stdin from r (nil = no input). Used when a decorator runs its block
+// with piped input.
+func (e *executionContext) withStdin(r io.Reader) *executionContext {
+	clone := *e
+	clone.stdin = r
+	return &clone
+}
+
+// withSiteSegment returns a copy of the context one level deeper in the
+// plan tree. The path is copied so sibling contexts never share a backing array.
+func (e *executionContext) withSiteSegment(name string, index int) *executionContext {
+	clone := *e
+	clone.sitePath = make([]vault.PathSegment, len(e.sitePath), len(e.sitePath)+1)
+	copy(clone.sitePath, e.sitePath)
+	clone.sitePath = append(clone.sitePath, vault.PathSegment{Name: name, Index: index})
+	return &clone
+}
+
+// withStep returns a copy of the context positioned inside step id.
+func (e *executionContext) withStep(id uint64) *executionContext {
+	clone := e.withSiteSegment(fmt.Sprintf("step-%d", id), -1)
+	clone.stepID = id
+	return clone
+}
+
+// withScope returns a copy of the context inside the scope the planner
+// opened for the current step's block (e.g., "@retry" or "@for"),
+// indexed by step ID to match the planner's site paths.
+func (e *executionContext) withScope(name string) *executionContext {
+	return e.withSiteSegment(name, int(e.stepID))
+}
+
+// withSession returns a copy of the context whose commands run in session.
+// Used for transport blocks (@ssh.connect); the session carries the remote
+// environment and working directory.
+func (e *executionContext) withSession(session decorator.Session) *executionContext {
+	clone := *e
+	clone.session = session
+	return &clone
+}
+
+// transportID returns the vault transport scope of the context's commands.
+func (e *executionContext) transportID() string {
+	if e.session == nil {
+		return "local"
+	}
+	return e.session.ID()
+}
+
+// stdoutWriter returns where unpiped stdout goes.
+func (e *executionContext) stdoutWriter() io.Writer {
+	if e.stdout != nil {
+		return e.stdout
+	}
+	return os.Stdout
+}
+
+// stderrWriter returns where stderr goes.
+func (e *executionContext) stderrWriter() io.Writer {
+	if e.stderr != nil {
+		return e.stderr
+	}
+	return os.Stderr
+}
+
 // Context returns the Go context for cancellation and deadlines
 func (e *executionContext) Context() context.Context {
 	return e.ctx
@@ -143,6 +237,12 @@ func (e *executionContext) WithContext(ctx context.Context) sdk.ExecutionContext
 		workdir:    e.workdir,    // Share immutable snapshot
 		stdin:      e.stdin,      // Preserve pipes
 		stdoutPipe: e.stdoutPipe, // Preserve pipes
+		span:       e.span,
+		stdout:     e.stdout,
+		stderr:     e.stderr,
+		sitePath:   e.sitePath,
+		stepID:     e.stepID,
+		session:    e.session,
 	}
 }
 
@@ -165,6 +265,12 @@ func (e *executionContext) WithEnviron(env map[string]string) sdk.ExecutionConte
 		workdir:    e.workdir,
 		stdin:      e.stdin,      // Preserve pipes
 		stdoutPipe: e.stdoutPipe, // Preserve pipes
+		span:       e.span,
+		stdout:     e.stdout,
+		stderr:     e.stderr,
+		sitePath:   e.sitePath,
+		stepID:     e.stepID,
+		session:    e.session,
 	}
 }
 
@@ -209,6 +315,12 @@ func (e *executionContext) WithWorkdir(dir string) sdk.ExecutionContext {
 		workdir:    resolved,
 		stdin:      e.stdin,      // Preserve pipes
 		stdoutPipe: e.stdoutPipe, // Preserve pipes
+		span:       e.span,
+		stdout:     e.stdout,
+		stderr:     e.stderr,
+		sitePath:   e.sitePath,
+		stepID:     e.stepID,
+		session:    e.session,
 	}
 }
 
@@ -240,15 +352,23 @@ func (e *executionContext) Clone(
 		workdir:    e.workdir,  // INHERIT workdir
 		stdin:      stdin,      // NEW (may be nil)
 		stdoutPipe: stdoutPipe, // NEW (may be nil)
+		span:       e.span,     // INHERIT telemetry parent
+		stdout:     e.stdout,   // INHERIT output streams
+		stderr:     e.stderr,   // INHERIT output streams
+		sitePath:   e.sitePath, // INHERIT tree position
+		stepID:     e.stepID,   // INHERIT tree position
+		session:    e.session,  // INHERIT transport
 	}
 }
 
 // Transport returns the transport for command execution and file operations.
-// For local execution, this returns a LocalTransport.
-// Decorators like @ssh.connect wrap ExecutionContext and return their own transport.
+// For local execution, this returns a LocalTransport. Inside transport
+// blocks (@ssh.connect) it runs through the block's session, so redirects
+// write files where the commands run.
 func (e *executionContext) Transport() interface{} {
-	// Return LocalTransport for local execution
-	// Future: decorators like @ssh.connect will wrap context and return SSHTransport
+	if e.session != nil {
+		return sdkexec.NewSessionTransport(e.session)
+	}
 	return &sdkexec.LocalTransport{}
 }
 
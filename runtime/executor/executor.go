@@ -2,10 +2,12 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +23,30 @@ import (
 type Config struct {
 	Debug     DebugLevel     // Debug tracing (development only)
 	Telemetry TelemetryLevel // Telemetry collection (production-safe)
+	Color     bool           // Decorator output may use ANSI colors (@log)
+
+	// Pipefail makes a pipeline fail when any command in it fails (bash's
+	// set -o pipefail): its exit code is the last non-zero one. By default
+	// only the last command's exit code counts.
+	Pipefail bool
+
+	// Sessions holds transport sessions (@ssh.connect), usually the pool the
+	// planner connected with. Optional: if nil, one is created and closed
+	// when execution finishes.
+	Sessions *decorator.SessionPool
+
+	// Stdout and Stderr receive commands' unpiped output (nil = os.Stdout
+	// and os.Stderr). Writes happen before the step that made them finishes.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// StepStarted, if set, is called before each top-level step runs
+	// (e.g., to attribute the output that follows to the step).
+	StepStarted func(stepID uint64)
+
+	// Trace, if set, is the parent of a span per top-level step (e.g., a
+	// run's exported root span). Decorator spans nest below the steps.
+	Trace decorator.Span
 }
 
 // DebugLevel controls debug tracing (development only)
@@ -48,6 +74,9 @@ type ExecutionResult struct {
 	StepsRun    int                 // Number of steps executed
 	Telemetry   *ExecutionTelemetry // Additional metrics (nil if TelemetryOff)
 	DebugEvents []DebugEvent        // Debug events (nil if DebugOff)
+
+	// Timeout is set when the failing step was stopped by a @timeout deadline
+	Timeout *decorator.TimeoutError
 }
 
 // ExecutionTelemetry holds additional execution metrics (optional, production-safe)
@@ -63,6 +92,18 @@ type StepTiming struct {
 	StepID   uint64
 	Duration time.Duration
 	ExitCode int
+	Attempts []AttemptTiming // Per-attempt results from @retry (nil if step never retried)
+
+	// PipeStatus holds the exit code of each command in the step's last
+	// pipeline, like bash's PIPESTATUS (nil if the step ran no pipeline)
+	PipeStatus []int
+}
+
+// AttemptTiming holds timing information for a single @retry attempt
+type AttemptTiming struct {
+	Attempt  int // 1-based attempt number
+	Duration time.Duration
+	ExitCode int
 }
 
 // DebugEvent represents a debug trace event
@@ -75,12 +116,17 @@ type DebugEvent struct {
 
 // executor holds execution state
 type executor struct {
-	config Config
-	vault  *vault.Vault // For DisplayID resolution (nil if no secrets)
+	config   Config
+	vault    *vault.Vault           // For DisplayID resolution (nil if no secrets)
+	sessions *decorator.SessionPool // Transport sessions, keyed by params
 
 	// Execution state
-	stepsRun int
-	exitCode int
+	stepsRun    int
+	exitCode    int
+	mu          sync.Mutex              // Guards lastTimeout, pipeStatus and debugEvents (@parallel branches report concurrently)
+	lastTimeout *decorator.TimeoutError // Most recent @timeout hit in the current step
+	pipeStatus  []int                   // Exit codes of the current step's last pipeline
+	timeout     *decorator.TimeoutError // Timeout that caused the failure (if any)
 
 	// Observability
 	debugEvents []DebugEvent
@@ -100,9 +146,16 @@ func Execute(ctx context.Context, steps []sdk.Step, config Config, vlt *vault.Va
 	invariant.NotNil(ctx, "ctx")
 	invariant.NotNil(steps, "steps")
 
+	sessions := config.Sessions
+	if sessions == nil {
+		sessions = decorator.NewSessionPool()
+		defer sessions.CloseAll()
+	}
+
 	e := &executor{
 		config:    config,
 		vault:     vlt,
+		sessions:  sessions,
 		startTime: time.Now(),
 	}
 
@@ -123,27 +176,50 @@ func Execute(ctx context.Context, steps []sdk.Step, config Config, vlt *vault.Va
 
 	// Create root ExecutionContext with current environment and workdir
 	// This is the entry point - all nested decorators will inherit from this
-	rootExecCtx := newExecutionContext(make(map[string]interface{}), e, ctx)
+	rootExecCtx := newExecutionContext(make(map[string]interface{}), e, ctx).(*executionContext)
+	if config.Stdout != nil || config.Stderr != nil {
+		rootExecCtx = rootExecCtx.withOutput(config.Stdout, config.Stderr)
+	}
 
 	// Execute all steps sequentially
 	for _, step := range steps {
+		if config.StepStarted != nil {
+			config.StepStarted(step.ID)
+		}
 		stepStart := time.Now()
 
 		if config.Debug >= DebugDetailed {
 			e.recordDebugEvent("step_start", step.ID, "executing tree")
 		}
 
-		exitCode := e.executeStep(rootExecCtx, step)
+		// Root span collects decorator-internal spans (e.g., retry attempts)
+		var stepSpan *span
+		stepExecCtx := rootExecCtx
+		if config.Telemetry == TelemetryTiming || config.Trace != nil {
+			stepSpan = newStepSpan(config.Trace, step.ID)
+			stepExecCtx = rootExecCtx.withSpan(stepSpan)
+		}
+
+		e.lastTimeout = nil
+		e.pipeStatus = nil
+		exitCode := e.executeStep(stepExecCtx, step)
 		e.stepsRun++
 
 		stepDuration := time.Since(stepStart)
 
+		if stepSpan != nil {
+			stepSpan.SetAttr("exit_code", exitCode)
+			stepSpan.End()
+		}
+
 		// Record timing if enabled
 		if config.Telemetry == TelemetryTiming {
 			e.telemetry.StepTimings = append(e.telemetry.StepTimings, StepTiming{
-				StepID:   step.ID,
-				Duration: stepDuration,
-				ExitCode: exitCode,
+				StepID:     step.ID,
+				Duration:   stepDuration,
+				ExitCode:   exitCode,
+				Attempts:   stepSpan.attemptTimings(),
+				PipeStatus: e.pipeStatus,
 			})
 		}
 
@@ -154,6 +230,7 @@ func Execute(ctx context.Context, steps []sdk.Step, config Config, vlt *vault.Va
 		// Fail-fast: stop on first failure
 		if exitCode != 0 {
 			e.exitCode = exitCode
+			e.timeout = e.lastTimeout
 			if e.telemetry != nil {
 				stepID := step.ID
 				e.telemetry.FailedStep = &stepID
@@ -187,26 +264,26 @@ func Execute(ctx context.Context, steps []sdk.Step, config Config, vlt *vault.Va
 		StepsRun:    e.stepsRun,
 		Telemetry:   e.telemetry,
 		DebugEvents: e.debugEvents,
+		Timeout:     e.timeout,
 	}, nil
 }
 
 // executeStep executes a single step by executing its tree.
 //
 // Site context matching: During planning, the planner records variable references
-// at site paths like "root/step-1/params/command". During execution, we must push
-// the same step context so AccessByDisplayID() can verify authorization at the
+// at site paths like "root/step-1/params/command". During execution, we must enter
+// the same step context so AccessByDisplayIDAt() can verify authorization at the
 // matching site. Without this, all authorization checks would fail.
+//
+// The path lives in the execution context rather than the vault's path stack,
+// so concurrent branches (@parallel) each resolve secrets at their own site.
 func (e *executor) executeStep(execCtx sdk.ExecutionContext, step sdk.Step) int {
 	// INPUT CONTRACT
 	invariant.NotNil(execCtx, "execCtx")
 	invariant.Precondition(step.Tree != nil, "step must have a tree")
 
-	// Push step context to vault for site path matching
-	if e.vault != nil {
-		stepName := fmt.Sprintf("step-%d", step.ID)
-		e.vault.ResetCounts() // Reset decorator indices for new step
-		e.vault.Push(stepName)
-		defer e.vault.Pop()
+	if ec, ok := execCtx.(*executionContext); ok {
+		execCtx = ec.withStep(step.ID)
 	}
 
 	return e.executeTree(execCtx, step.Tree)
@@ -250,12 +327,99 @@ func (e *executor) executeTree(execCtx sdk.ExecutionContext, node sdk.TreeNode)
 	case *sdk.RedirectNode:
 		return e.executeRedirect(execCtx, n)
 
+	case *sdk.RedirectInNode:
+		return e.executeRedirectIn(execCtx, n, nil)
+
+	case *sdk.GroupNode:
+		return e.executeGroup(execCtx, n)
+
+	case *sdk.TryNode:
+		return e.executeTry(execCtx, n)
+
 	default:
 		invariant.Invariant(false, "unknown TreeNode type: %T", node)
 		return 1 // Unreachable
 	}
 }
 
+// executeGroup executes a group's steps in order, stopping at the first
+// failure. Scoped groups run inside their own scope (e.g., "@for[N]") so
+// secrets resolve at the sites the planner recorded.
+func (e *executor) executeGroup(execCtx sdk.ExecutionContext, group *sdk.GroupNode) int {
+	if ec, ok := execCtx.(*executionContext); ok && group.Scoped() {
+		execCtx = ec.withScope("@" + group.Kind)
+	}
+	exitCode, _ := execCtx.ExecuteBlock(group.Steps)
+	return exitCode
+}
+
+// ExitCodeEnv is the environment variable holding the failed try block's
+// exit code inside the catch block.
+const ExitCodeEnv = "OPAL_EXIT_CODE"
+
+// exitTimeout is the try block's exit code seen by catch when a @timeout
+// stopped it (GNU timeout convention).
+const exitTimeout = 124
+
+// executeTry runs the try block, then the catch block if it failed, then the
+// finally block unconditionally.
+//
+// Cancellation (e.g., SIGINT) skips catch - it is not a command failure - but
+// finally still runs, detached from the canceled context, so cleanup happens.
+// The result is catch's exit code if catch ran, otherwise try's; a failing
+// finally only overrides success.
+func (e *executor) executeTry(execCtx sdk.ExecutionContext, node *sdk.TryNode) int {
+	scoped := func(ctx sdk.ExecutionContext, name string) sdk.ExecutionContext {
+		if ec, ok := ctx.(*executionContext); ok {
+			return ec.withScope(name)
+		}
+		return ctx
+	}
+
+	exitCode, _ := scoped(execCtx, "@try").ExecuteBlock(node.Try)
+	// Only canceling the run skips catch: a @timeout in the try block also
+	// exits with ExitCanceled, but catch handles it like any failure
+	canceled := execCtx.Context().Err() != nil
+
+	if exitCode != 0 && !canceled && node.HasCatch {
+		if e.config.Debug >= DebugDetailed {
+			e.recordDebugEvent("try_failed", 0, fmt.Sprintf("exit=%d, running catch", exitCode))
+		}
+		tryExit := exitCode
+		e.mu.Lock()
+		if exitCode == decorator.ExitCanceled && e.lastTimeout != nil {
+			// Handled here: a failing catch is not reported as a timeout
+			tryExit = exitTimeout
+			e.lastTimeout = nil
+		}
+		e.mu.Unlock()
+
+		env := make(map[string]string, len(execCtx.Environ())+1)
+		for k, v := range execCtx.Environ() {
+			env[k] = v
+		}
+		env[ExitCodeEnv] = strconv.Itoa(tryExit)
+		exitCode, _ = scoped(execCtx.WithEnviron(env), "@catch").ExecuteBlock(node.Catch)
+		canceled = execCtx.Context().Err() != nil
+	}
+
+	if node.HasFinally {
+		finallyCtx := execCtx
+		if canceled {
+			finallyCtx = execCtx.WithContext(context.WithoutCancel(execCtx.Context()))
+		}
+		finallyExit, _ := scoped(finallyCtx, "@finally").ExecuteBlock(node.Finally)
+		if exitCode == 0 {
+			exitCode = finallyExit
+		}
+	}
+
+	if canceled {
+		return decorator.ExitCanceled
+	}
+	return exitCode
+}
+
 // executePipeline executes a pipeline of commands with stdout→stdin streaming
 // Uses io.Pipe() for streaming (bash-compatible: concurrent execution, not buffered)
 // Returns exit code of last command (bash semantics)
@@ -345,7 +509,7 @@ func (e *executor) executePipeline(execCtx sdk.ExecutionContext, pipeline *sdk.P
 				}()
 			} else {
 				// Last command writes to terminal (scrubbed by CLI)
-				stdout = os.Stdout
+				stdout = stdoutFor(execCtx)
 			}
 
 			// Execute tree node (CommandNode or RedirectNode) with pipes
@@ -356,12 +520,37 @@ func (e *executor) executePipeline(execCtx sdk.ExecutionContext, pipeline *sdk.P
 	// Wait for all commands to complete
 	wg.Wait()
 
-	// Return last command's exit code (bash semantics)
-	// TODO: Store PIPESTATUS in telemetry for debugging
-	return exitCodes[numCommands-1]
+	return e.pipelineExit(execCtx, exitCodes)
+}
+
+// pipelineExit records a finished pipeline's exit codes (PIPESTATUS) and
+// returns its exit code: the last command's (bash semantics), or with
+// Pipefail the last non-zero one.
+func (e *executor) pipelineExit(execCtx sdk.ExecutionContext, exitCodes []int) int {
+	exitCode := exitCodes[len(exitCodes)-1]
+	if e.config.Pipefail {
+		for i := len(exitCodes) - 1; i >= 0; i-- {
+			if exitCodes[i] != 0 {
+				exitCode = exitCodes[i]
+				break
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.pipeStatus = exitCodes
+	e.mu.Unlock()
+	if e.config.Debug >= DebugDetailed {
+		var stepID uint64
+		if ec, ok := execCtx.(*executionContext); ok {
+			stepID = ec.stepID
+		}
+		e.recordDebugEvent("pipeline_complete", stepID, fmt.Sprintf("pipestatus=%v, exit=%d", exitCodes, exitCode))
+	}
+	return exitCode
 }
 
-// executeTreeNode executes a tree node (CommandNode or RedirectNode) with optional pipes
+// executeTreeNode executes a tree node (CommandNode, RedirectNode or RedirectInNode) with optional pipes
 // This is used by executePipeline to handle both commands and redirects in pipelines
 func (e *executor) executeTreeNode(execCtx sdk.ExecutionContext, node sdk.TreeNode, stdin io.Reader, stdout io.Writer) int {
 	invariant.NotNil(execCtx, "execCtx")
@@ -379,6 +568,9 @@ func (e *executor) executeTreeNode(execCtx sdk.ExecutionContext, node sdk.TreeNo
 
 		// Execute redirect with piped stdin
 		return e.executeRedirectWithStdin(execCtx, n, stdin)
+	case *sdk.RedirectInNode:
+		// The input redirect replaces the piped stdin (bash semantics)
+		return e.executeRedirectIn(execCtx, n, stdout)
 	default:
 		invariant.Invariant(false, "invalid pipeline element type %T", node)
 		return 1
@@ -393,10 +585,15 @@ func (e *executor) executeCommand(execCtx sdk.ExecutionContext, cmd *sdk.Command
 
 // executeCommandWithPipes executes a command with optional piped stdin/stdout
 // execCtx: execution context with environment, workdir, and cancellation
-// stdin: piped input (nil if not piped)
+// stdin: piped input (nil if not piped: the command reads execCtx's stdin)
 // stdout: piped output (nil if not piped)
 func (e *executor) executeCommandWithPipes(execCtx sdk.ExecutionContext, cmd *sdk.CommandNode, stdin io.Reader, stdout io.Writer) int {
 	invariant.NotNil(execCtx, "execCtx")
+	if stdin == nil {
+		// Inside a block given piped input (cat data | @retry { ... }),
+		// commands not piped from another read that input
+		stdin = execCtx.Stdin()
+	}
 	// Strip @ prefix from decorator name for registry lookup
 	decoratorName := strings.TrimPrefix(cmd.Name, "@")
 
@@ -446,7 +643,7 @@ func (e *executor) executeCommandWithPipes(execCtx sdk.ExecutionContext, cmd *sd
 	exitCode, err := sdkHandler(cmdExecCtx, cmd.Block)
 	if err != nil {
 		// Log error but return exit code
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(stderrFor(execCtx), "Error: %v\n", err)
 	}
 
 	return exitCode
@@ -463,7 +660,7 @@ func (e *executor) executeCommandWithPipes(execCtx sdk.ExecutionContext, cmd *sd
 // During execution, we resolve DisplayIDs back to actual values just before passing
 // params to decorators. The vault enforces site-based authorization to prevent
 // unauthorized access.
-func (e *executor) resolveDisplayIDs(params map[string]any, decoratorName string) (map[string]any, error) {
+func (e *executor) resolveDisplayIDs(execCtx sdk.ExecutionContext, params map[string]any, decoratorName string) (map[string]any, error) {
 	// Import regexp here since we need it
 	displayIDPattern := regexp.MustCompile(`opal:[A-Za-z0-9_-]{22}`)
 	resolved := make(map[string]any)
@@ -487,14 +684,14 @@ func (e *executor) resolveDisplayIDs(params map[string]any, decoratorName string
 		// Resolve each DisplayID
 		result := strVal
 		for _, displayID := range matches {
-			// Call vault.AccessByDisplayID with site info
-			actualValue, err := e.vault.AccessByDisplayID(displayID, key)
+			// Check authorization at this context's site and transport
+			actualValue, err := e.vault.AccessByDisplayIDInTransport(displayID, transportFor(execCtx), sitePathFor(execCtx), key)
 			if err != nil {
 				return nil, fmt.Errorf("failed to resolve %s in %s.%s: %w", displayID, decoratorName, key, err)
 			}
 
 			// Replace DisplayID with actual value
-			result = strings.ReplaceAll(result, displayID, fmt.Sprint(actualValue))
+			result = strings.ReplaceAll(result, displayID, valueText(actualValue))
 		}
 
 		resolved[key] = result
@@ -503,6 +700,15 @@ func (e *executor) resolveDisplayIDs(params map[string]any, decoratorName string
 	return resolved, nil
 }
 
+// valueText returns a resolved value as text. Strings are returned as they
+// are: fmt would keep a copy of the secret in its buffer pool.
+func valueText(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
 // executeNewDecorator executes a decorator from the new registry.
 // Converts ExecutionContext to decorator ExecContext and executes via Exec interface.
 func (e *executor) executeNewDecorator(
@@ -521,31 +727,62 @@ func (e *executor) executeNewDecorator(
 	// Resolve DisplayIDs to actual values if vault is available
 	if e.vault != nil {
 		var err error
-		params, err = e.resolveDisplayIDs(params, cmd.Name)
+		params, err = e.resolveDisplayIDs(execCtx, params, cmd.Name)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error resolving secrets: %v\n", err)
+			fmt.Fprintf(stderrFor(execCtx), "Error resolving secrets: %v\n", err)
 			return 1
 		}
 	}
 
-	// Create execution node
-	node := execDec.Wrap(nil, params)
+	// Create execution node. Block decorators (@retry, @timeout, ...) wrap
+	// their nested steps; leaf decorators receive nil.
+	var next decorator.ExecNode
+	if len(cmd.Block) > 0 {
+		blockCtx := execCtx
+		if ec, ok := execCtx.(*executionContext); ok {
+			blockCtx = ec.withScope("@" + strings.TrimPrefix(cmd.Name, "@"))
+		}
+		next = &blockNode{execCtx: blockCtx, steps: cmd.Block}
+	}
+	node := execDec.Wrap(next, params)
 
-	// CRITICAL: Create session from ExecutionContext to respect decorator hierarchy
-	// This ensures @env/@workdir decorators work correctly
+	// Inside a transport block, commands run in the block's session.
+	// Otherwise create a local session from the ExecutionContext to respect
+	// the decorator hierarchy, so @env/@workdir decorators work correctly.
 	// DO NOT use os.Getwd()/os.Environ() - that discards parent context!
-	session := decorator.NewLocalSession().
-		WithWorkdir(execCtx.Workdir()).
-		WithEnv(execCtx.Environ())
-	defer func() {
-		_ = session.Close() // Ignore close errors in defer
-	}()
+	session := sessionFor(execCtx)
+	if session == nil {
+		local := decorator.NewLocalSession().
+			WithWorkdir(execCtx.Workdir()).
+			WithEnv(execCtx.Environ())
+		defer func() {
+			_ = local.Close() // Ignore close errors in defer
+		}()
+		session = local
+	}
+
+	// Transports (@ssh.connect) run their block in a pooled session. The
+	// planner usually connected already; the pool returns that connection.
+	// Checked before Exec use: transports implement Wrap too.
+	if transport, ok := execDec.(decorator.Transport); ok {
+		transportSession, err := e.sessions.GetOrCreate(transport, session, decorator.SessionParams(params))
+		if err != nil {
+			fmt.Fprintf(stderrFor(execCtx), "Error: %s: %v\n", cmd.Name, err)
+			return 1
+		}
+		session = transportSession
+	}
 
 	// Default stdout to terminal if not provided
 	// This ensures output is visible for non-piped commands
 	if stdout == nil {
-		stdout = os.Stdout
+		stdout = stdoutFor(execCtx)
 	}
+	stderr := stderrFor(execCtx)
+
+	// The runtime's span for the decorator; spans the decorator creates
+	// (e.g., "retry.attempt") and its block's decorators nest below
+	trace := decoratorSpan(execCtx, cmd.Name)
 
 	// Create ExecContext with parent context for cancellation
 	decoratorExecCtx := decorator.ExecContext{
@@ -553,20 +790,166 @@ func (e *executor) executeNewDecorator(
 		Session: session,
 		Stdin:   stdin, // Pass io.Reader directly (was: io.ReadAll + []byte)
 		Stdout:  stdout,
-		Stderr:  os.Stderr, // NEW: Forward stderr to terminal
-		Trace:   nil,
+		Stderr:  stderr, // Terminal, or a @parallel branch's framed writer
+		Trace:   trace,
+		Color:   e.config.Color,
 	}
 
 	// Execute - the shellNode will pass ctx to Session.Run() for cancellation
 	result, err := node.Execute(decoratorExecCtx)
+	trace.SetAttr("exit_code", result.ExitCode)
+	trace.End()
 	if err != nil {
-		// Log error but return exit code (matches SDK behavior)
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		var timeoutErr *decorator.TimeoutError
+		switch {
+		case errors.As(err, &timeoutErr):
+			// Reported by the caller via ExecutionResult.Timeout
+			e.mu.Lock()
+			e.lastTimeout = timeoutErr
+			e.mu.Unlock()
+		case errors.Is(err, context.DeadlineExceeded):
+			// Nested step stopped by an enclosing deadline - reported there
+		default:
+			// Log error but return exit code (matches SDK behavior)
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
 	}
 
 	return result.ExitCode
 }
 
+// blockNode adapts a decorator block (nested steps) to decorator.ExecNode,
+// so Exec decorators can wrap it like any other node.
+type blockNode struct {
+	execCtx sdk.ExecutionContext
+	steps   []sdk.Step
+}
+
+// Execute runs the nested steps under the decorator's Go context, span,
+// stdin, output writers and working directory. Each call starts from the first
+// step, so wrappers like @retry can re-run it.
+func (b *blockNode) Execute(ctx decorator.ExecContext) (decorator.Result, error) {
+	execCtx := b.execCtx
+	if ctx.Context != nil {
+		execCtx = execCtx.WithContext(ctx.Context)
+	}
+	if ec, ok := execCtx.(*executionContext); ok {
+		if ctx.Trace != nil {
+			ec = ec.withSpan(ctx.Trace)
+		}
+		if ctx.Stdout != nil || ctx.Stderr != nil {
+			ec = ec.withOutput(ctx.Stdout, ctx.Stderr)
+		}
+		stdin, release := shareStdin(ctx.Stdin)
+		defer release()
+		execCtx = ec.withStdin(stdin)
+	}
+	switch {
+	case ctx.Session == nil:
+	case ctx.Session.TransportScope() != decorator.TransportScopeLocal:
+		// Transport blocks run in the remote session; it carries its own
+		// environment and directory (Cwd would ask the remote host)
+		if ec, ok := execCtx.(*executionContext); ok {
+			execCtx = ec.withSession(ctx.Session)
+		}
+	case ctx.Session.Cwd() != "" && ctx.Session.Cwd() != execCtx.Workdir():
+		// Wrappers like @workdir change the session's directory
+		execCtx = execCtx.WithWorkdir(ctx.Session.Cwd())
+	}
+
+	exitCode, err := execCtx.ExecuteBlock(b.steps)
+	return decorator.Result{ExitCode: exitCode}, err
+}
+
+// shareStdin returns a reader the block's commands can share. Each command
+// reading an io.Reader that is not a file gets its own copy goroutine,
+// which reads ahead and drops what the command left unread, so in
+// `read line && cat` cat would miss the rest. An OS pipe fed once from r is
+// shared like a shell's stdin: what one command leaves is there for the
+// next. release closes the pipe and waits for the copy to stop (at EOF or
+// on its next write), as exec does for a command's own copy.
+func shareStdin(r io.Reader) (io.Reader, func()) {
+	if _, ok := r.(*os.File); ok || r == nil {
+		return r, func() {}
+	}
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return r, func() {} // Commands read r directly
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(pw, r)
+		_ = pw.Close()
+	}()
+	return pr, func() {
+		_ = pr.Close()
+		<-done
+	}
+}
+
+// Branches implements decorator.BlockNode: one node per nested step.
+func (b *blockNode) Branches() []decorator.ExecNode {
+	branches := make([]decorator.ExecNode, len(b.steps))
+	for i := range b.steps {
+		branches[i] = &blockNode{execCtx: b.execCtx, steps: b.steps[i : i+1]}
+	}
+	return branches
+}
+
+// stdoutFor returns where unpiped stdout goes for execCtx.
+func stdoutFor(execCtx sdk.ExecutionContext) io.Writer {
+	if ec, ok := execCtx.(*executionContext); ok {
+		return ec.stdoutWriter()
+	}
+	return os.Stdout
+}
+
+// stderrFor returns where stderr goes for execCtx.
+func stderrFor(execCtx sdk.ExecutionContext) io.Writer {
+	if ec, ok := execCtx.(*executionContext); ok {
+		return ec.stderrWriter()
+	}
+	return os.Stderr
+}
+
+// sitePathFor returns the plan-tree position of execCtx for secret authorization.
+func sitePathFor(execCtx sdk.ExecutionContext) []vault.PathSegment {
+	if ec, ok := execCtx.(*executionContext); ok {
+		return ec.sitePath
+	}
+	return []vault.PathSegment{{Name: "root", Index: -1}}
+}
+
+// sessionFor returns the transport session execCtx runs in, or nil for local.
+func sessionFor(execCtx sdk.ExecutionContext) decorator.Session {
+	if ec, ok := execCtx.(*executionContext); ok {
+		return ec.session
+	}
+	return nil
+}
+
+// transportFor returns the vault transport scope of execCtx.
+func transportFor(execCtx sdk.ExecutionContext) string {
+	if ec, ok := execCtx.(*executionContext); ok {
+		return ec.transportID()
+	}
+	return "local"
+}
+
+// decoratorSpan starts the span of a decorator invocation under execCtx's
+// span ("decorator.retry" for @retry), or returns a no-op span when
+// telemetry is off.
+func decoratorSpan(execCtx sdk.ExecutionContext, name string) decorator.Span {
+	ec, ok := execCtx.(*executionContext)
+	if !ok || ec.span == nil {
+		return decorator.NoOpSpan{}
+	}
+	name = strings.TrimPrefix(name, "@")
+	return ec.span.Child("decorator."+name, map[string]any{"opal.decorator": "@" + name})
+}
+
 // executeTreeWithStdout executes a tree node with stdout redirected to a custom writer.
 // This is used by redirect and pipe operators to wire stdout between commands.
 // Supports all tree node types: CommandNode, PipelineNode, AndNode, OrNode, SequenceNode.
@@ -609,65 +992,35 @@ func (e *executor) executeTreeWithStdout(execCtx sdk.ExecutionContext, tree sdk.
 		}
 		return lastExit
 
+	case *sdk.RedirectInNode:
+		// Command reading a file: sort < in > out
+		return e.executeRedirectIn(execCtx, n, stdout)
+
 	case *sdk.RedirectNode:
 		// Nested redirect - not supported (would need to chain sinks)
-		fmt.Fprintf(os.Stderr, "Error: nested redirects not supported\n")
+		fmt.Fprintf(stderrFor(execCtx), "Error: nested redirects not supported\n")
 		return 127
 
 	default:
-		fmt.Fprintf(os.Stderr, "Error: unsupported tree node type for redirect: %T\n", tree)
+		fmt.Fprintf(stderrFor(execCtx), "Error: unsupported tree node type for redirect: %T\n", tree)
 		return 127
 	}
 }
 
 // executeTreeWithStdinStdout executes a tree node with both stdin and stdout redirected
-// This is used for redirects inside pipelines where the source needs piped stdin
+// This is used for redirects inside pipelines where the source needs piped stdin.
+// Commands not piped from another read stdin, as in a shell: the first command
+// of a pipeline, and every command of an and/or chain or sequence (they share
+// the stream, each consuming what it reads).
 func (e *executor) executeTreeWithStdinStdout(execCtx sdk.ExecutionContext, tree sdk.TreeNode, stdin io.Reader, stdout io.Writer) int {
 	invariant.NotNil(execCtx, "execCtx")
 
-	switch n := tree.(type) {
-	case *sdk.CommandNode:
-		// Simple command - redirect both stdin and stdout
-		return e.executeCommandWithPipes(execCtx, n, stdin, stdout)
-
-	case *sdk.PipelineNode:
-		// Pipeline - first command gets stdin, last command's stdout is redirected
-		// This is complex - for now, just execute with stdout redirect
-		// TODO: Handle stdin properly for pipelines
-		return e.executePipelineWithStdout(execCtx, n, stdout)
-
-	case *sdk.AndNode:
-		// AND operator: only right side gets stdin (bash semantics)
-		leftExit := e.executeTreeWithStdout(execCtx, n.Left, stdout)
-		if leftExit != 0 {
-			return leftExit
-		}
-		return e.executeTreeWithStdinStdout(execCtx, n.Right, stdin, stdout)
-
-	case *sdk.OrNode:
-		// OR operator: only right side gets stdin (bash semantics)
-		leftExit := e.executeTreeWithStdout(execCtx, n.Left, stdout)
-		if leftExit == 0 {
-			return leftExit
-		}
-		return e.executeTreeWithStdinStdout(execCtx, n.Right, stdin, stdout)
-
-	case *sdk.SequenceNode:
-		// Sequence: only last command gets stdin (bash semantics)
-		var lastExit int
-		for i, node := range n.Nodes {
-			if i == len(n.Nodes)-1 {
-				lastExit = e.executeTreeWithStdinStdout(execCtx, node, stdin, stdout)
-			} else {
-				lastExit = e.executeTreeWithStdout(execCtx, node, stdout)
-			}
-		}
-		return lastExit
-
-	default:
-		fmt.Fprintf(os.Stderr, "Error: unsupported tree node type: %T\n", tree)
-		return 127
+	if ec, ok := execCtx.(*executionContext); ok {
+		execCtx = ec.withStdin(stdin)
+	} else {
+		execCtx = execCtx.Clone(execCtx.Args(), stdin, execCtx.StdoutPipe())
 	}
+	return e.executeTreeWithStdout(execCtx, tree, stdout)
 }
 
 // executePipelineWithStdout executes a pipeline with the final command's stdout redirected.
@@ -690,7 +1043,7 @@ func (e *executor) executePipelineWithStdout(execCtx sdk.ExecutionContext, pipel
 	for i := 0; i < numCommands-1; i++ {
 		pr, pw, err := os.Pipe()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating pipe: %v\n", err)
+			fmt.Fprintf(stderrFor(execCtx), "Error creating pipe: %v\n", err)
 			return 1
 		}
 		pipeReaders[i] = pr
@@ -754,8 +1107,7 @@ func (e *executor) executePipelineWithStdout(execCtx sdk.ExecutionContext, pipel
 	// Wait for all commands to complete
 	wg.Wait()
 
-	// Return exit code of last command (bash semantics)
-	return exitCodes[numCommands-1]
+	return e.pipelineExit(execCtx, exitCodes)
 }
 
 // executeRedirect executes a redirect operation (> or >>)
@@ -772,12 +1124,12 @@ func (e *executor) executeRedirect(execCtx sdk.ExecutionContext, redirect *sdk.R
 	caps := redirect.Sink.Caps()
 	if redirect.Mode == sdk.RedirectOverwrite && !caps.Overwrite {
 		kind, path := redirect.Sink.Identity()
-		fmt.Fprintf(os.Stderr, "Error: sink %s (%s) does not support overwrite (>)\n", kind, path)
+		fmt.Fprintf(stderrFor(execCtx), "Error: sink %s (%s) does not support overwrite (>)\n", kind, path)
 		return 1
 	}
 	if redirect.Mode == sdk.RedirectAppend && !caps.Append {
 		kind, path := redirect.Sink.Identity()
-		fmt.Fprintf(os.Stderr, "Error: sink %s (%s) does not support append (>>)\n", kind, path)
+		fmt.Fprintf(stderrFor(execCtx), "Error: sink %s (%s) does not support append (>>)\n", kind, path)
 		return 1
 	}
 
@@ -785,13 +1137,13 @@ func (e *executor) executeRedirect(execCtx sdk.ExecutionContext, redirect *sdk.R
 	writer, err := redirect.Sink.Open(execCtx, redirect.Mode, nil)
 	if err != nil {
 		kind, path := redirect.Sink.Identity()
-		fmt.Fprintf(os.Stderr, "Error: failed to open sink %s (%s): %v\n", kind, path, err)
+		fmt.Fprintf(stderrFor(execCtx), "Error: failed to open sink %s (%s): %v\n", kind, path, err)
 		return 1
 	}
 	defer func() {
 		if closeErr := writer.Close(); closeErr != nil {
 			kind, path := redirect.Sink.Identity()
-			fmt.Fprintf(os.Stderr, "Error: failed to close sink %s (%s): %v\n", kind, path, closeErr)
+			fmt.Fprintf(stderrFor(execCtx), "Error: failed to close sink %s (%s): %v\n", kind, path, closeErr)
 		}
 	}()
 
@@ -813,12 +1165,12 @@ func (e *executor) executeRedirectWithStdin(execCtx sdk.ExecutionContext, redire
 	caps := redirect.Sink.Caps()
 	if redirect.Mode == sdk.RedirectOverwrite && !caps.Overwrite {
 		kind, path := redirect.Sink.Identity()
-		fmt.Fprintf(os.Stderr, "Error: sink %s (%s) does not support overwrite (>)\n", kind, path)
+		fmt.Fprintf(stderrFor(execCtx), "Error: sink %s (%s) does not support overwrite (>)\n", kind, path)
 		return 1
 	}
 	if redirect.Mode == sdk.RedirectAppend && !caps.Append {
 		kind, path := redirect.Sink.Identity()
-		fmt.Fprintf(os.Stderr, "Error: sink %s (%s) does not support append (>>)\n", kind, path)
+		fmt.Fprintf(stderrFor(execCtx), "Error: sink %s (%s) does not support append (>>)\n", kind, path)
 		return 1
 	}
 
@@ -826,13 +1178,13 @@ func (e *executor) executeRedirectWithStdin(execCtx sdk.ExecutionContext, redire
 	writer, err := redirect.Sink.Open(execCtx, redirect.Mode, nil)
 	if err != nil {
 		kind, path := redirect.Sink.Identity()
-		fmt.Fprintf(os.Stderr, "Error: failed to open sink %s (%s): %v\n", kind, path, err)
+		fmt.Fprintf(stderrFor(execCtx), "Error: failed to open sink %s (%s): %v\n", kind, path, err)
 		return 1
 	}
 	defer func() {
 		if closeErr := writer.Close(); closeErr != nil {
 			kind, path := redirect.Sink.Identity()
-			fmt.Fprintf(os.Stderr, "Error: failed to close sink %s (%s): %v\n", kind, path, closeErr)
+			fmt.Fprintf(stderrFor(execCtx), "Error: failed to close sink %s (%s): %v\n", kind, path, closeErr)
 		}
 	}()
 
@@ -840,12 +1192,66 @@ func (e *executor) executeRedirectWithStdin(execCtx sdk.ExecutionContext, redire
 	return e.executeTreeWithStdinStdout(execCtx, redirect.Source, stdin, writer)
 }
 
+// executeRedirectIn executes a command with its stdin read from an input
+// redirect (cmd < file). stdout is the command's piped or redirected output
+// (nil if not redirected).
+func (e *executor) executeRedirectIn(execCtx sdk.ExecutionContext, redirect *sdk.RedirectInNode, stdout io.Writer) int {
+	invariant.NotNil(execCtx, "execCtx")
+	invariant.NotNil(redirect, "redirect node")
+	invariant.NotNil(redirect.Input, "redirect input")
+
+	source, err := e.redirectSource(execCtx, redirect.Input)
+	if err != nil {
+		fmt.Fprintf(stderrFor(execCtx), "Error: %v\n", err)
+		return 1
+	}
+
+	// Open the source for reading where the command runs
+	reader, err := source.Open(execCtx, nil)
+	if err != nil {
+		kind, path := source.Identity()
+		fmt.Fprintf(stderrFor(execCtx), "Error: failed to open source %s (%s): %v\n", kind, path, err)
+		return 1
+	}
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil {
+			kind, path := source.Identity()
+			fmt.Fprintf(stderrFor(execCtx), "Error: failed to close source %s (%s): %v\n", kind, path, closeErr)
+		}
+	}()
+
+	return e.executeTreeWithStdinStdout(execCtx, redirect.Command, reader, stdout)
+}
+
+// redirectSource evaluates the decorator of an input redirect. Unlike sinks,
+// which are evaluated when the plan is converted, its args resolve here, so
+// secrets in the path (sort < @var.FILE) resolve at the command's site.
+func (e *executor) redirectSource(execCtx sdk.ExecutionContext, input *sdk.CommandNode) (sdk.Source, error) {
+	args := input.Args
+	if e.vault != nil {
+		resolved, err := e.resolveDisplayIDs(execCtx, args, input.Name)
+		if err != nil {
+			return nil, fmt.Errorf("resolving secrets: %w", err)
+		}
+		args = resolved
+	}
+
+	handler, _, exists := types.Global().GetSDKHandler(strings.TrimPrefix(input.Name, "@"))
+	provider, ok := handler.(sdk.SourceProvider)
+	if !exists || !ok {
+		return nil, fmt.Errorf("%s cannot be read from (<)", input.Name)
+	}
+	return provider.AsSource(execCtx.Clone(args, nil, nil)), nil
+}
+
 // recordDebugEvent records a debug event (only if debug enabled)
 func (e *executor) recordDebugEvent(event string, stepID uint64, contextInfo string) {
 	if e.config.Debug == DebugOff {
 		return
 	}
 
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.debugEvents = append(e.debugEvents, DebugEvent{
 		Timestamp: time.Now(),
 		Event:     event,
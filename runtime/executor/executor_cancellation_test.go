@@ -2,9 +2,12 @@ package executor
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/opal-lang/opal/core/decorator"
 	"github.com/opal-lang/opal/core/sdk"
 	_ "github.com/opal-lang/opal/runtime/decorators"
 	"github.com/stretchr/testify/assert"
@@ -128,8 +131,6 @@ func TestPipelineCancellationStopsAllCommands(t *testing.T) {
 // TestNestedDecoratorCancellation verifies that cancellation propagates
 // through nested decorators (e.g., @retry { @timeout { @shell } }).
 func TestNestedDecoratorCancellation(t *testing.T) {
-	t.Skip("TODO: Implement after nested decorator support is verified")
-
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 
@@ -409,3 +410,42 @@ func TestAlreadyCancelledContext(t *testing.T) {
 	assert.NotEqual(t, 0, result.ExitCode, "should return non-zero for cancelled context")
 	assert.NoError(t, err)
 }
+
+// TestCancellationRunsFinally verifies that a finally block still runs after
+// the context is canceled (e.g., SIGINT), while catch is skipped.
+func TestCancellationRunsFinally(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	out := filepath.Join(t.TempDir(), "out.txt")
+
+	shell := func(id uint64, cmd string) []sdk.Step {
+		return []sdk.Step{{ID: id, Tree: &sdk.CommandNode{
+			Name: "@shell",
+			Args: map[string]interface{}{"command": cmd},
+		}}}
+	}
+	steps := []sdk.Step{{
+		ID: 1,
+		Tree: &sdk.TryNode{
+			Try:        shell(2, "sleep 10"),
+			Catch:      shell(3, "echo catch >> "+out),
+			Finally:    shell(4, "echo finally >> "+out),
+			HasCatch:   true,
+			HasFinally: true,
+		},
+	}}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	result, err := Execute(ctx, steps, Config{}, testVault())
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 1*time.Second, "try block should stop quickly after cancellation")
+	assert.Equal(t, decorator.ExitCanceled, result.ExitCode)
+
+	data, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "finally\n", string(data), "finally should run and catch should not")
+}
@@ -2,10 +2,15 @@ package executor
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/opal-lang/opal/core/decorator"
 	"github.com/opal-lang/opal/core/planfmt"
 	_ "github.com/opal-lang/opal/runtime/decorators" // Register built-in decorators
 	"github.com/opal-lang/opal/runtime/vault"
@@ -75,6 +80,43 @@ func TestExecuteMultipleCommands(t *testing.T) {
 	assert.Equal(t, 2, result.StepsRun)
 }
 
+// TestExecuteOutputWriters tests that commands write to the configured
+// writers within the step StepStarted announced
+func TestExecuteOutputWriters(t *testing.T) {
+	plan := &planfmt.Plan{
+		Target: "output",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: shellCmd("echo first; echo oops >&2")},
+			{ID: 2, Tree: shellCmd("echo second")},
+		},
+	}
+
+	var step uint64
+	var stdout, stderr strings.Builder
+	stepWriter := func(b *strings.Builder) io.Writer {
+		return writerFunc(func(p []byte) (int, error) {
+			fmt.Fprintf(b, "%d:%s", step, p)
+			return len(p), nil
+		})
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{
+		Stdout:      stepWriter(&stdout),
+		Stderr:      stepWriter(&stderr),
+		StepStarted: func(id uint64) { step = id },
+	}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "1:first\n2:second\n", stdout.String())
+	assert.Equal(t, "1:oops\n", stderr.String())
+}
+
+// writerFunc adapts a function to io.Writer
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
 // TestExecuteFailingCommand tests that non-zero exit codes are returned
 func TestExecuteFailingCommand(t *testing.T) {
 	plan := &planfmt.Plan{
@@ -199,6 +241,776 @@ func TestExecuteTelemetryFailedStep(t *testing.T) {
 	assert.Equal(t, uint64(1), *result.Telemetry.FailedStep)
 }
 
+// TestExecutePipefail tests pipeline exit codes with and without pipefail
+func TestExecutePipefail(t *testing.T) {
+	pipeline := func(cmds ...string) *planfmt.PipelineNode {
+		node := &planfmt.PipelineNode{}
+		for _, cmd := range cmds {
+			node.Commands = append(node.Commands, shellCmd(cmd))
+		}
+		return node
+	}
+
+	tests := []struct {
+		name           string
+		tree           planfmt.ExecutionNode
+		pipefail       bool
+		wantExitCode   int
+		wantPipeStatus []int
+	}{
+		{"last command counts", pipeline("exit 7", "cat"), false, 0, []int{7, 0}},
+		{"pipefail", pipeline("exit 7", "cat"), true, 7, []int{7, 0}},
+		{"pipefail takes last failure", pipeline("exit 2", "exit 3", "cat"), true, 3, []int{2, 3, 0}},
+		{"pipefail success", pipeline("echo a", "cat"), true, 0, []int{0, 0}},
+		{"last pipeline recorded", &planfmt.AndNode{
+			Left:  pipeline("exit 1", "cat"),
+			Right: pipeline("true", "exit 4"),
+		}, false, 4, []int{0, 4}},
+		{"no pipeline", shellCmd("exit 5"), true, 5, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := planfmt.ToSDKSteps([]planfmt.Step{{ID: 1, Tree: tt.tree}})
+			config := Config{Telemetry: TelemetryTiming, Pipefail: tt.pipefail, Debug: DebugDetailed}
+			result, err := Execute(context.Background(), steps, config, testVault())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantExitCode, result.ExitCode)
+			require.Len(t, result.Telemetry.StepTimings, 1)
+			assert.Equal(t, tt.wantPipeStatus, result.Telemetry.StepTimings[0].PipeStatus)
+
+			var events []string
+			for _, e := range result.DebugEvents {
+				if e.Event == "pipeline_complete" {
+					events = append(events, e.Context)
+				}
+			}
+			if tt.wantPipeStatus != nil {
+				require.NotEmpty(t, events)
+				assert.Equal(t, fmt.Sprintf("pipestatus=%v, exit=%d", tt.wantPipeStatus, tt.wantExitCode), events[len(events)-1])
+			}
+		})
+	}
+}
+
+// retryCmd wraps block steps in @retry with the given attempts and no delay
+func retryCmd(times int64, block ...planfmt.Step) *planfmt.CommandNode {
+	return &planfmt.CommandNode{
+		Decorator: "@retry",
+		Args: []planfmt.Arg{
+			{Key: "delay", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "0s"}},
+			{Key: "times", Val: planfmt.Value{Kind: planfmt.ValueInt, Int: times}},
+		},
+		Block: block,
+	}
+}
+
+// TestExecuteRetryBlock tests that @retry re-runs its block until it succeeds
+func TestExecuteRetryBlock(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "count")
+	script := "n=$(cat " + counter + " 2>/dev/null || echo 0); n=$((n+1)); echo $n > " + counter + "; [ $n -ge 3 ]"
+
+	plan := &planfmt.Plan{
+		Target: "retry",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: retryCmd(5, planfmt.Step{ID: 2, Tree: shellCmd(script)})},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{Telemetry: TelemetryTiming}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+
+	require.Len(t, result.Telemetry.StepTimings, 1)
+	attempts := result.Telemetry.StepTimings[0].Attempts
+	require.Len(t, attempts, 3)
+	for i, want := range []int{1, 1, 0} {
+		assert.Equal(t, i+1, attempts[i].Attempt)
+		assert.Equal(t, want, attempts[i].ExitCode)
+	}
+}
+
+// TestExecuteRetryBlockExhausted tests that @retry returns the last failure
+func TestExecuteRetryBlockExhausted(t *testing.T) {
+	plan := &planfmt.Plan{
+		Target: "retry",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: retryCmd(2, planfmt.Step{ID: 2, Tree: shellCmd("exit 4")})},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{Telemetry: TelemetryTiming}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.ExitCode)
+	assert.Len(t, result.Telemetry.StepTimings[0].Attempts, 2)
+}
+
+// TestExecuteStdinIntoBlock tests that input piped into a block decorator
+// streams into the block's pipelines, chains and redirects
+func TestExecuteStdinIntoBlock(t *testing.T) {
+	dir := t.TempDir()
+	timeoutBlock := func(block ...planfmt.Step) *planfmt.CommandNode {
+		return &planfmt.CommandNode{
+			Decorator: "@timeout",
+			Args:      []planfmt.Arg{{Key: "duration", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "5s"}}},
+			Block:     block,
+		}
+	}
+	marker := filepath.Join(dir, "attempted")
+	sorted := filepath.Join(dir, "sorted.txt")
+
+	tests := []struct {
+		name  string
+		block *planfmt.CommandNode
+		want  string
+	}{
+		{"pipeline in @retry", retryCmd(2, planfmt.Step{ID: 3, Tree: &planfmt.PipelineNode{
+			Commands: []planfmt.ExecutionNode{shellCmd("grep x"), shellCmd("sort")},
+		}}), "x0\nx1\n"},
+		{"@retry replays input", retryCmd(2, planfmt.Step{ID: 3, Tree: shellCmd(
+			"if [ -e " + marker + " ]; then cat; else touch " + marker + "; head -c 2 >/dev/null; exit 1; fi",
+		)}), "b\nx1\na\nx0\n"},
+		{"chain shares input", timeoutBlock(planfmt.Step{ID: 3, Tree: &planfmt.AndNode{
+			Left:  shellCmd("read line; echo first $line"),
+			Right: shellCmd("cat"),
+		}}), "first b\nx1\na\nx0\n"},
+		{"chain in @retry shares input", retryCmd(2, planfmt.Step{ID: 3, Tree: &planfmt.AndNode{
+			Left:  shellCmd("read line; echo first $line"),
+			Right: shellCmd("cat"),
+		}}), "first b\nx1\na\nx0\n"},
+		{"redirect in block", timeoutBlock(planfmt.Step{ID: 3, Tree: &planfmt.RedirectNode{
+			Source: shellCmd("sort"),
+			Target: *shellCmd(sorted),
+			Mode:   planfmt.RedirectOverwrite,
+		}}), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout strings.Builder
+			steps := planfmt.ToSDKSteps([]planfmt.Step{{ID: 1, Tree: &planfmt.PipelineNode{
+				Commands: []planfmt.ExecutionNode{shellCmd(`printf 'b\nx1\na\nx0\n'`), tt.block},
+			}}})
+			result, err := Execute(context.Background(), steps, Config{Stdout: &stdout}, testVault())
+			require.NoError(t, err)
+			assert.Equal(t, 0, result.ExitCode)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	data, err := os.ReadFile(sorted)
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\nx0\nx1\n", string(data))
+}
+
+// TestExecuteStdinIntoRetryStopsWriter tests that a block reading only part
+// of an endless input ends the pipeline (the writer gets SIGPIPE)
+func TestExecuteStdinIntoRetryStopsWriter(t *testing.T) {
+	var stdout strings.Builder
+	steps := planfmt.ToSDKSteps([]planfmt.Step{{ID: 1, Tree: &planfmt.PipelineNode{
+		Commands: []planfmt.ExecutionNode{shellCmd("yes"), retryCmd(2, planfmt.Step{ID: 3, Tree: shellCmd("head -n 1")})},
+	}}})
+
+	start := time.Now()
+	result, err := Execute(context.Background(), steps, Config{Stdout: &stdout}, testVault())
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "y\n", stdout.String())
+}
+
+// recordedSpan is a decorator.Span recording the span tree for tests.
+type recordedSpan struct {
+	name     string
+	attrs    map[string]any
+	ended    bool
+	children []*recordedSpan
+}
+
+func (s *recordedSpan) End()                          { s.ended = true }
+func (s *recordedSpan) SetAttr(key string, value any) { s.attrs[key] = value }
+func (s *recordedSpan) Child(name string, attrs map[string]any) decorator.Span {
+	child := &recordedSpan{name: name, attrs: map[string]any{}}
+	for k, v := range attrs {
+		child.attrs[k] = v
+	}
+	s.children = append(s.children, child)
+	return child
+}
+
+// tree formats the span tree as "name[exit_code](children...)".
+func (s *recordedSpan) tree() string {
+	var b strings.Builder
+	b.WriteString(s.name)
+	if code, ok := s.attrs["exit_code"]; ok {
+		fmt.Fprintf(&b, "[%v]", code)
+	}
+	if !s.ended {
+		b.WriteString("!open")
+	}
+	if len(s.children) > 0 {
+		parts := make([]string, len(s.children))
+		for i, child := range s.children {
+			parts[i] = child.tree()
+		}
+		b.WriteString("(" + strings.Join(parts, " ") + ")")
+	}
+	return b.String()
+}
+
+// TestExecuteTrace tests that Config.Trace gets a span per step with
+// decorator spans nested below, retry attempts holding their commands.
+// The root stays open: the caller ends it
+func TestExecuteTrace(t *testing.T) {
+	plan := &planfmt.Plan{
+		Target: "trace",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: shellCmd("true")},
+			{ID: 2, Tree: retryCmd(2, planfmt.Step{ID: 3, Tree: shellCmd("exit 4")})},
+		},
+	}
+
+	root := &recordedSpan{name: "run", attrs: map[string]any{}}
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{Trace: root}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.ExitCode)
+
+	assert.Equal(t, "run!open(step[0](decorator.shell[0]) "+
+		"step[4](decorator.retry[4](retry.attempt[4](decorator.shell[4]) retry.attempt[4](decorator.shell[4]))))",
+		root.tree())
+	assert.Equal(t, uint64(2), root.children[1].attrs["opal.step_id"])
+	assert.Equal(t, "@retry", root.children[1].children[0].attrs["opal.decorator"])
+}
+
+// TestExecuteTimeoutBlock tests that @timeout stops nested steps at its deadline
+func TestExecuteTimeoutBlock(t *testing.T) {
+	timeoutCmd := &planfmt.CommandNode{
+		Decorator: "@timeout",
+		Args: []planfmt.Arg{
+			{Key: "duration", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "200ms"}},
+		},
+		Block: []planfmt.Step{
+			{ID: 2, Tree: shellCmd("echo first")},
+			{ID: 3, Tree: shellCmd("sleep 10")},
+			{ID: 4, Tree: shellCmd("echo never")},
+		},
+	}
+	plan := &planfmt.Plan{
+		Target: "timeout",
+		Steps:  []planfmt.Step{{ID: 1, Tree: timeoutCmd}},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	start := time.Now()
+	result, err := Execute(context.Background(), steps, Config{Telemetry: TelemetryBasic}, testVault())
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), 2*time.Second, "nested sleep should be stopped at the deadline")
+	assert.Equal(t, decorator.ExitCanceled, result.ExitCode)
+	require.NotNil(t, result.Timeout)
+	assert.Equal(t, 200*time.Millisecond, result.Timeout.Duration)
+	require.NotNil(t, result.Telemetry.FailedStep)
+	assert.Equal(t, uint64(1), *result.Telemetry.FailedStep)
+}
+
+// TestExecuteRetryRecoversFromTimeout tests that a timeout inside a successful
+// @retry is not reported as the cause of failure
+func TestExecuteRetryRecoversFromTimeout(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "count")
+	script := "n=$(cat " + counter + " 2>/dev/null || echo 0); n=$((n+1)); echo $n > " + counter + "; [ $n -ge 2 ] || sleep 10"
+
+	timeoutCmd := &planfmt.CommandNode{
+		Decorator: "@timeout",
+		Args: []planfmt.Arg{
+			{Key: "duration", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "200ms"}},
+		},
+		Block: []planfmt.Step{{ID: 3, Tree: shellCmd(script)}},
+	}
+	plan := &planfmt.Plan{
+		Target: "retry-timeout",
+		Steps:  []planfmt.Step{{ID: 1, Tree: retryCmd(3, planfmt.Step{ID: 2, Tree: timeoutCmd})}},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Nil(t, result.Timeout)
+}
+
+// parallelCmd wraps block steps in @parallel with the given params
+func parallelCmd(args []planfmt.Arg, block ...planfmt.Step) *planfmt.CommandNode {
+	return &planfmt.CommandNode{Decorator: "@parallel", Args: args, Block: block}
+}
+
+// TestExecuteParallelBlock tests that @parallel runs its steps concurrently
+func TestExecuteParallelBlock(t *testing.T) {
+	plan := &planfmt.Plan{
+		Target: "parallel",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: parallelCmd(nil,
+				planfmt.Step{ID: 2, Tree: shellCmd("sleep 0.3")},
+				planfmt.Step{ID: 3, Tree: shellCmd("sleep 0.3")},
+				planfmt.Step{ID: 4, Tree: shellCmd("sleep 0.3")},
+			)},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	start := time.Now()
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Less(t, time.Since(start), 800*time.Millisecond, "steps should overlap")
+}
+
+// TestExecuteParallelFailFast tests that a failing branch cancels its siblings
+func TestExecuteParallelFailFast(t *testing.T) {
+	args := []planfmt.Arg{{Key: "failFast", Val: planfmt.Value{Kind: planfmt.ValueBool, Bool: true}}}
+	plan := &planfmt.Plan{
+		Target: "parallel",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: parallelCmd(args,
+				planfmt.Step{ID: 2, Tree: shellCmd("sleep 10")},
+				planfmt.Step{ID: 3, Tree: shellCmd("exit 3")},
+			)},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	start := time.Now()
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "sleeping branch should be canceled")
+	assert.Equal(t, 3, result.ExitCode)
+}
+
+// TestExecuteParallelPrefixedOutput tests that branch output is framed per line
+func TestExecuteParallelPrefixedOutput(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.txt")
+	args := []planfmt.Arg{{Key: "output", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "prefixed"}}}
+	plan := &planfmt.Plan{
+		Target: "parallel",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: &planfmt.RedirectNode{
+				Source: parallelCmd(args,
+					planfmt.Step{ID: 2, Tree: shellCmd("for i in 1 2 3; do printf 'al'; printf 'pha\\n'; done")},
+					planfmt.Step{ID: 3, Tree: shellCmd("for i in 1 2 3; do printf 'be'; printf 'ta\\n'; done")},
+				),
+				Target: *shellCmd(out),
+				Mode:   planfmt.RedirectOverwrite,
+			}},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	require.Equal(t, 0, result.ExitCode)
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 6)
+	for _, line := range lines {
+		assert.Contains(t, []string{"[1] alpha", "[2] beta"}, line)
+	}
+}
+
+// workdirCmd builds a @workdir block
+func workdirCmd(path string, block ...planfmt.Step) *planfmt.CommandNode {
+	return &planfmt.CommandNode{
+		Decorator: "@workdir",
+		Args: []planfmt.Arg{
+			{Key: "path", Val: planfmt.Value{Kind: planfmt.ValueString, Str: path}},
+		},
+		Block: block,
+	}
+}
+
+// TestExecuteWorkdirBlock tests that nested steps, including nested
+// decorators, run in the @workdir directory
+func TestExecuteWorkdirBlock(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "inner"), 0o755))
+
+	plan := &planfmt.Plan{
+		Target: "workdir",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: workdirCmd(filepath.Join(dir, "sub"),
+				planfmt.Step{ID: 2, Tree: shellCmd("pwd > " + filepath.Join(dir, "outer.txt"))},
+				planfmt.Step{ID: 3, Tree: workdirCmd("inner",
+					planfmt.Step{ID: 4, Tree: shellCmd("pwd > " + filepath.Join(dir, "inner.txt"))},
+				)},
+			)},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	require.Equal(t, 0, result.ExitCode)
+
+	outer, err := os.ReadFile(filepath.Join(dir, "outer.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "sub"), strings.TrimSpace(string(outer)))
+
+	inner, err := os.ReadFile(filepath.Join(dir, "inner.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "sub", "inner"), strings.TrimSpace(string(inner)))
+}
+
+// TestExecuteWorkdirMissing tests that a missing directory fails the block
+// without running it
+func TestExecuteWorkdirMissing(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	plan := &planfmt.Plan{
+		Target: "workdir",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: workdirCmd(filepath.Join(dir, "missing"),
+				planfmt.Step{ID: 2, Tree: shellCmd("touch " + marker)},
+			)},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, decorator.ExitFailure, result.ExitCode)
+	assert.NoFileExists(t, marker)
+}
+
+// sshCmd builds an @ssh.connect block against the test server.
+// Host keys are not checked; the session tests in core cover known_hosts.
+func sshCmd(t *testing.T, srv *decorator.SSHTestServer, block ...planfmt.Step) *planfmt.CommandNode {
+	t.Helper()
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(keyPath, srv.ClientKeyPEM, 0o600))
+	return &planfmt.CommandNode{
+		Decorator: "@ssh.connect",
+		Args: []planfmt.Arg{
+			{Key: "host", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "127.0.0.1"}},
+			{Key: "port", Val: planfmt.Value{Kind: planfmt.ValueInt, Int: int64(srv.Port)}},
+			{Key: "user", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "opal"}},
+			{Key: "key", Val: planfmt.Value{Kind: planfmt.ValueString, Str: keyPath}},
+			{Key: "strict_host_key", Val: planfmt.Value{Kind: planfmt.ValueBool, Bool: false}},
+			{Key: "env.GREETING", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "remote"}},
+		},
+		Block: block,
+	}
+}
+
+// TestExecuteSSHBlock tests that commands and redirects inside an
+// @ssh.connect block run through the remote session, with the block's env
+func TestExecuteSSHBlock(t *testing.T) {
+	srv := decorator.StartSSHTestServer(t)
+	dir := t.TempDir()
+
+	plan := &planfmt.Plan{
+		Target: "ssh",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: sshCmd(t, srv,
+				planfmt.Step{ID: 2, Tree: &planfmt.RedirectNode{
+					Source: shellCmd("echo $GREETING"),
+					Target: *shellCmd(filepath.Join(dir, "env.txt")),
+					Mode:   planfmt.RedirectOverwrite,
+				}},
+				planfmt.Step{ID: 3, Tree: workdirCmd(dir,
+					planfmt.Step{ID: 4, Tree: &planfmt.RedirectNode{
+						Source: shellCmd("echo more"),
+						Target: *shellCmd("env.txt"),
+						Mode:   planfmt.RedirectAppend,
+					}},
+				)},
+			)},
+		},
+	}
+
+	sessions := decorator.NewSessionPool()
+	defer sessions.CloseAll()
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{Sessions: sessions}, testVault())
+	require.NoError(t, err)
+	require.Equal(t, 0, result.ExitCode)
+
+	data, err := os.ReadFile(filepath.Join(dir, "env.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "remote\nmore\n", string(data))
+}
+
+// TestExecuteSSHConnectFailure tests that an unreachable host fails the
+// block without running it
+func TestExecuteSSHConnectFailure(t *testing.T) {
+	srv := decorator.StartSSHTestServer(t)
+	marker := filepath.Join(t.TempDir(), "ran")
+	tree := sshCmd(t, srv, planfmt.Step{ID: 2, Tree: shellCmd("touch " + marker)})
+	tree.Args[1].Val.Int = 1 // nothing listens on port 1
+
+	plan := &planfmt.Plan{
+		Target: "ssh",
+		Steps:  []planfmt.Step{{ID: 1, Tree: tree}},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, decorator.ExitFailure, result.ExitCode)
+	assert.NoFileExists(t, marker)
+}
+
+// TestExecuteDockerBlock tests that commands and redirects inside a
+// @docker.exec block run through docker in the container's working directory
+func TestExecuteDockerBlock(t *testing.T) {
+	dir := t.TempDir()
+	decorator.InstallFakeDocker(t, dir, "app")
+
+	plan := &planfmt.Plan{
+		Target: "docker",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: &planfmt.CommandNode{
+				Decorator: "@docker.exec",
+				Args: []planfmt.Arg{
+					{Key: "container", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "app"}},
+					{Key: "env.GREETING", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "container"}},
+				},
+				Block: []planfmt.Step{
+					{ID: 2, Tree: &planfmt.RedirectNode{
+						Source: shellCmd("echo $GREETING; pwd"),
+						Target: *shellCmd("out.txt"),
+						Mode:   planfmt.RedirectOverwrite,
+					}},
+				},
+			}},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	require.Equal(t, 0, result.ExitCode)
+
+	data, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "container\n"+dir+"\n", string(data))
+}
+
+// groupNode builds a "for" group as produced by loop unrolling
+func groupNode(label string, steps ...planfmt.Step) *planfmt.GroupNode {
+	return &planfmt.GroupNode{Kind: "for", Label: label, Steps: steps}
+}
+
+// TestExecuteGroupSteps tests that group steps run in order and stop at the first failure
+func TestExecuteGroupSteps(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.txt")
+	plan := &planfmt.Plan{
+		Target: "loop",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: groupNode("for i in 1...2 [i=0]",
+				planfmt.Step{ID: 2, Tree: shellCmd("echo one >> " + out)},
+				planfmt.Step{ID: 3, Tree: shellCmd("echo two >> " + out)},
+			)},
+			{ID: 4, Tree: groupNode("for i in 1...2 [i=1]",
+				planfmt.Step{ID: 5, Tree: shellCmd("exit 4")},
+				planfmt.Step{ID: 6, Tree: shellCmd("echo unreachable >> " + out)},
+			)},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.ExitCode)
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", string(data))
+}
+
+// TestExecuteParallelOverGroups tests that each group is one @parallel branch
+func TestExecuteParallelOverGroups(t *testing.T) {
+	plan := &planfmt.Plan{
+		Target: "parallel",
+		Steps: []planfmt.Step{
+			{ID: 1, Tree: parallelCmd(nil,
+				planfmt.Step{ID: 2, Tree: groupNode("for i in 1...2 [i=0]",
+					planfmt.Step{ID: 3, Tree: shellCmd("sleep 0.3")},
+				)},
+				planfmt.Step{ID: 4, Tree: groupNode("for i in 1...2 [i=1]",
+					planfmt.Step{ID: 5, Tree: shellCmd("sleep 0.3")},
+				)},
+			)},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	start := time.Now()
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Less(t, time.Since(start), 550*time.Millisecond, "groups should overlap")
+}
+
+// TestExecuteTry tests that catch runs only on failure, finally always runs,
+// and the catch sees the failing exit code
+func TestExecuteTry(t *testing.T) {
+	tests := []struct {
+		name     string
+		try      string
+		catch    string // Empty: no catch block
+		finally  string // Empty: no finally block
+		wantExit int
+		wantOut  string
+	}{
+		{
+			name:     "success skips catch",
+			try:      "echo try",
+			catch:    "echo catch",
+			finally:  "echo finally",
+			wantExit: 0,
+			wantOut:  "try\nfinally\n",
+		},
+		{
+			name:     "failure runs catch with exit code",
+			try:      "exit 3",
+			catch:    "echo caught $" + ExitCodeEnv,
+			finally:  "echo finally",
+			wantExit: 0,
+			wantOut:  "caught 3\nfinally\n",
+		},
+		{
+			name:     "failing catch fails the step",
+			try:      "exit 3",
+			catch:    "exit 5",
+			finally:  "echo finally",
+			wantExit: 5,
+			wantOut:  "finally\n",
+		},
+		{
+			name:     "no catch propagates failure after finally",
+			try:      "exit 3",
+			finally:  "echo finally",
+			wantExit: 3,
+			wantOut:  "finally\n",
+		},
+		{
+			name:     "failing finally overrides success",
+			try:      "echo try",
+			finally:  "exit 7",
+			wantExit: 7,
+			wantOut:  "try\n",
+		},
+		{
+			name:     "failing finally keeps original failure",
+			try:      "exit 3",
+			finally:  "exit 7",
+			wantExit: 3,
+			wantOut:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out.txt")
+			require.NoError(t, os.WriteFile(out, nil, 0o644))
+			block := func(id uint64, cmd string) []planfmt.Step {
+				return []planfmt.Step{{ID: id, Tree: shellCmd("(" + cmd + ") >> " + out)}}
+			}
+
+			node := &planfmt.TryNode{Try: block(2, tt.try)}
+			if tt.catch != "" {
+				node.Catch, node.HasCatch = block(3, tt.catch), true
+			}
+			if tt.finally != "" {
+				node.Finally, node.HasFinally = block(4, tt.finally), true
+			}
+
+			steps := planfmt.ToSDKSteps([]planfmt.Step{{ID: 1, Tree: node}})
+			result, err := Execute(context.Background(), steps, Config{}, testVault())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantExit, result.ExitCode)
+
+			data, err := os.ReadFile(out)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOut, string(data))
+		})
+	}
+}
+
+// TestExecuteTryEmptyCatchSwallowsFailure tests that an empty catch block
+// still handles the failure
+func TestExecuteTryEmptyCatchSwallowsFailure(t *testing.T) {
+	steps := planfmt.ToSDKSteps([]planfmt.Step{{ID: 1, Tree: &planfmt.TryNode{
+		Try:      []planfmt.Step{{ID: 2, Tree: shellCmd("exit 3")}},
+		HasCatch: true,
+	}}})
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+}
+
+// TestExecuteTryCatchesTimeout tests that a @timeout in the try block is
+// handled by catch like any failure, with the timeout exit code
+func TestExecuteTryCatchesTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		catch    string
+		wantExit int
+		wantOut  string
+	}{
+		{
+			name:     "catch handles timeout",
+			catch:    "echo caught $" + ExitCodeEnv,
+			wantExit: 0,
+			wantOut:  "caught 124\nfinally\n",
+		},
+		{
+			name:     "failing catch is not a timeout",
+			catch:    "exit 5",
+			wantExit: 5,
+			wantOut:  "finally\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out.txt")
+			require.NoError(t, os.WriteFile(out, nil, 0o644))
+
+			timeoutCmd := &planfmt.CommandNode{
+				Decorator: "@timeout",
+				Args: []planfmt.Arg{
+					{Key: "duration", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "200ms"}},
+					{Key: "grace", Val: planfmt.Value{Kind: planfmt.ValueString, Str: "0s"}},
+				},
+				Block: []planfmt.Step{{ID: 3, Tree: shellCmd("sleep 5")}},
+			}
+			steps := planfmt.ToSDKSteps([]planfmt.Step{{ID: 1, Tree: &planfmt.TryNode{
+				Try:        []planfmt.Step{{ID: 2, Tree: timeoutCmd}},
+				Catch:      []planfmt.Step{{ID: 4, Tree: shellCmd("(" + tt.catch + ") >> " + out)}},
+				Finally:    []planfmt.Step{{ID: 5, Tree: shellCmd("echo finally >> " + out)}},
+				HasCatch:   true,
+				HasFinally: true,
+			}}})
+
+			start := time.Now()
+			result, err := Execute(context.Background(), steps, Config{}, testVault())
+			require.NoError(t, err)
+			assert.Less(t, time.Since(start), 3*time.Second, "timeout should stop the try block")
+			assert.Equal(t, tt.wantExit, result.ExitCode)
+			assert.Nil(t, result.Timeout, "a caught timeout does not fail the run as a timeout")
+
+			data, err := os.ReadFile(out)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOut, string(data))
+		})
+	}
+}
+
 // TestExecuteDebugPaths tests path-level debug tracing
 func TestExecuteDebugPaths(t *testing.T) {
 	plan := &planfmt.Plan{
@@ -610,6 +1422,100 @@ func TestExecuteRedirectWithSequence(t *testing.T) {
 	assert.Equal(t, "first\nsecond\nthird\n", string(content))
 }
 
+// TestExecuteRedirectIn tests input redirection with < operator
+func TestExecuteRedirectIn(t *testing.T) {
+	dir := t.TempDir()
+	inFile := dir + "/input.txt"
+	require.NoError(t, os.WriteFile(inFile, []byte("b\na\nc\n"), 0o644))
+
+	readInput := func(cmd string) *planfmt.RedirectInNode {
+		return &planfmt.RedirectInNode{Command: shellCmd(cmd), Input: *shellCmd(inFile)}
+	}
+
+	tests := []struct {
+		name   string
+		source planfmt.ExecutionNode
+		want   string
+	}{
+		{
+			name:   "read from file",
+			source: readInput("sort"),
+			want:   "a\nb\nc\n",
+		},
+		{
+			name: "read at the start of a pipeline",
+			source: &planfmt.PipelineNode{Commands: []planfmt.ExecutionNode{
+				readInput("cat"),
+				shellCmd("sort -r"),
+			}},
+			want: "c\nb\na\n",
+		},
+		{
+			name: "file replaces piped stdin",
+			source: &planfmt.PipelineNode{Commands: []planfmt.ExecutionNode{
+				shellCmd("echo ignored"),
+				readInput("sort"),
+			}},
+			want: "a\nb\nc\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outFile := t.TempDir() + "/output.txt"
+			plan := &planfmt.Plan{
+				Target: "redirect-in",
+				Steps: []planfmt.Step{
+					{
+						ID: 1,
+						Tree: &planfmt.RedirectNode{
+							Source: tt.source,
+							Target: *shellCmd(outFile),
+							Mode:   planfmt.RedirectOverwrite,
+						},
+					},
+				},
+			}
+
+			steps := planfmt.ToSDKSteps(plan.Steps)
+			result, err := Execute(context.Background(), steps, Config{}, testVault())
+			require.NoError(t, err)
+			assert.Equal(t, 0, result.ExitCode)
+
+			content, err := os.ReadFile(outFile)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(content))
+		})
+	}
+}
+
+// TestExecuteRedirectInMissingFile tests that an unreadable input fails the
+// command without running it
+func TestExecuteRedirectInMissingFile(t *testing.T) {
+	marker := t.TempDir() + "/ran"
+
+	plan := &planfmt.Plan{
+		Target: "redirect-in-missing",
+		Steps: []planfmt.Step{
+			{
+				ID: 1,
+				Tree: &planfmt.RedirectInNode{
+					Command: shellCmd("touch " + marker),
+					Input:   *shellCmd(t.TempDir() + "/missing.txt"),
+				},
+			},
+		},
+	}
+
+	steps := planfmt.ToSDKSteps(plan.Steps)
+	result, err := Execute(context.Background(), steps, Config{}, testVault())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ExitCode)
+
+	_, err = os.Stat(marker)
+	assert.True(t, os.IsNotExist(err), "command should not run")
+}
+
 // TestOperatorSemicolon tests semicolon operator (always execute next)
 func TestOperatorSemicolon(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,99 @@
+package executor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/opal-lang/opal/core/decorator"
+)
+
+// span is the executor's in-memory decorator.Span implementation.
+// One root span is created per top-level step when TelemetryTiming is enabled
+// or Config.Trace is set; decorators hang child spans off it (e.g.,
+// "retry.attempt").
+type span struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+	export   decorator.Span // Mirror under Config.Trace (nil if none)
+
+	mu       sync.Mutex // Children may be created from concurrent branches
+	attrs    map[string]any
+	children []*span
+}
+
+// newSpan starts a span with a copy of attrs.
+func newSpan(name string, attrs map[string]any) *span {
+	copied := make(map[string]any, len(attrs))
+	for k, v := range attrs {
+		copied[k] = v
+	}
+	return &span{name: name, start: time.Now(), attrs: copied}
+}
+
+// newStepSpan starts a top-level step's span, mirrored as a child of parent
+// (Config.Trace) when set.
+func newStepSpan(parent decorator.Span, stepID uint64) *span {
+	attrs := map[string]any{"opal.step_id": stepID}
+	s := newSpan("step", attrs)
+	if parent != nil {
+		s.export = parent.Child("step", attrs)
+	}
+	return s
+}
+
+// End records the span duration.
+func (s *span) End() {
+	s.mu.Lock()
+	s.duration = time.Since(s.start)
+	s.mu.Unlock()
+	if s.export != nil {
+		s.export.End()
+	}
+}
+
+// SetAttr records an attribute on the span.
+func (s *span) SetAttr(key string, value any) {
+	s.mu.Lock()
+	s.attrs[key] = value
+	s.mu.Unlock()
+	if s.export != nil {
+		s.export.SetAttr(key, value)
+	}
+}
+
+// Child starts a child span.
+func (s *span) Child(name string, attrs map[string]any) decorator.Span {
+	child := newSpan(name, attrs)
+	if s.export != nil {
+		child.export = s.export.Child(name, attrs)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.children = append(s.children, child)
+	return child
+}
+
+// attemptTimings flattens all "retry.attempt" spans below s in start order.
+func (s *span) attemptTimings() []AttemptTiming {
+	s.mu.Lock()
+	children := append([]*span(nil), s.children...)
+	s.mu.Unlock()
+
+	var attempts []AttemptTiming
+	for _, child := range children {
+		child.mu.Lock()
+		if child.name == "retry.attempt" {
+			attempt, _ := child.attrs["attempt"].(int)
+			exitCode, _ := child.attrs["exit_code"].(int)
+			attempts = append(attempts, AttemptTiming{
+				Attempt:  attempt,
+				Duration: child.duration,
+				ExitCode: exitCode,
+			})
+		}
+		child.mu.Unlock()
+		attempts = append(attempts, child.attemptTimings()...)
+	}
+	return attempts
+}
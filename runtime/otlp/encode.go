@@ -0,0 +1,129 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// OTLP/JSON encoding of ExportTraceServiceRequest
+// (opentelemetry-proto, JSON Protobuf Encoding): IDs are hex, 64-bit
+// integers are decimal strings, enums are numbers.
+
+const (
+	spanKindInternal = 1
+	statusCodeOK     = 1
+	statusCodeError  = 2
+)
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanJSON `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanJSON struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type status struct {
+	Code int `json:"code,omitempty"` // Unset unless the span has an exit code
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// encodeRequest encodes spans as one ExportTraceServiceRequest.
+func encodeRequest(serviceName string, spans []SpanData) ([]byte, error) {
+	encoded := make([]spanJSON, len(spans))
+	for i, s := range spans {
+		encoded[i] = spanJSON{
+			TraceID:           hex.EncodeToString(s.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.SpanID[:]),
+			Name:              s.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Status:            spanStatus(s.Attrs),
+		}
+		if s.ParentID != ([8]byte{}) {
+			encoded[i].ParentSpanID = hex.EncodeToString(s.ParentID[:])
+		}
+		for _, attr := range s.Attrs {
+			encoded[i].Attributes = append(encoded[i].Attributes, keyValue{Key: attr.Key, Value: encodeValue(attr.Value)})
+		}
+	}
+
+	return json.Marshal(exportRequest{ResourceSpans: []resourceSpans{{
+		Resource: resource{Attributes: []keyValue{
+			{Key: "service.name", Value: encodeValue(serviceName)},
+		}},
+		ScopeSpans: []scopeSpans{{
+			Scope: scope{Name: "github.com/opal-lang/opal"},
+			Spans: encoded,
+		}},
+	}}})
+}
+
+// spanStatus derives a span's status from its exit_code attribute.
+func spanStatus(attrs []Attr) status {
+	for _, attr := range attrs {
+		if attr.Key != "exit_code" {
+			continue
+		}
+		if code, ok := attr.Value.(int64); ok && code != 0 {
+			return status{Code: statusCodeError}
+		}
+		return status{Code: statusCodeOK}
+	}
+	return status{}
+}
+
+func encodeValue(value any) anyValue {
+	switch v := value.(type) {
+	case bool:
+		return anyValue{BoolValue: &v}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return anyValue{IntValue: &s}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case string:
+		return anyValue{StringValue: &v}
+	}
+	s := "" // attrList only produces the types above
+	return anyValue{StringValue: &s}
+}
@@ -0,0 +1,118 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// tracesPath is the OTLP/HTTP traces endpoint path.
+const tracesPath = "/v1/traces"
+
+// HTTPExporter posts requests to an OTLP/HTTP collector (JSON encoding).
+type HTTPExporter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewHTTPExporter creates an exporter for a collector endpoint: a base URL
+// (e.g., http://localhost:4318) gets /v1/traces appended, a URL already
+// ending in /v1/traces is used as is. headers are sent with each request
+// (e.g., authentication).
+func NewHTTPExporter(endpoint string, headers map[string]string) (*HTTPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: want an http:// or https:// URL", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, tracesPath) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + tracesPath
+	}
+	return &HTTPExporter{
+		url:     u.String(),
+		headers: headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Export posts the request.
+func (e *HTTPExporter) Export(ctx context.Context, request []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OTLP export: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("OTLP export to %s: %s: %s", e.url, resp.Status, bytes.TrimSpace(body))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body) // Let the connection be reused
+	return nil
+}
+
+// FileExporter appends requests to a file, one JSON object per line (the
+// OpenTelemetry Collector's file exporter format, read back by its
+// otlpjsonfile receiver).
+type FileExporter struct {
+	path string
+}
+
+// NewFileExporter creates an exporter appending to path.
+func NewFileExporter(path string) *FileExporter {
+	return &FileExporter{path: path}
+}
+
+// Export appends the request as one line.
+func (e *FileExporter) Export(_ context.Context, request []byte) error {
+	f, err := os.OpenFile(e.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	line := append(append(make([]byte, 0, len(request)+1), request...), '\n')
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ParseHeaders parses headers in the OTEL_EXPORTER_OTLP_HEADERS format:
+// comma-separated key=value pairs with URL-encoded values.
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for i, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			// Position only: a malformed pair may be a credential
+			return nil, fmt.Errorf("invalid OTLP header #%d: want key=value", i+1)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP header %q: %w", key, err)
+		}
+		headers[key] = decoded
+	}
+	return headers, nil
+}
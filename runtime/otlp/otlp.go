@@ -0,0 +1,215 @@
+// Package otlp exports opal's telemetry spans as OpenTelemetry traces.
+//
+// A Tracer collects the spans of one run: a root span, the phases (lex,
+// parse, plan), each step, and the decorator spans nested below (e.g.,
+// "retry.attempt"). Flush encodes them as an OTLP/JSON
+// ExportTraceServiceRequest and hands it to the exporters: OTLP/HTTP
+// (HTTPExporter) or a JSON lines file (FileExporter).
+//
+// Span attributes are values the runtime and decorators choose to record
+// (IDs, exit codes, attempt numbers), never command text. As a second line
+// of defense, string attributes pass through Config.Redact (secret
+// scrubbing) before they leave the span.
+package otlp
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opal-lang/opal/core/decorator"
+)
+
+// Exporter sends one encoded ExportTraceServiceRequest (OTLP/JSON).
+type Exporter interface {
+	Export(ctx context.Context, request []byte) error
+}
+
+// Config configures a Tracer.
+type Config struct {
+	ServiceName string     // Resource service.name (default "opal")
+	Exporters   []Exporter // Where Flush sends spans
+
+	// Redact, if set, rewrites string attribute values before export
+	// (e.g., replacing secrets with DisplayIDs).
+	Redact func(string) string
+}
+
+// Tracer collects the spans of one trace until they are flushed.
+type Tracer struct {
+	config  Config
+	traceID [16]byte
+
+	mu    sync.Mutex
+	ended []SpanData
+}
+
+// NewTracer creates a tracer with a random trace ID.
+func NewTracer(config Config) *Tracer {
+	if config.ServiceName == "" {
+		config.ServiceName = "opal"
+	}
+	t := &Tracer{config: config}
+	_, _ = rand.Read(t.traceID[:]) // Never fails: crypto/rand crashes instead
+	return t
+}
+
+// TraceID returns the trace ID in hex, as exported.
+func (t *Tracer) TraceID() string {
+	return fmt.Sprintf("%x", t.traceID)
+}
+
+// Start starts a root span.
+func (t *Tracer) Start(name string, attrs map[string]any) *Span {
+	return t.newSpan([8]byte{}, name, attrs)
+}
+
+// Flush exports the spans ended since the last flush. Spans still open are
+// left for a later flush.
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	spans := t.ended
+	t.ended = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	request, err := encodeRequest(t.config.ServiceName, spans)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, exporter := range t.config.Exporters {
+		if err := exporter.Export(ctx, request); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Tracer) newSpan(parent [8]byte, name string, attrs map[string]any) *Span {
+	s := &Span{
+		tracer: t,
+		parent: parent,
+		name:   name,
+		start:  time.Now(),
+		attrs:  make(map[string]any, len(attrs)),
+	}
+	_, _ = rand.Read(s.id[:])
+	for k, v := range attrs {
+		s.attrs[k] = v
+	}
+	return s
+}
+
+// Span is an exported decorator.Span. It is safe for concurrent use
+// (@parallel branches create children concurrently).
+type Span struct {
+	tracer *Tracer
+	id     [8]byte
+	parent [8]byte // Zero for a root span
+	name   string
+	start  time.Time
+
+	mu    sync.Mutex
+	attrs map[string]any
+	ended bool
+}
+
+// End ends the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	end := time.Now()
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	data := SpanData{
+		TraceID:  s.tracer.traceID,
+		SpanID:   s.id,
+		ParentID: s.parent,
+		Name:     s.name,
+		Start:    s.start,
+		End:      end,
+		Attrs:    s.tracer.attrList(s.attrs),
+	}
+	s.mu.Unlock()
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.ended = append(s.tracer.ended, data)
+}
+
+// SetAttr records an attribute. Attributes set after End are dropped.
+func (s *Span) SetAttr(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.attrs[key] = value
+	}
+}
+
+// Child starts a child span.
+func (s *Span) Child(name string, attrs map[string]any) decorator.Span {
+	return s.tracer.newSpan(s.id, name, attrs)
+}
+
+// SpanData is an ended span as exported.
+type SpanData struct {
+	TraceID  [16]byte
+	SpanID   [8]byte
+	ParentID [8]byte // Zero for a root span
+	Name     string
+	Start    time.Time
+	End      time.Time
+	Attrs    []Attr // Sorted by key
+}
+
+// Attr is an exported attribute. Value is a string, bool, int64 or float64.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// attrList converts attributes to their exported types, sorted by key.
+// Strings (and values of other types, formatted) are redacted.
+func (t *Tracer) attrList(attrs map[string]any) []Attr {
+	list := make([]Attr, 0, len(attrs))
+	for key, value := range attrs {
+		switch v := value.(type) {
+		case bool, int64, float64:
+		case int:
+			value = int64(v)
+		case int32:
+			value = int64(v)
+		case uint32:
+			value = int64(v)
+		case uint64:
+			value = int64(v) // IDs and counts; OTLP has no unsigned type
+		case float32:
+			value = float64(v)
+		case time.Duration:
+			value = v.String()
+		case string:
+			value = t.redact(v)
+		default:
+			value = t.redact(fmt.Sprint(v))
+		}
+		list = append(list, Attr{Key: key, Value: value})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+func (t *Tracer) redact(s string) string {
+	if t.config.Redact == nil {
+		return s
+	}
+	return t.config.Redact(s)
+}
@@ -0,0 +1,283 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memExporter keeps exported requests.
+type memExporter struct {
+	requests [][]byte
+}
+
+func (e *memExporter) Export(_ context.Context, request []byte) error {
+	e.requests = append(e.requests, request)
+	return nil
+}
+
+// decodedSpan is a span read back from an OTLP/JSON request.
+type decodedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Start        string `json:"startTimeUnixNano"`
+	End          string `json:"endTimeUnixNano"`
+	Attributes   []struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	} `json:"attributes"`
+	Status struct {
+		Code int `json:"code"`
+	} `json:"status"`
+}
+
+func (s decodedSpan) attr(key string) any {
+	for _, attr := range s.Attributes {
+		if attr.Key == key {
+			for _, v := range attr.Value {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// decodeSpans decodes the spans of an ExportTraceServiceRequest.
+func decodeSpans(t *testing.T, request []byte) (string, []decodedSpan) {
+	t.Helper()
+	var decoded struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string         `json:"key"`
+					Value map[string]any `json:"value"`
+				} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []decodedSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(request, &decoded); err != nil {
+		t.Fatalf("invalid request %s: %v", request, err)
+	}
+	if len(decoded.ResourceSpans) != 1 || len(decoded.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected request shape: %s", request)
+	}
+	rs := decoded.ResourceSpans[0]
+	service, _ := rs.Resource.Attributes[0].Value["stringValue"].(string)
+	return service, rs.ScopeSpans[0].Spans
+}
+
+func TestTracer_SpanTree(t *testing.T) {
+	exporter := &memExporter{}
+	tracer := NewTracer(Config{Exporters: []Exporter{exporter}})
+
+	root := tracer.Start("run", map[string]any{"opal.target": "deploy"})
+	step := root.Child("step", map[string]any{"opal.step_id": uint64(1)})
+	attempt := step.Child("retry.attempt", map[string]any{"attempt": 1})
+	attempt.SetAttr("exit_code", 3)
+	attempt.End()
+	step.SetAttr("exit_code", 0)
+	step.End()
+	root.Child("plan", nil) // Never ended: not exported
+	root.End()
+	root.SetAttr("late", true) // After End: dropped
+
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(exporter.requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(exporter.requests))
+	}
+	service, spans := decodeSpans(t, exporter.requests[0])
+	if service != "opal" {
+		t.Errorf("service.name = %q, want opal", service)
+	}
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 ended spans, got %d", len(spans))
+	}
+
+	byName := map[string]decodedSpan{}
+	for _, s := range spans {
+		byName[s.Name] = s
+		if s.TraceID != tracer.TraceID() || len(s.TraceID) != 32 || len(s.SpanID) != 16 {
+			t.Errorf("span %s: trace %q span %q", s.Name, s.TraceID, s.SpanID)
+		}
+		if s.Kind != spanKindInternal || s.Start == "" || s.End < s.Start {
+			t.Errorf("span %s: kind %d start %s end %s", s.Name, s.Kind, s.Start, s.End)
+		}
+	}
+	run, stepSpan, attemptSpan := byName["run"], byName["step"], byName["retry.attempt"]
+	if run.ParentSpanID != "" || stepSpan.ParentSpanID != run.SpanID || attemptSpan.ParentSpanID != stepSpan.SpanID {
+		t.Errorf("unexpected parents: run=%q step=%q attempt=%q", run.ParentSpanID, stepSpan.ParentSpanID, attemptSpan.ParentSpanID)
+	}
+
+	if got := run.attr("opal.target"); got != "deploy" {
+		t.Errorf("opal.target = %v", got)
+	}
+	if got := run.attr("late"); got != nil {
+		t.Errorf("attribute set after End was exported: %v", got)
+	}
+	if got := stepSpan.attr("opal.step_id"); got != "1" {
+		t.Errorf("opal.step_id = %v, want intValue \"1\"", got)
+	}
+	if run.Status.Code != 0 || stepSpan.Status.Code != statusCodeOK || attemptSpan.Status.Code != statusCodeError {
+		t.Errorf("statuses run=%d step=%d attempt=%d", run.Status.Code, stepSpan.Status.Code, attemptSpan.Status.Code)
+	}
+
+	// Spans are exported once
+	if err := tracer.Flush(context.Background()); err != nil || len(exporter.requests) != 1 {
+		t.Errorf("second flush: %v, %d requests", err, len(exporter.requests))
+	}
+}
+
+func TestTracer_RedactsStrings(t *testing.T) {
+	exporter := &memExporter{}
+	tracer := NewTracer(Config{
+		Exporters: []Exporter{exporter},
+		Redact:    func(s string) string { return strings.ReplaceAll(s, "hunter2", "opal:ID") },
+	})
+	span := tracer.Start("run", map[string]any{
+		"opal.source": "deploy-hunter2.opl",
+		"error":       struct{ pw string }{"hunter2"},
+		"count":       3,
+	})
+	span.End()
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(exporter.requests[0]), "hunter2") {
+		t.Fatalf("request contains the secret: %s", exporter.requests[0])
+	}
+	_, spans := decodeSpans(t, exporter.requests[0])
+	if got := spans[0].attr("opal.source"); got != "deploy-opal:ID.opl" {
+		t.Errorf("opal.source = %v", got)
+	}
+	if got := spans[0].attr("count"); got != "3" {
+		t.Errorf("count = %v", got)
+	}
+}
+
+func TestFileExporter_AppendsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	for _, name := range []string{"first", "second"} {
+		tracer := NewTracer(Config{Exporters: []Exporter{NewFileExporter(path)}})
+		tracer.Start(name, nil).End()
+		if err := tracer.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", data)
+	}
+	for i, want := range []string{"first", "second"} {
+		if _, spans := decodeSpans(t, []byte(lines[i])); spans[0].Name != want {
+			t.Errorf("line %d: span %q, want %q", i+1, spans[0].Name, want)
+		}
+	}
+}
+
+func TestHTTPExporter(t *testing.T) {
+	var gotPath, gotType, gotAuth string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotType, gotAuth = r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exporter, err := NewHTTPExporter(server.URL, map[string]string{"Authorization": "Bearer t0k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := NewTracer(Config{Exporters: []Exporter{exporter}})
+	tracer.Start("run", nil).End()
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotPath != "/v1/traces" || gotType != "application/json" || gotAuth != "Bearer t0k" {
+		t.Errorf("path=%q content-type=%q auth=%q", gotPath, gotType, gotAuth)
+	}
+	if _, spans := decodeSpans(t, gotBody); len(spans) != 1 || spans[0].Name != "run" {
+		t.Errorf("unexpected spans: %s", gotBody)
+	}
+}
+
+func TestHTTPExporter_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad batch", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	exporter, err := NewHTTPExporter(server.URL+"/v1/traces", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exporter.Export(context.Background(), []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "400 Bad Request: bad batch") {
+		t.Errorf("Expected the collector's error, got %v", err)
+	}
+}
+
+func TestNewHTTPExporter_Endpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string // URL, or error substring
+	}{
+		{"http://localhost:4318", "http://localhost:4318/v1/traces"},
+		{"https://otel.example.com/", "https://otel.example.com/v1/traces"},
+		{"https://otel.example.com/otlp", "https://otel.example.com/otlp/v1/traces"},
+		{"http://localhost:4318/v1/traces", "http://localhost:4318/v1/traces"},
+		{"localhost:4318", "want an http:// or https:// URL"},
+		{"grpc://localhost:4317", "want an http:// or https:// URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			exporter, err := NewHTTPExporter(tt.endpoint, nil)
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("error %v, want %q", err, tt.want)
+				}
+				return
+			}
+			if exporter.url != tt.want {
+				t.Errorf("url = %q, want %q", exporter.url, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders("Authorization=Bearer%20t0k, x-tenant = ops ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 2 || headers["Authorization"] != "Bearer t0k" || headers["x-tenant"] != "ops" {
+		t.Errorf("headers = %v", headers)
+	}
+
+	_, err = ParseHeaders("x-tenant=ops,Bearer s3cr3t")
+	if err == nil || !strings.Contains(err.Error(), "#2") || strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("Expected an error naming the position only, got %v", err)
+	}
+}
@@ -64,6 +64,108 @@ func TestIfStatement(t *testing.T) {
 	}
 }
 
+func TestIfConditionComparison(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		events []Event
+	}{
+		{
+			name:  "decorator equals string",
+			input: `if @var.ENV == "prod" { echo "yes" }`,
+			events: []Event{
+				{EventOpen, 0},                      // Source
+				{EventOpen, uint32(NodeIf)},         // If
+				{EventToken, 0},                     // if
+				{EventOpen, uint32(NodeDecorator)},  // Decorator
+				{EventToken, 1},                     // @
+				{EventToken, 2},                     // var
+				{EventToken, 3},                     // .
+				{EventToken, 4},                     // ENV
+				{EventClose, uint32(NodeDecorator)}, // Decorator
+				{EventOpen, uint32(NodeBinaryExpr)}, // BinaryExpr
+				{EventToken, 5},                     // ==
+				{EventToken, 6},                     // "prod"
+				{EventClose, uint32(NodeBinaryExpr)},
+				{EventOpen, 3},      // Block
+				{EventToken, 7},     // {
+				{EventStepEnter, 0}, // Step boundary
+				{EventOpen, 8},      // ShellCommand
+				{EventOpen, 9},      // ShellArg
+				{EventToken, 8},     // echo
+				{EventClose, 9},     // ShellArg
+				{EventOpen, 9},      // ShellArg
+				{EventToken, 9},     // "yes"
+				{EventClose, 9},     // ShellArg
+				{EventClose, 8},     // ShellCommand
+				{EventStepExit, 0},  // Step boundary
+				{EventToken, 10},    // }
+				{EventClose, 3},     // Block
+				{EventClose, uint32(NodeIf)},
+				{EventClose, 0}, // Source
+			},
+		},
+		{
+			name:  "negated decorator",
+			input: `if !@env.CI { echo "local" }`,
+			events: []Event{
+				{EventOpen, 0},                     // Source
+				{EventOpen, uint32(NodeIf)},        // If
+				{EventToken, 0},                    // if
+				{EventOpen, uint32(NodeUnaryExpr)}, // UnaryExpr
+				{EventToken, 1},                    // !
+				{EventOpen, uint32(NodeDecorator)}, // Decorator
+				{EventToken, 2},                    // @
+				{EventToken, 3},                    // env
+				{EventToken, 4},                    // .
+				{EventToken, 5},                    // CI
+				{EventClose, uint32(NodeDecorator)},
+				{EventClose, uint32(NodeUnaryExpr)},
+				{EventOpen, 3},      // Block
+				{EventToken, 6},     // {
+				{EventStepEnter, 0}, // Step boundary
+				{EventOpen, 8},      // ShellCommand
+				{EventOpen, 9},      // ShellArg
+				{EventToken, 7},     // echo
+				{EventClose, 9},     // ShellArg
+				{EventOpen, 9},      // ShellArg
+				{EventToken, 8},     // "local"
+				{EventClose, 9},     // ShellArg
+				{EventClose, 8},     // ShellCommand
+				{EventStepExit, 0},  // Step boundary
+				{EventToken, 9},     // }
+				{EventClose, 3},     // Block
+				{EventClose, uint32(NodeIf)},
+				{EventClose, 0}, // Source
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := ParseString(tt.input)
+
+			if len(tree.Errors) != 0 {
+				t.Errorf("Expected no errors, got: %v", tree.Errors)
+			}
+
+			if diff := cmp.Diff(tt.events, tree.Events); diff != "" {
+				t.Errorf("Events mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIfConditionMissingRightHandSide(t *testing.T) {
+	tree := ParseString(`if @var.ENV == { echo "yes" }`)
+	if len(tree.Errors) == 0 {
+		t.Fatal("Expected error for comparison without right-hand side")
+	}
+	if tree.Errors[0].Message != "missing right-hand side of comparison" {
+		t.Errorf("Unexpected error: %s", tree.Errors[0].Message)
+	}
+}
+
 func TestIfElseStatement(t *testing.T) {
 	tests := []struct {
 		name   string
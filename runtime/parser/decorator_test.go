@@ -1205,7 +1205,7 @@ func TestPositionalParameters(t *testing.T) {
 		// === Edge Cases ===
 		{
 			name:        "too many positional arguments",
-			input:       `@retry(3, 2s, "linear", "extra") { echo "test" }`,
+			input:       `@retry(3, 2s, "linear", 30s, 100ms, "1", "2", "extra") { echo "test" }`,
 			wantError:   true,
 			wantMessage: "too many positional arguments",
 		},
@@ -1493,3 +1493,46 @@ func TestMultiSegmentDecoratorPaths(t *testing.T) {
 		})
 	}
 }
+
+// TestDecoratorForwardedParameters tests that @cmd passes parameters through
+// to the called function instead of checking them against its own schema.
+func TestDecoratorForwardedParameters(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "named", input: `@cmd.deploy(env="prod", replicas=3)`},
+		{name: "positional", input: `@cmd.deploy("prod", 3)`},
+		{name: "same name as primary parameter", input: `@cmd.greet(name="world")`},
+		{name: "value decorator", input: `@cmd.test_module(module=@var.module)`},
+		{name: "array", input: `@cmd.build(targets=["linux", "darwin"])`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := Parse([]byte(tt.input))
+			for _, err := range tree.Errors {
+				t.Errorf("unexpected parse error: %s", err.Message)
+			}
+		})
+	}
+}
+
+// TestDecoratorValueDecoratorParameter tests that a parameter value can be a
+// value decorator, parsed as a nested Decorator node.
+func TestDecoratorValueDecoratorParameter(t *testing.T) {
+	tree := Parse([]byte(`@retry(times=@var.RETRIES) { echo "a" }`))
+	for _, err := range tree.Errors {
+		t.Errorf("unexpected parse error: %s", err.Message)
+	}
+
+	decorators := 0
+	for _, evt := range tree.Events {
+		if evt.Kind == EventOpen && NodeKind(evt.Data) == NodeDecorator {
+			decorators++
+		}
+	}
+	if decorators != 2 {
+		t.Errorf("expected @retry and a nested @var decorator node, got %d decorator nodes", decorators)
+	}
+}
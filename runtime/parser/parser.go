@@ -267,6 +267,9 @@ func (p *parser) file() {
 		} else if p.at(lexer.AT) {
 			// Decorator at top level (script mode)
 			p.decorator()
+			if p.atRedirectIn() {
+				p.decoratorRedirectIn()
+			}
 		} else if p.at(lexer.IDENTIFIER) {
 			// Shell command at top level
 			p.shellCommand()
@@ -444,9 +447,14 @@ func (p *parser) defaultValue() {
 		p.token()
 	}
 
-	// Parse expression (for now, just consume one token - string literal, number, etc.)
+	// Parse plan-time value: array/object literal, value decorator, or a single token
 	// TODO: Full expression parsing in later iteration
-	if !p.at(lexer.EOF) && !p.at(lexer.RPAREN) && !p.at(lexer.COMMA) {
+	switch {
+	case p.at(lexer.LSQUARE) || p.at(lexer.LBRACE):
+		p.expression()
+	case p.at(lexer.AT):
+		p.decorator()
+	case !p.at(lexer.EOF) && !p.at(lexer.RPAREN) && !p.at(lexer.COMMA):
 		p.token()
 	}
 
@@ -580,7 +588,9 @@ func (p *parser) statement() {
 
 		// Check for shell operators after decorator (for piping, chaining)
 		// e.g., @timeout(5s) { echo "test" } | grep "pattern"
-		if p.isShellOperator() {
+		if p.atRedirectIn() {
+			p.decoratorRedirectIn()
+		} else if p.isShellOperator() {
 			p.token() // Consume operator (&&, ||, |)
 
 			// Parse next command after operator
@@ -659,42 +669,7 @@ func (p *parser) ifStmt() {
 		})
 		// Continue parsing the block despite the error
 	} else if !p.at(lexer.EOF) {
-		// Parse condition - must be boolean or expression that evaluates to boolean
-		conditionToken := p.current()
-
-		// Type check: only allow boolean literals, identifiers, or decorators
-		// String and integer literals are not allowed
-		if conditionToken.Type == lexer.STRING || conditionToken.Type == lexer.INTEGER {
-			p.errors = append(p.errors, ParseError{
-				Position:   conditionToken.Position,
-				Message:    "if condition must be a boolean expression",
-				Context:    "if statement",
-				Got:        conditionToken.Type,
-				Expected:   []lexer.TokenType{lexer.BOOLEAN, lexer.IDENTIFIER},
-				Suggestion: "Use a boolean value (true/false), identifier, or comparison expression",
-				Example:    "if @var.enabled { ... } or if count > 0 { ... }",
-			})
-			p.token() // Consume invalid token
-		} else if p.at(lexer.AT) {
-			// Decorator expression: @var.enabled, @env.DEBUG, etc.
-			// Parse decorator reference without block
-			kind := p.start(NodeDecorator)
-			p.token() // @
-			if p.at(lexer.IDENTIFIER) || p.at(lexer.VAR) {
-				p.token() // decorator name
-			}
-			if p.at(lexer.DOT) {
-				p.token() // .
-				if p.at(lexer.IDENTIFIER) {
-					p.token() // property name
-				}
-			}
-			// Note: We don't parse parameters or blocks in condition context
-			p.finish(kind)
-		} else {
-			// Boolean, identifier, or other valid expression token
-			p.token()
-		}
+		p.ifCondition()
 	}
 
 	// Parse then block
@@ -716,6 +691,91 @@ func (p *parser) ifStmt() {
 	}
 }
 
+// ifCondition parses an if condition: [!] operand [(== | !=) operand]
+// Manually parsed like when/for - p.expression() would treat the body's
+// '{' as a decorator block.
+func (p *parser) ifCondition() {
+	if p.at(lexer.NOT) {
+		// ! binds to the operand, like in primary()
+		kind := p.start(NodeUnaryExpr)
+		p.token() // !
+		p.conditionOperand()
+		p.finish(kind)
+	} else if !p.conditionStart() {
+		return
+	}
+
+	// Comparison: left, OPEN BinaryExpr, TOKEN(op), right, CLOSE BinaryExpr
+	if p.at(lexer.EQ_EQ) || p.at(lexer.NOT_EQ) {
+		kind := p.start(NodeBinaryExpr)
+		p.token() // == or !=
+		if p.at(lexer.LBRACE) || p.at(lexer.EOF) {
+			p.errors = append(p.errors, ParseError{
+				Position:   p.current().Position,
+				Message:    "missing right-hand side of comparison",
+				Context:    "if statement",
+				Got:        p.current().Type,
+				Expected:   []lexer.TokenType{lexer.STRING, lexer.INTEGER, lexer.BOOLEAN, lexer.AT},
+				Suggestion: "Compare against a value",
+				Example:    `if @var.ENV == "production" { ... }`,
+			})
+		} else {
+			p.conditionOperand()
+		}
+		p.finish(kind)
+	}
+}
+
+// conditionStart parses the left operand of an if condition.
+// Returns false if the operand is invalid.
+func (p *parser) conditionStart() bool {
+	// Type check: only allow boolean literals, identifiers, or decorators
+	// String and integer literals are not allowed on their own
+	conditionToken := p.current()
+	if conditionToken.Type == lexer.STRING || conditionToken.Type == lexer.INTEGER {
+		p.errors = append(p.errors, ParseError{
+			Position:   conditionToken.Position,
+			Message:    "if condition must be a boolean expression",
+			Context:    "if statement",
+			Got:        conditionToken.Type,
+			Expected:   []lexer.TokenType{lexer.BOOLEAN, lexer.IDENTIFIER},
+			Suggestion: "Use a boolean value (true/false), identifier, or comparison expression",
+			Example:    `if @var.enabled { ... } or if @var.ENV == "production" { ... }`,
+		})
+		p.token() // Consume invalid token
+		return false
+	}
+	p.conditionOperand()
+	return true
+}
+
+// conditionOperand parses one operand of a condition: a decorator reference
+// (@var.enabled, @env.DEBUG) or a single literal/identifier token.
+func (p *parser) conditionOperand() {
+	if !p.at(lexer.AT) {
+		p.token()
+		return
+	}
+
+	// Parse decorator reference without parameters or block
+	kind := p.start(NodeDecorator)
+	p.token() // @
+	isVar := p.at(lexer.VAR)
+	if p.at(lexer.IDENTIFIER) || isVar {
+		p.token() // decorator name
+	}
+	if p.at(lexer.DOT) {
+		p.token() // .
+		if p.at(lexer.IDENTIFIER) {
+			p.token() // property name
+			if isVar {
+				p.fieldPath()
+			}
+		}
+	}
+	p.finish(kind)
+}
+
 // elseClause parses an else clause: else { ... } or else if { ... }
 func (p *parser) elseClause() {
 	if p.config.debug >= DebugPaths {
@@ -896,13 +956,17 @@ func (p *parser) parseForCollection() {
 func (p *parser) parseDecorator() {
 	kind := p.start(NodeDecorator)
 	p.token() // @
-	if p.at(lexer.IDENTIFIER) || p.at(lexer.VAR) {
+	isVar := p.at(lexer.VAR)
+	if p.at(lexer.IDENTIFIER) || isVar {
 		p.token() // decorator name
 	}
 	if p.at(lexer.DOT) {
 		p.token() // .
 		if p.at(lexer.IDENTIFIER) {
 			p.token() // property name
+			if isVar {
+				p.fieldPath()
+			}
 		}
 	}
 	p.finish(kind)
@@ -1040,13 +1104,17 @@ func (p *parser) whenStmt() {
 		// Parse decorator reference without parameters or blocks
 		kind := p.start(NodeDecorator)
 		p.token() // @
-		if p.at(lexer.IDENTIFIER) || p.at(lexer.VAR) {
+		isVar := p.at(lexer.VAR)
+		if p.at(lexer.IDENTIFIER) || isVar {
 			p.token() // decorator name
 		}
 		if p.at(lexer.DOT) {
 			p.token() // .
 			if p.at(lexer.IDENTIFIER) {
 				p.token() // property name
+				if isVar {
+					p.fieldPath()
+				}
 			}
 		}
 		// Note: We don't parse parameters or blocks in when expression context
@@ -1266,12 +1334,15 @@ func (p *parser) shellCommand() {
 	// If we stopped at a shell operator, validate and consume it
 	if p.isShellOperator() {
 		// Check if it's a redirect operator
-		if p.at(lexer.GT) || p.at(lexer.APPEND) {
-			p.shellRedirect()
+		if p.atRedirect() {
+			// A command may redirect both ways: sort < in > out
+			for p.atRedirect() {
+				p.shellRedirect()
+			}
 
 			// CRITICAL FIX: After redirect, check for chaining operators (&&, ||, |, ;)
 			// This allows: echo a > out && echo b (both redirect AND chaining)
-			if p.isShellOperator() && !p.at(lexer.GT) && !p.at(lexer.APPEND) {
+			if p.isShellOperator() {
 				p.token() // Consume chaining operator (&&, ||, |, ;)
 
 				// Parse next command after operator
@@ -1290,8 +1361,8 @@ func (p *parser) shellCommand() {
 	}
 }
 
-// shellRedirect parses output redirection (> or >>)
-// PRECONDITION: Current token is GT or APPEND
+// shellRedirect parses input or output redirection (<, > or >>)
+// PRECONDITION: Current token is a redirect operator (atRedirect)
 func (p *parser) shellRedirect() {
 	if p.config.debug >= DebugPaths {
 		p.recordDebugEvent("enter_shell_redirect", "parsing redirect")
@@ -1299,7 +1370,7 @@ func (p *parser) shellRedirect() {
 
 	kind := p.start(NodeRedirect)
 
-	// Consume redirect operator (> or >>)
+	// Consume redirect operator (<, > or >>)
 	p.token()
 
 	// Parse redirect target
@@ -1381,8 +1452,49 @@ func (p *parser) isShellOperator() bool {
 		p.at(lexer.OR_OR) || // ||
 		p.at(lexer.PIPE) || // |
 		p.at(lexer.SEMICOLON) || // ;
-		p.at(lexer.GT) || // >
-		p.at(lexer.APPEND) // >>
+		p.atRedirect() // <, >, >>
+}
+
+// decoratorRedirectIn reports a < after a decorator: input redirects bind
+// to shell commands only
+func (p *parser) decoratorRedirectIn() {
+	p.errors = append(p.errors, ParseError{
+		Position:   p.current().Position,
+		Message:    "cannot redirect input into a decorator",
+		Context:    "redirect operator",
+		Got:        lexer.LT,
+		Suggestion: "Redirect input into a command inside the block",
+		Example:    "@retry(times=3) { psql < schema.sql }",
+	})
+	p.advance() // Skip the <
+}
+
+// atRedirect checks if current token is a redirect operator (<, > or >>)
+func (p *parser) atRedirect() bool {
+	return p.at(lexer.GT) || p.at(lexer.APPEND) || p.atRedirectIn()
+}
+
+// atRedirectIn checks if current token is an input redirect (<).
+// Shell syntax built on < stays shell text: here-strings (<<<), heredocs
+// (<<), process substitution (<(cmd)), fd duplication (<&0) and fd
+// redirects (0<file).
+func (p *parser) atRedirectIn() bool {
+	if !p.at(lexer.LT) {
+		return false
+	}
+	if p.pos+1 < len(p.tokens) {
+		next := p.tokens[p.pos+1]
+		if !next.HasSpaceBefore && (next.Type == lexer.LT || next.Type == lexer.LPAREN || next.Type == lexer.ILLEGAL) {
+			return false
+		}
+	}
+	if p.pos > 0 && !p.current().HasSpaceBefore {
+		prev := p.tokens[p.pos-1]
+		if prev.Type == lexer.LT || prev.Type == lexer.INTEGER {
+			return false
+		}
+	}
+	return true
 }
 
 // isStatementBoundary checks if current token ends a statement
@@ -1676,13 +1788,13 @@ func (p *parser) objectLiteral() {
 func (p *parser) objectField() {
 	kind := p.start(NodeObjectField)
 
-	// Parse key (must be identifier)
-	if !p.at(lexer.IDENTIFIER) {
+	// Parse key (identifier, or quoted string: {"VERSION": "3.0"})
+	if !p.at(lexer.IDENTIFIER) && !p.at(lexer.STRING) {
 		p.errorExpected(lexer.IDENTIFIER, "object field")
 		p.finish(kind)
 		return
 	}
-	p.token() // Consume identifier
+	p.token() // Consume key
 
 	// Expect colon
 	if !p.at(lexer.COLON) {
@@ -1834,6 +1946,11 @@ func (p *parser) decorator() {
 		}
 	}
 
+	// Field access on object and array variables: @var.config.db.password
+	if hasPrimaryViaDot && decoratorName == "var" {
+		p.fieldPath()
+	}
+
 	// Track provided parameters for validation
 	providedParams := make(map[string]bool)
 	if hasPrimaryViaDot && hasSchema && schema.PrimaryParameter != "" {
@@ -1842,7 +1959,14 @@ func (p *parser) decorator() {
 
 	// Parse parameters: (param1=value1, param2=value2)
 	if p.at(lexer.LPAREN) {
-		p.decoratorParamsWithValidation(decoratorName, schema, providedParams)
+		if hasPrimaryViaDot && schema.ForwardsParameters {
+			// Every parameter belongs to the receiver (e.g., @cmd.deploy(name="x")
+			// passes name to deploy), so none are checked against this schema
+			forwarded := types.DecoratorSchema{Path: schema.Path, ForwardsParameters: true}
+			p.decoratorParamsWithValidation(decoratorName, forwarded, make(map[string]bool))
+		} else {
+			p.decoratorParamsWithValidation(decoratorName, schema, providedParams)
+		}
 	}
 
 	// Validate required parameters
@@ -1925,6 +2049,38 @@ func (p *parser) decorator() {
 	}
 }
 
+// fieldPath consumes the field segments after a variable reference:
+// .db.password in @var.config.db.password, .0 in @var.hosts.0 (the lexer
+// reads ".0" as a number).
+func (p *parser) fieldPath() {
+	for {
+		if p.at(lexer.DOT) && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].Type == lexer.IDENTIFIER {
+			p.token() // .
+			p.token() // field name
+			continue
+		}
+		if p.at(lexer.FLOAT) && !p.current().HasSpaceBefore && isArrayIndex(p.current().Text) {
+			p.token() // .N
+			continue
+		}
+		return
+	}
+}
+
+// isArrayIndex reports whether a FLOAT token is an array index following a
+// field (".0" in @var.hosts.0).
+func isArrayIndex(text []byte) bool {
+	if len(text) < 2 || text[0] != '.' {
+		return false
+	}
+	for _, ch := range text[1:] {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // decoratorParamsWithValidation parses and validates decorator parameters
 func (p *parser) decoratorParamsWithValidation(decoratorName string, schema types.DecoratorSchema, providedParams map[string]bool) {
 	if !p.at(lexer.LPAREN) {
@@ -2006,7 +2162,7 @@ func (p *parser) decoratorParamsWithValidation(decoratorName string, schema type
 						}
 					}
 
-					if !paramExists {
+					if !paramExists && !schema.ForwardsParameters {
 						// Unknown parameter
 						p.errorWithDetails(
 							fmt.Sprintf("unknown parameter '%s' for @%s", paramName, decoratorName),
@@ -2037,7 +2193,10 @@ func (p *parser) decoratorParamsWithValidation(decoratorName string, schema type
 				nextPositionIndex++
 			}
 
-			if !found {
+			if !found && schema.ForwardsParameters {
+				// Forwarded positional argument: the receiver maps it by position
+				paramName = ""
+			} else if !found {
 				p.errorWithDetails(
 					"too many positional arguments",
 					"decorator parameters",
@@ -2049,7 +2208,7 @@ func (p *parser) decoratorParamsWithValidation(decoratorName string, schema type
 		}
 
 		// Check for duplicate parameter (only for positional, named already checked)
-		if !isNamed {
+		if !isNamed && paramName != "" {
 			if providedParams[paramName] {
 				p.errorWithDetails(
 					fmt.Sprintf("duplicate parameter '%s'", paramName),
@@ -2075,6 +2234,15 @@ func (p *parser) decoratorParamsWithValidation(decoratorName string, schema type
 			if paramExists {
 				p.validateComplexLiteral(paramName, paramSchema, eventStartPos)
 			}
+		} else if p.at(lexer.AT) {
+			// Value decorator reference (e.g., times=@var.RETRIES), resolved at plan time
+			startPos := p.pos
+			p.decorator()
+			if p.pos == startPos {
+				p.errorUnexpected("parameter value")
+				p.finish(paramKind)
+				break
+			}
 		} else if p.at(lexer.STRING) || p.at(lexer.INTEGER) || p.at(lexer.FLOAT) ||
 			p.at(lexer.BOOLEAN) || p.at(lexer.DURATION) || p.at(lexer.IDENTIFIER) {
 
@@ -2370,6 +2538,9 @@ func (p *parser) extractObjectField(startPos int) (string, any, bool, int) {
 
 	nameToken := p.tokens[nameEvt.Data]
 	fieldName := string(nameToken.Text)
+	if nameToken.Type == lexer.STRING && len(fieldName) >= 2 {
+		fieldName = fieldName[1 : len(fieldName)-1]
+	}
 	pos++
 
 	// Skip colon token
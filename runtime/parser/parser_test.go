@@ -198,6 +198,38 @@ func TestParseEventStructure(t *testing.T) {
 				{EventClose, 0},  // Source
 			},
 		},
+		{
+			name:  "function with value decorator default",
+			input: `fun greet(name = @env.USER) {}`,
+			events: []Event{
+				{EventOpen, 0},   // Source
+				{EventOpen, 1},   // Function
+				{EventToken, 0},  // fun
+				{EventToken, 1},  // greet
+				{EventOpen, 2},   // ParamList
+				{EventToken, 2},  // (
+				{EventOpen, 4},   // Param
+				{EventToken, 3},  // name
+				{EventOpen, 6},   // DefaultValue
+				{EventToken, 4},  // =
+				{EventOpen, 18},  // Decorator
+				{EventToken, 5},  // @
+				{EventToken, 6},  // env
+				{EventToken, 7},  // .
+				{EventToken, 8},  // USER
+				{EventClose, 18}, // Decorator
+				{EventClose, 6},  // DefaultValue
+				{EventClose, 4},  // Param
+				{EventToken, 9},  // )
+				{EventClose, 2},  // ParamList
+				{EventOpen, 3},   // Block
+				{EventToken, 10}, // {
+				{EventToken, 11}, // }
+				{EventClose, 3},  // Block
+				{EventClose, 1},  // Function
+				{EventClose, 0},  // Source
+			},
+		},
 		{
 			name:  "function with two untyped parameters",
 			input: `fun greet(first, last) {}`,
@@ -569,6 +601,29 @@ func TestRedirectOperatorValidation(t *testing.T) {
 			input:         `echo "test" | grep "test" > output.txt`,
 			expectedError: nil,
 		},
+		{
+			name:  "input redirect from decorator without input support",
+			input: `cat < @file.temp()`,
+			expectedError: &ParseError{
+				Position:   lexer.Position{Line: 1, Column: 5, Offset: 4},
+				Message:    "@file.temp does not support input redirection",
+				Context:    "redirect operator",
+				Got:        lexer.LT,
+				Suggestion: "Only decorators with input redirect support can be read from with <",
+				Example:    "psql < schema.sql",
+				Note:       "Use a file path, @shell(\"schema.sql\") or decorators that support input redirect",
+			},
+		},
+		{
+			name:          "valid input redirect from file path",
+			input:         `sort < data.txt > sorted.txt`,
+			expectedError: nil,
+		},
+		{
+			name:          "valid input redirect from value decorator",
+			input:         `cat < @file.read("data.txt")`,
+			expectedError: nil,
+		},
 	}
 
 	for _, tt := range tests {
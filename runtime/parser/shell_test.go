@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/opal-lang/opal/runtime/lexer"
 )
 
 // TestSimpleShellCommand tests parsing of basic shell commands
@@ -609,6 +610,62 @@ func TestShellRedirect(t *testing.T) {
 				{Kind: EventClose, Data: uint32(NodeSource)},
 			},
 		},
+		{
+			name:  "read from file with <",
+			input: `sort < data.txt`,
+			events: []Event{
+				{Kind: EventOpen, Data: uint32(NodeSource)},
+				{Kind: EventStepEnter, Data: 0},
+				{Kind: EventOpen, Data: uint32(NodeShellCommand)},
+				{Kind: EventOpen, Data: uint32(NodeShellArg)},
+				{Kind: EventToken, Data: 0}, // sort
+				{Kind: EventClose, Data: uint32(NodeShellArg)},
+				{Kind: EventClose, Data: uint32(NodeShellCommand)},
+				{Kind: EventOpen, Data: uint32(NodeRedirect)},
+				{Kind: EventToken, Data: 1}, // <
+				{Kind: EventOpen, Data: uint32(NodeRedirectTarget)},
+				{Kind: EventOpen, Data: uint32(NodeShellArg)},
+				{Kind: EventToken, Data: 2}, // data
+				{Kind: EventToken, Data: 3}, // .
+				{Kind: EventToken, Data: 4}, // txt
+				{Kind: EventClose, Data: uint32(NodeShellArg)},
+				{Kind: EventClose, Data: uint32(NodeRedirectTarget)},
+				{Kind: EventClose, Data: uint32(NodeRedirect)},
+				{Kind: EventStepExit, Data: 0},
+				{Kind: EventClose, Data: uint32(NodeSource)},
+			},
+		},
+		{
+			name:  "read from file then write to file",
+			input: `sort < in > out`,
+			events: []Event{
+				{Kind: EventOpen, Data: uint32(NodeSource)},
+				{Kind: EventStepEnter, Data: 0},
+				{Kind: EventOpen, Data: uint32(NodeShellCommand)},
+				{Kind: EventOpen, Data: uint32(NodeShellArg)},
+				{Kind: EventToken, Data: 0}, // sort
+				{Kind: EventClose, Data: uint32(NodeShellArg)},
+				{Kind: EventClose, Data: uint32(NodeShellCommand)},
+				{Kind: EventOpen, Data: uint32(NodeRedirect)},
+				{Kind: EventToken, Data: 1}, // <
+				{Kind: EventOpen, Data: uint32(NodeRedirectTarget)},
+				{Kind: EventOpen, Data: uint32(NodeShellArg)},
+				{Kind: EventToken, Data: 2}, // in
+				{Kind: EventClose, Data: uint32(NodeShellArg)},
+				{Kind: EventClose, Data: uint32(NodeRedirectTarget)},
+				{Kind: EventClose, Data: uint32(NodeRedirect)},
+				{Kind: EventOpen, Data: uint32(NodeRedirect)},
+				{Kind: EventToken, Data: 3}, // >
+				{Kind: EventOpen, Data: uint32(NodeRedirectTarget)},
+				{Kind: EventOpen, Data: uint32(NodeShellArg)},
+				{Kind: EventToken, Data: 4}, // out
+				{Kind: EventClose, Data: uint32(NodeShellArg)},
+				{Kind: EventClose, Data: uint32(NodeRedirectTarget)},
+				{Kind: EventClose, Data: uint32(NodeRedirect)},
+				{Kind: EventStepExit, Data: 0},
+				{Kind: EventClose, Data: uint32(NodeSource)},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -625,3 +682,52 @@ func TestShellRedirect(t *testing.T) {
 		})
 	}
 }
+
+// TestShellInputRedirectRawForms tests that here-strings, heredocs, process
+// substitution and fd redirects stay shell text instead of input redirects
+func TestShellInputRedirectRawForms(t *testing.T) {
+	inputs := []string{
+		`cat <<< "here"`,
+		`cat << EOF`,
+		`diff <(sort a) <(sort b)`,
+		`cat <&0`,
+		`cat 0<data.txt`,
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			tree := Parse([]byte(input))
+
+			if len(tree.Errors) > 0 {
+				t.Fatalf("unexpected parse errors: %v", tree.Errors)
+			}
+			for _, evt := range tree.Events {
+				if evt.Kind == EventOpen && NodeKind(evt.Data) == NodeRedirect {
+					t.Fatalf("expected shell text, got a redirect node")
+				}
+			}
+		})
+	}
+}
+
+// TestInputRedirectOnDecoratorBlock tests that < after a decorator block is
+// rejected instead of starting a new command
+func TestInputRedirectOnDecoratorBlock(t *testing.T) {
+	inputs := []string{
+		`@timeout(5s) { cat } < data.txt`,
+		`fun deploy { @timeout(5s) { cat } < data.txt }`,
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			tree := Parse([]byte(input))
+
+			if len(tree.Errors) == 0 {
+				t.Fatal("expected a parse error")
+			}
+			if tree.Errors[0].Got != lexer.LT || tree.Errors[0].Message != "cannot redirect input into a decorator" {
+				t.Errorf("unexpected error: %v %s", tree.Errors[0].Got, tree.Errors[0].Message)
+			}
+		})
+	}
+}
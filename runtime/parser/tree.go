@@ -98,7 +98,7 @@ const (
 	NodeAssignment // Assignment: x += 5, total -= cost
 
 	// Output redirection - added at end to preserve existing node numbers
-	NodeRedirect       // Redirect operator: > or >>
+	NodeRedirect       // Redirect operator: <, > or >>
 	NodeRedirectTarget // Redirect target (path, variable, or decorator)
 
 	// Object and array literals - added at end to preserve existing node numbers
@@ -425,6 +425,43 @@ func (v *semanticValidator) validateRedirectOperators() {
 			v.validateRedirectSupport(targetDecorator, redirectToken)
 		}
 	}
+
+	// Input redirects are found through the redirect nodes: outside shell
+	// commands, < is the less-than operator
+	for i := 0; i+1 < len(v.events); i++ {
+		if v.events[i].Kind != EventOpen || NodeKind(v.events[i].Data) != NodeRedirect || v.events[i+1].Kind != EventToken {
+			continue
+		}
+		pos := int(v.events[i+1].Data)
+		if v.tokens[pos].Type != lexer.LT {
+			continue
+		}
+		if sourceDecorator := v.findDecoratorAfter(pos); sourceDecorator != "" {
+			v.validateRedirectInSupport(sourceDecorator, v.tokens[pos])
+		}
+	}
+}
+
+// validateRedirectInSupport checks that the decorator after < can be read
+// from. Value decorators are fine: their value names the file.
+func (v *semanticValidator) validateRedirectInSupport(decoratorName string, redirectToken lexer.Token) {
+	schema, exists := v.tree.getSchema(decoratorName)
+	if !exists {
+		return // Decorator not registered - parser already reported error
+	}
+	if schema.RedirectIn || schema.Kind == types.KindValue || schema.Returns != nil {
+		return
+	}
+
+	v.errors = append(v.errors, ParseError{
+		Position:   redirectToken.Position,
+		Message:    "@" + decoratorName + " does not support input redirection",
+		Context:    "redirect operator",
+		Got:        redirectToken.Type,
+		Suggestion: "Only decorators with input redirect support can be read from with <",
+		Example:    "psql < schema.sql",
+		Note:       "Use a file path, @shell(\"schema.sql\") or decorators that support input redirect",
+	})
 }
 
 func (v *semanticValidator) validateRedirectSupport(decoratorName string, redirectToken lexer.Token) {
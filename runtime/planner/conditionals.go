@@ -0,0 +1,468 @@
+package planner
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/opal-lang/opal/core/invariant"
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/runtime/lexer"
+	"github.com/opal-lang/opal/runtime/parser"
+)
+
+// planIf evaluates an if statement at plan time and plans only the taken branch.
+// Expects p.pos at OPEN If, leaves position after CLOSE If.
+//
+// Event structure:
+//
+//	OPEN If, TOKEN(if), <condition>, OPEN Block, ..., CLOSE Block,
+//	[OPEN Else, TOKEN(else), (OPEN Block, ..., CLOSE Block | OPEN If, ..., CLOSE If), CLOSE Else],
+//	CLOSE If
+//
+// The result is one step holding a GroupNode labeled with the condition and
+// its outcome ("if @var.ENV == \"prod\" -> true"). The group is emitted even
+// when no branch is taken, so the plan (and its hash) records the decision.
+// Condition inputs are recorded as use sites at "step-N/params/condition".
+//
+// Unlike loop iterations, branches share the enclosing variable scope:
+// variables declared in the taken branch stay visible after the if.
+func (p *planner) planIf() (planfmt.Step, error) {
+	// PRECONDITION: Must be at OPEN If
+	invariant.Precondition(p.atOpen(parser.NodeIf), "planIf must start at OPEN If")
+
+	id := p.nextStepID()
+	p.vault.Push(fmt.Sprintf("step-%d", id))
+	defer p.vault.Pop()
+
+	startPos := p.pos
+	p.pos++ // Move past OPEN If
+	p.pos++ // Skip TOKEN(if)
+
+	taken, text, err := p.evalCondition()
+	if err != nil {
+		return planfmt.Step{}, err
+	}
+
+	if !p.atOpen(parser.NodeBlock) {
+		return planfmt.Step{}, &PlanError{
+			Message:     "if statement has no body",
+			Context:     fmt.Sprintf("planning if %s", text),
+			EventPos:    startPos,
+			TotalEvents: len(p.events),
+		}
+	}
+	p.pos++ // Move past OPEN Block
+
+	var steps []planfmt.Step
+	if taken {
+		steps, err = p.planBlockSteps()
+		if err != nil {
+			return planfmt.Step{}, err
+		}
+	} else {
+		p.skipToClose(parser.NodeBlock)
+	}
+
+	if p.atOpen(parser.NodeElse) {
+		p.pos++ // Move past OPEN Else
+		p.pos++ // Skip TOKEN(else)
+
+		switch {
+		case taken:
+			// Pruned - never evaluated
+		case p.atOpen(parser.NodeBlock):
+			p.pos++
+			steps, err = p.planBlockSteps()
+		case p.atOpen(parser.NodeIf):
+			// else if: the nested if becomes a nested group
+			var step planfmt.Step
+			step, err = p.planIf()
+			steps = []planfmt.Step{step}
+		}
+		if err != nil {
+			return planfmt.Step{}, err
+		}
+		p.skipToClose(parser.NodeElse)
+	}
+
+	// Move past CLOSE If
+	p.skipToClose(parser.NodeIf)
+
+	if p.config.Debug >= DebugDetailed {
+		p.recordDebugEvent("if_evaluated", fmt.Sprintf("condition=%s taken=%t steps=%d", text, taken, len(steps)))
+	}
+
+	return planfmt.Step{
+		ID: id,
+		Tree: &planfmt.GroupNode{
+			Kind:  "if",
+			Label: fmt.Sprintf("if %s -> %t", text, taken),
+			Steps: steps,
+		},
+	}, nil
+}
+
+// planWhen matches a when statement at plan time and plans only the first
+// matching arm. Arms after the match are never evaluated.
+// Expects p.pos at OPEN When, leaves position after CLOSE When.
+//
+// Event structure:
+//
+//	OPEN When, TOKEN(when), <subject>, TOKEN({),
+//	[OPEN WhenArm, <patterns>, TOKEN(->), <block or statement>, CLOSE WhenArm]...,
+//	TOKEN(}), CLOSE When
+//
+// The result is one step holding a GroupNode labeled with the selected arm
+// ("when @env.ENV -> matched \"prod\" | \"production\""). The subject is
+// recorded as a use site at "step-N/params/subject".
+func (p *planner) planWhen() (planfmt.Step, error) {
+	// PRECONDITION: Must be at OPEN When
+	invariant.Precondition(p.atOpen(parser.NodeWhen), "planWhen must start at OPEN When")
+
+	id := p.nextStepID()
+	p.vault.Push(fmt.Sprintf("step-%d", id))
+	defer p.vault.Pop()
+
+	p.pos++ // Move past OPEN When
+	p.pos++ // Skip TOKEN(when)
+
+	subject, text, err := p.evalOperand("subject")
+	if err != nil {
+		return planfmt.Step{}, err
+	}
+
+	var steps []planfmt.Step
+	matched := ""
+	for p.pos < len(p.events) {
+		prevPos := p.pos
+		evt := p.events[p.pos]
+
+		if evt.Kind == parser.EventClose && parser.NodeKind(evt.Data) == parser.NodeWhen {
+			p.pos++
+			break
+		}
+
+		if p.atOpen(parser.NodeWhenArm) {
+			p.pos++ // Move past OPEN WhenArm
+
+			// First match wins - later arms are pruned without evaluation
+			if matched == "" {
+				patterns, ok, err := p.matchWhenPatterns(subject)
+				if err != nil {
+					return planfmt.Step{}, err
+				}
+				if ok {
+					matched = patterns
+					steps, err = p.planWhenArmBody()
+					if err != nil {
+						return planfmt.Step{}, err
+					}
+				}
+			}
+			p.skipToClose(parser.NodeWhenArm)
+			continue
+		}
+
+		p.pos++ // TOKEN({), TOKEN(})
+		invariant.Invariant(p.pos > prevPos, "planWhen stuck at pos %d", prevPos)
+	}
+
+	label := fmt.Sprintf("when %s -> no match", text)
+	if matched != "" {
+		label = fmt.Sprintf("when %s -> matched %s", text, matched)
+	}
+
+	if p.config.Debug >= DebugDetailed {
+		p.recordDebugEvent("when_matched", fmt.Sprintf("subject=%s arm=%q steps=%d", text, matched, len(steps)))
+	}
+
+	return planfmt.Step{
+		ID: id,
+		Tree: &planfmt.GroupNode{
+			Kind:  "when",
+			Label: label,
+			Steps: steps,
+		},
+	}, nil
+}
+
+// matchWhenPatterns matches subject against an arm's patterns.
+// OR patterns ("a" | "b") are alternatives of the same arm.
+// Returns the patterns' source text and whether any matched.
+// Expects p.pos just after OPEN WhenArm, leaves position after TOKEN(->).
+func (p *planner) matchWhenPatterns(subject any) (string, bool, error) {
+	var texts []string
+	matched := false
+
+	for p.pos < len(p.events) {
+		evt := p.events[p.pos]
+
+		if evt.Kind == parser.EventToken && p.tokens[evt.Data].Type == lexer.ARROW {
+			p.pos++
+			return strings.Join(texts, " | "), matched, nil
+		}
+
+		if evt.Kind != parser.EventOpen {
+			p.pos++ // TOKEN(|), CLOSE PatternOr
+			continue
+		}
+
+		kind := parser.NodeKind(evt.Data)
+		if kind == parser.NodePatternOr {
+			p.pos++
+			continue
+		}
+
+		p.pos++ // Move past OPEN Pattern*
+		tokens := p.patternTokens()
+
+		var text string
+		var ok bool
+		switch kind {
+		case parser.NodePatternElse:
+			text, ok = "else", true
+
+		case parser.NodePatternLiteral:
+			text = string(tokens[0].Text)
+			ok = valueText(subject) == unquote(text)
+
+		case parser.NodePatternRegex:
+			// Tokens: r, "pattern"
+			raw := string(tokens[len(tokens)-1].Text)
+			text = "r" + raw
+			re, err := regexp.Compile(unquote(raw))
+			if err != nil {
+				return "", false, &PlanError{
+					Message:     fmt.Sprintf("invalid regex pattern %s: %v", text, err),
+					Context:     "planning when statement",
+					EventPos:    p.pos,
+					TotalEvents: len(p.events),
+				}
+			}
+			ok = re.MatchString(valueText(subject))
+
+		case parser.NodePatternRange:
+			// Tokens: start, ..., end
+			var bounds []int64
+			for _, tok := range tokens {
+				if tok.Type == lexer.INTEGER {
+					n, err := strconv.ParseInt(string(tok.Text), 10, 64)
+					if err != nil {
+						return "", false, fmt.Errorf("invalid range pattern bound %q: %w", tok.Text, err)
+					}
+					bounds = append(bounds, n)
+				}
+			}
+			if len(bounds) != 2 {
+				return "", false, fmt.Errorf("range pattern must have a start and an end")
+			}
+			text = fmt.Sprintf("%d...%d", bounds[0], bounds[1])
+			// Non-integer subjects never match a range
+			if n, isInt := toInt64(subject); isInt {
+				ok = n >= bounds[0] && n <= bounds[1]
+			}
+
+		default:
+			return "", false, &PlanError{
+				Message:     fmt.Sprintf("unsupported when pattern %v", kind),
+				Context:     "planning when statement",
+				EventPos:    p.pos,
+				TotalEvents: len(p.events),
+			}
+		}
+
+		texts = append(texts, text)
+		matched = matched || ok
+	}
+
+	return "", false, fmt.Errorf("when arm is missing '->'")
+}
+
+// patternTokens returns the tokens of a pattern node.
+// Expects p.pos just after OPEN Pattern*, leaves position after its CLOSE.
+func (p *planner) patternTokens() []lexer.Token {
+	var tokens []lexer.Token
+	for p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
+		tokens = append(tokens, p.tokens[p.events[p.pos].Data])
+		p.pos++
+	}
+	p.pos++ // Move past CLOSE Pattern*
+	return tokens
+}
+
+// planWhenArmBody plans the body of the selected arm: a block or a single statement.
+// Expects p.pos just after TOKEN(->).
+func (p *planner) planWhenArmBody() ([]planfmt.Step, error) {
+	switch {
+	case p.atOpen(parser.NodeBlock):
+		p.pos++
+		return p.planBlockSteps()
+
+	case p.pos < len(p.events) && isControlFlow(p.events[p.pos]):
+		return p.planControlFlow()
+
+	case p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventStepEnter:
+		step, err := p.planStatementStep()
+		if err != nil || step.ID == 0 {
+			return nil, err
+		}
+		return []planfmt.Step{step}, nil
+	}
+	return nil, nil
+}
+
+// evalCondition evaluates an if condition: [!] operand [(== | !=) operand].
+// Returns the outcome and the condition's source text.
+// Expects p.pos at the condition, leaves position after it.
+func (p *planner) evalCondition() (bool, string, error) {
+	left, text, err := p.evalOperand("condition")
+	if err != nil {
+		return false, "", err
+	}
+
+	if !p.atOpen(parser.NodeBinaryExpr) {
+		result, err := conditionBool(text, left)
+		return result, text, err
+	}
+
+	// Comparison: OPEN BinaryExpr, TOKEN(op), <right>, CLOSE BinaryExpr
+	p.pos++
+	op := p.tokens[p.events[p.pos].Data].Type
+	p.pos++
+	right, rightText, err := p.evalOperand("condition")
+	if err != nil {
+		return false, "", err
+	}
+	p.skipToClose(parser.NodeBinaryExpr)
+
+	// Values compare by their string form; number and boolean literals
+	// are stored as strings in var declarations
+	equal := valueText(left) == valueText(right)
+	switch op {
+	case lexer.EQ_EQ:
+		return equal, fmt.Sprintf("%s == %s", text, rightText), nil
+	case lexer.NOT_EQ:
+		return !equal, fmt.Sprintf("%s != %s", text, rightText), nil
+	}
+	return false, "", fmt.Errorf("unsupported operator %s in condition %s", op, text)
+}
+
+// evalOperand resolves one condition operand: a literal, a variable (bare
+// identifier or @var.NAME), a value decorator (@env.ENV), or a negation.
+// Variable and decorator inputs are recorded as use sites under paramName.
+// Returns the value and the operand's source text.
+func (p *planner) evalOperand(paramName string) (any, string, error) {
+	if p.pos >= len(p.events) {
+		return nil, "", fmt.Errorf("missing condition")
+	}
+	evt := p.events[p.pos]
+
+	switch {
+	case p.atOpen(parser.NodeUnaryExpr):
+		p.pos++ // Move past OPEN UnaryExpr
+		p.pos++ // Skip TOKEN(!)
+		value, text, err := p.evalOperand(paramName)
+		if err != nil {
+			return nil, "", err
+		}
+		p.skipToClose(parser.NodeUnaryExpr)
+		b, err := conditionBool(text, value)
+		return !b, "!" + text, err
+
+	case p.atOpen(parser.NodeDecorator):
+		startPos := p.pos
+		parts := p.parseDecoratorRef()
+		text := "@" + strings.Join(parts, ".")
+		if len(parts) >= 2 && parts[0] == "var" {
+			value, err := p.resolveVarReference(strings.Join(parts[1:], "."), paramName)
+			return value, text, err
+		}
+		value, err := p.resolveDecoratorReference(parts, paramName, startPos)
+		return value, text, err
+
+	case evt.Kind == parser.EventToken:
+		tok := p.tokens[evt.Data]
+		p.pos++
+		text := string(tok.Text)
+		switch tok.Type {
+		case lexer.STRING:
+			return unquote(text), text, nil
+		case lexer.IDENTIFIER:
+			// Bare identifier names a variable: if enabled { ... }
+			value, err := p.resolveVarReference(text, paramName)
+			return value, text, err
+		default:
+			// Booleans and numbers compare by their literal text
+			return text, text, nil
+		}
+	}
+
+	return nil, "", &PlanError{
+		Message:     "unsupported condition",
+		Context:     "evaluating condition",
+		EventPos:    p.pos,
+		TotalEvents: len(p.events),
+		Suggestion:  "Use a boolean, a variable, or a comparison",
+		Example:     `if @var.ENV == "production" { ... }`,
+	}
+}
+
+// resolveDecoratorReference resolves a value decorator (@env.ENV) at plan
+// time and tracks it in the vault so the value gets a DisplayID and the
+// reference is recorded as a use site, like @var references.
+func (p *planner) resolveDecoratorReference(parts []string, paramName string, startPos int) (any, error) {
+	_, value, err := p.trackDecoratorReference(parts, paramName, "evaluating condition", startPos)
+	return value, err
+}
+
+// trackDecoratorReference resolves a value decorator and tracks it in the
+// vault, returning the expression ID along with the value.
+func (p *planner) trackDecoratorReference(parts []string, paramName, context string, startPos int) (string, any, error) {
+	value, err := p.resolveValueDecorator(parts, context, startPos)
+	if err != nil {
+		return "", nil, err
+	}
+
+	exprID := p.vault.TrackExpression("@" + strings.Join(parts, "."))
+	p.vault.StoreUnresolvedValue(exprID, value)
+	if err := p.vault.RecordReference(exprID, paramName); err != nil {
+		return "", nil, err
+	}
+	p.vault.MarkTouched(exprID)
+	p.vault.ResolveAllTouched()
+	p.recordDecoratorResolution("@" + parts[0])
+
+	return exprID, value, nil
+}
+
+// conditionBool converts a condition value to a boolean.
+// Boolean literals and @env values arrive as "true"/"false" strings.
+// The error never includes the value itself - it may be a secret.
+func conditionBool(text string, value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch v {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("condition %s must be a boolean (true or false)", text)
+}
+
+// atOpen reports whether p.pos is at OPEN kind.
+func (p *planner) atOpen(kind parser.NodeKind) bool {
+	return p.pos < len(p.events) &&
+		p.events[p.pos].Kind == parser.EventOpen &&
+		parser.NodeKind(p.events[p.pos].Data) == kind
+}
+
+// unquote strips the quotes from a string literal's source text.
+func unquote(text string) string {
+	return strings.Trim(text, `"'`)
+}
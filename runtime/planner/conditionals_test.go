@@ -0,0 +1,382 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/runtime/parser"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+// singleGroup asserts steps is one group of kind and returns it.
+func singleGroup(t *testing.T, steps []planfmt.Step, kind string) *planfmt.GroupNode {
+	t.Helper()
+	if len(steps) != 1 {
+		t.Fatalf("Expected 1 step, got %d", len(steps))
+	}
+	group, ok := steps[0].Tree.(*planfmt.GroupNode)
+	if !ok {
+		t.Fatalf("Expected GroupNode, got %T", steps[0].Tree)
+	}
+	if group.Kind != kind {
+		t.Errorf("Expected kind %q, got %q", kind, group.Kind)
+	}
+	return group
+}
+
+// groupCommands returns the shell commands directly inside a group.
+func groupCommands(group *planfmt.GroupNode) []string {
+	var commands []string
+	for _, step := range group.Steps {
+		commands = append(commands, getCommandArg(step.Tree, "command"))
+	}
+	return commands
+}
+
+func TestIf_PlansOnlyTakenBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		label    string
+		commands []string
+	}{
+		{
+			name:     "true without else",
+			source:   `if true { echo "yes" }`,
+			label:    "if true -> true",
+			commands: []string{`echo "yes"`},
+		},
+		{
+			name:     "false without else",
+			source:   `if false { echo "yes" }`,
+			label:    "if false -> false",
+			commands: nil,
+		},
+		{
+			name:     "false with else",
+			source:   `if false { echo "yes" } else { echo "no" }`,
+			label:    "if false -> false",
+			commands: []string{`echo "no"`},
+		},
+		{
+			name: "equality",
+			source: `var ENV = "production"
+if @var.ENV == "production" { echo "prod" } else { echo "dev" }`,
+			label:    `if @var.ENV == "production" -> true`,
+			commands: []string{`echo "prod"`},
+		},
+		{
+			name: "inequality",
+			source: `var ENV = "production"
+if @var.ENV != "production" { echo "dev" }`,
+			label:    `if @var.ENV != "production" -> false`,
+			commands: nil,
+		},
+		{
+			name: "negation",
+			source: `var DRY = false
+if !@var.DRY { echo "apply" }`,
+			label:    "if !@var.DRY -> true",
+			commands: []string{`echo "apply"`},
+		},
+		{
+			name: "number comparison",
+			source: `var REPLICAS = 3
+if @var.REPLICAS == 3 { echo "three" }`,
+			label:    "if @var.REPLICAS == 3 -> true",
+			commands: []string{`echo "three"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planLoopSource(t, tt.source, "")
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+			group := singleGroup(t, plan.Steps, "if")
+			if group.Label != tt.label {
+				t.Errorf("Expected label %q, got %q", tt.label, group.Label)
+			}
+			got := groupCommands(group)
+			if strings.Join(got, "\n") != strings.Join(tt.commands, "\n") {
+				t.Errorf("Expected commands %q, got %q", tt.commands, got)
+			}
+		})
+	}
+}
+
+func TestIf_ElseIfChainNestsGroups(t *testing.T) {
+	plan, err := planLoopSource(t, `
+var ENV = "staging"
+if @var.ENV == "production" {
+    echo "prod"
+} else if @var.ENV == "staging" {
+    echo "staging"
+} else {
+    echo "other"
+}
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	outer := singleGroup(t, plan.Steps, "if")
+	if outer.Label != `if @var.ENV == "production" -> false` {
+		t.Errorf("Unexpected outer label %q", outer.Label)
+	}
+	inner := singleGroup(t, outer.Steps, "if")
+	if inner.Label != `if @var.ENV == "staging" -> true` {
+		t.Errorf("Unexpected inner label %q", inner.Label)
+	}
+	if got := groupCommands(inner); len(got) != 1 || got[0] != `echo "staging"` {
+		t.Errorf("Expected only the staging branch, got %q", got)
+	}
+
+	// Groups take their ID before their children
+	if plan.Steps[0].ID != 1 || outer.Steps[0].ID != 2 || inner.Steps[0].ID != 3 {
+		t.Errorf("Expected pre-order step IDs 1, 2, 3, got %d, %d, %d",
+			plan.Steps[0].ID, outer.Steps[0].ID, inner.Steps[0].ID)
+	}
+}
+
+func TestIf_PrunedBranchIsNotPlanned(t *testing.T) {
+	// The untaken branch references an undeclared variable; it must not be planned
+	plan, err := planLoopSource(t, `
+if false {
+    echo "@var.MISSING"
+}
+echo "after"
+`, "")
+	if err != nil {
+		t.Fatalf("Pruned branch should not be planned, got: %v", err)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("Expected if group and trailing step, got %d steps", len(plan.Steps))
+	}
+}
+
+func TestIf_BranchSharesEnclosingScope(t *testing.T) {
+	// Language control blocks are not isolated: declarations leak out
+	plan, err := planLoopSource(t, `
+if true {
+    var MODE = "fast"
+}
+echo "@var.MODE"
+`, "")
+	if err != nil {
+		t.Fatalf("Variable declared in taken branch should be visible after the if: %v", err)
+	}
+	if got := getCommandArg(plan.Steps[1].Tree, "command"); !strings.Contains(got, "opal:") {
+		t.Errorf("Expected DisplayID in command, got %q", got)
+	}
+}
+
+func TestIf_EnvConditionRecordedAsUseSite(t *testing.T) {
+	t.Setenv("OPAL_TEST_DEPLOY_ENV", "production")
+
+	plan, err := planLoopSource(t, `
+if @env.OPAL_TEST_DEPLOY_ENV == "production" {
+    echo "prod"
+}
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	group := singleGroup(t, plan.Steps, "if")
+	if group.Label != `if @env.OPAL_TEST_DEPLOY_ENV == "production" -> true` {
+		t.Errorf("Unexpected label %q", group.Label)
+	}
+
+	found := false
+	for _, use := range plan.SecretUses {
+		if use.Site == "root/step-1/params/condition" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected condition input recorded at root/step-1/params/condition, got %+v", plan.SecretUses)
+	}
+}
+
+func TestIf_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "non-boolean condition",
+			source: `var NAME = "opal"
+if @var.NAME { echo "yes" }`,
+			want: "must be a boolean",
+		},
+		{
+			name:   "undeclared variable",
+			source: `if @var.MISSING == "x" { echo "yes" }`,
+			want:   "MISSING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planLoopSource(t, tt.source, "")
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWhen_SelectsFirstMatchingArm(t *testing.T) {
+	tests := []struct {
+		name     string
+		subject  string
+		label    string
+		commands []string
+	}{
+		{
+			name:     "literal",
+			subject:  `"staging"`,
+			label:    `when @var.TARGET -> matched "staging"`,
+			commands: []string{`echo "staging"`},
+		},
+		{
+			name:     "or pattern",
+			subject:  `"prod"`,
+			label:    `when @var.TARGET -> matched "production" | "prod"`,
+			commands: []string{`echo "prod"`},
+		},
+		{
+			name:     "regex",
+			subject:  `"release/v1.2"`,
+			label:    `when @var.TARGET -> matched r"^release/"`,
+			commands: []string{`echo "release"`, `echo "tagged"`},
+		},
+		{
+			name:     "range",
+			subject:  `204`,
+			label:    `when @var.TARGET -> matched 200...299`,
+			commands: []string{`echo "ok"`},
+		},
+		{
+			name:     "else",
+			subject:  `"unknown"`,
+			label:    `when @var.TARGET -> matched else`,
+			commands: []string{`echo "fallback"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := `var TARGET = ` + tt.subject + `
+when @var.TARGET {
+    "production" | "prod" -> echo "prod"
+    "staging" -> echo "staging"
+    r"^release/" -> {
+        echo "release"
+        echo "tagged"
+    }
+    200...299 -> echo "ok"
+    else -> echo "fallback"
+}`
+			plan, err := planLoopSource(t, source, "")
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+			group := singleGroup(t, plan.Steps, "when")
+			if group.Label != tt.label {
+				t.Errorf("Expected label %q, got %q", tt.label, group.Label)
+			}
+			got := groupCommands(group)
+			if strings.Join(got, "\n") != strings.Join(tt.commands, "\n") {
+				t.Errorf("Expected commands %q, got %q", tt.commands, got)
+			}
+		})
+	}
+}
+
+func TestWhen_NoMatchPlansNothing(t *testing.T) {
+	plan, err := planLoopSource(t, `
+var TARGET = "dev"
+when @var.TARGET {
+    "production" -> echo "prod"
+}
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	group := singleGroup(t, plan.Steps, "when")
+	if group.Label != "when @var.TARGET -> no match" {
+		t.Errorf("Unexpected label %q", group.Label)
+	}
+	if len(group.Steps) != 0 {
+		t.Errorf("Expected no steps, got %d", len(group.Steps))
+	}
+}
+
+func TestWhen_ArmsAfterMatchAreNotEvaluated(t *testing.T) {
+	// The invalid regex after the matching arm is never compiled
+	_, err := planLoopSource(t, `
+var TARGET = "prod"
+when @var.TARGET {
+    "prod" -> echo "prod"
+    r"(" -> echo "never"
+}
+`, "")
+	if err != nil {
+		t.Fatalf("Arms after the match should not be evaluated: %v", err)
+	}
+
+	_, err = planLoopSource(t, `
+var TARGET = "dev"
+when @var.TARGET {
+    r"(" -> echo "broken"
+}
+`, "")
+	if err == nil || !strings.Contains(err.Error(), "invalid regex") {
+		t.Errorf("Expected invalid regex error, got: %v", err)
+	}
+}
+
+// TestConditional_ContractHashTracksBranch verifies that re-planning with the
+// same plan key yields the same hash only while the same branch is taken.
+func TestConditional_ContractHashTracksBranch(t *testing.T) {
+	source := `
+if @env.OPAL_TEST_BRANCH_ENV == "production" {
+    echo "prod"
+} else {
+    echo "dev"
+}
+`
+	digest := func(env string) string {
+		t.Setenv("OPAL_TEST_BRANCH_ENV", env)
+		tree := parser.ParseString(source)
+		if len(tree.Errors) > 0 {
+			t.Fatalf("Parse errors: %v", tree.Errors)
+		}
+		plan, err := Plan(tree.Events, tree.Tokens, Config{
+			Vault: vault.NewWithPlanKey(make([]byte, 32)),
+		})
+		if err != nil {
+			t.Fatalf("Plan failed: %v", err)
+		}
+		d, err := plan.Digest()
+		if err != nil {
+			t.Fatalf("Digest failed: %v", err)
+		}
+		return d
+	}
+
+	contract := digest("production")
+	if digest("production") != contract {
+		t.Error("Same inputs should produce the same hash")
+	}
+	if digest("staging") == contract {
+		t.Error("Taking a different branch must change the hash")
+	}
+}
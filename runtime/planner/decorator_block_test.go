@@ -219,3 +219,43 @@ func TestDecoratorBlock_NoArguments(t *testing.T) {
 	t.Logf("✓ @parallel decorator created correctly")
 	t.Logf("✓ Block contains %d steps", len(cmd.Block))
 }
+
+// TestDecoratorBlock_ArgsSortedByKey verifies that decorator block arguments are
+// sorted by key regardless of source order (Plan.Validate requires sorted args).
+func TestDecoratorBlock_ArgsSortedByKey(t *testing.T) {
+	source := `
+@retry(times=3, delay=10ms, backoff="constant") {
+    echo "test"
+}
+`
+
+	tree := parser.ParseString(source)
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+
+	result, err := PlanWithObservability(tree.Events, tree.Tokens, Config{})
+	if err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+
+	cmd, ok := result.Plan.Steps[0].Tree.(*planfmt.CommandNode)
+	if !ok {
+		t.Fatalf("Expected CommandNode, got %T", result.Plan.Steps[0].Tree)
+	}
+
+	var keys []string
+	for _, arg := range cmd.Args {
+		keys = append(keys, arg.Key)
+	}
+	want := []string{"backoff", "delay", "times"}
+	if len(keys) != len(want) {
+		t.Fatalf("Expected args %v, got %v", want, keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("Expected args %v, got %v", want, keys)
+			break
+		}
+	}
+}
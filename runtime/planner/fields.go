@@ -0,0 +1,55 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field access on object and array variables.
+//
+// A reference like @var.config.db.password is tracked as its own expression
+// (vault.TrackField): the field gets its own DisplayID, use sites and scrub
+// pattern. The rest of config is never unwrapped where only the password is
+// used, and the plan shows one placeholder per field instead of one for the
+// whole object.
+
+// splitVarPath splits a variable reference ("config.db.password") into the
+// variable name and its field path.
+func splitVarPath(ref string) (string, []string) {
+	name, fields, found := strings.Cut(ref, ".")
+	if !found {
+		return name, nil
+	}
+	return name, strings.Split(fields, ".")
+}
+
+// trackField resolves the field path of variable name (exprID) to the
+// field's expression. Segments are consumed while the value is an object or
+// array; the number consumed is returned so text can keep the rest.
+func (p *planner) trackField(name, exprID string, path []string) (string, int, error) {
+	fieldID, consumed, err := p.vault.TrackField(exprID, path)
+	if err != nil {
+		return "", 0, fmt.Errorf("@var.%s.%w", name, err)
+	}
+
+	if consumed > 0 && p.config.Debug >= DebugDetailed {
+		p.recordDebugEvent("field_tracked", fmt.Sprintf("var=%s field=%s exprID=%s",
+			name, strings.Join(path[:consumed], "."), fieldID))
+	}
+	return fieldID, consumed, nil
+}
+
+// trackFieldPath is trackField for references that are only a field path
+// (conditions, loop collections, parameters): every segment must name a
+// field.
+func (p *planner) trackFieldPath(name, exprID string, path []string) (string, error) {
+	fieldID, consumed, err := p.trackField(name, exprID, path)
+	if err != nil {
+		return "", err
+	}
+	if consumed < len(path) {
+		return "", fmt.Errorf("@var.%s: cannot access field %q of a value that is not an object or array",
+			strings.Join(append([]string{name}, path[:consumed]...), "."), path[consumed])
+	}
+	return fieldID, nil
+}
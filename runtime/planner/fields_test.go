@@ -0,0 +1,188 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/runtime/parser"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+const fieldsSource = `var config = {db: {host: "db.internal", password: "hunter2-pw"}, hosts: ["web1", "web2"], enabled: "true", api_key: "unused-key"}
+var FILE = "report"
+`
+
+// planFieldsSource plans source after the shared declarations and returns
+// the plan and its vault.
+func planFieldsSource(t *testing.T, body string) (*planfmt.Plan, *vault.Vault, error) {
+	t.Helper()
+	tree := parser.ParseString(fieldsSource + body)
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+	vlt := vault.NewWithPlanKey(make([]byte, 32))
+	plan, err := Plan(tree.Events, tree.Tokens, Config{Vault: vlt})
+	return plan, vlt, err
+}
+
+// planCommands returns the shell commands of a plan, depth first.
+func planCommands(steps []planfmt.Step) []string {
+	var commands []string
+	var walk func(node planfmt.ExecutionNode)
+	walk = func(node planfmt.ExecutionNode) {
+		switch n := node.(type) {
+		case *planfmt.CommandNode:
+			for _, arg := range n.Args {
+				if arg.Key == "command" {
+					commands = append(commands, arg.Val.Str)
+				}
+			}
+		case *planfmt.GroupNode:
+			for _, step := range n.Steps {
+				walk(step.Tree)
+			}
+		}
+	}
+	for _, step := range steps {
+		walk(step.Tree)
+	}
+	return commands
+}
+
+// TestFieldAccess_OwnDisplayIDs verifies each field used gets its own
+// DisplayID, use site and scrub pattern, and unused fields get none
+func TestFieldAccess_OwnDisplayIDs(t *testing.T) {
+	plan, vlt, err := planFieldsSource(t, `psql -h @var.config.db.host -p @var.config.db.password`)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	commands := planCommands(plan.Steps)
+	if len(commands) != 1 {
+		t.Fatalf("Expected 1 command, got %v", commands)
+	}
+	ids := displayIDPattern.FindAllString(commands[0], -1)
+	if len(ids) != 2 || ids[0] == ids[1] {
+		t.Fatalf("Expected two distinct DisplayIDs, got %q", commands[0])
+	}
+	if !strings.HasPrefix(commands[0], "psql -h "+ids[0]+" -p "+ids[1]) {
+		t.Errorf("unexpected command %q", commands[0])
+	}
+	for _, secret := range []string{"db.internal", "hunter2-pw", "config"} {
+		if strings.Contains(commands[0], secret) {
+			t.Errorf("command should not contain %q: %q", secret, commands[0])
+		}
+	}
+
+	// Only the two fields are authorized, at their own sites
+	if len(plan.SecretUses) != 2 {
+		t.Fatalf("Expected 2 secret uses, got %+v", plan.SecretUses)
+	}
+	for _, use := range plan.SecretUses {
+		if use.DisplayID != ids[0] && use.DisplayID != ids[1] {
+			t.Errorf("unexpected secret use %+v", use)
+		}
+	}
+
+	// Each field is scrubbed on its own; the unused field is never resolved
+	out, err := vlt.SecretProvider().HandleChunk([]byte("host=db.internal pw=hunter2-pw key=unused-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "host=" + ids[0] + " pw=" + ids[1] + " key=unused-key"
+	if string(out) != want {
+		t.Errorf("scrubbed = %q, want %q", out, want)
+	}
+}
+
+// TestFieldAccess_WholeObjectScrubsLeaves verifies a whole object value
+// scrubs each leaf with the DisplayID it gets as a field
+func TestFieldAccess_WholeObjectScrubsLeaves(t *testing.T) {
+	plan, vlt, err := planFieldsSource(t, `echo @var.config
+echo @var.config.db.password`)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	commands := planCommands(plan.Steps)
+	fieldID := displayIDPattern.FindString(commands[1])
+
+	out, err := vlt.SecretProvider().HandleChunk([]byte("password is hunter2-pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "password is "+fieldID {
+		t.Errorf("scrubbed = %q, want the field's DisplayID %s", out, fieldID)
+	}
+}
+
+func TestFieldAccess_Forms(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string // Commands with DisplayIDs replaced by ID
+	}{
+		{"array index", `echo @var.config.hosts.1`, []string{"echo ID"}},
+		{"text after a string value", `cat @var.FILE.txt`, []string{"cat ID.txt"}},
+		{"text after a field", `ping @var.config.db.host.`, []string{"ping ID."}},
+		{"condition", `if @var.config.enabled { echo on }`, []string{"echo on"}},
+		{"loop collection", `for h in @var.config.hosts { echo @var.h }`, []string{"echo ID", "echo ID"}},
+		{"variable from a field", `var DB = @var.config.db
+echo @var.DB.host`, []string{"echo ID"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, _, err := planFieldsSource(t, tt.body)
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+			var got []string
+			for _, cmd := range planCommands(plan.Steps) {
+				got = append(got, displayIDPattern.ReplaceAllString(cmd, "ID"))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("commands = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFieldAccess_SameValueSameDisplayID verifies a field and a variable
+// holding the same value share a DisplayID, like any repeated value
+func TestFieldAccess_SameValueSameDisplayID(t *testing.T) {
+	plan, _, err := planFieldsSource(t, `var HOST = "db.internal"
+echo @var.config.db.host @var.HOST`)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	ids := displayIDPattern.FindAllString(planCommands(plan.Steps)[0], -1)
+	if len(ids) != 2 || ids[0] != ids[1] {
+		t.Errorf("Expected the same DisplayID twice, got %v", ids)
+	}
+}
+
+func TestFieldAccess_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"unknown field", `echo @var.config.db.pasword`, "@var.config.db.pasword: no such field"},
+		{"index out of range", `echo @var.config.hosts.5`, "@var.config.hosts.5: index out of range (2 items)"},
+		{"index not a number", `echo @var.config.hosts.first`, "@var.config.hosts.first: array index must be an integer"},
+		{"field of a string in a condition", `if @var.FILE.txt { echo on }`, `@var.FILE: cannot access field "txt"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := planFieldsSource(t, tt.body)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected error containing %q, got %v", tt.want, err)
+			}
+			if strings.Contains(err.Error(), "hunter2-pw") || strings.Contains(err.Error(), "report") {
+				t.Errorf("error reveals a value: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,607 @@
+package planner
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opal-lang/opal/core/invariant"
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/core/types"
+	"github.com/opal-lang/opal/runtime/lexer"
+	"github.com/opal-lang/opal/runtime/parser"
+)
+
+// paramTypes are the supported function parameter types.
+var paramTypes = []string{"String", "Int", "Float", "Bool", "Duration", "Array", "Map"}
+
+// funcParam is a declared function parameter.
+type funcParam struct {
+	name       string
+	typeName   string // Declared or inferred type ("" = untyped, accepts any value)
+	defaultPos int    // Event position of the default value (-1 = required)
+}
+
+// funcDef is a top-level function definition.
+type funcDef struct {
+	name   string
+	pos    int // Event position of OPEN Function
+	params []funcParam
+}
+
+// callArg is one argument of a function call.
+type callArg struct {
+	name  string // Parameter name ("" = positional)
+	pos   int    // Event position of the value (-1 = value holds a CLI argument)
+	value string // CLI argument text
+}
+
+// lookupFunction returns the top-level function called name.
+// The function index is built on first use.
+func (p *planner) lookupFunction(name string) (*funcDef, error) {
+	if p.functions == nil {
+		if err := p.indexFunctions(); err != nil {
+			return nil, err
+		}
+	}
+
+	def, ok := p.functions[name]
+	if !ok {
+		var names []string
+		for fn := range p.functions {
+			names = append(names, fn)
+		}
+		suggestion := fmt.Sprintf("Define the function with: fun %s = <command>", name)
+		if closest := findClosestMatch(name, names); closest != "" {
+			suggestion = fmt.Sprintf("Did you mean '%s'?", closest)
+		}
+		return nil, &PlanError{
+			Message:     fmt.Sprintf("function not found: %s", name),
+			Context:     fmt.Sprintf("calling @cmd.%s", name),
+			EventPos:    p.pos,
+			TotalEvents: len(p.events),
+			Suggestion:  suggestion,
+		}
+	}
+	return def, nil
+}
+
+// indexFunctions records every top-level function and its parameters.
+func (p *planner) indexFunctions() error {
+	p.functions = make(map[string]*funcDef)
+
+	depth := 0
+	for pos, evt := range p.events {
+		switch evt.Kind {
+		case parser.EventOpen:
+			if depth == 1 && parser.NodeKind(evt.Data) == parser.NodeFunction {
+				def, err := p.parseFunctionHeader(pos)
+				if err != nil {
+					return err
+				}
+				p.functions[def.name] = def
+			}
+			depth++
+		case parser.EventClose:
+			depth--
+		}
+	}
+	return nil
+}
+
+// parseFunctionHeader parses a function's name and parameter list.
+//
+// Event structure:
+//
+//	OPEN Function, TOKEN(fun), TOKEN(name), [OPEN ParamList, TOKEN((),
+//	  OPEN Param, TOKEN(name), [OPEN TypeAnnotation, TOKEN(:), TOKEN(Type), CLOSE TypeAnnotation],
+//	  [OPEN DefaultValue, TOKEN(=), <value>, CLOSE DefaultValue], CLOSE Param, ...
+//	TOKEN()), CLOSE ParamList], <body>
+func (p *planner) parseFunctionHeader(pos int) (*funcDef, error) {
+	invariant.Precondition(p.events[pos].Kind == parser.EventOpen &&
+		parser.NodeKind(p.events[pos].Data) == parser.NodeFunction,
+		"parseFunctionHeader must start at OPEN Function")
+
+	def := &funcDef{pos: pos}
+	pos += 2 // Skip OPEN Function and TOKEN(fun)
+	if pos < len(p.events) && p.events[pos].Kind == parser.EventToken {
+		def.name = string(p.tokens[p.events[pos].Data].Text)
+		pos++
+	}
+
+	if pos >= len(p.events) || p.events[pos].Kind != parser.EventOpen ||
+		parser.NodeKind(p.events[pos].Data) != parser.NodeParamList {
+		return def, nil
+	}
+
+	for depth := 0; pos < len(p.events); pos++ {
+		evt := p.events[pos]
+		if evt.Kind == parser.EventClose {
+			depth--
+			if depth == 0 {
+				break // CLOSE ParamList
+			}
+			continue
+		}
+		if evt.Kind != parser.EventOpen {
+			continue
+		}
+		depth++
+		if depth != 2 || parser.NodeKind(evt.Data) != parser.NodeParam {
+			continue
+		}
+
+		param, err := p.parseFunctionParam(def.name, pos)
+		if err != nil {
+			return nil, err
+		}
+		def.params = append(def.params, param)
+	}
+
+	return def, nil
+}
+
+// parseFunctionParam parses the parameter at OPEN Param pos.
+func (p *planner) parseFunctionParam(funcName string, pos int) (funcParam, error) {
+	param := funcParam{defaultPos: -1}
+	pos++ // Move past OPEN Param
+	if p.events[pos].Kind == parser.EventToken {
+		param.name = string(p.tokens[p.events[pos].Data].Text)
+		pos++
+	}
+
+	if p.events[pos].Kind == parser.EventOpen && parser.NodeKind(p.events[pos].Data) == parser.NodeTypeAnnotation {
+		pos += 2 // Skip OPEN TypeAnnotation and TOKEN(:)
+		if p.events[pos].Kind == parser.EventToken {
+			param.typeName = string(p.tokens[p.events[pos].Data].Text)
+			pos++
+		}
+		if !isParamType(param.typeName) {
+			return funcParam{}, &PlanError{
+				Message:     fmt.Sprintf("unknown type '%s' for parameter '%s' of function %s", param.typeName, param.name, funcName),
+				Context:     fmt.Sprintf("parsing function %s", funcName),
+				EventPos:    pos,
+				TotalEvents: len(p.events),
+				Suggestion:  fmt.Sprintf("Use one of: %s", strings.Join(paramTypes, ", ")),
+				Example:     fmt.Sprintf("fun %s(%s: String)", funcName, param.name),
+			}
+		}
+		pos++ // Move past CLOSE TypeAnnotation
+	}
+
+	if p.events[pos].Kind == parser.EventOpen && parser.NodeKind(p.events[pos].Data) == parser.NodeDefaultValue {
+		param.defaultPos = pos + 2 // Skip OPEN DefaultValue and TOKEN(=)
+		if param.typeName == "" {
+			param.typeName = p.inferParamType(param.defaultPos)
+		}
+	}
+
+	return param, nil
+}
+
+// isParamType reports whether name is a supported parameter type.
+func isParamType(name string) bool {
+	for _, t := range paramTypes {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// inferParamType returns the type of the default value literal at pos.
+// Value decorators have no static type, so their parameters stay untyped.
+func (p *planner) inferParamType(pos int) string {
+	evt := p.events[pos]
+	if evt.Kind == parser.EventOpen {
+		switch parser.NodeKind(evt.Data) {
+		case parser.NodeArrayLiteral:
+			return "Array"
+		case parser.NodeObjectLiteral:
+			return "Map"
+		}
+		return ""
+	}
+	if evt.Kind != parser.EventToken {
+		return ""
+	}
+	switch p.tokens[evt.Data].Type {
+	case lexer.STRING:
+		return "String"
+	case lexer.INTEGER:
+		return "Int"
+	case lexer.FLOAT, lexer.SCIENTIFIC:
+		return "Float"
+	case lexer.BOOLEAN:
+		return "Bool"
+	case lexer.DURATION:
+		return "Duration"
+	}
+	return ""
+}
+
+// isCmdCall reports whether the step at p.pos (STEP_ENTER) is a @cmd call.
+func (p *planner) isCmdCall() bool {
+	pos := p.pos + 1
+	return pos+2 < len(p.events) &&
+		p.events[pos].Kind == parser.EventOpen &&
+		parser.NodeKind(p.events[pos].Data) == parser.NodeDecorator &&
+		p.events[pos+2].Kind == parser.EventToken &&
+		string(p.tokens[p.events[pos+2].Data].Text) == "cmd"
+}
+
+// planCmdCall expands a @cmd.<name>(args...) call into the called function's
+// body. Expects p.pos at STEP_ENTER, leaves position after STEP_EXIT.
+//
+// Event structure:
+//
+//	STEP_ENTER, OPEN Decorator, TOKEN(@), TOKEN(cmd), TOKEN(.), TOKEN(name),
+//	[OPEN ParamList, TOKEN((), OPEN Param, [TOKEN(param), TOKEN(=)], <value>, CLOSE Param, ..., TOKEN()), CLOSE ParamList],
+//	CLOSE Decorator, STEP_EXIT
+//
+// The call becomes one step holding a "cmd" GroupNode. Arguments are
+// recorded as use sites at "step-N/params/<param>"; the body runs in scope
+// "step-N/@cmd[N]" with each parameter bound as a variable, so parameters
+// and variables declared in the body don't leak into the caller.
+func (p *planner) planCmdCall() (planfmt.Step, error) {
+	startPos := p.pos
+	p.pos++ // Move past STEP_ENTER
+	p.pos++ // Move past OPEN Decorator
+
+	// Function name is the last name token (@cmd.name)
+	var name string
+	for p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
+		tok := p.tokens[p.events[p.pos].Data]
+		if tok.Type != lexer.AT && tok.Type != lexer.DOT {
+			name = string(tok.Text)
+		}
+		p.pos++
+	}
+	if name == "cmd" {
+		return planfmt.Step{}, &PlanError{
+			Message:     "@cmd requires a function name",
+			Context:     "planning @cmd call",
+			EventPos:    startPos,
+			TotalEvents: len(p.events),
+			Example:     "@cmd.build_module(module=\"cli\")",
+		}
+	}
+
+	var args []callArg
+	if p.atOpen(parser.NodeParamList) {
+		args = p.parseCallArgs()
+	}
+
+	// Move past CLOSE Decorator and STEP_EXIT
+	p.skipToClose(parser.NodeDecorator)
+	endPos := p.pos
+	if endPos < len(p.events) && p.events[endPos].Kind == parser.EventStepExit {
+		endPos++
+	}
+
+	def, err := p.lookupFunction(name)
+	if err != nil {
+		return planfmt.Step{}, err
+	}
+	for i, caller := range p.callStack {
+		if caller == name {
+			cycle := append(append([]string{}, p.callStack[i:]...), name)
+			return planfmt.Step{}, &PlanError{
+				Message:     fmt.Sprintf("recursive @cmd call: %s", strings.Join(cycle, " -> ")),
+				Context:     fmt.Sprintf("calling @cmd.%s", name),
+				EventPos:    startPos,
+				TotalEvents: len(p.events),
+				Suggestion:  "Function calls must not form a cycle; move the shared commands into a separate function",
+			}
+		}
+	}
+
+	id := p.nextStepID()
+	p.vault.Push(fmt.Sprintf("step-%d", id))
+	defer p.vault.Pop()
+
+	// Arguments resolve in the caller's scope, parameters bind in the callee's
+	values, err := p.evalCallArgs(def, args)
+	if err != nil {
+		return planfmt.Step{}, err
+	}
+
+	p.vault.PushAt("@cmd", int(id))
+	defer p.vault.Pop()
+
+	bound := p.bindParams(def, values)
+
+	p.callStack = append(p.callStack, name)
+	defer func() { p.callStack = p.callStack[:len(p.callStack)-1] }()
+
+	if p.config.Debug >= DebugDetailed {
+		p.recordDebugEvent("cmd_expand", fmt.Sprintf("name=%s args=%d", name, len(args)))
+	}
+
+	p.pos = def.pos
+	steps, err := p.planFunctionBody(name)
+	if err != nil {
+		return planfmt.Step{}, err
+	}
+	p.pos = endPos
+
+	return planfmt.Step{
+		ID: id,
+		Tree: &planfmt.GroupNode{
+			Kind:  "cmd",
+			Label: fmt.Sprintf("@cmd.%s(%s)", name, strings.Join(bound, ", ")),
+			Steps: steps,
+		},
+	}, nil
+}
+
+// parseCallArgs records the arguments of a call's parameter list.
+// Values are evaluated later, once they are matched to parameters.
+// Expects p.pos at OPEN ParamList, leaves position after CLOSE ParamList.
+func (p *planner) parseCallArgs() []callArg {
+	p.pos++ // Move past OPEN ParamList
+
+	var args []callArg
+	for p.pos < len(p.events) {
+		if p.events[p.pos].Kind == parser.EventClose && parser.NodeKind(p.events[p.pos].Data) == parser.NodeParamList {
+			p.pos++ // Move past CLOSE ParamList
+			break
+		}
+		if !p.atOpen(parser.NodeParam) {
+			p.pos++ // TOKEN((), TOKEN(,), TOKEN())
+			continue
+		}
+
+		p.pos++ // Move past OPEN Param
+		arg := callArg{}
+		if p.pos+1 < len(p.events) && p.events[p.pos].Kind == parser.EventToken &&
+			p.events[p.pos+1].Kind == parser.EventToken &&
+			p.tokens[p.events[p.pos+1].Data].Type == lexer.EQUALS {
+			arg.name = string(p.tokens[p.events[p.pos].Data].Text)
+			p.pos += 2 // Move past name and = tokens
+		}
+		arg.pos = p.pos
+		args = append(args, arg)
+		p.skipToClose(parser.NodeParam)
+	}
+	return args
+}
+
+// evalCallArgs matches args to def's parameters and resolves each value:
+// the argument if given, else the default. Positional arguments fill the
+// parameters not given by name, in declaration order.
+// Returns one value per parameter.
+func (p *planner) evalCallArgs(def *funcDef, args []callArg) ([]any, error) {
+	byName := make(map[string]callArg)
+	var positional []callArg
+	for _, arg := range args {
+		if arg.name == "" {
+			positional = append(positional, arg)
+			continue
+		}
+		if !def.hasParam(arg.name) {
+			var names []string
+			for _, param := range def.params {
+				names = append(names, param.name)
+			}
+			suggestion := fmt.Sprintf("%s takes no parameters", def.name)
+			if len(names) > 0 {
+				suggestion = fmt.Sprintf("Parameters of %s: %s", def.name, strings.Join(names, ", "))
+			}
+			return nil, &PlanError{
+				Message:     fmt.Sprintf("unknown parameter '%s' for function %s", arg.name, def.name),
+				Context:     fmt.Sprintf("calling %s", def.name),
+				EventPos:    p.pos,
+				TotalEvents: len(p.events),
+				Suggestion:  suggestion,
+			}
+		}
+		if _, dup := byName[arg.name]; dup {
+			return nil, fmt.Errorf("duplicate parameter '%s' for function %s", arg.name, def.name)
+		}
+		byName[arg.name] = arg
+	}
+
+	for _, param := range def.params {
+		if len(positional) == 0 {
+			break
+		}
+		if _, ok := byName[param.name]; !ok {
+			byName[param.name] = positional[0]
+			positional = positional[1:]
+		}
+	}
+	if len(positional) > 0 {
+		return nil, fmt.Errorf("too many arguments for function %s: it takes %d parameters", def.name, len(def.params))
+	}
+
+	savedPos := p.pos
+	defer func() { p.pos = savedPos }()
+
+	values := make([]any, len(def.params))
+	for i, param := range def.params {
+		arg, given := byName[param.name]
+		var value any
+		var err error
+		switch {
+		case given && arg.pos < 0:
+			value, err = cliArgValue(param, arg.value)
+		case given:
+			p.pos = arg.pos
+			value, err = p.evalParamValue(param.name)
+		case param.defaultPos >= 0:
+			p.pos = param.defaultPos
+			value, err = p.evalParamValue(param.name)
+		default:
+			return nil, &PlanError{
+				Message:     fmt.Sprintf("missing required parameter '%s' for function %s", param.name, def.name),
+				Context:     fmt.Sprintf("calling %s", def.name),
+				EventPos:    p.pos,
+				TotalEvents: len(p.events),
+				Example:     fmt.Sprintf("@cmd.%s(%s=...) or opal %s --arg %s=...", def.name, param.name, def.name, param.name),
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := checkParamType(def.name, param, value); err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// hasParam reports whether the function declares a parameter called name.
+func (def *funcDef) hasParam(name string) bool {
+	for _, param := range def.params {
+		if param.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// evalParamValue resolves an argument or default value at p.pos: a literal,
+// an array or object literal, or a value decorator (@var.NAME, @env.HOME).
+// References are recorded as use sites under paramName.
+func (p *planner) evalParamValue(paramName string) (any, error) {
+	switch {
+	case p.atOpen(parser.NodeArrayLiteral), p.atOpen(parser.NodeObjectLiteral):
+		// The literal parsers also consume the enclosing CLOSE, so find the
+		// end first and restore it
+		kind := parser.NodeKind(p.events[p.pos].Data)
+		start := p.pos
+		p.pos++
+		p.skipToClose(kind)
+		end := p.pos
+		p.pos = start
+		value, err := p.parseVarValue(paramName)
+		p.pos = end
+		return value, err
+
+	case p.atOpen(parser.NodeDecorator):
+		startPos := p.pos
+		parts := p.parseDecoratorRef()
+		if len(parts) == 2 && parts[0] == "var" {
+			return p.resolveVarReference(parts[1], paramName)
+		}
+		return p.resolveDecoratorReference(parts, paramName, startPos)
+
+	case p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken:
+		tok := p.tokens[p.events[p.pos].Data]
+		p.pos++
+		if tok.Type == lexer.STRING {
+			return unquote(string(tok.Text)), nil
+		}
+		// Numbers, booleans and durations keep their literal text, like var declarations
+		return string(tok.Text), nil
+	}
+
+	return nil, &PlanError{
+		Message:     fmt.Sprintf("unsupported value for parameter '%s'", paramName),
+		Context:     "evaluating function arguments",
+		EventPos:    p.pos,
+		TotalEvents: len(p.events),
+		Suggestion:  "Use a literal, an array or object literal, or a value decorator",
+	}
+}
+
+// cliArgValue converts a command-line argument for param. Array and Map
+// parameters take JSON; everything else is the argument text.
+func cliArgValue(param funcParam, text string) (any, error) {
+	switch param.typeName {
+	case "Array":
+		var value []any
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("parameter '%s' must be a JSON array", param.name)
+		}
+		return value, nil
+	case "Map":
+		var value map[string]any
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("parameter '%s' must be a JSON object", param.name)
+		}
+		return value, nil
+	}
+	return text, nil
+}
+
+// checkParamType validates value against param's type. Scalars arrive as
+// their literal text (or @env strings), so they are checked by parsing.
+// The error never includes the value itself - it may be a secret.
+func checkParamType(funcName string, param funcParam, value any) error {
+	if param.typeName == "" {
+		return nil
+	}
+
+	ok := false
+	switch v := value.(type) {
+	case []any:
+		ok = param.typeName == "Array"
+	case map[string]any:
+		ok = param.typeName == "Map"
+	case bool:
+		ok = param.typeName == "Bool" || param.typeName == "String"
+	case string:
+		switch param.typeName {
+		case "String":
+			ok = true
+		case "Int":
+			_, err := strconv.ParseInt(v, 10, 64)
+			ok = err == nil
+		case "Float":
+			_, err := strconv.ParseFloat(v, 64)
+			ok = err == nil
+		case "Bool":
+			ok = v == "true" || v == "false"
+		case "Duration":
+			_, err := types.ParseDuration(v)
+			ok = err == nil
+		}
+	default:
+		ok = param.typeName == "String"
+	}
+
+	if !ok {
+		return fmt.Errorf("parameter '%s' of function %s must be %s", param.name, funcName, param.typeName)
+	}
+	return nil
+}
+
+// bindParams declares each parameter as a variable in the current scope.
+// Returns "name=DisplayID" labels in declaration order; values never appear
+// in the plan.
+func (p *planner) bindParams(def *funcDef, values []any) []string {
+	labels := make([]string, len(def.params))
+	for i, param := range def.params {
+		exprID := p.vault.DeclareVariable(param.name, literalRaw(values[i]))
+		p.vault.StoreUnresolvedValue(exprID, values[i])
+		p.vault.MarkTouched(exprID)
+		p.vault.ResolveAllTouched()
+		labels[i] = fmt.Sprintf("%s=%s", param.name, p.vault.GetDisplayID(exprID))
+	}
+	if len(def.params) > 0 {
+		p.recordDecoratorResolution("@var")
+	}
+	return labels
+}
+
+// targetArgs converts the configured command-line arguments to call arguments.
+func (p *planner) targetArgs() []callArg {
+	var args []callArg
+	for _, value := range p.config.Args {
+		args = append(args, callArg{pos: -1, value: value})
+	}
+	names := make([]string, 0, len(p.config.NamedArgs))
+	for name := range p.config.NamedArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, callArg{name: name, pos: -1, value: p.config.NamedArgs[name]})
+	}
+	return args
+}
@@ -0,0 +1,279 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/runtime/parser"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+// planWithArgs plans source for target with command-line arguments.
+func planWithArgs(t *testing.T, source, target string, args []string, named map[string]string) (*planfmt.Plan, error) {
+	t.Helper()
+	tree := parser.ParseString(source)
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+	return Plan(tree.Events, tree.Tokens, Config{
+		Target:    target,
+		Args:      args,
+		NamedArgs: named,
+		Vault:     vault.NewWithPlanKey(make([]byte, 32)),
+	})
+}
+
+// cmdGroup asserts step is a "cmd" group and returns it.
+func cmdGroup(t *testing.T, step planfmt.Step) *planfmt.GroupNode {
+	t.Helper()
+	group, ok := step.Tree.(*planfmt.GroupNode)
+	if !ok || group.Kind != "cmd" {
+		t.Fatalf("Expected cmd GroupNode, got %#v", step.Tree)
+	}
+	return group
+}
+
+func TestCmd_ExpandsCalleeInLoop(t *testing.T) {
+	plan, err := planLoopSource(t, `
+var MODULES = ["cli", "runtime"]
+
+fun test_module(module) {
+    echo "testing @var.module"
+}
+
+fun test {
+    for module in @var.MODULES {
+        @cmd.test_module(module=@var.module)
+    }
+}
+`, "test")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	groups := loopGroups(t, plan.Steps)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 iterations, got %d", len(groups))
+	}
+	for i, group := range groups {
+		if len(group.Steps) != 1 {
+			t.Fatalf("iteration %d: expected 1 step, got %d", i, len(group.Steps))
+		}
+		call := cmdGroup(t, group.Steps[0])
+		if !strings.HasPrefix(call.Label, "@cmd.test_module(module=opal:") {
+			t.Errorf("iteration %d: unexpected label %q", i, call.Label)
+		}
+		if strings.Contains(call.Label, "cli") || strings.Contains(call.Label, "runtime") {
+			t.Errorf("iteration %d: label leaks the argument value: %q", i, call.Label)
+		}
+		got := getCommandArg(call.Steps[0].Tree, "command")
+		if !strings.HasPrefix(got, "echo \"testing opal:") {
+			t.Errorf("iteration %d: expected interpolated DisplayID, got %q", i, got)
+		}
+	}
+
+	// Each call binds its own value, so the commands differ
+	first := getCommandArg(cmdGroup(t, groups[0].Steps[0]).Steps[0].Tree, "command")
+	second := getCommandArg(cmdGroup(t, groups[1].Steps[0]).Steps[0].Tree, "command")
+	if first == second {
+		t.Errorf("Expected different arguments per iteration, both got %q", first)
+	}
+}
+
+func TestCmd_SecretUseSites(t *testing.T) {
+	plan, err := planLoopSource(t, `
+var TOKEN = "s3cr3t-value"
+fun push(token) = echo "@var.token"
+@cmd.push(token=@var.TOKEN)
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	sites := make(map[string]bool)
+	for _, use := range plan.SecretUses {
+		sites[use.Site] = true
+	}
+	for _, want := range []string{
+		"root/step-1/params/token",
+		"root/step-1/@cmd[1]/step-2/params/command",
+	} {
+		if !sites[want] {
+			t.Errorf("Expected use site %s, got %v", want, sites)
+		}
+	}
+}
+
+func TestCmd_Arguments(t *testing.T) {
+	const funcs = `
+fun deploy(env: String, replicas: Int = 3, timeout: Duration = 30s, verbose = false) {
+    echo "@var.env @var.replicas @var.timeout @var.verbose"
+}
+`
+	tests := []struct {
+		name    string
+		call    string
+		wantErr string
+	}{
+		{name: "named", call: `@cmd.deploy(env="prod", replicas=5)`},
+		{name: "positional", call: `@cmd.deploy("prod", 5, 1m)`},
+		{name: "mixed", call: `@cmd.deploy("prod", replicas=5)`},
+		{name: "missing required", call: `@cmd.deploy(replicas=5)`, wantErr: "missing required parameter 'env' for function deploy"},
+		{name: "unknown parameter", call: `@cmd.deploy(env="prod", region="eu")`, wantErr: "unknown parameter 'region' for function deploy"},
+		{name: "too many", call: `@cmd.deploy("prod", 5, 1m, true, "x")`, wantErr: "too many arguments for function deploy"},
+		{name: "bad int", call: `@cmd.deploy(env="prod", replicas="many")`, wantErr: "parameter 'replicas' of function deploy must be Int"},
+		{name: "bad duration", call: `@cmd.deploy(env="prod", timeout=5)`, wantErr: "parameter 'timeout' of function deploy must be Duration"},
+		{name: "inferred bool", call: `@cmd.deploy(env="prod", verbose="yes")`, wantErr: "parameter 'verbose' of function deploy must be Bool"},
+		{name: "unknown function", call: `@cmd.deplyo(env="prod")`, wantErr: "function not found: deplyo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planLoopSource(t, funcs+tt.call, "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+			call := cmdGroup(t, plan.Steps[0])
+			if !strings.HasPrefix(call.Label, "@cmd.deploy(env=opal:") || strings.Count(call.Label, "=opal:") != 4 {
+				t.Errorf("Expected all four parameters bound, got %q", call.Label)
+			}
+		})
+	}
+}
+
+func TestCmd_TypeErrorHidesValue(t *testing.T) {
+	_, err := planLoopSource(t, `
+var REPLICAS = "s3cr3t"
+fun scale(replicas: Int) = echo "@var.replicas"
+@cmd.scale(replicas=@var.REPLICAS)
+`, "")
+	if err == nil {
+		t.Fatal("Expected type error")
+	}
+	if strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("Error leaks the argument value: %v", err)
+	}
+}
+
+func TestCmd_RecursionIsAnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{
+			name:    "self",
+			source:  "fun a { @cmd.a() }",
+			wantErr: "recursive @cmd call: a -> a",
+		},
+		{
+			name: "cycle",
+			source: `
+fun a { @cmd.b() }
+fun b { @cmd.c() }
+fun c { @cmd.a() }
+`,
+			wantErr: "recursive @cmd call: a -> b -> c -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planLoopSource(t, tt.source, "a")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCmd_DiamondIsNotACycle(t *testing.T) {
+	plan, err := planLoopSource(t, `
+fun base = echo "base"
+fun left { @cmd.base() }
+fun right { @cmd.base() }
+fun all {
+    @cmd.left()
+    @cmd.right()
+}
+`, "all")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(plan.Steps))
+	}
+}
+
+func TestCmd_ParametersAreScoped(t *testing.T) {
+	_, err := planLoopSource(t, `
+fun greet(name) = echo "@var.name"
+@cmd.greet(name="world")
+echo "@var.name"
+`, "")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected 'not found' error for a parameter used after the call, got %v", err)
+	}
+}
+
+func TestTargetArguments(t *testing.T) {
+	const source = `
+fun deploy(env: String, replicas: Int = 3) {
+    echo "@var.env @var.replicas"
+}
+`
+	tests := []struct {
+		name    string
+		args    []string
+		named   map[string]string
+		wantErr string
+	}{
+		{name: "named", named: map[string]string{"env": "prod"}},
+		{name: "positional", args: []string{"prod", "5"}},
+		{name: "positional and named", args: []string{"prod"}, named: map[string]string{"replicas": "5"}},
+		{name: "missing", wantErr: "missing required parameter 'env' for function deploy"},
+		{name: "unknown", named: map[string]string{"env": "prod", "zone": "a"}, wantErr: "unknown parameter 'zone'"},
+		{name: "wrong type", named: map[string]string{"env": "prod", "replicas": "five"}, wantErr: "must be Int"},
+		{name: "too many", args: []string{"prod", "5", "x"}, wantErr: "too many arguments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planWithArgs(t, source, "deploy", tt.args, tt.named)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+			got := getCommandArg(plan.Steps[0].Tree, "command")
+			if strings.Count(got, "opal:") != 2 {
+				t.Errorf("Expected both parameters interpolated, got %q", got)
+			}
+		})
+	}
+}
+
+func TestDecoratorPositionalParams(t *testing.T) {
+	plan, err := planLoopSource(t, `@retry(5, delay=2s) { echo "a" }`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	cmd, ok := plan.Steps[0].Tree.(*planfmt.CommandNode)
+	if !ok {
+		t.Fatalf("Expected CommandNode, got %T", plan.Steps[0].Tree)
+	}
+	if len(cmd.Args) != 2 || cmd.Args[1].Key != "times" || cmd.Args[1].Val.Int != 5 {
+		t.Errorf("Expected positional 5 bound to times, got %+v", cmd.Args)
+	}
+}
@@ -0,0 +1,376 @@
+package planner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/opal-lang/opal/core/invariant"
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/runtime/lexer"
+	"github.com/opal-lang/opal/runtime/parser"
+)
+
+// maxLoopIterations bounds plan-time loop unrolling so a typo like
+// 1...1000000 fails fast instead of producing an unusable plan.
+const maxLoopIterations = 10000
+
+// planFor unrolls a for loop at plan time.
+// Expects p.pos at OPEN For, leaves position after CLOSE For.
+//
+// Event structure:
+//
+//	OPEN For, TOKEN(for), TOKEN(name), TOKEN(in), <collection>, OPEN Block, ..., CLOSE Block, CLOSE For
+//
+// where <collection> is TOKEN(identifier), a Decorator (@var.NAME), or a Range (1...10).
+//
+// Each iteration becomes one step holding a GroupNode, so the plan shows exactly
+// which iterations run. The loop variable is declared in the iteration's own
+// scope ("step-N/@for[N]"), so it is invisible after the loop and each
+// iteration's commands capture their own value.
+func (p *planner) planFor() ([]planfmt.Step, error) {
+	// PRECONDITION: Must be at OPEN For
+	invariant.Precondition(p.pos < len(p.events) &&
+		p.events[p.pos].Kind == parser.EventOpen &&
+		parser.NodeKind(p.events[p.pos].Data) == parser.NodeFor,
+		"planFor must start at OPEN For")
+
+	startPos := p.pos
+	p.pos++ // Move past OPEN For
+	p.pos++ // Skip TOKEN(for)
+
+	// Loop variable
+	if p.pos >= len(p.events) || p.events[p.pos].Kind != parser.EventToken {
+		return nil, &PlanError{
+			Message:     "expected loop variable after 'for'",
+			Context:     "planning for loop",
+			EventPos:    p.pos,
+			TotalEvents: len(p.events),
+			Example:     "for item in @var.ITEMS { echo @var.item }",
+		}
+	}
+	varName := string(p.tokens[p.events[p.pos].Data].Text)
+	p.pos++
+	p.pos++ // Skip TOKEN(in)
+
+	source, items, err := p.resolveForCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.config.Debug >= DebugDetailed {
+		p.recordDebugEvent("for_unroll", fmt.Sprintf("var=%s collection=%s iterations=%d", varName, source, len(items)))
+	}
+
+	// Find the loop body
+	for p.pos < len(p.events) && (p.events[p.pos].Kind != parser.EventOpen ||
+		parser.NodeKind(p.events[p.pos].Data) != parser.NodeBlock) {
+		p.pos++
+	}
+	if p.pos >= len(p.events) {
+		return nil, &PlanError{
+			Message:     "for loop has no body",
+			Context:     fmt.Sprintf("planning for %s in %s", varName, source),
+			EventPos:    startPos,
+			TotalEvents: len(p.events),
+		}
+	}
+	bodyStart := p.pos + 1 // After OPEN Block
+
+	var steps []planfmt.Step
+	for i, item := range items {
+		p.pos = bodyStart
+		step, err := p.planForIteration(varName, source, i, item)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+
+	// Empty collections never plan the body - skip it
+	if len(items) == 0 {
+		p.pos = bodyStart
+		p.skipToClose(parser.NodeBlock)
+	}
+
+	// Move past CLOSE For
+	p.skipToClose(parser.NodeFor)
+
+	return steps, nil
+}
+
+// planForIteration plans one iteration of a loop body with varName bound to item.
+// Expects p.pos just after OPEN Block, leaves position after CLOSE Block.
+func (p *planner) planForIteration(varName, source string, index int, item any) (planfmt.Step, error) {
+	id := p.nextStepID()
+	p.vault.Push(fmt.Sprintf("step-%d", id))
+	p.vault.PushAt("@for", int(id))
+	defer func() {
+		p.vault.Pop()
+		p.vault.Pop()
+	}()
+
+	// Bind the loop variable in the iteration scope. Resolving now gives the
+	// label a DisplayID; the value is never shown in the plan.
+	exprID := p.vault.DeclareVariable(varName, literalRaw(item))
+	p.vault.StoreUnresolvedValue(exprID, item)
+	p.vault.MarkTouched(exprID)
+	p.vault.ResolveAllTouched()
+	p.recordDecoratorResolution("@var")
+
+	steps, err := p.planBlockSteps()
+	if err != nil {
+		return planfmt.Step{}, err
+	}
+
+	return planfmt.Step{
+		ID: id,
+		Tree: &planfmt.GroupNode{
+			Kind:  "for",
+			Label: fmt.Sprintf("for %s in %s [i=%d] %s=%s", varName, source, index, varName, p.vault.GetDisplayID(exprID)),
+			Steps: steps,
+		},
+	}, nil
+}
+
+// resolveForCollection resolves a loop's collection to its items.
+// Returns the collection's source text (for labels) and the items.
+// Expects p.pos at the collection, leaves position after it.
+func (p *planner) resolveForCollection() (string, []any, error) {
+	if p.pos >= len(p.events) {
+		return "", nil, fmt.Errorf("for loop is missing a collection")
+	}
+	evt := p.events[p.pos]
+
+	switch {
+	case evt.Kind == parser.EventToken:
+		// Bare identifier names a variable: for x in ITEMS
+		name := string(p.tokens[evt.Data].Text)
+		p.pos++
+		value, err := p.resolveVarReference(name, "items")
+		if err != nil {
+			return "", nil, err
+		}
+		items, err := loopItems(name, value)
+		return name, items, err
+
+	case evt.Kind == parser.EventOpen && parser.NodeKind(evt.Data) == parser.NodeDecorator:
+		name, source, err := p.parseLoopVarRef()
+		if err != nil {
+			return "", nil, err
+		}
+		value, err := p.resolveVarReference(name, "items")
+		if err != nil {
+			return "", nil, err
+		}
+		items, err := loopItems(source, value)
+		return source, items, err
+
+	case evt.Kind == parser.EventOpen && parser.NodeKind(evt.Data) == parser.NodeRange:
+		return p.resolveForRange()
+	}
+
+	return "", nil, &PlanError{
+		Message:     "unsupported for loop collection",
+		Context:     "planning for loop",
+		EventPos:    p.pos,
+		TotalEvents: len(p.events),
+		Suggestion:  "Iterate over a variable or a range",
+		Example:     "for module in @var.MODULES { ... } or for i in 1...3 { ... }",
+	}
+}
+
+// resolveForRange resolves an inclusive range (1...3, 3...1, 1...@var.N).
+// Expects p.pos at OPEN Range, leaves position after CLOSE Range.
+func (p *planner) resolveForRange() (string, []any, error) {
+	p.pos++ // Move past OPEN Range
+
+	var bounds []int64
+	var parts []string
+	for p.pos < len(p.events) {
+		evt := p.events[p.pos]
+		if evt.Kind == parser.EventClose && parser.NodeKind(evt.Data) == parser.NodeRange {
+			p.pos++
+			break
+		}
+
+		switch {
+		case evt.Kind == parser.EventToken && p.tokens[evt.Data].Type == lexer.INTEGER:
+			text := string(p.tokens[evt.Data].Text)
+			n, err := strconv.ParseInt(text, 10, 64)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid range bound %q: %w", text, err)
+			}
+			bounds = append(bounds, n)
+			parts = append(parts, text)
+			p.pos++
+
+		case evt.Kind == parser.EventOpen && parser.NodeKind(evt.Data) == parser.NodeDecorator:
+			name, source, err := p.parseLoopVarRef()
+			if err != nil {
+				return "", nil, err
+			}
+			value, err := p.resolveVarReference(name, "range")
+			if err != nil {
+				return "", nil, err
+			}
+			n, ok := toInt64(value)
+			if !ok {
+				return "", nil, fmt.Errorf("range bound %s must be an integer, got %T", source, value)
+			}
+			bounds = append(bounds, n)
+			parts = append(parts, source)
+
+		default:
+			p.pos++ // TOKEN(...)
+		}
+	}
+
+	source := strings.Join(parts, "...")
+	if len(bounds) != 2 {
+		return "", nil, fmt.Errorf("range %q must have a start and an end", source)
+	}
+
+	start, end := bounds[0], bounds[1]
+	step := int64(1)
+	if start > end {
+		step = -1
+	}
+	if count := (end-start)*step + 1; count > maxLoopIterations {
+		return "", nil, fmt.Errorf("range %s has %d iterations, more than the limit of %d", source, count, maxLoopIterations)
+	}
+
+	// Items are decimal strings, like number literals in var declarations
+	var items []any
+	for n := start; ; n += step {
+		items = append(items, strconv.FormatInt(n, 10))
+		if n == end {
+			break
+		}
+	}
+	return source, items, nil
+}
+
+// parseLoopVarRef parses a @var.NAME decorator in a loop header.
+// Returns the variable name and its source text.
+// Expects p.pos at OPEN Decorator, leaves position after CLOSE Decorator.
+func (p *planner) parseLoopVarRef() (string, string, error) {
+	startPos := p.pos
+	parts := p.parseDecoratorRef()
+
+	text := "@" + strings.Join(parts, ".")
+	name, ok := strings.CutPrefix(text, "@var.")
+	if !ok || name == "" {
+		return "", "", &PlanError{
+			Message:     fmt.Sprintf("for loops can only iterate over variables, got %s", text),
+			Context:     "planning for loop",
+			EventPos:    startPos,
+			TotalEvents: len(p.events),
+			Suggestion:  "Assign the value to a variable first",
+			Example:     "var ITEMS = [\"a\", \"b\"]\nfor item in @var.ITEMS { ... }",
+		}
+	}
+	return name, text, nil
+}
+
+// parseDecoratorRef parses a decorator reference without parameters
+// (@var.NAME, @env.HOME, @var.config.hosts.0) and returns its name
+// segments (["var", "NAME"]).
+// Expects p.pos at OPEN Decorator, leaves position after CLOSE Decorator.
+func (p *planner) parseDecoratorRef() []string {
+	p.pos++ // Move past OPEN Decorator
+
+	var parts []string
+	for p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
+		tok := p.tokens[p.events[p.pos].Data]
+		if tok.Type != lexer.AT && tok.Type != lexer.DOT {
+			// Array indexes lex as numbers with their dot (".0")
+			parts = append(parts, strings.TrimPrefix(string(tok.Text), "."))
+		}
+		p.pos++
+	}
+	p.skipToClose(parser.NodeDecorator)
+	return parts
+}
+
+// resolveVarReference resolves a variable's value at plan time for
+// control flow (loop collections, conditions). The reference is recorded as
+// a use site (paramName "items", "range", "condition", ...), then read back
+// through the vault's access checks.
+func (p *planner) resolveVarReference(name, paramName string) (any, error) {
+	exprID, err := p.trackVarReference(name, paramName)
+	if err != nil {
+		return nil, err
+	}
+	return p.vault.Access(exprID, paramName)
+}
+
+// trackVarReference records a reference to variable name as a use site of
+// paramName and resolves it, returning the variable's expression ID. A
+// field reference (config.db.host) tracks and returns the field instead.
+func (p *planner) trackVarReference(name, paramName string) (string, error) {
+	varName, path := splitVarPath(name)
+	exprID, err := p.vault.LookupVariable(varName)
+	if err != nil {
+		return "", fmt.Errorf("variable %q not found: %w", varName, err)
+	}
+	if len(path) > 0 {
+		if exprID, err = p.trackFieldPath(varName, exprID, path); err != nil {
+			return "", err
+		}
+	}
+	if err := p.vault.RecordReference(exprID, paramName); err != nil {
+		return "", err
+	}
+	p.vault.MarkTouched(exprID)
+	p.vault.ResolveAllTouched()
+	p.recordDecoratorResolution("@var")
+
+	return exprID, nil
+}
+
+// skipToClose advances past the next CLOSE of kind at the current depth.
+func (p *planner) skipToClose(kind parser.NodeKind) {
+	depth := 0
+	for p.pos < len(p.events) {
+		evt := p.events[p.pos]
+		p.pos++
+		switch evt.Kind {
+		case parser.EventOpen:
+			depth++
+		case parser.EventClose:
+			if depth == 0 && parser.NodeKind(evt.Data) == kind {
+				return
+			}
+			depth--
+		}
+	}
+}
+
+// loopItems returns the items of a resolved collection value.
+func loopItems(source string, value any) ([]any, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot iterate over %s: expected an array, got %T", source, value)
+	}
+	if len(items) > maxLoopIterations {
+		return nil, fmt.Errorf("%s has %d items, more than the limit of %d", source, len(items), maxLoopIterations)
+	}
+	return items, nil
+}
+
+// toInt64 converts an integer variable value. Number literals in var
+// declarations are stored as strings.
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,292 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/runtime/parser"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+// planLoopSource parses and plans source with a deterministic vault.
+func planLoopSource(t *testing.T, source, target string) (*planfmt.Plan, error) {
+	t.Helper()
+	tree := parser.ParseString(source)
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+	return Plan(tree.Events, tree.Tokens, Config{
+		Target: target,
+		Vault:  vault.NewWithPlanKey(make([]byte, 32)),
+	})
+}
+
+// loopGroups asserts every step is a "for" group and returns the groups.
+func loopGroups(t *testing.T, steps []planfmt.Step) []*planfmt.GroupNode {
+	t.Helper()
+	var groups []*planfmt.GroupNode
+	for i, step := range steps {
+		group, ok := step.Tree.(*planfmt.GroupNode)
+		if !ok {
+			t.Fatalf("step %d: expected GroupNode, got %T", i, step.Tree)
+		}
+		if group.Kind != "for" {
+			t.Errorf("step %d: expected kind 'for', got %q", i, group.Kind)
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func TestFor_ArrayUnrollsOneGroupPerItem(t *testing.T) {
+	plan, err := planLoopSource(t, `
+var MODULES = ["cli", "runtime"]
+for module in @var.MODULES {
+    echo "testing @var.module"
+}
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	groups := loopGroups(t, plan.Steps)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 iterations, got %d", len(groups))
+	}
+
+	displayIDs := make(map[string]bool)
+	for i, group := range groups {
+		prefix := fmt.Sprintf("for module in @var.MODULES [i=%d] module=", i)
+		if !strings.HasPrefix(group.Label, prefix) {
+			t.Errorf("iteration %d: expected label prefix %q, got %q", i, prefix, group.Label)
+		}
+		if len(group.Steps) != 1 {
+			t.Fatalf("iteration %d: expected 1 step, got %d", i, len(group.Steps))
+		}
+
+		command := getCommandArg(group.Steps[0].Tree, "command")
+		if strings.Contains(command, "@var.module") || strings.Contains(command, "runtime") {
+			t.Errorf("iteration %d: loop variable not replaced by a DisplayID: %q", i, command)
+		}
+		displayID := strings.TrimPrefix(group.Label, prefix)
+		if !strings.Contains(command, displayID) {
+			t.Errorf("iteration %d: command %q should use the label's DisplayID %s", i, command, displayID)
+		}
+		displayIDs[displayID] = true
+	}
+	if len(displayIDs) != 2 {
+		t.Errorf("Expected distinct DisplayIDs per iteration, got %v", displayIDs)
+	}
+}
+
+func TestFor_StepIDsArePreOrder(t *testing.T) {
+	plan, err := planLoopSource(t, `
+echo "before"
+for i in 1...2 {
+    echo "a"
+    echo "b"
+}
+echo "after"
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	// Groups take their ID before their children, so IDs read top to bottom
+	var ids []uint64
+	var walk func(steps []planfmt.Step)
+	walk = func(steps []planfmt.Step) {
+		for _, step := range steps {
+			ids = append(ids, step.ID)
+			if group, ok := step.Tree.(*planfmt.GroupNode); ok {
+				walk(group.Steps)
+			}
+		}
+	}
+	walk(plan.Steps)
+
+	want := []uint64{1, 2, 3, 4, 5, 6, 7, 8}
+	if len(ids) != len(want) {
+		t.Fatalf("Expected step IDs %v, got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("Expected step IDs %v, got %v", want, ids)
+		}
+	}
+}
+
+func TestFor_Ranges(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string // Iteration label prefixes
+	}{
+		{
+			name:   "ascending",
+			source: `for i in 1...3 { echo "@var.i" }`,
+			want:   []string{"for i in 1...3 [i=0]", "for i in 1...3 [i=1]", "for i in 1...3 [i=2]"},
+		},
+		{
+			name:   "descending",
+			source: `for i in 2...1 { echo "@var.i" }`,
+			want:   []string{"for i in 2...1 [i=0]", "for i in 2...1 [i=1]"},
+		},
+		{
+			name:   "single",
+			source: `for i in 5...5 { echo "@var.i" }`,
+			want:   []string{"for i in 5...5 [i=0]"},
+		},
+		{
+			name: "variable bound",
+			source: `var N = 2
+for i in 1...@var.N { echo "@var.i" }`,
+			want: []string{"for i in 1...@var.N [i=0]", "for i in 1...@var.N [i=1]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planLoopSource(t, tt.source, "")
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+			groups := loopGroups(t, plan.Steps)
+			if len(groups) != len(tt.want) {
+				t.Fatalf("Expected %d iterations, got %d", len(tt.want), len(groups))
+			}
+			for i, group := range groups {
+				if !strings.HasPrefix(group.Label, tt.want[i]) {
+					t.Errorf("iteration %d: expected label prefix %q, got %q", i, tt.want[i], group.Label)
+				}
+			}
+		})
+	}
+}
+
+func TestFor_EmptyCollectionPlansNothing(t *testing.T) {
+	plan, err := planLoopSource(t, `
+var NONE = []
+for x in @var.NONE {
+    echo "@var.x"
+}
+echo "done"
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Steps) != 1 {
+		t.Fatalf("Expected only the step after the loop, got %d steps", len(plan.Steps))
+	}
+	if got := getCommandArg(plan.Steps[0].Tree, "command"); got != `echo "done"` {
+		t.Errorf("Expected the step after the loop, got %q", got)
+	}
+}
+
+func TestFor_LoopVariableScopedToIteration(t *testing.T) {
+	_, err := planLoopSource(t, `
+for i in 1...2 {
+    echo "@var.i"
+}
+echo "@var.i"
+`, "")
+	if err == nil {
+		t.Fatal("Expected error using the loop variable after the loop")
+	}
+	if !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected 'not found' error, got: %v", err)
+	}
+}
+
+func TestFor_NestedInDecoratorBlock(t *testing.T) {
+	plan, err := planLoopSource(t, `
+var HOSTS = ["a", "b", "c"]
+@parallel {
+    for host in @var.HOSTS {
+        echo "@var.host"
+    }
+}
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Steps) != 1 {
+		t.Fatalf("Expected 1 step, got %d", len(plan.Steps))
+	}
+	cmd, ok := plan.Steps[0].Tree.(*planfmt.CommandNode)
+	if !ok || cmd.Decorator != "@parallel" {
+		t.Fatalf("Expected @parallel step, got %#v", plan.Steps[0].Tree)
+	}
+
+	// Each iteration is one branch of the @parallel block
+	if groups := loopGroups(t, cmd.Block); len(groups) != 3 {
+		t.Errorf("Expected 3 branches, got %d", len(groups))
+	}
+}
+
+func TestFor_TopLevelVarInTargetFunction(t *testing.T) {
+	plan, err := planLoopSource(t, `
+var MODULES = ["cli", "runtime"]
+
+fun other {
+    var MODULES = ["ignored"]
+    echo "other"
+}
+
+fun test {
+    for module in @var.MODULES {
+        echo "@var.module"
+    }
+}
+`, "test")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if groups := loopGroups(t, plan.Steps); len(groups) != 2 {
+		t.Errorf("Expected 2 iterations from the top-level MODULES, got %d", len(groups))
+	}
+}
+
+func TestFor_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "not an array",
+			source: `var NAME = "opal"
+for x in @var.NAME { echo "@var.x" }`,
+			want: "expected an array",
+		},
+		{
+			name:   "undeclared collection",
+			source: `for x in @var.MISSING { echo "@var.x" }`,
+			want:   "MISSING",
+		},
+		{
+			name:   "non-var decorator",
+			source: `for x in @env.PATH { echo "@var.x" }`,
+			want:   "can only iterate over variables",
+		},
+		{
+			name:   "too many iterations",
+			source: `for i in 1...100000 { echo "@var.i" }`,
+			want:   "more than the limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planLoopSource(t, tt.source, "")
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/core/types"
+	"github.com/opal-lang/opal/runtime/lexer"
+	"github.com/opal-lang/opal/runtime/parser"
+)
+
+// paramRef is a decorator parameter whose value is a value decorator
+// (@var.module, @env.HOME). It is resolved once positional parameters are
+// named, because the parameter name is part of the recorded use site.
+type paramRef struct {
+	index int // Index into the parameter list
+	pos   int // Event position of the OPEN Decorator value
+}
+
+// resolveParamValues replaces value decorator references and @var
+// interpolations in decorator parameters with DisplayIDs. The executor swaps
+// them back for the values at the recorded use site, so the plan never
+// holds the values themselves. Restores p.pos when done.
+func (p *planner) resolveParamValues(decoratorName string, args []planfmt.Arg, refs []paramRef) error {
+	end := p.pos
+	defer func() { p.pos = end }()
+
+	for _, ref := range refs {
+		arg := &args[ref.index]
+		p.pos = ref.pos
+		exprID, value, err := p.resolveParamReference(arg.Key)
+		if err != nil {
+			return err
+		}
+		if err := p.checkParamValue(decoratorName, arg.Key, value, ref.pos); err != nil {
+			return err
+		}
+		arg.Val = planfmt.Value{Kind: planfmt.ValueString, Str: p.vault.GetDisplayID(exprID)}
+	}
+
+	for i := range args {
+		arg := &args[i]
+		if arg.Val.Kind != planfmt.ValueString || !strings.Contains(arg.Val.Str, "@var.") {
+			continue
+		}
+		ir, err := p.buildInterpolationIR(arg.Val.Str, arg.Key)
+		if err != nil {
+			return err
+		}
+		p.vault.ResolveAllTouched()
+		arg.Val.Str = p.interpolateCommandIR(ir)
+	}
+
+	return nil
+}
+
+// resolveParamReference resolves the value decorator at p.pos as a use site
+// of paramName, returning its expression ID and value.
+func (p *planner) resolveParamReference(paramName string) (string, any, error) {
+	startPos := p.pos
+	parts := p.parseDecoratorRef()
+	if len(parts) >= 2 && parts[0] == "var" {
+		exprID, err := p.trackVarReference(strings.Join(parts[1:], "."), paramName)
+		if err != nil {
+			return "", nil, err
+		}
+		value, err := p.vault.Access(exprID, paramName)
+		return exprID, value, err
+	}
+	return p.trackDecoratorReference(parts, paramName, "resolving decorator parameters", startPos)
+}
+
+// checkParamValue validates a resolved parameter value against the
+// decorator's schema, so values only known at plan time (such as a
+// @workdir path from a variable) fail before anything runs.
+// The error never includes the value itself - it may be a secret.
+func (p *planner) checkParamValue(decoratorName, paramName string, value any, pos int) error {
+	entry, ok := decorator.Global().Lookup(strings.TrimPrefix(decoratorName, "@"))
+	if !ok {
+		return nil
+	}
+	schema, ok := entry.Impl.Descriptor().Schema.Parameters[paramName]
+	if !ok {
+		return nil
+	}
+
+	planErr := func(message string) error {
+		return &PlanError{
+			Message:     message,
+			Context:     fmt.Sprintf("resolving %s parameters", decoratorName),
+			EventPos:    pos,
+			TotalEvents: len(p.events),
+			Suggestion:  schema.Description,
+		}
+	}
+
+	switch value.(type) {
+	case []any, map[string]any:
+		return planErr(fmt.Sprintf("parameter '%s' of %s must be a single value, not a collection", paramName, decoratorName))
+	}
+
+	// Only strings can be checked as-is; other scalars arrive as literal text
+	if schema.Type != types.TypeString && schema.Type != types.TypeEnum {
+		return nil
+	}
+	str := valueText(value)
+	if str == "" && schema.MinLength != nil && *schema.MinLength > 0 {
+		return planErr(fmt.Sprintf("parameter '%s' of %s must not be empty", paramName, decoratorName))
+	}
+	validator := types.NewValidator(types.DefaultValidationConfig())
+	if err := validator.ValidateParams(&schema, str); err != nil {
+		return planErr(fmt.Sprintf("invalid value for parameter '%s' of %s", paramName, decoratorName))
+	}
+	return nil
+}
+
+// parseObjectParam flattens an object parameter (env={HOME: "/srv", TOKEN:
+// @var.token}) into one arg per field, keyed "name.field", so each value gets
+// its own use site and DisplayID. Fields must be literals or value
+// decorators; decorator fields are returned with their event positions.
+// Expects p.pos at OPEN ObjectLiteral, leaves position after its CLOSE.
+func (p *planner) parseObjectParam(decoratorName, paramName string) ([]planfmt.Arg, []int, error) {
+	planErr := func(message string) error {
+		return &PlanError{
+			Message:     message,
+			Context:     fmt.Sprintf("resolving %s parameters", decoratorName),
+			EventPos:    p.pos,
+			TotalEvents: len(p.events),
+			Example:     fmt.Sprintf(`%s(env={VERSION: "3.0", TOKEN: @var.token})`, decoratorName),
+		}
+	}
+	if paramName == "" {
+		return nil, nil, planErr(fmt.Sprintf("object parameters of %s must be named", decoratorName))
+	}
+
+	var args []planfmt.Arg
+	var refPositions []int
+	p.pos++ // Move past OPEN ObjectLiteral
+	for p.pos < len(p.events) {
+		evt := p.events[p.pos]
+		if evt.Kind == parser.EventClose && parser.NodeKind(evt.Data) == parser.NodeObjectLiteral {
+			p.pos++
+			return args, refPositions, nil
+		}
+		if !p.atOpen(parser.NodeObjectField) {
+			p.pos++ // Braces and commas
+			continue
+		}
+
+		p.pos++ // Move past OPEN ObjectField
+		key := paramName + "." + unquote(string(p.tokens[p.events[p.pos].Data].Text))
+		p.pos += 2 // Move past key and colon tokens
+
+		switch {
+		case p.atOpen(parser.NodeDecorator):
+			args = append(args, planfmt.Arg{Key: key})
+			refPositions = append(refPositions, p.pos)
+		case p.atOpen(parser.NodeLiteral):
+			value, err := literalParamValue(key, p.tokens[p.events[p.pos+1].Data])
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, planfmt.Arg{Key: key, Val: value})
+			refPositions = append(refPositions, -1)
+		default:
+			return nil, nil, planErr(fmt.Sprintf("field '%s' of %s must be a string, number, boolean or value decorator", key, decoratorName))
+		}
+		p.skipToClose(parser.NodeObjectField)
+	}
+	return nil, nil, planErr(fmt.Sprintf("object parameter '%s' of %s is not closed", paramName, decoratorName))
+}
+
+// planDecoratorCommand plans a leaf execution decorator used as a statement
+// (@log("...")). Returns false if the decorator at p.pos is not an execution
+// decorator, leaving p.pos unchanged.
+// Expects p.pos at OPEN Decorator, leaves position after CLOSE Decorator.
+func (p *planner) planDecoratorCommand() (Command, bool, error) {
+	start := p.pos
+	p.pos++ // Move past OPEN Decorator
+
+	var parts []string
+	for p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
+		tok := p.tokens[p.events[p.pos].Data]
+		if tok.Type != lexer.AT && tok.Type != lexer.DOT {
+			parts = append(parts, string(tok.Text))
+		}
+		p.pos++
+	}
+
+	entry, ok := decorator.Global().Lookup(strings.Join(parts, "."))
+	if !ok {
+		p.pos = start
+		return Command{}, false, nil
+	}
+	if _, isExec := entry.Impl.(decorator.Exec); !isExec {
+		p.pos = start
+		return Command{}, false, nil
+	}
+	decoratorName := "@" + strings.Join(parts, ".")
+
+	var args []planfmt.Arg
+	if p.atOpen(parser.NodeParamList) {
+		var err error
+		args, err = p.parseParamList(decoratorName)
+		if err != nil {
+			return Command{}, false, err
+		}
+		// Plan.Validate requires args sorted by key
+		sort.Slice(args, func(i, j int) bool { return args[i].Key < args[j].Key })
+	}
+	p.skipToClose(parser.NodeDecorator)
+
+	return Command{Decorator: decoratorName, Args: args}, true, nil
+}
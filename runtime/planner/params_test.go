@@ -0,0 +1,131 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+// useSites returns the secret use sites recorded in plan.
+func useSites(plan *planfmt.Plan) map[string]bool {
+	sites := make(map[string]bool)
+	for _, use := range plan.SecretUses {
+		sites[use.Site] = true
+	}
+	return sites
+}
+
+func TestDecoratorParams_ValueDecorator(t *testing.T) {
+	for _, call := range []string{"@workdir(@var.module)", "@workdir(path=@var.module)"} {
+		t.Run(call, func(t *testing.T) {
+			plan, err := planLoopSource(t, `
+var module = "cli-module"
+`+call+` { echo "x" }
+`, "")
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+
+			cmd, ok := plan.Steps[0].Tree.(*planfmt.CommandNode)
+			if !ok || cmd.Decorator != "@workdir" {
+				t.Fatalf("Expected @workdir CommandNode, got %#v", plan.Steps[0].Tree)
+			}
+			path := getCommandArg(cmd, "path")
+			if !strings.HasPrefix(path, "opal:") {
+				t.Errorf("Expected path to be a DisplayID, got %q", path)
+			}
+			if site := "root/step-1/params/path"; !useSites(plan)[site] {
+				t.Errorf("Expected use site %s, got %v", site, useSites(plan))
+			}
+		})
+	}
+}
+
+func TestDecoratorParams_Interpolation(t *testing.T) {
+	plan, err := planLoopSource(t, `
+var module = "cli-module"
+@log("Testing @var.module now", level="warn")
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	cmd, ok := plan.Steps[0].Tree.(*planfmt.CommandNode)
+	if !ok || cmd.Decorator != "@log" {
+		t.Fatalf("Expected @log CommandNode, got %#v", plan.Steps[0].Tree)
+	}
+	message := getCommandArg(cmd, "message")
+	if !strings.HasPrefix(message, "Testing opal:") || !strings.HasSuffix(message, " now") {
+		t.Errorf("Expected interpolated DisplayID, got %q", message)
+	}
+	if got := getCommandArg(cmd, "level"); got != "warn" {
+		t.Errorf("Expected level warn, got %q", got)
+	}
+	if site := "root/step-1/params/message"; !useSites(plan)[site] {
+		t.Errorf("Expected use site %s, got %v", site, useSites(plan))
+	}
+}
+
+func TestDecoratorParams_LeafDecoratorSteps(t *testing.T) {
+	plan, err := planLoopSource(t, `
+fun build {
+    @log("start")
+    echo "build"
+    @log("done")
+}
+`, "build")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d", len(plan.Steps))
+	}
+	for i, want := range []string{"@log", "@shell", "@log"} {
+		cmd, ok := plan.Steps[i].Tree.(*planfmt.CommandNode)
+		if !ok || cmd.Decorator != want {
+			t.Errorf("step %d: expected %s, got %#v", i, want, plan.Steps[i].Tree)
+		}
+	}
+}
+
+func TestDecoratorParams_ValidatedAtPlanTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{
+			name:    "empty path",
+			source:  "var dir = \"\"\n@workdir(@var.dir) { echo x }",
+			wantErr: "parameter 'path' of @workdir must not be empty",
+		},
+		{
+			name:    "collection",
+			source:  "var dir = [\"a\", \"b\"]\n@workdir(@var.dir) { echo x }",
+			wantErr: "parameter 'path' of @workdir must be a single value",
+		},
+		{
+			name:    "unknown variable",
+			source:  "@workdir(@var.dir) { echo x }",
+			wantErr: "variable \"dir\" not found",
+		},
+		{
+			name:    "enum",
+			source:  "var level = \"loud\"\n@log(\"x\", level=@var.level)",
+			wantErr: "invalid value for parameter 'level' of @log",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planLoopSource(t, tt.source, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if strings.Contains(err.Error(), "loud") {
+				t.Errorf("Error leaks the value: %v", err)
+			}
+		})
+	}
+}
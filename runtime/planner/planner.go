@@ -33,7 +33,11 @@ package planner
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 	"time"
 
@@ -42,6 +46,7 @@ import (
 	"github.com/opal-lang/opal/core/invariant"
 	"github.com/opal-lang/opal/core/planfmt"
 	"github.com/opal-lang/opal/core/sdk/secret"
+	"github.com/opal-lang/opal/core/types"
 	"github.com/opal-lang/opal/runtime/lexer"
 	"github.com/opal-lang/opal/runtime/parser"
 	"github.com/opal-lang/opal/runtime/vault"
@@ -57,6 +62,7 @@ type Command struct {
 	Operator       string         // "&&", "||", "|", ";" - how to chain to NEXT command (empty for last)
 	RedirectMode   string         // ">", ">>" - redirect mode (empty if no redirect)
 	RedirectTarget *Command       // For redirect operators, the target decorator (nil otherwise)
+	RedirectIn     *Command       // For <, the decorator providing stdin (nil otherwise)
 }
 
 // CommandPartKind identifies the type of a command part
@@ -85,11 +91,15 @@ type CommandIR struct {
 
 // Config configures the planner
 type Config struct {
-	Target    string           // Command name (e.g., "hello") or "" for script mode
-	IDFactory secret.IDFactory // Factory for generating deterministic secret IDs (optional, uses run-mode if nil)
-	Vault     *vault.Vault     // Shared vault for variable storage and scrubbing (optional, creates new if nil)
-	Telemetry TelemetryLevel   // Telemetry level (production-safe)
-	Debug     DebugLevel       // Debug level (development only)
+	Target    string                 // Command name (e.g., "hello") or "" for script mode
+	Args      []string               // Positional arguments for the target's parameters, in declaration order
+	NamedArgs map[string]string      // Named arguments for the target's parameters (--arg name=value)
+	IDFactory secret.IDFactory       // Factory for generating deterministic secret IDs (optional, uses run-mode if nil)
+	Vault     *vault.Vault           // Shared vault for variable storage and scrubbing (optional, creates new if nil)
+	Sessions  *decorator.SessionPool // Transport sessions opened while planning (optional, creates one closed after planning if nil)
+	Pipefail  bool                   // Record that pipelines fail when any command fails (hashed with the plan)
+	Telemetry TelemetryLevel         // Telemetry level (production-safe)
+	Debug     DebugLevel             // Debug level (development only)
 }
 
 // TelemetryLevel controls telemetry collection (production-safe)
@@ -220,6 +230,14 @@ func PlanWithObservability(events []parser.Event, tokens []lexer.Token, config C
 		vlt = vault.NewWithPlanKey(planKey)
 	}
 
+	// Transports (@ssh.connect) connect at plan time; sessions are shared
+	// with the executor when the caller passes a pool
+	sessions := config.Sessions
+	if sessions == nil {
+		sessions = decorator.NewSessionPool()
+		defer sessions.CloseAll()
+	}
+
 	p := &planner{
 		events:        events,
 		tokens:        tokens,
@@ -228,6 +246,7 @@ func PlanWithObservability(events []parser.Event, tokens []lexer.Token, config C
 		stepID:        1,
 		vault:         vlt,                         // Scope-aware variable storage (shared or new)
 		session:       decorator.NewLocalSession(), // Session for decorator resolution
+		sessions:      sessions,                    // Transport sessions, keyed by params
 		idFactory:     idFactory,                   // For placeholder generation
 		commandIRs:    make(map[uint64]*CommandIR), // CommandIR storage (Pass 1 → Pass 3)
 		nextCommandID: 1,
@@ -271,8 +290,19 @@ type planner struct {
 	stepID uint64 // Next step ID to assign
 
 	// Variable scoping with transport boundary guards
-	vault   *vault.Vault      // Scope-aware variable storage
-	session decorator.Session // Session for decorator resolution (LocalSession by default)
+	vault    *vault.Vault           // Scope-aware variable storage
+	session  decorator.Session      // Session for decorator resolution (LocalSession by default)
+	sessions *decorator.SessionPool // Open transport sessions (@ssh.connect)
+
+	// Function calls (@cmd): definitions indexed on first call, and the
+	// functions being expanded, for cycle detection
+	functions map[string]*funcDef
+	callStack []string
+
+	// Provenance: event positions of the functions and declarations the
+	// plan is built from, and decorator paths used (see provenance.go)
+	sourceUnits    []int
+	decoratorsUsed map[string]bool
 
 	// Decorator block scope tracking
 	// Execution decorators (@retry, @timeout, @parallel, etc.) create isolated scopes
@@ -308,6 +338,7 @@ func (p *planner) recordDebugEvent(event, context string) {
 
 // recordDecoratorResolution records a single decorator resolution
 func (p *planner) recordDecoratorResolution(decoratorName string) {
+	p.recordDecorator(decoratorName)
 	if p.telemetry == nil {
 		return
 	}
@@ -343,18 +374,16 @@ func (p *planner) checkDecoratorBlock() (bool, string) {
 
 	p.pos++
 
-	// Skip @ token
-	if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
-		p.pos++
-	}
-
-	// Extract decorator name
-	decoratorName := ""
-	if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
-		tokenIdx := p.events[p.pos].Data
-		decoratorName = "@" + string(p.tokens[tokenIdx].Text)
+	// Extract decorator name (dotted names like @ssh.connect span several tokens)
+	var parts []string
+	for p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
+		tok := p.tokens[p.events[p.pos].Data]
+		if tok.Type != lexer.AT && tok.Type != lexer.DOT {
+			parts = append(parts, string(tok.Text))
+		}
 		p.pos++
 	}
+	decoratorName := "@" + strings.Join(parts, ".")
 
 	// Search for NodeBlock within decorator
 	depth := 0
@@ -385,9 +414,17 @@ func (p *planner) checkDecoratorBlock() (bool, string) {
 // processDecoratorBlock handles a decorator block by creating a Step for the decorator itself.
 // Assumes p.pos is at STEP_ENTER and the step contains a decorator block.
 // Returns a Step containing the decorator CommandNode with its block steps.
+//
+// The decorator step takes its ID before its block steps (pre-order), and the
+// block runs in scope "step-N/@name[N]" so the executor can rebuild the same
+// site path from the plan.
 func (p *planner) processDecoratorBlock(decoratorName string) (planfmt.Step, error) {
 	p.pos++ // Move past STEP_ENTER
 
+	id := p.nextStepID()
+	p.vault.Push(fmt.Sprintf("step-%d", id))
+	defer p.vault.Pop()
+
 	// Skip to decorator
 	for p.pos < len(p.events) && p.events[p.pos].Kind != parser.EventOpen {
 		p.pos++
@@ -406,14 +443,27 @@ func (p *planner) processDecoratorBlock(decoratorName string) (planfmt.Step, err
 	if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventOpen &&
 		parser.NodeKind(p.events[p.pos].Data) == parser.NodeParamList {
 		var err error
-		args, err = p.parseParamList()
+		args, err = p.parseParamList(decoratorName)
 		if err != nil {
 			return planfmt.Step{}, err
 		}
+		// Plan.Validate requires args sorted by key
+		sort.Slice(args, func(i, j int) bool { return args[i].Key < args[j].Key })
+	}
+
+	// Transport blocks (@ssh.connect) resolve values in the remote session
+	if entry, ok := decorator.Global().Lookup(strings.TrimPrefix(decoratorName, "@")); ok {
+		if transport, isTransport := entry.Impl.(decorator.Transport); isTransport {
+			exit, err := p.enterTransport(decoratorName, transport, args)
+			if err != nil {
+				return planfmt.Step{}, err
+			}
+			defer exit()
+		}
 	}
 
 	// Enter scope for variable isolation
-	p.vault.Push(decoratorName)
+	p.vault.PushAt(decoratorName, int(id))
 	p.decoratorStack = append(p.decoratorStack, decoratorBlockContext{
 		name: decoratorName,
 	})
@@ -444,102 +494,162 @@ func (p *planner) processDecoratorBlock(decoratorName string) (planfmt.Step, err
 		invariant.Invariant(p.pos > prevPos, "processDecoratorBlock stuck finding block at pos %d", prevPos)
 	}
 
-	// Collect block steps into local slice
-	var blockSteps []planfmt.Step
+	// Collect block steps
+	blockSteps, err := p.planBlockSteps()
+	if err != nil {
+		return planfmt.Step{}, err
+	}
+
+	// Pop scope
+	p.vault.Pop()
+	p.decoratorStack = p.decoratorStack[:len(p.decoratorStack)-1]
+	closed = true // Mark as closed so defer becomes no-op
+
+	if p.config.Debug >= DebugDetailed {
+		p.recordDebugEvent("decorator_block_exit", fmt.Sprintf("name=%s", decoratorName))
+	}
 
-	// Process nested steps
+	// Skip past CLOSE Decorator and STEP_EXIT
 	for p.pos < len(p.events) {
-		prevPos := p.pos
 		evt := p.events[p.pos]
+		p.pos++
+		if evt.Kind == parser.EventStepExit {
+			break
+		}
+	}
 
-		if evt.Kind == parser.EventClose && parser.NodeKind(evt.Data) == parser.NodeBlock {
-			// Exit block
-			p.pos++
+	// Create Step with a CommandNode for the decorator
+	return planfmt.Step{
+		ID: id,
+		Tree: &planfmt.CommandNode{
+			Decorator: decoratorName,
+			Args:      args,
+			Block:     blockSteps,
+		},
+	}, nil
+}
 
-			// Pop scope
-			p.vault.Pop()
-			p.decoratorStack = p.decoratorStack[:len(p.decoratorStack)-1]
-			closed = true // Mark as closed so defer becomes no-op
+// planBlockSteps plans the statements of a block.
+// Expects p.pos just after OPEN Block, leaves position after the matching CLOSE Block.
+func (p *planner) planBlockSteps() ([]planfmt.Step, error) {
+	var steps []planfmt.Step
 
-			if p.config.Debug >= DebugDetailed {
-				p.recordDebugEvent("decorator_block_exit", fmt.Sprintf("name=%s", decoratorName))
-			}
+	// Depth of nodes opened inside the block (not consumed as steps)
+	depth := 0
+	for p.pos < len(p.events) {
+		prevPos := p.pos
+		evt := p.events[p.pos]
 
-			// Skip past CLOSE Decorator and STEP_EXIT
-			for p.pos < len(p.events) {
-				evt := p.events[p.pos]
-				p.pos++
-				if evt.Kind == parser.EventStepExit {
-					break
-				}
-			}
+		switch {
+		case evt.Kind == parser.EventClose && parser.NodeKind(evt.Data) == parser.NodeBlock && depth == 0:
+			p.pos++ // Move past CLOSE Block
+			return steps, nil
 
-			// Create CommandNode for the decorator
-			decoratorCmd := &planfmt.CommandNode{
-				Decorator: decoratorName,
-				Args:      args,
-				Block:     blockSteps,
+		case evt.Kind == parser.EventStepEnter:
+			step, err := p.planStatementStep()
+			if err != nil {
+				return nil, err
+			}
+			if step.ID != 0 {
+				steps = append(steps, step)
 			}
+			continue
 
-			// Create Step
-			step := planfmt.Step{
-				ID:   p.nextStepID(),
-				Tree: decoratorCmd,
+		case isControlFlow(evt):
+			flowSteps, err := p.planControlFlow()
+			if err != nil {
+				return nil, err
 			}
+			steps = append(steps, flowSteps...)
+			continue
 
-			return step, nil
+		case evt.Kind == parser.EventOpen:
+			depth++
+		case evt.Kind == parser.EventClose:
+			depth--
 		}
 
-		if evt.Kind == parser.EventStepEnter {
-			// Check for nested decorator block
-			savedPos := p.pos
-			p.pos++
+		p.pos++
+		invariant.Invariant(p.pos > prevPos, "planBlockSteps stuck at pos %d", prevPos)
+	}
 
-			hasNestedBlock := false
-			nestedName := ""
+	return nil, fmt.Errorf("block not closed properly")
+}
 
-			if p.pos < len(p.events) {
-				nextEvt := p.events[p.pos]
-				if nextEvt.Kind == parser.EventOpen && parser.NodeKind(nextEvt.Data) == parser.NodeDecorator {
-					hasNestedBlock, nestedName = p.checkDecoratorBlock()
-				}
-			}
+// isControlFlow reports whether evt opens a for, if, when or try statement.
+// These are not wrapped in step boundaries; they are planned as groups.
+func isControlFlow(evt parser.Event) bool {
+	if evt.Kind != parser.EventOpen {
+		return false
+	}
+	switch parser.NodeKind(evt.Data) {
+	case parser.NodeFor, parser.NodeIf, parser.NodeWhen, parser.NodeTry:
+		return true
+	}
+	return false
+}
 
-			p.pos = savedPos
+// planControlFlow plans the for, if, when or try statement at p.pos.
+// Loops unroll into one group step per iteration; conditionals become a
+// single group step holding only the taken branch; try keeps every block.
+func (p *planner) planControlFlow() ([]planfmt.Step, error) {
+	invariant.Precondition(p.pos < len(p.events) && isControlFlow(p.events[p.pos]),
+		"planControlFlow must start at OPEN For, If, When or Try")
+
+	var step planfmt.Step
+	var err error
+	switch parser.NodeKind(p.events[p.pos].Data) {
+	case parser.NodeFor:
+		return p.planFor()
+	case parser.NodeIf:
+		step, err = p.planIf()
+	case parser.NodeTry:
+		step, err = p.planTry()
+	default: // NodeWhen
+		step, err = p.planWhen()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []planfmt.Step{step}, nil
+}
 
-			if hasNestedBlock {
-				// Recursively handle nested decorator block
-				nestedStep, err := p.processDecoratorBlock(nestedName)
-				if err != nil {
-					return planfmt.Step{}, err
-				}
-				if nestedStep.ID != 0 {
-					blockSteps = append(blockSteps, nestedStep)
-				}
-				continue
-			}
+// planStatementStep plans the step starting at STEP_ENTER, either as a
+// decorator block or as a normal step. Returns a step with ID=0 if the step
+// produced no commands (e.g., only var declarations).
+func (p *planner) planStatementStep() (planfmt.Step, error) {
+	// Check if this step contains a decorator block
+	savedPos := p.pos
+	p.pos++
 
-			// Normal step
-			step, err := p.planStep()
-			if err != nil {
-				return planfmt.Step{}, err
-			}
-			if step.ID != 0 {
-				blockSteps = append(blockSteps, step)
-			}
-			continue
+	hasDecoratorBlock := false
+	decoratorName := ""
+
+	if p.pos < len(p.events) {
+		nextEvt := p.events[p.pos]
+		if nextEvt.Kind == parser.EventOpen && parser.NodeKind(nextEvt.Data) == parser.NodeDecorator {
+			hasDecoratorBlock, decoratorName = p.checkDecoratorBlock()
 		}
+	}
 
-		p.pos++
-		invariant.Invariant(p.pos > prevPos, "processDecoratorBlock stuck processing steps at pos %d", prevPos)
+	p.pos = savedPos
+
+	if p.isCmdCall() {
+		return p.planCmdCall()
+	}
+
+	if hasDecoratorBlock {
+		return p.processDecoratorBlock(decoratorName)
 	}
 
-	return planfmt.Step{}, fmt.Errorf("decorator block not closed properly")
+	// Normal step
+	return p.planStep()
 }
 
 // parseParamList parses decorator parameters from the event stream.
+// Positional parameters are named by the decorator's schema order.
 // Expects to be positioned at OPEN ParamList, leaves position after CLOSE ParamList.
-func (p *planner) parseParamList() ([]planfmt.Arg, error) {
+func (p *planner) parseParamList(decoratorName string) ([]planfmt.Arg, error) {
 	var args []planfmt.Arg
 
 	// PRECONDITION: Must be at OPEN ParamList
@@ -556,6 +666,7 @@ func (p *planner) parseParamList() ([]planfmt.Arg, error) {
 	}
 
 	// Parse each parameter
+	var refs []paramRef
 	for p.pos < len(p.events) {
 		prevPos := p.pos
 		evt := p.events[p.pos]
@@ -568,11 +679,16 @@ func (p *planner) parseParamList() ([]planfmt.Arg, error) {
 
 		// Parse individual parameter
 		if evt.Kind == parser.EventOpen && parser.NodeKind(evt.Data) == parser.NodeParam {
-			arg, err := p.parseParam()
+			paramArgs, refPositions, err := p.parseParam(decoratorName)
 			if err != nil {
 				return nil, err
 			}
-			args = append(args, arg)
+			for i, arg := range paramArgs {
+				if refPositions[i] >= 0 {
+					refs = append(refs, paramRef{index: len(args), pos: refPositions[i]})
+				}
+				args = append(args, arg)
+			}
 			continue
 		}
 
@@ -586,12 +702,61 @@ func (p *planner) parseParamList() ([]planfmt.Arg, error) {
 		invariant.Invariant(p.pos > prevPos, "parseParamList stuck at pos %d", prevPos)
 	}
 
+	args, err := nameParams(decoratorName, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.resolveParamValues(decoratorName, args, refs); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// nameParams assigns schema parameter names to positional arguments (empty
+// Key), filling the parameters not given by name in declaration order.
+func nameParams(decoratorName string, args []planfmt.Arg) ([]planfmt.Arg, error) {
+	named := make(map[string]bool)
+	positional := 0
+	for _, arg := range args {
+		if arg.Key == "" {
+			positional++
+		} else {
+			named[arg.Key] = true
+		}
+	}
+	if positional == 0 {
+		return args, nil
+	}
+
+	var order []string
+	if entry, ok := decorator.Global().Lookup(strings.TrimPrefix(decoratorName, "@")); ok {
+		order = entry.Impl.Descriptor().Schema.ParameterOrder
+	}
+
+	next := 0
+	for i := range args {
+		if args[i].Key != "" {
+			continue
+		}
+		for next < len(order) && named[order[next]] {
+			next++
+		}
+		if next == len(order) {
+			return nil, fmt.Errorf("too many positional parameters for %s", decoratorName)
+		}
+		args[i].Key = order[next]
+		next++
+	}
 	return args, nil
 }
 
-// parseParam parses a single parameter (key=value).
+// parseParam parses a single parameter (key=value, or a positional value
+// returned with an empty Key). Object values (env={...}) are flattened into
+// one arg per field, keyed "name.field". A value decorator is left for
+// resolveParamValues: its event position is returned alongside each arg,
+// or -1 for literals.
 // Expects to be positioned at OPEN Param, leaves position after CLOSE Param.
-func (p *planner) parseParam() (planfmt.Arg, error) {
+func (p *planner) parseParam(decoratorName string) ([]planfmt.Arg, []int, error) {
 	// PRECONDITION: Must be at OPEN Param
 	invariant.Precondition(p.pos < len(p.events) &&
 		p.events[p.pos].Kind == parser.EventOpen &&
@@ -600,86 +765,75 @@ func (p *planner) parseParam() (planfmt.Arg, error) {
 
 	p.pos++ // Move past OPEN Param
 
-	// Parse parameter name
+	// Parse parameter name (named form: TOKEN(name), TOKEN(=), value)
 	var paramName string
-	if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
+	if p.pos+1 < len(p.events) && p.events[p.pos].Kind == parser.EventToken &&
+		p.events[p.pos+1].Kind == parser.EventToken &&
+		p.tokens[p.events[p.pos+1].Data].Type == lexer.EQUALS {
 		tokenIdx := p.events[p.pos].Data
 		paramName = string(p.tokens[tokenIdx].Text)
-		p.pos++
+		p.pos += 2 // Move past name and = tokens
 	}
 
-	// Skip = token
-	if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
-		p.pos++
+	if p.atOpen(parser.NodeDecorator) {
+		refPos := p.pos
+		p.skipToClose(parser.NodeParam)
+		return []planfmt.Arg{{Key: paramName}}, []int{refPos}, nil
+	}
+
+	if p.atOpen(parser.NodeObjectLiteral) {
+		args, refPositions, err := p.parseObjectParam(decoratorName, paramName)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.skipToClose(parser.NodeParam)
+		return args, refPositions, nil
 	}
 
 	// Parse parameter value
 	var paramValue planfmt.Value
 	if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
-		tokenIdx := p.events[p.pos].Data
-		token := p.tokens[tokenIdx]
-		tokenText := string(token.Text)
-
-		// Determine value type from token
-		switch token.Type {
-		case lexer.INTEGER:
-			// Parse integer
-			var intVal int64
-			if _, err := fmt.Sscanf(tokenText, "%d", &intVal); err != nil {
-				return planfmt.Arg{}, fmt.Errorf("failed to parse integer parameter %q: %w", paramName, err)
-			}
-			paramValue = planfmt.Value{
-				Kind: planfmt.ValueInt,
-				Int:  intVal,
-			}
-		case lexer.STRING:
-			// String value (remove quotes)
-			str := tokenText
-			if len(str) >= 2 && str[0] == '"' && str[len(str)-1] == '"' {
-				str = str[1 : len(str)-1]
-			}
-			paramValue = planfmt.Value{
-				Kind: planfmt.ValueString,
-				Str:  str,
-			}
-		case lexer.BOOLEAN:
-			// Boolean value
-			paramValue = planfmt.Value{
-				Kind: planfmt.ValueBool,
-				Bool: tokenText == "true",
-			}
-		case lexer.DURATION:
-			// Duration value (stored as string)
-			paramValue = planfmt.Value{
-				Kind: planfmt.ValueString,
-				Str:  tokenText,
-			}
-		default:
-			// Default to string
-			paramValue = planfmt.Value{
-				Kind: planfmt.ValueString,
-				Str:  tokenText,
-			}
+		var err error
+		paramValue, err = literalParamValue(paramName, p.tokens[p.events[p.pos].Data])
+		if err != nil {
+			return nil, nil, err
 		}
 		p.pos++
 	}
 
-	// Skip to CLOSE Param
-	for p.pos < len(p.events) {
-		prevPos := p.pos
-		evt := p.events[p.pos]
-		if evt.Kind == parser.EventClose && parser.NodeKind(evt.Data) == parser.NodeParam {
-			p.pos++ // Move past CLOSE Param
-			break
+	// Skip to CLOSE Param (past nested literals)
+	p.skipToClose(parser.NodeParam)
+
+	return []planfmt.Arg{{Key: paramName, Val: paramValue}}, []int{-1}, nil
+}
+
+// literalParamValue converts a literal parameter token to a plan value.
+func literalParamValue(paramName string, token lexer.Token) (planfmt.Value, error) {
+	tokenText := string(token.Text)
+
+	// Determine value type from token
+	switch token.Type {
+	case lexer.INTEGER:
+		// Parse integer
+		var intVal int64
+		if _, err := fmt.Sscanf(tokenText, "%d", &intVal); err != nil {
+			return planfmt.Value{}, fmt.Errorf("failed to parse integer parameter %q: %w", paramName, err)
 		}
-		p.pos++
-		invariant.Invariant(p.pos > prevPos, "parseParam stuck at pos %d", prevPos)
+		return planfmt.Value{Kind: planfmt.ValueInt, Int: intVal}, nil
+	case lexer.STRING:
+		// String value (remove quotes)
+		str := tokenText
+		if len(str) >= 2 && str[0] == '"' && str[len(str)-1] == '"' {
+			str = str[1 : len(str)-1]
+		}
+		return planfmt.Value{Kind: planfmt.ValueString, Str: str}, nil
+	case lexer.BOOLEAN:
+		// Boolean value
+		return planfmt.Value{Kind: planfmt.ValueBool, Bool: tokenText == "true"}, nil
+	default:
+		// Durations and anything else are kept as strings
+		return planfmt.Value{Kind: planfmt.ValueString, Str: tokenText}, nil
 	}
-
-	return planfmt.Arg{
-		Key: paramName,
-		Val: paramValue,
-	}, nil
 }
 
 // plan is the main planning entry point
@@ -699,6 +853,9 @@ func (p *planner) plan() (*planfmt.Plan, error) {
 		plan.PlanSalt = vaultKey
 	}
 	plan.Header.PlanKind = 0 // View plan
+	plan.Header.SchemaID = planfmt.SchemaID
+	plan.Header.Build = BuildFingerprint()
+	plan.Header.Compiler = planfmt.CompilerID(plan.Header.Build)
 	plan.Target = p.config.Target
 	plan.Steps = []planfmt.Step{}
 
@@ -743,6 +900,9 @@ func (p *planner) plan() (*planfmt.Plan, error) {
 		})
 	}
 
+	plan.Provenance = p.provenance(plan.Steps)
+	plan.Pipefail = p.config.Pipefail
+
 	// POSTCONDITION: plan must be valid
 	err := plan.Validate()
 	invariant.ExpectNoError(err, "plan validation")
@@ -765,10 +925,20 @@ func (p *planner) planTargetFunction() ([]planfmt.Step, error) {
 	var availableFunctions []string
 
 	// Walk events to find the target function
+	// Top-level var declarations before it are in scope for its body
+	depth := 0
 	for p.pos < len(p.events) {
 		prevPos := p.pos
 		evt := p.events[p.pos]
 
+		if evt.Kind == parser.EventOpen && parser.NodeKind(evt.Data) == parser.NodeVarDecl && depth == 1 {
+			p.recordSourceUnit(p.pos)
+			if err := p.planVarDecl(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		if evt.Kind == parser.EventOpen && parser.NodeKind(evt.Data) == parser.NodeFunction {
 			// Found a function, check if it's our target
 			// Event structure: OPEN Function, TOKEN(fun), TOKEN(name), TOKEN(=), ...
@@ -785,12 +955,17 @@ func (p *planner) planTargetFunction() ([]planfmt.Step, error) {
 						p.recordDebugEvent("function_found", fmt.Sprintf("name=%s pos=%d", funcName, p.pos))
 					}
 
-					// Plan the function body
-					return p.planFunctionBody()
+					return p.planTarget(funcName)
 				}
 			}
 		}
 
+		switch evt.Kind {
+		case parser.EventOpen:
+			depth++
+		case parser.EventClose:
+			depth--
+		}
 		p.pos++
 
 		// INVARIANT: position must advance (no infinite loops)
@@ -819,13 +994,35 @@ func (p *planner) planTargetFunction() ([]planfmt.Step, error) {
 	}
 }
 
-// planFunctionBody plans the body of a function using depth tracking.
-// Stops when depth reaches 0 (exited function), ensuring only target function events are processed.
-func (p *planner) planFunctionBody() ([]planfmt.Step, error) {
+// planTarget binds the target function's parameters from the configured
+// arguments, then plans its body. Expects p.pos at the target's OPEN Function.
+// Parameters are declared at root scope, like top-level variables.
+func (p *planner) planTarget(name string) ([]planfmt.Step, error) {
+	def, err := p.lookupFunction(name)
+	if err != nil {
+		return nil, err
+	}
+	values, err := p.evalCallArgs(def, p.targetArgs())
+	if err != nil {
+		return nil, err
+	}
+	p.bindParams(def, values)
+
+	p.callStack = append(p.callStack, name)
+	defer func() { p.callStack = p.callStack[:len(p.callStack)-1] }()
+
+	return p.planFunctionBody(name)
+}
+
+// planFunctionBody plans the body of function name using depth tracking.
+// Stops when depth reaches 0 (exited function), ensuring only that function's events are processed.
+func (p *planner) planFunctionBody(name string) ([]planfmt.Step, error) {
 	if p.config.Debug >= DebugPaths {
 		p.recordDebugEvent("enter_planFunctionBody", fmt.Sprintf("pos=%d", p.pos))
 	}
 
+	p.recordSourceUnit(p.pos)
+
 	// Skip to function body (past OPEN Function, name token, '=' token)
 	depth := 1
 	p.pos++ // Move past OPEN Function
@@ -837,35 +1034,7 @@ func (p *planner) planFunctionBody() ([]planfmt.Step, error) {
 		evt := p.events[p.pos]
 
 		if evt.Kind == parser.EventStepEnter {
-			// Check if this step contains a decorator block
-			savedPos := p.pos
-			p.pos++
-
-			hasDecoratorBlock := false
-			decoratorName := ""
-
-			if p.pos < len(p.events) {
-				nextEvt := p.events[p.pos]
-				if nextEvt.Kind == parser.EventOpen && parser.NodeKind(nextEvt.Data) == parser.NodeDecorator {
-					hasDecoratorBlock, decoratorName = p.checkDecoratorBlock()
-				}
-			}
-
-			p.pos = savedPos
-
-			if hasDecoratorBlock {
-				step, err := p.processDecoratorBlock(decoratorName)
-				if err != nil {
-					return nil, err
-				}
-				if step.ID != 0 {
-					steps = append(steps, step)
-				}
-				continue
-			}
-
-			// Normal step
-			step, err := p.planStep()
+			step, err := p.planStatementStep()
 			if err != nil {
 				return nil, err
 			}
@@ -873,6 +1042,14 @@ func (p *planner) planFunctionBody() ([]planfmt.Step, error) {
 				steps = append(steps, step)
 			}
 			continue
+		} else if isControlFlow(evt) {
+			// Loops unroll and conditionals keep only the taken branch
+			flowSteps, err := p.planControlFlow()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, flowSteps...)
+			continue
 		} else if evt.Kind == parser.EventOpen {
 			depth++
 		} else if evt.Kind == parser.EventClose {
@@ -888,7 +1065,7 @@ func (p *planner) planFunctionBody() ([]planfmt.Step, error) {
 	if len(steps) == 0 {
 		return nil, &PlanError{
 			Message:     "no commands found in function body",
-			Context:     fmt.Sprintf("planning function %s", p.config.Target),
+			Context:     fmt.Sprintf("planning function %s", name),
 			EventPos:    p.pos,
 			TotalEvents: len(p.events),
 			Suggestion:  "Add at least one command to the function body",
@@ -918,40 +1095,21 @@ func (p *planner) planSource() ([]planfmt.Step, error) {
 		prevPos := p.pos
 		evt := p.events[p.pos]
 
-		if evt.Kind == parser.EventOpen {
+		if isControlFlow(evt) && depth == 1 {
+			// Top-level for/if/when
+			flowSteps, err := p.planControlFlow()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, flowSteps...)
+			continue
+		} else if evt.Kind == parser.EventOpen {
 			depth++
 		} else if evt.Kind == parser.EventClose {
 			depth--
 		} else if evt.Kind == parser.EventStepEnter && depth == 1 {
-			// Top-level step - check if it contains a decorator block
-			savedPos := p.pos
-			p.pos++
-
-			hasDecoratorBlock := false
-			decoratorName := ""
-
-			if p.pos < len(p.events) {
-				nextEvt := p.events[p.pos]
-				if nextEvt.Kind == parser.EventOpen && parser.NodeKind(nextEvt.Data) == parser.NodeDecorator {
-					hasDecoratorBlock, decoratorName = p.checkDecoratorBlock()
-				}
-			}
-
-			p.pos = savedPos
-
-			if hasDecoratorBlock {
-				step, err := p.processDecoratorBlock(decoratorName)
-				if err != nil {
-					return nil, err
-				}
-				if step.ID != 0 {
-					steps = append(steps, step)
-				}
-				continue
-			}
-
-			// Normal step
-			step, err := p.planStep()
+			// Top-level step
+			step, err := p.planStatementStep()
 			if err != nil {
 				return nil, err
 			}
@@ -1048,6 +1206,18 @@ func (p *planner) planStep() (planfmt.Step, error) {
 			continue
 		}
 
+		if evt.Kind == parser.EventOpen && parser.NodeKind(evt.Data) == parser.NodeDecorator {
+			// Leaf execution decorator used as a statement (@log("..."))
+			cmd, ok, err := p.planDecoratorCommand()
+			if err != nil {
+				return planfmt.Step{}, err
+			}
+			if ok {
+				commands = append(commands, cmd)
+				continue
+			}
+		}
+
 		p.pos++
 	}
 
@@ -1122,8 +1292,7 @@ func (p *planner) planVarDecl() error {
 	}
 
 	// Variable scope excludes step segments because steps are not scopes
-	rawExpr := fmt.Sprintf("literal:%v", value)
-	exprID := p.vault.DeclareVariable(varName, rawExpr)
+	exprID := p.vault.DeclareVariable(varName, literalRaw(value))
 
 	// Store value for deferred resolution to enable batching efficiency
 	// Preserves original type (string, int, bool, map, slice)
@@ -1135,13 +1304,35 @@ func (p *planner) planVarDecl() error {
 	// Record debug event
 	if p.config.Debug >= DebugDetailed {
 		displayID := p.vault.GetDisplayID(exprID)
-		p.recordDebugEvent("var_declared", fmt.Sprintf("name=%s value=%v exprID=%s displayID=%s",
-			varName, value, exprID, displayID))
+		p.recordDebugEvent("var_declared", fmt.Sprintf("name=%s exprID=%s displayID=%s",
+			varName, exprID, displayID))
 	}
 
 	return nil
 }
 
+// literalRaw is the raw expression of a plain value (variables, loop items,
+// function arguments): "literal:" and a digest of the value, so equal values
+// share an expression without the vault keeping the value in plain text.
+func literalRaw(value any) string {
+	h := sha256.New()
+	if s, ok := value.(string); ok {
+		io.WriteString(h, s) // fmt would keep a copy in its buffer pool
+	} else {
+		fmt.Fprint(h, value)
+	}
+	return "literal:" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// valueText returns a value as text. Strings are returned as they are: fmt
+// would keep a copy of a secret in its buffer pool after the run.
+func valueText(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
 // planCommand plans a single command within a step (shell command + optional operator)
 func (p *planner) planCommand() (Command, error) {
 	if p.config.Debug >= DebugDetailed {
@@ -1224,134 +1415,75 @@ func (p *planner) planCommand() (Command, error) {
 		Str:  command,
 	}
 
-	// Check for redirect operator after this command (> or >>)
+	// Check for redirect operators after this command (<, > or >>)
 	var redirectTarget *Command
+	var redirectIn *Command
 	redirectMode := "" // ">" or ">>" - stored separately from chaining operator
 	operator := ""     // Chaining operator: "&&", "||", "|", ";"
 
-	if p.pos < len(p.events) {
-		evt := p.events[p.pos]
-
-		// Check for NodeRedirect
-		if evt.Kind == parser.EventOpen && parser.NodeKind(evt.Data) == parser.NodeRedirect {
-			p.pos++ // Move past OPEN NodeRedirect
+	// A command may redirect both ways: sort < in > out
+	for p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventOpen &&
+		parser.NodeKind(p.events[p.pos].Data) == parser.NodeRedirect {
+		p.pos++ // Move past OPEN NodeRedirect
 
-			// Next should be the redirect operator token (> or >>)
-			if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
-				tokenIdx := p.events[p.pos].Data
-				tokenType := p.tokens[tokenIdx].Type
+		// Next should be the redirect operator token (<, > or >>)
+		input := false
+		if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
+			tokenIdx := p.events[p.pos].Data
+			tokenType := p.tokens[tokenIdx].Type
 
-				switch tokenType {
-				case lexer.GT:
-					redirectMode = ">"
-				case lexer.APPEND:
-					redirectMode = ">>"
-				}
-				p.pos++ // Consume the operator token
+			switch tokenType {
+			case lexer.LT:
+				input = true
+			case lexer.GT:
+				redirectMode = ">"
+			case lexer.APPEND:
+				redirectMode = ">>"
 			}
+			p.pos++ // Consume the operator token
+		}
 
-			// Next should be OPEN NodeRedirectTarget
-			if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventOpen &&
-				parser.NodeKind(p.events[p.pos].Data) == parser.NodeRedirectTarget {
-				p.pos++ // Move past OPEN NodeRedirectTarget
-
-				// Collect tokens for redirect target
-				var targetTokens []uint32
-				targetDepth := 1
-
-				for p.pos < len(p.events) && targetDepth > 0 {
-					evt := p.events[p.pos]
-
-					if evt.Kind == parser.EventOpen {
-						targetDepth++
-					} else if evt.Kind == parser.EventClose {
-						targetDepth--
-						if targetDepth == 0 {
-							p.pos++ // Move past CLOSE NodeRedirectTarget
-							break
-						}
-					} else if evt.Kind == parser.EventToken {
-						targetTokens = append(targetTokens, evt.Data)
-					}
-
-					p.pos++
-				}
-
-				// Build target command string
-				targetCmd := ""
-				for i, tokenIdx := range targetTokens {
-					token := p.tokens[tokenIdx]
-
-					if i > 0 && token.HasSpaceBefore {
-						targetCmd += " "
-					}
-
-					tokenText := getTokenText(token)
-					targetCmd += tokenText
-				}
+		// Next should be OPEN NodeRedirectTarget
+		if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventOpen &&
+			parser.NodeKind(p.events[p.pos].Data) == parser.NodeRedirectTarget {
+			p.pos++ // Move past OPEN NodeRedirectTarget
 
-				// Create redirect target command
-				if targetCmd != "" {
-					redirectTarget = &Command{
-						Decorator: "@shell",
-						Args: []planfmt.Arg{
-							{
-								Key: "command",
-								Val: planfmt.Value{
-									Kind: planfmt.ValueString,
-									Str:  targetCmd,
-								},
-							},
-						},
-					}
+			if input {
+				source, err := p.planRedirectInput()
+				if err != nil {
+					return Command{}, err
 				}
+				redirectIn = source
+			} else {
+				redirectTarget = p.planRedirectTarget()
 			}
+		}
 
-			// Move past CLOSE NodeRedirect
-			if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventClose {
-				p.pos++
-			}
+		// Move past CLOSE NodeRedirect
+		if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventClose {
+			p.pos++
+		}
+	}
 
-			// CRITICAL FIX: After processing redirect, continue checking for chaining operators
-			// This allows: echo a > out && echo b (both redirect AND chaining)
-			if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
-				tokenIdx := p.events[p.pos].Data
-				tokenType := p.tokens[tokenIdx].Type
-
-				switch tokenType {
-				case lexer.AND_AND:
-					operator = "&&"
-					p.pos++ // Consume the operator
-				case lexer.OR_OR:
-					operator = "||"
-					p.pos++ // Consume the operator
-				case lexer.PIPE:
-					operator = "|"
-					p.pos++ // Consume the operator
-				case lexer.SEMICOLON:
-					operator = ";"
-					p.pos++ // Consume the operator
-				}
-			}
-		} else if evt.Kind == parser.EventToken {
-			// Check for chaining operators (&&, ||, |, ;) when no redirect
-			tokenIdx := evt.Data
-			tokenType := p.tokens[tokenIdx].Type
+	// Check for chaining operators (&&, ||, |, ;), after any redirect
+	// This allows: echo a > out && echo b (both redirect AND chaining)
+	if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventToken {
+		tokenIdx := p.events[p.pos].Data
+		tokenType := p.tokens[tokenIdx].Type
 
-			switch tokenType {
-			case lexer.AND_AND:
-				operator = "&&"
-				p.pos++ // Consume the operator
-			case lexer.OR_OR:
-				operator = "||"
-				p.pos++ // Consume the operator
-			case lexer.PIPE:
-				operator = "|"
-				p.pos++ // Consume the operator
-			case lexer.SEMICOLON:
-				operator = ";"
-				p.pos++ // Consume the operator
-			}
+		switch tokenType {
+		case lexer.AND_AND:
+			operator = "&&"
+			p.pos++ // Consume the operator
+		case lexer.OR_OR:
+			operator = "||"
+			p.pos++ // Consume the operator
+		case lexer.PIPE:
+			operator = "|"
+			p.pos++ // Consume the operator
+		case lexer.SEMICOLON:
+			operator = ";"
+			p.pos++ // Consume the operator
 		}
 	}
 
@@ -1381,6 +1513,7 @@ func (p *planner) planCommand() (Command, error) {
 		Operator:       operator,
 		RedirectMode:   redirectMode,
 		RedirectTarget: redirectTarget,
+		RedirectIn:     redirectIn,
 	}
 
 	if p.config.Debug >= DebugDetailed {
@@ -1487,6 +1620,152 @@ func getTokenText(token lexer.Token) string {
 	}
 }
 
+// planRedirectTarget plans the target of a > or >> redirect: the target
+// text is a file path for @shell. Expects p.pos just past OPEN
+// NodeRedirectTarget, leaves it past the matching CLOSE.
+func (p *planner) planRedirectTarget() *Command {
+	targetTokens, _ := p.collectRedirectTarget()
+	targetCmd := p.tokensText(targetTokens)
+	if targetCmd == "" {
+		return nil
+	}
+	return &Command{
+		Decorator: "@shell",
+		Args: []planfmt.Arg{
+			{
+				Key: "command",
+				Val: planfmt.Value{
+					Kind: planfmt.ValueString,
+					Str:  targetCmd,
+				},
+			},
+		},
+	}
+}
+
+// planRedirectInput plans the input of a < redirect. A decorator that can
+// be read from (< @shell("data.txt")) is called with its arguments;
+// anything else is a file path for @shell, with @var references
+// interpolated like commands. Expects p.pos just past OPEN
+// NodeRedirectTarget, leaves it past the matching CLOSE.
+func (p *planner) planRedirectInput() (*Command, error) {
+	start := p.pos
+	tokens, hasDecorator := p.collectRedirectTarget()
+	end := p.pos
+
+	// Decorator call: OPEN ShellArg, OPEN Decorator, @name... [ParamList]
+	if start+1 < end && p.events[start+1].Kind == parser.EventOpen &&
+		parser.NodeKind(p.events[start+1].Data) == parser.NodeDecorator {
+		pos := start + 2
+		var parts []string
+		for pos < end && p.events[pos].Kind == parser.EventToken {
+			tok := p.tokens[p.events[pos].Data]
+			if tok.Type != lexer.AT && tok.Type != lexer.DOT {
+				parts = append(parts, string(tok.Text))
+			}
+			pos++
+		}
+		name := strings.Join(parts, ".")
+		if isRedirectInSource(name) {
+			var args []planfmt.Arg
+			if pos < end && p.events[pos].Kind == parser.EventOpen &&
+				parser.NodeKind(p.events[pos].Data) == parser.NodeParamList {
+				p.pos = pos
+				var err error
+				args, err = p.parseParamList("@" + name)
+				p.pos = end
+				if err != nil {
+					return nil, err
+				}
+				// Plan.Validate requires args sorted by key
+				sort.Slice(args, func(i, j int) bool { return args[i].Key < args[j].Key })
+			}
+			return &Command{Decorator: "@" + name, Args: args}, nil
+		}
+	}
+
+	path := p.tokensText(tokens)
+	if path == "" {
+		return nil, nil
+	}
+	var args []planfmt.Arg
+	if hasDecorator {
+		ir, err := p.buildCommandIR(path)
+		if err != nil {
+			return nil, err
+		}
+		commandID := p.nextCommandID
+		p.nextCommandID++
+		p.commandIRs[commandID] = ir
+		args = append(args, planfmt.Arg{
+			Key: "__commandID",
+			Val: planfmt.Value{Kind: planfmt.ValueInt, Int: int64(commandID)},
+		})
+	}
+	args = append(args, planfmt.Arg{
+		Key: "command",
+		Val: planfmt.Value{Kind: planfmt.ValueString, Str: path},
+	})
+	return &Command{Decorator: "@shell", Args: args}, nil
+}
+
+// collectRedirectTarget collects the token indices of a redirect target
+// and reports whether it holds a decorator. Expects p.pos just past OPEN
+// NodeRedirectTarget, leaves it past the matching CLOSE.
+func (p *planner) collectRedirectTarget() ([]uint32, bool) {
+	var targetTokens []uint32
+	hasDecorator := false
+	targetDepth := 1
+
+	for p.pos < len(p.events) && targetDepth > 0 {
+		evt := p.events[p.pos]
+
+		if evt.Kind == parser.EventOpen {
+			targetDepth++
+			if parser.NodeKind(evt.Data) == parser.NodeDecorator {
+				hasDecorator = true
+			}
+		} else if evt.Kind == parser.EventClose {
+			targetDepth--
+			if targetDepth == 0 {
+				p.pos++ // Move past CLOSE NodeRedirectTarget
+				break
+			}
+		} else if evt.Kind == parser.EventToken {
+			targetTokens = append(targetTokens, evt.Data)
+		}
+
+		p.pos++
+	}
+
+	return targetTokens, hasDecorator
+}
+
+// tokensText joins tokens into text, keeping the original spacing.
+func (p *planner) tokensText(tokenIndices []uint32) string {
+	text := ""
+	for i, tokenIdx := range tokenIndices {
+		token := p.tokens[tokenIdx]
+
+		if i > 0 && token.HasSpaceBefore {
+			text += " "
+		}
+
+		text += getTokenText(token)
+	}
+	return text
+}
+
+// isRedirectInSource reports whether decorator name (without @) can be read
+// from with <, rather than naming a file with its value.
+func isRedirectInSource(name string) bool {
+	if entry, ok := decorator.Global().Lookup(name); ok {
+		return entry.Impl.Descriptor().Schema.RedirectIn
+	}
+	schema, ok := types.Global().GetSchema(name)
+	return ok && schema.RedirectIn
+}
+
 // parseVarValue parses a variable value expression (literal, object, or array)
 func (p *planner) parseVarValue(varName string) (any, error) {
 	if p.pos >= len(p.events) {
@@ -1608,7 +1887,7 @@ func (p *planner) parseObjectLiteral(varName string) (any, error) {
 					}
 				}
 				keyTokenIdx := p.events[p.pos].Data
-				key := string(p.tokens[keyTokenIdx].Text)
+				key := unquote(string(p.tokens[keyTokenIdx].Text))
 				p.pos++ // Move past key token
 
 				// Skip colon token
@@ -1709,7 +1988,6 @@ func (p *planner) parseDecoratorValue(varName string) (any, error) {
 	// Extract decorator name and property from tokens
 	// Expected structure: TOKEN(@), TOKEN(decorator), TOKEN(.), TOKEN(property)
 	var decoratorParts []string
-	var primary *string
 
 	for p.pos < len(p.events) {
 		evt := p.events[p.pos]
@@ -1749,6 +2027,14 @@ func (p *planner) parseDecoratorValue(varName string) (any, error) {
 			//   @aws.ssm.param → path="aws.ssm", primary="param"
 			decoratorParts = append(decoratorParts, string(tok.Text))
 			p.pos++
+		case lexer.VAR:
+			// @var.NAME, @var.config.db
+			decoratorParts = append(decoratorParts, "var")
+			p.pos++
+		case lexer.FLOAT:
+			// Array index after a field: @var.hosts.0
+			decoratorParts = append(decoratorParts, strings.TrimPrefix(string(tok.Text), "."))
+			p.pos++
 		case lexer.DOT:
 			// Separator between decorator and property
 			p.pos++
@@ -1777,10 +2063,30 @@ func (p *planner) parseDecoratorValue(varName string) (any, error) {
 		p.pos++
 	}
 
+	// Skip CLOSE VarDecl (like the literal parsers, so callers tracking
+	// depth see the declaration as closed)
+	if p.pos < len(p.events) && p.events[p.pos].Kind == parser.EventClose {
+		p.pos++
+	}
+
+	// Another variable or one of its fields: var DB = @var.config.db
+	if len(decoratorParts) > 2 && decoratorParts[0] == "var" {
+		return p.resolveVarReference(strings.Join(decoratorParts[1:], "."), "value")
+	}
+
+	return p.resolveValueDecorator(decoratorParts, fmt.Sprintf("parsing variable '%s'", varName), startPos)
+}
+
+// resolveValueDecorator resolves a value decorator reference such as
+// @env.HOME (parts ["env", "HOME"]) through the global registry.
+// context describes the caller for error messages.
+func (p *planner) resolveValueDecorator(decoratorParts []string, context string, startPos int) (any, error) {
+	var primary *string
+
 	if len(decoratorParts) == 0 {
 		return nil, &PlanError{
 			Message:     "empty decorator name",
-			Context:     fmt.Sprintf("parsing variable '%s'", varName),
+			Context:     context,
 			EventPos:    startPos,
 			TotalEvents: len(p.events),
 		}
@@ -1813,7 +2119,7 @@ func (p *planner) parseDecoratorValue(varName string) (any, error) {
 					Message: fmt.Sprintf("decorator @%s: found registered decorator %q but %d segments remain (%s); only 1 primary parameter allowed",
 						strings.Join(decoratorParts, "."), candidatePath, remainingSegments,
 						strings.Join(decoratorParts[splitPoint:], ".")),
-					Context:     fmt.Sprintf("parsing variable '%s'", varName),
+					Context:     context,
 					EventPos:    startPos,
 					TotalEvents: len(p.events),
 				}
@@ -1831,7 +2137,7 @@ func (p *planner) parseDecoratorValue(varName string) (any, error) {
 	if decoratorName == "" {
 		return nil, &PlanError{
 			Message:     fmt.Sprintf("decorator @%s not found in registry", strings.Join(decoratorParts, ".")),
-			Context:     fmt.Sprintf("parsing variable '%s'", varName),
+			Context:     context,
 			EventPos:    startPos,
 			TotalEvents: len(p.events),
 		}
@@ -1854,11 +2160,12 @@ func (p *planner) parseDecoratorValue(varName string) (any, error) {
 	currentScope := p.session.TransportScope()
 
 	// Resolve decorator using global registry
+	p.recordDecorator(decoratorName)
 	result, err := decorator.ResolveValue(ctx, call, currentScope)
 	if err != nil {
 		return nil, &PlanError{
 			Message:     fmt.Sprintf("failed to resolve @%s: %v", decoratorName, err),
-			Context:     fmt.Sprintf("parsing variable '%s'", varName),
+			Context:     context,
 			EventPos:    startPos,
 			TotalEvents: len(p.events),
 		}
@@ -1883,6 +2190,12 @@ func (p *planner) parseDecoratorValue(varName string) (any, error) {
 //
 // This is Pass 2 - builds IR, captures exprIDs, validates (hoisting check), marks touched.
 func (p *planner) buildCommandIR(command string) (*CommandIR, error) {
+	return p.buildInterpolationIR(command, "command")
+}
+
+// buildInterpolationIR builds the IR for text interpolated into parameter
+// paramName, recording each @var reference as a use site of that parameter.
+func (p *planner) buildInterpolationIR(command, paramName string) (*CommandIR, error) {
 	if p.config.Debug >= DebugDetailed {
 		p.recordDebugEvent("buildCommandIR", fmt.Sprintf("command=%s", command))
 	}
@@ -1934,6 +2247,22 @@ func (p *planner) buildCommandIR(command string) (*CommandIR, error) {
 
 		varName := command[varStart:varEnd]
 
+		// Candidate field path: @var.config.db.password
+		var fields []string
+		var fieldEnds []int
+		for end := varEnd; end < len(command) && command[end] == '.'; {
+			start := end + 1
+			end = start
+			for end < len(command) && (isAlphaNumeric(command[end]) || command[end] == '_') {
+				end++
+			}
+			if end == start {
+				break
+			}
+			fields = append(fields, command[start:end])
+			fieldEnds = append(fieldEnds, end)
+		}
+
 		// Lookup variable in Vault (captures exprID at this point in time)
 		// This is where hoisting validation happens - if variable not declared yet, error
 		exprID, err := p.vault.LookupVariable(varName)
@@ -1941,15 +2270,29 @@ func (p *planner) buildCommandIR(command string) (*CommandIR, error) {
 			return nil, fmt.Errorf("variable %q not found: %w", varName, err)
 		}
 
+		// Fields of objects and arrays are tracked on their own. The path
+		// ends at a value without fields, so "@var.FILE.txt" keeps ".txt".
+		if len(fields) > 0 {
+			fieldID, consumed, err := p.trackField(varName, exprID, fields)
+			if err != nil {
+				return nil, err
+			}
+			if consumed > 0 {
+				exprID = fieldID
+				varEnd = fieldEnds[consumed-1]
+			}
+		}
+
 		// DEBUG: Log captured exprID
 		if p.config.Debug >= DebugDetailed {
 			p.recordDebugEvent("buildCommandIR", fmt.Sprintf("captured exprID=%s for var=%s in command=%s", exprID, varName, command))
 		}
 
 		// Record reference (authorize this site)
-		if err := p.vault.RecordReference(exprID, "command"); err != nil {
+		if err := p.vault.RecordReference(exprID, paramName); err != nil {
 			return nil, err
 		}
+		p.recordDecorator("var")
 
 		// Mark as touched (in execution path)
 		p.vault.MarkTouched(exprID)
@@ -2069,6 +2412,22 @@ func (p *planner) interpolateStepTree(node *planfmt.ExecutionNode) error {
 			}
 		}
 
+	case *planfmt.GroupNode:
+		for i := range n.Steps {
+			if err := p.interpolateStepTree(&n.Steps[i].Tree); err != nil {
+				return err
+			}
+		}
+
+	case *planfmt.TryNode:
+		for _, block := range [][]planfmt.Step{n.Try, n.Catch, n.Finally} {
+			for i := range block {
+				if err := p.interpolateStepTree(&block[i].Tree); err != nil {
+					return err
+				}
+			}
+		}
+
 	case *planfmt.RedirectNode:
 		if err := p.interpolateStepTree(&n.Source); err != nil {
 			return err
@@ -2078,6 +2437,15 @@ func (p *planner) interpolateStepTree(node *planfmt.ExecutionNode) error {
 		if err := p.interpolateStepTree(&targetNode); err != nil {
 			return err
 		}
+
+	case *planfmt.RedirectInNode:
+		if err := p.interpolateStepTree(&n.Command); err != nil {
+			return err
+		}
+		var inputNode planfmt.ExecutionNode = &n.Input
+		if err := p.interpolateStepTree(&inputNode); err != nil {
+			return err
+		}
 	}
 
 	return nil
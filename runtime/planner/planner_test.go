@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/core/planfmt/formatter"
 	"github.com/opal-lang/opal/runtime/lexer"
 	"github.com/opal-lang/opal/runtime/parser"
 	"github.com/opal-lang/opal/runtime/planner"
@@ -938,6 +939,95 @@ func TestRedirectWithChaining(t *testing.T) {
 	}
 }
 
+// TestInputRedirect tests input redirection (<) and its place in the tree
+func TestInputRedirect(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "read from file",
+			input: `sort < data.txt`,
+			want:  `@shell sort < @shell data.txt`,
+		},
+		{
+			name:  "read and write",
+			input: `sort < in.txt > out.txt`,
+			want:  `@shell sort < @shell in.txt > @shell out.txt`,
+		},
+		{
+			name:  "read at the start of a pipeline",
+			input: `cat < in.txt | sort`,
+			want:  `@shell cat < @shell in.txt | @shell sort`,
+		},
+		{
+			name:  "read at the end of a pipeline",
+			input: `echo ignored | sort < in.txt`,
+			want:  `@shell echo ignored | @shell sort < @shell in.txt`,
+		},
+		{
+			name:  "read then AND",
+			input: `wc -l < in.txt && echo done`,
+			want:  `@shell wc -l < @shell in.txt && @shell echo done`,
+		},
+		{
+			name:  "read from decorator",
+			input: `sort < @shell("data.txt")`,
+			want:  `@shell sort < @shell data.txt`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := parser.Parse([]byte(tt.input))
+			if len(tree.Errors) > 0 {
+				t.Fatalf("Parse errors: %v", tree.Errors)
+			}
+
+			result, err := planner.Plan(tree.Events, tree.Tokens, planner.Config{})
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+			if len(result.Steps) != 1 {
+				t.Fatalf("Expected 1 step, got %d", len(result.Steps))
+			}
+
+			if got := formatter.FormatStep(&result.Steps[0]); got != tt.want {
+				t.Errorf("step = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestInputRedirectVarPath tests that a variable in an input path is
+// resolved at plan time and recorded as a use site
+func TestInputRedirectVarPath(t *testing.T) {
+	tree := parser.Parse([]byte(`var IN = "data.txt"
+sort < @var.IN`))
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+
+	plan, err := planner.Plan(tree.Events, tree.Tokens, planner.Config{
+		Vault: vault.NewWithPlanKey(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	redirect, ok := plan.Steps[0].Tree.(*planfmt.RedirectInNode)
+	if !ok {
+		t.Fatalf("Expected RedirectInNode, got %T", plan.Steps[0].Tree)
+	}
+	if path := getCommandArg(&redirect.Input, "command"); !strings.HasPrefix(path, "opal:") {
+		t.Errorf("Expected DisplayID in input path, got %q", path)
+	}
+	if len(plan.SecretUses) != 1 {
+		t.Errorf("Expected 1 SecretUse, got %+v", plan.SecretUses)
+	}
+}
+
 // TestPlannerInitialization tests that planner initializes with empty vars map and telemetry
 func TestPlannerInitialization(t *testing.T) {
 	source := []byte(`echo "test"`)
@@ -0,0 +1,209 @@
+package planner
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/runtime/parser"
+	"golang.org/x/crypto/blake2b"
+)
+
+// buildFingerprint is computed once per process (build info never changes).
+var buildFingerprint = sync.OnceValue(readBuildFingerprint)
+
+// BuildFingerprint identifies the running opal build: main module path and
+// version, Go version, and VCS revision when the binary was built from a
+// checkout (with "+dirty" for uncommitted changes).
+func BuildFingerprint() string {
+	return buildFingerprint()
+}
+
+func readBuildFingerprint() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	parts := []string{info.Main.Path}
+	if info.Main.Version != "" {
+		parts = append(parts, info.Main.Version)
+	}
+	parts = append(parts, info.GoVersion)
+
+	var revision string
+	var modified bool
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if revision != "" {
+		if len(revision) > 12 {
+			revision = revision[:12]
+		}
+		if modified {
+			revision += "+dirty"
+		}
+		parts = append(parts, "rev="+revision)
+	}
+	return strings.Join(parts, " ")
+}
+
+// recordSourceUnit marks the node opened at pos (a function or top-level
+// declaration) as part of the source the plan is built from.
+func (p *planner) recordSourceUnit(pos int) {
+	for _, unit := range p.sourceUnits {
+		if unit == pos {
+			return
+		}
+	}
+	p.sourceUnits = append(p.sourceUnits, pos)
+}
+
+// recordDecorator notes a decorator used while planning (e.g., a value
+// decorator that is resolved and does not appear in the tree).
+func (p *planner) recordDecorator(path string) {
+	if p.decoratorsUsed == nil {
+		p.decoratorsUsed = make(map[string]bool)
+	}
+	p.decoratorsUsed[strings.TrimPrefix(path, "@")] = true
+}
+
+// provenance builds the plan's provenance once its steps are planned.
+func (p *planner) provenance(steps []planfmt.Step) planfmt.Provenance {
+	for i := range steps {
+		p.collectDecorators(steps[i].Tree)
+	}
+
+	paths := make([]string, 0, len(p.decoratorsUsed))
+	for path := range p.decoratorsUsed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	pv := planfmt.Provenance{
+		SourceDigest: p.sourceDigest(),
+	}
+	for _, path := range paths {
+		var version string
+		if entry, ok := decorator.Global().Lookup(path); ok {
+			version = entry.Impl.Descriptor().Version
+		}
+		pv.Decorators = append(pv.Decorators, planfmt.DecoratorVersion{Path: path, Version: version})
+	}
+	return pv
+}
+
+// collectDecorators records every decorator in an execution tree.
+func (p *planner) collectDecorators(node planfmt.ExecutionNode) {
+	collectSteps := func(steps []planfmt.Step) {
+		for i := range steps {
+			p.collectDecorators(steps[i].Tree)
+		}
+	}
+
+	switch n := node.(type) {
+	case *planfmt.CommandNode:
+		p.recordDecorator(n.Decorator)
+		collectSteps(n.Block)
+	case *planfmt.PipelineNode:
+		for _, cmd := range n.Commands {
+			p.collectDecorators(cmd)
+		}
+	case *planfmt.AndNode:
+		p.collectDecorators(n.Left)
+		p.collectDecorators(n.Right)
+	case *planfmt.OrNode:
+		p.collectDecorators(n.Left)
+		p.collectDecorators(n.Right)
+	case *planfmt.SequenceNode:
+		for _, child := range n.Nodes {
+			p.collectDecorators(child)
+		}
+	case *planfmt.RedirectNode:
+		p.collectDecorators(n.Source)
+		p.collectDecorators(&n.Target)
+	case *planfmt.RedirectInNode:
+		p.collectDecorators(n.Command)
+		p.collectDecorators(&n.Input)
+	case *planfmt.GroupNode:
+		if n.Kind == "cmd" {
+			p.recordDecorator("cmd")
+		}
+		collectSteps(n.Steps)
+	case *planfmt.TryNode:
+		collectSteps(n.Try)
+		collectSteps(n.Catch)
+		collectSteps(n.Finally)
+	}
+}
+
+// sourceDigest hashes the source tokens the plan was built from: the whole
+// script in script mode, otherwise the target function, the functions it
+// calls and the top-level declarations in scope. Unrelated functions and
+// formatting do not change it, so they do not invalidate contracts.
+func (p *planner) sourceDigest() [32]byte {
+	hasher, err := blake2b.New256(nil)
+	if err != nil {
+		panic(fmt.Sprintf("blake2b: %v", err)) // Only fails for invalid keys
+	}
+
+	writeTokens := func(start, end int) {
+		var lenBuf [4]byte
+		for _, evt := range p.events[start:end] {
+			if evt.Kind != parser.EventToken {
+				continue
+			}
+			tok := p.tokens[evt.Data]
+			binary.LittleEndian.PutUint32(lenBuf[:], uint32(len(tok.Text)))
+			flags := byte(0)
+			if tok.HasSpaceBefore {
+				flags = 1
+			}
+			_, _ = hasher.Write([]byte{byte(tok.Type), flags})
+			_, _ = hasher.Write(lenBuf[:])
+			_, _ = hasher.Write(tok.Text)
+		}
+	}
+
+	if p.config.Target == "" {
+		writeTokens(0, len(p.events))
+	} else {
+		units := append([]int(nil), p.sourceUnits...)
+		sort.Ints(units)
+		for _, start := range units {
+			_, _ = hasher.Write([]byte{0xff}) // Unit separator
+			writeTokens(start, p.nodeEnd(start))
+		}
+	}
+
+	var digest [32]byte
+	copy(digest[:], hasher.Sum(nil))
+	return digest
+}
+
+// nodeEnd returns the position after the CLOSE event matching the OPEN at start.
+func (p *planner) nodeEnd(start int) int {
+	depth := 0
+	for pos := start; pos < len(p.events); pos++ {
+		switch p.events[pos].Kind {
+		case parser.EventOpen:
+			depth++
+		case parser.EventClose:
+			depth--
+			if depth == 0 {
+				return pos + 1
+			}
+		}
+	}
+	return len(p.events)
+}
@@ -0,0 +1,117 @@
+package planner_test
+
+import (
+	"testing"
+
+	"github.com/opal-lang/opal/core/planfmt"
+	_ "github.com/opal-lang/opal/runtime/decorators" // Register decorators for provenance versions
+	"github.com/opal-lang/opal/runtime/parser"
+	"github.com/opal-lang/opal/runtime/planner"
+	"github.com/opal-lang/opal/runtime/vault"
+)
+
+func planProvenanceSource(t *testing.T, source, target string) *planfmt.Plan {
+	t.Helper()
+	tree := parser.ParseString(source)
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+	plan, err := planner.Plan(tree.Events, tree.Tokens, planner.Config{
+		Target: target,
+		Vault:  vault.NewWithPlanKey(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	return plan
+}
+
+// TestPlanProvenance verifies plans record the build, a source digest and
+// the version of every decorator they use
+func TestPlanProvenance(t *testing.T) {
+	t.Setenv("OPAL_TEST_REGION", "eu-west-1")
+	plan := planProvenanceSource(t, `var REGION = @env.OPAL_TEST_REGION
+fun deploy {
+    @retry(times=2) { echo "deploy @var.REGION" > out.txt }
+    @cmd.notify()
+}
+fun notify = echo "done"`, "deploy")
+
+	pv := plan.Provenance
+	if plan.Header.Build != planner.BuildFingerprint() || plan.Header.Build == "" {
+		t.Errorf("Build = %q, want build fingerprint %q", plan.Header.Build, planner.BuildFingerprint())
+	}
+	if pv.SourceDigest == [32]byte{} {
+		t.Error("SourceDigest should be set")
+	}
+	if plan.Header.Compiler != planfmt.CompilerID(plan.Header.Build) {
+		t.Error("Header.Compiler should be derived from the fingerprint")
+	}
+	if plan.Header.SchemaID != planfmt.SchemaID {
+		t.Error("Header.SchemaID should be set")
+	}
+
+	var paths []string
+	for _, d := range pv.Decorators {
+		paths = append(paths, d.Path)
+		if d.Version == "" {
+			t.Errorf("decorator %s has no version", d.Path)
+		}
+	}
+	want := []string{"cmd", "env", "retry", "shell", "var"}
+	if len(paths) != len(want) {
+		t.Fatalf("Decorators = %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Fatalf("Decorators = %v, want %v", paths, want)
+		}
+	}
+}
+
+// TestSourceDigestScope verifies the digest covers the source the plan is
+// built from, and nothing else
+func TestSourceDigestScope(t *testing.T) {
+	base := `var UNUSED = "a"
+fun hello = echo "Hello"
+fun log = echo "Log"`
+
+	tests := []struct {
+		name    string
+		source  string
+		changes bool
+	}{
+		{"unrelated function edited", `var UNUSED = "a"
+fun hello = echo "Hello"
+fun log = echo "Different log"`, false},
+		{"formatting", `var UNUSED   =   "a"
+
+fun hello = echo "Hello"
+fun log = echo "Log"`, false},
+		{"unused declaration edited", `var UNUSED = "b"
+fun hello = echo "Hello"
+fun log = echo "Log"`, true},
+		{"target edited", `var UNUSED = "a"
+fun hello = echo "Hello!"
+fun log = echo "Log"`, true},
+	}
+
+	baseDigest := planProvenanceSource(t, base, "hello").Provenance.SourceDigest
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest := planProvenanceSource(t, tt.source, "hello").Provenance.SourceDigest
+			if changed := digest != baseDigest; changed != tt.changes {
+				t.Errorf("digest changed = %v, want %v", changed, tt.changes)
+			}
+		})
+	}
+
+	// Script mode plans the whole file
+	script := planProvenanceSource(t, base, "").Provenance.SourceDigest
+	edited := planProvenanceSource(t, `var UNUSED = "a"
+fun hello = echo "Hello"
+fun log = echo "Different log"`, "").Provenance.SourceDigest
+	if script == edited {
+		t.Error("script mode digest should cover every function")
+	}
+}
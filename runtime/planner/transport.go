@@ -0,0 +1,77 @@
+package planner
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+// displayIDPattern matches DisplayID placeholders (opal:<22 base64url chars>).
+var displayIDPattern = regexp.MustCompile(`opal:[A-Za-z0-9_-]{22}`)
+
+// enterTransport opens the session for a transport decorator block
+// (@ssh.connect) and makes it current, so value decorators inside the
+// block (@env.HOME) read from the remote session and are bound to it in the
+// vault. Connecting here also means an unreachable host fails the plan
+// before anything runs. Returns a function restoring the enclosing session.
+// Expects the vault positioned at the decorator's step.
+func (p *planner) enterTransport(decoratorName string, transport decorator.Transport, args []planfmt.Arg) (func(), error) {
+	params, err := p.transportParams(decoratorName, args)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := p.sessions.GetOrCreate(transport, p.session, decorator.SessionParams(params))
+	if err != nil {
+		return nil, &PlanError{
+			Message:     fmt.Sprintf("%s failed to connect: %v", decoratorName, err),
+			Context:     "opening transport",
+			EventPos:    p.pos,
+			TotalEvents: len(p.events),
+			Suggestion:  "Transports connect while planning, so the host must be reachable",
+		}
+	}
+	if env := decorator.EnvParams(params); len(env) > 0 {
+		session = session.WithEnv(env)
+	}
+
+	parent := p.session
+	p.session = session
+	p.vault.EnterTransport(session.ID())
+
+	if p.config.Debug >= DebugDetailed {
+		p.recordDebugEvent("transport_enter", fmt.Sprintf("name=%s session=%s", decoratorName, session.ID()))
+	}
+
+	return func() {
+		p.vault.ExitTransport()
+		p.session = parent
+	}, nil
+}
+
+// transportParams converts planned args to the params a transport opens
+// with, swapping DisplayIDs back for their values. Only the connection sees
+// the values; the plan keeps the DisplayIDs.
+func (p *planner) transportParams(decoratorName string, args []planfmt.Arg) (map[string]any, error) {
+	params := planfmt.ToSDKArgs(args)
+	for key, value := range params {
+		str, ok := value.(string)
+		if !ok || !displayIDPattern.MatchString(str) {
+			continue
+		}
+		var resolveErr error
+		params[key] = displayIDPattern.ReplaceAllStringFunc(str, func(displayID string) string {
+			actual, err := p.vault.AccessByDisplayID(displayID, key)
+			if err != nil && resolveErr == nil {
+				resolveErr = err
+			}
+			return valueText(actual)
+		})
+		if resolveErr != nil {
+			return nil, fmt.Errorf("failed to resolve %s.%s: %w", decoratorName, key, resolveErr)
+		}
+	}
+	return params, nil
+}
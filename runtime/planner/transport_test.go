@@ -0,0 +1,82 @@
+package planner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/decorator"
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+// sshBlock returns an @ssh.connect call against the test server wrapping body.
+func sshBlock(t *testing.T, srv *decorator.SSHTestServer, extra, body string) string {
+	t.Helper()
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyPath, srv.ClientKeyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf(`@ssh.connect(host="127.0.0.1", port=%d, user="opal", key=%q, strict_host_key=false%s) {
+%s
+}`, srv.Port, keyPath, extra, body)
+}
+
+func TestTransport_EnvObjectFlattened(t *testing.T) {
+	srv := decorator.StartSSHTestServer(t)
+	plan, err := planLoopSource(t, sshBlock(t, srv, `, env={GREETING: "hi", "APP_MODE": "prod"}`, `echo "x"`), "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	cmd, ok := plan.Steps[0].Tree.(*planfmt.CommandNode)
+	if !ok || cmd.Decorator != "@ssh.connect" {
+		t.Fatalf("Expected @ssh.connect CommandNode, got %#v", plan.Steps[0].Tree)
+	}
+	for key, want := range map[string]string{"env.GREETING": "hi", "env.APP_MODE": "prod"} {
+		if got := getCommandArg(cmd, key); got != want {
+			t.Errorf("Expected %s=%q, got %q", key, want, got)
+		}
+	}
+	if len(cmd.Block) != 1 {
+		t.Errorf("Expected 1 step in block, got %d", len(cmd.Block))
+	}
+}
+
+func TestTransport_EnvReadsRemoteSession(t *testing.T) {
+	srv := decorator.StartSSHTestServer(t)
+	// GREETING only exists in the remote session's env
+	t.Setenv("GREETING", "")
+	os.Unsetenv("GREETING")
+	body := `var g = @env.GREETING
+echo "@var.g"`
+
+	if _, err := planLoopSource(t, sshBlock(t, srv, `, env={GREETING: "hi"}`, body), ""); err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if _, err := planLoopSource(t, body, ""); err == nil {
+		t.Fatal("Expected @env.GREETING to be missing outside the block")
+	}
+}
+
+func TestTransport_ConnectFailureIsPlanError(t *testing.T) {
+	srv := decorator.StartSSHTestServer(t)
+	source := strings.Replace(sshBlock(t, srv, "", `echo "x"`), fmt.Sprintf("port=%d", srv.Port), "port=1", 1)
+	_, err := planLoopSource(t, source, "")
+	if err == nil || !strings.Contains(err.Error(), "@ssh.connect failed to connect") {
+		t.Fatalf("Expected connect error, got %v", err)
+	}
+}
+
+func TestTransport_DockerContainerMustBeRunning(t *testing.T) {
+	decorator.InstallFakeDocker(t, "/", "app")
+
+	if _, err := planLoopSource(t, `@docker.exec(container="app") { echo "x" }`, ""); err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	_, err := planLoopSource(t, `@docker.exec(container="db") { echo "x" }`, "")
+	if err == nil || !strings.Contains(err.Error(), "No such container: db") {
+		t.Fatalf("Expected missing container error, got %v", err)
+	}
+}
@@ -23,11 +23,7 @@ func buildStepTree(commands []Command) planfmt.ExecutionNode {
 
 		// Check if this command has a redirect
 		if cmd.RedirectMode != "" && cmd.RedirectTarget != nil {
-			source := &planfmt.CommandNode{
-				Decorator: cmd.Decorator,
-				Args:      cmd.Args,
-				Block:     cmd.Block,
-			}
+			source := commandWithInput(cmd)
 
 			target := commandToNode(*cmd.RedirectTarget)
 
@@ -44,7 +40,7 @@ func buildStepTree(commands []Command) planfmt.ExecutionNode {
 		}
 
 		// No redirect - just return the command
-		return commandToNode(cmd)
+		return commandWithInput(cmd)
 	}
 
 	// Parse operators by precedence (lowest to highest)
@@ -71,7 +67,7 @@ func buildStepTree(commands []Command) planfmt.ExecutionNode {
 	}
 
 	// No operators found - single command
-	return commandToNode(commands[0])
+	return commandWithInput(commands[0])
 }
 
 // commandToNode converts planfmt.Command to CommandNode
@@ -83,6 +79,30 @@ func commandToNode(cmd Command) *planfmt.CommandNode {
 	}
 }
 
+// commandWithInput converts cmd to a CommandNode, reading its stdin from
+// its input redirect (cmd < file) if it has one
+func commandWithInput(cmd Command) planfmt.ExecutionNode {
+	node := commandToNode(cmd)
+	if cmd.RedirectIn == nil {
+		return node
+	}
+	return &planfmt.RedirectInNode{
+		Command: node,
+		Input:   *commandToNode(*cmd.RedirectIn),
+	}
+}
+
+// isPipelineElement reports whether node can be an element of a pipeline:
+// a command, possibly with its input or output redirected
+func isPipelineElement(node planfmt.ExecutionNode) bool {
+	switch node.(type) {
+	case *planfmt.CommandNode, *planfmt.RedirectNode, *planfmt.RedirectInNode:
+		return true
+	default:
+		return false
+	}
+}
+
 // parseSemicolon splits on semicolon operators (lowest precedence)
 func parseSemicolon(commands []Command) planfmt.ExecutionNode {
 	var segments [][]Command
@@ -213,16 +233,11 @@ func parsePipeAndRedirect(commands []Command) planfmt.ExecutionNode {
 
 				// Create pipeline with redirect on left
 				switch rightNode := right.(type) {
-				case *planfmt.CommandNode:
+				case *planfmt.CommandNode, *planfmt.RedirectNode, *planfmt.RedirectInNode:
 					// redirect | cmd → PipelineNode([redirect, cmd])
 					return &planfmt.PipelineNode{
 						Commands: []planfmt.ExecutionNode{redirectNode, rightNode},
 					}
-				case *planfmt.RedirectNode:
-					// redirect | redirect → PipelineNode([redirect, redirect])
-					return &planfmt.PipelineNode{
-						Commands: []planfmt.ExecutionNode{redirectNode, rightNode},
-					}
 				case *planfmt.PipelineNode:
 					// redirect | pipeline → PipelineNode([redirect, ...pipeline])
 					nodes := make([]planfmt.ExecutionNode, 1+len(rightNode.Commands))
@@ -251,45 +266,22 @@ func parsePipeAndRedirect(commands []Command) planfmt.ExecutionNode {
 				rightCmds := commands[i+1:]
 				right := buildStepTree(rightCmds)
 
-				// Try to flatten into PipelineNode
-				leftCmd, leftIsCmd := left.(*planfmt.CommandNode)
-				rightCmd, rightIsCmd := right.(*planfmt.CommandNode)
-				rightPipe, rightIsPipe := right.(*planfmt.PipelineNode)
-				leftRedirect, leftIsRedirect := left.(*planfmt.RedirectNode)
-				rightRedirect, rightIsRedirect := right.(*planfmt.RedirectNode)
-
-				if leftIsCmd && rightIsCmd {
-					// Simple case: cmd | cmd
-					return &planfmt.PipelineNode{
-						Commands: []planfmt.ExecutionNode{leftCmd, rightCmd},
-					}
-				} else if leftIsCmd && rightIsPipe {
-					// Flatten: cmd | (cmd | cmd | ...) → cmd | cmd | cmd | ...
-					nodes := make([]planfmt.ExecutionNode, 1+len(rightPipe.Commands))
-					nodes[0] = leftCmd
-					copy(nodes[1:], rightPipe.Commands)
-					return &planfmt.PipelineNode{Commands: nodes}
-				} else if leftIsCmd && rightIsRedirect {
-					// cmd | (cmd > file) → pipeline with redirect at end
-					return &planfmt.PipelineNode{
-						Commands: []planfmt.ExecutionNode{leftCmd, rightRedirect},
+				// Try to flatten into PipelineNode. Elements are commands,
+				// possibly redirected: cmd | (cmd > file), (cmd < file) | cmd
+				if isPipelineElement(left) {
+					if rightPipe, ok := right.(*planfmt.PipelineNode); ok {
+						// Flatten: cmd | (cmd | cmd | ...) → cmd | cmd | cmd | ...
+						nodes := make([]planfmt.ExecutionNode, 1+len(rightPipe.Commands))
+						nodes[0] = left
+						copy(nodes[1:], rightPipe.Commands)
+						return &planfmt.PipelineNode{Commands: nodes}
 					}
-				} else if leftIsRedirect && rightIsCmd {
-					// (cmd > file) | cmd → pipeline with redirect at start
-					return &planfmt.PipelineNode{
-						Commands: []planfmt.ExecutionNode{leftRedirect, rightCmd},
+					if isPipelineElement(right) {
+						// Simple case: cmd | cmd
+						return &planfmt.PipelineNode{
+							Commands: []planfmt.ExecutionNode{left, right},
+						}
 					}
-				} else if leftIsRedirect && rightIsRedirect {
-					// (cmd > file1) | (cmd > file2) → pipeline with two redirects
-					return &planfmt.PipelineNode{
-						Commands: []planfmt.ExecutionNode{leftRedirect, rightRedirect},
-					}
-				} else if leftIsRedirect && rightIsPipe {
-					// (cmd > file) | (cmd | cmd | ...) → pipeline with redirect at start
-					nodes := make([]planfmt.ExecutionNode, 1+len(rightPipe.Commands))
-					nodes[0] = leftRedirect
-					copy(nodes[1:], rightPipe.Commands)
-					return &planfmt.PipelineNode{Commands: nodes}
 				}
 
 				// Complex case: one side is not a simple command/redirect
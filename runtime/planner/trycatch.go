@@ -0,0 +1,92 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/opal-lang/opal/core/invariant"
+	"github.com/opal-lang/opal/core/planfmt"
+	"github.com/opal-lang/opal/runtime/parser"
+)
+
+// planTry plans a try/catch/finally statement.
+// Expects p.pos at OPEN Try, leaves position after CLOSE Try.
+//
+// Event structure:
+//
+//	OPEN Try, TOKEN(try), OPEN Block, ..., CLOSE Block,
+//	[OPEN Catch, TOKEN(catch), OPEN Block, ..., CLOSE Block, CLOSE Catch],
+//	[OPEN Finally, TOKEN(finally), OPEN Block, ..., CLOSE Block, CLOSE Finally],
+//	CLOSE Try
+//
+// Unlike if/when, every block is planned: which one runs depends on exit
+// codes at execution time. Each block is scope-isolated, so steps in the
+// catch block of step N resolve secrets at "step-N/@catch[N]/...".
+func (p *planner) planTry() (planfmt.Step, error) {
+	// PRECONDITION: Must be at OPEN Try
+	invariant.Precondition(p.atOpen(parser.NodeTry), "planTry must start at OPEN Try")
+
+	id := p.nextStepID()
+	p.vault.Push(fmt.Sprintf("step-%d", id))
+	defer p.vault.Pop()
+
+	startPos := p.pos
+	p.pos++ // Move past OPEN Try
+	p.pos++ // Skip TOKEN(try)
+
+	node := &planfmt.TryNode{}
+	var err error
+	node.Try, err = p.planTryBlock("try", int(id), startPos)
+	if err != nil {
+		return planfmt.Step{}, err
+	}
+
+	if p.atOpen(parser.NodeCatch) {
+		p.pos++ // Move past OPEN Catch
+		p.pos++ // Skip TOKEN(catch)
+		node.HasCatch = true
+		node.Catch, err = p.planTryBlock("catch", int(id), startPos)
+		if err != nil {
+			return planfmt.Step{}, err
+		}
+		p.skipToClose(parser.NodeCatch)
+	}
+
+	if p.atOpen(parser.NodeFinally) {
+		p.pos++ // Move past OPEN Finally
+		p.pos++ // Skip TOKEN(finally)
+		node.HasFinally = true
+		node.Finally, err = p.planTryBlock("finally", int(id), startPos)
+		if err != nil {
+			return planfmt.Step{}, err
+		}
+		p.skipToClose(parser.NodeFinally)
+	}
+
+	// Move past CLOSE Try
+	p.skipToClose(parser.NodeTry)
+
+	if p.config.Debug >= DebugDetailed {
+		p.recordDebugEvent("try_planned", fmt.Sprintf("try=%d catch=%d finally=%d",
+			len(node.Try), len(node.Catch), len(node.Finally)))
+	}
+
+	return planfmt.Step{ID: id, Tree: node}, nil
+}
+
+// planTryBlock plans the block at p.pos in its own "@<name>[id]" scope.
+func (p *planner) planTryBlock(name string, id, startPos int) ([]planfmt.Step, error) {
+	if !p.atOpen(parser.NodeBlock) {
+		return nil, &PlanError{
+			Message:     fmt.Sprintf("%s has no body", name),
+			Context:     "planning try statement",
+			EventPos:    startPos,
+			TotalEvents: len(p.events),
+		}
+	}
+	p.pos++ // Move past OPEN Block
+
+	p.vault.PushAt("@"+name, id)
+	defer p.vault.Pop()
+
+	return p.planBlockSteps()
+}
@@ -0,0 +1,166 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opal-lang/opal/core/planfmt"
+)
+
+// singleTry asserts steps is one try node and returns it.
+func singleTry(t *testing.T, steps []planfmt.Step) *planfmt.TryNode {
+	t.Helper()
+	if len(steps) != 1 {
+		t.Fatalf("Expected 1 step, got %d", len(steps))
+	}
+	node, ok := steps[0].Tree.(*planfmt.TryNode)
+	if !ok {
+		t.Fatalf("Expected TryNode, got %T", steps[0].Tree)
+	}
+	return node
+}
+
+// blockCommands returns the shell commands directly inside a block.
+func blockCommands(steps []planfmt.Step) []string {
+	var commands []string
+	for _, step := range steps {
+		commands = append(commands, getCommandArg(step.Tree, "command"))
+	}
+	return commands
+}
+
+func TestTry_PlansAllBlocks(t *testing.T) {
+	plan, err := planLoopSource(t, `
+try {
+    kubectl apply -f k8s/
+    kubectl rollout status deployment/app
+} catch {
+    kubectl rollout undo deployment/app
+} finally {
+    echo "cleanup"
+}
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	node := singleTry(t, plan.Steps)
+	if !node.HasCatch || !node.HasFinally {
+		t.Errorf("Expected catch and finally, got HasCatch=%t HasFinally=%t", node.HasCatch, node.HasFinally)
+	}
+	if got := blockCommands(node.Try); strings.Join(got, "\n") != "kubectl apply -f k8s/\nkubectl rollout status deployment/app" {
+		t.Errorf("Unexpected try block %q", got)
+	}
+	if got := blockCommands(node.Catch); len(got) != 1 || got[0] != "kubectl rollout undo deployment/app" {
+		t.Errorf("Unexpected catch block %q", got)
+	}
+	if got := blockCommands(node.Finally); len(got) != 1 || got[0] != `echo "cleanup"` {
+		t.Errorf("Unexpected finally block %q", got)
+	}
+
+	// The try takes its ID before the steps of its blocks
+	ids := []uint64{plan.Steps[0].ID, node.Try[0].ID, node.Try[1].ID, node.Catch[0].ID, node.Finally[0].ID}
+	for i, id := range ids {
+		if id != uint64(i+1) {
+			t.Fatalf("Expected pre-order step IDs 1..5, got %v", ids)
+		}
+	}
+}
+
+func TestTry_OptionalBlocks(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		wantCatch   bool
+		wantFinally bool
+	}{
+		{name: "try only", source: `try { echo "a" }`},
+		{name: "empty catch", source: `try { echo "a" } catch {}`, wantCatch: true},
+		{name: "finally only", source: `try { echo "a" } finally { echo "b" }`, wantFinally: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planLoopSource(t, tt.source, "")
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+			node := singleTry(t, plan.Steps)
+			if node.HasCatch != tt.wantCatch || node.HasFinally != tt.wantFinally {
+				t.Errorf("Expected HasCatch=%t HasFinally=%t, got %t %t",
+					tt.wantCatch, tt.wantFinally, node.HasCatch, node.HasFinally)
+			}
+		})
+	}
+}
+
+func TestTry_BlocksAreScopeIsolated(t *testing.T) {
+	_, err := planLoopSource(t, `
+try {
+    var MODE = "fast"
+    echo "@var.MODE"
+}
+echo "@var.MODE"
+`, "")
+	if err == nil {
+		t.Fatal("Expected error using a try-scoped variable after the try")
+	}
+	if !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected 'not found' error, got: %v", err)
+	}
+}
+
+func TestTry_SecretUseSitesPerBlock(t *testing.T) {
+	plan, err := planLoopSource(t, `
+var TOKEN = "s3cr3t-value"
+try {
+    echo "@var.TOKEN"
+} catch {
+    echo "@var.TOKEN"
+}
+`, "")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	sites := make(map[string]bool)
+	for _, use := range plan.SecretUses {
+		sites[use.Site] = true
+	}
+	for _, want := range []string{
+		"root/step-1/@try[1]/step-2/params/command",
+		"root/step-1/@catch[1]/step-3/params/command",
+	} {
+		if !sites[want] {
+			t.Errorf("Expected use site %s, got %v", want, sites)
+		}
+	}
+}
+
+func TestTry_InFunctionAndDecoratorBlock(t *testing.T) {
+	plan, err := planLoopSource(t, `
+fun deploy {
+    @retry(times=2) {
+        try {
+            echo "a"
+        } finally {
+            echo "b"
+        }
+    }
+}
+`, "deploy")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Steps) != 1 {
+		t.Fatalf("Expected 1 step, got %d", len(plan.Steps))
+	}
+	cmd, ok := plan.Steps[0].Tree.(*planfmt.CommandNode)
+	if !ok || cmd.Decorator != "@retry" {
+		t.Fatalf("Expected @retry step, got %#v", plan.Steps[0].Tree)
+	}
+	node := singleTry(t, cmd.Block)
+	if len(node.Try) != 1 || len(node.Finally) != 1 {
+		t.Errorf("Expected one step in try and finally, got %d and %d", len(node.Try), len(node.Finally))
+	}
+}
@@ -500,3 +500,21 @@ echo "Hello, @var.USED"`
 	t.Logf("  Command: %s", commandStr)
 	t.Logf("  SecretUses count: %d (expected 1)", len(result.Plan.SecretUses))
 }
+
+// TestVarDeclaration_AfterDecoratorValue tests that a top-level declaration
+// following one with a decorator value is still in scope for a target
+func TestVarDeclaration_AfterDecoratorValue(t *testing.T) {
+	t.Setenv("OPAL_TEST_HOME", "/home/opal")
+	source := `var HOME_DIR = @env.OPAL_TEST_HOME
+var USER_NAME = "opal"
+fun greet = echo "@var.USER_NAME"`
+
+	tree := parser.ParseString(source)
+	if len(tree.Errors) > 0 {
+		t.Fatalf("Parse errors: %v", tree.Errors)
+	}
+
+	if _, err := Plan(tree.Events, tree.Tokens, Config{Target: "greet"}); err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package streamscrub
 import (
 	"bytes"
 	"sort"
+	"strings"
 )
 
 // SecretProvider processes chunks to handle secrets.
@@ -26,8 +27,8 @@ import (
 // Providers can implement different behaviors:
 //
 //   - Replace mode: Replace secrets with placeholders (default)
-//   - Fail-fast mode: Return error if secrets detected
-//   - Audit mode: Log secrets then replace
+//   - Fail-fast mode: Return error if secrets detected (NewStrictProvider)
+//   - Audit mode: Log secrets then replace (WithRedactionHandler)
 //   - Custom mode: Any combination of above
 //
 // # Performance Considerations
@@ -67,18 +68,11 @@ import (
 //	    return result, nil
 //	}
 //
-// Fail-fast mode:
+// Fail-fast mode wraps a replacing provider:
 //
-//	type StrictProvider struct {
-//	    inner SecretProvider
-//	}
-//
-//	func (s *StrictProvider) HandleChunk(chunk []byte) ([]byte, error) {
-//	    if s.hasSecret(chunk) {
-//	        return nil, errors.New("secret detected in output")
-//	    }
-//	    return chunk, nil
-//	}
+//	provider := streamscrub.NewStrictProvider(vault.SecretProvider())
+//	scrubber := streamscrub.New(output, streamscrub.WithSecretProvider(provider))
+//	// Write returns a *SecretLeakError instead of writing a secret
 type SecretProvider interface {
 	// HandleChunk processes chunk and returns modified version.
 	//
@@ -123,6 +117,22 @@ type SecretProvider interface {
 	MaxSecretLength() int
 }
 
+// Redaction is one secret replaced in output. It identifies the secret by
+// its placeholder only; the secret itself is never reported.
+type Redaction struct {
+	Placeholder string // Replacement written instead of the secret (e.g., its DisplayID)
+	Offset      int64  // Byte offset of the placeholder in the processed output
+}
+
+// RedactionReporter is implemented by providers that can report each
+// redaction they make. Strict mode and WithRedactionHandler require it.
+type RedactionReporter interface {
+	// HandleChunkRedactions processes chunk like HandleChunk and also returns
+	// its redactions in order, with offsets into processed. Matching is
+	// leftmost-longest: at each position the longest secret wins.
+	HandleChunkRedactions(chunk []byte) (processed []byte, redactions []Redaction, err error)
+}
+
 // Pattern represents a secret to find and replace.
 type Pattern struct {
 	Value       []byte // Secret bytes to find
@@ -212,14 +222,62 @@ func (p *patternProvider) MaxSecretLength() int {
 	return maxLen
 }
 
+// HandleChunkRedactions implements RedactionReporter interface.
+func (p *patternProvider) HandleChunkRedactions(chunk []byte) ([]byte, []Redaction, error) {
+	// Drop patterns the chunk cannot contain, so clean chunks cost one scan per pattern
+	var patterns []Pattern
+	for _, pattern := range p.getPatterns() {
+		if len(pattern.Value) > 0 && bytes.Contains(chunk, pattern.Value) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) == 0 {
+		return chunk, nil, nil
+	}
+
+	// Longest first, so the first pattern matching at a position is the longest
+	sort.Slice(patterns, func(i, j int) bool {
+		return len(patterns[i].Value) > len(patterns[j].Value)
+	})
+
+	result := make([]byte, 0, len(chunk))
+	var redactions []Redaction
+	for i := 0; i < len(chunk); {
+		matched := false
+		for _, pattern := range patterns {
+			if bytes.HasPrefix(chunk[i:], pattern.Value) {
+				redactions = append(redactions, Redaction{
+					Placeholder: string(pattern.Placeholder),
+					Offset:      int64(len(result)),
+				})
+				result = append(result, pattern.Placeholder...)
+				i += len(pattern.Value)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			result = append(result, chunk[i])
+			i++
+		}
+	}
+
+	return result, redactions, nil
+}
+
 // NewPatternProviderWithVariants creates a SecretProvider that automatically
 // generates encoding variants for defense-in-depth.
 //
 // For each secret, it generates variants in common encodings:
 //   - Hex (lowercase and uppercase)
-//   - Base64 (standard, raw, URL)
+//   - Base64 (standard, raw, URL, and wrapped at 76 and 64 columns)
 //   - Percent encoding (lowercase and uppercase)
 //   - Separator-inserted variants (-, _, :, ., space)
+//   - JSON string escaping (plain, HTML-safe, ASCII-only)
+//   - POSIX shell quoting (backslash or $'...' as printf %q, and single quotes)
+//
+// Encoded variants are matched as the secret alone would be encoded: a
+// secret base64-encoded inside a larger value is not caught.
 //
 // This provides additional security if secrets are accidentally encoded
 // somewhere in the pipeline. The tradeoff is more patterns to match.
@@ -287,5 +345,29 @@ func generateVariants(pattern Pattern) []Pattern {
 		variants = append(variants, Pattern{Value: []byte(variant), Placeholder: placeholder})
 	}
 
+	// Structured encodings: JSON strings (jq, encoders), shell quoting
+	// (printf %q, shlex.quote) and line-wrapped base64 (base64, PEM, MIME).
+	// Encodings that leave the secret intact are skipped: the raw pattern
+	// already catches them, and matching them would also eat the quotes.
+	seen := make(map[string]bool)
+	for _, variant := range []string{
+		toJSONString(secret, false, false),
+		toJSONString(secret, true, false),
+		toJSONString(secret, false, true),
+		toShellEscaped(secret),
+		toShellANSIC(secret, true),
+		toShellSingleQuoted(secret, `'\''`),
+		toShellSingleQuoted(secret, `'"'"'`),
+		wrapLines(b64Std, 76, "\n"),
+		wrapLines(b64Std, 64, "\n"),
+		wrapLines(b64Std, 76, "\r\n"),
+	} {
+		if seen[variant] || strings.Contains(variant, string(secret)) {
+			continue
+		}
+		seen[variant] = true
+		variants = append(variants, Pattern{Value: []byte(variant), Placeholder: placeholder})
+	}
+
 	return variants
 }
@@ -2,7 +2,9 @@ package streamscrub
 
 import (
 	"bytes"
+	"encoding/json"
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -565,3 +567,120 @@ func TestNewPatternProviderWithVariants(t *testing.T) {
 		})
 	}
 }
+
+// TestHandleChunkRedactions verifies leftmost-longest matching and offsets
+// into the processed chunk
+func TestHandleChunkRedactions(t *testing.T) {
+	provider := NewPatternProvider(func() []Pattern {
+		return []Pattern{
+			{Value: []byte("SECRET"), Placeholder: []byte("<S>")},
+			{Value: []byte("SECRET_EXTENDED"), Placeholder: []byte("<E>")},
+		}
+	}).(RedactionReporter)
+
+	got, redactions, err := provider.HandleChunkRedactions([]byte("a SECRET_EXTENDED b SECRET"))
+	if err != nil {
+		t.Fatalf("HandleChunkRedactions failed: %v", err)
+	}
+	if string(got) != "a <E> b <S>" {
+		t.Errorf("processed = %q, want %q", got, "a <E> b <S>")
+	}
+	want := []Redaction{{Placeholder: "<E>", Offset: 2}, {Placeholder: "<S>", Offset: 8}}
+	if len(redactions) != len(want) {
+		t.Fatalf("redactions = %+v, want %+v", redactions, want)
+	}
+	for i := range want {
+		if redactions[i] != want[i] {
+			t.Errorf("redactions[%d] = %+v, want %+v", i, redactions[i], want[i])
+		}
+	}
+
+	clean := []byte("nothing to see")
+	got, redactions, _ = provider.HandleChunkRedactions(clean)
+	if string(got) != string(clean) || redactions != nil {
+		t.Errorf("clean chunk changed: %q, %+v", got, redactions)
+	}
+}
+
+// TestStructuredVariants verifies secrets are caught as JSON encoders,
+// shell quoting and line-wrapping base64 tools write them
+func TestStructuredVariants(t *testing.T) {
+	long := strings.Repeat("k", 80) // Base64 is 108 characters, wrapped at 76 or 64
+	tests := []struct {
+		name    string
+		secret  string
+		encoded string
+	}{
+		{"jq", `p@ss"wörd<&>`, `p@ss\"wörd<&>`},
+		{"jq ascii", `p@ss"wörd<&>`, `p@ss\"w\u00f6rd<&>`},
+		{"go json", `p@ss"wörd<&>`, `p@ss\"wörd\u003c\u0026\u003e`},
+		{"json control", "line1\nline2\x7f", `line1\nline2\u007f`},
+		{"json astral", "key😀", `key\ud83d\ude00`},
+		{"printf q", `p@ss"wörd<&>`, `p@ss\"wörd\<\&\>`},
+		{"printf q C locale", `p@ss"wörd<&>`, `$'p@ss"w\303\266rd<&>'`},
+		{"printf q quote", "it's-a-secret", `it\'s-a-secret`},
+		{"printf q control", "tab\there", `$'tab\there'`},
+		{"single quoted", "it's-a-secret", `'it'\''s-a-secret'`},
+		{"shlex quote", "it's-a-secret", `'it'"'"'s-a-secret'`},
+		{"base64 wrapped", long, "a2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tr\na2tra2tra2tra2tra2tra2tra2tra2s="},
+		{"pem wrapped", long, "a2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tr\na2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2s="},
+		{"mime wrapped", long, "a2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2tr\r\na2tra2tra2tra2tra2tra2tra2tra2s="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewPatternProviderWithVariants(func() []Pattern {
+				return []Pattern{{Value: []byte(tt.secret), Placeholder: []byte("REDACTED")}}
+			})
+			got, err := provider.HandleChunk([]byte("x " + tt.encoded + " y"))
+			if err != nil {
+				t.Fatalf("HandleChunk failed: %v", err)
+			}
+			if string(got) != "x REDACTED y" {
+				t.Errorf("got %q, want %q", got, "x REDACTED y")
+			}
+		})
+	}
+}
+
+// TestToJSONStringMatchesEncodingJSON verifies the hand-written JSON escaping
+// matches encoding/json, with and without HTML escaping
+func TestToJSONStringMatchesEncodingJSON(t *testing.T) {
+	inputs := []string{
+		`p@ss"wörd<&>\`,
+		"ctl\b\f\n\r\t\x00\x1f\x7f",
+		"sep\u2028\u2029",
+		"bad\xffutf8",
+		"key😀",
+	}
+	for _, in := range inputs {
+		for _, escapeHTML := range []bool{false, true} {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(escapeHTML)
+			if err := enc.Encode(in); err != nil {
+				t.Fatal(err)
+			}
+			want := strings.TrimSuffix(buf.String(), "\n")
+			want = want[1 : len(want)-1]
+			if got := toJSONString([]byte(in), escapeHTML, false); got != want {
+				t.Errorf("toJSONString(%q, %v) = %q, want %q", in, escapeHTML, got, want)
+			}
+		}
+	}
+}
+
+// TestStructuredVariantsKeepQuotes verifies encodings that leave the secret
+// intact are not separate patterns, so quotes around a secret survive
+func TestStructuredVariantsKeepQuotes(t *testing.T) {
+	provider := NewPatternProviderWithVariants(func() []Pattern {
+		return []Pattern{{Value: []byte("plain-secret"), Placeholder: []byte("REDACTED")}}
+	})
+	got, err := provider.HandleChunk([]byte(`token='plain-secret' json="plain-secret"`))
+	if err != nil {
+		t.Fatalf("HandleChunk failed: %v", err)
+	}
+	if want := `token='REDACTED' json="REDACTED"`; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
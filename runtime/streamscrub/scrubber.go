@@ -5,9 +5,12 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
+	"unicode/utf16"
 
 	"github.com/opal-lang/opal/core/invariant"
 )
@@ -22,8 +25,66 @@ type Scrubber struct {
 	out             io.Writer
 	provider        SecretProvider // Provider for secret detection and replacement
 	frames          []frame
-	carry           []byte // Rolling window for chunk-boundary secrets
+	carry           []byte          // Unscrubbed tail held back for chunk-boundary secrets
+	streams         []*StreamWriter // Independent writers sharing this scrubber, until closed
 	placeholderFunc PlaceholderFunc
+
+	onRedaction func(Redaction) // Audit handler (nil = not auditing)
+	onDropped   func(error)     // Lockdown write errors (nil = dropped silently)
+	written     int64           // Bytes written to out, for redaction offsets
+}
+
+// StreamWriter is an io.Writer into a Scrubber with its own carry window.
+// Concurrent producers (e.g., stdout and stderr) each need their own
+// window: with a shared one, interleaved writes split a secret's bytes
+// across unrelated chunks and neither half is ever matched. Carries hold
+// unscrubbed bytes only, so every scrubbed chunk is written whole and a
+// placeholder is never split between streams.
+type StreamWriter struct {
+	s      *Scrubber
+	carry  []byte // Guarded by s.mu
+	closed bool   // Guarded by s.mu
+}
+
+// Write implements io.Writer - scrubs secrets before writing.
+func (st *StreamWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	st.s.mu.Lock()
+	defer st.s.mu.Unlock()
+	if st.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return st.s.writeLocked(&st.carry, p)
+}
+
+// Flush writes the stream's carry after redaction. Callers that know a
+// producer has finished (e.g., a step's command exited) can flush its
+// stream without flushing the others.
+func (st *StreamWriter) Flush() error {
+	st.s.mu.Lock()
+	defer st.s.mu.Unlock()
+	return st.s.flushCarryLocked(&st.carry)
+}
+
+// Close flushes the stream's carry and releases the stream: the scrubber
+// stops tracking it and later writes fail. Close is idempotent.
+func (st *StreamWriter) Close() error {
+	st.s.mu.Lock()
+	defer st.s.mu.Unlock()
+	if st.closed {
+		return nil
+	}
+	st.closed = true
+	for i, other := range st.s.streams {
+		if other == st {
+			st.s.streams = append(st.s.streams[:i], st.s.streams[i+1:]...)
+			break
+		}
+	}
+	return st.s.flushCarryLocked(&st.carry)
 }
 
 // frame represents a buffering scope.
@@ -58,6 +119,29 @@ func WithSecretProvider(provider SecretProvider) Option {
 	}
 }
 
+// WithRedactionHandler calls fn for every secret the scrubber redacts, once
+// the placeholder's position in the output is final. Offsets count bytes
+// written to the scrubber's writer. fn is called with the scrubber locked,
+// so it must not write to the scrubber.
+//
+// The provider must implement RedactionReporter.
+func WithRedactionHandler(fn func(Redaction)) Option {
+	return func(s *Scrubber) {
+		s.onRedaction = fn
+	}
+}
+
+// WithDropHandler calls fn with the error for every chunk LockdownStreams
+// could not write, such as output a strict provider rejected. The lockdown
+// streams keep draining after an error so writers never block; without a
+// handler such chunks are dropped silently. fn is called without the
+// scrubber locked, from the lockdown's own goroutines.
+func WithDropHandler(fn func(error)) Option {
+	return func(s *Scrubber) {
+		s.onDropped = fn
+	}
+}
+
 // New creates a new Scrubber that writes to w.
 // By default, uses keyed BLAKE2b placeholders with a random per-run key.
 // This prevents correlation attacks across runs.
@@ -90,6 +174,11 @@ func New(w io.Writer, opts ...Option) *Scrubber {
 		opt(s)
 	}
 
+	if s.onRedaction != nil {
+		_, ok := s.provider.(RedactionReporter)
+		invariant.Precondition(ok, "redaction handler requires a RedactionReporter provider, got %T", s.provider)
+	}
+
 	// OUTPUT CONTRACT
 	invariant.Postcondition(s.out != nil, "scrubber must have output writer")
 	invariant.Postcondition(len(s.frames) == 0, "scrubber must start with no active frames")
@@ -142,7 +231,7 @@ func (s *Scrubber) EndFrame() error {
 	frameBuf := currentFrame.buf.Bytes()
 
 	// Scrub frame buffer with provider (if available)
-	scrubbed, err := s.scrubAll(frameBuf)
+	scrubbed, redactions, err := s.scrubAll(frameBuf)
 	if err != nil {
 		// Provider rejected chunk - do not write unsanitized data
 		// Zeroize buffer and return error
@@ -154,7 +243,7 @@ func (s *Scrubber) EndFrame() error {
 	}
 
 	// Flush to output BEFORE zeroizing (scrubbed may share underlying array with frameBuf)
-	_, err = s.out.Write(scrubbed)
+	err = s.emitLocked(scrubbed, redactions)
 
 	// Zeroize frame buffer after writing
 	for i := range frameBuf {
@@ -170,15 +259,36 @@ func (s *Scrubber) EndFrame() error {
 
 // scrubAll replaces all secrets in buf using the SecretProvider.
 // Returns an error if the provider rejects the chunk (e.g., fail-fast mode).
+// Redactions are only collected when auditing.
 // Assumes mu is held.
-func (s *Scrubber) scrubAll(buf []byte) ([]byte, error) {
+func (s *Scrubber) scrubAll(buf []byte) ([]byte, []Redaction, error) {
+	if s.provider != nil && s.onRedaction != nil {
+		return s.provider.(RedactionReporter).HandleChunkRedactions(buf)
+	}
+
 	// Use provider-based scrubbing if available
 	if s.provider != nil {
-		return s.scrubAllProvider(buf)
+		processed, err := s.scrubAllProvider(buf)
+		return processed, nil, err
 	}
 
 	// No provider - pass through unchanged
-	return buf, nil
+	return buf, nil, nil
+}
+
+// emitLocked writes scrubbed output and reports its redactions, whose
+// offsets are relative to data.
+// Assumes mu is held.
+func (s *Scrubber) emitLocked(data []byte, redactions []Redaction) error {
+	for _, r := range redactions {
+		if s.onRedaction == nil {
+			break
+		}
+		s.onRedaction(Redaction{Placeholder: r.Placeholder, Offset: s.written + r.Offset})
+	}
+	n, err := s.out.Write(data)
+	s.written += int64(n)
+	return err
 }
 
 // scrubAllProvider uses SecretProvider to process chunk.
@@ -255,6 +365,130 @@ func insertSeparators(b []byte, sep string) string {
 	return string(result)
 }
 
+// toJSONString returns b as the contents of a JSON string (without the
+// quotes), escaped the way encoding/json does. escapeHTML also escapes <, >
+// and & (Go's encoding/json default); asciiOnly writes non-ASCII and DEL as
+// \uXXXX (jq -a, Python's json). It does not use encoding/json, whose pooled
+// encoders would keep a copy of the secret after the run.
+func toJSONString(b []byte, escapeHTML, asciiOnly bool) string {
+	var out strings.Builder
+	for _, r := range string(b) {
+		switch {
+		case r == '"' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r == '\b':
+			out.WriteString(`\b`)
+		case r == '\f':
+			out.WriteString(`\f`)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r < 0x20, r == '\u2028', r == '\u2029', escapeHTML && (r == '<' || r == '>' || r == '&'):
+			fmt.Fprintf(&out, `\u%04x`, r)
+		case asciiOnly && r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(&out, `\u%04x\u%04x`, r1, r2)
+		case asciiOnly && r >= 0x7f:
+			fmt.Fprintf(&out, `\u%04x`, r)
+		default:
+			out.WriteRune(r) // Invalid UTF-8 becomes U+FFFD, as in encoding/json
+		}
+	}
+	return out.String()
+}
+
+// toShellEscaped quotes b the way bash's printf %q does in a UTF-8 locale:
+// metacharacters are backslash-escaped, and strings with control
+// characters use $'...'.
+func toShellEscaped(b []byte) string {
+	for _, c := range b {
+		if c < 0x20 || c == 0x7f {
+			return toShellANSIC(b, false)
+		}
+	}
+
+	result := make([]byte, 0, len(b)*2)
+	for i, c := range b {
+		switch c {
+		case ' ', '\'', '"', '\\', '|', '&', ';', '(', ')', '<', '>',
+			'!', '{', '}', '*', '[', '?', ']', '^', '$', '`', ',':
+			result = append(result, '\\')
+		case '~':
+			if i == 0 || b[i-1] == '=' || b[i-1] == ':' {
+				result = append(result, '\\')
+			}
+		case '#':
+			if i == 0 {
+				result = append(result, '\\')
+			}
+		}
+		result = append(result, c)
+	}
+	return string(result)
+}
+
+// toShellANSIC quotes b as a bash $'...' string. octalHigh also writes
+// bytes above 0x7f as octal escapes, as printf %q does in the C locale.
+func toShellANSIC(b []byte, octalHigh bool) string {
+	result := []byte("$'")
+	for _, c := range b {
+		switch c {
+		case '\a':
+			result = append(result, `\a`...)
+		case '\b':
+			result = append(result, `\b`...)
+		case '\f':
+			result = append(result, `\f`...)
+		case '\n':
+			result = append(result, `\n`...)
+		case '\r':
+			result = append(result, `\r`...)
+		case '\t':
+			result = append(result, `\t`...)
+		case '\v':
+			result = append(result, `\v`...)
+		case 0x1b:
+			result = append(result, `\E`...)
+		case '\'', '\\':
+			result = append(result, '\\', c)
+		default:
+			if c < 0x20 || c == 0x7f || (octalHigh && c > 0x7f) {
+				result = append(result, fmt.Sprintf("\\%03o", c)...)
+			} else {
+				result = append(result, c)
+			}
+		}
+	}
+	return string(append(result, '\''))
+}
+
+// toShellSingleQuoted quotes b in single quotes, writing each embedded
+// quote as quote: close, escaped quote, reopen in most shell code, or
+// close, double-quoted quote, reopen from Python's shlex.quote.
+func toShellSingleQuoted(b []byte, quote string) string {
+	return "'" + strings.ReplaceAll(string(b), "'", quote) + "'"
+}
+
+// wrapLines breaks s into lines of width characters joined by eol, as
+// base64 (76), PEM (64) and MIME (76, CRLF) encoders do.
+func wrapLines(s string, width int, eol string) string {
+	if len(s) <= width {
+		return s
+	}
+	var out strings.Builder
+	for len(s) > width {
+		out.WriteString(s[:width])
+		out.WriteString(eol)
+		s = s[width:]
+	}
+	out.WriteString(s)
+	return out.String()
+}
+
 // LockdownStreams redirects stdout and stderr through the scrubber.
 // Returns a restore function that MUST be deferred to restore original streams.
 //
@@ -291,15 +525,30 @@ func (s *Scrubber) LockdownStreams() func() {
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	go func() {
-		defer wg.Done()
-		_, _ = io.Copy(s, rOut)
-	}()
+	// Each stream gets its own carry window so interleaved stdout/stderr
+	// writes cannot split a secret
+	outStream, errStream := s.Stream(), s.Stream()
 
-	go func() {
+	// Unlike io.Copy, keep reading after a rejected chunk (strict mode):
+	// the chunk is dropped (and reported) and writers never block on a
+	// full pipe
+	drain := func(dst io.Writer, src io.Reader) {
 		defer wg.Done()
-		_, _ = io.Copy(s, rErr)
-	}()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := src.Read(buf)
+			if n > 0 {
+				if _, werr := dst.Write(buf[:n]); werr != nil {
+					s.dropped(werr)
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	go drain(outStream, rOut)
+	go drain(errStream, rErr)
 
 	// Return idempotent restore function
 	var once sync.Once
@@ -309,8 +558,13 @@ func (s *Scrubber) LockdownStreams() func() {
 			_ = wOut.Close()
 			_ = wErr.Close()
 
-			// Wait for copy goroutines to finish
+			// Wait for copy goroutines to finish, then release their streams
 			wg.Wait()
+			for _, st := range []*StreamWriter{outStream, errStream} {
+				if err := st.Close(); err != nil {
+					s.dropped(err)
+				}
+			}
 
 			// Close read ends
 			_ = rOut.Close()
@@ -321,11 +575,34 @@ func (s *Scrubber) LockdownStreams() func() {
 			os.Stderr = originalStderr
 
 			// Flush any remaining buffered data
-			_ = s.Flush()
+			if err := s.Flush(); err != nil {
+				s.dropped(err)
+			}
 		})
 	}
 }
 
+// dropped reports a chunk the lockdown streams could not write.
+func (s *Scrubber) dropped(err error) {
+	if s.onDropped != nil {
+		s.onDropped(err)
+	}
+}
+
+// Stream returns a writer into the scrubber with its own carry window.
+// Use one stream per concurrent producer; writes from different streams
+// may interleave without breaking chunk-boundary detection. The scrubber's
+// Flush and Close also flush every open stream; Close the stream when its
+// producer is done so the scrubber stops tracking it.
+func (s *Scrubber) Stream() *StreamWriter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := &StreamWriter{s: s}
+	s.streams = append(s.streams, st)
+	return st
+}
+
 // Write implements io.Writer - scrubs secrets before writing.
 func (s *Scrubber) Write(p []byte) (int, error) {
 	// INPUT CONTRACT
@@ -337,7 +614,14 @@ func (s *Scrubber) Write(p []byte) (int, error) {
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.writeLocked(&s.carry, p)
+}
 
+// writeLocked scrubs p using the given carry window and writes the result.
+// The carry holds the unscrubbed tail of earlier writes that may be the
+// start of a secret.
+// Assumes mu is held.
+func (s *Scrubber) writeLocked(carry *[]byte, p []byte) (int, error) {
 	// If we're in a frame, buffer the output
 	if len(s.frames) > 0 {
 		currentFrame := &s.frames[len(s.frames)-1]
@@ -349,14 +633,7 @@ func (s *Scrubber) Write(p []byte) (int, error) {
 	}
 
 	// Streaming mode: merge with carry from previous write
-	buf := append(append([]byte{}, s.carry...), p...)
-
-	// Scrub all secrets (longest-first)
-	result, err := s.scrubAll(buf)
-	if err != nil {
-		// Provider rejected chunk - do not write unsanitized data
-		return 0, err
-	}
+	buf := append(append([]byte{}, (*carry)...), p...)
 
 	// Keep last maxLen-1 bytes as carry for next write
 	// (in case secret is split across chunk boundary)
@@ -380,28 +657,51 @@ func (s *Scrubber) Write(p []byte) (int, error) {
 	invariant.Postcondition(carrySize >= 0, "carrySize must be non-negative")
 	invariant.Postcondition(carrySize < 1024*1024, "carrySize must be reasonable (<1MB)")
 
-	if carrySize > 0 && len(result) > carrySize {
-		// Write everything except the carry
-		toWrite := result[:len(result)-carrySize]
-		s.carry = append(s.carry[:0], result[len(result)-carrySize:]...)
-
-		// INVARIANT: carry size matches expected
-		invariant.Postcondition(len(s.carry) == carrySize, "carry must be exactly carrySize bytes")
+	// Scrub all secrets (longest-first)
+	result, redactions, err := s.scrubAll(buf)
+	if err != nil {
+		// Provider rejected chunk - do not write unsanitized data
+		return 0, err
+	}
 
-		_, err := s.out.Write(toWrite)
-		if err != nil {
-			return 0, err
-		}
-	} else if carrySize > 0 {
+	if carrySize > 0 && len(buf) <= carrySize {
 		// Buffer is smaller than carry size, accumulate
-		s.carry = append(s.carry[:0], result...)
+		*carry = append((*carry)[:0], buf...)
 
 		// INVARIANT: carry doesn't exceed expected size
-		invariant.Postcondition(len(s.carry) <= carrySize, "carry must not exceed carrySize")
-	} else {
-		// No secrets registered, write everything immediately
-		_, err := s.out.Write(result)
-		if err != nil {
+		invariant.Postcondition(len(*carry) <= carrySize, "carry must not exceed carrySize")
+		return len(p), nil
+	}
+
+	if carrySize > 0 {
+		// Write everything except the carry. A secret starting before the
+		// cut lies wholly in buf; if one crosses the cut, the scrubbed head
+		// is not a prefix of result, so move the cut back past its start.
+		cut := len(buf) - carrySize
+		for ; cut > 0; cut-- {
+			head, headRedactions, err := s.scrubAll(buf[:cut])
+			if err != nil {
+				return 0, err
+			}
+			if bytes.HasPrefix(result, head) {
+				result, redactions = head, headRedactions
+				break
+			}
+		}
+		if cut == 0 {
+			result, redactions = nil, nil
+		}
+		*carry = append((*carry)[:0], buf[cut:]...)
+
+		// INVARIANT: carry holds at least the last carrySize bytes
+		invariant.Postcondition(len(*carry) >= carrySize, "carry must hold at least carrySize bytes")
+	}
+
+	// Secret bytes in buf are no longer needed once scrubbed
+	defer clear(buf)
+
+	if len(result) > 0 {
+		if err := s.emitLocked(result, redactions); err != nil {
 			return 0, err
 		}
 	}
@@ -411,37 +711,50 @@ func (s *Scrubber) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
-// Flush writes any remaining carry bytes after redaction.
+// Flush writes any remaining carry bytes after redaction,
+// including the carry of every stream created by Stream.
 func (s *Scrubber) Flush() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if len(s.carry) == 0 {
+	err := s.flushCarryLocked(&s.carry)
+	for _, st := range s.streams {
+		if streamErr := s.flushCarryLocked(&st.carry); err == nil {
+			err = streamErr
+		}
+	}
+	return err
+}
+
+// flushCarryLocked scrubs, writes and zeroizes one carry window.
+// Assumes mu is held.
+func (s *Scrubber) flushCarryLocked(carry *[]byte) error {
+	if len(*carry) == 0 {
 		return nil
 	}
 
 	// Scrub carry one final time (longest-first)
-	result, err := s.scrubAll(s.carry)
+	result, redactions, err := s.scrubAll(*carry)
 	if err != nil {
 		// Provider rejected chunk - zeroize carry and return error
-		for i := range s.carry {
-			s.carry[i] = 0
+		for i := range *carry {
+			(*carry)[i] = 0
 		}
-		s.carry = s.carry[:0]
+		*carry = (*carry)[:0]
 		return err
 	}
 
 	// Write and zeroize carry
-	_, err = s.out.Write(result)
+	err = s.emitLocked(result, redactions)
 
 	// Zeroize carry buffer
-	for i := range s.carry {
-		s.carry[i] = 0
+	for i := range *carry {
+		(*carry)[i] = 0
 	}
-	s.carry = s.carry[:0]
+	*carry = (*carry)[:0]
 
 	// OUTPUT CONTRACT
-	invariant.Postcondition(len(s.carry) == 0, "carry must be cleared after flush")
+	invariant.Postcondition(len(*carry) == 0, "carry must be cleared after flush")
 
 	return err
 }
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -265,6 +267,120 @@ func TestConcurrentWrites(t *testing.T) {
 	}
 }
 
+// TestStreamsKeepSeparateCarry verifies a secret split across two writes on
+// one stream is still redacted when another stream writes in between
+func TestStreamsKeepSeparateCarry(t *testing.T) {
+	var buf bytes.Buffer
+	provider := testProvider(map[string]string{
+		"my-secret-key": "<REDACTED>",
+	})
+	s := New(&buf, WithSecretProvider(provider))
+	stdout, stderr := s.Stream(), s.Stream()
+
+	stdout.Write([]byte("key=my-sec"))
+	stderr.Write([]byte("warning: slow\n"))
+	stdout.Write([]byte("ret-key\n"))
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	got := buf.String()
+	if !bytes.Contains([]byte(got), []byte("key=<REDACTED>")) {
+		t.Errorf("secret split by another stream was not redacted: %q", got)
+	}
+	if !bytes.Contains([]byte(got), []byte("slow\n")) {
+		t.Errorf("other stream output lost: %q", got)
+	}
+}
+
+// TestStreamsKeepPlaceholdersWhole verifies another stream's output never
+// lands inside a placeholder
+func TestStreamsKeepPlaceholdersWhole(t *testing.T) {
+	var buf bytes.Buffer
+	provider := testProvider(map[string]string{
+		"secret": "<REDACTED>",
+	})
+	s := New(&buf, WithSecretProvider(provider))
+	stdout, stderr := s.Stream(), s.Stream()
+
+	stdout.Write([]byte("Password is: secret"))
+	stderr.Write([]byte("Error: failed\n"))
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	got := buf.String()
+	if !bytes.Contains([]byte(got), []byte("<REDACTED>")) {
+		t.Errorf("placeholder split by another stream: %q", got)
+	}
+}
+
+// TestStreamCloseReleases verifies Close flushes a stream and stops the
+// scrubber tracking it
+func TestStreamCloseReleases(t *testing.T) {
+	var buf bytes.Buffer
+	provider := testProvider(map[string]string{
+		"my-secret-key": "<REDACTED>",
+	})
+	s := New(&buf, WithSecretProvider(provider))
+
+	for i := 0; i < 100; i++ {
+		w := s.Stream()
+		w.Write([]byte("key=my-secret-key"))
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("second Close failed: %v", err)
+		}
+		if _, err := w.Write([]byte("late")); err == nil {
+			t.Fatal("write after Close should fail")
+		}
+	}
+	if len(s.streams) != 0 {
+		t.Errorf("scrubber still tracks %d closed streams", len(s.streams))
+	}
+	if got := strings.Count(buf.String(), "key=<REDACTED>"); got != 100 {
+		t.Errorf("got %d redacted writes, want 100: %q", got, buf.String())
+	}
+}
+
+// TestConcurrentStreams verifies redaction with many concurrent streams
+// writing secrets in small chunks
+func TestConcurrentStreams(t *testing.T) {
+	var buf safeBuffer
+	provider := testProvider(map[string]string{
+		"my-secret-key": "<REDACTED>",
+	})
+	s := New(&buf, WithSecretProvider(provider))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := s.Stream()
+			for j := 0; j < 20; j++ {
+				for _, chunk := range []string{"my-", "secr", "et-k", "ey\n"} {
+					w.Write([]byte(chunk))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	got := buf.String()
+	if bytes.Contains([]byte(got), []byte("my-secret-key")) {
+		t.Errorf("secret leaked in concurrent streams: %q", got)
+	}
+	if n := bytes.Count([]byte(got), []byte("<REDACTED>")); n != 160 {
+		t.Errorf("expected 160 redactions, got %d", n)
+	}
+}
+
 // ============================================================================
 // Lockdown Tests
 // ============================================================================
@@ -549,3 +665,108 @@ func (p *conditionalErrorProvider) HandleChunk(chunk []byte) ([]byte, error) {
 func (p *conditionalErrorProvider) MaxSecretLength() int {
 	return 100
 }
+
+// TestRedactionHandler verifies each redaction is reported once, with its
+// placeholder's offset in the written output
+func TestRedactionHandler(t *testing.T) {
+	var output bytes.Buffer
+	var got []Redaction
+	provider := testProvider(map[string]string{
+		"my-secret-key": "<KEY>",
+		"token":         "<TOKEN>",
+	})
+	s := New(&output, WithSecretProvider(provider), WithRedactionHandler(func(r Redaction) {
+		got = append(got, r)
+	}))
+
+	stdout, stderr := s.Stream(), s.Stream()
+	for _, w := range []struct {
+		w    io.Writer
+		text string
+	}{
+		{stdout, "key=my-sec"},
+		{stderr, "warning: token expired, renewing it now\n"},
+		{stdout, "ret-key and token\n"},
+	} {
+		if _, err := w.w.Write([]byte(w.text)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	text := output.String()
+	if len(got) != 3 {
+		t.Fatalf("got %d redactions, want 3: %+v (output %q)", len(got), got, text)
+	}
+	for _, r := range got {
+		if !strings.HasPrefix(text[r.Offset:], r.Placeholder) {
+			t.Errorf("redaction %+v does not point at its placeholder in %q", r, text)
+		}
+	}
+}
+
+// FuzzVariantsChunkBoundary writes a secret in one of its encodings, split
+// into writes of every size, and checks that no encoding reaches the output
+// and that audited redactions point at their placeholders
+func FuzzVariantsChunkBoundary(f *testing.F) {
+	long := strings.Repeat("k", 80)
+	f.Add([]byte(`p@ss"wörd<&>`), []byte("token: "), []byte("\n"), uint8(0), uint8(3), false)
+	f.Add([]byte(`p@ss"wörd<&>`), []byte(`{"token":"`), []byte(`"}`), uint8(16), uint8(5), true)
+	f.Add([]byte("it's-a-secret"), []byte("export T="), []byte("\n"), uint8(19), uint8(1), false)
+	f.Add([]byte("tab\there"), []byte("x"), []byte("y"), uint8(18), uint8(7), true)
+	f.Add([]byte(long), []byte("-----BEGIN-----\n"), []byte("\n-----END-----"), uint8(22), uint8(11), false)
+	f.Add([]byte(long), []byte(""), []byte(""), uint8(23), uint8(15), true)
+
+	f.Fuzz(func(t *testing.T, secret, prefix, suffix []byte, variant, chunk uint8, audit bool) {
+		// The placeholder must not occur in any encoding, or replacing one
+		// encoding could assemble another
+		if len(secret) < 4 || len(secret) > 128 || bytes.IndexByte(secret, '#') >= 0 {
+			t.Skip()
+		}
+		pattern := Pattern{Value: secret, Placeholder: []byte("#")}
+		encodings := append([]Pattern{pattern}, generateVariants(pattern)...)
+		encoded := encodings[int(variant)%len(encodings)].Value
+
+		var output bytes.Buffer
+		var redactions []Redaction
+		opts := []Option{WithSecretProvider(NewPatternProviderWithVariants(func() []Pattern {
+			return []Pattern{pattern}
+		}))}
+		if audit {
+			opts = append(opts, WithRedactionHandler(func(r Redaction) { redactions = append(redactions, r) }))
+		}
+		s := New(&output, opts...)
+
+		input := append(append(append([]byte{}, prefix...), encoded...), suffix...)
+		size := int(chunk)%16 + 1
+		for len(input) > 0 {
+			n := min(size, len(input))
+			if _, err := s.Write(input[:n]); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			input = input[n:]
+		}
+		if err := s.Flush(); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+
+		got := output.Bytes()
+		for _, e := range encodings {
+			if bytes.Contains(got, e.Value) {
+				t.Fatalf("encoding %q of the secret reached the output: %q", e.Value, got)
+			}
+		}
+		if audit {
+			if len(redactions) == 0 {
+				t.Fatalf("no redaction reported for %q", got)
+			}
+			for _, r := range redactions {
+				if r.Offset < 0 || r.Offset >= int64(len(got)) || got[r.Offset] != '#' {
+					t.Fatalf("redaction offset %d does not point at a placeholder in %q", r.Offset, got)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package streamscrub
+
+import (
+	"fmt"
+
+	"github.com/opal-lang/opal/core/invariant"
+)
+
+// SecretLeakError reports a secret found in output by a strict provider.
+// It names the secret by placeholder only, so it is safe to print.
+type SecretLeakError struct {
+	Placeholder string // Placeholder of the leaked secret (e.g., its DisplayID)
+}
+
+func (e *SecretLeakError) Error() string {
+	return fmt.Sprintf("secret %s found in output", e.Placeholder)
+}
+
+// NewStrictProvider creates a fail-fast SecretProvider: any secret the inner
+// provider would redact (including its encoded variants) makes HandleChunk
+// return a *SecretLeakError instead of output. The scrubber then writes
+// nothing for that chunk.
+//
+// The inner provider must implement RedactionReporter (providers from
+// NewPatternProvider and NewPatternProviderWithVariants do).
+func NewStrictProvider(inner SecretProvider) SecretProvider {
+	// INPUT CONTRACT
+	invariant.NotNil(inner, "inner")
+	reporter, ok := inner.(RedactionReporter)
+	invariant.Precondition(ok, "strict provider requires a RedactionReporter, got %T", inner)
+
+	return &strictProvider{inner: inner, reporter: reporter}
+}
+
+// strictProvider rejects chunks containing secrets.
+type strictProvider struct {
+	inner    SecretProvider
+	reporter RedactionReporter
+}
+
+// HandleChunk implements SecretProvider interface.
+func (s *strictProvider) HandleChunk(chunk []byte) ([]byte, error) {
+	_, redactions, err := s.reporter.HandleChunkRedactions(chunk)
+	if err != nil {
+		return nil, err
+	}
+	if len(redactions) > 0 {
+		return nil, &SecretLeakError{Placeholder: redactions[0].Placeholder}
+	}
+	return chunk, nil
+}
+
+// MaxSecretLength implements SecretProvider interface.
+func (s *strictProvider) MaxSecretLength() int {
+	return s.inner.MaxSecretLength()
+}
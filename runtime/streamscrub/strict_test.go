@@ -0,0 +1,107 @@
+package streamscrub
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestStrictProvider verifies secrets and their encoded variants are
+// rejected without revealing the secret
+func TestStrictProvider(t *testing.T) {
+	provider := NewStrictProvider(NewPatternProviderWithVariants(func() []Pattern {
+		return []Pattern{{Value: []byte("hunter2"), Placeholder: []byte("opal:3J98t56A")}}
+	}))
+
+	tests := []struct {
+		name  string
+		chunk string
+		leak  bool
+	}{
+		{"clean", "deploying app\n", false},
+		{"raw", "password=hunter2\n", true},
+		{"hex", "token 68756e74657232\n", true},
+		{"base64", "aHVudGVyMg==\n", true},
+		{"separated", "h-u-n-t-e-r-2\n", true},
+		{"placeholder", "password=opal:3J98t56A\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.HandleChunk([]byte(tt.chunk))
+			if !tt.leak {
+				if err != nil || string(got) != tt.chunk {
+					t.Fatalf("HandleChunk = %q, %v; want chunk unchanged", got, err)
+				}
+				return
+			}
+
+			var leak *SecretLeakError
+			if !errors.As(err, &leak) {
+				t.Fatalf("Expected *SecretLeakError, got %v", err)
+			}
+			if leak.Placeholder != "opal:3J98t56A" {
+				t.Errorf("Placeholder = %q, want opal:3J98t56A", leak.Placeholder)
+			}
+			if strings.Contains(err.Error(), "hunter2") {
+				t.Errorf("error reveals the secret: %v", err)
+			}
+		})
+	}
+}
+
+// TestStrictScrubberChunkBoundary verifies a secret split across writes is
+// still rejected and nothing of it is written
+func TestStrictScrubberChunkBoundary(t *testing.T) {
+	var output bytes.Buffer
+	provider := NewStrictProvider(testProvider(map[string]string{"my-secret-key": "<REDACTED>"}))
+	s := New(&output, WithSecretProvider(provider))
+
+	if _, err := s.Write([]byte("safe output then my-sec")); err != nil {
+		t.Fatalf("first write failed: %v", err)
+	}
+	_, err := s.Write([]byte("ret-key\n"))
+	var leak *SecretLeakError
+	if !errors.As(err, &leak) {
+		t.Fatalf("Expected *SecretLeakError, got %v", err)
+	}
+	if strings.Contains(output.String(), "my-sec") {
+		t.Errorf("part of the secret was written: %q", output.String())
+	}
+}
+
+// TestStrictLockdownReportsLeak verifies a secret written to the locked-down
+// os.Stderr is reported to the drop handler, not dropped silently
+func TestStrictLockdownReportsLeak(t *testing.T) {
+	var output safeBuffer
+	var mu sync.Mutex
+	var dropped []error
+	provider := NewStrictProvider(testProvider(map[string]string{"my-secret-key": "<REDACTED>"}))
+	s := New(&output, WithSecretProvider(provider), WithDropHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped = append(dropped, err)
+	}))
+
+	restore := s.LockdownStreams()
+	defer restore()
+	fmt.Fprintln(os.Stderr, "Error: failed to open sink (/tmp/my-secret-key)")
+	restore()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dropped) == 0 {
+		t.Fatal("leak through the lockdown streams was not reported")
+	}
+	var leak *SecretLeakError
+	if !errors.As(dropped[0], &leak) || leak.Placeholder != "<REDACTED>" {
+		t.Errorf("Expected *SecretLeakError for <REDACTED>, got %v", dropped[0])
+	}
+	if strings.Contains(output.String(), "my-secret-key") {
+		t.Errorf("secret written: %q", output.String())
+	}
+}
@@ -494,3 +494,132 @@ func TestBuildSecretUses_UnresolvedExpression_IsExcluded(t *testing.T) {
 		t.Errorf("Expected 0 SecretUses for unresolved expression, got %d", len(uses))
 	}
 }
+
+// ========== Explicit Site Paths ==========
+
+func TestAccessByDisplayIDAt_ExplicitPath_IgnoresPathStack(t *testing.T) {
+	v := NewWithPlanKey([]byte("test-key-32-bytes-long!!!!!!"))
+
+	// GIVEN: Expression referenced at root/step-1/step-2
+	exprID := v.DeclareVariable("TOKEN", "@env.TOKEN")
+	v.MarkTouched(exprID)
+	v.StoreUnresolvedValue(exprID, "secret-value")
+	v.ResolveAllTouched()
+	v.Push("step-1")
+	v.Push("step-2")
+	v.RecordReference(exprID, "command")
+	displayID := v.GetDisplayID(exprID)
+
+	// AND: The shared path stack has moved elsewhere (e.g., a sibling branch)
+	v.Pop()
+	v.Push("step-3")
+
+	path := []PathSegment{{Name: "root", Index: -1}, {Name: "step-1", Index: -1}, {Name: "step-2", Index: -1}}
+
+	// WHEN: Access with the explicit path
+	value, err := v.AccessByDisplayIDAt(displayID, path, "command")
+	// THEN: Authorized by the explicit path, not the stack
+	if err != nil {
+		t.Fatalf("AccessByDisplayIDAt() should succeed at authorized site, got error: %v", err)
+	}
+	if value != "secret-value" {
+		t.Errorf("AccessByDisplayIDAt() = %q, want %q", value, "secret-value")
+	}
+
+	// AND: A sibling path is still unauthorized
+	sibling := []PathSegment{{Name: "root", Index: -1}, {Name: "step-1", Index: -1}, {Name: "step-3", Index: -1}}
+	_, err = v.AccessByDisplayIDAt(displayID, sibling, "command")
+	if err == nil || !containsString(err.Error(), "no authority") {
+		t.Errorf("AccessByDisplayIDAt() at sibling site should fail with authority error, got: %v", err)
+	}
+}
+
+func TestAccessByDisplayIDAt_UnknownDisplayID_Fails(t *testing.T) {
+	v := NewWithPlanKey([]byte("test-key-32-bytes-long!!!!!!"))
+
+	_, err := v.AccessByDisplayIDAt("opal:unknown", []PathSegment{{Name: "root", Index: -1}}, "command")
+	if err == nil || !containsString(err.Error(), "not found") {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+}
+
+// ========== Transport Scopes ==========
+
+func TestAccess_LiteralVariable_CrossesTransportBoundary(t *testing.T) {
+	v := NewWithPlanKey([]byte("test-key-32-bytes-long!!!!!!"))
+
+	// GIVEN: A planner-declared variable (plain value) resolved locally
+	exprID := v.DeclareVariable("VERSION", "literal:1.2.3")
+	v.MarkTouched(exprID)
+	v.StoreUnresolvedValue(exprID, "1.2.3")
+	v.ResolveAllTouched()
+	v.Push("step-1")
+	v.RecordReference(exprID, "command")
+
+	// WHEN: Used inside a remote transport
+	v.EnterTransport("ssh:remote")
+	value, err := v.Access(exprID, "command")
+	// THEN: Plain values pass into transports
+	if err != nil {
+		t.Fatalf("Access() for a literal should succeed across transports, got: %v", err)
+	}
+	if value != "1.2.3" {
+		t.Errorf("Access() = %q, want %q", value, "1.2.3")
+	}
+}
+
+func TestEnterTransport_Nested_ExitRestoresEnclosing(t *testing.T) {
+	v := NewWithPlanKey([]byte("test-key-32-bytes-long!!!!!!"))
+
+	v.EnterTransport("ssh:bastion")
+	v.EnterTransport("ssh:app")
+	if got := v.CurrentTransport(); got != "ssh:app" {
+		t.Errorf("CurrentTransport() = %q, want %q", got, "ssh:app")
+	}
+
+	v.ExitTransport()
+	if got := v.CurrentTransport(); got != "ssh:bastion" {
+		t.Errorf("after first ExitTransport() = %q, want %q", got, "ssh:bastion")
+	}
+
+	v.ExitTransport()
+	if got := v.CurrentTransport(); got != "local" {
+		t.Errorf("after second ExitTransport() = %q, want %q", got, "local")
+	}
+}
+
+func TestAccessByDisplayIDInTransport_ChecksGivenTransport(t *testing.T) {
+	v := NewWithPlanKey([]byte("test-key-32-bytes-long!!!!!!"))
+
+	// GIVEN: @env.HOME read from the remote session
+	v.EnterTransport("ssh:remote")
+	exprID := v.TrackExpression("@env.HOME")
+	v.MarkTouched(exprID)
+	v.StoreUnresolvedValue(exprID, "/home/deploy")
+	v.ResolveAllTouched()
+	v.Push("step-1")
+	v.RecordReference(exprID, "command")
+	displayID := v.GetDisplayID(exprID)
+
+	// AND: Planning has left the transport
+	v.ExitTransport()
+
+	path := []PathSegment{{Name: "root", Index: -1}, {Name: "step-1", Index: -1}}
+
+	// WHEN: Accessed in the transport it was read from
+	value, err := v.AccessByDisplayIDInTransport(displayID, "ssh:remote", path, "command")
+	// THEN: Allowed
+	if err != nil {
+		t.Fatalf("AccessByDisplayIDInTransport() in its own transport should succeed, got: %v", err)
+	}
+	if value != "/home/deploy" {
+		t.Errorf("AccessByDisplayIDInTransport() = %q, want %q", value, "/home/deploy")
+	}
+
+	// WHEN: Accessed locally
+	_, err = v.AccessByDisplayIDInTransport(displayID, "local", path, "command")
+	// THEN: Boundary violation
+	if err == nil || !containsString(err.Error(), "transport boundary violation") {
+		t.Errorf("expected transport boundary violation, got: %v", err)
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/opal-lang/opal/core/invariant"
+	"github.com/opal-lang/opal/core/sdk/secure"
 	"github.com/opal-lang/opal/runtime/streamscrub"
 )
 
@@ -66,7 +68,8 @@ import (
 //  1. Call ResolveAllTouched when resolving (captures transport context)
 //  2. Call MarkTouched for expressions in execution path
 //  3. Call PruneUntouched before BuildSecretUses
-//  4. Do not copy Vault after first use
+//  4. Call Close once execution and output scrubbing finish
+//  5. Do not copy Vault after first use
 //
 // See docs/ARCHITECTURE.md for complete architecture.
 type Vault struct {
@@ -88,6 +91,7 @@ type Vault struct {
 
 	// Transport boundary tracking
 	currentTransport string            // Current transport scope
+	transportStack   []string          // Enclosing transport scopes (for nested transports)
 	exprTransport    map[string]string // exprID → transport where resolved
 
 	// Security
@@ -101,9 +105,68 @@ type Vault struct {
 // In our security model: ALL expressions are secrets.
 type Expression struct {
 	Raw       string // Original source: "@var.X", "@aws.secret('key')", etc.
-	Value     any    // Resolved value (preserves original type: string, int, bool, map, slice)
+	Value     any    // Resolved value when not sealed (preserves original type: int, bool, ...)
 	DisplayID string // Placeholder ID for plan (e.g., "opal:3J98t56A")
 	Resolved  bool   // True if expression has been resolved (even if Value is nil)
+
+	// Strings, byte slices, objects and arrays are sealed in wiped memory
+	// instead of Value (see seal)
+	sealed *secure.Buffer
+	kind   sealKind
+}
+
+// sealKind records how a sealed value is decoded.
+type sealKind uint8
+
+const (
+	sealNone   sealKind = iota
+	sealString          // string
+	sealBytes           // []byte
+	sealJSON            // Objects and arrays, as canonical JSON
+)
+
+// seal stores value in the expression. Strings, byte slices, objects and
+// arrays are copied into a secure.Buffer that Close wipes; other values
+// (numbers, booleans) are kept in Value.
+func (e *Expression) seal(value any) {
+	switch val := value.(type) {
+	case string:
+		e.sealed, e.kind = secure.NewString(val), sealString
+	case []byte:
+		e.sealed, e.kind = secure.New(val), sealBytes
+	case map[string]any, []any:
+		// JSON sorts map keys, like computeDisplayID
+		data, err := json.Marshal(val)
+		invariant.Invariant(err == nil, "seal: failed to marshal value to JSON: %v", err)
+		e.sealed, e.kind = secure.New(data), sealJSON
+		secure.Wipe(data)
+	default:
+		e.Value = value
+	}
+}
+
+// hasValue reports whether a value has been stored.
+func (e *Expression) hasValue() bool {
+	return e.kind != sealNone || e.Value != nil
+}
+
+// value returns the stored value with its original type. Sealed values are
+// copied out of their buffer into ordinary memory: keep the copy
+// short-lived.
+func (e *Expression) value() any {
+	switch e.kind {
+	case sealString:
+		return string(e.sealed.Bytes())
+	case sealBytes:
+		return append([]byte{}, e.sealed.Bytes()...)
+	case sealJSON:
+		var value any
+		err := json.Unmarshal(e.sealed.Bytes(), &value)
+		invariant.Invariant(err == nil, "value: sealed JSON does not decode: %v", err)
+		return value
+	default:
+		return e.Value
+	}
 }
 
 // Note: No ExprType, no IsSecret - everything is a secret.
@@ -185,6 +248,20 @@ func (v *Vault) Push(name string) int {
 	return index
 }
 
+// PushAt adds a segment with an explicit instance index instead of the
+// per-level count. Used for scopes the executor must reproduce from the
+// plan alone (e.g., "@retry" indexed by its step ID), so that sibling
+// blocks never share a variable scope.
+func (v *Vault) PushAt(name string, index int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.pathStack = append(v.pathStack, PathSegment{
+		Name:  name,
+		Index: index,
+	})
+}
+
 // Pop removes the top segment from the path stack.
 // Panics if attempting to pop root (programmer error).
 func (v *Vault) Pop() {
@@ -217,9 +294,14 @@ func (v *Vault) BuildSitePath(paramName string) string {
 // buildSitePathLocked is the internal unlocked version of BuildSitePath.
 // Caller must hold at least a read lock.
 func (v *Vault) buildSitePathLocked(paramName string) string {
+	return sitePathFor(v.pathStack, paramName)
+}
+
+// sitePathFor formats path and paramName as a canonical site path.
+func sitePathFor(path []PathSegment, paramName string) string {
 	var parts []string
 
-	for _, seg := range v.pathStack {
+	for _, seg := range path {
 		// Decorators (starting with @) include instance index
 		if strings.HasPrefix(seg.Name, "@") {
 			parts = append(parts, fmt.Sprintf("%s[%d]", seg.Name, seg.Index))
@@ -237,22 +319,6 @@ func (v *Vault) buildSitePathLocked(paramName string) string {
 
 // ========== Scope Management ==========
 
-// currentScopePath converts pathStack to a scope path string.
-// Used for site paths (authorization). Includes all segments.
-func (v *Vault) currentScopePath() string {
-	var parts []string
-	for _, seg := range v.pathStack {
-		// Decorators (starting with @) include instance index
-		if strings.HasPrefix(seg.Name, "@") {
-			parts = append(parts, fmt.Sprintf("%s[%d]", seg.Name, seg.Index))
-		} else {
-			// Non-decorators (root, step-N) are just the name
-			parts = append(parts, seg.Name)
-		}
-	}
-	return strings.Join(parts, "/")
-}
-
 // currentVariableScopePath converts pathStack to a variable scope path.
 // Variable scopes exclude step segments (steps are not scopes).
 // Only root and decorator blocks create variable scopes.
@@ -316,7 +382,9 @@ func (v *Vault) LookupVariable(varName string) (string, error) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
-	scopePath := v.currentScopePath()
+	// Lookup walks variable scopes (step segments are not scopes), so a
+	// block nested inside step N still sees the enclosing scopes
+	scopePath := v.currentVariableScopePath()
 	visited := make(map[string]bool)
 
 	for scopePath != "" {
@@ -396,6 +464,78 @@ func (v *Vault) TrackExpression(raw string) string {
 	return exprID
 }
 
+// TrackField registers a field of a structured expression (an object or
+// array value) as its own expression, so @var.config.db.password gets its
+// own DisplayID, use sites and scrub pattern instead of unwrapping config.
+//
+// Path segments are object keys or array indexes, consumed while the value
+// is an object or array. Returns the field's expression ID and the number
+// of segments consumed: callers interpolating text leave the rest as text
+// ("@var.FILE.txt" is the FILE variable followed by ".txt"). With none
+// consumed, the expression itself is returned.
+//
+// Errors name the path, never the value.
+func (v *Vault) TrackField(exprID string, path []string) (string, int, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	expr, exists := v.expressions[exprID]
+	invariant.Precondition(exists, "TrackField: expression %q not found", exprID)
+
+	value := expr.value()
+	consumed := 0
+	for consumed < len(path) {
+		field, structured, err := fieldValue(value, path[consumed])
+		if !structured {
+			break
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("%s: %w", strings.Join(path[:consumed+1], "."), err)
+		}
+		value = field
+		consumed++
+	}
+	if consumed == 0 {
+		return exprID, 0, nil
+	}
+
+	// Fields of the same expression share IDs, like repeated expressions
+	raw := expr.Raw + "." + strings.Join(path[:consumed], ".")
+	fieldID := v.generateExprID(raw)
+	if _, exists := v.expressions[fieldID]; !exists {
+		// Resolved with the rest in ResolveAllTouched()
+		field := &Expression{Raw: raw}
+		field.seal(value)
+		v.expressions[fieldID] = field
+	}
+
+	return fieldID, consumed, nil
+}
+
+// fieldValue returns the field named by segment of an object or array
+// value. structured is false for other values, which have no fields.
+func fieldValue(value any, segment string) (field any, structured bool, err error) {
+	switch val := value.(type) {
+	case map[string]any:
+		field, ok := val[segment]
+		if !ok {
+			return nil, true, fmt.Errorf("no such field")
+		}
+		return field, true, nil
+	case []any:
+		index, err := strconv.Atoi(segment)
+		if err != nil {
+			return nil, true, fmt.Errorf("array index must be an integer")
+		}
+		if index < 0 || index >= len(val) {
+			return nil, true, fmt.Errorf("index out of range (%d items)", len(val))
+		}
+		return val[index], true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
 // generateExprID creates a deterministic expression ID including transport context.
 func (v *Vault) generateExprID(raw string) string {
 	// Include current transport for context-sensitive IDs
@@ -467,6 +607,11 @@ func (v *Vault) computeDisplayID(value any) string {
 		invariant.Invariant(err == nil, "computeDisplayID: failed to marshal value to JSON: %v", err)
 	}
 
+	return v.displayIDFor(canonical)
+}
+
+// displayIDFor computes the DisplayID hash of a value's canonical bytes.
+func (v *Vault) displayIDFor(canonical []byte) string {
 	if len(v.planKey) == 0 {
 		// Backward compatibility for tests that don't set planKey
 		h := sha256.New()
@@ -590,18 +735,36 @@ func (v *Vault) PruneUntouched() {
 	}
 }
 
-// EnterTransport enters a new transport scope.
+// EnterTransport enters a new transport scope (e.g., "ssh:web1" for an
+// @ssh.connect block). Transports nest: ExitTransport returns to the
+// enclosing one.
 func (v *Vault) EnterTransport(scope string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.transportStack = append(v.transportStack, v.currentTransport)
 	v.currentTransport = scope
 }
 
-// ExitTransport exits current transport scope (returns to local).
+// ExitTransport exits the current transport scope, returning to the
+// enclosing one ("local" at the outermost level).
 func (v *Vault) ExitTransport() {
-	v.currentTransport = "local"
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.transportStack) == 0 {
+		v.currentTransport = "local"
+		return
+	}
+	v.currentTransport = v.transportStack[len(v.transportStack)-1]
+	v.transportStack = v.transportStack[:len(v.transportStack)-1]
 }
 
 // CurrentTransport returns the current transport scope.
 func (v *Vault) CurrentTransport() string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
 	return v.currentTransport
 }
 
@@ -621,7 +784,7 @@ func (v *Vault) ResolveAllTouched() {
 			continue
 		}
 
-		invariant.Invariant(expr.Value != nil,
+		invariant.Invariant(expr.hasValue(),
 			"ResolveAllTouched: expression %q is touched but has no value stored", exprID)
 
 		// Mark as resolved and capture transport context
@@ -629,7 +792,13 @@ func (v *Vault) ResolveAllTouched() {
 		v.exprTransport[exprID] = v.currentTransport
 
 		// Generate DisplayID from value using HMAC for unlinkability
-		hash := v.computeDisplayID(expr.Value)
+		var hash string
+		if expr.sealed != nil {
+			// Sealed bytes are the canonical form (see computeDisplayID)
+			hash = v.displayIDFor(expr.sealed.Bytes())
+		} else {
+			hash = v.computeDisplayID(expr.Value)
+		}
 		expr.DisplayID = fmt.Sprintf("opal:%s", hash)
 
 		// Build reverse index for execution (DisplayID → exprID lookup)
@@ -647,11 +816,11 @@ func (v *Vault) StoreUnresolvedValue(exprID string, value any) {
 	expr, exists := v.expressions[exprID]
 	invariant.Precondition(exists, "StoreUnresolvedValue: expression %q not found", exprID)
 
-	if expr.Value != nil {
+	if expr.hasValue() {
 		return
 	}
 
-	expr.Value = value
+	expr.seal(value)
 }
 
 // GetDisplayID returns the placeholder ID for an expression.
@@ -694,8 +863,13 @@ func (v *Vault) GetPlanKey() []byte {
 	return keyCopy
 }
 
-// checkTransportBoundary checks if expression can be used in current transport.
-func (v *Vault) checkTransportBoundary(exprID string) error {
+// checkTransportBoundary checks if expression can be used in transport.
+//
+// Values are bound to the transport they were resolved in: the local HOME
+// means nothing on a remote host. Plain values the planner declares as
+// "literal:..." (variables, loop items, function arguments) cross freely;
+// passing one into a transport is how values are meant to cross.
+func (v *Vault) checkTransportBoundary(exprID, transport string) error {
 	// Get transport where expression was resolved
 	exprTransport, exists := v.exprTransport[exprID]
 
@@ -705,11 +879,15 @@ func (v *Vault) checkTransportBoundary(exprID string) error {
 		"expression %q has no transport recorded (ResolveAllTouched not called?)",
 		exprID)
 
+	if strings.HasPrefix(v.expressions[exprID].Raw, "literal:") {
+		return nil
+	}
+
 	// Check if crossing transport boundary (legitimate security check - return error)
-	if exprTransport != v.currentTransport {
+	if exprTransport != transport {
 		return fmt.Errorf(
 			"transport boundary violation: expression %q resolved in %q, cannot use in %q",
-			exprID, exprTransport, v.currentTransport,
+			exprID, exprTransport, transport,
 		)
 	}
 
@@ -740,6 +918,12 @@ func (v *Vault) Access(exprID, paramName string) (any, error) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
+	return v.accessAtSiteLocked(exprID, v.buildSitePathLocked(paramName), v.currentTransport)
+}
+
+// accessAtSiteLocked performs the Access checks for an explicit site path
+// and transport. Caller must hold the write lock.
+func (v *Vault) accessAtSiteLocked(exprID, currentSite, transport string) (any, error) {
 	// 0. Security: Require planKey for authorization checks
 	// Without planKey, all sites have SiteID="" which bypasses authorization
 	invariant.Precondition(len(v.planKey) > 0,
@@ -755,12 +939,11 @@ func (v *Vault) Access(exprID, paramName string) (any, error) {
 	}
 
 	// 2. Check transport boundary (Caveat - checked first as more fundamental)
-	if err := v.checkTransportBoundary(exprID); err != nil {
+	if err := v.checkTransportBoundary(exprID, transport); err != nil {
 		return nil, err
 	}
 
-	// 3. Build current site with parameter name
-	currentSite := v.buildSitePathLocked(paramName)
+	// 3. Compute SiteID for the current site
 	currentSiteID := v.computeSiteID(currentSite)
 
 	// 4. Check if current site is authorized (Tuple)
@@ -776,7 +959,7 @@ func (v *Vault) Access(exprID, paramName string) (any, error) {
 	}
 
 	// 5. Return value (preserves original type)
-	return expr.Value, nil
+	return expr.value(), nil
 }
 
 // AccessByDisplayID resolves a DisplayID to its actual value.
@@ -796,6 +979,60 @@ func (v *Vault) AccessByDisplayID(displayID, paramName string) (any, error) {
 	return v.Access(exprID, paramName)
 }
 
+// AccessByDisplayIDAt resolves a DisplayID at a site built from path instead
+// of the shared path stack. Used by concurrent execution (e.g., @parallel
+// branches), where each branch tracks its own position in the tree.
+// Performs the same authorization checks as Access.
+func (v *Vault) AccessByDisplayIDAt(displayID string, path []PathSegment, paramName string) (any, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	exprID, found := v.displayIDIndex[displayID]
+	if !found {
+		return nil, fmt.Errorf("DisplayID %q not found in vault", displayID)
+	}
+
+	return v.accessAtSiteLocked(exprID, sitePathFor(path, paramName), v.currentTransport)
+}
+
+// AccessByDisplayIDInTransport is AccessByDisplayIDAt for a use site inside
+// the given transport (e.g., a command in an @ssh.connect block). The
+// executor tracks transports per branch, like site paths, instead of
+// through EnterTransport.
+func (v *Vault) AccessByDisplayIDInTransport(displayID, transport string, path []PathSegment, paramName string) (any, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	exprID, found := v.displayIDIndex[displayID]
+	if !found {
+		return nil, fmt.Errorf("DisplayID %q not found in vault", displayID)
+	}
+
+	return v.accessAtSiteLocked(exprID, sitePathFor(path, paramName), transport)
+}
+
+// Close wipes every value the vault holds and forgets all expressions.
+// Call it once execution and output scrubbing finish: afterwards nothing
+// can be accessed, and the secret provider has no patterns left.
+func (v *Vault) Close() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, expr := range v.expressions {
+		if expr.sealed != nil {
+			expr.sealed.Destroy()
+		}
+		expr.Value = nil
+	}
+
+	v.expressions = make(map[string]*Expression)
+	v.displayIDIndex = make(map[string]string)
+	v.references = make(map[string][]SiteRef)
+	v.touched = make(map[string]bool)
+	v.scopes = make(map[string]*VaultScope)
+	v.exprTransport = make(map[string]string)
+}
+
 // ============================================================================
 // SecretProvider Implementation (for streamscrub integration)
 // ============================================================================
@@ -816,19 +1053,29 @@ func (v *Vault) getPatterns() []streamscrub.Pattern {
 
 		// Must match computeDisplayID() representation for scrubbing to work
 		var valueBytes []byte
-		switch v := expr.Value.(type) {
-		case string:
-			valueBytes = []byte(v)
-		case []byte:
-			valueBytes = v
-		case nil:
-			continue
+		switch expr.kind {
+		case sealString, sealBytes:
+			// Borrowed: patterns are only used while scrubbing, before Close
+			valueBytes = expr.sealed.Bytes()
 		default:
-			valueStr := fmt.Sprintf("%v", v)
-			if valueStr == "" || valueStr == "<nil>" {
+			switch val := expr.value().(type) {
+			case string:
+				valueBytes = []byte(val)
+			case []byte:
+				valueBytes = val
+			case nil:
 				continue
+			default:
+				switch val.(type) {
+				case map[string]any, []any:
+					patterns = v.appendLeafPatterns(patterns, val)
+				}
+				valueStr := fmt.Sprintf("%v", val)
+				if valueStr == "" || valueStr == "<nil>" {
+					continue
+				}
+				valueBytes = []byte(valueStr)
 			}
-			valueBytes = []byte(valueStr)
 		}
 
 		if len(valueBytes) == 0 {
@@ -844,6 +1091,32 @@ func (v *Vault) getPatterns() []streamscrub.Pattern {
 	return patterns
 }
 
+// appendLeafPatterns adds a pattern for each leaf of an object or array
+// value, with the DisplayID the leaf gets as a field (see TrackField), so
+// each leaf is scrubbed on its own wherever it appears.
+func (v *Vault) appendLeafPatterns(patterns []streamscrub.Pattern, value any) []streamscrub.Pattern {
+	switch val := value.(type) {
+	case map[string]any:
+		for _, field := range val {
+			patterns = v.appendLeafPatterns(patterns, field)
+		}
+	case []any:
+		for _, item := range val {
+			patterns = v.appendLeafPatterns(patterns, item)
+		}
+	default:
+		leaf := fmt.Sprintf("%v", val)
+		if leaf == "" || val == nil {
+			return patterns
+		}
+		patterns = append(patterns, streamscrub.Pattern{
+			Value:       []byte(leaf),
+			Placeholder: []byte("opal:" + v.computeDisplayID(val)),
+		})
+	}
+	return patterns
+}
+
 // SecretProvider returns a streamscrub.SecretProvider for this vault.
 // The provider replaces all resolved expression values with their DisplayIDs.
 //
@@ -91,6 +91,40 @@ func TestVault_PathTracking_NestedDecorators(t *testing.T) {
 	}
 }
 
+// TestVault_PathTracking_PushAt tests that explicit indices bypass the
+// per-level counters, so sibling blocks get distinct scopes.
+func TestVault_PathTracking_PushAt(t *testing.T) {
+	v := New()
+
+	// GIVEN: Two sibling @retry blocks in steps 1 and 3, each declaring X
+	v.Push("step-1")
+	v.PushAt("@retry", 1)
+	path1 := v.BuildSitePath("command")
+	v.DeclareVariable("X", "literal:first")
+	v.Pop()
+	v.Pop()
+
+	v.Push("step-3")
+	v.PushAt("@retry", 3)
+	path2 := v.BuildSitePath("command")
+	_, lookupErr := v.LookupVariable("X")
+	v.Pop()
+	v.Pop()
+
+	// THEN: Paths use the explicit indices
+	if path1 != "root/step-1/@retry[1]/params/command" {
+		t.Errorf("Path[0] = %q, want %q", path1, "root/step-1/@retry[1]/params/command")
+	}
+	if path2 != "root/step-3/@retry[3]/params/command" {
+		t.Errorf("Path[1] = %q, want %q", path2, "root/step-3/@retry[3]/params/command")
+	}
+
+	// AND: The second block does not see the first block's variable
+	if lookupErr == nil {
+		t.Error("sibling @retry blocks should not share a variable scope")
+	}
+}
+
 // TestVault_PathTracking_MultipleDecoratorsAtSameLevel tests that
 // different decorators at the same level get independent indices.
 func TestVault_PathTracking_MultipleDecoratorsAtSameLevel(t *testing.T) {
@@ -1302,3 +1336,150 @@ func TestVault_DisplayID_ByteSliceConsistency(t *testing.T) {
 	t.Logf("  Pattern value: %q", string(pattern.Value))
 	t.Logf("  Uses raw byte representation (not JSON-marshaled)")
 }
+
+// ========== Structured Value Tests ==========
+
+func TestVault_TrackField(t *testing.T) {
+	v := NewWithPlanKey(testKey)
+	config := map[string]any{
+		"db":    map[string]any{"host": "db.internal", "password": "hunter2"},
+		"hosts": []any{"web1", "web2"},
+	}
+	exprID := v.DeclareVariable("config", "literal:config")
+	v.StoreUnresolvedValue(exprID, config)
+
+	// Segments are consumed while the value has fields
+	pwID, consumed, err := v.TrackField(exprID, []string{"db", "password", "txt"})
+	if err != nil {
+		t.Fatalf("TrackField failed: %v", err)
+	}
+	if consumed != 2 || pwID == exprID {
+		t.Fatalf("Expected the password field with 2 segments consumed, got %q (%d)", pwID, consumed)
+	}
+
+	// The same field is the same expression
+	again, _, _ := v.TrackField(exprID, []string{"db", "password"})
+	if again != pwID {
+		t.Errorf("Expected the same expression for the same field, got %q and %q", pwID, again)
+	}
+
+	hostID, _, err := v.TrackField(exprID, []string{"hosts", "1"})
+	if err != nil {
+		t.Fatalf("TrackField failed: %v", err)
+	}
+
+	// Fields are resolved, authorized and scrubbed on their own
+	v.Push("step-1")
+	if err := v.RecordReference(pwID, "command"); err != nil {
+		t.Fatal(err)
+	}
+	v.MarkTouched(pwID)
+	v.MarkTouched(hostID)
+	v.ResolveAllTouched()
+
+	value, err := v.Access(pwID, "command")
+	if err != nil || value != "hunter2" {
+		t.Fatalf("Access = %v, %v; want the password", value, err)
+	}
+	if _, err := v.Access(hostID, "command"); err == nil {
+		t.Error("field without a use site should not be accessible")
+	}
+	if v.IsResolved(exprID) {
+		t.Error("using a field should not resolve the whole object")
+	}
+	if got, want := v.GetDisplayID(pwID), "opal:"+v.computeDisplayID("hunter2"); got != want {
+		t.Errorf("DisplayID = %q, want %q", got, want)
+	}
+
+	out, err := v.SecretProvider().HandleChunk([]byte("pw=hunter2 host=web2 db=db.internal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pw=" + v.GetDisplayID(pwID) + " host=" + v.GetDisplayID(hostID) + " db=db.internal"
+	if string(out) != want {
+		t.Errorf("scrubbed = %q, want %q", out, want)
+	}
+}
+
+func TestVault_TrackField_Errors(t *testing.T) {
+	v := NewWithPlanKey(testKey)
+	exprID := v.DeclareVariable("config", "literal:config")
+	v.StoreUnresolvedValue(exprID, map[string]any{"hosts": []any{"web1"}})
+
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{[]string{"port"}, "port: no such field"},
+		{[]string{"hosts", "1"}, "hosts.1: index out of range (1 items)"},
+		{[]string{"hosts", "-1"}, "hosts.-1: index out of range (1 items)"},
+		{[]string{"hosts", "first"}, "hosts.first: array index must be an integer"},
+	}
+	for _, tt := range tests {
+		_, _, err := v.TrackField(exprID, tt.path)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("TrackField(%v) error = %v, want %q", tt.path, err, tt.want)
+		}
+	}
+
+	// Values without fields consume nothing
+	nameID := v.DeclareVariable("NAME", "literal:report")
+	v.StoreUnresolvedValue(nameID, "report")
+	got, consumed, err := v.TrackField(nameID, []string{"txt"})
+	if err != nil || consumed != 0 || got != nameID {
+		t.Errorf("TrackField on a string = %q, %d, %v; want the variable itself", got, consumed, err)
+	}
+}
+
+// TestVault_StructuredValueLeafPatterns verifies a whole object scrubs each
+// leaf with the DisplayID the leaf has as a field
+func TestVault_StructuredValueLeafPatterns(t *testing.T) {
+	v := NewWithPlanKey(testKey)
+	exprID := v.DeclareVariable("config", "literal:config")
+	v.StoreUnresolvedValue(exprID, map[string]any{"user": "admin", "tokens": []any{"tok-a", ""}})
+	v.MarkTouched(exprID)
+	v.ResolveAllTouched()
+
+	out, err := v.SecretProvider().HandleChunk([]byte("user=admin token=tok-a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "user=opal:" + v.computeDisplayID("admin") + " token=opal:" + v.computeDisplayID("tok-a")
+	if string(out) != want {
+		t.Errorf("scrubbed = %q, want %q", out, want)
+	}
+}
+
+// TestVault_Close verifies Close wipes resolved values and leaves nothing
+// to access or scrub
+func TestVault_Close(t *testing.T) {
+	v := NewWithPlanKey(testKey)
+	exprID := v.DeclareVariable("TOKEN", "literal:token")
+	v.StoreUnresolvedValue(exprID, "s3cr3t-value")
+	v.Push("step-1")
+	if err := v.RecordReference(exprID, "command"); err != nil {
+		t.Fatal(err)
+	}
+	v.MarkTouched(exprID)
+	v.ResolveAllTouched()
+
+	if value, err := v.Access(exprID, "command"); err != nil || value != "s3cr3t-value" {
+		t.Fatalf("Access = %v, %v; want the value", value, err)
+	}
+	sealed := v.expressions[exprID].sealed
+	if sealed == nil {
+		t.Fatal("resolved value should be held in a secure buffer")
+	}
+
+	v.Close()
+	if sealed.Bytes() != nil {
+		t.Error("Close should destroy the value's buffer")
+	}
+	if _, err := v.Access(exprID, "command"); err == nil {
+		t.Error("Access should fail after Close")
+	}
+	if len(v.getPatterns()) != 0 {
+		t.Error("no scrub patterns should remain after Close")
+	}
+	v.Close() // Safe twice
+}